@@ -5,7 +5,9 @@ import (
 	"crypto/tls"
 	"crypto/x509/pkix"
 	"fmt"
-	"math/rand"
+	"hash/fnv"
+	"math"
+	"net"
 	"reflect"
 	"time"
 
@@ -17,11 +19,14 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	certutil "k8s.io/client-go/util/cert"
 	"k8s.io/client-go/util/keyutil"
 	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 const (
@@ -45,8 +50,20 @@ const (
 	// ClientCertificateUpdatedReason is a reason of condition ClusterCertificateRotatedCondition that
 	// the the client certificate succeeds
 	ClientCertificateUpdatedReason = "ClientCertificateUpdated"
+
+	// CSRCreateFailedReason is a reason of condition ClusterCertificateRotatedCondition that the csr of
+	// the client certificate fails to be created on the hub.
+	CSRCreateFailedReason = "CSRCreateFailed"
+
+	// SecretWriteFailedReason is a reason of condition ClusterCertificateRotatedCondition that the secret
+	// of the client certificate fails to be written.
+	SecretWriteFailedReason = "SecretWriteFailed"
 )
 
+// DefaultRotationJitter is the RotationJitter used if it is not set, the client certificate is rotated once it
+// has a random percentage range from 20% to 25% of its life remaining.
+const DefaultRotationJitter = 0.05
+
 // ControllerResyncInterval is exposed so that integration tests can crank up the constroller sync speed.
 var ControllerResyncInterval = 5 * time.Minute
 
@@ -59,6 +76,11 @@ type CSROption struct {
 	Subject *pkix.Name
 	// DNSNames represents DNS names used to create the client certificate
 	DNSNames []string
+	// IPAddresses represents IP addresses used to create the client certificate
+	IPAddresses []net.IP
+	// SubjectAltNamesSensitive is true indicates the client cert is sensitive to the DNSNames and IPAddresses.
+	// That means once any of them is missing in the client cert, the client cert will be recreated.
+	SubjectAltNamesSensitive bool
 	// SignerName is the name of the signer specified in the created csrs
 	SignerName string
 
@@ -93,6 +115,18 @@ type ClientCertOption struct {
 	// AdditonalSecretDataSensitive is true indicates the client cert is sensitive to the AdditonalSecretData.
 	// That means once AdditonalSecretData changes, the client cert will be recreated.
 	AdditionalSecretDataSensitive bool
+	// RotationJitter is the max fraction of the life of the client certificate added to the 20% of its life
+	// remaining when it is rotated, e.g. 0.3 rotates it with a random percentage range from 20% to 50% of its
+	// life remaining. The random percentage is derived from the certificate, so the certificates issued at the
+	// same time to many agents are rotated at different times. DefaultRotationJitter is used if it is nil, and
+	// 0 rotates it with exactly 20% of its life remaining.
+	RotationJitter *float64
+	// RetryBackoff retries the failed syncs of the controller with a backoff instead of the rate limiter of
+	// its queue if it is set.
+	RetryBackoff *helpers.RetryBackoff
+	// SecretStore stores the secret containing client certificate. The secret SecretNamespace/SecretName
+	// on the management cluster is used if it is not set.
+	SecretStore SecretStore
 }
 
 type StatusUpdateFunc func(ctx context.Context, cond metav1.Condition) error
@@ -105,10 +139,8 @@ type StatusUpdateFunc func(ctx context.Context, cond metav1.Condition) error
 type clientCertificateController struct {
 	ClientCertOption
 	CSROption
-	csrControl CSRControl
-	// managementCoreClient is used to create/delete hub kubeconfig secret on the management cluster
-	managementCoreClient corev1client.CoreV1Interface
-	controllerName       string
+	csrControl     CSRControl
+	controllerName string
 
 	// csrName is the name of csr created by controller and waiting for approval.
 	csrName string
@@ -137,12 +169,14 @@ func NewClientCertificateController(
 	controllerName string,
 ) factory.Controller {
 	c := clientCertificateController{
-		ClientCertOption:     clientCertOption,
-		CSROption:            csrOption,
-		csrControl:           csrControl,
-		managementCoreClient: managementCoreClient,
-		controllerName:       controllerName,
-		statusUpdater:        statusUpdater,
+		ClientCertOption: clientCertOption,
+		CSROption:        csrOption,
+		csrControl:       csrControl,
+		controllerName:   controllerName,
+		statusUpdater:    statusUpdater,
+	}
+	if c.SecretStore == nil {
+		c.SecretStore = NewKubeSecretStore(managementCoreClient, c.SecretNamespace, c.SecretName)
 	}
 
 	return factory.New().
@@ -162,14 +196,14 @@ func NewClientCertificateController(
 		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
 			return factory.DefaultQueueKey
 		}, c.EventFilterFunc, csrControl.Informer()).
-		WithSync(c.sync).
+		WithSync(c.RetryBackoff.WrapSync(controllerName, c.sync)).
 		ResyncEvery(ControllerResyncInterval).
 		ToController(controllerName, recorder)
 }
 
 func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
 	// get secret containing client certificate
-	secret, err := c.managementCoreClient.Secrets(c.SecretNamespace).Get(ctx, c.SecretName, metav1.GetOptions{})
+	secret, err := c.SecretStore.Get(ctx)
 	switch {
 	case apierrors.IsNotFound(err):
 		secret = &corev1.Secret{
@@ -247,13 +281,15 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 			newSecretConfig[k] = v
 		}
 		secret.Data = newSecretConfig
+		SetSecretChecksum(secret)
 		// save the changes into secret
-		if err := saveSecret(c.managementCoreClient, c.SecretNamespace, secret); err != nil {
+		if err := c.SecretStore.Save(ctx, secret); err != nil {
 			if updateErr := c.statusUpdater(ctx, metav1.Condition{
-				Type:    "ClusterCertificateRotated",
-				Status:  metav1.ConditionFalse,
-				Reason:  "ClientCertificateUpdateFailed",
-				Message: fmt.Sprintf("Failed to rotated client certificate %v", err),
+				Type:   ClusterCertificateRotatedCondition,
+				Status: metav1.ConditionFalse,
+				Reason: SecretWriteFailedReason,
+				Message: fmt.Sprintf("Failed to write client certificate into secret %s: %v",
+					c.SecretNamespace+"/"+c.SecretName, err),
 			}); updateErr != nil {
 				return updateErr
 			}
@@ -287,6 +323,8 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 		}
 
 		syncCtx.Recorder().Eventf("ClientCertificateCreated", "A new client certificate for %s is available", c.controllerName)
+		syncCtx.Recorder().Eventf("SecretChecksumChanged", "The secret %s is regenerated with checksum %s",
+			c.SecretNamespace+"/"+c.SecretName, secret.Annotations[SecretChecksumAnnotation])
 		c.reset()
 		return nil
 	}
@@ -294,14 +332,24 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 	// create a csr to request new client certificate if
 	// a. there is no valid client certificate issued for the current cluster/agent;
 	// b. client certificate is sensitive to the additional secret data and the data changes;
-	// c. client certificate exists and has less than a random percentage range from 20% to 25% of its life remaining;
+	// c. client certificate is sensitive to the subject alternative names and any of them is missing;
+	// d. client certificate exists and has less than a random percentage range from 20% to 20%+RotationJitter
+	//    of its life remaining;
+	var dnsNames []string
+	var ipAddresses []net.IP
+	if c.SubjectAltNamesSensitive {
+		dnsNames, ipAddresses = c.DNSNames, c.IPAddresses
+	}
 	shouldCreate, err := shouldCreateCSR(
 		c.controllerName,
 		secret,
 		syncCtx.Recorder(),
 		c.Subject,
 		c.AdditionalSecretDataSensitive,
-		c.AdditionalSecretData)
+		c.AdditionalSecretData,
+		dnsNames,
+		ipAddresses,
+		c.RotationJitter)
 	if err != nil {
 		return err
 	}
@@ -332,12 +380,20 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 	if err != nil {
 		return fmt.Errorf("invalid private key for certificate request: %w", err)
 	}
-	csrData, err := certutil.MakeCSR(privateKey, c.Subject, c.DNSNames, nil)
+	csrData, err := certutil.MakeCSR(privateKey, c.Subject, c.DNSNames, c.IPAddresses)
 	if err != nil {
 		return fmt.Errorf("unable to generate certificate request: %w", err)
 	}
 	createdCSRName, err := c.csrControl.create(ctx, syncCtx.Recorder(), c.ObjectMeta, csrData, c.SignerName, c.ExpirationSeconds)
 	if err != nil {
+		if updateErr := c.statusUpdater(ctx, metav1.Condition{
+			Type:    ClusterCertificateRotatedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  CSRCreateFailedReason,
+			Message: fmt.Sprintf("Failed to create csr with signer %q on hub: %v", c.SignerName, err),
+		}); updateErr != nil {
+			return updateErr
+		}
 		return err
 	}
 	c.keyData = keyData
@@ -345,16 +401,6 @@ func (c *clientCertificateController) sync(ctx context.Context, syncCtx factory.
 	return nil
 }
 
-func saveSecret(spokeCoreClient corev1client.CoreV1Interface, secretNamespace string, secret *corev1.Secret) error {
-	var err error
-	if secret.ResourceVersion == "" {
-		_, err = spokeCoreClient.Secrets(secretNamespace).Create(context.Background(), secret, metav1.CreateOptions{})
-		return err
-	}
-	_, err = spokeCoreClient.Secrets(secretNamespace).Update(context.Background(), secret, metav1.UpdateOptions{})
-	return err
-}
-
 func (c *clientCertificateController) reset() {
 	c.csrName = ""
 	c.keyData = nil
@@ -366,12 +412,17 @@ func shouldCreateCSR(
 	recorder events.Recorder,
 	subject *pkix.Name,
 	additionalSecretDataSensitive bool,
-	additionalSecretData map[string][]byte) (bool, error) {
+	additionalSecretData map[string][]byte,
+	dnsNames []string,
+	ipAddresses []net.IP,
+	rotationJitter *float64) (bool, error) {
 	switch {
 	case !hasValidClientCertificate(subject, secret):
 		recorder.Eventf("NoValidCertificateFound", "No valid client certificate for %s is found. Bootstrap is required", controllerName)
 	case additionalSecretDataSensitive && !hasAdditionalSecretData(additionalSecretData, secret):
 		recorder.Eventf("AdditonalSecretDataChanged", "The additonal secret data is changed. Re-create the client certificate for %s", controllerName)
+	case !hasSubjectAltNames(dnsNames, ipAddresses, secret):
+		recorder.Eventf("SubjectAltNamesChanged", "The subject alternative names are changed. Re-create the client certificate for %s", controllerName)
 	default:
 		notBefore, notAfter, err := getCertValidityPeriod(secret)
 		if err != nil {
@@ -381,9 +432,9 @@ func shouldCreateCSR(
 		total := notAfter.Sub(*notBefore)
 		remaining := time.Until(*notAfter)
 		klog.V(4).Infof("Client certificate for %s: time total=%v, remaining=%v, remaining/total=%v", controllerName, total, remaining, remaining.Seconds()/total.Seconds())
-		threshold := jitter(0.2, 0.25)
+		threshold := rotationThreshold(secret.Data[TLSCertFile], rotationJitter)
 		if remaining.Seconds()/total.Seconds() > threshold {
-			// Do nothing if the client certificate is valid and has more than a random percentage range from 20% to 25% of its life remaining
+			// Do nothing if the client certificate is valid and has more than the threshold of its life remaining
 			klog.V(4).Infof("Client certificate for %s is valid and has more than %.2f%% of its life remaining", controllerName, threshold*100)
 			return false, nil
 		}
@@ -407,12 +458,47 @@ func hasAdditionalSecretData(additionalSecretData map[string][]byte, secret *cor
 	return true
 }
 
-func jitter(percentage float64, maxFactor float64) float64 {
-	if maxFactor <= 0.0 {
-		maxFactor = 1.0
+// hasSubjectAltNames checks if the client certificate in the secret includes the DNS names and IP addresses.
+func hasSubjectAltNames(dnsNames []string, ipAddresses []net.IP, secret *corev1.Secret) bool {
+	if len(dnsNames) == 0 && len(ipAddresses) == 0 {
+		return true
+	}
+	certs, err := certutil.ParseCertsPEM(secret.Data[TLSCertFile])
+	if err != nil || len(certs) == 0 {
+		return false
+	}
+
+	cert := certs[0]
+	if !sets.New[string](cert.DNSNames...).HasAll(dnsNames...) {
+		return false
+	}
+	for _, ipAddress := range ipAddresses {
+		found := false
+		for _, certIP := range cert.IPAddresses {
+			if certIP.Equal(ipAddress) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// rotationThreshold returns the fraction of the life of the certificate remaining when it is rotated, which is
+// 20% plus a random fraction up to the jitter. The random fraction is derived from the hash of the certificate
+// instead of being drawn on each sync, otherwise the certificate is rotated at about the max threshold anyway
+// since it is checked on every resync.
+func rotationThreshold(certData []byte, rotationJitter *float64) float64 {
+	jitter := DefaultRotationJitter
+	if rotationJitter != nil {
+		jitter = *rotationJitter
 	}
-	newPercentage := percentage + percentage*rand.Float64()*maxFactor //#nosec G404
-	return newPercentage
+	hash := fnv.New64a()
+	_, _ = hash.Write(certData)
+	return 0.2 + jitter*float64(hash.Sum64())/math.MaxUint64
 }
 
 func hasValidClientCertificate(subject *pkix.Name, secret *corev1.Secret) bool {
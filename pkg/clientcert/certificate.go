@@ -143,8 +143,10 @@ func getCertValidityPeriod(secret *corev1.Secret) (*time.Time, *time.Time, error
 	return notBefore, notAfter, nil
 }
 
-// BuildKubeconfig builds a kubeconfig based on a rest config template with a cert/key pair
-func BuildKubeconfig(clientConfig *restclient.Config, certPath, keyPath string) clientcmdapi.Config {
+// BuildKubeconfig builds a kubeconfig based on a rest config template with a cert/key pair. If proxyURL
+// is not empty, the kubeconfig connects to the server through the proxy. Only the server settings of the
+// template are kept, the credential of the template, e.g. an exec credential plugin, is not copied.
+func BuildKubeconfig(clientConfig *restclient.Config, proxyURL, certPath, keyPath string) clientcmdapi.Config {
 	// Build kubeconfig.
 	kubeconfig := clientcmdapi.Config{
 		// Define a cluster stanza based on the bootstrap kubeconfig.
@@ -152,6 +154,8 @@ func BuildKubeconfig(clientConfig *restclient.Config, certPath, keyPath string)
 			Server:                   clientConfig.Host,
 			InsecureSkipTLSVerify:    false,
 			CertificateAuthorityData: clientConfig.CAData,
+			TLSServerName:            clientConfig.ServerName,
+			ProxyURL:                 proxyURL,
 		}},
 		// Define auth based on the obtained client cert.
 		AuthInfos: map[string]*clientcmdapi.AuthInfo{"default-auth": {
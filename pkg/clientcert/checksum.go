@@ -0,0 +1,83 @@
+package clientcert
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SecretChecksumAnnotation is the annotation on a secret written by the agent, e.g. the hub kubeconfig
+// secret, containing the checksum of the secret data. It changes once the credential in the secret is
+// regenerated, so the consumers of the secret are able to tell when to reload it.
+const SecretChecksumAnnotation = "open-cluster-management.io/secret-checksum"
+
+// SecretDataChecksum returns the sha256 checksum of the secret data.
+func SecretDataChecksum(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		// key and value are separated by a zero byte, so different data never share a checksum
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write(data[key])
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// SetSecretChecksum sets the checksum annotation of the secret with its data. It returns true if the
+// annotation is changed.
+func SetSecretChecksum(secret *corev1.Secret) bool {
+	checksum := SecretDataChecksum(secret.Data)
+	if secret.Annotations[SecretChecksumAnnotation] == checksum {
+		return false
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[SecretChecksumAnnotation] = checksum
+	return true
+}
+
+// SecretChecksum returns the checksum of the secret. The checksum is computed with the secret data if
+// the secret has no checksum annotation, e.g. it is written by an older agent.
+func SecretChecksum(secret *corev1.Secret) string {
+	if checksum, ok := secret.Annotations[SecretChecksumAnnotation]; ok {
+		return checksum
+	}
+	return SecretDataChecksum(secret.Data)
+}
+
+// AddCredentialChangedHandler registers a handler on the secret informer which is called with the
+// secret once the secret is created or its checksum changes. It can be used by addons to reload the
+// hub kubeconfig secret once the credential in it is regenerated, instead of watching the mounted files.
+func AddCredentialChangedHandler(secretInformer corev1informers.SecretInformer, namespace, name string, handler func(secret *corev1.Secret)) error {
+	_, err := secretInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			secret, ok := obj.(*corev1.Secret)
+			return ok && secret.Namespace == namespace && secret.Name == name
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				handler(obj.(*corev1.Secret))
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldSecret, newSecret := oldObj.(*corev1.Secret), newObj.(*corev1.Secret)
+				if SecretChecksum(oldSecret) == SecretChecksum(newSecret) {
+					return
+				}
+				handler(newSecret)
+			},
+		},
+	})
+	return err
+}
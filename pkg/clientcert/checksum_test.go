@@ -0,0 +1,123 @@
+package clientcert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSecretDataChecksum(t *testing.T) {
+	cases := []struct {
+		name     string
+		data1    map[string][]byte
+		data2    map[string][]byte
+		expected bool
+	}{
+		{
+			name:     "same data",
+			data1:    map[string][]byte{"a": []byte("1"), "b": []byte("2")},
+			data2:    map[string][]byte{"b": []byte("2"), "a": []byte("1")},
+			expected: true,
+		},
+		{
+			name:  "different value",
+			data1: map[string][]byte{"a": []byte("1")},
+			data2: map[string][]byte{"a": []byte("2")},
+		},
+		{
+			name:  "key and value are not mixed",
+			data1: map[string][]byte{"a": []byte("b1")},
+			data2: map[string][]byte{"ab": []byte("1")},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := SecretDataChecksum(c.data1) == SecretDataChecksum(c.data2)
+			if actual != c.expected {
+				t.Errorf("expected checksums equal to be %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSetSecretChecksum(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{KubeconfigFile: []byte("kubeconfig")}}
+	if !SetSecretChecksum(secret) {
+		t.Errorf("expected checksum to be set")
+	}
+	if SetSecretChecksum(secret) {
+		t.Errorf("expected checksum not to be changed")
+	}
+	if SecretChecksum(secret) != SecretDataChecksum(secret.Data) {
+		t.Errorf("expected checksum %q, but got %q", SecretDataChecksum(secret.Data), SecretChecksum(secret))
+	}
+
+	secret.Data[TLSCertFile] = []byte("cert")
+	if !SetSecretChecksum(secret) {
+		t.Errorf("expected checksum to be changed")
+	}
+}
+
+func TestAddCredentialChangedHandler(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset()
+	informerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	secretInformer := informerFactory.Core().V1().Secrets()
+
+	changed := make(chan string, 10)
+	if err := AddCredentialChangedHandler(secretInformer, "test", "hub-kubeconfig-secret", func(secret *corev1.Secret) {
+		changed <- string(secret.Data[KubeconfigFile])
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	newSecret := func(name, kubeconfig string) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: name, ResourceVersion: kubeconfig},
+			Data:       map[string][]byte{KubeconfigFile: []byte(kubeconfig)},
+		}
+		SetSecretChecksum(secret)
+		return secret
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory.Start(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+
+	secrets := kubeClient.CoreV1().Secrets("test")
+	if _, err := secrets.Create(context.TODO(), newSecret("other", "other"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := secrets.Create(context.TODO(), newSecret("hub-kubeconfig-secret", "v1"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertChanged(t, changed, "v1")
+
+	// the checksum is unchanged
+	unchanged := newSecret("hub-kubeconfig-secret", "v1")
+	unchanged.Labels = map[string]string{"test": "test"}
+	if _, err := secrets.Update(context.TODO(), unchanged, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := secrets.Update(context.TODO(), newSecret("hub-kubeconfig-secret", "v2"), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertChanged(t, changed, "v2")
+}
+
+func assertChanged(t *testing.T, changed chan string, expected string) {
+	select {
+	case actual := <-changed:
+		if actual != expected {
+			t.Errorf("expected changed kubeconfig %q, but got %q", expected, actual)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Errorf("expected changed kubeconfig %q, but got nothing", expected)
+	}
+}
@@ -2,8 +2,15 @@ package clientcert
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"net"
 	"testing"
 	"time"
 
@@ -17,6 +24,7 @@ import (
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
 
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 	"open-cluster-management.io/registration/pkg/hub/user"
@@ -44,6 +52,9 @@ func TestSync(t *testing.T) {
 		keyDataExpected              bool
 		csrNameExpected              bool
 		additonalSecretDataSensitive bool
+		createCSRErr                 error
+		writeSecretErr               error
+		expectedErr                  string
 		expectedCondition            *metav1.Condition
 		validateActions              func(t *testing.T, hubActions, agentActions []clienttesting.Action)
 	}{
@@ -148,6 +159,49 @@ func TestSync(t *testing.T) {
 				testinghelpers.AssertActions(t, agentActions, "get")
 			},
 		},
+		{
+			name:            "failed to create csr",
+			secrets:         []runtime.Object{},
+			queueKey:        "key",
+			createCSRErr:    fmt.Errorf("forbidden"),
+			keyDataExpected: false,
+			csrNameExpected: false,
+			expectedErr:     "forbidden",
+			expectedCondition: &metav1.Condition{
+				Type:   ClusterCertificateRotatedCondition,
+				Status: metav1.ConditionFalse,
+				Reason: CSRCreateFailedReason,
+			},
+			validateActions: func(t *testing.T, hubActions, agentActions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, hubActions, "create")
+				testinghelpers.AssertActions(t, agentActions, "get")
+			},
+		},
+		{
+			name:     "failed to write secret",
+			queueKey: testSecretName,
+			secrets: []runtime.Object{
+				testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "1", nil, map[string][]byte{
+					ClusterNameFile: []byte(testinghelpers.TestManagedClusterName),
+					AgentNameFile:   []byte(testAgentName),
+				},
+				),
+			},
+			writeSecretErr:  fmt.Errorf("conflict"),
+			approvedCSRCert: testinghelpers.NewTestCert(commonName, 10*time.Second),
+			keyDataExpected: true,
+			csrNameExpected: true,
+			expectedErr:     "conflict",
+			expectedCondition: &metav1.Condition{
+				Type:   ClusterCertificateRotatedCondition,
+				Status: metav1.ConditionFalse,
+				Reason: SecretWriteFailedReason,
+			},
+			validateActions: func(t *testing.T, hubActions, agentActions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, hubActions, "get", "get")
+				testinghelpers.AssertActions(t, agentActions, "get", "update")
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -171,7 +225,17 @@ func TestSync(t *testing.T) {
 					return true, testinghelpers.NewCSR(testinghelpers.CSRHolder{Name: testCSRName}), nil
 				},
 			)
+			if c.createCSRErr != nil {
+				hubKubeClient.PrependReactor("create", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, c.createCSRErr
+				})
+			}
 			agentKubeClient := kubefake.NewSimpleClientset(c.secrets...)
+			if c.writeSecretErr != nil {
+				agentKubeClient.PrependReactor("update", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, c.writeSecretErr
+				})
+			}
 
 			clientCertOption := ClientCertOption{
 				SecretNamespace: testNamespace,
@@ -181,6 +245,7 @@ func TestSync(t *testing.T) {
 					AgentNameFile:   []byte(testAgentName),
 				},
 				AdditionalSecretDataSensitive: c.additonalSecretDataSensitive,
+				SecretStore:                   NewKubeSecretStore(agentKubeClient.CoreV1(), testNamespace, testSecretName),
 			}
 			csrOption := CSROption{
 				ObjectMeta: metav1.ObjectMeta{
@@ -194,12 +259,11 @@ func TestSync(t *testing.T) {
 			updater := &fakeStatusUpdater{}
 
 			controller := &clientCertificateController{
-				ClientCertOption:     clientCertOption,
-				CSROption:            csrOption,
-				csrControl:           ctrl,
-				managementCoreClient: agentKubeClient.CoreV1(),
-				controllerName:       "test-agent",
-				statusUpdater:        updater.update,
+				ClientCertOption: clientCertOption,
+				CSROption:        csrOption,
+				csrControl:       ctrl,
+				controllerName:   "test-agent",
+				statusUpdater:    updater.update,
 			}
 
 			if c.approvedCSRCert != nil {
@@ -208,9 +272,7 @@ func TestSync(t *testing.T) {
 			}
 
 			err := controller.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, c.queueKey))
-			if err != nil {
-				t.Errorf("unexpected error %v", err)
-			}
+			testinghelpers.AssertError(t, err, c.expectedErr)
 
 			hasKeyData := controller.keyData != nil
 			if c.keyDataExpected != hasKeyData {
@@ -250,6 +312,10 @@ func conditionEqual(expected, actual *metav1.Condition) bool {
 		return false
 	}
 
+	if len(expected.Reason) != 0 && expected.Reason != actual.Reason {
+		return false
+	}
+
 	return true
 }
 
@@ -305,3 +371,120 @@ func (m *mockCSRControl) getIssuedCertificate(name string) ([]byte, error) {
 func (m *mockCSRControl) Informer() cache.SharedIndexInformer {
 	panic("implement me")
 }
+
+func TestHasSubjectAltNames(t *testing.T) {
+	secret := &corev1.Secret{
+		Data: map[string][]byte{
+			TLSCertFile: newTestCertWithSubjectAltNames(t, []string{"addon1.addon.open-cluster-management.io", "addon1.svc"},
+				[]net.IP{net.ParseIP("10.0.0.1")}),
+		},
+	}
+
+	cases := []struct {
+		name        string
+		secret      *corev1.Secret
+		dnsNames    []string
+		ipAddresses []net.IP
+		expected    bool
+	}{
+		{
+			name:     "no subject alternative names",
+			secret:   &corev1.Secret{},
+			expected: true,
+		},
+		{
+			name:     "no client certificate",
+			secret:   &corev1.Secret{},
+			dnsNames: []string{"addon1.svc"},
+		},
+		{
+			name:        "all subject alternative names are in the client certificate",
+			secret:      secret,
+			dnsNames:    []string{"addon1.svc"},
+			ipAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+			expected:    true,
+		},
+		{
+			name:     "dns name is missing",
+			secret:   secret,
+			dnsNames: []string{"addon1.svc", "addon1.svc.cluster.local"},
+		},
+		{
+			name:        "ip address is missing",
+			secret:      secret,
+			ipAddresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := hasSubjectAltNames(c.dnsNames, c.ipAddresses, c.secret); actual != c.expected {
+				t.Errorf("expected %t, but got %t", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRotationThreshold(t *testing.T) {
+	cases := []struct {
+		name        string
+		jitter      *float64
+		expectedMax float64
+	}{
+		{
+			name:        "default jitter",
+			expectedMax: 0.2 + DefaultRotationJitter,
+		},
+		{
+			name:        "large jitter",
+			jitter:      pointer.Float64(0.5),
+			expectedMax: 0.7,
+		},
+		{
+			name:        "jitter is disabled",
+			jitter:      pointer.Float64(0),
+			expectedMax: 0.2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			thresholds := map[float64]bool{}
+			for i := 0; i < 10; i++ {
+				certData := testinghelpers.NewTestCert(commonName, time.Hour).Cert
+				threshold := rotationThreshold(certData, c.jitter)
+				if threshold < 0.2 || threshold > c.expectedMax {
+					t.Errorf("expected the threshold in the range from 0.2 to %v, but got %v", c.expectedMax, threshold)
+				}
+				if rotationThreshold(certData, c.jitter) != threshold {
+					t.Errorf("expected the threshold of a certificate is stable")
+				}
+				thresholds[threshold] = true
+			}
+			// the certificates issued at the same time are rotated at different times
+			if c.expectedMax > 0.2 && len(thresholds) == 1 {
+				t.Errorf("expected the thresholds of the certificates are different")
+			}
+		})
+	}
+}
+
+func newTestCertWithSubjectAltNames(t *testing.T, dnsNames []string, ipAddresses []net.IP) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		Subject:      pkix.Name{CommonName: commonName},
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     dnsNames,
+		IPAddresses:  ipAddresses,
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,244 @@
+package clientcert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/storage/value"
+	"k8s.io/apiserver/pkg/storage/value/encrypt/aes"
+	"k8s.io/apiserver/pkg/storage/value/encrypt/envelope"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// SecretStore persists the secret containing the client certificate.
+type SecretStore interface {
+	// Get returns the secret, a NotFound error is returned if the secret does not exist.
+	Get(ctx context.Context) (*corev1.Secret, error)
+	// Save creates the secret if it has no resource version, or updates it otherwise.
+	Save(ctx context.Context, secret *corev1.Secret) error
+}
+
+// kubeSecretStore stores the secret in a cluster, e.g. the management cluster where the agent runs.
+type kubeSecretStore struct {
+	coreClient corev1client.CoreV1Interface
+	namespace  string
+	name       string
+}
+
+// NewKubeSecretStore returns a SecretStore which stores the secret namespace/name with the given client.
+func NewKubeSecretStore(coreClient corev1client.CoreV1Interface, namespace, name string) SecretStore {
+	return &kubeSecretStore{
+		coreClient: coreClient,
+		namespace:  namespace,
+		name:       name,
+	}
+}
+
+func (s *kubeSecretStore) Get(ctx context.Context) (*corev1.Secret, error) {
+	return s.coreClient.Secrets(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+}
+
+func (s *kubeSecretStore) Save(ctx context.Context, secret *corev1.Secret) error {
+	var err error
+	if secret.ResourceVersion == "" {
+		_, err = s.coreClient.Secrets(s.namespace).Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	}
+	_, err = s.coreClient.Secrets(s.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+// MemorySecretStore keeps the secret in memory only, so that the client certificate and its private key
+// are never written into a cluster or a file. The secret is lost once the process exits, and a new
+// client certificate is requested on the next start.
+type MemorySecretStore struct {
+	namespace string
+	name      string
+
+	lock     sync.RWMutex
+	secret   *corev1.Secret
+	revision int64
+}
+
+var _ SecretStore = &MemorySecretStore{}
+
+// NewMemorySecretStore returns an empty MemorySecretStore, the namespace and name are only set on the
+// secret returned.
+func NewMemorySecretStore(namespace, name string) *MemorySecretStore {
+	return &MemorySecretStore{
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (s *MemorySecretStore) Get(ctx context.Context) (*corev1.Secret, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.secret == nil {
+		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), s.name)
+	}
+	return s.secret.DeepCopy(), nil
+}
+
+// ResourceVersion returns the resource version of the secret in the store without copying it, it is empty if the
+// secret does not exist.
+func (s *MemorySecretStore) ResourceVersion() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.secret == nil {
+		return ""
+	}
+	return s.secret.ResourceVersion
+}
+
+// Save keeps a copy of the secret, a new resource version is set on the copy every time the secret is saved,
+// so the callers are able to tell whether the secret is changed.
+func (s *MemorySecretStore) Save(ctx context.Context, secret *corev1.Secret) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.secret != nil && secret.ResourceVersion != s.secret.ResourceVersion {
+		return apierrors.NewConflict(corev1.Resource("secrets"), s.name,
+			errors.New("the object has been modified; please apply your changes to the latest version and try again"))
+	}
+
+	s.revision++
+	s.secret = secret.DeepCopy()
+	s.secret.Namespace = s.namespace
+	s.secret.Name = s.name
+	s.secret.ResourceVersion = strconv.FormatInt(s.revision, 10)
+	return nil
+}
+
+const (
+	// EncryptedTLSKeyFile is the key of the private key encrypted by a transforming SecretStore in the secret.
+	EncryptedTLSKeyFile = "tls.key.enc"
+)
+
+// EncryptedSecretKeys maps the keys of the secret data encrypted by a transforming SecretStore to the keys
+// of the encrypted data in the stored secret. The encrypted data is kept under a separate key, so that the
+// other consumers of the secret, e.g. the addon agents mounting it, never read the encrypted data as the
+// private key. The certificate, the kubeconfig and the names are kept in plain text, so that they are still
+// readable by the other controllers and tools, e.g. to report the expiry of the certificate.
+var EncryptedSecretKeys = map[string]string{TLSKeyFile: EncryptedTLSKeyFile}
+
+// kmsSecretPrefix is the prefix of the secret data encrypted with a KMS plugin.
+const kmsSecretPrefix = "k8s:enc:kms:v1:hub-kubeconfig:"
+
+// NewKMSSecretTransformer returns a transformer which encrypts the secret data with the envelope encryption of
+// the KMS service, e.g. a KMS v1 plugin of the kube-apiserver.
+func NewKMSSecretTransformer(service envelope.Service) value.Transformer {
+	return value.NewPrefixTransformers(fmt.Errorf("no valid encryption prefix of the secret data"),
+		value.PrefixTransformer{
+			Prefix:      []byte(kmsSecretPrefix),
+			Transformer: envelope.NewEnvelopeTransformer(service, 100, aes.NewGCMTransformer),
+		},
+	)
+}
+
+// transformingSecretStore encrypts the EncryptedSecretKeys of the secret before it is saved into the delegate
+// store, and decrypts them once the secret is read.
+type transformingSecretStore struct {
+	delegate    SecretStore
+	transformer value.Transformer
+}
+
+// NewTransformingSecretStore returns a SecretStore which encrypts and decrypts the secret in the store with the
+// transformer, e.g. for the environments which distrust the encryption of the etcd of the managed cluster.
+func NewTransformingSecretStore(store SecretStore, transformer value.Transformer) SecretStore {
+	return &transformingSecretStore{
+		delegate:    store,
+		transformer: transformer,
+	}
+}
+
+// Get returns the decrypted secret. A secret with the data in plain text or encrypted with a stale key is saved
+// with the data encrypted again before it is returned.
+func (s *transformingSecretStore) Get(ctx context.Context) (*corev1.Secret, error) {
+	secret, err := s.delegate.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, stale, err := TransformSecretFromStorage(ctx, s.transformer, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !stale {
+		return decrypted, nil
+	}
+
+	klog.Infof("Encrypt the data of secret %s/%s", secret.Namespace, secret.Name)
+	if err := s.Save(ctx, decrypted); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx)
+}
+
+func (s *transformingSecretStore) Save(ctx context.Context, secret *corev1.Secret) error {
+	encrypted, err := TransformSecretToStorage(ctx, s.transformer, secret)
+	if err != nil {
+		return err
+	}
+	return s.delegate.Save(ctx, encrypted)
+}
+
+// TransformSecretFromStorage returns a copy of the secret whose EncryptedSecretKeys are decrypted with the
+// transformer and kept under their own keys. It returns true if the secret needs to be encrypted again, e.g. the
+// data is in plain text or encrypted with a stale key.
+func TransformSecretFromStorage(ctx context.Context, transformer value.Transformer, secret *corev1.Secret) (*corev1.Secret, bool, error) {
+	secret = secret.DeepCopy()
+	stale := false
+	for key, encryptedKey := range EncryptedSecretKeys {
+		data, ok := secret.Data[encryptedKey]
+		if !ok {
+			// the data in plain text is encrypted once the secret is saved
+			_, plain := secret.Data[key]
+			stale = stale || plain
+			continue
+		}
+		decrypted, keyStale, err := transformer.TransformFromStorage(ctx, data, secretDataContext(secret, key))
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to decrypt %s of secret %s/%s: %w", encryptedKey, secret.Namespace, secret.Name, err)
+		}
+		// the data in plain text left beside the encrypted data is removed once the secret is saved
+		_, plain := secret.Data[key]
+		secret.Data[key] = decrypted
+		delete(secret.Data, encryptedKey)
+		stale = stale || keyStale || plain
+	}
+	return secret, stale, nil
+}
+
+// TransformSecretToStorage returns a copy of the secret whose EncryptedSecretKeys are encrypted with the
+// transformer and moved to the keys of the encrypted data.
+func TransformSecretToStorage(ctx context.Context, transformer value.Transformer, secret *corev1.Secret) (*corev1.Secret, error) {
+	secret = secret.DeepCopy()
+	for key, encryptedKey := range EncryptedSecretKeys {
+		data, ok := secret.Data[key]
+		if !ok {
+			continue
+		}
+		encrypted, err := transformer.TransformToStorage(ctx, data, secretDataContext(secret, key))
+		if err != nil {
+			return nil, fmt.Errorf("unable to encrypt %s of secret %s/%s: %w", key, secret.Namespace, secret.Name, err)
+		}
+		secret.Data[encryptedKey] = encrypted
+		delete(secret.Data, key)
+	}
+	return secret, nil
+}
+
+// secretDataContext binds the encrypted data to the key of the secret, so that it cannot be copied to another
+// secret or key.
+func secretDataContext(secret *corev1.Secret, key string) value.Context {
+	return value.DefaultContext(fmt.Sprintf("%s/%s/%s", secret.Namespace, secret.Name, key))
+}
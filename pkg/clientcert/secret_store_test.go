@@ -0,0 +1,173 @@
+package clientcert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubeSecretStore(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset()
+	store := NewKubeSecretStore(kubeClient.CoreV1(), testNamespace, testSecretName)
+
+	if _, err := store.Get(context.TODO()); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected not found error, but got %v", err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testSecretName},
+		Data:       map[string][]byte{TLSCertFile: []byte("cert1")},
+	}
+	if err := store.Save(context.TODO(), secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secret.ResourceVersion = "1"
+	secret.Data[TLSCertFile] = []byte("cert2")
+	if err := store.Save(context.TODO(), secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actions := kubeClient.Actions()
+	if len(actions) != 3 || actions[1].GetVerb() != "create" || actions[2].GetVerb() != "update" {
+		t.Errorf("expected get, create and update actions, but got %v", actions)
+	}
+	saved, err := store.Get(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(saved.Data[TLSCertFile]) != "cert2" {
+		t.Errorf("expected cert2 in secret, but got %q", saved.Data[TLSCertFile])
+	}
+}
+
+func TestMemorySecretStore(t *testing.T) {
+	store := NewMemorySecretStore(testNamespace, testSecretName)
+
+	if _, err := store.Get(context.TODO()); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected not found error, but got %v", err)
+	}
+	if len(store.ResourceVersion()) != 0 {
+		t.Errorf("expected no resource version without the secret, but got %q", store.ResourceVersion())
+	}
+
+	secret := &corev1.Secret{Data: map[string][]byte{TLSCertFile: []byte("cert1")}}
+	if err := store.Save(context.TODO(), secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secret.ResourceVersion) != 0 {
+		t.Errorf("expected the saved secret is not changed, but got resource version %q", secret.ResourceVersion)
+	}
+
+	saved, err := store.Get(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.ResourceVersion() != "1" {
+		t.Errorf("expected resource version 1 in store, but got %q", store.ResourceVersion())
+	}
+	if saved.Namespace != testNamespace || saved.Name != testSecretName || saved.ResourceVersion != "1" {
+		t.Errorf("expected secret %s/%s with resource version 1, but got %s/%s with %q",
+			testNamespace, testSecretName, saved.Namespace, saved.Name, saved.ResourceVersion)
+	}
+
+	// the secret returned is a copy
+	saved.Data[TLSCertFile] = []byte("cert2")
+	if current, _ := store.Get(context.TODO()); string(current.Data[TLSCertFile]) != "cert1" {
+		t.Errorf("expected cert1 in store, but got %q", current.Data[TLSCertFile])
+	}
+
+	// a secret created again or updated from a stale copy conflicts
+	if err := store.Save(context.TODO(), secret); !apierrors.IsConflict(err) {
+		t.Errorf("expected conflict error, but got %v", err)
+	}
+
+	if err := store.Save(context.TODO(), saved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current, _ := store.Get(context.TODO())
+	if string(current.Data[TLSCertFile]) != "cert2" || current.ResourceVersion != "2" {
+		t.Errorf("expected cert2 with resource version 2 in store, but got %q with %q",
+			current.Data[TLSCertFile], current.ResourceVersion)
+	}
+}
+
+// fakeKMSService reverses the data encrypted, the data not reversed by it fails to be decrypted.
+type fakeKMSService struct{}
+
+func (fakeKMSService) Encrypt(data []byte) ([]byte, error) {
+	return append([]byte("kms:"), reverse(data)...), nil
+}
+
+func (fakeKMSService) Decrypt(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte("kms:")) {
+		return nil, errors.New("invalid data")
+	}
+	return reverse(bytes.TrimPrefix(data, []byte("kms:"))), nil
+}
+
+func reverse(data []byte) []byte {
+	reversed := make([]byte, len(data))
+	for i := range data {
+		reversed[len(data)-1-i] = data[i]
+	}
+	return reversed
+}
+
+func TestTransformingSecretStore(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testSecretName, ResourceVersion: "1"},
+		Data: map[string][]byte{
+			TLSCertFile: []byte("cert1"),
+			TLSKeyFile:  []byte("key1"),
+		},
+	})
+	store := NewTransformingSecretStore(NewKubeSecretStore(kubeClient.CoreV1(), testNamespace, testSecretName),
+		NewKMSSecretTransformer(fakeKMSService{}))
+
+	// the key in plain text is encrypted once it is read
+	secret, err := store.Get(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(secret.Data[TLSCertFile]) != "cert1" || string(secret.Data[TLSKeyFile]) != "key1" {
+		t.Errorf("expected cert1 and key1 in secret, but got %q and %q", secret.Data[TLSCertFile], secret.Data[TLSKeyFile])
+	}
+	stored, err := kubeClient.CoreV1().Secrets(testNamespace).Get(context.TODO(), testSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(stored.Data[TLSCertFile]) != "cert1" {
+		t.Errorf("expected cert1 in plain text, but got %q", stored.Data[TLSCertFile])
+	}
+	if !bytes.HasPrefix(stored.Data[EncryptedTLSKeyFile], []byte(kmsSecretPrefix)) || bytes.Contains(stored.Data[EncryptedTLSKeyFile], []byte("key1")) {
+		t.Errorf("expected key1 encrypted, but got %q", stored.Data[EncryptedTLSKeyFile])
+	}
+	if _, ok := stored.Data[TLSKeyFile]; ok {
+		t.Errorf("expected no %s in the stored secret, but got %q", TLSKeyFile, stored.Data[TLSKeyFile])
+	}
+
+	secret.Data[TLSKeyFile] = []byte("key2")
+	if err := store.Save(context.TODO(), secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secret, err = store.Get(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(secret.Data[TLSKeyFile]) != "key2" {
+		t.Errorf("expected key2 in secret, but got %q", secret.Data[TLSKeyFile])
+	}
+
+	// the encrypted key is bound to the secret
+	stored, _ = kubeClient.CoreV1().Secrets(testNamespace).Get(context.TODO(), testSecretName, metav1.GetOptions{})
+	stored.Name = "another"
+	if _, _, err := TransformSecretFromStorage(context.TODO(), NewKMSSecretTransformer(fakeKMSService{}), stored); err == nil {
+		t.Errorf("expected error to decrypt the key of another secret")
+	}
+}
@@ -1,20 +1,36 @@
 package hub
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"github.com/openshift/library-go/pkg/config/client"
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/config"
 	"open-cluster-management.io/registration/pkg/hub"
 	"open-cluster-management.io/registration/pkg/version"
 )
 
+const (
+	// componentName is the name of the controller, the lease of its leader election is named after it
+	componentName = "registration-controller"
+	// defaultSecurePort is the port the controller serves at by default
+	defaultSecurePort = 8443
+)
+
 func NewController() *cobra.Command {
 	manager := hub.NewHubManagerOptions()
 	cmdConfig := controllercmd.
-		NewControllerCommandConfig("registration-controller", version.Get(), manager.RunControllerManager)
+		NewControllerCommandConfig(componentName, version.Get(), manager.RunControllerManager)
 	cmd := cmdConfig.NewCommand()
 	cmd.Use = "controller"
 	cmd.Short = "Start the Cluster Registration Controller"
@@ -35,7 +51,75 @@ func NewController() *cobra.Command {
 		"The duration the clients should wait between attempting acquisition and renewal "+
 		"of a leadership. This is only applicable if leader election is enabled.")
 
+	flags.BoolVar(&cmdConfig.DisableLeaderElection, "disable-leader-election", false, "Disable leader election for the controller.")
+
+	var bindAddress string
+	var securePort int
+	flags.StringVar(&bindAddress, "bind-address", "", ""+
+		"The IP address the metrics and health endpoints of the controller serve at, e.g. '::' for a dual-stack or IPv6-only hub. "+
+		"All the addresses of the host are served at if not set. It cannot be used together with --listen.")
+	flags.IntVar(&securePort, "secure-port", defaultSecurePort, ""+
+		"The port the metrics and health endpoints of the controller serve at. It cannot be used together with --listen.")
+
 	manager.AddFlags(cmd.Flags())
 
+	// the health probes are served before the leader election, so that the instances which are not leading are
+	// probed as well
+	run := cmd.Run
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		if err := serveHealthProbes(cmd.Context(), cmd.Flags(), manager, cmdConfig); err != nil {
+			klog.Fatal(err)
+		}
+		run(cmd, args)
+	}
+
+	// the flags which are not set on the command line are loaded from the configuration file
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := applyBindAddress(cmd.Flags(), bindAddress, securePort); err != nil {
+			return err
+		}
+		configFile, err := cmd.Flags().GetString(config.ConfigFlagName)
+		if err != nil || len(configFile) == 0 {
+			return err
+		}
+		return config.ApplyControllerConfigurationFile(configFile, cmd.Flags())
+	}
+
 	return cmd
 }
+
+// serveHealthProbes serves the health probes of the manager with the kubeconfig and the leader election lease of
+// the controller.
+func serveHealthProbes(ctx context.Context, flags *pflag.FlagSet, manager *hub.HubManagerOptions, cmdConfig *controllercmd.ControllerCommandConfig) error {
+	kubeconfig, err := flags.GetString("kubeconfig")
+	if err != nil {
+		return err
+	}
+	kubeConfig, err := client.GetKubeConfigOrInClusterConfig(kubeconfig, nil)
+	if err != nil {
+		return err
+	}
+	leaseName := componentName + "-lock"
+	if cmdConfig.DisableLeaderElection {
+		leaseName = ""
+	}
+	return manager.ServeHealthProbes(ctx, kubeConfig, leaseName, cmdConfig.LeaseDuration.Duration)
+}
+
+// applyBindAddress sets the --listen flag of the controller with the bind address and the secure port, if
+// either of them is set on the command line.
+func applyBindAddress(flags *pflag.FlagSet, bindAddress string, securePort int) error {
+	if !flags.Changed("bind-address") && !flags.Changed("secure-port") {
+		return nil
+	}
+	if flags.Changed("listen") {
+		return fmt.Errorf("--bind-address and --secure-port cannot be used together with --listen")
+	}
+	if len(bindAddress) == 0 {
+		bindAddress = "0.0.0.0"
+	}
+	if net.ParseIP(bindAddress) == nil {
+		return fmt.Errorf("invalid --bind-address %q: not an IP address", bindAddress)
+	}
+	return flags.Set("listen", net.JoinHostPort(bindAddress, strconv.Itoa(securePort)))
+}
@@ -5,6 +5,11 @@ import (
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	// to ensure that the bootstrap kubeconfig can make use of them.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"open-cluster-management.io/registration/pkg/config"
 	"open-cluster-management.io/registration/pkg/spoke"
 	"open-cluster-management.io/registration/pkg/version"
 )
@@ -22,5 +27,14 @@ func NewAgent() *cobra.Command {
 	agentOptions.AddFlags(flags)
 
 	flags.BoolVar(&cmdConfig.DisableLeaderElection, "disable-leader-election", false, "Disable leader election for the agent.")
+
+	// the flags which are not set on the command line are loaded from the configuration file
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		configFile, err := cmd.Flags().GetString(config.ConfigFlagName)
+		if err != nil || len(configFile) == 0 {
+			return err
+		}
+		return config.ApplyAgentConfigurationFile(configFile, cmd.Flags())
+	}
 	return cmd
 }
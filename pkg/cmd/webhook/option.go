@@ -1,23 +1,135 @@
 package webhook
 
-import "github.com/spf13/pflag"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	cliflag "k8s.io/component-base/cli/flag"
+
+	"open-cluster-management.io/registration/pkg/webhook/authorizer"
+)
 
 // Config contains the server (the webhook) cert and key.
 type Options struct {
-	Port    int
-	CertDir string
+	BindAddress                      string
+	Port                             int
+	CertDir                          string
+	TLSCertName                      string
+	TLSKeyName                       string
+	TLSMinVersion                    string
+	TLSMaxVersion                    string
+	TLSCipherSuites                  []string
+	ClientCAName                     string
+	MetricsBindAddress               string
+	HealthProbeBindAddress           string
+	ManagedClusterDeletionProtection bool
+	StatusUpdateMinInterval          time.Duration
+	StatusUpdateBurst                int
+	MaxClientConfigs                 int
+	MaxCABundleBytes                 int
+	ProtectedLabelPrefixes           []string
+	ProtectedLabelWriters            []string
+	Authorizer                       string
+	AuthorizerPolicyFile             string
+	AuthorizerWebhookURL             string
+	AuthorizerWebhookCAFile          string
 }
 
 // NewOptions constructs a new set of default options for webhook.
 func NewOptions() *Options {
 	return &Options{
-		Port: 9443,
+		Port:                   9443,
+		TLSCertName:            "tls.crt",
+		TLSKeyName:             "tls.key",
+		TLSMinVersion:          "VersionTLS13",
+		MetricsBindAddress:     ":8080",
+		HealthProbeBindAddress: ":8000",
+		Authorizer:             authorizer.SubjectAccessReviewMode,
+		StatusUpdateBurst:      3,
+		MaxClientConfigs:       16,
+		MaxCABundleBytes:       256 * 1024,
 	}
 }
 
 func (c *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&c.BindAddress, "bind-address", c.BindAddress,
+		"The IP address the webhook server serves at. All the addresses of the host are served at if not set, including the IPv6 ones of a dual-stack or IPv6-only host, "+
+			"set it to '::' or '0.0.0.0' explicitly to serve at all the IPv6 or IPv4 addresses.")
 	fs.IntVar(&c.Port, "port", c.Port,
 		"Port is the port that the webhook server serves at.")
 	fs.StringVar(&c.CertDir, "certdir", c.CertDir,
-		"CertDir is the directory that contains the server key and certificate. If not set, webhook server would look up the server key and certificate in {TempDir}/k8s-webhook-server/serving-certs")
+		"CertDir is the directory that contains the server key and certificate. If not set, webhook server would look up the server key and certificate in {TempDir}/k8s-webhook-server/serving-certs. "+
+			"The server key and certificate, and the client CA, are reloaded once they change.")
+	fs.StringVar(&c.TLSCertName, "tls-cert-name", c.TLSCertName,
+		"The name of the server certificate file in the CertDir.")
+	fs.StringVar(&c.TLSKeyName, "tls-private-key-name", c.TLSKeyName,
+		"The name of the server key file in the CertDir.")
+	fs.StringVar(&c.TLSMinVersion, "tls-min-version", c.TLSMinVersion,
+		"The minimum TLS version supported. Possible values: "+strings.Join(cliflag.TLSPossibleVersions(), ", ")+".")
+	fs.StringVar(&c.TLSMaxVersion, "tls-max-version", c.TLSMaxVersion,
+		"The maximum TLS version supported, the highest version supported by the server if not set. Possible values: "+strings.Join(cliflag.TLSPossibleVersions(), ", ")+".")
+	fs.StringSliceVar(&c.TLSCipherSuites, "tls-cipher-suites", c.TLSCipherSuites,
+		"Comma-separated list of cipher suites for the server, the default Go cipher suites are used if not set. It does not apply to TLS 1.3. "+
+			"Possible values: "+strings.Join(cliflag.TLSCipherPossibleValues(), ", ")+".")
+	fs.StringVar(&c.ClientCAName, "client-ca-name", c.ClientCAName,
+		"The name of the CA file in the CertDir to verify the client certificates with. If set, the clients, e.g. the kube-apiserver, must present a certificate signed by it.")
+	fs.StringVar(&c.MetricsBindAddress, "metrics-bind-address", c.MetricsBindAddress,
+		"The address the metrics endpoint binds to, e.g. '[::]:8080' for an IPv6-only host, set it to \"0\" to disable the metrics.")
+	fs.StringVar(&c.HealthProbeBindAddress, "health-probe-bind-address", c.HealthProbeBindAddress,
+		"The address the health probe endpoint binds to, e.g. '[::]:8000' for an IPv6-only host, set it to \"0\" to disable the health probes.")
+	fs.BoolVar(&c.ManagedClusterDeletionProtection, "managed-cluster-deletion-protection", c.ManagedClusterDeletionProtection,
+		"Deny deleting an available ManagedCluster unless it has the annotation 'cluster.open-cluster-management.io/deletion-confirmed: \"true\"'. "+
+			"It is an explicit opt-in: the DELETE operation is not in the rules of the ManagedCluster validating webhook configuration deployed by default and must be added along with this flag, "+
+			"after that the ManagedClusters cannot be deleted while the webhook is unavailable.")
+	fs.DurationVar(&c.StatusUpdateMinInterval, "managed-cluster-status-update-min-interval", c.StatusUpdateMinInterval,
+		"The min interval between the status updates of a ManagedCluster by its agent, the status updates beyond --managed-cluster-status-update-burst within the interval are denied "+
+			"with TooManyRequests so that a misbehaving agent cannot overwhelm the hub. Only the persisted status updates are counted, and the limit applies to each webhook replica separately. "+
+			"It can be overridden per cluster with the annotation 'cluster.open-cluster-management.io/status-update-min-interval'. "+
+			"Set it to zero to disable the limit. The managedclusters/status resource must be added to the rules of the ManagedCluster validating webhook configuration.")
+	fs.IntVar(&c.StatusUpdateBurst, "managed-cluster-status-update-burst", c.StatusUpdateBurst,
+		"The max number of the status updates of a ManagedCluster by its agent within --managed-cluster-status-update-min-interval, so that the controllers of the agent are able to update the status at once.")
+	fs.IntVar(&c.MaxClientConfigs, "managed-cluster-max-client-configs", c.MaxClientConfigs,
+		"The max number of the client configs of a ManagedCluster, a ManagedCluster with more client configs is denied and a warning is returned once 80% of the limit is reached. "+
+			"It only applies to the ManagedClusters whose client configs are changed. Set it to zero to disable the limit.")
+	fs.IntVar(&c.MaxCABundleBytes, "managed-cluster-max-ca-bundle-bytes", c.MaxCABundleBytes,
+		"The max size in bytes of the CA bundle of a client config of a ManagedCluster, a ManagedCluster with a larger CA bundle is denied and a warning is returned once 80% of the limit is reached. "+
+			"It only applies to the ManagedClusters whose client configs are changed. Set it to zero to disable the limit.")
+	fs.StringSliceVar(&c.ProtectedLabelPrefixes, "managed-cluster-protected-label-prefixes", c.ProtectedLabelPrefixes,
+		"Comma-separated list of the prefixes of the labels of a ManagedCluster maintained by the hub, e.g. 'feature.open-cluster-management.io/' of the feature labels of the addons, "+
+			"which only the users in --managed-cluster-protected-label-writers can set, change or remove. No label is protected if it is not set.")
+	fs.StringSliceVar(&c.ProtectedLabelWriters, "managed-cluster-protected-label-writers", c.ProtectedLabelWriters,
+		"Comma-separated list of the users allowed to write the protected labels of a ManagedCluster, e.g. the service account of the registration hub controller, "+
+			"'system:serviceaccount:open-cluster-management-hub:registration-controller-sa'.")
+	fs.StringVar(&c.Authorizer, "authorizer", c.Authorizer,
+		"The authorizer checking the permissions to accept a ManagedCluster, set its clusterset label and bind a ManagedClusterSet, "+
+			"one of SubjectAccessReview, StaticPolicy and Webhook. Use StaticPolicy or Webhook on a hub which denies the webhook to create SubjectAccessReviews.")
+	fs.StringVar(&c.AuthorizerPolicyFile, "authorizer-policy-file", c.AuthorizerPolicyFile,
+		"The file of the rules of the StaticPolicy authorizer.")
+	fs.StringVar(&c.AuthorizerWebhookURL, "authorizer-webhook-url", c.AuthorizerWebhookURL,
+		"The https url the Webhook authorizer posts SubjectAccessReviews to.")
+	fs.StringVar(&c.AuthorizerWebhookCAFile, "authorizer-webhook-ca-file", c.AuthorizerWebhookCAFile,
+		"The CA file to verify the server certificate of the Webhook authorizer. The system CAs are used if it is not set.")
+}
+
+// newAuthorizer returns the authorizer of the webhook, nil means the SubjectAccessReview api of the hub.
+func (c *Options) newAuthorizer() (authorizer.Authorizer, error) {
+	switch c.Authorizer {
+	case authorizer.SubjectAccessReviewMode:
+		return nil, nil
+	case authorizer.StaticPolicyMode:
+		if len(c.AuthorizerPolicyFile) == 0 {
+			return nil, fmt.Errorf("--authorizer-policy-file is required by the %s authorizer", c.Authorizer)
+		}
+		return authorizer.NewStaticPolicyAuthorizer(c.AuthorizerPolicyFile)
+	case authorizer.WebhookMode:
+		if len(c.AuthorizerWebhookURL) == 0 {
+			return nil, fmt.Errorf("--authorizer-webhook-url is required by the %s authorizer", c.Authorizer)
+		}
+		return authorizer.NewWebhookAuthorizer(c.AuthorizerWebhookURL, c.AuthorizerWebhookCAFile)
+	default:
+		return nil, fmt.Errorf("unsupported authorizer %q", c.Authorizer)
+	}
 }
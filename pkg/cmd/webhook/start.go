@@ -1,6 +1,10 @@
 package webhook
 
 import (
+	"context"
+	"fmt"
+	"net"
+
 	"k8s.io/klog/v2"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -15,6 +19,7 @@ import (
 
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -33,12 +38,48 @@ func init() {
 }
 
 func (c *Options) RunWebhookServer() error {
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	return c.RunWebhookServerWithContext(ctrl.SetupSignalHandler(), ctrl.GetConfigOrDie())
+}
+
+// RunWebhookServerWithContext runs the webhook server against the kube-apiserver of the config until the
+// ctx is done.
+func (c *Options) RunWebhookServerWithContext(ctx context.Context, config *rest.Config) error {
+	authorizer, err := c.newAuthorizer()
+	if err != nil {
+		klog.Errorf("unable to create the authorizer: %v", err)
+		return err
+	}
+
+	if len(c.BindAddress) != 0 && net.ParseIP(c.BindAddress) == nil {
+		err := fmt.Errorf("invalid --bind-address %q: not an IP address", c.BindAddress)
+		klog.Error(err)
+		return err
+	}
+
+	if len(c.ProtectedLabelPrefixes) != 0 && len(c.ProtectedLabelWriters) == 0 {
+		err := fmt.Errorf("--managed-cluster-protected-label-writers is required by --managed-cluster-protected-label-prefixes")
+		klog.Error(err)
+		return err
+	}
+
+	tlsOpts, err := c.tlsOptions()
+	if err != nil {
+		klog.Errorf("unable to configure the tls of the webhook server: %v", err)
+		return err
+	}
+
+	mgr, err := ctrl.NewManager(config, ctrl.Options{
 		Scheme:                 scheme,
 		Port:                   c.Port,
-		HealthProbeBindAddress: ":8000",
+		MetricsBindAddress:     c.MetricsBindAddress,
+		HealthProbeBindAddress: c.HealthProbeBindAddress,
 		CertDir:                c.CertDir,
-		WebhookServer:          &webhook.Server{TLSMinVersion: "1.3"},
+		WebhookServer: &webhook.Server{
+			Host:     c.BindAddress,
+			CertName: c.TLSCertName,
+			KeyName:  c.TLSKeyName,
+			TLSOpts:  tlsOpts,
+		},
 	})
 
 	if err != nil {
@@ -57,15 +98,24 @@ func (c *Options) RunWebhookServer() error {
 		return err
 	}
 
-	if err = (&internalv1.ManagedClusterWebhook{}).Init(mgr); err != nil {
+	if err = (&internalv1.ManagedClusterWebhook{
+		DeletionProtection:      c.ManagedClusterDeletionProtection,
+		StatusUpdateMinInterval: c.StatusUpdateMinInterval,
+		StatusUpdateBurst:       c.StatusUpdateBurst,
+		MaxClientConfigs:        c.MaxClientConfigs,
+		MaxCABundleBytes:        c.MaxCABundleBytes,
+		ProtectedLabelPrefixes:  c.ProtectedLabelPrefixes,
+		ProtectedLabelWriters:   c.ProtectedLabelWriters,
+		Authorizer:              authorizer,
+	}).Init(mgr); err != nil {
 		klog.Error(err, "unable to create ManagedCluster webhook")
 		return err
 	}
-	if err = (&internalv1beta1.ManagedClusterSetBindingWebhook{}).Init(mgr); err != nil {
+	if err = (&internalv1beta1.ManagedClusterSetBindingWebhook{Authorizer: authorizer}).Init(mgr); err != nil {
 		klog.Error(err, "unable to create ManagedClusterSetBinding webhook", "v1beta1")
 		return err
 	}
-	if err = (&internalv1beta2.ManagedClusterSetBindingWebhook{}).Init(mgr); err != nil {
+	if err = (&internalv1beta2.ManagedClusterSetBindingWebhook{Authorizer: authorizer}).Init(mgr); err != nil {
 		klog.Error(err, "unable to create ManagedClusterSetBinding webhook", "v1beta1")
 		return err
 	}
@@ -79,7 +129,7 @@ func (c *Options) RunWebhookServer() error {
 	}
 
 	klog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		klog.Error(err, "problem running manager")
 		return err
 	}
@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	cliflag "k8s.io/component-base/cli/flag"
+	"k8s.io/klog/v2"
+)
+
+// tlsOptions returns the options to configure the tls of the webhook server. The serving certificate and key
+// in the CertDir are reloaded by the webhook server once they change, and so is the client CA.
+func (c *Options) tlsOptions() ([]func(*tls.Config), error) {
+	minVersion, err := cliflag.TLSVersion(c.TLSMinVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --tls-min-version: %w", err)
+	}
+	var maxVersion uint16
+	if len(c.TLSMaxVersion) != 0 {
+		if maxVersion, err = cliflag.TLSVersion(c.TLSMaxVersion); err != nil {
+			return nil, fmt.Errorf("invalid --tls-max-version: %w", err)
+		}
+		if maxVersion < minVersion {
+			return nil, fmt.Errorf("--tls-max-version %s is lower than --tls-min-version %s", c.TLSMaxVersion, c.TLSMinVersion)
+		}
+	}
+	cipherSuites, err := cliflag.TLSCipherSuites(c.TLSCipherSuites)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --tls-cipher-suites: %w", err)
+	}
+
+	opts := []func(*tls.Config){
+		func(cfg *tls.Config) {
+			cfg.MinVersion = minVersion
+			cfg.MaxVersion = maxVersion
+			if len(cipherSuites) != 0 {
+				cfg.CipherSuites = cipherSuites
+			}
+		},
+	}
+	if len(c.ClientCAName) != 0 {
+		clientCA := newClientCAReloader(filepath.Join(c.CertDir, c.ClientCAName))
+		if _, err := clientCA.pool(); err != nil {
+			return nil, err
+		}
+		opts = append(opts, clientCA.configure)
+	}
+	return opts, nil
+}
+
+// clientCAReloader loads the client CA to verify the client certificates with, the file is reloaded once
+// its modification time changes, so that the client CA can be rotated without restarting the webhook server.
+type clientCAReloader struct {
+	file string
+
+	lock    sync.Mutex
+	modTime time.Time
+	certs   *x509.CertPool
+}
+
+func newClientCAReloader(file string) *clientCAReloader {
+	return &clientCAReloader{file: file}
+}
+
+// configure makes the server verify the client certificates with the latest client CA in each handshake.
+func (r *clientCAReloader) configure(cfg *tls.Config) {
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		certs, err := r.pool()
+		if err != nil {
+			return nil, err
+		}
+		clientCfg := cfg.Clone()
+		clientCfg.GetConfigForClient = nil
+		clientCfg.ClientCAs = certs
+		return clientCfg, nil
+	}
+}
+
+// pool returns the certificates of the client CA. The last loaded certificates are returned if the file
+// is unable to be reloaded, e.g. it is being rewritten.
+func (r *clientCAReloader) pool() (*x509.CertPool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	info, err := os.Stat(r.file)
+	if err != nil {
+		if r.certs == nil {
+			return nil, fmt.Errorf("unable to read the client CA %q: %w", r.file, err)
+		}
+		return r.certs, nil
+	}
+	if r.certs != nil && info.ModTime().Equal(r.modTime) {
+		return r.certs, nil
+	}
+
+	certs, err := loadCertPool(r.file)
+	if err != nil {
+		if r.certs == nil {
+			return nil, err
+		}
+		// the file is not reloaded again until it changes
+		klog.Warningf("Unable to reload the client CA %q, the last loaded one is used: %v", r.file, err)
+		r.modTime = info.ModTime()
+		return r.certs, nil
+	}
+	if r.certs != nil {
+		klog.Infof("The client CA %q is reloaded", r.file)
+	}
+	r.modTime, r.certs = info.ModTime(), certs
+	return certs, nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the client CA %q: %w", file, err)
+	}
+	certs := x509.NewCertPool()
+	if !certs.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate is found in the client CA %q", file)
+	}
+	return certs, nil
+}
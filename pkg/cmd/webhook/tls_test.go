@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestTLSOptions(t *testing.T) {
+	certDir := t.TempDir()
+	if err := os.WriteFile(path.Join(certDir, "ca.crt"), testinghelpers.NewTestCert("ca", time.Hour).Cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name                 string
+		options              func(o *Options)
+		expectedErr          string
+		expectedMinVersion   uint16
+		expectedMaxVersion   uint16
+		expectedCipherSuites []uint16
+		expectedClientAuth   tls.ClientAuthType
+	}{
+		{
+			name:               "default",
+			options:            func(o *Options) {},
+			expectedMinVersion: tls.VersionTLS13,
+		},
+		{
+			name: "tls versions and cipher suites",
+			options: func(o *Options) {
+				o.TLSMinVersion = "VersionTLS12"
+				o.TLSMaxVersion = "VersionTLS12"
+				o.TLSCipherSuites = []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}
+			},
+			expectedMinVersion:   tls.VersionTLS12,
+			expectedMaxVersion:   tls.VersionTLS12,
+			expectedCipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		},
+		{
+			name:        "invalid min version",
+			options:     func(o *Options) { o.TLSMinVersion = "1.3" },
+			expectedErr: "invalid --tls-min-version: unknown tls version \"1.3\"",
+		},
+		{
+			name:        "max version lower than min version",
+			options:     func(o *Options) { o.TLSMaxVersion = "VersionTLS12" },
+			expectedErr: "--tls-max-version VersionTLS12 is lower than --tls-min-version VersionTLS13",
+		},
+		{
+			name:        "invalid cipher suite",
+			options:     func(o *Options) { o.TLSCipherSuites = []string{"invalid"} },
+			expectedErr: "invalid --tls-cipher-suites: Cipher suite invalid not supported or doesn't exist",
+		},
+		{
+			name: "client ca",
+			options: func(o *Options) {
+				o.CertDir = certDir
+				o.ClientCAName = "ca.crt"
+			},
+			expectedMinVersion: tls.VersionTLS13,
+			expectedClientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name: "client ca does not exist",
+			options: func(o *Options) {
+				o.CertDir = certDir
+				o.ClientCAName = "missing.crt"
+			},
+			expectedErr: "unable to read the client CA \"" + path.Join(certDir, "missing.crt") +
+				"\": stat " + path.Join(certDir, "missing.crt") + ": no such file or directory",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			o := NewOptions()
+			c.options(o)
+			opts, err := o.tlsOptions()
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if err != nil {
+				return
+			}
+
+			cfg := &tls.Config{}
+			for _, opt := range opts {
+				opt(cfg)
+			}
+			if cfg.MinVersion != c.expectedMinVersion || cfg.MaxVersion != c.expectedMaxVersion {
+				t.Errorf("expected tls versions %d-%d, but got %d-%d", c.expectedMinVersion, c.expectedMaxVersion, cfg.MinVersion, cfg.MaxVersion)
+			}
+			if len(cfg.CipherSuites) != len(c.expectedCipherSuites) {
+				t.Errorf("expected cipher suites %v, but got %v", c.expectedCipherSuites, cfg.CipherSuites)
+			}
+			if cfg.ClientAuth != c.expectedClientAuth {
+				t.Errorf("expected client auth %v, but got %v", c.expectedClientAuth, cfg.ClientAuth)
+			}
+			if c.expectedClientAuth == tls.NoClientCert {
+				return
+			}
+			clientCfg, err := cfg.GetConfigForClient(&tls.ClientHelloInfo{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if clientCfg.ClientCAs == nil {
+				t.Errorf("expected the client ca is loaded")
+			}
+		})
+	}
+}
+
+func TestClientCAReloader(t *testing.T) {
+	file := path.Join(t.TempDir(), "ca.crt")
+	writeCA := func(data []byte, modTime time.Time) {
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	reloader := newClientCAReloader(file)
+	writeCA(testinghelpers.NewTestCert("ca1", time.Hour).Cert, now)
+	first, err := reloader.pool()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the client ca is not reloaded until it changes
+	if current, _ := reloader.pool(); current != first {
+		t.Errorf("expected the client ca is not reloaded")
+	}
+
+	// the last loaded client ca is used if the changed one is invalid
+	writeCA([]byte("invalid"), now.Add(time.Second))
+	if current, err := reloader.pool(); err != nil || current != first {
+		t.Errorf("expected the last loaded client ca is used, but got error %v", err)
+	}
+
+	// the client ca is reloaded once it changes
+	writeCA(testinghelpers.NewTestCert("ca2", time.Hour).Cert, now.Add(2*time.Second))
+	if current, err := reloader.pool(); err != nil || current == first {
+		t.Errorf("expected the client ca is reloaded, but got error %v", err)
+	}
+}
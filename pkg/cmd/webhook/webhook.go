@@ -2,6 +2,7 @@ package webhook
 
 import (
 	"github.com/spf13/cobra"
+	"open-cluster-management.io/registration/pkg/config"
 	"open-cluster-management.io/registration/pkg/features"
 )
 
@@ -20,5 +21,15 @@ func NewWebhook() *cobra.Command {
 	ops.AddFlags(flags)
 
 	features.DefaultHubMutableFeatureGate.AddFlag(flags)
+	flags.String(config.ConfigFlagName, "", "The path of the WebhookConfiguration file. The flags set on the command line take precedence over the file.")
+
+	// the flags which are not set on the command line are loaded from the configuration file
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		configFile, err := cmd.Flags().GetString(config.ConfigFlagName)
+		if err != nil || len(configFile) == 0 {
+			return err
+		}
+		return config.ApplyWebhookConfigurationFile(configFile, cmd.Flags())
+	}
 	return cmd
 }
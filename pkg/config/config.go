@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigFlagName is the name of the flag of the configuration file. The flag is registered by the
+// controller command of library-go, which reads the same file as the GenericOperatorConfig.
+const ConfigFlagName = "config"
+
+// ApplyControllerConfigurationFile loads the ControllerConfiguration from the file and sets the
+// flags which are not set on the command line accordingly.
+func ApplyControllerConfigurationFile(file string, fs *pflag.FlagSet) error {
+	config := &ControllerConfiguration{}
+	if err := load(file, ControllerConfigurationKind, config); err != nil {
+		return err
+	}
+	return applyToFlags(fs, config.flagValues())
+}
+
+// ApplyAgentConfigurationFile loads the AgentConfiguration from the file and sets the flags which
+// are not set on the command line accordingly.
+func ApplyAgentConfigurationFile(file string, fs *pflag.FlagSet) error {
+	config := &AgentConfiguration{}
+	if err := load(file, AgentConfigurationKind, config); err != nil {
+		return err
+	}
+	return applyToFlags(fs, config.flagValues())
+}
+
+// ApplyWebhookConfigurationFile loads the WebhookConfiguration from the file and sets the flags which
+// are not set on the command line accordingly.
+func ApplyWebhookConfigurationFile(file string, fs *pflag.FlagSet) error {
+	config := &WebhookConfiguration{}
+	if err := load(file, WebhookConfigurationKind, config); err != nil {
+		return err
+	}
+	return applyToFlags(fs, config.flagValues())
+}
+
+func load(file, kind string, config interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("unable to read the configuration file %q: %w", file, err)
+	}
+
+	typeMeta := &metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, typeMeta); err != nil {
+		return fmt.Errorf("unable to decode the configuration file %q: %w", file, err)
+	}
+	if expected := GroupName + "/" + Version; typeMeta.APIVersion != expected || typeMeta.Kind != kind {
+		return fmt.Errorf("unsupported configuration %s/%s in file %q, expected %s/%s",
+			typeMeta.APIVersion, typeMeta.Kind, file, expected, kind)
+	}
+
+	// the file is shared with the GenericOperatorConfig of library-go, so unknown fields are allowed
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("unable to decode the configuration file %q: %w", file, err)
+	}
+	return nil
+}
+
+// applyToFlags sets the flags with the values, the flags which are set on the command line are
+// left untouched.
+func applyToFlags(fs *pflag.FlagSet, values map[string][]string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		flag := fs.Lookup(name)
+		if flag == nil {
+			return fmt.Errorf("flag %q of the configuration is not supported", name)
+		}
+		if flag.Changed {
+			continue
+		}
+
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			if err := sliceValue.Replace(values[name]); err != nil {
+				return fmt.Errorf("invalid value %v of %q in the configuration: %w", values[name], name, err)
+			}
+			continue
+		}
+		if err := flag.Value.Set(strings.Join(values[name], ",")); err != nil {
+			return fmt.Errorf("invalid value %v of %q in the configuration: %w", values[name], name, err)
+		}
+	}
+	return nil
+}
+
+func (c *ControllerConfiguration) flagValues() map[string][]string {
+	values := map[string][]string{}
+	setFeatureGates(values, c.FeatureGates)
+	if c.ClientConnection != nil {
+		setFloat32(values, "kube-api-qps", c.ClientConnection.QPS)
+		setInt32(values, "kube-api-burst", c.ClientConnection.Burst)
+	}
+	if c.LeaderElection != nil {
+		setBool(values, "disable-leader-election", c.LeaderElection.Disable)
+		setDuration(values, "leader-election-lease-duration", c.LeaderElection.LeaseDuration)
+		setDuration(values, "leader-election-renew-deadline", c.LeaderElection.RenewDeadline)
+		setDuration(values, "leader-election-retry-period", c.LeaderElection.RetryPeriod)
+	}
+	setDuration(values, "informer-resync-period", c.InformerResyncPeriod)
+	setDuration(values, "lease-controller-resync-period", c.LeaseControllerResyncPeriod)
+	setInt32(values, "lease-duration-times", c.LeaseDurationTimes)
+	setStrings(values, "cluster-auto-approval-users", c.ClusterAutoApprovalUsers)
+	setStrings(values, "auto-approve-bootstrap-users", c.AutoApproveBootstrapUsers)
+	setInt32(values, "csr-approving-workers", c.CSRApprovingWorkers)
+	setInt32(values, "managed-cluster-workers", c.ManagedClusterWorkers)
+	setInt32(values, "rbac-finalizer-workers", c.RBACFinalizerWorkers)
+	setDuration(values, "csr-deny-threshold", c.CSRDenyThreshold)
+	setBool(values, "enable-aws-iam-identity-mapping", c.EnableAWSIAMIdentityMapping)
+	setStrings(values, "disabled-controllers", c.DisabledControllers)
+	setInt32(values, "max-agent-version-skew", c.MaxAgentVersionSkew)
+	setStrings(values, "cluster-claim-labels", c.ClusterClaimLabels)
+	setStrings(values, "default-clusterset-binding-namespaces", c.DefaultClusterSetBindingNamespaces)
+	setBool(values, "enable-validating-admission-policies", c.EnableValidatingAdmissionPolicies)
+	setBool(values, "enable-addon-statuses-annotation", c.EnableAddOnStatusesAnnotation)
+	setString(values, "health-probe-bind-address", c.HealthProbeBindAddress)
+	setString(values, "controller-panic-policy", c.ControllerPanicPolicy)
+	setString(values, "orphaned-cluster-namespace-gc", c.OrphanedClusterNamespaceGC)
+	setDuration(values, "orphaned-cluster-namespace-gc-delay", c.OrphanedClusterNamespaceGCDelay)
+	setStrings(values, "cleanup-finalizer-prefixes", c.CleanupFinalizerPrefixes)
+	setString(values, "managed-cluster-manifest-values-file", c.ManagedClusterManifestValuesFile)
+	return values
+}
+
+func (c *AgentConfiguration) flagValues() map[string][]string {
+	values := map[string][]string{}
+	setFeatureGates(values, c.FeatureGates)
+	if c.HubClientConnection != nil {
+		setFloat32(values, "hub-kube-api-qps", c.HubClientConnection.QPS)
+		setInt32(values, "hub-kube-api-burst", c.HubClientConnection.Burst)
+	}
+	if c.LeaderElection != nil {
+		setBool(values, "disable-leader-election", c.LeaderElection.Disable)
+		setDuration(values, "leader-election-lease-duration", c.LeaderElection.LeaseDuration)
+		setDuration(values, "leader-election-renew-deadline", c.LeaderElection.RenewDeadline)
+		setDuration(values, "leader-election-retry-period", c.LeaderElection.RetryPeriod)
+	}
+	setString(values, "cluster-name", c.ClusterName)
+	setString(values, "bootstrap-kubeconfig", c.BootstrapKubeconfig)
+	setString(values, "bootstrap-kubeconfig-secret", c.BootstrapKubeconfigSecret)
+	setStrings(values, "bootstrap-kubeconfigs", c.BootstrapKubeconfigs)
+	setString(values, "spoke-kubeconfig", c.SpokeKubeconfig)
+	setStrings(values, "spoke-external-server-urls", c.SpokeExternalServerURLs)
+	setDuration(values, "spoke-external-server-url-probe-period", c.SpokeExternalServerURLProbePeriod)
+	setDuration(values, "cluster-healthcheck-period", c.ClusterHealthCheckPeriod)
+	setInt32(values, "max-custom-cluster-claims", c.MaxCustomClusterClaims)
+	setInt32(values, "client-cert-expiration-seconds", c.ClientCertExpirationSeconds)
+	setString(values, "registration-driver", c.RegistrationDriver)
+	setString(values, "hub-proxy-url", c.HubProxyURL)
+	setString(values, "addon-hub-ca-file", c.AddOnHubCAFile)
+	return values
+}
+
+func (c *WebhookConfiguration) flagValues() map[string][]string {
+	values := map[string][]string{}
+	setFeatureGates(values, c.FeatureGates)
+	setString(values, "bind-address", c.BindAddress)
+	setInt32(values, "port", c.Port)
+	setString(values, "certdir", c.CertDir)
+	setString(values, "tls-cert-name", c.TLSCertName)
+	setString(values, "tls-private-key-name", c.TLSPrivateKeyName)
+	setString(values, "tls-min-version", c.TLSMinVersion)
+	setString(values, "tls-max-version", c.TLSMaxVersion)
+	setStrings(values, "tls-cipher-suites", c.TLSCipherSuites)
+	setString(values, "client-ca-name", c.ClientCAName)
+	setString(values, "metrics-bind-address", c.MetricsBindAddress)
+	setString(values, "health-probe-bind-address", c.HealthProbeBindAddress)
+	if c.ManagedCluster != nil {
+		setBool(values, "managed-cluster-deletion-protection", c.ManagedCluster.DeletionProtection)
+		setDuration(values, "managed-cluster-status-update-min-interval", c.ManagedCluster.StatusUpdateMinInterval)
+		setInt32(values, "managed-cluster-status-update-burst", c.ManagedCluster.StatusUpdateBurst)
+		setInt32(values, "managed-cluster-max-client-configs", c.ManagedCluster.MaxClientConfigs)
+		setInt32(values, "managed-cluster-max-ca-bundle-bytes", c.ManagedCluster.MaxCABundleBytes)
+		setStrings(values, "managed-cluster-protected-label-prefixes", c.ManagedCluster.ProtectedLabelPrefixes)
+		setStrings(values, "managed-cluster-protected-label-writers", c.ManagedCluster.ProtectedLabelWriters)
+	}
+	if c.Authorizer != nil {
+		setString(values, "authorizer", c.Authorizer.Mode)
+		setString(values, "authorizer-policy-file", c.Authorizer.PolicyFile)
+		setString(values, "authorizer-webhook-url", c.Authorizer.WebhookURL)
+		setString(values, "authorizer-webhook-ca-file", c.Authorizer.WebhookCAFile)
+	}
+	return values
+}
+
+func setFeatureGates(values map[string][]string, featureGates map[string]bool) {
+	if len(featureGates) == 0 {
+		return
+	}
+	pairs := make([]string, 0, len(featureGates))
+	for name, enabled := range featureGates {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	sort.Strings(pairs)
+	values["feature-gates"] = pairs
+}
+
+func setString(values map[string][]string, name, value string) {
+	if len(value) != 0 {
+		values[name] = []string{value}
+	}
+}
+
+func setStrings(values map[string][]string, name string, value []string) {
+	if len(value) != 0 {
+		values[name] = value
+	}
+}
+
+func setBool(values map[string][]string, name string, value *bool) {
+	if value != nil {
+		values[name] = []string{strconv.FormatBool(*value)}
+	}
+}
+
+func setInt32(values map[string][]string, name string, value *int32) {
+	if value != nil {
+		values[name] = []string{strconv.FormatInt(int64(*value), 10)}
+	}
+}
+
+func setFloat32(values map[string][]string, name string, value *float32) {
+	if value != nil {
+		values[name] = []string{strconv.FormatFloat(float64(*value), 'f', -1, 32)}
+	}
+}
+
+func setDuration(values map[string][]string, name string, value *metav1.Duration) {
+	if value != nil {
+		values[name] = []string{value.Duration.String()}
+	}
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/featuregate"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+type testOptions struct {
+	featureGate   featuregate.MutableFeatureGate
+	workers       int
+	threshold     time.Duration
+	users         []string
+	urls          []string
+	qps           float32
+	leaseDuration time.Duration
+}
+
+func newTestFlagSet(t *testing.T, o *testOptions) *pflag.FlagSet {
+	o.featureGate = featuregate.NewFeatureGate()
+	if err := o.featureGate.Add(map[featuregate.Feature]featuregate.FeatureSpec{
+		"FeatureA": {Default: false, PreRelease: featuregate.Alpha},
+		"FeatureB": {Default: true, PreRelease: featuregate.Beta},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	o.featureGate.AddFlag(fs)
+	fs.IntVar(&o.workers, "csr-approving-workers", 5, "")
+	fs.DurationVar(&o.threshold, "csr-deny-threshold", 10*time.Minute, "")
+	fs.StringSliceVar(&o.users, "cluster-auto-approval-users", []string{"default"}, "")
+	fs.StringArrayVar(&o.urls, "spoke-external-server-urls", nil, "")
+	fs.Float32Var(&o.qps, "kube-api-qps", 100, "")
+	fs.DurationVar(&o.leaseDuration, "leader-election-lease-duration", 137*time.Second, "")
+	return fs
+}
+
+func TestApplyControllerConfigurationFile(t *testing.T) {
+	cases := []struct {
+		name            string
+		config          string
+		args            []string
+		expectedErr     string
+		expectedOptions testOptions
+		expectedFeature bool
+	}{
+		{
+			name: "unsupported kind",
+			config: `apiVersion: config.registration.open-cluster-management.io/v1alpha1
+kind: AgentConfiguration`,
+			expectedErr: "unsupported configuration config.registration.open-cluster-management.io/v1alpha1/AgentConfiguration " +
+				"in file \"{file}\", expected config.registration.open-cluster-management.io/v1alpha1/ControllerConfiguration",
+		},
+		{
+			name: "unsupported flag",
+			config: `apiVersion: config.registration.open-cluster-management.io/v1alpha1
+kind: ControllerConfiguration
+enableAWSIAMIdentityMapping: true`,
+			expectedErr: "flag \"enable-aws-iam-identity-mapping\" of the configuration is not supported",
+		},
+		{
+			name: "load configuration",
+			config: `apiVersion: config.registration.open-cluster-management.io/v1alpha1
+kind: ControllerConfiguration
+servingInfo:
+  bindAddress: ":8443"
+featureGates:
+  FeatureA: true
+clientConnection:
+  qps: 50
+leaderElection:
+  leaseDuration: 60s
+clusterAutoApprovalUsers:
+- user1
+- user2
+csrApprovingWorkers: 10
+csrDenyThreshold: 5m`,
+			expectedOptions: testOptions{
+				workers:       10,
+				threshold:     5 * time.Minute,
+				users:         []string{"user1", "user2"},
+				qps:           50,
+				leaseDuration: 60 * time.Second,
+			},
+			expectedFeature: true,
+		},
+		{
+			name: "flags take precedence",
+			config: `apiVersion: config.registration.open-cluster-management.io/v1alpha1
+kind: ControllerConfiguration
+featureGates:
+  FeatureA: true
+clusterAutoApprovalUsers:
+- user1
+csrApprovingWorkers: 10`,
+			args: []string{"--feature-gates=FeatureA=false", "--csr-approving-workers=20"},
+			expectedOptions: testOptions{
+				workers:       20,
+				threshold:     10 * time.Minute,
+				users:         []string{"user1"},
+				qps:           100,
+				leaseDuration: 137 * time.Second,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			file := path.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(file, []byte(c.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			o := &testOptions{}
+			fs := newTestFlagSet(t, o)
+			if err := fs.Parse(c.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ApplyControllerConfigurationFile(file, fs)
+			testinghelpers.AssertError(t, err, strings.ReplaceAll(c.expectedErr, "{file}", file))
+			if err != nil {
+				return
+			}
+
+			if enabled := o.featureGate.Enabled("FeatureA"); enabled != c.expectedFeature {
+				t.Errorf("expected FeatureA enabled %v, but got %v", c.expectedFeature, enabled)
+			}
+			o.featureGate = nil
+			c.expectedOptions.featureGate = nil
+			if !reflect.DeepEqual(*o, c.expectedOptions) {
+				t.Errorf("expected options %v, but got %v", c.expectedOptions, *o)
+			}
+		})
+	}
+}
+
+func TestApplyAgentConfigurationFile(t *testing.T) {
+	file := path.Join(t.TempDir(), "config.yaml")
+	config := `apiVersion: config.registration.open-cluster-management.io/v1alpha1
+kind: AgentConfiguration
+spokeExternalServerURLs:
+- https://spoke1:6443
+- https://spoke2:6443`
+	if err := os.WriteFile(file, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &testOptions{}
+	fs := newTestFlagSet(t, o)
+	if err := ApplyAgentConfigurationFile(file, fs); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"https://spoke1:6443", "https://spoke2:6443"}; !reflect.DeepEqual(o.urls, expected) {
+		t.Errorf("expected urls %v, but got %v", expected, o.urls)
+	}
+}
+
+func TestApplyWebhookConfigurationFile(t *testing.T) {
+	file := path.Join(t.TempDir(), "config.yaml")
+	config := `apiVersion: config.registration.open-cluster-management.io/v1alpha1
+kind: WebhookConfiguration
+port: 9444
+managedCluster:
+  statusUpdateMinInterval: 30s
+  protectedLabelPrefixes:
+  - feature.open-cluster-management.io/
+authorizer:
+  mode: StaticPolicy`
+	if err := os.WriteFile(file, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var port int
+	var interval time.Duration
+	var prefixes []string
+	var mode string
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.IntVar(&port, "port", 9443, "")
+	fs.DurationVar(&interval, "managed-cluster-status-update-min-interval", 0, "")
+	fs.StringSliceVar(&prefixes, "managed-cluster-protected-label-prefixes", nil, "")
+	fs.StringVar(&mode, "authorizer", "SubjectAccessReview", "")
+	if err := fs.Parse([]string{"--authorizer=Webhook"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyWebhookConfigurationFile(file, fs); err != nil {
+		t.Fatal(err)
+	}
+	if port != 9444 {
+		t.Errorf("expected port 9444, but got %d", port)
+	}
+	if interval != 30*time.Second {
+		t.Errorf("expected status update min interval 30s, but got %v", interval)
+	}
+	if expected := []string{"feature.open-cluster-management.io/"}; !reflect.DeepEqual(prefixes, expected) {
+		t.Errorf("expected protected label prefixes %v, but got %v", expected, prefixes)
+	}
+	if mode != "Webhook" {
+		t.Errorf("expected the authorizer set on the command line, but got %q", mode)
+	}
+}
+
+func TestControllerConfigurationFlagValues(t *testing.T) {
+	enabled := true
+	config := &ControllerConfiguration{
+		EnableAddOnStatusesAnnotation:   &enabled,
+		HealthProbeBindAddress:          "[::]:8000",
+		ControllerPanicPolicy:           "Recover",
+		OrphanedClusterNamespaceGC:      "DryRun",
+		OrphanedClusterNamespaceGCDelay: &metav1.Duration{Duration: time.Hour},
+		CleanupFinalizerPrefixes:        []string{"cleanup.open-cluster-management.io/", "backup.example.com/"},
+	}
+	expected := map[string][]string{
+		"enable-addon-statuses-annotation":    {"true"},
+		"health-probe-bind-address":           {"[::]:8000"},
+		"controller-panic-policy":             {"Recover"},
+		"orphaned-cluster-namespace-gc":       {"DryRun"},
+		"orphaned-cluster-namespace-gc-delay": {"1h0m0s"},
+		"cleanup-finalizer-prefixes":          {"cleanup.open-cluster-management.io/", "backup.example.com/"},
+	}
+	if values := config.flagValues(); !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected flag values %v, but got %v", expected, values)
+	}
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// GroupName is the group of the configurations of the registration components
+	GroupName = "config.registration.open-cluster-management.io"
+	// Version is the version of the configurations of the registration components
+	Version = "v1alpha1"
+
+	// ControllerConfigurationKind is the kind of the configuration of the registration hub controller
+	ControllerConfigurationKind = "ControllerConfiguration"
+	// AgentConfigurationKind is the kind of the configuration of the registration agent
+	AgentConfigurationKind = "AgentConfiguration"
+	// WebhookConfigurationKind is the kind of the configuration of the registration webhook server
+	WebhookConfigurationKind = "WebhookConfiguration"
+)
+
+// ControllerConfiguration is the configuration of the registration hub controller. Each field maps to
+// a command-line flag of the controller, a flag set on the command line takes precedence over the field.
+//
+// The file is also read as the GenericOperatorConfig of the controller, so it may contain the
+// servingInfo, authentication and authorization of the controller as well.
+type ControllerConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// FeatureGates maps the feature names to their enablement, see --feature-gates.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// ClientConnection configures the client to the kube-apiserver, see --kube-api-qps and --kube-api-burst.
+	ClientConnection *ClientConnection `json:"clientConnection,omitempty"`
+	// LeaderElection configures the leader election, see --leader-election-*.
+	LeaderElection *LeaderElection `json:"leaderElection,omitempty"`
+	// InformerResyncPeriod see --informer-resync-period.
+	InformerResyncPeriod *metav1.Duration `json:"informerResyncPeriod,omitempty"`
+	// LeaseControllerResyncPeriod see --lease-controller-resync-period.
+	LeaseControllerResyncPeriod *metav1.Duration `json:"leaseControllerResyncPeriod,omitempty"`
+	// LeaseDurationTimes see --lease-duration-times.
+	LeaseDurationTimes *int32 `json:"leaseDurationTimes,omitempty"`
+	// ClusterAutoApprovalUsers see --cluster-auto-approval-users.
+	ClusterAutoApprovalUsers []string `json:"clusterAutoApprovalUsers,omitempty"`
+	// AutoApproveBootstrapUsers see --auto-approve-bootstrap-users.
+	AutoApproveBootstrapUsers []string `json:"autoApproveBootstrapUsers,omitempty"`
+	// CSRApprovingWorkers see --csr-approving-workers.
+	CSRApprovingWorkers *int32 `json:"csrApprovingWorkers,omitempty"`
+	// ManagedClusterWorkers see --managed-cluster-workers.
+	ManagedClusterWorkers *int32 `json:"managedClusterWorkers,omitempty"`
+	// RBACFinalizerWorkers see --rbac-finalizer-workers.
+	RBACFinalizerWorkers *int32 `json:"rbacFinalizerWorkers,omitempty"`
+	// CSRDenyThreshold see --csr-deny-threshold.
+	CSRDenyThreshold *metav1.Duration `json:"csrDenyThreshold,omitempty"`
+	// EnableAWSIAMIdentityMapping see --enable-aws-iam-identity-mapping.
+	EnableAWSIAMIdentityMapping *bool `json:"enableAWSIAMIdentityMapping,omitempty"`
+	// DisabledControllers see --disabled-controllers.
+	DisabledControllers []string `json:"disabledControllers,omitempty"`
+	// MaxAgentVersionSkew see --max-agent-version-skew.
+	MaxAgentVersionSkew *int32 `json:"maxAgentVersionSkew,omitempty"`
+	// ClusterClaimLabels see --cluster-claim-labels.
+	ClusterClaimLabels []string `json:"clusterClaimLabels,omitempty"`
+	// DefaultClusterSetBindingNamespaces see --default-clusterset-binding-namespaces.
+	DefaultClusterSetBindingNamespaces []string `json:"defaultClusterSetBindingNamespaces,omitempty"`
+	// EnableValidatingAdmissionPolicies see --enable-validating-admission-policies.
+	EnableValidatingAdmissionPolicies *bool `json:"enableValidatingAdmissionPolicies,omitempty"`
+	// EnableAddOnStatusesAnnotation see --enable-addon-statuses-annotation.
+	EnableAddOnStatusesAnnotation *bool `json:"enableAddOnStatusesAnnotation,omitempty"`
+	// HealthProbeBindAddress see --health-probe-bind-address.
+	HealthProbeBindAddress string `json:"healthProbeBindAddress,omitempty"`
+	// ControllerPanicPolicy see --controller-panic-policy.
+	ControllerPanicPolicy string `json:"controllerPanicPolicy,omitempty"`
+	// OrphanedClusterNamespaceGC see --orphaned-cluster-namespace-gc.
+	OrphanedClusterNamespaceGC string `json:"orphanedClusterNamespaceGC,omitempty"`
+	// OrphanedClusterNamespaceGCDelay see --orphaned-cluster-namespace-gc-delay.
+	OrphanedClusterNamespaceGCDelay *metav1.Duration `json:"orphanedClusterNamespaceGCDelay,omitempty"`
+	// CleanupFinalizerPrefixes see --cleanup-finalizer-prefixes.
+	CleanupFinalizerPrefixes []string `json:"cleanupFinalizerPrefixes,omitempty"`
+	// ManagedClusterManifestValuesFile see --managed-cluster-manifest-values-file.
+	ManagedClusterManifestValuesFile string `json:"managedClusterManifestValuesFile,omitempty"`
+}
+
+// AgentConfiguration is the configuration of the registration agent. Each field maps to a command-line
+// flag of the agent, a flag set on the command line takes precedence over the field.
+type AgentConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// FeatureGates maps the feature names to their enablement, see --feature-gates.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// HubClientConnection configures the client to the hub kube-apiserver, see --hub-kube-api-qps
+	// and --hub-kube-api-burst.
+	HubClientConnection *ClientConnection `json:"hubClientConnection,omitempty"`
+	// LeaderElection configures the leader election, only disable is supported by the agent.
+	LeaderElection *LeaderElection `json:"leaderElection,omitempty"`
+	// ClusterName see --cluster-name.
+	ClusterName string `json:"clusterName,omitempty"`
+	// BootstrapKubeconfig see --bootstrap-kubeconfig.
+	BootstrapKubeconfig string `json:"bootstrapKubeconfig,omitempty"`
+	// BootstrapKubeconfigSecret see --bootstrap-kubeconfig-secret.
+	BootstrapKubeconfigSecret string `json:"bootstrapKubeconfigSecret,omitempty"`
+	// BootstrapKubeconfigs see --bootstrap-kubeconfigs.
+	BootstrapKubeconfigs []string `json:"bootstrapKubeconfigs,omitempty"`
+	// SpokeKubeconfig see --spoke-kubeconfig.
+	SpokeKubeconfig string `json:"spokeKubeconfig,omitempty"`
+	// SpokeExternalServerURLs see --spoke-external-server-urls.
+	SpokeExternalServerURLs []string `json:"spokeExternalServerURLs,omitempty"`
+	// SpokeExternalServerURLProbePeriod see --spoke-external-server-url-probe-period.
+	SpokeExternalServerURLProbePeriod *metav1.Duration `json:"spokeExternalServerURLProbePeriod,omitempty"`
+	// ClusterHealthCheckPeriod see --cluster-healthcheck-period.
+	ClusterHealthCheckPeriod *metav1.Duration `json:"clusterHealthCheckPeriod,omitempty"`
+	// MaxCustomClusterClaims see --max-custom-cluster-claims.
+	MaxCustomClusterClaims *int32 `json:"maxCustomClusterClaims,omitempty"`
+	// ClientCertExpirationSeconds see --client-cert-expiration-seconds.
+	ClientCertExpirationSeconds *int32 `json:"clientCertExpirationSeconds,omitempty"`
+	// RegistrationDriver see --registration-driver.
+	RegistrationDriver string `json:"registrationDriver,omitempty"`
+	// HubProxyURL see --hub-proxy-url.
+	HubProxyURL string `json:"hubProxyURL,omitempty"`
+	// AddOnHubCAFile see --addon-hub-ca-file.
+	AddOnHubCAFile string `json:"addOnHubCAFile,omitempty"`
+}
+
+// WebhookConfiguration is the configuration of the registration webhook server. Each field maps to a
+// command-line flag of the webhook server, a flag set on the command line takes precedence over the field.
+type WebhookConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// FeatureGates maps the feature names to their enablement, see --feature-gates.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// BindAddress see --bind-address.
+	BindAddress string `json:"bindAddress,omitempty"`
+	// Port see --port.
+	Port *int32 `json:"port,omitempty"`
+	// CertDir see --certdir.
+	CertDir string `json:"certDir,omitempty"`
+	// TLSCertName see --tls-cert-name.
+	TLSCertName string `json:"tlsCertName,omitempty"`
+	// TLSPrivateKeyName see --tls-private-key-name.
+	TLSPrivateKeyName string `json:"tlsPrivateKeyName,omitempty"`
+	// TLSMinVersion see --tls-min-version.
+	TLSMinVersion string `json:"tlsMinVersion,omitempty"`
+	// TLSMaxVersion see --tls-max-version.
+	TLSMaxVersion string `json:"tlsMaxVersion,omitempty"`
+	// TLSCipherSuites see --tls-cipher-suites.
+	TLSCipherSuites []string `json:"tlsCipherSuites,omitempty"`
+	// ClientCAName see --client-ca-name.
+	ClientCAName string `json:"clientCAName,omitempty"`
+	// MetricsBindAddress see --metrics-bind-address.
+	MetricsBindAddress string `json:"metricsBindAddress,omitempty"`
+	// HealthProbeBindAddress see --health-probe-bind-address.
+	HealthProbeBindAddress string `json:"healthProbeBindAddress,omitempty"`
+	// ManagedCluster configures the validation of the ManagedClusters.
+	ManagedCluster *ManagedClusterValidation `json:"managedCluster,omitempty"`
+	// Authorizer configures the authorizer of the webhook server.
+	Authorizer *Authorizer `json:"authorizer,omitempty"`
+}
+
+// ManagedClusterValidation configures the validation of the ManagedClusters by the webhook server.
+type ManagedClusterValidation struct {
+	// DeletionProtection see --managed-cluster-deletion-protection.
+	DeletionProtection *bool `json:"deletionProtection,omitempty"`
+	// StatusUpdateMinInterval see --managed-cluster-status-update-min-interval.
+	StatusUpdateMinInterval *metav1.Duration `json:"statusUpdateMinInterval,omitempty"`
+	// StatusUpdateBurst see --managed-cluster-status-update-burst.
+	StatusUpdateBurst *int32 `json:"statusUpdateBurst,omitempty"`
+	// MaxClientConfigs see --managed-cluster-max-client-configs.
+	MaxClientConfigs *int32 `json:"maxClientConfigs,omitempty"`
+	// MaxCABundleBytes see --managed-cluster-max-ca-bundle-bytes.
+	MaxCABundleBytes *int32 `json:"maxCABundleBytes,omitempty"`
+	// ProtectedLabelPrefixes see --managed-cluster-protected-label-prefixes.
+	ProtectedLabelPrefixes []string `json:"protectedLabelPrefixes,omitempty"`
+	// ProtectedLabelWriters see --managed-cluster-protected-label-writers.
+	ProtectedLabelWriters []string `json:"protectedLabelWriters,omitempty"`
+}
+
+// Authorizer configures the authorizer of the webhook server.
+type Authorizer struct {
+	// Mode see --authorizer.
+	Mode string `json:"mode,omitempty"`
+	// PolicyFile see --authorizer-policy-file.
+	PolicyFile string `json:"policyFile,omitempty"`
+	// WebhookURL see --authorizer-webhook-url.
+	WebhookURL string `json:"webhookURL,omitempty"`
+	// WebhookCAFile see --authorizer-webhook-ca-file.
+	WebhookCAFile string `json:"webhookCAFile,omitempty"`
+}
+
+// ClientConnection configures the rate limit of a kube client.
+type ClientConnection struct {
+	QPS   *float32 `json:"qps,omitempty"`
+	Burst *int32   `json:"burst,omitempty"`
+}
+
+// LeaderElection configures the leader election of a component.
+type LeaderElection struct {
+	Disable       *bool            `json:"disable,omitempty"`
+	LeaseDuration *metav1.Duration `json:"leaseDuration,omitempty"`
+	RenewDeadline *metav1.Duration `json:"renewDeadline,omitempty"`
+	RetryPeriod   *metav1.Duration `json:"retryPeriod,omitempty"`
+}
@@ -0,0 +1,154 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/component-base/featuregate"
+	"k8s.io/klog/v2"
+)
+
+// FeatureGateReloadPeriod is exposed so that integration tests can crank up the reload speed.
+var FeatureGateReloadPeriod = 10 * time.Second
+
+// FeatureGateReloader reloads the mutable feature gates from a file, e.g. a key of a mounted ConfigMap.
+// The file contains a list of 'Feature=true|false' pairs separated by commas or newlines, the same
+// as the value of --feature-gates. A feature which is removed from the file is reset to the value
+// it had on startup.
+type FeatureGateReloader struct {
+	file        string
+	featureGate featuregate.MutableFeatureGate
+	recorder    events.Recorder
+	// initial holds the enablement of the features on startup, after the command-line flags are applied
+	initial map[featuregate.Feature]bool
+	lock    sync.Mutex
+}
+
+// NewFeatureGateReloader returns a FeatureGateReloader. It must be created after the command-line
+// flags are parsed.
+func NewFeatureGateReloader(file string, featureGate featuregate.MutableFeatureGate, recorder events.Recorder) *FeatureGateReloader {
+	initial := map[featuregate.Feature]bool{}
+	for feature := range featureGate.GetAll() {
+		// AllAlpha and AllBeta change the other features, they are not supported in the file
+		if feature == "AllAlpha" || feature == "AllBeta" {
+			continue
+		}
+		initial[feature] = featureGate.Enabled(feature)
+	}
+	return &FeatureGateReloader{
+		file:        file,
+		featureGate: featureGate,
+		recorder:    recorder,
+		initial:     initial,
+	}
+}
+
+// Run loads the feature gates from the file and calls the run func until the context is done. Once
+// the feature gates in the file change, the context passed to the run func is cancelled, and the run
+// func is called again with the new feature gates after it returns, so that the controllers are
+// restarted in-process without restarting the pod.
+func (r *FeatureGateReloader) Run(ctx context.Context, run func(ctx context.Context) error) error {
+	if _, err := r.reload(); err != nil {
+		return err
+	}
+
+	for {
+		runCtx, cancel := context.WithCancel(ctx)
+		var reloaded atomic.Bool
+		go wait.UntilWithContext(runCtx, func(ctx context.Context) {
+			changed, err := r.reload()
+			if err != nil {
+				klog.Errorf("Failed to reload feature gates from %q: %v", r.file, err)
+				return
+			}
+			if changed {
+				reloaded.Store(true)
+				cancel()
+			}
+		}, FeatureGateReloadPeriod)
+
+		err := run(runCtx)
+		cancel()
+		if err != nil || ctx.Err() != nil || !reloaded.Load() {
+			return err
+		}
+		klog.Infof("Restarting controllers with the reloaded feature gates")
+	}
+}
+
+// reload applies the feature gates in the file and returns true if any of them is changed.
+func (r *FeatureGateReloader) reload() (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	data, err := ioutil.ReadFile(r.file)
+	if err != nil {
+		return false, err
+	}
+	desired, err := parseFeatureGates(string(data))
+	if err != nil {
+		return false, fmt.Errorf("invalid feature gates in %q: %w", r.file, err)
+	}
+
+	changed := map[string]bool{}
+	for feature, enabled := range r.initial {
+		if value, ok := desired[string(feature)]; ok {
+			enabled = value
+		}
+		if r.featureGate.Enabled(feature) != enabled {
+			changed[string(feature)] = enabled
+		}
+	}
+	for name := range desired {
+		if _, ok := r.initial[featuregate.Feature(name)]; !ok {
+			return false, fmt.Errorf("unrecognized feature gate %q in %q", name, r.file)
+		}
+	}
+	if len(changed) == 0 {
+		return false, nil
+	}
+
+	if err := r.featureGate.SetFromMap(changed); err != nil {
+		return false, err
+	}
+
+	pairs := []string{}
+	for name, enabled := range changed {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	sort.Strings(pairs)
+	r.recorder.Eventf("FeatureGatesChanged", "Feature gates are changed: %s", strings.Join(pairs, ","))
+	return true, nil
+}
+
+func parseFeatureGates(value string) (map[string]bool, error) {
+	features := map[string]bool{}
+	pairs := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n'
+	})
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if len(pair) == 0 || strings.HasPrefix(pair, "#") {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("missing bool value for %q", pair)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %q: %w", pair, err)
+		}
+		features[strings.TrimSpace(kv[0])] = enabled
+	}
+	return features, nil
+}
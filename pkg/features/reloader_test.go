@@ -0,0 +1,136 @@
+package features
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"k8s.io/component-base/featuregate"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func newTestFeatureGate(t *testing.T) featuregate.MutableFeatureGate {
+	featureGate := featuregate.NewFeatureGate()
+	if err := featureGate.Add(map[featuregate.Feature]featuregate.FeatureSpec{
+		"FeatureA": {Default: false, PreRelease: featuregate.Alpha},
+		"FeatureB": {Default: true, PreRelease: featuregate.Beta},
+		"FeatureC": {Default: true, PreRelease: featuregate.GA, LockToDefault: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return featureGate
+}
+
+func TestReload(t *testing.T) {
+	cases := []struct {
+		name            string
+		initial         map[string]bool
+		content         string
+		expectedErr     string
+		expectedChanged bool
+		expected        map[featuregate.Feature]bool
+	}{
+		{
+			name:     "empty file",
+			expected: map[featuregate.Feature]bool{"FeatureA": false, "FeatureB": true},
+		},
+		{
+			name:            "enable features",
+			content:         "FeatureA=true\nFeatureB=false",
+			expectedChanged: true,
+			expected:        map[featuregate.Feature]bool{"FeatureA": true, "FeatureB": false},
+		},
+		{
+			name:     "unchanged",
+			initial:  map[string]bool{"FeatureA": true},
+			content:  "# comment\nFeatureA=true,FeatureB=true",
+			expected: map[featuregate.Feature]bool{"FeatureA": true, "FeatureB": true},
+		},
+		{
+			name:        "invalid value",
+			content:     "FeatureA=yes",
+			expectedErr: "invalid feature gates in \"{file}\": invalid value of \"FeatureA=yes\": strconv.ParseBool: parsing \"yes\": invalid syntax",
+			expected:    map[featuregate.Feature]bool{"FeatureA": false, "FeatureB": true},
+		},
+		{
+			name:        "unrecognized feature",
+			content:     "FeatureD=true",
+			expectedErr: "unrecognized feature gate \"FeatureD\" in \"{file}\"",
+			expected:    map[featuregate.Feature]bool{"FeatureA": false, "FeatureB": true},
+		},
+		{
+			name:        "locked feature",
+			content:     "FeatureC=false",
+			expectedErr: "cannot set feature gate FeatureC to false, feature is locked to true",
+			expected:    map[featuregate.Feature]bool{"FeatureA": false, "FeatureB": true},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			file := path.Join(t.TempDir(), "feature-gates")
+			if err := os.WriteFile(file, []byte(c.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			featureGate := newTestFeatureGate(t)
+			if err := featureGate.SetFromMap(c.initial); err != nil {
+				t.Fatal(err)
+			}
+			reloader := NewFeatureGateReloader(file, featureGate, eventstesting.NewTestingEventRecorder(t))
+			changed, err := reloader.reload()
+			testinghelpers.AssertError(t, err, strings.ReplaceAll(c.expectedErr, "{file}", file))
+			if changed != c.expectedChanged {
+				t.Errorf("expected changed %v, but got %v", c.expectedChanged, changed)
+			}
+			for feature, enabled := range c.expected {
+				if featureGate.Enabled(feature) != enabled {
+					t.Errorf("expected %s enabled %v, but got %v", feature, enabled, featureGate.Enabled(feature))
+				}
+			}
+		})
+	}
+}
+
+func TestRunRestartsOnChange(t *testing.T) {
+	FeatureGateReloadPeriod = 10 * time.Millisecond
+	defer func() { FeatureGateReloadPeriod = 10 * time.Second }()
+
+	file := path.Join(t.TempDir(), "feature-gates")
+	if err := os.WriteFile(file, []byte("FeatureA=true"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	featureGate := newTestFeatureGate(t)
+	reloader := NewFeatureGateReloader(file, featureGate, eventstesting.NewTestingEventRecorder(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runs := []bool{}
+	err := reloader.Run(ctx, func(runCtx context.Context) error {
+		runs = append(runs, featureGate.Enabled("FeatureA"))
+		switch len(runs) {
+		case 1:
+			// remove the feature from the file, it is reset to the initial value
+			if err := os.WriteFile(file, []byte(""), 0600); err != nil {
+				return err
+			}
+		default:
+			// stop the reloader after the restart
+			cancel()
+		}
+		<-runCtx.Done()
+		return nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(runs) != 2 || !runs[0] || runs[1] {
+		t.Errorf("expected the run func is restarted with FeatureA disabled, but got %v", runs)
+	}
+}
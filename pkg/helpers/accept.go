@@ -0,0 +1,152 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+)
+
+// DefaultAcceptFieldManager is the field manager of hubAcceptsClient applied by AcceptManagedCluster and
+// DenyManagedCluster if no field manager is given.
+const DefaultAcceptFieldManager = "managedcluster-accept"
+
+// AcceptManagedCluster accepts the ManagedCluster on the hub by applying hubAcceptsClient true with the server
+// side apply, so that the tools, e.g. clusteradm, don't reimplement the accept flow. The permissions of the
+// user to accept the cluster are reviewed before, and the denial of the admission webhooks is returned as an
+// error with the reason only.
+func AcceptManagedCluster(ctx context.Context, kubeClient kubernetes.Interface, clusterClient clusterclientset.Interface,
+	clusterName, fieldManager string) error {
+	return applyHubAcceptsClient(ctx, kubeClient, clusterClient, clusterName, fieldManager, true)
+}
+
+// DenyManagedCluster denies the ManagedCluster on the hub by applying hubAcceptsClient false with the server
+// side apply, the hub then removes the permissions of the agent on the hub. See AcceptManagedCluster.
+func DenyManagedCluster(ctx context.Context, kubeClient kubernetes.Interface, clusterClient clusterclientset.Interface,
+	clusterName, fieldManager string) error {
+	return applyHubAcceptsClient(ctx, kubeClient, clusterClient, clusterName, fieldManager, false)
+}
+
+func applyHubAcceptsClient(ctx context.Context, kubeClient kubernetes.Interface, clusterClient clusterclientset.Interface,
+	clusterName, fieldManager string, accept bool) error {
+	action := "accept"
+	if !accept {
+		action = "deny"
+	}
+
+	// the cluster is never created by the apply
+	cluster, err := clusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to %s managed cluster %q: %w", action, clusterName, err)
+	}
+	if cluster.Spec.HubAcceptsClient == accept {
+		return nil
+	}
+
+	if err := reviewAcceptPermissions(ctx, kubeClient, clusterName, accept); err != nil {
+		return err
+	}
+
+	if len(fieldManager) == 0 {
+		fieldManager = DefaultAcceptFieldManager
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"apiVersion": clusterv1.GroupVersion.String(),
+		"kind":       "ManagedCluster",
+		"metadata": map[string]interface{}{
+			"name": clusterName,
+			// the uid is a precondition, so that a recreated cluster is not accepted by mistake
+			"uid": cluster.UID,
+		},
+		"spec": map[string]interface{}{
+			"hubAcceptsClient": accept,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to %s managed cluster %q: %w", action, clusterName, err)
+	}
+	_, err = clusterClient.ClusterV1().ManagedClusters().Patch(ctx, clusterName, types.ApplyPatchType, patch,
+		metav1.PatchOptions{FieldManager: fieldManager, Force: pointer.Bool(true)})
+	if err != nil {
+		return admissionError(clusterName, action, err)
+	}
+	return nil
+}
+
+// reviewAcceptPermissions checks whether the user is allowed to accept or deny the cluster before the request,
+// the user must be able to patch the cluster. The webhook only requires the permission to update the accept
+// subresource once hubAcceptsClient becomes true, so it is reviewed to accept the cluster only.
+func reviewAcceptPermissions(ctx context.Context, kubeClient kubernetes.Interface, clusterName string, accept bool) error {
+	action := "accept"
+	if !accept {
+		action = "deny"
+	}
+	attributes := []authorizationv1.ResourceAttributes{
+		{
+			Group:    clusterv1.GroupName,
+			Resource: "managedclusters",
+			Verb:     "patch",
+			Name:     clusterName,
+		},
+	}
+	if accept {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{
+			Group:       "register.open-cluster-management.io",
+			Resource:    "managedclusters",
+			Subresource: "accept",
+			Verb:        "update",
+			Name:        clusterName,
+		})
+	}
+	for i := range attributes {
+		review, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes[i]},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to review the permissions to %s managed cluster %q: %w", action, clusterName, err)
+		}
+		if review.Status.Allowed {
+			continue
+		}
+
+		resource := attributes[i].Resource
+		if len(attributes[i].Subresource) != 0 {
+			resource = resource + "/" + attributes[i].Subresource
+		}
+		reason := fmt.Errorf("the user is not allowed to %s %s", attributes[i].Verb, resource)
+		if len(review.Status.Reason) != 0 {
+			reason = fmt.Errorf("%v: %s", reason, review.Status.Reason)
+		}
+		return apierrors.NewForbidden(schema.GroupResource{Group: attributes[i].Group, Resource: resource}, clusterName, reason)
+	}
+	return nil
+}
+
+// admissionError returns the error of the request, if it is denied by an admission webhook, the message of the
+// error is replaced by the reason of the denial, and the status of the error is kept.
+func admissionError(clusterName, action string, err error) error {
+	statusErr := &apierrors.StatusError{}
+	if !errors.As(err, &statusErr) {
+		return fmt.Errorf("unable to %s managed cluster %q: %w", action, clusterName, err)
+	}
+
+	message := statusErr.ErrStatus.Message
+	if prefix := "denied the request: "; strings.HasPrefix(message, "admission webhook") && strings.Contains(message, prefix) {
+		message = message[strings.Index(message, prefix)+len(prefix):]
+	}
+	status := statusErr.ErrStatus
+	status.Message = fmt.Sprintf("unable to %s managed cluster %q: %s", action, clusterName, message)
+	return &apierrors.StatusError{ErrStatus: status}
+}
@@ -0,0 +1,146 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestApplyHubAcceptsClient(t *testing.T) {
+	cases := []struct {
+		name              string
+		clusters          []runtime.Object
+		accept            bool
+		fieldManager      string
+		forbiddenResource string
+		webhookErr        *errors.StatusError
+		expectedErr       string
+		expectedForbidden bool
+		expectedPatched   bool
+	}{
+		{
+			name:        "cluster is not found",
+			accept:      true,
+			expectedErr: "unable to accept managed cluster \"testmanagedcluster\": managedclusters.cluster.open-cluster-management.io \"testmanagedcluster\" not found",
+		},
+		{
+			name:     "cluster is accepted",
+			clusters: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			accept:   true,
+		},
+		{
+			name:            "accept cluster",
+			clusters:        []runtime.Object{testinghelpers.NewManagedCluster()},
+			accept:          true,
+			expectedPatched: true,
+		},
+		{
+			name:            "deny cluster",
+			clusters:        []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			fieldManager:    "clusteradm",
+			expectedPatched: true,
+		},
+		{
+			name:              "not allowed to accept cluster",
+			clusters:          []runtime.Object{testinghelpers.NewManagedCluster()},
+			accept:            true,
+			forbiddenResource: "managedclusters/accept",
+			expectedErr:       "managedclusters/accept.register.open-cluster-management.io \"testmanagedcluster\" is forbidden: the user is not allowed to update managedclusters/accept: no RBAC policy matched",
+			expectedForbidden: true,
+		},
+		{
+			name:              "deny cluster without accept permission",
+			clusters:          []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			forbiddenResource: "managedclusters/accept",
+			expectedPatched:   true,
+		},
+		{
+			name:              "not allowed to deny cluster",
+			clusters:          []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			forbiddenResource: "managedclusters/",
+			expectedErr:       "managedclusters.cluster.open-cluster-management.io \"testmanagedcluster\" is forbidden: the user is not allowed to patch managedclusters: no RBAC policy matched",
+			expectedForbidden: true,
+		},
+		{
+			name:       "denied by webhook",
+			clusters:   []runtime.Object{testinghelpers.NewManagedCluster()},
+			accept:     true,
+			webhookErr: errors.NewForbidden(clusterv1.Resource("managedclusters"), "testmanagedcluster", nil),
+			expectedErr: "unable to accept managed cluster \"testmanagedcluster\": " +
+				"cluster namespace \"testmanagedcluster\" is terminating",
+			expectedForbidden: true,
+			expectedPatched:   true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				attributes := review.Spec.ResourceAttributes
+				review.Status.Allowed = attributes.Resource+"/"+attributes.Subresource != c.forbiddenResource
+				if !review.Status.Allowed {
+					review.Status.Reason = "no RBAC policy matched"
+				}
+				return true, review, nil
+			})
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			if c.webhookErr != nil {
+				c.webhookErr.ErrStatus.Message = "admission webhook \"managedclustervalidators.admission.cluster.open-cluster-management.io\" " +
+					"denied the request: cluster namespace \"testmanagedcluster\" is terminating"
+				clusterClient.PrependReactor("patch", "managedclusters", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, c.webhookErr
+				})
+			}
+
+			var err error
+			if c.accept {
+				err = AcceptManagedCluster(context.TODO(), kubeClient, clusterClient, testinghelpers.TestManagedClusterName, c.fieldManager)
+			} else {
+				err = DenyManagedCluster(context.TODO(), kubeClient, clusterClient, testinghelpers.TestManagedClusterName, c.fieldManager)
+			}
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if errors.IsForbidden(err) != c.expectedForbidden {
+				t.Errorf("expected forbidden %v, but got %v", c.expectedForbidden, err)
+			}
+
+			var patchAction clienttesting.PatchAction
+			for _, action := range clusterClient.Actions() {
+				if action.GetVerb() == "patch" {
+					patchAction = action.(clienttesting.PatchAction)
+				}
+			}
+			if !c.expectedPatched {
+				if patchAction != nil {
+					t.Errorf("expected no patch, but got %s", patchAction.GetPatch())
+				}
+				return
+			}
+			if patchAction == nil {
+				t.Fatalf("expected the cluster is patched")
+			}
+			if patchAction.GetPatchType() != types.ApplyPatchType {
+				t.Errorf("expected apply patch, but got %s", patchAction.GetPatchType())
+			}
+			cluster := &clusterv1.ManagedCluster{}
+			if err := json.Unmarshal(patchAction.GetPatch(), cluster); err != nil {
+				t.Fatal(err)
+			}
+			if cluster.Name != testinghelpers.TestManagedClusterName || cluster.Spec.HubAcceptsClient != c.accept {
+				t.Errorf("expected hubAcceptsClient %v applied, but got %s", c.accept, patchAction.GetPatch())
+			}
+		})
+	}
+}
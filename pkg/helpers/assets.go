@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	"open-cluster-management.io/registration/pkg/hub/user"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"sigs.k8s.io/yaml"
+)
+
+// ManagedClusterAssetData returns the data to render the manifests of a managed cluster, the
+// manifests may refer to
+//   - .ManagedClusterName, the name of the managed cluster;
+//   - .ManagedClusterLabels, the labels of the managed cluster;
+//   - .ManagedClusterSetName, the clusterset of the managed cluster, it is empty if the managed
+//     cluster does not belong to any clusterset;
+//   - .HubNamespace, the namespace of the registration hub controller;
+//   - .TokenUsername, the user of the agent registered with the token driver, it is empty if the
+//     agent is registered with another driver;
+//   - .Values, the custom values.
+func ManagedClusterAssetData(managedCluster *clusterv1.ManagedCluster, hubNamespace string, values map[string]interface{}) map[string]interface{} {
+	labels := map[string]string{}
+	for key, value := range managedCluster.Labels {
+		labels[key] = value
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"ManagedClusterName":    managedCluster.Name,
+		"ManagedClusterLabels":  labels,
+		"ManagedClusterSetName": labels[clusterv1beta2.ClusterSetLabel],
+		"HubNamespace":          hubNamespace,
+		"TokenUsername":         managedCluster.Annotations[user.TokenUsernameAnnotation],
+		"Values":                values,
+	}
+}
+
+// ManagedClusterAssetFn returns an AssetFunc which renders the manifests in the fs with the name of
+// the managed cluster.
+func ManagedClusterAssetFn(fsys fs.FS, managedClusterName string) resourceapply.AssetFunc {
+	return ManagedClusterAssetFnWithData(fsys, map[string]interface{}{
+		"ManagedClusterName": managedClusterName,
+	})
+}
+
+// ManagedClusterAssetFnWithData returns an AssetFunc which renders the manifests in the fs with the
+// data, see ManagedClusterAssetData for the data of a managed cluster. Besides the functions of the
+// text/template, the manifests may use the functions: default, required, quote, lower, upper, trim,
+// indent, toYaml and base64.
+func ManagedClusterAssetFnWithData(fsys fs.FS, data map[string]interface{}) resourceapply.AssetFunc {
+	return func(name string) ([]byte, error) {
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		return renderAsset(name, raw, data)
+	}
+}
+
+func renderAsset(name string, raw []byte, data map[string]interface{}) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(assetTemplateFuncs).Parse(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the manifest %q: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("unable to render the manifest %q: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+var assetTemplateFuncs = template.FuncMap{
+	"default":  defaultValue,
+	"required": required,
+	"quote":    quote,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"trim":     strings.TrimSpace,
+	"indent":   indent,
+	"toYaml":   toYaml,
+	"base64":   base64Encode,
+}
+
+// defaultValue returns the value if it is not empty, otherwise returns the default value. It is
+// used as {{ .Values.key | default "value" }}.
+func defaultValue(defaultValue interface{}, value interface{}) interface{} {
+	if isEmptyValue(value) {
+		return defaultValue
+	}
+	return value
+}
+
+// required returns an error with the message if the value is empty, it is used as
+// {{ .Values.key | required "key is required" }}.
+func required(message string, value interface{}) (interface{}, error) {
+	if isEmptyValue(value) {
+		return nil, errors.New(message)
+	}
+	return value, nil
+}
+
+func isEmptyValue(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// quote returns the value as a double-quoted string, so that it is always a string in yaml.
+func quote(value interface{}) string {
+	if value == nil {
+		return `""`
+	}
+	return strconv.Quote(fmt.Sprint(value))
+}
+
+// indent prepends the spaces to each line of the value.
+func indent(spaces int, value string) string {
+	padding := strings.Repeat(" ", spaces)
+	return padding + strings.ReplaceAll(value, "\n", "\n"+padding)
+}
+
+// toYaml returns the value in yaml without the trailing newline.
+func toYaml(value interface{}) (string, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+func base64Encode(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
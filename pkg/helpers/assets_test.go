@@ -0,0 +1,202 @@
+package helpers
+
+import (
+	"testing"
+	"testing/fstest"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestManagedClusterAssetFn(t *testing.T) {
+	fsys := fstest.MapFS{
+		"namespace.yaml": &fstest.MapFile{Data: []byte("name: {{ .ManagedClusterName }}")},
+	}
+
+	data, err := ManagedClusterAssetFn(fsys, "cluster1")("namespace.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "name: cluster1" {
+		t.Errorf("unexpected manifest: %q", string(data))
+	}
+
+	_, err = ManagedClusterAssetFn(fsys, "cluster1")("missing.yaml")
+	if err == nil {
+		t.Errorf("expected error for a missing manifest")
+	}
+}
+
+func TestManagedClusterAssetFnWithData(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster1",
+			Labels: map[string]string{
+				"cluster.open-cluster-management.io/clusterset": "set1",
+				"env": "Prod",
+			},
+			Annotations: map[string]string{
+				"agent.open-cluster-management.io/managed-cluster-token-username": "system:serviceaccount:cluster1:agent",
+			},
+		},
+	}
+
+	cases := []struct {
+		name          string
+		manifest      string
+		values        map[string]interface{}
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "cluster name",
+			manifest: "name: {{ .ManagedClusterName }}",
+			expected: "name: cluster1",
+		},
+		{
+			name:     "labels and clusterset",
+			manifest: `env: {{ index .ManagedClusterLabels "env" | lower }}, set: {{ .ManagedClusterSetName }}`,
+			expected: "env: prod, set: set1",
+		},
+		{
+			name:     "hub namespace",
+			manifest: "namespace: {{ .HubNamespace }}",
+			expected: "namespace: open-cluster-management-hub",
+		},
+		{
+			name:     "token username",
+			manifest: "{{- if .TokenUsername }}user: {{ .TokenUsername }}{{- end }}",
+			expected: "user: system:serviceaccount:cluster1:agent",
+		},
+		{
+			name:     "values",
+			manifest: "image: {{ .Values.image | quote }}",
+			values:   map[string]interface{}{"image": "registration:latest"},
+			expected: `image: "registration:latest"`,
+		},
+		{
+			name:     "default value",
+			manifest: `replicas: {{ .Values.replicas | default 1 }}`,
+			expected: "replicas: 1",
+		},
+		{
+			name:          "missing required value",
+			manifest:      `image: {{ .Values.image | required "image is required" }}`,
+			expectedError: `unable to render the manifest "manifest.yaml": template: manifest.yaml:1:26: executing "manifest.yaml" at <required "image is required">: error calling required: image is required`,
+		},
+		{
+			name:          "invalid template",
+			manifest:      "name: {{ .ManagedClusterName ",
+			expectedError: `unable to parse the manifest "manifest.yaml": template: manifest.yaml:1: unclosed action`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"manifest.yaml": &fstest.MapFile{Data: []byte(c.manifest)}}
+			assetFn := ManagedClusterAssetFnWithData(fsys, ManagedClusterAssetData(managedCluster, "open-cluster-management-hub", c.values))
+
+			data, err := assetFn("manifest.yaml")
+			testinghelpers.AssertError(t, err, c.expectedError)
+			if string(data) != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, string(data))
+			}
+		})
+	}
+}
+
+func TestAssetTemplateFuncs(t *testing.T) {
+	cases := []struct {
+		name     string
+		template string
+		data     map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "default with empty string",
+			template: `{{ .value | default "foo" }}`,
+			data:     map[string]interface{}{"value": ""},
+			expected: "foo",
+		},
+		{
+			name:     "default with empty slice",
+			template: `{{ .value | default "foo" }}`,
+			data:     map[string]interface{}{"value": []string{}},
+			expected: "foo",
+		},
+		{
+			name:     "default with value",
+			template: `{{ .value | default "foo" }}`,
+			data:     map[string]interface{}{"value": "bar"},
+			expected: "bar",
+		},
+		{
+			name:     "default with false",
+			template: `{{ .value | default true }}`,
+			data:     map[string]interface{}{"value": false},
+			expected: "true",
+		},
+		{
+			name:     "required with value",
+			template: `{{ .value | required "value is required" }}`,
+			data:     map[string]interface{}{"value": 3},
+			expected: "3",
+		},
+		{
+			name:     "quote",
+			template: `{{ .value | quote }} {{ .missing | quote }}`,
+			data:     map[string]interface{}{"value": 1},
+			expected: `"1" ""`,
+		},
+		{
+			name:     "quote with escape",
+			template: `{{ .value | quote }}`,
+			data:     map[string]interface{}{"value": `a "b"`},
+			expected: `"a \"b\""`,
+		},
+		{
+			name:     "lower upper trim",
+			template: `{{ .value | trim | lower }}/{{ .value | trim | upper }}`,
+			data:     map[string]interface{}{"value": " Foo "},
+			expected: "foo/FOO",
+		},
+		{
+			name:     "indent",
+			template: "labels:\n{{ .value | indent 2 }}",
+			data:     map[string]interface{}{"value": "a: b\nc: d"},
+			expected: "labels:\n  a: b\n  c: d",
+		},
+		{
+			name:     "toYaml",
+			template: "{{ .value | toYaml }}",
+			data:     map[string]interface{}{"value": map[string]string{"b": "2", "a": "1"}},
+			expected: "a: \"1\"\nb: \"2\"",
+		},
+		{
+			name:     "toYaml and indent",
+			template: "labels:\n{{ .value | toYaml | indent 2 }}",
+			data:     map[string]interface{}{"value": map[string]string{"a": "1"}},
+			expected: "labels:\n  a: \"1\"",
+		},
+		{
+			name:     "base64",
+			template: "{{ .value | base64 }}",
+			data:     map[string]interface{}{"value": "hello"},
+			expected: "aGVsbG8=",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := renderAsset("test", []byte(c.template), c.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, string(data))
+			}
+		})
+	}
+}
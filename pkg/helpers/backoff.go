@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// RetryBackoff retries the failed syncs of the controllers with an exponential backoff with jitter,
+// instead of the rate limiter of their queues which is not configurable. A controller keeps failing once
+// its syncs fail longer than the max elapsed time, which is reported by Terminal.
+type RetryBackoff struct {
+	backoff        wait.Backoff
+	maxElapsedTime time.Duration
+	clock          clock.Clock
+
+	lock     sync.Mutex
+	failures map[string]*syncFailure
+}
+
+// syncFailure is the state of the failed syncs of a controller.
+type syncFailure struct {
+	backoff      wait.Backoff
+	firstFailure time.Time
+	lastErr      error
+}
+
+// NewRetryBackoff returns a RetryBackoff which retries the failed syncs after the initialInterval, and
+// doubles the interval after each failure up to the maxInterval. Each interval is extended randomly by up
+// to the jitter times of itself. The failures are never terminal if the maxElapsedTime is 0.
+func NewRetryBackoff(initialInterval, maxInterval time.Duration, jitter float64, maxElapsedTime time.Duration) *RetryBackoff {
+	return &RetryBackoff{
+		backoff: wait.Backoff{
+			Duration: initialInterval,
+			Factor:   2,
+			Jitter:   jitter,
+			Steps:    math.MaxInt32,
+			Cap:      maxInterval,
+		},
+		maxElapsedTime: maxElapsedTime,
+		clock:          clock.RealClock{},
+		failures:       map[string]*syncFailure{},
+	}
+}
+
+// WrapSync wraps the sync of the controller of the name. The failed syncs are logged and requeued after
+// the backoff, and the state of the backoff is reset once a sync succeeds. The sync is returned as it is
+// if the RetryBackoff is nil.
+func (b *RetryBackoff) WrapSync(name string, sync factory.SyncFunc) factory.SyncFunc {
+	if b == nil {
+		return sync
+	}
+	return func(ctx context.Context, syncCtx factory.SyncContext) error {
+		err := sync(ctx, syncCtx)
+		if err == nil {
+			b.lock.Lock()
+			delete(b.failures, name)
+			b.lock.Unlock()
+			return nil
+		}
+
+		retryAfter := b.failed(name, err)
+		klog.Errorf("%s failed to sync, retry after %s: %v", name, retryAfter.Round(time.Millisecond), err)
+		syncCtx.Queue().AddAfter(syncCtx.QueueKey(), retryAfter)
+		return nil
+	}
+}
+
+func (b *RetryBackoff) failed(name string, err error) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	failure, ok := b.failures[name]
+	if !ok {
+		failure = &syncFailure{backoff: b.backoff, firstFailure: b.clock.Now()}
+		b.failures[name] = failure
+	}
+	failure.lastErr = err
+	return failure.backoff.Step()
+}
+
+// Terminal returns an error if the syncs of any controller have been failing longer than the max elapsed
+// time.
+func (b *RetryBackoff) Terminal() error {
+	if b == nil || b.maxElapsedTime <= 0 {
+		return nil
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for name, failure := range b.failures {
+		if elapsed := b.clock.Since(failure.firstFailure); elapsed > b.maxElapsedTime {
+			return fmt.Errorf("%s has been failing for %s: %w", name, elapsed.Round(time.Second), failure.lastErr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestRetryBackoffWrapSync(t *testing.T) {
+	cases := []struct {
+		name             string
+		syncErrs         []error
+		maxElapsedTime   time.Duration
+		elapsed          time.Duration
+		expectedRequeued int
+		expectedTerminal bool
+	}{
+		{
+			name:             "sync succeeds",
+			syncErrs:         []error{nil},
+			maxElapsedTime:   time.Minute,
+			elapsed:          2 * time.Minute,
+			expectedRequeued: 0,
+		},
+		{
+			name:             "sync fails",
+			syncErrs:         []error{errors.New("hub is down")},
+			maxElapsedTime:   time.Minute,
+			elapsed:          30 * time.Second,
+			expectedRequeued: 1,
+		},
+		{
+			name:             "sync fails longer than the max elapsed time",
+			syncErrs:         []error{errors.New("hub is down"), errors.New("hub is down")},
+			maxElapsedTime:   time.Minute,
+			elapsed:          2 * time.Minute,
+			expectedRequeued: 1,
+			expectedTerminal: true,
+		},
+		{
+			name:             "sync succeeds after failures",
+			syncErrs:         []error{errors.New("hub is down"), nil},
+			maxElapsedTime:   time.Minute,
+			elapsed:          2 * time.Minute,
+			expectedRequeued: 1,
+		},
+		{
+			name:             "sync fails without max elapsed time",
+			syncErrs:         []error{errors.New("hub is down")},
+			elapsed:          time.Hour,
+			expectedRequeued: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fakeClock := clocktesting.NewFakeClock(time.Now())
+			// retry immediately, so the failed sync is requeued without waiting
+			retryBackoff := NewRetryBackoff(0, time.Minute, 0, c.maxElapsedTime)
+			retryBackoff.clock = fakeClock
+
+			syncCtx := testinghelpers.NewFakeSyncContext(t, "key")
+			for i, syncErr := range c.syncErrs {
+				if i != 0 {
+					fakeClock.Step(c.elapsed)
+				}
+				sync := retryBackoff.WrapSync("controller", func(ctx context.Context, syncCtx factory.SyncContext) error {
+					return syncErr
+				})
+				if err := sync(context.TODO(), syncCtx); err != nil {
+					t.Errorf("expected the failed sync retried with the backoff, but got %v", err)
+				}
+			}
+
+			if syncCtx.Queue().Len() != c.expectedRequeued {
+				t.Errorf("expected %d keys requeued, but got %d", c.expectedRequeued, syncCtx.Queue().Len())
+			}
+			err := retryBackoff.Terminal()
+			if c.expectedTerminal && err == nil {
+				t.Errorf("expected terminal, but got nil")
+			}
+			if !c.expectedTerminal && err != nil {
+				t.Errorf("expected not terminal, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestRetryBackoffInterval(t *testing.T) {
+	retryBackoff := NewRetryBackoff(time.Second, 5*time.Second, 0, 0)
+	expectedIntervals := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, expected := range expectedIntervals {
+		if actual := retryBackoff.failed("controller", errors.New("hub is down")); actual != expected {
+			t.Errorf("expected the interval of the failure %d is %s, but got %s", i, expected, actual)
+		}
+	}
+
+	// the interval of each controller is independent
+	if actual := retryBackoff.failed("another-controller", errors.New("hub is down")); actual != time.Second {
+		t.Errorf("expected the interval of another controller is 1s, but got %s", actual)
+	}
+
+	var nilBackoff *RetryBackoff
+	if nilBackoff.Terminal() != nil {
+		t.Errorf("expected nil backoff never terminal")
+	}
+}
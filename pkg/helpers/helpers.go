@@ -2,7 +2,6 @@ package helpers
 
 import (
 	"context"
-	"embed"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -13,40 +12,244 @@ import (
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 
 	jsonpatch "github.com/evanphx/json-patch"
-	"github.com/openshift/api"
-	"github.com/openshift/library-go/pkg/assets"
-	"github.com/openshift/library-go/pkg/operator/events"
-	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
-	"github.com/openshift/library-go/pkg/operator/resource/resourcehelper"
-	errorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
 
 	certificatesv1 "k8s.io/api/certificates/v1"
 	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
-	corev1 "k8s.io/api/core/v1"
-	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
-	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/util/retry"
 )
 
-var (
-	genericScheme = runtime.NewScheme()
-	genericCodecs = serializer.NewCodecFactory(genericScheme)
-	genericCodec  = genericCodecs.UniversalDeserializer()
+// ManagedClusterLeaseName is the name of the lease in the namespace of a managed cluster on the hub, which is
+// created by the hub once the managed cluster is accepted and updated by its registration agent.
+const ManagedClusterLeaseName = "managed-cluster-lease"
+
+// HubClusterAdminDeniedReason is the reason of the HubAcceptedManagedCluster condition of a ManagedCluster once
+// the hub cluster admin denies it, i.e. the hubAcceptsClient is changed to false after it is accepted.
+const HubClusterAdminDeniedReason = "HubClusterAdminDenied"
+
+// ManagedClusterConditionHubAccessReady is the condition type of a ManagedCluster reported by the agent.
+// It is false if the agent is missing the permissions it requires on the hub.
+const ManagedClusterConditionHubAccessReady = "HubAccessReady"
+
+// ManagedClusterConditionClockSynced is the condition type of a ManagedCluster reported by the agent.
+// It is false if the clock of the managed cluster is out of sync with the clock of the hub.
+const ManagedClusterConditionClockSynced = "ManagedClusterConditionClockSynced"
+
+// ManagedClusterConditionHubClientCertificateValid is the condition type of a ManagedCluster reported by the
+// agent. It is false if the client certificate of the agent for the hub is about to expire, which usually
+// means the rotation of the client certificate keeps failing.
+const ManagedClusterConditionHubClientCertificateValid = "HubClientCertificateValid"
+
+// ManagedClusterConditionAgentVersionCompatible is the condition type of a ManagedCluster reported by the
+// hub. It is false if the registration agent of the managed cluster is too old for the hub.
+const ManagedClusterConditionAgentVersionCompatible = "AgentVersionCompatible"
+
+const (
+	// AgentVersionAnnotation is the annotation of a ManagedCluster and its lease which holds the version of
+	// its registration agent.
+	AgentVersionAnnotation = "agent.open-cluster-management.io/registration-version"
+	// AgentBuildAnnotation is the annotation of the lease of a ManagedCluster which holds the git commit
+	// its registration agent is built from.
+	AgentBuildAnnotation = "agent.open-cluster-management.io/registration-build"
+	// AgentFeatureGatesAnnotation is the annotation of the lease of a ManagedCluster which holds the
+	// comma-separated feature gates enabled on its registration agent.
+	AgentFeatureGatesAnnotation = "agent.open-cluster-management.io/feature-gates"
+	// AgentAPICompatibilityLevelAnnotation is the annotation of a ManagedCluster which holds the api
+	// compatibility level of its registration agent.
+	AgentAPICompatibilityLevelAnnotation = "agent.open-cluster-management.io/api-compatibility-level"
+	// AgentClusterIdentityAnnotation is the annotation of a ManagedCluster which holds the identity of the
+	// cluster the registration agent runs on, it is the UID of the kube-system namespace.
+	AgentClusterIdentityAnnotation = "agent.open-cluster-management.io/cluster-identity"
+	// AgentDeployModeAnnotation is the annotation of the lease of a ManagedCluster which holds the deploy
+	// mode of its registration agent, it is AgentDeployModeHosted if the agent runs outside the managed
+	// cluster, otherwise AgentDeployModeDefault. It is stamped each time the lease is renewed.
+	AgentDeployModeAnnotation = "agent.open-cluster-management.io/deploy-mode"
+	// AgentDeployModeDefault is the deploy mode of the registration agent running on the managed cluster.
+	AgentDeployModeDefault = "Default"
+	// AgentDeployModeHosted is the deploy mode of the registration agent running on a management cluster
+	// other than the managed cluster.
+	AgentDeployModeHosted = "Hosted"
+	// AgentClientCertificateRotationsAnnotation is the annotation of a ManagedCluster which holds the history
+	// of the client certificates of its registration agent in the json of ClientCertificateRotations.
+	AgentClientCertificateRotationsAnnotation = "agent.open-cluster-management.io/client-certificate-rotations"
 )
 
-func init() {
-	utilruntime.Must(api.InstallKube(genericScheme))
+// ClientCertificateRotations is the history of the client certificates of a registration agent.
+type ClientCertificateRotations struct {
+	// Count is the number of the client certificates the agent has used.
+	Count int `json:"count"`
+	// Recent are the most recent client certificates, the current one is the last.
+	Recent []ClientCertificate `json:"recent,omitempty"`
+}
+
+// ClientCertificate is a client certificate of a registration agent.
+type ClientCertificate struct {
+	SerialNumber string      `json:"serialNumber"`
+	NotBefore    metav1.Time `json:"notBefore"`
+	NotAfter     metav1.Time `json:"notAfter"`
+}
+
+// ManagedClusterConditionAgentAvailable is the condition type of a ManagedCluster in the hosted mode
+// reported by the hub. It is false if the registration agent stops updating its lease, and it is true
+// with the reason ManagedClusterUnreachable if the agent is running but unable to reach the managed cluster.
+const ManagedClusterConditionAgentAvailable = "RegistrationAgentAvailable"
+
+const (
+	// ManagedClusterMaintenanceAnnotation is the annotation of a ManagedCluster to cordon it, the hub adds
+	// the maintenance taint to the managed cluster once the annotation is "true", and removes the taint
+	// once the annotation is removed.
+	ManagedClusterMaintenanceAnnotation = "cluster.open-cluster-management.io/maintenance"
+	// ManagedClusterTaintMaintenance is the key of the taint added to a managed cluster in maintenance.
+	ManagedClusterTaintMaintenance = "cluster.open-cluster-management.io/maintenance"
+)
+
+const (
+	// ManagedClusterAcceptedByAnnotation is the annotation of a ManagedCluster which holds the user who
+	// accepts the managed cluster, it is set by the webhook once hubAcceptsClient is set to true.
+	ManagedClusterAcceptedByAnnotation = "cluster.open-cluster-management.io/accepted-by"
+	// ManagedClusterAcceptedTimeAnnotation is the annotation of a ManagedCluster which holds the time in
+	// RFC3339 when the managed cluster is accepted.
+	ManagedClusterAcceptedTimeAnnotation = "cluster.open-cluster-management.io/accepted-time"
+)
+
+const (
+	// ManagedClusterClientConfigsHashAnnotation is the annotation of a ManagedCluster which holds the hash
+	// of the client configs of the managed cluster, it is recorded by the hub once the managed cluster is
+	// accepted. Remove it to accept the current client configs.
+	ManagedClusterClientConfigsHashAnnotation = "cluster.open-cluster-management.io/client-configs-hash"
+	// ManagedClusterConditionClientConfigsDrifted is the condition type of a ManagedCluster reported by the
+	// hub. It is true if the client configs of the managed cluster are different from the recorded ones,
+	// e.g. the managed cluster is re-registered with a different api server.
+	ManagedClusterConditionClientConfigsDrifted = "ClientConfigsDrifted"
+)
+
+const (
+	// ManagedClusterIdentityAnnotation is the annotation of a ManagedCluster which holds the identity of the
+	// cluster reported by the agent, it is recorded by the hub once the managed cluster is accepted. Remove
+	// it to accept the currently reported identity.
+	ManagedClusterIdentityAnnotation = "cluster.open-cluster-management.io/cluster-identity"
+	// ManagedClusterConditionIdentityConflicted is the condition type of a ManagedCluster reported by the
+	// hub. It is true if the identity reported by the agent is different from the recorded one, e.g.
+	// another cluster is registered with the same cluster name.
+	ManagedClusterConditionIdentityConflicted = "ClusterIdentityConflicted"
+)
+
+const (
+	// ManagedClusterAddOnStatusesAnnotation is the annotation of a ManagedCluster which holds the condensed
+	// statuses of its addons in the json of a map from the addon name to AddOnStatusSummary, so that they
+	// can be read with one request. It is maintained by the hub if it is enabled.
+	ManagedClusterAddOnStatusesAnnotation = "cluster.open-cluster-management.io/addon-statuses"
+	// AddOnVersionAnnotation is the annotation of a ManagedClusterAddOn which holds the version of the addon,
+	// it is set by the addon manager and copied into the ManagedClusterAddOnStatusesAnnotation.
+	AddOnVersionAnnotation = "addon.open-cluster-management.io/version"
+)
+
+// AddOnStatusSummary is the condensed status of an addon on a managed cluster.
+type AddOnStatusSummary struct {
+	// Version is the version of the addon, it is empty if the addon manager does not report it.
+	Version string `json:"version,omitempty"`
+	// Health is one of available, unhealthy and unreachable, it is the same as the value of the addon label.
+	Health string `json:"health"`
+}
+
+const (
+	// ManagedClusterDeletionModeAnnotation is the annotation of a ManagedCluster which chooses how the hub
+	// handles its workloads once it is deleted. Without the annotation, the registration resources of the
+	// managed cluster are removed at once, and the ManifestWorks are left to be deleted by others.
+	ManagedClusterDeletionModeAnnotation = "cluster.open-cluster-management.io/deletion-mode"
+	// ManagedClusterDeletionModePurge deletes the ManifestWorks and ManagedClusterAddOns in the namespace
+	// of the managed cluster, and keeps the registration resources until they are gone, so that the agents
+	// are able to remove the workloads from the managed cluster.
+	ManagedClusterDeletionModePurge = "Purge"
+	// ManagedClusterDeletionModeDetach removes the registration resources, including the permissions of
+	// the work agent, without waiting for the ManifestWorks, so the workloads are left running on the
+	// managed cluster.
+	ManagedClusterDeletionModeDetach = "Detach"
+)
+
+const (
+	// ManagedClusterConditionContentDeleteSuccess is the condition type of a ManagedCluster deleted in the
+	// Purge mode, which reports the progress of the deletion of its ManifestWorks and ManagedClusterAddOns.
+	// It is false while the resources are remaining, its message is for display only, e.g.
+	// "2 of 5 resources remaining: 1 manifestworks, 1 addons", the progress is read from the
+	// ManagedClusterContentDeleteProgressAnnotation instead.
+	ManagedClusterConditionContentDeleteSuccess = "ContentDeleteSuccess"
+	// ContentDeleteReasonResourceRemaining is the reason of the ContentDeleteSuccess condition while the
+	// resources are being deleted.
+	ContentDeleteReasonResourceRemaining = "ResourceRemaining"
+	// ContentDeleteReasonContentDeleted is the reason of the ContentDeleteSuccess condition once all the
+	// resources are deleted.
+	ContentDeleteReasonContentDeleted = "ContentDeleted"
+	// ManagedClusterContentDeleteProgressAnnotation is the annotation of a ManagedCluster deleted in the Purge
+	// mode which holds its ContentDeleteProgress in json, it is maintained by the hub along with the
+	// ContentDeleteSuccess condition.
+	ManagedClusterContentDeleteProgressAnnotation = "cluster.open-cluster-management.io/content-delete-progress"
+)
+
+// ContentDeleteProgress is the progress of the deletion of the ManifestWorks and ManagedClusterAddOns of a
+// ManagedCluster deleted in the Purge mode.
+type ContentDeleteProgress struct {
+	// Total is the number of the resources found once the purge started.
+	Total int `json:"total"`
+	// Remaining is the number of the resources which are not deleted yet.
+	Remaining int `json:"remaining"`
+	// ManifestWorks is the number of the remaining ManifestWorks.
+	ManifestWorks int `json:"manifestWorks"`
+	// AddOns is the number of the remaining ManagedClusterAddOns.
+	AddOns int `json:"addOns"`
+}
+
+// ManagedClusterCleanupFinalizerPrefix is the default prefix of the finalizers of a ManagedCluster added by the
+// third-party controllers which clean up once the managed cluster is deleted, e.g.
+// "cleanup.open-cluster-management.io/backup". The hub waits until all of them are removed before it purges the
+// workloads and removes the registration resources of the managed cluster, so the controllers are able to reach
+// the managed cluster through its agents and ManifestWorks during the cleanup. A controller adds its finalizer
+// before it starts to depend on the managed cluster, and removes it once its cleanup is done. See
+// docs/cleanup-finalizers.md for the contract.
+const ManagedClusterCleanupFinalizerPrefix = "cleanup.open-cluster-management.io/"
+
+const (
+	// HubMigrationBootstrapKubeconfigSecretName is the name of the secret in the namespace of a managed
+	// cluster on the hub which holds the bootstrap kubeconfig of the new hub the managed cluster is
+	// migrated to. The kubeconfig is in the "kubeconfig" key of the secret.
+	HubMigrationBootstrapKubeconfigSecretName = "hub-migration-bootstrap-kubeconfig"
+	// ManagedClusterConditionHubMigrated is the condition type of a ManagedCluster reported by the agent.
+	// It is true once the agent starts to bootstrap with the new hub, and the hub stops accepting the
+	// managed cluster instead of deleting it.
+	ManagedClusterConditionHubMigrated = "HubMigrated"
+)
+
+const (
+	// ClusterClaimKubeVersion is the well-known claim of the kubernetes version of the managed cluster.
+	ClusterClaimKubeVersion = "kubeversion.open-cluster-management.io"
+	// ClusterClaimPlatform is the well-known claim of the platform the managed cluster is running on,
+	// e.g. AWS, GCP and Azure.
+	ClusterClaimPlatform = "platform.open-cluster-management.io"
+	// ClusterClaimRegion is the well-known claim of the region of the nodes of the managed cluster, it is
+	// only synthesized if all the labeled nodes are in the same region.
+	ClusterClaimRegion = "region.open-cluster-management.io"
+	// ClusterClaimZone is the well-known claim of the zone of the nodes of the managed cluster, it is only
+	// synthesized if all the labeled nodes are in the same zone.
+	ClusterClaimZone = "zone.open-cluster-management.io"
+	// ClusterClaimOpenShiftID is the well-known claim of the cluster ID of an OpenShift cluster.
+	ClusterClaimOpenShiftID = "id.openshift.io"
+)
+
+// WellKnownClusterClaimNames are the names of the cluster claims synthesized by the agent if they are
+// not created on the managed cluster.
+var WellKnownClusterClaimNames = []string{
+	ClusterClaimKubeVersion,
+	ClusterClaimPlatform,
+	ClusterClaimRegion,
+	ClusterClaimZone,
+	ClusterClaimOpenShiftID,
 }
 
 type UpdateManagedClusterStatusFunc func(status *clusterv1.ManagedClusterStatus) error
@@ -121,6 +324,12 @@ func UpdateManagedClusterConditionFn(cond metav1.Condition) UpdateManagedCluster
 
 type UpdateManagedClusterAddOnStatusFunc func(status *addonv1alpha1.ManagedClusterAddOnStatus) error
 
+// UpdateManagedClusterAddOnStatus applies the updateFuncs to the latest status of the addon, and patches the
+// changes only. The patch is preconditioned on the resource version, so once the addon is updated by another
+// writer in the meantime, e.g. the lease controller and the registration controller of the same addon, the
+// update funcs are applied again to the status updated by the other writer instead of overwriting it. The
+// update funcs should merge their changes into the status, e.g. with UpdateManagedClusterAddOnStatusFn, rather
+// than replacing the fields owned by the others.
 func UpdateManagedClusterAddOnStatus(
 	ctx context.Context,
 	client addonv1alpha1client.Interface,
@@ -184,9 +393,12 @@ func UpdateManagedClusterAddOnStatus(
 	return updatedAddOnStatus, updated, err
 }
 
-func UpdateManagedClusterAddOnStatusFn(cond metav1.Condition) UpdateManagedClusterAddOnStatusFunc {
+// UpdateManagedClusterAddOnStatusFn sets the conditions of the addon, the other conditions are kept.
+func UpdateManagedClusterAddOnStatusFn(conds ...metav1.Condition) UpdateManagedClusterAddOnStatusFunc {
 	return func(oldStatus *addonv1alpha1.ManagedClusterAddOnStatus) error {
-		meta.SetStatusCondition(&oldStatus.Conditions, cond)
+		for _, cond := range conds {
+			meta.SetStatusCondition(&oldStatus.Conditions, cond)
+		}
 		return nil
 	}
 }
@@ -235,68 +447,6 @@ func IsValidHTTPSURL(serverURL string) bool {
 	return true
 }
 
-// CleanUpManagedClusterManifests clean up managed cluster resources from its manifest files
-func CleanUpManagedClusterManifests(
-	ctx context.Context,
-	client kubernetes.Interface,
-	recorder events.Recorder,
-	assetFunc resourceapply.AssetFunc,
-	files ...string) error {
-	errs := []error{}
-	for _, file := range files {
-		objectRaw, err := assetFunc(file)
-		if err != nil {
-			errs = append(errs, err)
-			continue
-		}
-		object, _, err := genericCodec.Decode(objectRaw, nil, nil)
-		if err != nil {
-			errs = append(errs, err)
-			continue
-		}
-		switch t := object.(type) {
-		case *corev1.Namespace:
-			err = client.CoreV1().Namespaces().Delete(ctx, t.Name, metav1.DeleteOptions{})
-		case *rbacv1.Role:
-			err = client.RbacV1().Roles(t.Namespace).Delete(ctx, t.Name, metav1.DeleteOptions{})
-		case *rbacv1.RoleBinding:
-			err = client.RbacV1().RoleBindings(t.Namespace).Delete(ctx, t.Name, metav1.DeleteOptions{})
-		case *rbacv1.ClusterRole:
-			err = client.RbacV1().ClusterRoles().Delete(ctx, t.Name, metav1.DeleteOptions{})
-		case *rbacv1.ClusterRoleBinding:
-			err = client.RbacV1().ClusterRoleBindings().Delete(ctx, t.Name, metav1.DeleteOptions{})
-		default:
-			err = fmt.Errorf("unhandled type %T", object)
-		}
-		if errors.IsNotFound(err) {
-			continue
-		}
-		if err != nil {
-			errs = append(errs, err)
-			continue
-		}
-		gvk := resourcehelper.GuessObjectGroupVersionKind(object)
-		recorder.Eventf(fmt.Sprintf("ManagedCluster%sDeleted", gvk.Kind), "Deleted %s", resourcehelper.FormatResourceForCLIWithNamespace(object))
-	}
-	return errorhelpers.NewMultiLineAggregate(errs)
-}
-
-func ManagedClusterAssetFn(fs embed.FS, managedClusterName string) resourceapply.AssetFunc {
-	return func(name string) ([]byte, error) {
-		config := struct {
-			ManagedClusterName string
-		}{
-			ManagedClusterName: managedClusterName,
-		}
-
-		template, err := fs.ReadFile(name)
-		if err != nil {
-			return nil, err
-		}
-		return assets.MustCreateAssetFromTemplate(name, template, config).Data, nil
-	}
-}
-
 // FindTaintByKey returns a taint if the managed cluster has a taint with the given key.
 func FindTaintByKey(managedCluster *clusterv1.ManagedCluster, key string) *clusterv1.Taint {
 	if managedCluster == nil {
@@ -355,6 +505,44 @@ func FindTaint(taints []clusterv1.Taint, taint clusterv1.Taint) *clusterv1.Taint
 	return nil
 }
 
+// NewMaintenanceTaint returns the NoSelect taint of a managed cluster in maintenance added at the time.
+func NewMaintenanceTaint(timeAdded metav1.Time) clusterv1.Taint {
+	return clusterv1.Taint{
+		Key:       ManagedClusterTaintMaintenance,
+		Effect:    clusterv1.TaintEffectNoSelect,
+		TimeAdded: timeAdded,
+	}
+}
+
+// IsManagedClusterInMaintenance returns true if the managed cluster is cordoned with the maintenance
+// annotation.
+func IsManagedClusterInMaintenance(managedCluster *clusterv1.ManagedCluster) bool {
+	return managedCluster.Annotations[ManagedClusterMaintenanceAnnotation] == "true"
+}
+
+// CordonManagedCluster sets the maintenance annotation of the managed cluster, so that the hub adds the
+// maintenance taint to it. It returns false if the managed cluster is already cordoned.
+func CordonManagedCluster(managedCluster *clusterv1.ManagedCluster) bool {
+	if IsManagedClusterInMaintenance(managedCluster) {
+		return false
+	}
+	if managedCluster.Annotations == nil {
+		managedCluster.Annotations = map[string]string{}
+	}
+	managedCluster.Annotations[ManagedClusterMaintenanceAnnotation] = "true"
+	return true
+}
+
+// UncordonManagedCluster removes the maintenance annotation of the managed cluster, so that the hub
+// removes the maintenance taint from it. It returns false if the managed cluster is not cordoned.
+func UncordonManagedCluster(managedCluster *clusterv1.ManagedCluster) bool {
+	if _, ok := managedCluster.Annotations[ManagedClusterMaintenanceAnnotation]; !ok {
+		return false
+	}
+	delete(managedCluster.Annotations, ManagedClusterMaintenanceAnnotation)
+	return true
+}
+
 // IsCSRSupported checks whether the cluster supports v1 or v1beta1 csr api.
 func IsCSRSupported(nativeClient kubernetes.Interface) (bool, bool, error) {
 	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(nativeClient.Discovery()))
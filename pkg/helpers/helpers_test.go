@@ -2,7 +2,6 @@ package helpers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"reflect"
 	"testing"
@@ -14,15 +13,12 @@ import (
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
-	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
-
-	corev1 "k8s.io/api/core/v1"
-	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/diff"
-	fakekube "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
 )
 
@@ -214,6 +210,51 @@ func TestUpdateManagedClusterAddOnStatus(t *testing.T) {
 	}
 }
 
+func TestUpdateManagedClusterAddOnStatusOnConflict(t *testing.T) {
+	fakeAddOnClient := addonfake.NewSimpleClientset(&addonv1alpha1.ManagedClusterAddOn{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "test"},
+	})
+	// another writer updates the addon between the get and the patch of the first attempt
+	conflicted := false
+	fakeAddOnClient.PrependReactor("patch", "managedclusteraddons", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if conflicted {
+			return false, nil, nil
+		}
+		conflicted = true
+		addOn, err := fakeAddOnClient.Tracker().Get(addonv1alpha1.SchemeGroupVersion.WithResource("managedclusteraddons"), "test", "test")
+		if err != nil {
+			return true, nil, err
+		}
+		addOn = addOn.DeepCopyObject()
+		addOnStatus := &addOn.(*addonv1alpha1.ManagedClusterAddOn).Status
+		addOnStatus.Conditions = []metav1.Condition{testinghelpers.NewManagedClusterCondition("other", "True", "my-reason", "my-message", nil)}
+		addOnStatus.Registrations = []addonv1alpha1.RegistrationConfig{{SignerName: "other"}}
+		if err := fakeAddOnClient.Tracker().Update(addonv1alpha1.SchemeGroupVersion.WithResource("managedclusteraddons"), addOn, "test"); err != nil {
+			return true, nil, err
+		}
+		return true, nil, errors.NewConflict(addonv1alpha1.Resource("managedclusteraddons"), "test", fmt.Errorf("the object has been modified"))
+	})
+
+	status, updated, err := UpdateManagedClusterAddOnStatus(
+		context.TODO(),
+		fakeAddOnClient,
+		"test", "test",
+		UpdateManagedClusterAddOnStatusFn(testinghelpers.NewManagedClusterCondition("one", "True", "my-reason", "my-message", nil)),
+	)
+	if err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+	if !updated {
+		t.Errorf("expected the addon status updated")
+	}
+	if !meta.IsStatusConditionTrue(status.Conditions, "other") || !meta.IsStatusConditionTrue(status.Conditions, "one") {
+		t.Errorf("expected the conditions of both writers, but got %v", status.Conditions)
+	}
+	if len(status.Registrations) != 1 {
+		t.Errorf("expected the registrations of the other writer, but got %v", status.Registrations)
+	}
+}
+
 func TestIsValidHTTPSURL(t *testing.T) {
 	cases := []struct {
 		name      string
@@ -252,76 +293,6 @@ func TestIsValidHTTPSURL(t *testing.T) {
 	}
 }
 
-func TestCleanUpManagedClusterManifests(t *testing.T) {
-	applyFiles := map[string]runtime.Object{
-		"namespace":          testinghelpers.NewUnstructuredObj("v1", "Namespace", "", "n1"),
-		"clusterrole":        testinghelpers.NewUnstructuredObj("rbac.authorization.k8s.io/v1", "ClusterRole", "", "cr1"),
-		"clusterrolebinding": testinghelpers.NewUnstructuredObj("rbac.authorization.k8s.io/v1", "ClusterRoleBinding", "", "crb1"),
-		"role":               testinghelpers.NewUnstructuredObj("rbac.authorization.k8s.io/v1", "Role", "n1", "r1"),
-		"rolebinding":        testinghelpers.NewUnstructuredObj("rbac.authorization.k8s.io/v1", "RoleBinding", "n1", "rb1"),
-	}
-	expectedActions := []string{}
-	for i := 0; i < len(applyFiles); i++ {
-		expectedActions = append(expectedActions, "delete")
-	}
-	cases := []struct {
-		name            string
-		applyObject     []runtime.Object
-		applyFiles      map[string]runtime.Object
-		validateActions func(t *testing.T, actions []clienttesting.Action)
-		expectedErr     string
-	}{
-		{
-			name: "delete applied objects",
-			applyObject: []runtime.Object{
-				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "n1"}},
-				&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "cr1"}},
-				&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "crb1"}},
-				&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "r1", Namespace: "n1"}},
-				&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "rb1", Namespace: "n1"}},
-			},
-			applyFiles: applyFiles,
-			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, expectedActions...)
-			},
-		},
-		{
-			name:        "there are no applied objects",
-			applyObject: []runtime.Object{},
-			applyFiles:  applyFiles,
-			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, expectedActions...)
-			},
-		},
-		{
-			name:            "unhandled types",
-			applyObject:     []runtime.Object{},
-			applyFiles:      map[string]runtime.Object{"secret": testinghelpers.NewUnstructuredObj("v1", "Secret", "n1", "s1")},
-			expectedErr:     "unhandled type *v1.Secret",
-			validateActions: testinghelpers.AssertNoActions,
-		},
-	}
-	for _, c := range cases {
-		t.Run(c.name, func(t *testing.T) {
-			kubeClient := fakekube.NewSimpleClientset(c.applyObject...)
-			cleanUpErr := CleanUpManagedClusterManifests(
-				context.TODO(),
-				kubeClient,
-				eventstesting.NewTestingEventRecorder(t),
-				func(name string) ([]byte, error) {
-					if c.applyFiles[name] == nil {
-						return nil, fmt.Errorf("Failed to find file")
-					}
-					return json.Marshal(c.applyFiles[name])
-				},
-				getApplyFileNames(c.applyFiles)...,
-			)
-			testinghelpers.AssertError(t, cleanUpErr, c.expectedErr)
-			c.validateActions(t, kubeClient.Actions())
-		})
-	}
-}
-
 func TestFindTaintByKey(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -376,14 +347,6 @@ func TestFindTaintByKey(t *testing.T) {
 	}
 }
 
-func getApplyFileNames(applyFiles map[string]runtime.Object) []string {
-	keys := []string{}
-	for key := range applyFiles {
-		keys = append(keys, key)
-	}
-	return keys
-}
-
 var (
 	UnavailableTaint = clusterv1.Taint{
 		Key:    clusterv1.ManagedClusterTaintUnavailable,
@@ -483,6 +446,65 @@ func TestAddTaints(t *testing.T) {
 	}
 }
 
+func TestCordonManagedCluster(t *testing.T) {
+	cases := []struct {
+		name                string
+		annotations         map[string]string
+		cordon              bool
+		expectedUpdated     bool
+		expectedMaintenance bool
+	}{
+		{
+			name:                "cordon a cluster without annotations",
+			cordon:              true,
+			expectedUpdated:     true,
+			expectedMaintenance: true,
+		},
+		{
+			name:                "cordon a cordoned cluster",
+			annotations:         map[string]string{ManagedClusterMaintenanceAnnotation: "true"},
+			cordon:              true,
+			expectedMaintenance: true,
+		},
+		{
+			name:                "cordon a cluster with the annotation false",
+			annotations:         map[string]string{ManagedClusterMaintenanceAnnotation: "false"},
+			cordon:              true,
+			expectedUpdated:     true,
+			expectedMaintenance: true,
+		},
+		{
+			name:            "uncordon a cordoned cluster",
+			annotations:     map[string]string{ManagedClusterMaintenanceAnnotation: "true"},
+			expectedUpdated: true,
+		},
+		{
+			name:        "uncordon a cluster without annotations",
+			annotations: map[string]string{"foo": "bar"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{}
+			cluster.Annotations = c.annotations
+
+			var updated bool
+			if c.cordon {
+				updated = CordonManagedCluster(cluster)
+			} else {
+				updated = UncordonManagedCluster(cluster)
+			}
+			if updated != c.expectedUpdated {
+				t.Errorf("updated expected %t, but %t", c.expectedUpdated, updated)
+			}
+			if maintenance := IsManagedClusterInMaintenance(cluster); maintenance != c.expectedMaintenance {
+				t.Errorf("maintenance expected %t, but %t", c.expectedMaintenance, maintenance)
+			}
+		})
+	}
+}
+
 func TestRemoveTaints(t *testing.T) {
 	cases := []struct {
 		name          string
@@ -0,0 +1,313 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcehelper"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	errorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+)
+
+// ManifestClientHolder holds the clients to apply and clean up the resources in the manifest files of
+// managed clusters. The dynamic client and the rest mapper are only required by the resources which
+// are not handled by the kube client, e.g. custom resources.
+type ManifestClientHolder struct {
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+	restMapper    meta.RESTMapper
+	fieldManager  string
+	getExisting   ExistingObjectFunc
+}
+
+// ExistingObjectFunc returns the existing resource of the required object, e.g. from an informer cache. Only
+// the metadata of the existing resource is required.
+type ExistingObjectFunc func(required runtime.Object) (runtime.Object, error)
+
+// NewManifestClientHolder returns a ManifestClientHolder with the kube client.
+func NewManifestClientHolder(kubeClient kubernetes.Interface) *ManifestClientHolder {
+	return &ManifestClientHolder{kubeClient: kubeClient}
+}
+
+// WithDynamicClient sets the dynamic client and the rest mapper which are used to apply and clean up
+// the resources which are not handled by the kube client.
+func (h *ManifestClientHolder) WithDynamicClient(dynamicClient dynamic.Interface, restMapper meta.RESTMapper) *ManifestClientHolder {
+	h.dynamicClient = dynamicClient
+	h.restMapper = restMapper
+	return h
+}
+
+// WithFieldManager makes the resources applied with server-side apply by the field manager, so that only
+// the fields in the manifest files are owned and corrected, and the fields added by others, e.g. the
+// labels and annotations added by users, are kept.
+func (h *ManifestClientHolder) WithFieldManager(fieldManager string) *ManifestClientHolder {
+	h.fieldManager = fieldManager
+	return h
+}
+
+// WithExistingObjectFunc sets the func to get the existing resources applied with server-side apply. An
+// existing resource which is not changed since it was applied last time is skipped, and whether a resource
+// is changed is reported.
+func (h *ManifestClientHolder) WithExistingObjectFunc(getExisting ExistingObjectFunc) *ManifestClientHolder {
+	h.getExisting = getExisting
+	return h
+}
+
+// ApplyManagedClusterManifests applies the resources in the manifest files with resourceapply.ApplyDirectly.
+// If the field manager of the clients is set, the resources are applied with server-side apply instead, the cache is only used if the existing resources can be got, see
+// ManifestClientHolder.WithExistingObjectFunc. The resources which are not handled by the kube client, e.g. custom
+// resources, are always applied with server-side apply by the dynamic client, so the field manager is required.
+func ApplyManagedClusterManifests(
+	ctx context.Context,
+	clients *ManifestClientHolder,
+	recorder events.Recorder,
+	cache resourceapply.ResourceCache,
+	assetFunc resourceapply.AssetFunc,
+	files ...string) []resourceapply.ApplyResult {
+	results := []resourceapply.ApplyResult{}
+	for _, file := range files {
+		objectRaw, err := assetFunc(file)
+		if err != nil {
+			results = append(results, resourceapply.ApplyResult{File: file, Error: fmt.Errorf("missing %q: %v", file, err)})
+			continue
+		}
+		object, err := resourceread.ReadGenericWithUnstructured(objectRaw)
+		if err != nil {
+			results = append(results, resourceapply.ApplyResult{File: file, Error: fmt.Errorf("cannot decode %q: %v", file, err)})
+			continue
+		}
+
+		if _, ok := object.(*unstructured.Unstructured); ok || len(clients.fieldManager) != 0 {
+			result := resourceapply.ApplyResult{File: file, Type: fmt.Sprintf("%T", object)}
+			result.Result, result.Changed, result.Error = clients.serverSideApply(ctx, recorder, cache, object, objectRaw)
+			results = append(results, result)
+			continue
+		}
+
+		results = append(results, resourceapply.ApplyDirectly(
+			ctx,
+			resourceapply.NewKubeClientHolder(clients.kubeClient),
+			recorder,
+			cache,
+			func(string) ([]byte, error) { return objectRaw, nil },
+			file,
+		)...)
+	}
+	return results
+}
+
+// CleanUpManagedClusterManifests clean up managed cluster resources from its manifest files
+func CleanUpManagedClusterManifests(
+	ctx context.Context,
+	clients *ManifestClientHolder,
+	recorder events.Recorder,
+	assetFunc resourceapply.AssetFunc,
+	files ...string) error {
+	errs := []error{}
+	for _, file := range files {
+		objectRaw, err := assetFunc(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		object, err := resourceread.ReadGenericWithUnstructured(objectRaw)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		switch t := object.(type) {
+		case *corev1.Namespace:
+			err = clients.kubeClient.CoreV1().Namespaces().Delete(ctx, t.Name, metav1.DeleteOptions{})
+		case *corev1.Secret:
+			err = clients.kubeClient.CoreV1().Secrets(t.Namespace).Delete(ctx, t.Name, metav1.DeleteOptions{})
+		case *corev1.ConfigMap:
+			err = clients.kubeClient.CoreV1().ConfigMaps(t.Namespace).Delete(ctx, t.Name, metav1.DeleteOptions{})
+		case *corev1.ServiceAccount:
+			err = clients.kubeClient.CoreV1().ServiceAccounts(t.Namespace).Delete(ctx, t.Name, metav1.DeleteOptions{})
+		case *rbacv1.Role:
+			err = clients.kubeClient.RbacV1().Roles(t.Namespace).Delete(ctx, t.Name, metav1.DeleteOptions{})
+		case *rbacv1.RoleBinding:
+			err = clients.kubeClient.RbacV1().RoleBindings(t.Namespace).Delete(ctx, t.Name, metav1.DeleteOptions{})
+		case *rbacv1.ClusterRole:
+			err = clients.kubeClient.RbacV1().ClusterRoles().Delete(ctx, t.Name, metav1.DeleteOptions{})
+		case *rbacv1.ClusterRoleBinding:
+			err = clients.kubeClient.RbacV1().ClusterRoleBindings().Delete(ctx, t.Name, metav1.DeleteOptions{})
+		case *unstructured.Unstructured:
+			var client dynamic.ResourceInterface
+			client, err = clients.resourceClient(t)
+			if err == nil {
+				err = client.Delete(ctx, t.GetName(), metav1.DeleteOptions{})
+			}
+		default:
+			err = fmt.Errorf("unhandled type %T", object)
+		}
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		gvk := resourcehelper.GuessObjectGroupVersionKind(object)
+		recorder.Eventf(fmt.Sprintf("ManagedCluster%sDeleted", gvk.Kind), "Deleted %s", resourcehelper.FormatResourceForCLIWithNamespace(object))
+	}
+	return errorhelpers.NewMultiLineAggregate(errs)
+}
+
+// serverSideApply applies the object with server-side apply, the raw manifest is used as the apply
+// configuration so that only the fields in it are owned by the field manager. The conflicts with the
+// other field managers are forced, so that the drift of the owned fields is corrected. The apply is
+// skipped if neither the manifest nor the existing resource is changed since the last apply.
+func (h *ManifestClientHolder) serverSideApply(
+	ctx context.Context,
+	recorder events.Recorder,
+	cache resourceapply.ResourceCache,
+	object runtime.Object,
+	objectRaw []byte) (runtime.Object, bool, error) {
+	var existing runtime.Object
+	if h.getExisting != nil {
+		var err error
+		existing, err = h.getExisting(object)
+		switch {
+		case errors.IsNotFound(err):
+			existing = nil
+		case err != nil:
+			return nil, false, err
+		case cache != nil && cache.SafeToSkipApply(object, existing):
+			return existing, false, nil
+		}
+	}
+
+	applied, err := h.applyPatch(ctx, object, objectRaw)
+	if err != nil {
+		return nil, false, err
+	}
+	if cache != nil {
+		cache.UpdateCachedResourceMetadata(object, applied)
+	}
+	if h.getExisting == nil {
+		return applied, false, nil
+	}
+
+	gvk := resourcehelper.GuessObjectGroupVersionKind(object)
+	if existing == nil {
+		recorder.Eventf(fmt.Sprintf("%sCreated", gvk.Kind), "Created %s because it was missing",
+			resourcehelper.FormatResourceForCLIWithNamespace(object))
+		return applied, true, nil
+	}
+	existingAccessor, err := meta.Accessor(existing)
+	if err != nil {
+		return nil, false, err
+	}
+	appliedAccessor, err := meta.Accessor(applied)
+	if err != nil {
+		return nil, false, err
+	}
+	if existingAccessor.GetResourceVersion() == appliedAccessor.GetResourceVersion() {
+		return applied, false, nil
+	}
+	recorder.Eventf(fmt.Sprintf("%sUpdated", gvk.Kind), "Updated %s because it changed",
+		resourcehelper.FormatResourceForCLIWithNamespace(object))
+	return applied, true, nil
+}
+
+// applyPatch sends the raw manifest as an apply patch with the client of the object.
+func (h *ManifestClientHolder) applyPatch(ctx context.Context, object runtime.Object, objectRaw []byte) (runtime.Object, error) {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return nil, err
+	}
+	data, err := yaml.YAMLToJSON(objectRaw)
+	if err != nil {
+		return nil, err
+	}
+	name, namespace := accessor.GetName(), accessor.GetNamespace()
+	opts := metav1.PatchOptions{FieldManager: h.fieldManager, Force: pointer.Bool(true)}
+
+	switch t := object.(type) {
+	case *corev1.Namespace:
+		return h.kubeClient.CoreV1().Namespaces().Patch(ctx, name, types.ApplyPatchType, data, opts)
+	case *corev1.Secret:
+		return h.kubeClient.CoreV1().Secrets(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts)
+	case *corev1.ConfigMap:
+		return h.kubeClient.CoreV1().ConfigMaps(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts)
+	case *corev1.ServiceAccount:
+		return h.kubeClient.CoreV1().ServiceAccounts(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts)
+	case *rbacv1.Role:
+		return h.kubeClient.RbacV1().Roles(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts)
+	case *rbacv1.RoleBinding:
+		return h.kubeClient.RbacV1().RoleBindings(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts)
+	case *rbacv1.ClusterRole:
+		return h.kubeClient.RbacV1().ClusterRoles().Patch(ctx, name, types.ApplyPatchType, data, opts)
+	case *rbacv1.ClusterRoleBinding:
+		return h.kubeClient.RbacV1().ClusterRoleBindings().Patch(ctx, name, types.ApplyPatchType, data, opts)
+	case *unstructured.Unstructured:
+		if len(h.fieldManager) == 0 {
+			return nil, fmt.Errorf("the field manager is required to apply %s",
+				resourcehelper.FormatResourceForCLIWithNamespace(object))
+		}
+		client, err := h.resourceClient(t)
+		if err != nil {
+			return nil, err
+		}
+		return client.Patch(ctx, name, types.ApplyPatchType, data, opts)
+	default:
+		return nil, fmt.Errorf("unhandled type %T", object)
+	}
+}
+
+// resourceClient returns the dynamic client of the resource of the object, the resource is resolved with the
+// rest mapper.
+func (h *ManifestClientHolder) resourceClient(object *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	if h.dynamicClient == nil || h.restMapper == nil {
+		return nil, fmt.Errorf("unhandled type %T without the dynamic client", object)
+	}
+
+	gvk := object.GroupVersionKind()
+	mapping, err := h.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return h.dynamicClient.Resource(mapping.Resource).Namespace(object.GetNamespace()), nil
+	}
+	return h.dynamicClient.Resource(mapping.Resource), nil
+}
+
+// NewResourceCache returns a resource cache which is safe to be shared by the workers of a controller.
+func NewResourceCache() resourceapply.ResourceCache {
+	return &syncResourceCache{cache: resourceapply.NewResourceCache()}
+}
+
+type syncResourceCache struct {
+	lock  sync.Mutex
+	cache resourceapply.ResourceCache
+}
+
+func (c *syncResourceCache) UpdateCachedResourceMetadata(required runtime.Object, actual runtime.Object) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cache.UpdateCachedResourceMetadata(required, actual)
+}
+
+func (c *syncResourceCache) SafeToSkipApply(required runtime.Object, existing runtime.Object) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cache.SafeToSkipApply(required, existing)
+}
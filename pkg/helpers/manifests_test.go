@@ -0,0 +1,392 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newTestRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "test.open-cluster-management.io", Version: "v1", Kind: "Foo"}, meta.RESTScopeNamespace)
+	return mapper
+}
+
+func newFoo(spec map[string]interface{}) *unstructured.Unstructured {
+	foo := testinghelpers.NewUnstructuredObj("test.open-cluster-management.io/v1", "Foo", "n1", "foo1")
+	if spec != nil {
+		foo.Object["spec"] = spec
+	}
+	return foo
+}
+
+func TestApplyManagedClusterManifests(t *testing.T) {
+	cases := []struct {
+		name                   string
+		applyFile              runtime.Object
+		kubeObjects            []runtime.Object
+		dynamicObjects         []runtime.Object
+		withDynamicClient      bool
+		fieldManager           string
+		expectedChanged        bool
+		expectedErr            string
+		validateKubeActions    func(t *testing.T, actions []clienttesting.Action)
+		validateDynamicActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "apply a kind supported by the kube client",
+			applyFile:       testinghelpers.NewUnstructuredObj("v1", "Secret", "n1", "s1"),
+			expectedChanged: true,
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create")
+			},
+			validateDynamicActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:                   "apply a kind unsupported by the kube client without field manager",
+			applyFile:              newFoo(nil),
+			withDynamicClient:      true,
+			expectedErr:            "the field manager is required to apply Foo.test.open-cluster-management.io/foo1 -n n1",
+			validateKubeActions:    testinghelpers.AssertNoActions,
+			validateDynamicActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name: "apply a kind supported by the kube client with server-side apply",
+			applyFile: func() runtime.Object {
+				secret := testinghelpers.NewUnstructuredObj("v1", "Secret", "n1", "s1")
+				secret.SetLabels(map[string]string{"owned": "true"})
+				return secret
+			}(),
+			kubeObjects: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "n1", Name: "s1", Labels: map[string]string{"user": "true"}},
+			}},
+			fieldManager: "test",
+			validateKubeActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := actions[0].(clienttesting.PatchActionImpl)
+				if patch.PatchType != types.ApplyPatchType {
+					t.Errorf("expected apply patch, but got %q", patch.PatchType)
+				}
+				secret := &corev1.Secret{}
+				if err := json.Unmarshal(patch.Patch, secret); err != nil {
+					t.Fatal(err)
+				}
+				if len(secret.Labels) != 1 || secret.Labels["owned"] != "true" {
+					t.Errorf("expected only the labels in the manifest are applied, but got %v", secret.Labels)
+				}
+			},
+			validateDynamicActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:                   "apply a kind unsupported by the kube client without dynamic client",
+			applyFile:              newFoo(nil),
+			fieldManager:           "test",
+			expectedErr:            "unhandled type *unstructured.Unstructured without the dynamic client",
+			validateKubeActions:    testinghelpers.AssertNoActions,
+			validateDynamicActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:                "apply a custom resource with server-side apply",
+			applyFile:           newFoo(map[string]interface{}{"replicas": int64(2)}),
+			dynamicObjects:      []runtime.Object{newFoo(map[string]interface{}{"replicas": int64(1)})},
+			withDynamicClient:   true,
+			fieldManager:        "test",
+			validateKubeActions: testinghelpers.AssertNoActions,
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := actions[0].(clienttesting.PatchActionImpl)
+				if patch.PatchType != types.ApplyPatchType {
+					t.Errorf("expected apply patch, but got %q", patch.PatchType)
+				}
+				if patch.GetResource().Resource != "foos" || patch.GetNamespace() != "n1" || patch.GetName() != "foo1" {
+					t.Errorf("expected foos n1/foo1 is applied, but got %s %s/%s",
+						patch.GetResource().Resource, patch.GetNamespace(), patch.GetName())
+				}
+				applied := &unstructured.Unstructured{}
+				if err := applied.UnmarshalJSON(patch.Patch); err != nil {
+					t.Fatal(err)
+				}
+				if replicas, _, _ := unstructured.NestedInt64(applied.Object, "spec", "replicas"); replicas != 2 {
+					t.Errorf("expected replicas 2, but got %d", replicas)
+				}
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := fakekube.NewSimpleClientset(c.kubeObjects...)
+			dynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), c.dynamicObjects...)
+			// the fake dynamic client is unable to merge an apply patch into an unstructured object
+			dynamicClient.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				applied := &unstructured.Unstructured{}
+				if err := applied.UnmarshalJSON(action.(clienttesting.PatchActionImpl).Patch); err != nil {
+					return true, nil, err
+				}
+				return true, applied, nil
+			})
+			clients := NewManifestClientHolder(kubeClient).WithFieldManager(c.fieldManager)
+			if c.withDynamicClient {
+				clients = clients.WithDynamicClient(dynamicClient, newTestRESTMapper())
+			}
+
+			results := ApplyManagedClusterManifests(
+				context.TODO(),
+				clients,
+				eventstesting.NewTestingEventRecorder(t),
+				resourceapply.NewResourceCache(),
+				func(name string) ([]byte, error) {
+					return json.Marshal(c.applyFile)
+				},
+				"manifest",
+			)
+			if len(results) != 1 {
+				t.Fatalf("expected 1 result, but got %d", len(results))
+			}
+			testinghelpers.AssertError(t, results[0].Error, c.expectedErr)
+			if results[0].Changed != c.expectedChanged {
+				t.Errorf("expected changed %t, but got %t", c.expectedChanged, results[0].Changed)
+			}
+			c.validateKubeActions(t, kubeClient.Actions())
+			c.validateDynamicActions(t, dynamicClient.Actions())
+		})
+	}
+}
+
+func TestServerSideApplyWithExistingObjects(t *testing.T) {
+	cases := []struct {
+		name            string
+		existing        runtime.Object
+		cached          runtime.Object
+		expectedChanged bool
+		expectedActions []string
+	}{
+		{
+			name:            "create a missing resource",
+			expectedChanged: true,
+			expectedActions: []string{"patch"},
+		},
+		{
+			name:            "skip an unchanged resource",
+			existing:        newSecretMetadata("1"),
+			cached:          newSecretMetadata("1"),
+			expectedActions: []string{},
+		},
+		{
+			name:            "update a resource changed since the last apply",
+			existing:        newSecretMetadata("2"),
+			cached:          newSecretMetadata("1"),
+			expectedChanged: true,
+			expectedActions: []string{"patch"},
+		},
+		{
+			name:            "apply a resource which is not cached",
+			existing:        newSecretMetadata("3"),
+			expectedActions: []string{"patch"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := fakekube.NewSimpleClientset()
+			// the fake kube client is unable to apply a resource which does not exist
+			kubeClient.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				applied := &corev1.Secret{}
+				if err := json.Unmarshal(action.(clienttesting.PatchActionImpl).Patch, applied); err != nil {
+					return true, nil, err
+				}
+				applied.ResourceVersion = "3"
+				return true, applied, nil
+			})
+			clients := NewManifestClientHolder(kubeClient).
+				WithFieldManager("test").
+				WithExistingObjectFunc(func(required runtime.Object) (runtime.Object, error) {
+					if c.existing == nil {
+						return nil, errors.NewNotFound(corev1.Resource("secrets"), "s1")
+					}
+					return c.existing, nil
+				})
+
+			required := &corev1.Secret{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: "n1", Name: "s1"},
+			}
+			requiredRaw, err := json.Marshal(required)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cache := NewResourceCache()
+			if c.cached != nil {
+				cache.UpdateCachedResourceMetadata(required, c.cached)
+			}
+
+			results := ApplyManagedClusterManifests(
+				context.TODO(),
+				clients,
+				eventstesting.NewTestingEventRecorder(t),
+				cache,
+				func(name string) ([]byte, error) { return requiredRaw, nil },
+				"manifest",
+			)
+			if len(results) != 1 {
+				t.Fatalf("expected 1 result, but got %d", len(results))
+			}
+			testinghelpers.AssertError(t, results[0].Error, "")
+			if results[0].Changed != c.expectedChanged {
+				t.Errorf("expected changed %t, but got %t", c.expectedChanged, results[0].Changed)
+			}
+			testinghelpers.AssertActions(t, kubeClient.Actions(), c.expectedActions...)
+		})
+	}
+}
+
+func newSecretMetadata(resourceVersion string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "n1", Name: "s1", ResourceVersion: resourceVersion},
+	}
+}
+
+func TestCleanUpManagedClusterManifests(t *testing.T) {
+	applyFiles := map[string]runtime.Object{
+		"namespace":          testinghelpers.NewUnstructuredObj("v1", "Namespace", "", "n1"),
+		"secret":             testinghelpers.NewUnstructuredObj("v1", "Secret", "n1", "s1"),
+		"configmap":          testinghelpers.NewUnstructuredObj("v1", "ConfigMap", "n1", "cm1"),
+		"serviceaccount":     testinghelpers.NewUnstructuredObj("v1", "ServiceAccount", "n1", "sa1"),
+		"clusterrole":        testinghelpers.NewUnstructuredObj("rbac.authorization.k8s.io/v1", "ClusterRole", "", "cr1"),
+		"clusterrolebinding": testinghelpers.NewUnstructuredObj("rbac.authorization.k8s.io/v1", "ClusterRoleBinding", "", "crb1"),
+		"role":               testinghelpers.NewUnstructuredObj("rbac.authorization.k8s.io/v1", "Role", "n1", "r1"),
+		"rolebinding":        testinghelpers.NewUnstructuredObj("rbac.authorization.k8s.io/v1", "RoleBinding", "n1", "rb1"),
+	}
+	expectedActions := []string{}
+	for i := 0; i < len(applyFiles); i++ {
+		expectedActions = append(expectedActions, "delete")
+	}
+	cases := []struct {
+		name                   string
+		applyObject            []runtime.Object
+		dynamicObjects         []runtime.Object
+		withDynamicClient      bool
+		applyFiles             map[string]runtime.Object
+		validateActions        func(t *testing.T, actions []clienttesting.Action)
+		validateDynamicActions func(t *testing.T, actions []clienttesting.Action)
+		expectedErr            string
+	}{
+		{
+			name: "delete applied objects",
+			applyObject: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "n1"}},
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "s1", Namespace: "n1"}},
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "n1"}},
+				&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "sa1", Namespace: "n1"}},
+				&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "cr1"}},
+				&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "crb1"}},
+				&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "r1", Namespace: "n1"}},
+				&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "rb1", Namespace: "n1"}},
+			},
+			applyFiles: applyFiles,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, expectedActions...)
+			},
+			validateDynamicActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:        "there are no applied objects",
+			applyObject: []runtime.Object{},
+			applyFiles:  applyFiles,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, expectedActions...)
+			},
+			validateDynamicActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:                   "unhandled types",
+			applyObject:            []runtime.Object{},
+			applyFiles:             map[string]runtime.Object{"service": testinghelpers.NewUnstructuredObj("v1", "Service", "n1", "s1")},
+			expectedErr:            "unhandled type *v1.Service",
+			validateActions:        testinghelpers.AssertNoActions,
+			validateDynamicActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:                   "delete a custom resource without dynamic client",
+			applyObject:            []runtime.Object{},
+			applyFiles:             map[string]runtime.Object{"foo": newFoo(nil)},
+			expectedErr:            "unhandled type *unstructured.Unstructured without the dynamic client",
+			validateActions:        testinghelpers.AssertNoActions,
+			validateDynamicActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:              "delete a custom resource",
+			applyObject:       []runtime.Object{},
+			dynamicObjects:    []runtime.Object{newFoo(nil)},
+			withDynamicClient: true,
+			applyFiles:        map[string]runtime.Object{"foo": newFoo(nil)},
+			validateActions:   testinghelpers.AssertNoActions,
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+				if resource := actions[0].GetResource().Resource; resource != "foos" {
+					t.Errorf("expected foos is deleted, but got %s", resource)
+				}
+			},
+		},
+		{
+			name:              "custom resource is not found",
+			applyObject:       []runtime.Object{},
+			withDynamicClient: true,
+			applyFiles:        map[string]runtime.Object{"foo": newFoo(nil)},
+			validateActions:   testinghelpers.AssertNoActions,
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "delete")
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := fakekube.NewSimpleClientset(c.applyObject...)
+			dynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), c.dynamicObjects...)
+			clients := NewManifestClientHolder(kubeClient)
+			if c.withDynamicClient {
+				clients = clients.WithDynamicClient(dynamicClient, newTestRESTMapper())
+			}
+			cleanUpErr := CleanUpManagedClusterManifests(
+				context.TODO(),
+				clients,
+				eventstesting.NewTestingEventRecorder(t),
+				func(name string) ([]byte, error) {
+					if c.applyFiles[name] == nil {
+						return nil, fmt.Errorf("Failed to find file")
+					}
+					return json.Marshal(c.applyFiles[name])
+				},
+				getApplyFileNames(c.applyFiles)...,
+			)
+			testinghelpers.AssertError(t, cleanUpErr, c.expectedErr)
+			c.validateActions(t, kubeClient.Actions())
+			c.validateDynamicActions(t, dynamicClient.Actions())
+		})
+	}
+}
+
+func getApplyFileNames(applyFiles map[string]runtime.Object) []string {
+	keys := []string{}
+	for key := range applyFiles {
+		keys = append(keys, key)
+	}
+	return keys
+}
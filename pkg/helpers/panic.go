@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// PanicPolicyCrash crashes the process once a controller panics, so that it is restarted, it is the default.
+	PanicPolicyCrash = "crash"
+	// PanicPolicyRecover recovers a controller from a panic, the sync panicking fails and is retried with backoff.
+	PanicPolicyRecover = "recover"
+)
+
+var controllerPanicsTotal = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Name: "open_cluster_management_registration_controller_panics_total",
+		Help: "The number of the panics of the syncs of the controllers, by the panic policy.",
+	},
+	[]string{"controller", "policy"},
+)
+
+func init() {
+	legacyregistry.MustRegister(controllerPanicsTotal)
+}
+
+var recoverControllerPanics atomic.Bool
+
+// SetControllerPanicPolicy sets the policy of the syncs wrapped with RecoverSync once they panic, either
+// PanicPolicyCrash or PanicPolicyRecover.
+func SetControllerPanicPolicy(policy string) error {
+	switch policy {
+	case PanicPolicyCrash:
+		recoverControllerPanics.Store(false)
+	case PanicPolicyRecover:
+		recoverControllerPanics.Store(true)
+	default:
+		return fmt.Errorf("unsupported controller panic policy %q", policy)
+	}
+	return nil
+}
+
+// RecoverSync wraps the sync of the controller, so that a panic is counted and recorded as an event before the
+// process crashes, or is returned as an error of the sync once the panics are recovered, see SetControllerPanicPolicy.
+func RecoverSync(controllerName string, sync factory.SyncFunc) factory.SyncFunc {
+	return func(ctx context.Context, syncCtx factory.SyncContext) (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+
+			policy := PanicPolicyCrash
+			if recoverControllerPanics.Load() {
+				policy = PanicPolicyRecover
+			}
+			controllerPanicsTotal.WithLabelValues(controllerName, policy).Inc()
+			klog.Errorf("Controller %s panicked on %q: %v\n%s", controllerName, syncCtx.QueueKey(), r, debug.Stack())
+			syncCtx.Recorder().Warningf("ControllerPanic", "Controller %s panicked on %q: %v", controllerName, syncCtx.QueueKey(), r)
+			if policy == PanicPolicyCrash {
+				panic(r)
+			}
+			err = fmt.Errorf("controller %s panicked: %v", controllerName, r)
+		}()
+		return sync(ctx, syncCtx)
+	}
+}
@@ -0,0 +1,101 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestRecoverSync(t *testing.T) {
+	cases := []struct {
+		name            string
+		policy          string
+		sync            factory.SyncFunc
+		expectedErr     string
+		expectedPanic   bool
+		expectedCounted bool
+	}{
+		{
+			name:   "sync succeeds",
+			policy: PanicPolicyRecover,
+			sync: func(ctx context.Context, syncCtx factory.SyncContext) error {
+				return nil
+			},
+		},
+		{
+			name:   "sync fails",
+			policy: PanicPolicyRecover,
+			sync: func(ctx context.Context, syncCtx factory.SyncContext) error {
+				return errors.New("failed")
+			},
+			expectedErr: "failed",
+		},
+		{
+			name:   "recover from panic",
+			policy: PanicPolicyRecover,
+			sync: func(ctx context.Context, syncCtx factory.SyncContext) error {
+				panic("boom")
+			},
+			expectedErr:     "controller TestController panicked: boom",
+			expectedCounted: true,
+		},
+		{
+			name:   "crash on panic",
+			policy: PanicPolicyCrash,
+			sync: func(ctx context.Context, syncCtx factory.SyncContext) error {
+				panic("boom")
+			},
+			expectedPanic:   true,
+			expectedCounted: true,
+		},
+	}
+
+	defer func() {
+		if err := SetControllerPanicPolicy(PanicPolicyCrash); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := SetControllerPanicPolicy(c.policy); err != nil {
+				t.Fatal(err)
+			}
+			counter := controllerPanicsTotal.WithLabelValues("TestController", c.policy)
+			before, err := testutil.GetCounterMetricValue(counter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			panicked := false
+			func() {
+				defer func() {
+					panicked = recover() != nil
+				}()
+				err = RecoverSync("TestController", c.sync)(context.TODO(), testinghelpers.NewFakeSyncContext(t, "key"))
+			}()
+			if panicked != c.expectedPanic {
+				t.Errorf("expected panic %v, but got %v", c.expectedPanic, panicked)
+			}
+			if !panicked {
+				testinghelpers.AssertError(t, err, c.expectedErr)
+			}
+
+			after, err := testutil.GetCounterMetricValue(counter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counted := after > before; counted != c.expectedCounted {
+				t.Errorf("expected the panic counted %v, but got %v", c.expectedCounted, counted)
+			}
+		})
+	}
+}
+
+func TestSetControllerPanicPolicy(t *testing.T) {
+	testinghelpers.AssertError(t, SetControllerPanicPolicy("ignore"), "unsupported controller panic policy \"ignore\"")
+}
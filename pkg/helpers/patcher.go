@@ -0,0 +1,128 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// ClusterPatcher patches the ManagedClusters on the hub. Each patch is a json merge patch of the changed
+// fields only, with the uid and the resourceVersion of the ManagedCluster as the preconditions, and it is
+// recomputed from the latest ManagedCluster and retried on conflicts, so the changes of the concurrent
+// writers are never overwritten.
+type ClusterPatcher struct {
+	client clusterclientset.Interface
+}
+
+// NewClusterPatcher returns a ClusterPatcher with the cluster client of the hub.
+func NewClusterPatcher(client clusterclientset.Interface) *ClusterPatcher {
+	return &ClusterPatcher{client: client}
+}
+
+// PatchLabels merges the labels into the labels of the ManagedCluster, a key with the suffix "-" removes
+// the label. It returns true if the ManagedCluster is patched.
+func (p *ClusterPatcher) PatchLabels(ctx context.Context, cluster *clusterv1.ManagedCluster, labels map[string]string) (bool, error) {
+	return p.patch(ctx, cluster, func(cluster *clusterv1.ManagedCluster) {
+		modified := false
+		resourcemerge.MergeMap(&modified, &cluster.Labels, labels)
+	})
+}
+
+// PatchAnnotations merges the annotations into the annotations of the ManagedCluster, a key with the suffix
+// "-" removes the annotation. It returns true if the ManagedCluster is patched.
+func (p *ClusterPatcher) PatchAnnotations(ctx context.Context, cluster *clusterv1.ManagedCluster, annotations map[string]string) (bool, error) {
+	return p.patch(ctx, cluster, func(cluster *clusterv1.ManagedCluster) {
+		modified := false
+		resourcemerge.MergeMap(&modified, &cluster.Annotations, annotations)
+	})
+}
+
+// PatchTaints removes the taints in remove from the taints of the ManagedCluster and adds the taints in add
+// to them. It returns true if the ManagedCluster is patched.
+func (p *ClusterPatcher) PatchTaints(ctx context.Context, cluster *clusterv1.ManagedCluster, add, remove []clusterv1.Taint) (bool, error) {
+	return p.patch(ctx, cluster, func(cluster *clusterv1.ManagedCluster) {
+		RemoveTaints(&cluster.Spec.Taints, remove...)
+		for _, taint := range add {
+			AddTaints(&cluster.Spec.Taints, taint)
+		}
+	})
+}
+
+// PatchStatusConditions sets the conditions of the ManagedCluster with the status subresource. It returns
+// true if the ManagedCluster is patched.
+func (p *ClusterPatcher) PatchStatusConditions(ctx context.Context, cluster *clusterv1.ManagedCluster, conds ...metav1.Condition) (bool, error) {
+	return p.patch(ctx, cluster, func(cluster *clusterv1.ManagedCluster) {
+		for _, cond := range conds {
+			meta.SetStatusCondition(&cluster.Status.Conditions, cond)
+		}
+	}, "status")
+}
+
+// patch patches the ManagedCluster with the changes of the mutate func. The cluster, which is usually from
+// the lister, is patched at first to save a get, the latest one is got from the hub once there is a conflict.
+func (p *ClusterPatcher) patch(ctx context.Context, cluster *clusterv1.ManagedCluster,
+	mutate func(cluster *clusterv1.ManagedCluster), subresources ...string) (bool, error) {
+	name := cluster.Name
+	patched := false
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if cluster == nil {
+			latest, err := p.client.ClusterV1().ManagedClusters().Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			cluster = latest
+		}
+		oldCluster := cluster
+		// the latest cluster is got in the next retry
+		cluster = nil
+
+		newCluster := oldCluster.DeepCopy()
+		mutate(newCluster)
+		if equality.Semantic.DeepEqual(oldCluster, newCluster) {
+			return nil
+		}
+
+		patchBytes, err := newClusterMergePatch(oldCluster, newCluster)
+		if err != nil {
+			return err
+		}
+		_, err = p.client.ClusterV1().ManagedClusters().Patch(ctx, name, types.MergePatchType, patchBytes, metav1.PatchOptions{}, subresources...)
+		patched = err == nil
+		return err
+	})
+	return patched, err
+}
+
+// newClusterMergePatch returns the json merge patch from the old cluster to the new one, with the uid and
+// the resourceVersion of the old cluster as the preconditions.
+func newClusterMergePatch(oldCluster, newCluster *clusterv1.ManagedCluster) ([]byte, error) {
+	oldCluster = oldCluster.DeepCopy()
+	// to ensure they appear in the patch as preconditions
+	oldCluster.UID = ""
+	oldCluster.ResourceVersion = ""
+	oldData, err := json.Marshal(oldCluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Marshal old data for cluster %s: %w", oldCluster.Name, err)
+	}
+	newData, err := json.Marshal(newCluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Marshal new data for cluster %s: %w", newCluster.Name, err)
+	}
+	patchBytes, err := jsonpatch.CreateMergePatch(oldData, newData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patch for cluster %s: %w", newCluster.Name, err)
+	}
+	return patchBytes, nil
+}
@@ -0,0 +1,156 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestClusterPatcher(t *testing.T) {
+	newCluster := func() *clusterv1.ManagedCluster {
+		cluster := testinghelpers.NewManagedCluster()
+		cluster.UID = "uid"
+		cluster.ResourceVersion = "1"
+		cluster.Labels = map[string]string{"a": "1", "b": "2"}
+		cluster.Spec.Taints = []clusterv1.Taint{{Key: "t1", Effect: clusterv1.TaintEffectNoSelect}}
+		cluster.Status.Conditions = []metav1.Condition{{Type: "c1", Status: metav1.ConditionTrue, Reason: "r"}}
+		return cluster
+	}
+
+	cases := []struct {
+		name                string
+		patch               func(p *ClusterPatcher, cluster *clusterv1.ManagedCluster) (bool, error)
+		expectedPatched     bool
+		expectedSubresource string
+		expectedPatch       string
+	}{
+		{
+			name: "patch labels",
+			patch: func(p *ClusterPatcher, cluster *clusterv1.ManagedCluster) (bool, error) {
+				return p.PatchLabels(context.TODO(), cluster, map[string]string{"a-": "", "b": "3", "c": "4"})
+			},
+			expectedPatched: true,
+			expectedPatch:   `{"metadata":{"labels":{"a":null,"b":"3","c":"4"},"resourceVersion":"1","uid":"uid"}}`,
+		},
+		{
+			name: "labels are not changed",
+			patch: func(p *ClusterPatcher, cluster *clusterv1.ManagedCluster) (bool, error) {
+				return p.PatchLabels(context.TODO(), cluster, map[string]string{"a": "1", "d-": ""})
+			},
+		},
+		{
+			name: "patch taints",
+			patch: func(p *ClusterPatcher, cluster *clusterv1.ManagedCluster) (bool, error) {
+				return p.PatchTaints(context.TODO(), cluster,
+					[]clusterv1.Taint{{Key: "t2", Effect: clusterv1.TaintEffectNoSelect}},
+					[]clusterv1.Taint{{Key: "t1", Effect: clusterv1.TaintEffectNoSelect}})
+			},
+			expectedPatched: true,
+			expectedPatch:   `{"metadata":{"resourceVersion":"1","uid":"uid"},"spec":{"taints":[{"effect":"NoSelect","key":"t2","timeAdded":null}]}}`,
+		},
+		{
+			name: "taints are not changed",
+			patch: func(p *ClusterPatcher, cluster *clusterv1.ManagedCluster) (bool, error) {
+				return p.PatchTaints(context.TODO(), cluster, []clusterv1.Taint{{Key: "t1", Effect: clusterv1.TaintEffectNoSelect}}, nil)
+			},
+		},
+		{
+			name: "patch status conditions",
+			patch: func(p *ClusterPatcher, cluster *clusterv1.ManagedCluster) (bool, error) {
+				return p.PatchStatusConditions(context.TODO(), cluster, metav1.Condition{Type: "c1", Status: metav1.ConditionFalse, Reason: "r"})
+			},
+			expectedPatched:     true,
+			expectedSubresource: "status",
+		},
+		{
+			name: "status conditions are not changed",
+			patch: func(p *ClusterPatcher, cluster *clusterv1.ManagedCluster) (bool, error) {
+				return p.PatchStatusConditions(context.TODO(), cluster, metav1.Condition{Type: "c1", Status: metav1.ConditionTrue, Reason: "r"})
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := newCluster()
+			clusterClient := clusterfake.NewSimpleClientset(cluster)
+
+			patched, err := c.patch(NewClusterPatcher(clusterClient), cluster)
+			if err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+			if patched != c.expectedPatched {
+				t.Errorf("expected patched %t, but got %t", c.expectedPatched, patched)
+			}
+
+			actions := clusterClient.Actions()
+			if !c.expectedPatched {
+				testinghelpers.AssertNoActions(t, actions)
+				return
+			}
+			testinghelpers.AssertActions(t, actions, "patch")
+			patch := actions[0].(clienttesting.PatchActionImpl)
+			if patch.GetPatchType() != types.MergePatchType {
+				t.Errorf("expected merge patch, but got %q", patch.GetPatchType())
+			}
+			if patch.GetSubresource() != c.expectedSubresource {
+				t.Errorf("expected subresource %q, but got %q", c.expectedSubresource, patch.GetSubresource())
+			}
+			if len(c.expectedPatch) != 0 && string(patch.GetPatch()) != c.expectedPatch {
+				t.Errorf("expected patch %s, but got %s", c.expectedPatch, patch.GetPatch())
+			}
+			if !equality.Semantic.DeepEqual(cluster, newCluster()) {
+				t.Errorf("expected the cluster is not modified")
+			}
+		})
+	}
+}
+
+func TestClusterPatcherOnConflict(t *testing.T) {
+	cluster := testinghelpers.NewManagedCluster()
+	cluster.Labels = map[string]string{"a": "1"}
+	clusterClient := clusterfake.NewSimpleClientset(cluster)
+	// another writer adds a label after the cluster is cached
+	conflicted := false
+	clusterClient.PrependReactor("patch", "managedclusters", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if conflicted {
+			return false, nil, nil
+		}
+		conflicted = true
+		latest := cluster.DeepCopy()
+		latest.Labels["other"] = "true"
+		if err := clusterClient.Tracker().Update(clusterv1.SchemeGroupVersion.WithResource("managedclusters"), latest, ""); err != nil {
+			return true, nil, err
+		}
+		return true, nil, errors.NewConflict(clusterv1.Resource("managedclusters"), cluster.Name, fmt.Errorf("the object has been modified"))
+	})
+
+	patched, err := NewClusterPatcher(clusterClient).PatchLabels(context.TODO(), cluster, map[string]string{"b": "2"})
+	if err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+	if !patched {
+		t.Errorf("expected the cluster is patched")
+	}
+	testinghelpers.AssertActions(t, clusterClient.Actions(), "patch", "get", "patch")
+
+	latest, err := clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), cluster.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "other"} {
+		if _, ok := latest.Labels[key]; !ok {
+			t.Errorf("expected label %q, but got %v", key, latest.Labels)
+		}
+	}
+}
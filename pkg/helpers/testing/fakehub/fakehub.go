@@ -0,0 +1,234 @@
+// Package fakehub provides an in-memory hub for the unit tests of the spoke controllers. The hub is backed
+// by the fake clientsets, and simulates what the hub cluster admin and the hub controllers do in the
+// registration, e.g. accepting a managed cluster, approving the csr of the agent and creating the lease
+// of the managed cluster, so the tests can drive a spoke controller through the registration without a
+// kube-apiserver.
+package fakehub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// Hub is an in-memory hub. The spoke controllers under test use its clients and informers as the hub
+// clients and informers.
+type Hub struct {
+	KubeClient       *kubefake.Clientset
+	ClusterClient    *clusterfake.Clientset
+	KubeInformers    kubeinformers.SharedInformerFactory
+	ClusterInformers clusterinformers.SharedInformerFactory
+
+	caCert *x509.Certificate
+	caKey  *rsa.PrivateKey
+	serial int64
+}
+
+// NewHub returns a hub with the objects, the ManagedClusters are served by the cluster client and the
+// others by the kube client.
+func NewHub(objects ...runtime.Object) (*Hub, error) {
+	kubeObjects, clusterObjects := []runtime.Object{}, []runtime.Object{}
+	for _, obj := range objects {
+		if _, ok := obj.(*clusterv1.ManagedCluster); ok {
+			clusterObjects = append(clusterObjects, obj)
+			continue
+		}
+		kubeObjects = append(kubeObjects, obj)
+	}
+
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: "fake-hub-ca"}, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeClient := kubefake.NewSimpleClientset(kubeObjects...)
+	clusterClient := clusterfake.NewSimpleClientset(clusterObjects...)
+	// the fake clientsets do not generate the names, e.g. of the csrs created by the agent
+	kubeClient.PrependReactor("create", "*", generateName)
+	clusterClient.PrependReactor("create", "*", generateName)
+	return &Hub{
+		KubeClient:       kubeClient,
+		ClusterClient:    clusterClient,
+		KubeInformers:    kubeinformers.NewSharedInformerFactory(kubeClient, 10*time.Minute),
+		ClusterInformers: clusterinformers.NewSharedInformerFactory(clusterClient, 10*time.Minute),
+		caCert:           caCert,
+		caKey:            caKey,
+	}, nil
+}
+
+func generateName(action clienttesting.Action) (bool, runtime.Object, error) {
+	obj, ok := action.(clienttesting.CreateAction).GetObject().(metav1.Object)
+	if ok && len(obj.GetName()) == 0 && len(obj.GetGenerateName()) != 0 {
+		obj.SetName(obj.GetGenerateName() + utilrand.String(5))
+	}
+	return false, nil, nil
+}
+
+// Start starts the informers requested from the hub so far, and waits until their caches are synced.
+func (h *Hub) Start(ctx context.Context) {
+	h.KubeInformers.Start(ctx.Done())
+	h.ClusterInformers.Start(ctx.Done())
+	h.KubeInformers.WaitForCacheSync(ctx.Done())
+	h.ClusterInformers.WaitForCacheSync(ctx.Done())
+}
+
+// CABundle returns the CA which signs the certificates of the approved csrs.
+func (h *Hub) CABundle() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: certutil.CertificateBlockType, Bytes: h.caCert.Raw})
+}
+
+// ManagedCluster returns the managed cluster of the name.
+func (h *Hub) ManagedCluster(ctx context.Context, clusterName string) (*clusterv1.ManagedCluster, error) {
+	return h.ClusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+}
+
+// AcceptCluster accepts the managed cluster with the lease duration as the hub cluster admin does, then
+// sets its HubAccepted condition and creates its lease as the hub controllers do.
+func (h *Hub) AcceptCluster(ctx context.Context, clusterName string, leaseDurationSeconds int32) error {
+	cluster, err := h.ManagedCluster(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	cluster = cluster.DeepCopy()
+	cluster.Spec.HubAcceptsClient = true
+	cluster.Spec.LeaseDurationSeconds = leaseDurationSeconds
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:    clusterv1.ManagedClusterConditionHubAccepted,
+		Status:  metav1.ConditionTrue,
+		Reason:  "HubClusterAdminAccepted",
+		Message: "Accepted by hub cluster admin",
+	})
+	if _, err := h.ClusterClient.ClusterV1().ManagedClusters().Update(ctx, cluster, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helpers.ManagedClusterLeaseName,
+			Namespace: clusterName,
+		},
+		Spec: coordinationv1.LeaseSpec{
+			RenewTime: &metav1.MicroTime{Time: time.Now()},
+		},
+	}
+	_, err = h.KubeClient.CoordinationV1().Leases(clusterName).Create(ctx, lease, metav1.CreateOptions{})
+	return err
+}
+
+// Lease returns the lease of the managed cluster, which is renewed by the agent once it is accepted.
+func (h *Hub) Lease(ctx context.Context, clusterName string) (*coordinationv1.Lease, error) {
+	return h.KubeClient.CoordinationV1().Leases(clusterName).Get(ctx, helpers.ManagedClusterLeaseName, metav1.GetOptions{})
+}
+
+// ApproveClusterCSRs approves the pending csrs of the managed cluster, and issues the certificates valid
+// in certAge with the CA of the hub. It returns the number of the csrs approved.
+func (h *Hub) ApproveClusterCSRs(ctx context.Context, clusterName string, certAge time.Duration) (int, error) {
+	csrs, err := h.KubeClient.CertificatesV1().CertificateSigningRequests().List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", clusterv1.ClusterNameLabelKey, clusterName),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	approved := 0
+	for i := range csrs.Items {
+		if len(csrs.Items[i].Status.Conditions) != 0 {
+			continue
+		}
+		if err := h.ApproveCSR(ctx, csrs.Items[i].Name, certAge); err != nil {
+			return approved, err
+		}
+		approved++
+	}
+	return approved, nil
+}
+
+// ApproveCSR approves the csr of the name, and issues the certificate valid in certAge with the CA of the
+// hub.
+func (h *Hub) ApproveCSR(ctx context.Context, name string, certAge time.Duration) error {
+	csr, err := h.KubeClient.CertificatesV1().CertificateSigningRequests().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	certData, err := h.sign(csr.Spec.Request, certAge)
+	if err != nil {
+		return fmt.Errorf("unable to sign csr %q: %w", name, err)
+	}
+
+	csr = csr.DeepCopy()
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:           certificatesv1.CertificateApproved,
+		Status:         corev1.ConditionTrue,
+		Reason:         "ApprovedByFakeHub",
+		Message:        "Approved by the fake hub",
+		LastUpdateTime: metav1.Now(),
+	})
+	csr.Status.Certificate = certData
+	_, err = h.KubeClient.CertificatesV1().CertificateSigningRequests().UpdateStatus(ctx, csr, metav1.UpdateOptions{})
+	return err
+}
+
+func (h *Hub) sign(request []byte, certAge time.Duration) ([]byte, error) {
+	block, _ := pem.Decode(request)
+	if block == nil {
+		return nil, fmt.Errorf("no certificate request found")
+	}
+	cr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	certDERBytes, err := x509.CreateCertificate(
+		rand.Reader,
+		&x509.Certificate{
+			SerialNumber: big.NewInt(atomic.AddInt64(&h.serial, 1)),
+			Subject:      pkix.Name{Organization: cr.Subject.Organization, CommonName: cr.Subject.CommonName},
+			NotBefore:    now.UTC(),
+			NotAfter:     now.Add(certAge).UTC(),
+			KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		},
+		h.caCert,
+		cr.PublicKey,
+		h.caKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	certBuffer := bytes.Buffer{}
+	if err := pem.Encode(&certBuffer, &pem.Block{Type: certutil.CertificateBlockType, Bytes: certDERBytes}); err != nil {
+		return nil, err
+	}
+	return certBuffer.Bytes(), nil
+}
@@ -0,0 +1,149 @@
+package fakehub
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestAcceptCluster(t *testing.T) {
+	hub, err := NewHub(testinghelpers.NewManagedCluster())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.TODO()
+	if err := hub.AcceptCluster(ctx, testinghelpers.TestManagedClusterName, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster, err := hub.ManagedCluster(ctx, testinghelpers.TestManagedClusterName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cluster.Spec.HubAcceptsClient || cluster.Spec.LeaseDurationSeconds != 60 {
+		t.Errorf("expected the cluster accepted with lease duration 60, but got %#v", cluster.Spec)
+	}
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+		t.Errorf("expected the cluster has the hub accepted condition, but got %#v", cluster.Status.Conditions)
+	}
+
+	lease, err := hub.Lease(ctx, testinghelpers.TestManagedClusterName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Spec.RenewTime == nil {
+		t.Errorf("expected the lease has the renew time")
+	}
+}
+
+func TestApproveClusterCSRs(t *testing.T) {
+	clusterLabels := map[string]string{clusterv1.ClusterNameLabelKey: testinghelpers.TestManagedClusterName}
+	cases := []struct {
+		name             string
+		csrs             []runtime.Object
+		expectedApproved int
+	}{
+		{
+			name:             "no csr",
+			expectedApproved: 0,
+		},
+		{
+			name: "approve the pending csr of the cluster",
+			csrs: []runtime.Object{
+				testinghelpers.NewCSR(testinghelpers.CSRHolder{Name: "csr1", Labels: clusterLabels, ReqBlockType: "CERTIFICATE REQUEST"}),
+			},
+			expectedApproved: 1,
+		},
+		{
+			name: "skip the approved csr and the csr of other clusters",
+			csrs: []runtime.Object{
+				testinghelpers.NewApprovedCSR(testinghelpers.CSRHolder{Name: "csr1", Labels: clusterLabels, ReqBlockType: "CERTIFICATE REQUEST"}),
+				testinghelpers.NewCSR(testinghelpers.CSRHolder{
+					Name:         "csr2",
+					Labels:       map[string]string{clusterv1.ClusterNameLabelKey: "cluster2"},
+					ReqBlockType: "CERTIFICATE REQUEST",
+				}),
+			},
+			expectedApproved: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hub, err := NewHub(c.csrs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.TODO()
+			approved, err := hub.ApproveClusterCSRs(ctx, testinghelpers.TestManagedClusterName, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if approved != c.expectedApproved {
+				t.Errorf("expected %d csrs approved, but got %d", c.expectedApproved, approved)
+			}
+			if approved == 0 {
+				return
+			}
+
+			csr, err := hub.KubeClient.CertificatesV1().CertificateSigningRequests().Get(ctx, "csr1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			testinghelpers.AssertCSRCondition(t, csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+				Type:    certificatesv1.CertificateApproved,
+				Reason:  "ApprovedByFakeHub",
+				Message: "Approved by the fake hub",
+			})
+			assertIssuedByHub(t, hub, csr.Status.Certificate)
+		})
+	}
+}
+
+func TestGenerateName(t *testing.T) {
+	hub, err := NewHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csr := testinghelpers.NewCSR(testinghelpers.CSRHolder{ReqBlockType: "CERTIFICATE REQUEST"})
+	created, err := hub.KubeClient.CertificatesV1().CertificateSigningRequests().Create(context.TODO(), csr, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Name) <= len(csr.GenerateName) {
+		t.Errorf("expected the name generated from %q, but got %q", csr.GenerateName, created.Name)
+	}
+}
+
+func assertIssuedByHub(t *testing.T, hub *Hub, certData []byte) {
+	block, _ := pem.Decode(certData)
+	if block == nil {
+		t.Fatalf("expected the certificate issued, but got %q", string(certData))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(hub.CABundle())
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Errorf("expected the certificate issued by the hub, but got %v", err)
+	}
+}
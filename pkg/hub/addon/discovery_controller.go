@@ -2,21 +2,23 @@ package addon
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
-	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
 
 	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
@@ -24,6 +26,8 @@ import (
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 const (
@@ -31,15 +35,26 @@ const (
 	addOnStatusAvailable   = "available"
 	addOnStatusUnhealthy   = "unhealthy"
 	addOnStatusUnreachable = "unreachable"
+
+	// addOnStatusesFieldManager is the field manager of the addon statuses annotation applied by the controller.
+	addOnStatusesFieldManager = "addon-feature-discovery"
 )
 
 // addOnFeatureDiscoveryController monitors ManagedCluster and its ManagedClusterAddOns on hub and
-// create/update/delete labels of the ManagedCluster to reflect the status of addons.
+// create/update/delete labels of the ManagedCluster to reflect the status of addons. The status of
+// addons is also exposed with the addOnStatus metric, so that the broken addons of the fleet can be
+// alerted on the hub.
+//
+// If addOnStatusesEnabled is true, it also maintains the ManagedClusterAddOnStatusesAnnotation of the
+// ManagedCluster with server-side apply, so that it does not conflict with the other writers of the
+// ManagedCluster.
 type addOnFeatureDiscoveryController struct {
-	clusterClient clientset.Interface
-	clusterLister clusterv1listers.ManagedClusterLister
-	addOnLister   addonlisterv1alpha1.ManagedClusterAddOnLister
-	recorder      events.Recorder
+	clusterClient        clientset.Interface
+	clusterPatcher       *helpers.ClusterPatcher
+	clusterLister        clusterv1listers.ManagedClusterLister
+	addOnLister          addonlisterv1alpha1.ManagedClusterAddOnLister
+	addOnStatusesEnabled bool
+	recorder             events.Recorder
 }
 
 // NewAddOnFeatureDiscoveryController returns an instance of addOnFeatureDiscoveryController
@@ -47,13 +62,16 @@ func NewAddOnFeatureDiscoveryController(
 	clusterClient clientset.Interface,
 	clusterInformer clusterv1informer.ManagedClusterInformer,
 	addOnInformers addoninformerv1alpha1.ManagedClusterAddOnInformer,
+	addOnStatusesEnabled bool,
 	recorder events.Recorder,
 ) factory.Controller {
 	c := &addOnFeatureDiscoveryController{
-		clusterClient: clusterClient,
-		clusterLister: clusterInformer.Lister(),
-		addOnLister:   addOnInformers.Lister(),
-		recorder:      recorder,
+		clusterClient:        clusterClient,
+		clusterPatcher:       helpers.NewClusterPatcher(clusterClient),
+		clusterLister:        clusterInformer.Lister(),
+		addOnLister:          addOnInformers.Lister(),
+		addOnStatusesEnabled: addOnStatusesEnabled,
+		recorder:             recorder,
 	}
 
 	return factory.New().
@@ -69,7 +87,7 @@ func NewAddOnFeatureDiscoveryController(
 				return key
 			},
 			addOnInformers.Informer()).
-		WithSync(c.sync).
+		WithSync(helpers.RecoverSync("AddOnFeatureDiscoveryController", c.sync)).
 		ResyncEvery(10*time.Minute).
 		ToController("AddOnFeatureDiscoveryController", recorder)
 }
@@ -117,14 +135,17 @@ func (c *addOnFeatureDiscoveryController) syncAddOn(ctx context.Context, cluster
 		// addon is deleted
 		key := fmt.Sprintf("%s%s-", addOnFeaturePrefix, addOnName)
 		labels[key] = ""
+		deleteAddOnStatusMetric(clusterName, addOnName)
 	case err != nil:
 		return err
 	case !addOn.DeletionTimestamp.IsZero():
 		key := fmt.Sprintf("%s%s-", addOnFeaturePrefix, addOnName)
 		labels[key] = ""
+		deleteAddOnStatusMetric(clusterName, addOnName)
 	default:
 		key := fmt.Sprintf("%s%s", addOnFeaturePrefix, addOn.Name)
 		labels[key] = getAddOnLabelValue(addOn)
+		setAddOnStatusMetric(clusterName, addOn.Name, labels[key])
 	}
 
 	cluster, err := c.clusterLister.Get(clusterName)
@@ -140,19 +161,12 @@ func (c *addOnFeatureDiscoveryController) syncAddOn(ctx context.Context, cluster
 		return nil
 	}
 
-	// merge labels
-	modified := false
-	cluster = cluster.DeepCopy()
-	resourcemerge.MergeMap(&modified, &cluster.Labels, labels)
-
-	// no work if the cluster labels have no change
-	if !modified {
-		return nil
+	// patch the cluster if the cluster labels have changes
+	if _, err := c.clusterPatcher.PatchLabels(ctx, cluster, labels); err != nil {
+		return err
 	}
 
-	// otherwise, update cluster
-	_, err = c.clusterClient.ClusterV1().ManagedClusters().Update(ctx, cluster, metav1.UpdateOptions{})
-	return err
+	return c.applyAddOnStatuses(ctx, cluster)
 }
 
 func (c *addOnFeatureDiscoveryController) syncCluster(ctx context.Context, clusterName string) error {
@@ -180,10 +194,12 @@ func (c *addOnFeatureDiscoveryController) syncCluster(ctx context.Context, clust
 	for _, addOn := range addOns {
 		// addon is deleting
 		if !addOn.DeletionTimestamp.IsZero() {
+			deleteAddOnStatusMetric(clusterName, addOn.Name)
 			continue
 		}
 		key := fmt.Sprintf("%s%s", addOnFeaturePrefix, addOn.Name)
 		addOnLabels[key] = getAddOnLabelValue(addOn)
+		setAddOnStatusMetric(clusterName, addOn.Name, addOnLabels[key])
 	}
 
 	// remove addon lable if its corresponding addon no longer exists
@@ -194,22 +210,67 @@ func (c *addOnFeatureDiscoveryController) syncCluster(ctx context.Context, clust
 
 		if _, ok := addOnLabels[key]; !ok {
 			addOnLabels[fmt.Sprintf("%s-", key)] = ""
+			deleteAddOnStatusMetric(clusterName, strings.TrimPrefix(key, addOnFeaturePrefix))
 		}
 	}
 
-	// merge labels
-	modified := false
-	cluster = cluster.DeepCopy()
-	resourcemerge.MergeMap(&modified, &cluster.Labels, addOnLabels)
+	// patch the cluster if the cluster labels have changes
+	if _, err := c.clusterPatcher.PatchLabels(ctx, cluster, addOnLabels); err != nil {
+		return err
+	}
+
+	return c.applyAddOnStatuses(ctx, cluster)
+}
+
+// applyAddOnStatuses applies the ManagedClusterAddOnStatusesAnnotation of the cluster with the statuses
+// of all its addons if it is enabled.
+func (c *addOnFeatureDiscoveryController) applyAddOnStatuses(ctx context.Context, cluster *clusterv1.ManagedCluster) error {
+	if !c.addOnStatusesEnabled {
+		return nil
+	}
 
-	// no work if the cluster labels have no change
-	if !modified {
+	addOns, err := c.addOnLister.ManagedClusterAddOns(cluster.Name).List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("unable to list addOns of cluster %q: %w", cluster.Name, err)
+	}
+	statuses := map[string]helpers.AddOnStatusSummary{}
+	for _, addOn := range addOns {
+		if !addOn.DeletionTimestamp.IsZero() {
+			continue
+		}
+		statuses[addOn.Name] = helpers.AddOnStatusSummary{
+			Version: addOn.Annotations[helpers.AddOnVersionAnnotation],
+			Health:  getAddOnLabelValue(addOn),
+		}
+	}
+	// the keys of the map are sorted, so the value is stable
+	value, err := json.Marshal(statuses)
+	if err != nil {
+		return err
+	}
+	if current, ok := cluster.Annotations[helpers.ManagedClusterAddOnStatusesAnnotation]; ok && current == string(value) {
 		return nil
 	}
 
-	// otherwise, update cluster
-	_, err = c.clusterClient.ClusterV1().ManagedClusters().Update(ctx, cluster, metav1.UpdateOptions{})
-	return err
+	patch, err := json.Marshal(map[string]interface{}{
+		"apiVersion": clusterv1.GroupVersion.String(),
+		"kind":       "ManagedCluster",
+		"metadata": map[string]interface{}{
+			"name": cluster.Name,
+			"annotations": map[string]string{
+				helpers.ManagedClusterAddOnStatusesAnnotation: string(value),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.clusterClient.ClusterV1().ManagedClusters().Patch(ctx, cluster.Name, types.ApplyPatchType, patch,
+		metav1.PatchOptions{FieldManager: addOnStatusesFieldManager, Force: pointer.Bool(true)})
+	if err != nil {
+		return fmt.Errorf("unable to apply the addon statuses of cluster %q: %w", cluster.Name, err)
+	}
+	return nil
 }
 
 func getAddOnLabelValue(addOn *addonv1alpha1.ManagedClusterAddOn) string {
@@ -2,13 +2,17 @@ package addon
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/component-base/metrics/legacyregistry"
 
 	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
@@ -16,6 +20,7 @@ import (
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 )
 
@@ -100,9 +105,9 @@ func TestDiscoveryController_SyncAddOn(t *testing.T) {
 				},
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update")
-				actual := actions[0].(clienttesting.UpdateActionImpl).Object
-				assertNoAddonLabel(t, actual.(*clusterv1.ManagedCluster), "addon1")
+				testinghelpers.AssertActions(t, actions, "patch")
+				actual := patchedLabels(t, actions[0])
+				assertNoAddonLabel(t, actual, "addon1")
 			},
 		},
 		{
@@ -136,9 +141,9 @@ func TestDiscoveryController_SyncAddOn(t *testing.T) {
 				},
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update")
-				actual := actions[0].(clienttesting.UpdateActionImpl).Object
-				assertAddonLabel(t, actual.(*clusterv1.ManagedCluster), "addon1", addOnStatusUnreachable)
+				testinghelpers.AssertActions(t, actions, "patch")
+				actual := patchedLabels(t, actions[0])
+				assertAddonLabel(t, actual, "addon1", addOnStatusUnreachable)
 			},
 		},
 		{
@@ -159,9 +164,9 @@ func TestDiscoveryController_SyncAddOn(t *testing.T) {
 				},
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update")
-				actual := actions[0].(clienttesting.UpdateActionImpl).Object
-				assertAddonLabel(t, actual.(*clusterv1.ManagedCluster), "addon1", addOnStatusUnreachable)
+				testinghelpers.AssertActions(t, actions, "patch")
+				actual := patchedLabels(t, actions[0])
+				assertAddonLabel(t, actual, "addon1", addOnStatusUnreachable)
 			},
 		},
 		{
@@ -214,9 +219,10 @@ func TestDiscoveryController_SyncAddOn(t *testing.T) {
 			}
 
 			controller := addOnFeatureDiscoveryController{
-				clusterClient: clusterClient,
-				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
-				addOnLister:   addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				clusterClient:  clusterClient,
+				clusterPatcher: helpers.NewClusterPatcher(clusterClient),
+				clusterLister:  clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				addOnLister:    addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
 			}
 
 			err := controller.syncAddOn(context.Background(), clusterName, c.addOnName)
@@ -257,9 +263,9 @@ func TestDiscoveryController_Sync(t *testing.T) {
 				},
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update")
-				actual := actions[0].(clienttesting.UpdateActionImpl).Object
-				assertAddonLabel(t, actual.(*clusterv1.ManagedCluster), "addon1", addOnStatusUnreachable)
+				testinghelpers.AssertActions(t, actions, "patch")
+				actual := patchedLabels(t, actions[0])
+				assertAddonLabel(t, actual, "addon1", addOnStatusUnreachable)
 			},
 		},
 		{
@@ -329,11 +335,11 @@ func TestDiscoveryController_Sync(t *testing.T) {
 				},
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update")
-				actual := actions[0].(clienttesting.UpdateActionImpl).Object
-				assertAddonLabel(t, actual.(*clusterv1.ManagedCluster), "addon1", addOnStatusUnreachable)
-				assertAddonLabel(t, actual.(*clusterv1.ManagedCluster), "addon3", addOnStatusAvailable)
-				assertNoAddonLabel(t, actual.(*clusterv1.ManagedCluster), "addon4")
+				testinghelpers.AssertActions(t, actions, "patch")
+				actual := patchedLabels(t, actions[0])
+				assertAddonLabel(t, actual, "addon1", addOnStatusUnreachable)
+				assertAddonLabel(t, actual, "addon3", addOnStatusAvailable)
+				assertNoAddonLabel(t, actual, "addon4")
 			},
 		},
 	}
@@ -369,9 +375,10 @@ func TestDiscoveryController_Sync(t *testing.T) {
 			}
 
 			controller := addOnFeatureDiscoveryController{
-				clusterClient: clusterClient,
-				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
-				addOnLister:   addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				clusterClient:  clusterClient,
+				clusterPatcher: helpers.NewClusterPatcher(clusterClient),
+				clusterLister:  clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				addOnLister:    addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
 			}
 
 			err := controller.sync(context.Background(), testinghelpers.NewFakeSyncContext(t, c.queueKey))
@@ -384,21 +391,341 @@ func TestDiscoveryController_Sync(t *testing.T) {
 	}
 }
 
-func assertAddonLabel(t *testing.T, cluster *clusterv1.ManagedCluster, addOnName, addOnStatus string) {
-	key := fmt.Sprintf("%s%s", addOnFeaturePrefix, addOnName)
-	value, ok := cluster.Labels[key]
+func TestDiscoveryController_AddOnStatuses(t *testing.T) {
+	clusterName := "cluster1"
+	deleteTime := metav1.Now()
+	addOns := []*addonv1alpha1.ManagedClusterAddOn{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "addon1",
+				Namespace:   clusterName,
+				Annotations: map[string]string{helpers.AddOnVersionAnnotation: "v1.0.0"},
+			},
+			Status: addonv1alpha1.ManagedClusterAddOnStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:   addonv1alpha1.ManagedClusterAddOnConditionAvailable,
+						Status: metav1.ConditionTrue,
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "addon2",
+				Namespace: clusterName,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "addon3",
+				Namespace:         clusterName,
+				DeletionTimestamp: &deleteTime,
+			},
+		},
+	}
+	labels := map[string]string{
+		"feature.open-cluster-management.io/addon-addon1": addOnStatusAvailable,
+		"feature.open-cluster-management.io/addon-addon2": addOnStatusUnreachable,
+	}
+	statuses := `{"addon1":{"version":"v1.0.0","health":"available"},"addon2":{"health":"unreachable"}}`
+
+	cases := []struct {
+		name            string
+		queueKey        string
+		enabled         bool
+		annotations     map[string]string
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "disabled",
+			queueKey:        clusterName,
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:     "apply the addon statuses of the cluster",
+			queueKey: clusterName,
+			enabled:  true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				assertAppliedAddOnStatuses(t, actions, statuses)
+			},
+		},
+		{
+			name:     "apply the addon statuses once an addon is synced",
+			queueKey: "cluster1/addon2",
+			enabled:  true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				assertAppliedAddOnStatuses(t, actions, statuses)
+			},
+		},
+		{
+			name:     "addon statuses are changed",
+			queueKey: clusterName,
+			enabled:  true,
+			annotations: map[string]string{
+				helpers.ManagedClusterAddOnStatusesAnnotation: `{"addon1":{"health":"unhealthy"}}`,
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				assertAppliedAddOnStatuses(t, actions, statuses)
+			},
+		},
+		{
+			name:            "addon statuses are not changed",
+			queueKey:        clusterName,
+			enabled:         true,
+			annotations:     map[string]string{helpers.ManagedClusterAddOnStatusesAnnotation: statuses},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        clusterName,
+					Labels:      labels,
+					Annotations: c.annotations,
+				},
+			}
+			clusterClient := clusterfake.NewSimpleClientset(cluster)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			if err := clusterStore.Add(cluster); err != nil {
+				t.Fatal(err)
+			}
+
+			addOnInformerFactory := addoninformers.NewSharedInformerFactoryWithOptions(addonfake.NewSimpleClientset(), 10*time.Minute)
+			addOnStore := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore()
+			for _, addOn := range addOns {
+				if err := addOnStore.Add(addOn); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			controller := addOnFeatureDiscoveryController{
+				clusterClient:        clusterClient,
+				clusterPatcher:       helpers.NewClusterPatcher(clusterClient),
+				clusterLister:        clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				addOnLister:          addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				addOnStatusesEnabled: c.enabled,
+			}
+
+			err := controller.sync(context.Background(), testinghelpers.NewFakeSyncContext(t, c.queueKey))
+			if err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
+
+func assertAppliedAddOnStatuses(t *testing.T, actions []clienttesting.Action, expected string) {
+	testinghelpers.AssertActions(t, actions, "patch")
+	patchAction := actions[0].(clienttesting.PatchActionImpl)
+	if patchAction.GetPatchType() != types.ApplyPatchType {
+		t.Errorf("expected apply patch, but got %q", patchAction.GetPatchType())
+	}
+	cluster := &clusterv1.ManagedCluster{}
+	if err := json.Unmarshal(patchAction.GetPatch(), cluster); err != nil {
+		t.Fatal(err)
+	}
+	if actual := cluster.Annotations[helpers.ManagedClusterAddOnStatusesAnnotation]; actual != expected {
+		t.Errorf("expected addon statuses %s, but got %s", expected, actual)
+	}
+}
+
+func TestDiscoveryController_AddOnStatusMetric(t *testing.T) {
+	clusterName := "cluster1"
+	deleteTime := metav1.Now()
+	availableAddOn := &addonv1alpha1.ManagedClusterAddOn{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "addon1",
+			Namespace: clusterName,
+		},
+		Status: addonv1alpha1.ManagedClusterAddOnStatus{
+			Conditions: []metav1.Condition{
+				{
+					Type:   addonv1alpha1.ManagedClusterAddOnConditionAvailable,
+					Status: metav1.ConditionTrue,
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		name             string
+		queueKey         string
+		addOns           []*addonv1alpha1.ManagedClusterAddOn
+		existingStatuses map[string]string
+		expectedStatuses map[string]string
+	}{
+		{
+			name:             "addon synced",
+			queueKey:         "cluster1/addon1",
+			addOns:           []*addonv1alpha1.ManagedClusterAddOn{availableAddOn},
+			expectedStatuses: map[string]string{"addon1": addOnStatusAvailable},
+		},
+		{
+			name:             "addon is deleted",
+			queueKey:         "cluster1/addon1",
+			existingStatuses: map[string]string{"addon1": addOnStatusAvailable},
+		},
+		{
+			name:     "cluster synced",
+			queueKey: clusterName,
+			addOns: []*addonv1alpha1.ManagedClusterAddOn{
+				availableAddOn,
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "addon2",
+						Namespace: clusterName,
+					},
+					Status: addonv1alpha1.ManagedClusterAddOnStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   addonv1alpha1.ManagedClusterAddOnConditionAvailable,
+								Status: metav1.ConditionFalse,
+							},
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "addon3",
+						Namespace:         clusterName,
+						DeletionTimestamp: &deleteTime,
+					},
+				},
+			},
+			existingStatuses: map[string]string{
+				"addon2": addOnStatusAvailable,
+				"addon3": addOnStatusAvailable,
+				"addon4": addOnStatusAvailable,
+			},
+			expectedStatuses: map[string]string{
+				"addon1": addOnStatusAvailable,
+				"addon2": addOnStatusUnhealthy,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addOnStatus.Reset()
+
+			labels := map[string]string{}
+			for addOnName, status := range c.existingStatuses {
+				setAddOnStatusMetric(clusterName, addOnName, status)
+				labels[addOnFeaturePrefix+addOnName] = status
+			}
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   clusterName,
+					Labels: labels,
+				},
+			}
+			clusterClient := clusterfake.NewSimpleClientset(cluster)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			if err := clusterStore.Add(cluster); err != nil {
+				t.Fatal(err)
+			}
+
+			addOnInformerFactory := addoninformers.NewSharedInformerFactoryWithOptions(addonfake.NewSimpleClientset(), 10*time.Minute)
+			addOnStore := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore()
+			for _, addOn := range c.addOns {
+				if err := addOnStore.Add(addOn); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			controller := addOnFeatureDiscoveryController{
+				clusterClient:  clusterClient,
+				clusterPatcher: helpers.NewClusterPatcher(clusterClient),
+				clusterLister:  clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				addOnLister:    addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+			}
+
+			err := controller.sync(context.Background(), testinghelpers.NewFakeSyncContext(t, c.queueKey))
+			if err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+
+			metrics, err := legacyregistry.DefaultGatherer.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			actualStatuses := map[string]string{}
+			for _, family := range metrics {
+				if family.GetName() != "open_cluster_management_registration_addon_status" {
+					continue
+				}
+				for _, metric := range family.GetMetric() {
+					labels := map[string]string{}
+					for _, label := range metric.GetLabel() {
+						labels[label.GetName()] = label.GetValue()
+					}
+					if labels["cluster"] != clusterName {
+						t.Errorf("unexpected cluster %q", labels["cluster"])
+					}
+					if _, ok := actualStatuses[labels["addon"]]; !ok {
+						actualStatuses[labels["addon"]] = ""
+					}
+					if metric.GetGauge().GetValue() == 1 {
+						actualStatuses[labels["addon"]] = labels["status"]
+					}
+				}
+			}
+			if !reflect.DeepEqual(actualStatuses, c.expectedStatuses) && (len(actualStatuses) != 0 || len(c.expectedStatuses) != 0) {
+				t.Errorf("expected addon statuses %v, but got %v", c.expectedStatuses, actualStatuses)
+			}
+		})
+	}
+}
+
+// patchedLabels returns the labels in the merge patch of the cluster, a nil value means the label is removed,
+// and a nil map means all the labels are removed.
+func patchedLabels(t *testing.T, action clienttesting.Action) map[string]*string {
+	patch := struct {
+		Metadata map[string]json.RawMessage `json:"metadata"`
+	}{}
+	if err := json.Unmarshal(action.(clienttesting.PatchActionImpl).GetPatch(), &patch); err != nil {
+		t.Fatal(err)
+	}
+	labels := map[string]*string{}
+	raw, ok := patch.Metadata["labels"]
 	if !ok {
+		return labels
+	}
+	if string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &labels); err != nil {
+		t.Fatal(err)
+	}
+	return labels
+}
+
+func assertAddonLabel(t *testing.T, labels map[string]*string, addOnName, addOnStatus string) {
+	key := fmt.Sprintf("%s%s", addOnFeaturePrefix, addOnName)
+	value, ok := labels[key]
+	if !ok || value == nil {
 		t.Errorf("label %q not found", key)
+		return
 	}
 
-	if value != addOnStatus {
-		t.Errorf("expect label value %q but found %q", addOnStatus, value)
+	if *value != addOnStatus {
+		t.Errorf("expect label value %q but found %q", addOnStatus, *value)
 	}
 }
 
-func assertNoAddonLabel(t *testing.T, cluster *clusterv1.ManagedCluster, addOnName string) {
+func assertNoAddonLabel(t *testing.T, labels map[string]*string, addOnName string) {
+	if labels == nil {
+		return
+	}
 	key := fmt.Sprintf("%s%s", addOnFeaturePrefix, addOnName)
-	if _, ok := cluster.Labels[key]; ok {
-		t.Errorf("label %q found", key)
+	if value, ok := labels[key]; !ok || value != nil {
+		t.Errorf("label %q is not removed", key)
 	}
 }
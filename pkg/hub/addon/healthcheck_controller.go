@@ -47,7 +47,7 @@ func NewManagedClusterAddOnHealthCheckController(addOnClient addonclient.Interfa
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(helpers.RecoverSync("ManagedClusterAddonHealthCheckController", c.sync)).
 		ToController("ManagedClusterAddonHealthCheckController", recorder)
 }
 
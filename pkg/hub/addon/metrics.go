@@ -0,0 +1,40 @@
+package addon
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// addOnStatuses are the statuses of an addon reported with the addOnStatus metric.
+var addOnStatuses = []string{addOnStatusAvailable, addOnStatusUnhealthy, addOnStatusUnreachable}
+
+var addOnStatus = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Name: "open_cluster_management_registration_addon_status",
+		Help: "The status of an addon on a managed cluster computed from its Available condition, " +
+			"it is 1 for the current status and 0 for the others.",
+	},
+	[]string{"cluster", "addon", "status"},
+)
+
+func init() {
+	legacyregistry.MustRegister(addOnStatus)
+}
+
+// setAddOnStatusMetric sets the current status of the addon to 1 and the others to 0.
+func setAddOnStatusMetric(clusterName, addOnName, status string) {
+	for _, s := range addOnStatuses {
+		value := 0.0
+		if s == status {
+			value = 1
+		}
+		addOnStatus.WithLabelValues(clusterName, addOnName, s).Set(value)
+	}
+}
+
+// deleteAddOnStatusMetric deletes the status of the addon once it is deleted.
+func deleteAddOnStatusMetric(clusterName, addOnName string) {
+	for _, s := range addOnStatuses {
+		addOnStatus.Delete(map[string]string{"cluster": clusterName, "addon": addOnName, "status": s})
+	}
+}
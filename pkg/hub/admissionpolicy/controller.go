@@ -0,0 +1,243 @@
+package admissionpolicy
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	admissionregistrationv1alpha1 "k8s.io/api/admissionregistration/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// specHashAnnotation holds the hash of the spec in the manifest, the spec of the existing resource is
+// not compared with the manifest directly, since it is defaulted by the kube-apiserver.
+const specHashAnnotation = "cluster.open-cluster-management.io/spec-hash"
+
+var policyFiles = []string{
+	"manifests/managedcluster-policy.yaml",
+	"manifests/managedclusterset-policy.yaml",
+}
+
+var policyBindingFiles = []string{
+	"manifests/managedcluster-policybinding.yaml",
+	"manifests/managedclusterset-policybinding.yaml",
+}
+
+//go:embed manifests
+var manifestFiles embed.FS
+
+// admissionPolicyController maintains the ValidatingAdmissionPolicies and their bindings which enforce
+// the structural rules, i.e. the https urls of the client configs, the immutable timeAdded of the taints
+// and the clusterset label key reserved for the ExclusiveClusterSetLabel selector, so that the webhook is
+// optional for those rules on Kubernetes 1.26+. The checks relying on SubjectAccessReviews are kept in the
+// webhook. The controller only runs once the policies are enabled, see RemovePolicies once they are not.
+type admissionPolicyController struct {
+	kubeClient kubernetes.Interface
+}
+
+// NewAdmissionPolicyController creates a new admission policy controller.
+func NewAdmissionPolicyController(
+	kubeClient kubernetes.Interface,
+	recorder events.Recorder) factory.Controller {
+	c := &admissionPolicyController{
+		kubeClient: kubeClient,
+	}
+	return factory.New().
+		WithSync(helpers.RecoverSync("AdmissionPolicyController", c.sync)).
+		ResyncEvery(10*time.Minute).
+		ToController("AdmissionPolicyController", recorder)
+}
+
+func (c *admissionPolicyController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	policies, bindings, err := loadManifests()
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	// the policies are applied before the bindings which refer to them
+	for _, policy := range policies {
+		errs = append(errs, c.applyPolicy(ctx, syncCtx.Recorder(), policy))
+	}
+	for _, binding := range bindings {
+		errs = append(errs, c.applyPolicyBinding(ctx, syncCtx.Recorder(), binding))
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+func (c *admissionPolicyController) applyPolicy(ctx context.Context, recorder events.Recorder,
+	required *admissionregistrationv1alpha1.ValidatingAdmissionPolicy) error {
+	client := c.kubeClient.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicies()
+	existing, err := client.Get(ctx, required.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		if _, err := client.Create(ctx, required, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("unable to create ValidatingAdmissionPolicy %q: %w", required.Name, err)
+		}
+		recorder.Eventf("ValidatingAdmissionPolicyCreated", "Created ValidatingAdmissionPolicy %q", required.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get ValidatingAdmissionPolicy %q: %w", required.Name, err)
+	}
+	if existing.Annotations[specHashAnnotation] == required.Annotations[specHashAnnotation] {
+		return nil
+	}
+
+	toUpdate := existing.DeepCopy()
+	toUpdate.Annotations = mergeAnnotations(existing.Annotations, required.Annotations)
+	toUpdate.Spec = required.Spec
+	if _, err := client.Update(ctx, toUpdate, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to update ValidatingAdmissionPolicy %q: %w", required.Name, err)
+	}
+	recorder.Eventf("ValidatingAdmissionPolicyUpdated", "Updated ValidatingAdmissionPolicy %q", required.Name)
+	return nil
+}
+
+func (c *admissionPolicyController) applyPolicyBinding(ctx context.Context, recorder events.Recorder,
+	required *admissionregistrationv1alpha1.ValidatingAdmissionPolicyBinding) error {
+	client := c.kubeClient.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicyBindings()
+	existing, err := client.Get(ctx, required.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		if _, err := client.Create(ctx, required, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("unable to create ValidatingAdmissionPolicyBinding %q: %w", required.Name, err)
+		}
+		recorder.Eventf("ValidatingAdmissionPolicyBindingCreated", "Created ValidatingAdmissionPolicyBinding %q", required.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get ValidatingAdmissionPolicyBinding %q: %w", required.Name, err)
+	}
+	if existing.Annotations[specHashAnnotation] == required.Annotations[specHashAnnotation] {
+		return nil
+	}
+
+	toUpdate := existing.DeepCopy()
+	toUpdate.Annotations = mergeAnnotations(existing.Annotations, required.Annotations)
+	toUpdate.Spec = required.Spec
+	if _, err := client.Update(ctx, toUpdate, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to update ValidatingAdmissionPolicyBinding %q: %w", required.Name, err)
+	}
+	recorder.Eventf("ValidatingAdmissionPolicyBindingUpdated", "Updated ValidatingAdmissionPolicyBinding %q", required.Name)
+	return nil
+}
+
+// RemovePolicies removes the ValidatingAdmissionPolicies and their bindings applied once the policies were
+// enabled, it is called instead of running the controller once they are disabled.
+func RemovePolicies(ctx context.Context, kubeClient kubernetes.Interface, recorder events.Recorder) error {
+	policies, bindings, err := loadManifests()
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	// the bindings are removed before the policies they refer to
+	for _, binding := range bindings {
+		errs = append(errs, deletePolicyBinding(ctx, kubeClient, recorder, binding.Name))
+	}
+	for _, policy := range policies {
+		errs = append(errs, deletePolicy(ctx, kubeClient, recorder, policy.Name))
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
+
+func deletePolicy(ctx context.Context, kubeClient kubernetes.Interface, recorder events.Recorder, name string) error {
+	err := kubeClient.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicies().Delete(ctx, name, metav1.DeleteOptions{})
+	// the resource is not found on a cluster without the ValidatingAdmissionPolicy api as well
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to delete ValidatingAdmissionPolicy %q: %w", name, err)
+	}
+	recorder.Eventf("ValidatingAdmissionPolicyDeleted", "Deleted ValidatingAdmissionPolicy %q", name)
+	return nil
+}
+
+func deletePolicyBinding(ctx context.Context, kubeClient kubernetes.Interface, recorder events.Recorder, name string) error {
+	err := kubeClient.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicyBindings().Delete(ctx, name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to delete ValidatingAdmissionPolicyBinding %q: %w", name, err)
+	}
+	recorder.Eventf("ValidatingAdmissionPolicyBindingDeleted", "Deleted ValidatingAdmissionPolicyBinding %q", name)
+	return nil
+}
+
+// loadManifests decodes the policies and bindings in the manifest files, and sets the hash of their
+// spec in the annotation.
+func loadManifests() ([]*admissionregistrationv1alpha1.ValidatingAdmissionPolicy,
+	[]*admissionregistrationv1alpha1.ValidatingAdmissionPolicyBinding, error) {
+	policies := []*admissionregistrationv1alpha1.ValidatingAdmissionPolicy{}
+	for _, file := range policyFiles {
+		policy := &admissionregistrationv1alpha1.ValidatingAdmissionPolicy{}
+		if err := decodeManifest(file, policy); err != nil {
+			return nil, nil, err
+		}
+		if err := setSpecHash(&policy.ObjectMeta, policy.Spec); err != nil {
+			return nil, nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	bindings := []*admissionregistrationv1alpha1.ValidatingAdmissionPolicyBinding{}
+	for _, file := range policyBindingFiles {
+		binding := &admissionregistrationv1alpha1.ValidatingAdmissionPolicyBinding{}
+		if err := decodeManifest(file, binding); err != nil {
+			return nil, nil, err
+		}
+		if err := setSpecHash(&binding.ObjectMeta, binding.Spec); err != nil {
+			return nil, nil, err
+		}
+		bindings = append(bindings, binding)
+	}
+	return policies, bindings, nil
+}
+
+func decodeManifest(file string, into runtime.Object) error {
+	raw, err := manifestFiles.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("missing %q: %w", file, err)
+	}
+	if _, _, err := scheme.Codecs.UniversalDeserializer().Decode(raw, nil, into); err != nil {
+		return fmt.Errorf("cannot decode %q: %w", file, err)
+	}
+	return nil
+}
+
+func setSpecHash(objectMeta *metav1.ObjectMeta, spec interface{}) error {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+	objectMeta.Annotations[specHashAnnotation] = fmt.Sprintf("%x", sha256.Sum256(data))
+	return nil
+}
+
+func mergeAnnotations(existing, required map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range existing {
+		merged[key] = value
+	}
+	for key, value := range required {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,147 @@
+package admissionpolicy
+
+import (
+	"context"
+	"testing"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	admissionregistrationv1alpha1 "k8s.io/api/admissionregistration/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newExistingObjects(t *testing.T, outdated bool) []runtime.Object {
+	policies, bindings, err := loadManifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := []runtime.Object{}
+	for _, policy := range policies {
+		if outdated {
+			policy.Annotations[specHashAnnotation] = "outdated"
+		}
+		objects = append(objects, policy)
+	}
+	for _, binding := range bindings {
+		objects = append(objects, binding)
+	}
+	return objects
+}
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name            string
+		existingObjects func(t *testing.T) []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name: "create policies",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create", "get", "create", "get", "create", "get", "create")
+				policy := actions[1].(clienttesting.CreateAction).GetObject().(*admissionregistrationv1alpha1.ValidatingAdmissionPolicy)
+				if policy.Name != "managedclusters.admission.cluster.open-cluster-management.io" {
+					t.Errorf("unexpected policy %q", policy.Name)
+				}
+				if len(policy.Spec.Validations) != 2 || len(policy.Annotations[specHashAnnotation]) == 0 {
+					t.Errorf("unexpected policy %v", policy)
+				}
+				binding := actions[5].(clienttesting.CreateAction).GetObject().(*admissionregistrationv1alpha1.ValidatingAdmissionPolicyBinding)
+				if binding.Spec.PolicyName != policy.Name {
+					t.Errorf("expected binding of policy %q, but got %q", policy.Name, binding.Spec.PolicyName)
+				}
+			},
+		},
+		{
+			name: "policies are up to date",
+			existingObjects: func(t *testing.T) []runtime.Object {
+				return newExistingObjects(t, false)
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "get", "get", "get")
+			},
+		},
+		{
+			name: "update outdated policies",
+			existingObjects: func(t *testing.T) []runtime.Object {
+				return newExistingObjects(t, true)
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update", "get", "update", "get", "get")
+				policy := actions[1].(clienttesting.UpdateAction).GetObject().(*admissionregistrationv1alpha1.ValidatingAdmissionPolicy)
+				if policy.Annotations[specHashAnnotation] == "outdated" {
+					t.Errorf("expected the spec hash updated, but got %v", policy.Annotations)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if c.existingObjects != nil {
+				objects = c.existingObjects(t)
+			}
+			kubeClient := kubefake.NewSimpleClientset(objects...)
+
+			ctrl := &admissionPolicyController{
+				kubeClient: kubeClient,
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+			testinghelpers.AssertError(t, syncErr, "")
+
+			c.validateActions(t, kubeClient.Actions())
+
+			policies, err := kubeClient.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicies().List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(policies.Items) != len(policyFiles) {
+				t.Errorf("expected %d policies, but got %d", len(policyFiles), len(policies.Items))
+			}
+		})
+	}
+}
+
+func TestRemovePolicies(t *testing.T) {
+	cases := []struct {
+		name            string
+		existingObjects func(t *testing.T) []runtime.Object
+	}{
+		{
+			name: "remove the policies",
+			existingObjects: func(t *testing.T) []runtime.Object {
+				return newExistingObjects(t, false)
+			},
+		},
+		{
+			name:            "policies are not found",
+			existingObjects: func(t *testing.T) []runtime.Object { return nil },
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(c.existingObjects(t)...)
+			err := RemovePolicies(context.TODO(), kubeClient, eventstesting.NewTestingEventRecorder(t))
+			testinghelpers.AssertError(t, err, "")
+
+			actions := kubeClient.Actions()
+			testinghelpers.AssertActions(t, actions, "delete", "delete", "delete", "delete")
+			if actions[0].GetResource().Resource != "validatingadmissionpolicybindings" {
+				t.Errorf("expected the bindings are removed first, but got %v", actions[0])
+			}
+			policies, err := kubeClient.AdmissionregistrationV1alpha1().ValidatingAdmissionPolicies().List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(policies.Items) != 0 {
+				t.Errorf("expected the policies are removed, but got %d", len(policies.Items))
+			}
+		})
+	}
+}
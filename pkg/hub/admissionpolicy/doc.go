@@ -0,0 +1,3 @@
+// package admissionpolicy contains the hub-side controller which maintains the ValidatingAdmissionPolicies
+// enforcing the structural rules of the webhook with CEL on Kubernetes 1.26+.
+package admissionpolicy
@@ -0,0 +1,152 @@
+package agentversion
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/version"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/klog/v2"
+)
+
+// agentVersionController compares the version and the api compatibility level of the registration
+// agents published in the annotations of the managed clusters with the hub, and reports the result
+// with the AgentVersionCompatible condition of the managed clusters.
+type agentVersionController struct {
+	clusterClient    clientset.Interface
+	clusterLister    listerv1.ManagedClusterLister
+	hubVersionString string
+	// hubVersion is nil if the hub is not built from a release
+	hubVersion     *utilversion.Version
+	maxVersionSkew int
+	eventRecorder  events.Recorder
+}
+
+// NewAgentVersionController creates a new agent version controller. The agent of a managed cluster
+// is reported as incompatible once its minor version is more than maxVersionSkew releases behind
+// the hubVersion, or its api compatibility level is different from the hub.
+func NewAgentVersionController(
+	clusterClient clientset.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	hubVersion string,
+	maxVersionSkew int,
+	recorder events.Recorder) factory.Controller {
+	c := &agentVersionController{
+		clusterClient:    clusterClient,
+		clusterLister:    clusterInformer.Lister(),
+		hubVersionString: hubVersion,
+		maxVersionSkew:   maxVersionSkew,
+		eventRecorder:    recorder.WithComponentSuffix("agent-version-controller"),
+	}
+	if v, err := utilversion.ParseGeneric(hubVersion); err == nil {
+		c.hubVersion = v
+	} else {
+		klog.Warningf("Unable to parse the hub version %q, the version skew of the agents is not checked: %v", hubVersion, err)
+	}
+
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(helpers.RecoverSync("AgentVersionController", c.sync)).
+		ToController("AgentVersionController", recorder)
+}
+
+func (c *agentVersionController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	managedClusterName := syncCtx.QueueKey()
+	managedCluster, err := c.clusterLister.Get(managedClusterName)
+	if errors.IsNotFound(err) {
+		// Spoke cluster not found, could have been deleted, do nothing.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !managedCluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	// the agent publishes its version after the managed cluster is accepted
+	if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, v1.ManagedClusterConditionHubAccepted) {
+		return nil
+	}
+
+	cond := c.agentVersionCondition(managedCluster)
+	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.clusterClient, managedClusterName,
+		helpers.UpdateManagedClusterConditionFn(cond))
+	if err != nil {
+		return err
+	}
+	if updated && cond.Status == metav1.ConditionFalse {
+		c.eventRecorder.Warningf("AgentVersionIncompatible", "managed cluster %s: %s", managedClusterName, cond.Message)
+	}
+	return nil
+}
+
+func (c *agentVersionController) agentVersionCondition(managedCluster *v1.ManagedCluster) metav1.Condition {
+	cond := metav1.Condition{
+		Type:    helpers.ManagedClusterConditionAgentVersionCompatible,
+		Status:  metav1.ConditionTrue,
+		Reason:  "AgentVersionCompatible",
+		Message: "The registration agent is compatible with the hub.",
+	}
+
+	level, ok := managedCluster.Annotations[helpers.AgentAPICompatibilityLevelAnnotation]
+	if !ok {
+		cond.Status = metav1.ConditionUnknown
+		cond.Reason = "AgentVersionUnknown"
+		cond.Message = "The registration agent does not report its version."
+		return cond
+	}
+	if level != strconv.Itoa(version.APICompatibilityLevel) {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "AgentAPIIncompatible"
+		cond.Message = fmt.Sprintf("The api compatibility level %s of the registration agent is different from the level %d of the hub.",
+			level, version.APICompatibilityLevel)
+		return cond
+	}
+
+	if c.hubVersion == nil {
+		return cond
+	}
+	agentVersionString := managedCluster.Annotations[helpers.AgentVersionAnnotation]
+	agentVersion, err := utilversion.ParseGeneric(agentVersionString)
+	if err != nil {
+		// the agent is not built from a release
+		return cond
+	}
+	if releasesBehind(c.hubVersion, agentVersion) > c.maxVersionSkew {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "AgentVersionOutdated"
+		cond.Message = fmt.Sprintf("The version %s of the registration agent is more than %d releases behind the version %s of the hub.",
+			agentVersionString, c.maxVersionSkew, c.hubVersionString)
+	}
+	return cond
+}
+
+// releasesBehind returns the number of the minor releases the agent version is behind the hub version,
+// an agent of an older major version is always considered too old.
+func releasesBehind(hubVersion, agentVersion *utilversion.Version) int {
+	switch {
+	case agentVersion.Major() < hubVersion.Major():
+		return math.MaxInt
+	case agentVersion.Major() > hubVersion.Major():
+		return 0
+	}
+	return int(hubVersion.Minor()) - int(agentVersion.Minor())
+}
@@ -0,0 +1,184 @@
+package agentversion
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newAcceptedManagedCluster(annotations map[string]string) *v1.ManagedCluster {
+	managedCluster := testinghelpers.NewAcceptedManagedCluster()
+	managedCluster.Annotations = annotations
+	return managedCluster
+}
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name              string
+		hubVersion        string
+		startingObjects   []runtime.Object
+		expectedCondition *metav1.Condition
+	}{
+		{
+			name:            "managed cluster is not found",
+			hubVersion:      "v0.11.0",
+			startingObjects: []runtime.Object{},
+		},
+		{
+			name:            "managed cluster is not accepted",
+			hubVersion:      "v0.11.0",
+			startingObjects: []runtime.Object{testinghelpers.NewManagedCluster()},
+		},
+		{
+			name:            "agent does not report its version",
+			hubVersion:      "v0.11.0",
+			startingObjects: []runtime.Object{newAcceptedManagedCluster(nil)},
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionAgentVersionCompatible,
+				Status:  metav1.ConditionUnknown,
+				Reason:  "AgentVersionUnknown",
+				Message: "The registration agent does not report its version.",
+			},
+		},
+		{
+			name:       "agent is compatible",
+			hubVersion: "v0.11.0",
+			startingObjects: []runtime.Object{newAcceptedManagedCluster(map[string]string{
+				helpers.AgentVersionAnnotation:               "v0.9.2",
+				helpers.AgentAPICompatibilityLevelAnnotation: "1",
+			})},
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionAgentVersionCompatible,
+				Status:  metav1.ConditionTrue,
+				Reason:  "AgentVersionCompatible",
+				Message: "The registration agent is compatible with the hub.",
+			},
+		},
+		{
+			name:       "agent is not built from a release",
+			hubVersion: "v0.11.0",
+			startingObjects: []runtime.Object{newAcceptedManagedCluster(map[string]string{
+				helpers.AgentAPICompatibilityLevelAnnotation: "1",
+			})},
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionAgentVersionCompatible,
+				Status:  metav1.ConditionTrue,
+				Reason:  "AgentVersionCompatible",
+				Message: "The registration agent is compatible with the hub.",
+			},
+		},
+		{
+			name:       "hub is not built from a release",
+			hubVersion: "",
+			startingObjects: []runtime.Object{newAcceptedManagedCluster(map[string]string{
+				helpers.AgentVersionAnnotation:               "v0.1.0",
+				helpers.AgentAPICompatibilityLevelAnnotation: "1",
+			})},
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionAgentVersionCompatible,
+				Status:  metav1.ConditionTrue,
+				Reason:  "AgentVersionCompatible",
+				Message: "The registration agent is compatible with the hub.",
+			},
+		},
+		{
+			name:       "agent is outdated",
+			hubVersion: "v0.11.0",
+			startingObjects: []runtime.Object{newAcceptedManagedCluster(map[string]string{
+				helpers.AgentVersionAnnotation:               "v0.8.1-12-gabcdef",
+				helpers.AgentAPICompatibilityLevelAnnotation: "1",
+			})},
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionAgentVersionCompatible,
+				Status:  metav1.ConditionFalse,
+				Reason:  "AgentVersionOutdated",
+				Message: "The version v0.8.1-12-gabcdef of the registration agent is more than 2 releases behind the version v0.11.0 of the hub.",
+			},
+		},
+		{
+			name:       "agent api is incompatible",
+			hubVersion: "v0.11.0",
+			startingObjects: []runtime.Object{newAcceptedManagedCluster(map[string]string{
+				helpers.AgentVersionAnnotation:               "v0.11.0",
+				helpers.AgentAPICompatibilityLevelAnnotation: "0",
+			})},
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionAgentVersionCompatible,
+				Status:  metav1.ConditionFalse,
+				Reason:  "AgentAPIIncompatible",
+				Message: "The api compatibility level 0 of the registration agent is different from the level 1 of the hub.",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.startingObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterInformer := clusterInformerFactory.Cluster().V1().ManagedClusters()
+			for _, cluster := range c.startingObjects {
+				if err := clusterInformer.Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			recorder := eventstesting.NewTestingEventRecorder(t)
+			ctrl := NewAgentVersionController(clusterClient, clusterInformer, c.hubVersion, 2, recorder)
+			syncErr := ctrl.Sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			actions := clusterClient.Actions()
+			if c.expectedCondition == nil {
+				testinghelpers.AssertNoActions(t, actions)
+				return
+			}
+			testinghelpers.AssertActions(t, actions, "get", "patch")
+			managedCluster := &v1.ManagedCluster{}
+			if err := json.Unmarshal(actions[1].(clienttesting.PatchAction).GetPatch(), managedCluster); err != nil {
+				t.Fatal(err)
+			}
+			testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, *c.expectedCondition)
+		})
+	}
+}
+
+func TestReleasesBehind(t *testing.T) {
+	cases := []struct {
+		hubVersion   string
+		agentVersion string
+		expected     int
+	}{
+		{hubVersion: "v0.11.0", agentVersion: "v0.11.3", expected: 0},
+		{hubVersion: "v0.11.0", agentVersion: "v0.9.0", expected: 2},
+		{hubVersion: "v0.11.0", agentVersion: "v0.12.0", expected: -1},
+		{hubVersion: "v1.0.0", agentVersion: "v2.0.0", expected: 0},
+	}
+	for _, c := range cases {
+		t.Run(c.hubVersion+"-"+c.agentVersion, func(t *testing.T) {
+			actual := releasesBehind(utilversion.MustParseGeneric(c.hubVersion), utilversion.MustParseGeneric(c.agentVersion))
+			if actual != c.expected {
+				t.Errorf("expected %d, but got %d", c.expected, actual)
+			}
+		})
+	}
+
+	if actual := releasesBehind(utilversion.MustParseGeneric("v1.0.0"), utilversion.MustParseGeneric("v0.13.0")); actual <= 2 {
+		t.Errorf("expected an agent of an older major version to be outdated, but got %d", actual)
+	}
+}
@@ -0,0 +1,3 @@
+// package agentversion contains the hub-side controller which reports the registration agents which
+// are too old for the hub.
+package agentversion
@@ -0,0 +1,192 @@
+package awsauth
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/hub/user"
+)
+
+const (
+	// AWSAuthNamespace and AWSAuthName is the ConfigMap used by EKS to map AWS IAM roles to kubernetes identities
+	AWSAuthNamespace = "kube-system"
+	AWSAuthName      = "aws-auth"
+
+	// MapRolesAnnotation is the annotation of the aws-auth ConfigMap which lists the usernames of the mapRoles
+	// entries written by the controller, separated by comma. Only these entries are changed or removed by the
+	// controller, the entries added by others are kept even if they are for the managed clusters.
+	MapRolesAnnotation = "cluster.open-cluster-management.io/managed-cluster-map-roles"
+
+	mapRolesKey = "mapRoles"
+	queueKey    = AWSAuthNamespace + "/" + AWSAuthName
+)
+
+// mapRole is an entry of the mapRoles in the aws-auth ConfigMap
+type mapRole struct {
+	RoleARN  string   `json:"rolearn"`
+	Username string   `json:"username"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// awsAuthController maps the AWS IAM role in the annotation of each accepted managed cluster to the
+// identity of the managed cluster in the aws-auth ConfigMap, so the agent of the managed cluster is able
+// to access the EKS hub with the IAM role as if it used a client certificate issued for the cluster.
+// The mapping is removed once the managed cluster is denied or deleted. Entries of the aws-auth
+// ConfigMap which are not written by the controller are kept as they are.
+type awsAuthController struct {
+	kubeClient    kubernetes.Interface
+	clusterLister listerv1.ManagedClusterLister
+	eventRecorder events.Recorder
+}
+
+// NewAWSAuthController creates a new aws-auth controller
+func NewAWSAuthController(
+	kubeClient kubernetes.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &awsAuthController{
+		kubeClient:    kubeClient,
+		clusterLister: clusterInformer.Lister(),
+		eventRecorder: recorder.WithComponentSuffix("aws-auth-controller"),
+	}
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return queueKey
+		}, clusterInformer.Informer()).
+		WithSync(helpers.RecoverSync("AWSAuthController", c.sync)).
+		ResyncEvery(10*time.Minute).
+		ToController("AWSAuthController", recorder)
+}
+
+func (c *awsAuthController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	managedClusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	desired := []mapRole{}
+	for _, managedCluster := range managedClusters {
+		if !isIdentityMappingRequired(managedCluster) {
+			continue
+		}
+		desired = append(desired, mapRole{
+			RoleARN:  managedCluster.Annotations[user.IAMRoleARNAnnotation],
+			Username: fmt.Sprintf("%s%s:agent", user.SubjectPrefix, managedCluster.Name),
+			Groups:   []string{user.SubjectPrefix + managedCluster.Name, user.ManagedClustersGroup},
+		})
+	}
+	sort.Slice(desired, func(i, j int) bool { return desired[i].Username < desired[j].Username })
+	usernames := []string{}
+	for _, role := range desired {
+		usernames = append(usernames, role.Username)
+	}
+	desiredAnnotation := strings.Join(usernames, ",")
+
+	configMap, err := c.kubeClient.CoreV1().ConfigMaps(AWSAuthNamespace).Get(ctx, AWSAuthName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		if len(desired) == 0 {
+			return nil
+		}
+		mapRoles, err := yaml.Marshal(desired)
+		if err != nil {
+			return err
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   AWSAuthNamespace,
+				Name:        AWSAuthName,
+				Annotations: map[string]string{MapRolesAnnotation: desiredAnnotation},
+			},
+			Data: map[string]string{mapRolesKey: string(mapRoles)},
+		}
+		if _, err := c.kubeClient.CoreV1().ConfigMaps(AWSAuthNamespace).Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		c.eventRecorder.Eventf("AWSAuthCreated", "The aws-auth configmap is created with %d managed cluster identities", len(desired))
+		return nil
+	case err != nil:
+		return err
+	}
+
+	existing := []mapRole{}
+	if err := yaml.Unmarshal([]byte(configMap.Data[mapRolesKey]), &existing); err != nil {
+		return fmt.Errorf("unable to parse %s of configmap %s: %w", mapRolesKey, queueKey, err)
+	}
+
+	// keep the entries not written by the controller and replace the others with the desired ones. An entry
+	// which is the same as a desired one is taken over, so it is not duplicated, e.g. the entries written
+	// before the controller annotated them.
+	written := sets.New[string]()
+	if annotation := configMap.Annotations[MapRolesAnnotation]; len(annotation) != 0 {
+		written.Insert(strings.Split(annotation, ",")...)
+	}
+	mapRoles := []mapRole{}
+	managed := []mapRole{}
+	for _, role := range existing {
+		if written.Has(role.Username) || containsMapRole(desired, role) {
+			managed = append(managed, role)
+			continue
+		}
+		mapRoles = append(mapRoles, role)
+	}
+	if reflect.DeepEqual(managed, desired) && configMap.Annotations[MapRolesAnnotation] == desiredAnnotation {
+		return nil
+	}
+	mapRoles = append(mapRoles, desired...)
+
+	data, err := yaml.Marshal(mapRoles)
+	if err != nil {
+		return err
+	}
+	configMap = configMap.DeepCopy()
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[mapRolesKey] = string(data)
+	if configMap.Annotations == nil {
+		configMap.Annotations = map[string]string{}
+	}
+	configMap.Annotations[MapRolesAnnotation] = desiredAnnotation
+	if _, err := c.kubeClient.CoreV1().ConfigMaps(AWSAuthNamespace).Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	c.eventRecorder.Eventf("AWSAuthUpdated", "The aws-auth configmap is updated with %d managed cluster identities", len(desired))
+	return nil
+}
+
+func containsMapRole(mapRoles []mapRole, role mapRole) bool {
+	for _, r := range mapRoles {
+		if reflect.DeepEqual(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdentityMappingRequired returns true if the managed cluster is accepted and has an IAM role annotated.
+func isIdentityMappingRequired(managedCluster *v1.ManagedCluster) bool {
+	if !managedCluster.DeletionTimestamp.IsZero() || !managedCluster.Spec.HubAcceptsClient {
+		return false
+	}
+	return len(managedCluster.Annotations[user.IAMRoleARNAnnotation]) != 0
+}
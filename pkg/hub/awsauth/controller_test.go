@@ -0,0 +1,190 @@
+package awsauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/hub/user"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	testRoleARN  = "arn:aws:iam::123456789012:role/testmanagedcluster"
+	otherRoleARN = "arn:aws:iam::123456789012:role/node"
+)
+
+const testUsername = "system:open-cluster-management:testmanagedcluster:agent"
+
+var testMapRoles = `- rolearn: ` + testRoleARN + `
+  username: ` + testUsername + `
+  groups:
+  - system:open-cluster-management:testmanagedcluster
+  - system:open-cluster-management:managed-clusters
+`
+
+var otherMapRoles = `- rolearn: ` + otherRoleARN + `
+  username: system:node:{{EC2PrivateDNSName}}
+  groups:
+  - system:bootstrappers
+  - system:nodes
+`
+
+func newAnnotatedManagedCluster(cluster *v1.ManagedCluster) *v1.ManagedCluster {
+	cluster.Annotations = map[string]string{user.IAMRoleARNAnnotation: testRoleARN}
+	return cluster
+}
+
+func newAWSAuth(mapRoles string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: AWSAuthNamespace, Name: AWSAuthName},
+		Data:       map[string]string{mapRolesKey: mapRoles},
+	}
+}
+
+func newWrittenAWSAuth(mapRoles, usernames string) *corev1.ConfigMap {
+	configMap := newAWSAuth(mapRoles)
+	configMap.Annotations = map[string]string{MapRolesAnnotation: usernames}
+	return configMap
+}
+
+func assertMapRolesAnnotation(t *testing.T, obj runtime.Object, expected string) {
+	configMap := obj.(*corev1.ConfigMap)
+	if actual := configMap.Annotations[MapRolesAnnotation]; actual != expected {
+		t.Errorf("expected annotation %q, but got %q", expected, actual)
+	}
+}
+
+func assertMapRoles(t *testing.T, obj runtime.Object, expectedRoleARNs ...string) {
+	configMap := obj.(*corev1.ConfigMap)
+	mapRoles := []mapRole{}
+	if err := yaml.Unmarshal([]byte(configMap.Data[mapRolesKey]), &mapRoles); err != nil {
+		t.Fatal(err)
+	}
+	if len(mapRoles) != len(expectedRoleARNs) {
+		t.Fatalf("expected %d map roles, but got %v", len(expectedRoleARNs), mapRoles)
+	}
+	for i, roleARN := range expectedRoleARNs {
+		if mapRoles[i].RoleARN != roleARN {
+			t.Errorf("expected role arn %q, but got %q", roleARN, mapRoles[i].RoleARN)
+		}
+	}
+}
+
+func TestSyncAWSAuth(t *testing.T) {
+	cases := []struct {
+		name            string
+		clusters        []runtime.Object
+		configMaps      []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:     "no managed cluster with iam role",
+			clusters: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:     "create aws-auth",
+			clusters: []runtime.Object{newAnnotatedManagedCluster(testinghelpers.NewAcceptedManagedCluster())},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create")
+				actual := actions[1].(clienttesting.CreateActionImpl).Object
+				assertMapRoles(t, actual, testRoleARN)
+				assertMapRolesAnnotation(t, actual, testUsername)
+			},
+		},
+		{
+			name:       "add identity mapping to aws-auth",
+			clusters:   []runtime.Object{newAnnotatedManagedCluster(testinghelpers.NewAcceptedManagedCluster())},
+			configMaps: []runtime.Object{newAWSAuth(otherMapRoles)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				actual := actions[1].(clienttesting.UpdateActionImpl).Object
+				assertMapRoles(t, actual, otherRoleARN, testRoleARN)
+				assertMapRolesAnnotation(t, actual, testUsername)
+			},
+		},
+		{
+			name:       "identity mapping is up to date",
+			clusters:   []runtime.Object{newAnnotatedManagedCluster(testinghelpers.NewAcceptedManagedCluster())},
+			configMaps: []runtime.Object{newWrittenAWSAuth(otherMapRoles+testMapRoles, testUsername)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:       "take over the same identity mapping without annotation",
+			clusters:   []runtime.Object{newAnnotatedManagedCluster(testinghelpers.NewAcceptedManagedCluster())},
+			configMaps: []runtime.Object{newAWSAuth(otherMapRoles + testMapRoles)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				actual := actions[1].(clienttesting.UpdateActionImpl).Object
+				assertMapRoles(t, actual, otherRoleARN, testRoleARN)
+				assertMapRolesAnnotation(t, actual, testUsername)
+			},
+		},
+		{
+			name:     "remove identity mapping of denied cluster",
+			clusters: []runtime.Object{newAnnotatedManagedCluster(testinghelpers.NewDeniedManagedCluster())},
+			configMaps: []runtime.Object{newWrittenAWSAuth(otherMapRoles+`- rolearn: `+testRoleARN+`
+  username: system:open-cluster-management:testmanagedcluster:agent
+`, testUsername)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				actual := actions[1].(clienttesting.UpdateActionImpl).Object
+				assertMapRoles(t, actual, otherRoleARN)
+				assertMapRolesAnnotation(t, actual, "")
+			},
+		},
+		{
+			name:     "keep identity mapping added by others",
+			clusters: []runtime.Object{newAnnotatedManagedCluster(testinghelpers.NewDeniedManagedCluster())},
+			configMaps: []runtime.Object{newAWSAuth(otherMapRoles + `- rolearn: ` + testRoleARN + `
+  username: system:open-cluster-management:testmanagedcluster:agent
+`)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.clusters {
+				if err := clusterStore.Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+			kubeClient := kubefake.NewSimpleClientset(c.configMaps...)
+
+			ctrl := &awsAuthController{
+				kubeClient:    kubeClient,
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				eventRecorder: eventstesting.NewTestingEventRecorder(t),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, queueKey))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
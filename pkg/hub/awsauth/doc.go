@@ -0,0 +1,3 @@
+// package awsauth contains the controller which maps the AWS IAM roles of the managed clusters to
+// their identities on an EKS hub.
+package awsauth
@@ -0,0 +1,144 @@
+package clientconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// clientConfigDriftController records the hash of the client configs of a managed cluster in the
+// ManagedClusterClientConfigsHashAnnotation once the managed cluster is accepted, and reports with the
+// ClientConfigsDrifted condition whether the client configs reported by the agent are changed since then,
+// which helps to detect a managed cluster re-registered with a different api server.
+type clientConfigDriftController struct {
+	clusterClient clientset.Interface
+	clusterLister listerv1.ManagedClusterLister
+	eventRecorder events.Recorder
+}
+
+// NewClientConfigDriftController creates a new client config drift controller.
+func NewClientConfigDriftController(
+	clusterClient clientset.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &clientConfigDriftController{
+		clusterClient: clusterClient,
+		clusterLister: clusterInformer.Lister(),
+		eventRecorder: recorder.WithComponentSuffix("client-config-drift-controller"),
+	}
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(helpers.RecoverSync("ClientConfigDriftController", c.sync)).
+		ToController("ClientConfigDriftController", recorder)
+}
+
+func (c *clientConfigDriftController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	managedClusterName := syncCtx.QueueKey()
+	managedCluster, err := c.clusterLister.Get(managedClusterName)
+	if errors.IsNotFound(err) {
+		// Spoke cluster not found, could have been deleted, do nothing.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !managedCluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	recordedHash, recorded := managedCluster.Annotations[helpers.ManagedClusterClientConfigsHashAnnotation]
+	// the recorded client configs are forgotten once the managed cluster is denied, so that it can be
+	// accepted with other client configs
+	if !managedCluster.Spec.HubAcceptsClient {
+		if !recorded {
+			return nil
+		}
+		return c.patchHashAnnotation(ctx, managedClusterName, nil)
+	}
+	// the client configs are reported by the agent after the managed cluster is accepted
+	if len(managedCluster.Spec.ManagedClusterClientConfigs) == 0 {
+		return nil
+	}
+
+	hash, err := clientConfigsHash(managedCluster.Spec.ManagedClusterClientConfigs)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		if err := c.patchHashAnnotation(ctx, managedClusterName, &hash); err != nil {
+			return err
+		}
+		recordedHash = hash
+	}
+
+	cond := metav1.Condition{
+		Type:    helpers.ManagedClusterConditionClientConfigsDrifted,
+		Status:  metav1.ConditionFalse,
+		Reason:  "ClientConfigsUnchanged",
+		Message: "The client configs of the managed cluster are the same as the recorded ones.",
+	}
+	if recordedHash != hash {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "ClientConfigsChanged"
+		cond.Message = fmt.Sprintf("The client configs of the managed cluster are different from the recorded ones, "+
+			"remove the annotation %q to accept the current client configs.", helpers.ManagedClusterClientConfigsHashAnnotation)
+	}
+	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.clusterClient, managedClusterName,
+		helpers.UpdateManagedClusterConditionFn(cond))
+	if err != nil {
+		return err
+	}
+	if updated && cond.Status == metav1.ConditionTrue {
+		c.eventRecorder.Warningf("ClientConfigsDrifted", "managed cluster %s: %s", managedClusterName, cond.Message)
+	}
+	return nil
+}
+
+// patchHashAnnotation sets the hash annotation of the managed cluster, or removes it if the hash is nil.
+func (c *clientConfigDriftController) patchHashAnnotation(ctx context.Context, managedClusterName string, hash *string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{
+				helpers.ManagedClusterClientConfigsHashAnnotation: hash,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.clusterClient.ClusterV1().ManagedClusters().Patch(ctx, managedClusterName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// clientConfigsHash returns the hash of the client configs, the order of the client configs is ignored.
+func clientConfigsHash(clientConfigs []v1.ClientConfig) (string, error) {
+	sorted := make([]v1.ClientConfig, len(clientConfigs))
+	copy(sorted, clientConfigs)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].URL < sorted[j].URL })
+
+	data, err := json.Marshal(sorted)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
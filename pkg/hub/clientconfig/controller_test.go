@@ -0,0 +1,155 @@
+package clientconfig
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var clientConfigs = []v1.ClientConfig{
+	{URL: "https://api.cluster1.example.com:6443"},
+	{URL: "https://api-internal.cluster1.example.com:6443", CABundle: []byte("ca")},
+}
+
+func newManagedCluster(accepted bool, clientConfigs []v1.ClientConfig, hash string) *v1.ManagedCluster {
+	managedCluster := testinghelpers.NewAcceptedManagedCluster()
+	managedCluster.Spec.HubAcceptsClient = accepted
+	managedCluster.Spec.ManagedClusterClientConfigs = clientConfigs
+	if len(hash) != 0 {
+		managedCluster.Annotations = map[string]string{helpers.ManagedClusterClientConfigsHashAnnotation: hash}
+	}
+	return managedCluster
+}
+
+func TestSync(t *testing.T) {
+	hash, err := clientConfigsHash(clientConfigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name              string
+		startingObjects   []runtime.Object
+		validateActions   func(t *testing.T, actions []clienttesting.Action)
+		expectedCondition *metav1.Condition
+	}{
+		{
+			name:            "managed cluster is not found",
+			startingObjects: []runtime.Object{},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "managed cluster is not accepted",
+			startingObjects: []runtime.Object{newManagedCluster(false, clientConfigs, "")},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "managed cluster is denied",
+			startingObjects: []runtime.Object{newManagedCluster(false, clientConfigs, hash)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := string(actions[0].(clienttesting.PatchAction).GetPatch())
+				expected := `{"metadata":{"annotations":{"cluster.open-cluster-management.io/client-configs-hash":null}}}`
+				if patch != expected {
+					t.Errorf("expected patch %s, but got %s", expected, patch)
+				}
+			},
+		},
+		{
+			name:            "client configs are not reported",
+			startingObjects: []runtime.Object{newManagedCluster(true, nil, "")},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "record the client configs",
+			startingObjects: []runtime.Object{newManagedCluster(true, clientConfigs, "")},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch", "get", "patch")
+				managedCluster := &v1.ManagedCluster{}
+				if err := json.Unmarshal(actions[0].(clienttesting.PatchAction).GetPatch(), managedCluster); err != nil {
+					t.Fatal(err)
+				}
+				if managedCluster.Annotations[helpers.ManagedClusterClientConfigsHashAnnotation] != hash {
+					t.Errorf("expected the hash of the client configs recorded, but got %v", managedCluster.Annotations)
+				}
+			},
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionClientConfigsDrifted,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ClientConfigsUnchanged",
+				Message: "The client configs of the managed cluster are the same as the recorded ones.",
+			},
+		},
+		{
+			name: "client configs are reordered",
+			startingObjects: []runtime.Object{newManagedCluster(true,
+				[]v1.ClientConfig{clientConfigs[1], clientConfigs[0]}, hash)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+			},
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionClientConfigsDrifted,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ClientConfigsUnchanged",
+				Message: "The client configs of the managed cluster are the same as the recorded ones.",
+			},
+		},
+		{
+			name: "client configs are changed",
+			startingObjects: []runtime.Object{newManagedCluster(true,
+				[]v1.ClientConfig{{URL: "https://api.other.example.com:6443"}}, hash)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+			},
+			expectedCondition: &metav1.Condition{
+				Type:   helpers.ManagedClusterConditionClientConfigsDrifted,
+				Status: metav1.ConditionTrue,
+				Reason: "ClientConfigsChanged",
+				Message: "The client configs of the managed cluster are different from the recorded ones, " +
+					"remove the annotation \"cluster.open-cluster-management.io/client-configs-hash\" to accept the current client configs.",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.startingObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterInformer := clusterInformerFactory.Cluster().V1().ManagedClusters()
+			for _, cluster := range c.startingObjects {
+				if err := clusterInformer.Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			recorder := eventstesting.NewTestingEventRecorder(t)
+			ctrl := NewClientConfigDriftController(clusterClient, clusterInformer, recorder)
+			syncErr := ctrl.Sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			testinghelpers.AssertError(t, syncErr, "")
+
+			actions := clusterClient.Actions()
+			c.validateActions(t, actions)
+			if c.expectedCondition == nil {
+				return
+			}
+			managedCluster := &v1.ManagedCluster{}
+			if err := json.Unmarshal(actions[len(actions)-1].(clienttesting.PatchAction).GetPatch(), managedCluster); err != nil {
+				t.Fatal(err)
+			}
+			testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, *c.expectedCondition)
+		})
+	}
+}
@@ -0,0 +1,3 @@
+// package clientconfig contains the hub-side controller which detects the changes of the client configs
+// of the accepted managed clusters.
+package clientconfig
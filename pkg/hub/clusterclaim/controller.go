@@ -0,0 +1,165 @@
+package clusterclaim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+)
+
+// fieldManager is the field manager of the labels applied by the controller.
+const fieldManager = "cluster-claim-labels"
+
+// clusterClaimLabelController copies the cluster claims in the allowlist into the labels of the
+// managed clusters, so that they can be used by the label selectors of Placements. The claim name is
+// the label key and the claim value is the label value, a claim which is not a valid label is ignored.
+//
+// The labels are applied with server-side apply, the controller only owns the labels it applies, the
+// labels are removed once the claims are removed, and a label set by others with a different value is
+// never overwritten.
+type clusterClaimLabelController struct {
+	clusterClient clientset.Interface
+	clusterLister listerv1.ManagedClusterLister
+	claims        sets.Set[string]
+	eventRecorder events.Recorder
+}
+
+// NewClusterClaimLabelController creates a new cluster claim label controller which copies the claims
+// into the labels of the managed clusters.
+func NewClusterClaimLabelController(
+	clusterClient clientset.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	claims []string,
+	recorder events.Recorder) factory.Controller {
+	c := &clusterClaimLabelController{
+		clusterClient: clusterClient,
+		clusterLister: clusterInformer.Lister(),
+		claims:        sets.New[string](claims...),
+		eventRecorder: recorder.WithComponentSuffix("cluster-claim-label-controller"),
+	}
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(helpers.RecoverSync("ClusterClaimLabelController", c.sync)).
+		ToController("ClusterClaimLabelController", recorder)
+}
+
+func (c *clusterClaimLabelController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	managedClusterName := syncCtx.QueueKey()
+	klog.V(4).Infof("Reconciling ManagedCluster %s", managedClusterName)
+	managedCluster, err := c.clusterLister.Get(managedClusterName)
+	if errors.IsNotFound(err) {
+		// Spoke cluster not found, could have been deleted, do nothing.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !managedCluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	labels := c.claimLabels(managedCluster)
+	if !c.needsApply(managedCluster, labels) {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"apiVersion": v1.GroupVersion.String(),
+		"kind":       "ManagedCluster",
+		"metadata": map[string]interface{}{
+			"name":   managedClusterName,
+			"labels": labels,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.clusterClient.ClusterV1().ManagedClusters().Patch(ctx, managedClusterName, types.ApplyPatchType, patch,
+		metav1.PatchOptions{FieldManager: fieldManager})
+	if errors.IsConflict(err) {
+		// the labels are owned by others, do not overwrite them
+		c.eventRecorder.Warningf("ClusterClaimLabelsConflict", "Unable to copy the cluster claims into the labels of managed cluster %q: %v",
+			managedClusterName, err)
+		return nil
+	}
+	return err
+}
+
+// claimLabels returns the labels of the claims in the allowlist.
+func (c *clusterClaimLabelController) claimLabels(managedCluster *v1.ManagedCluster) map[string]string {
+	labels := map[string]string{}
+	for _, claim := range managedCluster.Status.ClusterClaims {
+		if !c.claims.Has(claim.Name) {
+			continue
+		}
+		if errs := validation.IsQualifiedName(claim.Name); len(errs) != 0 {
+			klog.V(4).Infof("Claim %q of managed cluster %q is not a valid label key: %s", claim.Name, managedCluster.Name, strings.Join(errs, "; "))
+			continue
+		}
+		if errs := validation.IsValidLabelValue(claim.Value); len(errs) != 0 {
+			klog.V(4).Infof("Value of claim %q of managed cluster %q is not a valid label value: %s", claim.Name, managedCluster.Name, strings.Join(errs, "; "))
+			continue
+		}
+		labels[claim.Name] = claim.Value
+	}
+	return labels
+}
+
+// needsApply returns true if the labels are not set, or the labels applied by the controller are not
+// the same as the labels.
+func (c *clusterClaimLabelController) needsApply(managedCluster *v1.ManagedCluster, labels map[string]string) bool {
+	for key, value := range labels {
+		if current, ok := managedCluster.Labels[key]; !ok || current != value {
+			return true
+		}
+	}
+
+	applied, err := appliedLabels(managedCluster)
+	if err != nil {
+		klog.Warningf("Unable to get the labels applied to managed cluster %q: %v", managedCluster.Name, err)
+		return true
+	}
+	return !equality.Semantic.DeepEqual(applied, sets.KeySet(labels))
+}
+
+// appliedLabels returns the keys of the labels applied by the controller from the managed fields.
+func appliedLabels(managedCluster *v1.ManagedCluster) (sets.Set[string], error) {
+	applied := sets.New[string]()
+	for _, entry := range managedCluster.ManagedFields {
+		if entry.Manager != fieldManager || entry.Operation != metav1.ManagedFieldsOperationApply || entry.FieldsV1 == nil {
+			continue
+		}
+
+		fields := map[string]map[string]map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			return nil, fmt.Errorf("unable to decode the managed fields: %w", err)
+		}
+		for key := range fields["f:metadata"]["f:labels"] {
+			applied.Insert(strings.TrimPrefix(key, "f:"))
+		}
+	}
+	return applied, nil
+}
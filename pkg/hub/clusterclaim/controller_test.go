@@ -0,0 +1,153 @@
+package clusterclaim
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestSync(t *testing.T) {
+	newCluster := func(labels map[string]string, appliedFields string, claims ...v1.ManagedClusterClaim) *v1.ManagedCluster {
+		cluster := testinghelpers.NewAcceptedManagedCluster()
+		cluster.Labels = labels
+		cluster.Status.ClusterClaims = claims
+		if len(appliedFields) != 0 {
+			cluster.ManagedFields = []metav1.ManagedFieldsEntry{{
+				Manager:    fieldManager,
+				Operation:  metav1.ManagedFieldsOperationApply,
+				FieldsType: "FieldsV1",
+				FieldsV1:   &metav1.FieldsV1{Raw: []byte(appliedFields)},
+			}}
+		}
+		return cluster
+	}
+	platform := v1.ManagedClusterClaim{Name: "platform.open-cluster-management.io", Value: "AWS"}
+	version := v1.ManagedClusterClaim{Name: "version.openshift.io", Value: "4.12.0"}
+	invalid := v1.ManagedClusterClaim{Name: "id.k8s.io", Value: "invalid value"}
+	appliedPlatform := `{"f:metadata":{"f:labels":{"f:platform.open-cluster-management.io":{}}}}`
+
+	cases := []struct {
+		name            string
+		cluster         *v1.ManagedCluster
+		conflict        bool
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name: "sync a deleted spoke cluster",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:    "no claims in the allowlist",
+			cluster: newCluster(nil, "", v1.ManagedClusterClaim{Name: "kubeversion.open-cluster-management.io", Value: "v1.26.0"}),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:    "copy claims into labels",
+			cluster: newCluster(map[string]string{"k": "v"}, "", platform, version, invalid),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				assertAppliedLabels(t, actions, map[string]string{
+					"platform.open-cluster-management.io": "AWS",
+					"version.openshift.io":                "4.12.0",
+				})
+			},
+		},
+		{
+			name:    "claims are copied already",
+			cluster: newCluster(map[string]string{"platform.open-cluster-management.io": "AWS"}, appliedPlatform, platform),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:    "claim value is changed",
+			cluster: newCluster(map[string]string{"platform.open-cluster-management.io": "GCP"}, appliedPlatform, platform),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				assertAppliedLabels(t, actions, map[string]string{"platform.open-cluster-management.io": "AWS"})
+			},
+		},
+		{
+			name:    "claim is removed",
+			cluster: newCluster(map[string]string{"platform.open-cluster-management.io": "AWS"}, appliedPlatform),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				assertAppliedLabels(t, actions, map[string]string{})
+			},
+		},
+		{
+			name:     "label is owned by others",
+			cluster:  newCluster(map[string]string{"platform.open-cluster-management.io": "GCP"}, "", platform),
+			conflict: true,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				assertAppliedLabels(t, actions, map[string]string{"platform.open-cluster-management.io": "AWS"})
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if c.cluster != nil {
+				objects = append(objects, c.cluster)
+			}
+			clusterClient := clusterfake.NewSimpleClientset(objects...)
+			if c.conflict {
+				clusterClient.PrependReactor("patch", "managedclusters", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "managedclusters"}, c.cluster.Name, nil)
+				})
+			}
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range objects {
+				if err := clusterStore.Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := NewClusterClaimLabelController(clusterClient, clusterInformerFactory.Cluster().V1().ManagedClusters(),
+				[]string{platform.Name, version.Name, invalid.Name}, eventstesting.NewTestingEventRecorder(t))
+			syncErr := ctrl.Sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
+
+func assertAppliedLabels(t *testing.T, actions []clienttesting.Action, expected map[string]string) {
+	testinghelpers.AssertActions(t, actions, "patch")
+	patchAction := actions[0].(clienttesting.PatchActionImpl)
+	if patchAction.GetPatchType() != types.ApplyPatchType {
+		t.Errorf("expected apply patch, but got %q", patchAction.GetPatchType())
+	}
+	managedCluster := &v1.ManagedCluster{}
+	if err := json.Unmarshal(patchAction.GetPatch(), managedCluster); err != nil {
+		t.Fatal(err)
+	}
+	if len(managedCluster.Labels) == 0 && len(expected) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(managedCluster.Labels, expected) {
+		t.Errorf("expected labels %v, but got %v", expected, managedCluster.Labels)
+	}
+}
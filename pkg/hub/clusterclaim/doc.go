@@ -0,0 +1,3 @@
+// package clusterclaim contains the hub-side controller which copies the selected cluster claims of
+// the managed clusters into their labels.
+package clusterclaim
@@ -0,0 +1,132 @@
+package clusteridentity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// clusterIdentityController records the identity of the cluster reported by the agent in the
+// ManagedClusterIdentityAnnotation once the managed cluster is accepted. If another cluster is registered
+// with the same cluster name later, its agent reports a different identity, and the conflict is reported
+// with the ClusterIdentityConflicted condition, a warning event and a metric instead of silently taking
+// over the identity of the managed cluster.
+type clusterIdentityController struct {
+	clusterClient clientset.Interface
+	clusterLister listerv1.ManagedClusterLister
+	eventRecorder events.Recorder
+}
+
+// NewClusterIdentityController creates a new cluster identity controller.
+func NewClusterIdentityController(
+	clusterClient clientset.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &clusterIdentityController{
+		clusterClient: clusterClient,
+		clusterLister: clusterInformer.Lister(),
+		eventRecorder: recorder.WithComponentSuffix("cluster-identity-controller"),
+	}
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(helpers.RecoverSync("ClusterIdentityController", c.sync)).
+		ToController("ClusterIdentityController", recorder)
+}
+
+func (c *clusterIdentityController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	managedClusterName := syncCtx.QueueKey()
+	managedCluster, err := c.clusterLister.Get(managedClusterName)
+	if errors.IsNotFound(err) {
+		// Spoke cluster not found, could have been deleted, remove its metric.
+		clusterIdentityConflicted.DeleteLabelValues(managedClusterName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !managedCluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	recordedIdentity, recorded := managedCluster.Annotations[helpers.ManagedClusterIdentityAnnotation]
+	// the recorded identity is forgotten once the managed cluster is denied, so that it can be accepted
+	// with another identity
+	if !managedCluster.Spec.HubAcceptsClient {
+		clusterIdentityConflicted.DeleteLabelValues(managedClusterName)
+		if !recorded {
+			return nil
+		}
+		return c.patchIdentityAnnotation(ctx, managedClusterName, nil)
+	}
+	// the agents of the old versions do not report the identity
+	identity := managedCluster.Annotations[helpers.AgentClusterIdentityAnnotation]
+	if len(identity) == 0 {
+		return nil
+	}
+
+	if !recorded {
+		if err := c.patchIdentityAnnotation(ctx, managedClusterName, &identity); err != nil {
+			return err
+		}
+		recordedIdentity = identity
+	}
+
+	cond := metav1.Condition{
+		Type:    helpers.ManagedClusterConditionIdentityConflicted,
+		Status:  metav1.ConditionFalse,
+		Reason:  "ClusterIdentityMatched",
+		Message: "The identity reported by the agent is the same as the recorded one.",
+	}
+	clusterIdentityConflicted.WithLabelValues(managedClusterName).Set(0)
+	if recordedIdentity != identity {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "ClusterIdentityMismatched"
+		cond.Message = fmt.Sprintf("The identity %q reported by the agent is different from the recorded identity %q, "+
+			"another cluster may be registered with the same cluster name. Remove the annotation %q to accept the reported identity.",
+			identity, recordedIdentity, helpers.ManagedClusterIdentityAnnotation)
+		clusterIdentityConflicted.WithLabelValues(managedClusterName).Set(1)
+	}
+	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.clusterClient, managedClusterName,
+		helpers.UpdateManagedClusterConditionFn(cond))
+	if err != nil {
+		return err
+	}
+	if updated && cond.Status == metav1.ConditionTrue {
+		c.eventRecorder.Warningf("ClusterIdentityConflicted", "managed cluster %s: %s", managedClusterName, cond.Message)
+	}
+	return nil
+}
+
+// patchIdentityAnnotation sets the identity annotation of the managed cluster, or removes it if the
+// identity is nil.
+func (c *clusterIdentityController) patchIdentityAnnotation(ctx context.Context, managedClusterName string, identity *string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{
+				helpers.ManagedClusterIdentityAnnotation: identity,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.clusterClient.ClusterV1().ManagedClusters().Patch(ctx, managedClusterName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,147 @@
+package clusteridentity
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func newManagedCluster(accepted bool, identity, recordedIdentity string) *v1.ManagedCluster {
+	managedCluster := testinghelpers.NewAcceptedManagedCluster()
+	managedCluster.Spec.HubAcceptsClient = accepted
+	managedCluster.Annotations = map[string]string{}
+	if len(identity) != 0 {
+		managedCluster.Annotations[helpers.AgentClusterIdentityAnnotation] = identity
+	}
+	if len(recordedIdentity) != 0 {
+		managedCluster.Annotations[helpers.ManagedClusterIdentityAnnotation] = recordedIdentity
+	}
+	return managedCluster
+}
+
+func TestSync(t *testing.T) {
+	cases := []struct {
+		name              string
+		startingObjects   []runtime.Object
+		validateActions   func(t *testing.T, actions []clienttesting.Action)
+		expectedCondition *metav1.Condition
+		expectedMetric    float64
+	}{
+		{
+			name:            "managed cluster is not found",
+			startingObjects: []runtime.Object{},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "managed cluster is not accepted",
+			startingObjects: []runtime.Object{newManagedCluster(false, "uid1", "")},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "managed cluster is denied",
+			startingObjects: []runtime.Object{newManagedCluster(false, "uid1", "uid1")},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := string(actions[0].(clienttesting.PatchAction).GetPatch())
+				expected := `{"metadata":{"annotations":{"cluster.open-cluster-management.io/cluster-identity":null}}}`
+				if patch != expected {
+					t.Errorf("expected patch %s, but got %s", expected, patch)
+				}
+			},
+		},
+		{
+			name:            "identity is not reported",
+			startingObjects: []runtime.Object{newManagedCluster(true, "", "")},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "record the identity",
+			startingObjects: []runtime.Object{newManagedCluster(true, "uid1", "")},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "patch", "get", "patch")
+				managedCluster := &v1.ManagedCluster{}
+				if err := json.Unmarshal(actions[0].(clienttesting.PatchAction).GetPatch(), managedCluster); err != nil {
+					t.Fatal(err)
+				}
+				if managedCluster.Annotations[helpers.ManagedClusterIdentityAnnotation] != "uid1" {
+					t.Errorf("expected the identity recorded, but got %v", managedCluster.Annotations)
+				}
+			},
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionIdentityConflicted,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ClusterIdentityMatched",
+				Message: "The identity reported by the agent is the same as the recorded one.",
+			},
+		},
+		{
+			name:            "identity is conflicted",
+			startingObjects: []runtime.Object{newManagedCluster(true, "uid2", "uid1")},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+			},
+			expectedCondition: &metav1.Condition{
+				Type:   helpers.ManagedClusterConditionIdentityConflicted,
+				Status: metav1.ConditionTrue,
+				Reason: "ClusterIdentityMismatched",
+				Message: "The identity \"uid2\" reported by the agent is different from the recorded identity \"uid1\", " +
+					"another cluster may be registered with the same cluster name. " +
+					"Remove the annotation \"cluster.open-cluster-management.io/cluster-identity\" to accept the reported identity.",
+			},
+			expectedMetric: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterIdentityConflicted.Reset()
+
+			clusterClient := clusterfake.NewSimpleClientset(c.startingObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterInformer := clusterInformerFactory.Cluster().V1().ManagedClusters()
+			for _, cluster := range c.startingObjects {
+				if err := clusterInformer.Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			recorder := eventstesting.NewTestingEventRecorder(t)
+			ctrl := NewClusterIdentityController(clusterClient, clusterInformer, recorder)
+			syncErr := ctrl.Sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			testinghelpers.AssertError(t, syncErr, "")
+
+			actions := clusterClient.Actions()
+			c.validateActions(t, actions)
+			if c.expectedCondition == nil {
+				return
+			}
+			managedCluster := &v1.ManagedCluster{}
+			if err := json.Unmarshal(actions[len(actions)-1].(clienttesting.PatchAction).GetPatch(), managedCluster); err != nil {
+				t.Fatal(err)
+			}
+			testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, *c.expectedCondition)
+
+			metric, err := testutil.GetGaugeMetricValue(clusterIdentityConflicted.WithLabelValues(testinghelpers.TestManagedClusterName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metric != c.expectedMetric {
+				t.Errorf("expected metric %v, but got %v", c.expectedMetric, metric)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+// package clusteridentity contains the hub-side controller which detects the managed clusters registered
+// by different clusters with the same cluster name.
+package clusteridentity
@@ -0,0 +1,18 @@
+package clusteridentity
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var clusterIdentityConflicted = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Name: "open_cluster_management_registration_managed_cluster_identity_conflicted",
+		Help: "Whether the identity reported by the agent of a managed cluster is different from the recorded one, 1 if it is conflicted and 0 otherwise.",
+	},
+	[]string{"cluster"},
+)
+
+func init() {
+	legacyregistry.MustRegister(clusterIdentityConflicted)
+}
@@ -0,0 +1,212 @@
+package clusternamespace
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// The modes of the garbage collection of the orphaned cluster namespaces.
+const (
+	// GCModeDisabled keeps the namespaces of the deleted managed clusters, it is the default.
+	GCModeDisabled = "disabled"
+	// GCModeDryRun reports the orphaned cluster namespaces with events without deleting them.
+	GCModeDryRun = "dry-run"
+	// GCModeDelete deletes the orphaned cluster namespaces.
+	GCModeDelete = "delete"
+)
+
+// ResyncInterval is exposed so that integration tests can crank up the controller sync speed.
+var ResyncInterval = 10 * time.Minute
+
+type orphan struct {
+	uid      types.UID
+	since    time.Time
+	reported bool
+}
+
+// orphanedNamespaceController finds the cluster namespaces, which are labeled with the cluster name label by the
+// managedcluster controller, whose managed clusters no longer exist, e.g. a managed cluster is deleted while the
+// workloads in its namespace are not cleaned up. The namespace is deleted, or reported only in the dry-run mode,
+// once it is orphaned for the delay, so that a managed cluster deleted and registered again in the meantime keeps
+// its namespace. The time the namespaces are orphaned is kept in memory, and the delay starts over once the
+// controller is restarted.
+type orphanedNamespaceController struct {
+	kubeClient      kubernetes.Interface
+	clusterClient   clientset.Interface
+	namespaceLister corev1listers.NamespaceLister
+	clusterLister   listerv1.ManagedClusterLister
+	delay           time.Duration
+	dryRun          bool
+	clock           clock.Clock
+	eventRecorder   events.Recorder
+
+	lock     sync.Mutex
+	orphaned map[string]*orphan
+}
+
+// NewOrphanedNamespaceController creates a new orphaned namespace controller, the namespaces are only reported
+// with events if dryRun is true.
+func NewOrphanedNamespaceController(
+	kubeClient kubernetes.Interface,
+	clusterClient clientset.Interface,
+	namespaceInformer corev1informers.NamespaceInformer,
+	clusterInformer informerv1.ManagedClusterInformer,
+	delay time.Duration,
+	dryRun bool,
+	recorder events.Recorder) factory.Controller {
+	c := &orphanedNamespaceController{
+		kubeClient:      kubeClient,
+		clusterClient:   clusterClient,
+		namespaceLister: namespaceInformer.Lister(),
+		clusterLister:   clusterInformer.Lister(),
+		delay:           delay,
+		dryRun:          dryRun,
+		clock:           clock.RealClock{},
+		eventRecorder:   recorder.WithComponentSuffix("orphaned-namespace-controller"),
+		orphaned:        map[string]*orphan{},
+	}
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return accessor.GetName()
+			},
+			func(obj interface{}) bool {
+				accessor, err := meta.Accessor(obj)
+				if err != nil {
+					return false
+				}
+				_, ok := accessor.GetLabels()[clusterv1.ClusterNameLabelKey]
+				return ok
+			},
+			namespaceInformer.Informer()).
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(helpers.RecoverSync("OrphanedNamespaceController", c.sync)).
+		ResyncEvery(ResyncInterval).
+		ToController("OrphanedNamespaceController", recorder)
+}
+
+func (c *orphanedNamespaceController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	namespaceName := syncCtx.QueueKey()
+	if namespaceName == factory.DefaultQueueKey {
+		// handle resync
+		requirement, err := labels.NewRequirement(clusterv1.ClusterNameLabelKey, selection.Exists, nil)
+		if err != nil {
+			return err
+		}
+		namespaces, err := c.namespaceLister.List(labels.NewSelector().Add(*requirement))
+		if err != nil {
+			return err
+		}
+		for _, namespace := range namespaces {
+			syncCtx.Queue().Add(namespace.Name)
+		}
+		return nil
+	}
+
+	namespace, err := c.namespaceLister.Get(namespaceName)
+	if errors.IsNotFound(err) {
+		c.forget(namespaceName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !isClusterNamespace(namespace) || !namespace.DeletionTimestamp.IsZero() {
+		c.forget(namespaceName)
+		return nil
+	}
+
+	_, err = c.clusterLister.Get(namespaceName)
+	switch {
+	case err == nil:
+		c.forget(namespaceName)
+		return nil
+	case !errors.IsNotFound(err):
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	o, ok := c.orphaned[namespaceName]
+	if !ok || o.uid != namespace.UID {
+		o = &orphan{uid: namespace.UID, since: c.clock.Now()}
+		c.orphaned[namespaceName] = o
+		klog.Infof("The managed cluster of namespace %s is not found, the namespace is orphaned", namespaceName)
+	}
+	if remaining := c.delay - c.clock.Since(o.since); remaining > 0 {
+		syncCtx.Queue().AddAfter(namespaceName, remaining)
+		return nil
+	}
+
+	// the managed cluster may be registered again, it is checked once more without the cache before the
+	// namespace is deleted
+	_, err = c.clusterClient.ClusterV1().ManagedClusters().Get(ctx, namespaceName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		delete(c.orphaned, namespaceName)
+		return nil
+	case !errors.IsNotFound(err):
+		return err
+	}
+
+	if c.dryRun {
+		if !o.reported {
+			c.eventRecorder.Warningf("OrphanedClusterNamespace",
+				"namespace %s is orphaned since %s and would be deleted, its managed cluster is not found", namespaceName, o.since.Format(time.RFC3339))
+			o.reported = true
+		}
+		return nil
+	}
+
+	// the namespace recreated in the meantime is not deleted
+	err = c.kubeClient.CoreV1().Namespaces().Delete(ctx, namespaceName, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &o.uid},
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("unable to delete orphaned namespace %s: %w", namespaceName, err)
+	}
+	delete(c.orphaned, namespaceName)
+	c.eventRecorder.Eventf("OrphanedClusterNamespaceDeleted",
+		"namespace %s orphaned since %s is deleted, its managed cluster is not found", namespaceName, o.since.Format(time.RFC3339))
+	return nil
+}
+
+func (c *orphanedNamespaceController) forget(namespaceName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.orphaned, namespaceName)
+}
+
+// isClusterNamespace returns true if the namespace is labeled with its own name as the cluster name.
+func isClusterNamespace(namespace *corev1.Namespace) bool {
+	return namespace.Labels[clusterv1.ClusterNameLabelKey] == namespace.Name
+}
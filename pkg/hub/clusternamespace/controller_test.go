@@ -0,0 +1,170 @@
+package clusternamespace
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func newClusterNamespace(labelValue string) *corev1.Namespace {
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: testinghelpers.TestManagedClusterName,
+			UID:  types.UID("uid1"),
+		},
+	}
+	if len(labelValue) != 0 {
+		namespace.Labels = map[string]string{clusterv1.ClusterNameLabelKey: labelValue}
+	}
+	return namespace
+}
+
+func TestSync(t *testing.T) {
+	now := time.Now()
+	deletingNamespace := newClusterNamespace(testinghelpers.TestManagedClusterName)
+	deletingNamespace.DeletionTimestamp = &metav1.Time{Time: now}
+
+	cases := []struct {
+		name            string
+		namespace       *corev1.Namespace
+		clusters        []runtime.Object
+		liveClusters    []runtime.Object
+		orphanedSince   time.Time
+		dryRun          bool
+		expectedDeleted bool
+		expectedOrphan  bool
+	}{
+		{
+			name: "namespace is not found",
+		},
+		{
+			name:      "namespace is not labeled",
+			namespace: newClusterNamespace(""),
+		},
+		{
+			name:      "namespace is labeled with another cluster",
+			namespace: newClusterNamespace("cluster2"),
+		},
+		{
+			name:      "namespace is deleting",
+			namespace: deletingNamespace,
+		},
+		{
+			name:         "cluster exists",
+			namespace:    newClusterNamespace(testinghelpers.TestManagedClusterName),
+			clusters:     []runtime.Object{testinghelpers.NewManagedCluster()},
+			liveClusters: []runtime.Object{testinghelpers.NewManagedCluster()},
+		},
+		{
+			name:           "namespace is orphaned",
+			namespace:      newClusterNamespace(testinghelpers.TestManagedClusterName),
+			expectedOrphan: true,
+		},
+		{
+			name:           "namespace is orphaned within the delay",
+			namespace:      newClusterNamespace(testinghelpers.TestManagedClusterName),
+			orphanedSince:  now.Add(-30 * time.Minute),
+			expectedOrphan: true,
+		},
+		{
+			name:            "namespace is orphaned for the delay",
+			namespace:       newClusterNamespace(testinghelpers.TestManagedClusterName),
+			orphanedSince:   now.Add(-2 * time.Hour),
+			expectedDeleted: true,
+		},
+		{
+			name:           "namespace is orphaned for the delay in dry-run mode",
+			namespace:      newClusterNamespace(testinghelpers.TestManagedClusterName),
+			orphanedSince:  now.Add(-2 * time.Hour),
+			dryRun:         true,
+			expectedOrphan: true,
+		},
+		{
+			name:          "cluster is registered again",
+			namespace:     newClusterNamespace(testinghelpers.TestManagedClusterName),
+			liveClusters:  []runtime.Object{testinghelpers.NewManagedCluster()},
+			orphanedSince: now.Add(-2 * time.Hour),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if c.namespace != nil {
+				objects = append(objects, c.namespace)
+			}
+			kubeClient := kubefake.NewSimpleClientset(objects...)
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Minute*10)
+			for _, obj := range objects {
+				if err := kubeInformerFactory.Core().V1().Namespaces().Informer().GetStore().Add(obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			clusterClient := clusterfake.NewSimpleClientset(c.liveClusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterfake.NewSimpleClientset(), time.Minute*10)
+			for _, cluster := range c.clusters {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := &orphanedNamespaceController{
+				kubeClient:      kubeClient,
+				clusterClient:   clusterClient,
+				namespaceLister: kubeInformerFactory.Core().V1().Namespaces().Lister(),
+				clusterLister:   clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				delay:           time.Hour,
+				dryRun:          c.dryRun,
+				clock:           clocktesting.NewFakeClock(now),
+				eventRecorder:   eventstesting.NewTestingEventRecorder(t),
+				orphaned:        map[string]*orphan{},
+			}
+			if !c.orphanedSince.IsZero() {
+				ctrl.orphaned[testinghelpers.TestManagedClusterName] = &orphan{uid: types.UID("uid1"), since: c.orphanedSince}
+			}
+
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			deleted := false
+			for _, action := range kubeClient.Actions() {
+				if action.GetVerb() != "delete" {
+					continue
+				}
+				deleted = true
+				preconditions := action.(clienttesting.DeleteActionImpl).GetDeleteOptions().Preconditions
+				if preconditions == nil || preconditions.UID == nil || *preconditions.UID != "uid1" {
+					t.Errorf("expected the namespace deleted with the uid precondition, but got %v", preconditions)
+				}
+			}
+			if deleted != c.expectedDeleted {
+				t.Errorf("expected namespace deleted %v, but got %v", c.expectedDeleted, deleted)
+			}
+			if _, orphaned := ctrl.orphaned[testinghelpers.TestManagedClusterName]; orphaned != c.expectedOrphan {
+				t.Errorf("expected namespace orphaned %v, but got %v", c.expectedOrphan, orphaned)
+			}
+			if o := ctrl.orphaned[testinghelpers.TestManagedClusterName]; o != nil && o.reported != c.dryRun {
+				t.Errorf("expected namespace reported %v, but got %v", c.dryRun, o.reported)
+			}
+		})
+	}
+}
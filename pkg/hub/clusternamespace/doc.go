@@ -0,0 +1,3 @@
+// package clusternamespace contains the hub-side controller which cleans up the namespaces of the managed
+// clusters which no longer exist.
+package clusternamespace
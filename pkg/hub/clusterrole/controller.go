@@ -62,7 +62,7 @@ func NewManagedClusterClusterroleController(
 				return clusterRoles.Has(metaObj.GetName())
 			}, clusterRoleInformer.Informer()).
 		WithInformers(clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(helpers.RecoverSync("ManagedClusterClusterRoleController", c.sync)).
 		ToController("ManagedClusterClusterRoleController", recorder)
 }
 
@@ -76,7 +76,7 @@ func (c *clusterroleController) sync(ctx context.Context, syncCtx factory.SyncCo
 	if len(managedClusters) == 0 {
 		return helpers.CleanUpManagedClusterManifests(
 			ctx,
-			c.kubeClient,
+			helpers.NewManifestClientHolder(c.kubeClient),
 			c.eventRecorder,
 			manifestFiles.ReadFile,
 			clusterRoleFiles...,
@@ -84,9 +84,9 @@ func (c *clusterroleController) sync(ctx context.Context, syncCtx factory.SyncCo
 	}
 
 	// Make sure the managedcluser cluserroles are existed if there are clusters
-	results := resourceapply.ApplyDirectly(
+	results := helpers.ApplyManagedClusterManifests(
 		ctx,
-		resourceapply.NewKubeClientHolder(c.kubeClient),
+		helpers.NewManifestClientHolder(c.kubeClient),
 		syncCtx.Recorder(),
 		c.cache,
 		manifestFiles.ReadFile,
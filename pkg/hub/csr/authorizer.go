@@ -0,0 +1,95 @@
+package csr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"k8s.io/client-go/kubernetes"
+)
+
+// subjectAccessReviewer evaluates whether the requester of a csr is authorized to renew its client certificate.
+// When a large number of clusters are registering, the csrs of the same agent are usually approved in parallel,
+// so the concurrent reviews of the same requester are evaluated with a single SubjectAccessReview, and a positive
+// result is reused for a short period. The negative results are not cached, so a csr created right after the
+// managed cluster is accepted is always approved.
+type subjectAccessReviewer struct {
+	kubeClient kubernetes.Interface
+	ttl        time.Duration
+
+	group   singleflight.Group
+	lock    sync.Mutex
+	allowed map[string]time.Time
+}
+
+func newSubjectAccessReviewer(kubeClient kubernetes.Interface, ttl time.Duration) *subjectAccessReviewer {
+	return &subjectAccessReviewer{
+		kubeClient: kubeClient,
+		ttl:        ttl,
+		allowed:    map[string]time.Time{},
+	}
+}
+
+func (r *subjectAccessReviewer) authorize(ctx context.Context, csr csrInfo) (bool, error) {
+	key := requesterKey(csr)
+	if r.isAllowed(key) {
+		return true, nil
+	}
+
+	result, err, _ := r.group.Do(key, func() (interface{}, error) {
+		// the result may be cached by the review finished just now
+		if r.isAllowed(key) {
+			return true, nil
+		}
+
+		allowed, err := authorize(ctx, r.kubeClient, csr)
+		if err != nil || !allowed {
+			return allowed, err
+		}
+
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		now := time.Now()
+		// prune the expired results
+		for k, expiry := range r.allowed {
+			if now.After(expiry) {
+				delete(r.allowed, k)
+			}
+		}
+		r.allowed[key] = now.Add(r.ttl)
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.(bool), nil
+}
+
+func (r *subjectAccessReviewer) isAllowed(key string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	expiry, ok := r.allowed[key]
+	return ok && time.Now().Before(expiry)
+}
+
+// requesterKey returns a key of all the attributes of the requester used in the SubjectAccessReview
+func requesterKey(csr csrInfo) string {
+	groups := append([]string{}, csr.groups...)
+	sort.Strings(groups)
+
+	extraKeys := make([]string, 0, len(csr.extra))
+	for k := range csr.extra {
+		extraKeys = append(extraKeys, k)
+	}
+	sort.Strings(extraKeys)
+	extra := make([]string, 0, len(extraKeys))
+	for _, k := range extraKeys {
+		extra = append(extra, fmt.Sprintf("%s=%s", k, strings.Join(csr.extra[k], ",")))
+	}
+
+	return strings.Join([]string{csr.username, csr.uid, strings.Join(groups, ","), strings.Join(extra, ";")}, "/")
+}
@@ -0,0 +1,106 @@
+package csr
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestSubjectAccessReviewer(t *testing.T) {
+	cases := []struct {
+		name            string
+		allowed         bool
+		ttl             time.Duration
+		requests        int
+		expectedReviews int32
+	}{
+		{
+			name:            "allowed result is reused",
+			allowed:         true,
+			ttl:             time.Minute,
+			requests:        10,
+			expectedReviews: 1,
+		},
+		{
+			name:            "allowed result is expired",
+			allowed:         true,
+			requests:        3,
+			expectedReviews: 3,
+		},
+		{
+			name:            "denied result is not reused",
+			ttl:             time.Minute,
+			requests:        3,
+			expectedReviews: 3,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var reviews int32
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "subjectaccessreviews",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					atomic.AddInt32(&reviews, 1)
+					return true, &authorizationv1.SubjectAccessReview{
+						Status: authorizationv1.SubjectAccessReviewStatus{Allowed: c.allowed},
+					}, nil
+				},
+			)
+
+			reviewer := newSubjectAccessReviewer(kubeClient, c.ttl)
+			csr := csrInfo{name: "testcsr", username: "user1", groups: []string{"group2", "group1"}}
+			for i := 0; i < c.requests; i++ {
+				allowed, err := reviewer.authorize(context.TODO(), csr)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if allowed != c.allowed {
+					t.Errorf("expected allowed %v, but got %v", c.allowed, allowed)
+				}
+			}
+
+			if reviews != c.expectedReviews {
+				t.Errorf("expected %d reviews, but got %d", c.expectedReviews, reviews)
+			}
+		})
+	}
+}
+
+func TestSubjectAccessReviewerConcurrently(t *testing.T) {
+	var reviews int32
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "subjectaccessreviews",
+		func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+			atomic.AddInt32(&reviews, 1)
+			return true, &authorizationv1.SubjectAccessReview{
+				Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true},
+			}, nil
+		},
+	)
+
+	reviewer := newSubjectAccessReviewer(kubeClient, time.Minute)
+	users := []string{"user1", "user2"}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			if _, err := reviewer.authorize(context.TODO(), csrInfo{username: user}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(users[i%len(users)])
+	}
+	wg.Wait()
+
+	if reviews > int32(len(users)) {
+		t.Errorf("expected at most %d reviews, but got %d", len(users), reviews)
+	}
+}
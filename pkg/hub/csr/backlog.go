@@ -0,0 +1,128 @@
+package csr
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	certificatesinformers "k8s.io/client-go/informers/certificates/v1"
+	certificateslisters "k8s.io/client-go/listers/certificates/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// signingBacklogResyncInterval is the period the ages of the csrs in the signing backlog are refreshed.
+var signingBacklogResyncInterval = 30 * time.Second
+
+// csrSigningBacklogController exposes the csrs of the managed clusters which are approved but not signed yet
+// with the metrics. The approved csrs are signed by the signer of kube-controller-manager in seconds, so a
+// growing backlog usually means the signer is not configured, e.g. --cluster-signing-cert-file is not set
+// on a managed kube-apiserver. Once the backlog exceeds the threshold, a warning is logged and an event is
+// emitted, until the backlog goes down below the threshold.
+type csrSigningBacklogController struct {
+	csrLister     certificateslisters.CertificateSigningRequestLister
+	threshold     int
+	exceeded      bool
+	clock         clock.Clock
+	eventRecorder events.Recorder
+}
+
+// NewCSRSigningBacklogController creates a new csr signing backlog controller, the threshold of the backlog
+// is disabled if it is zero.
+func NewCSRSigningBacklogController(
+	csrInformer certificatesinformers.CertificateSigningRequestInformer,
+	threshold int,
+	recorder events.Recorder) factory.Controller {
+	c := &csrSigningBacklogController{
+		csrLister:     csrInformer.Lister(),
+		threshold:     threshold,
+		clock:         clock.RealClock{},
+		eventRecorder: recorder.WithComponentSuffix("csr-signing-backlog-controller"),
+	}
+
+	return factory.New().
+		WithInformers(csrInformer.Informer()).
+		WithSync(helpers.RecoverSync("CSRSigningBacklogController", c.sync)).
+		ResyncEvery(signingBacklogResyncInterval).
+		ToController("CSRSigningBacklogController", recorder)
+}
+
+func (c *csrSigningBacklogController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	csrs, err := c.csrLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	backlog := map[string]int{}
+	oldestApproved := map[string]time.Time{}
+	total := 0
+	for _, csr := range csrs {
+		clusterName, ok := csr.Labels[clusterv1.ClusterNameLabelKey]
+		if !ok {
+			continue
+		}
+		approved, ok := approvedButNotSigned(csr)
+		if !ok {
+			continue
+		}
+		backlog[clusterName]++
+		total++
+		if oldest, ok := oldestApproved[clusterName]; !ok || approved.Before(oldest) {
+			oldestApproved[clusterName] = approved
+		}
+	}
+
+	// the gauges of the clusters without backlog are removed
+	signingBacklog.Reset()
+	signingBacklogOldestAge.Reset()
+	for clusterName, count := range backlog {
+		signingBacklog.WithLabelValues(clusterName).Set(float64(count))
+		signingBacklogOldestAge.WithLabelValues(clusterName).Set(c.clock.Since(oldestApproved[clusterName]).Seconds())
+	}
+
+	if c.threshold <= 0 {
+		return nil
+	}
+	switch {
+	case total >= c.threshold && !c.exceeded:
+		c.exceeded = true
+		klog.Warningf("%d approved csrs of the managed clusters are not signed, check the signer of kube-controller-manager", total)
+		c.eventRecorder.Warningf("CSRSigningBacklogExceeded",
+			"%d approved csrs of the managed clusters are not signed, check the signer of kube-controller-manager", total)
+	case total < c.threshold && c.exceeded:
+		c.exceeded = false
+		klog.Infof("The approved csrs of the managed clusters not signed are down to %d", total)
+	}
+	return nil
+}
+
+// approvedButNotSigned returns the time the csr is approved if it is approved but not signed yet. The csr
+// which is denied or failed to be signed is not in the backlog.
+func approvedButNotSigned(csr *certificatesv1.CertificateSigningRequest) (time.Time, bool) {
+	if len(csr.Status.Certificate) != 0 {
+		return time.Time{}, false
+	}
+
+	var approved *certificatesv1.CertificateSigningRequestCondition
+	for i := range csr.Status.Conditions {
+		switch csr.Status.Conditions[i].Type {
+		case certificatesv1.CertificateApproved:
+			approved = &csr.Status.Conditions[i]
+		case certificatesv1.CertificateDenied, certificatesv1.CertificateFailed:
+			return time.Time{}, false
+		}
+	}
+	if approved == nil {
+		return time.Time{}, false
+	}
+	if approved.LastUpdateTime.IsZero() {
+		return csr.CreationTimestamp.Time, true
+	}
+	return approved.LastUpdateTime.Time, true
+}
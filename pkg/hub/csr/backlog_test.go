@@ -0,0 +1,145 @@
+package csr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/component-base/metrics/testutil"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestSigningBacklogSync(t *testing.T) {
+	now := time.Now()
+	cluster1CSR := func(name string, approvedAgo time.Duration) *certificatesv1.CertificateSigningRequest {
+		csr := testinghelpers.NewApprovedCSR(testinghelpers.CSRHolder{
+			Name:         name,
+			Labels:       map[string]string{"open-cluster-management.io/cluster-name": "cluster1"},
+			SignerName:   certificatesv1.KubeAPIServerClientSignerName,
+			ReqBlockType: "CERTIFICATE REQUEST",
+		})
+		csr.Status.Conditions[0].LastUpdateTime = metav1.NewTime(now.Add(-approvedAgo))
+		return csr
+	}
+	signedCSR := cluster1CSR("signed", time.Minute)
+	signedCSR.Status.Certificate = []byte("cert")
+	failedCSR := cluster1CSR("failed", time.Minute)
+	failedCSR.Status.Conditions = append(failedCSR.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:   certificatesv1.CertificateFailed,
+		Status: corev1.ConditionTrue,
+	})
+
+	cases := []struct {
+		name             string
+		csrs             []runtime.Object
+		threshold        int
+		exceeded         bool
+		expectedBacklog  float64
+		expectedAge      float64
+		expectedExceeded bool
+	}{
+		{
+			name: "no backlog",
+			csrs: []runtime.Object{
+				testinghelpers.NewCSR(validCSR),
+				testinghelpers.NewDeniedCSR(validCSR),
+				signedCSR,
+				failedCSR,
+			},
+			threshold: 1,
+		},
+		{
+			name: "backlog below the threshold",
+			csrs: []runtime.Object{
+				cluster1CSR("csr1", time.Minute),
+				cluster1CSR("csr2", 2*time.Minute),
+			},
+			threshold:       3,
+			expectedBacklog: 2,
+			expectedAge:     120,
+		},
+		{
+			name: "backlog exceeds the threshold",
+			csrs: []runtime.Object{
+				cluster1CSR("csr1", time.Minute),
+				cluster1CSR("csr2", 2*time.Minute),
+			},
+			threshold:        2,
+			expectedBacklog:  2,
+			expectedAge:      120,
+			expectedExceeded: true,
+		},
+		{
+			name: "backlog goes down below the threshold",
+			csrs: []runtime.Object{
+				cluster1CSR("csr1", time.Minute),
+			},
+			threshold:       2,
+			exceeded:        true,
+			expectedBacklog: 1,
+			expectedAge:     60,
+		},
+		{
+			name: "threshold disabled",
+			csrs: []runtime.Object{
+				cluster1CSR("csr1", time.Minute),
+			},
+			expectedBacklog: 1,
+			expectedAge:     60,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			signingBacklog.Reset()
+			signingBacklogOldestAge.Reset()
+
+			kubeClient := kubefake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, 3*time.Minute)
+			csrStore := informerFactory.Certificates().V1().CertificateSigningRequests().Informer().GetStore()
+			for _, csr := range c.csrs {
+				if err := csrStore.Add(csr); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := &csrSigningBacklogController{
+				csrLister:     informerFactory.Certificates().V1().CertificateSigningRequests().Lister(),
+				threshold:     c.threshold,
+				exceeded:      c.exceeded,
+				clock:         clocktesting.NewFakeClock(now),
+				eventRecorder: eventstesting.NewTestingEventRecorder(t),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "key"))
+			testinghelpers.AssertError(t, syncErr, "")
+
+			if ctrl.exceeded != c.expectedExceeded {
+				t.Errorf("expected exceeded %t, but got %t", c.expectedExceeded, ctrl.exceeded)
+			}
+			backlog, err := testutil.GetGaugeMetricValue(signingBacklog.WithLabelValues("cluster1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if backlog != c.expectedBacklog {
+				t.Errorf("expected backlog %v, but got %v", c.expectedBacklog, backlog)
+			}
+			age, err := testutil.GetGaugeMetricValue(signingBacklogOldestAge.WithLabelValues("cluster1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if age != c.expectedAge {
+				t.Errorf("expected oldest age %v, but got %v", c.expectedAge, age)
+			}
+		})
+	}
+}
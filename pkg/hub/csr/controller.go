@@ -27,27 +27,34 @@ type CSRLister[T CSR] interface {
 
 type CSRApprover[T CSR] interface {
 	approve(ctx context.Context, csr T) approveCSRFunc
+	deny(ctx context.Context, csr T, reason, message string) error
 	isInTerminalState(csr T) bool
 }
 
 // csrApprovingController auto approve the renewal CertificateSigningRequests for an accepted spoke cluster on the hub.
+// If a denier is specified, the CertificateSigningRequests of the deleted or denied spoke clusters are denied.
 type csrApprovingController[T CSR] struct {
-	lister      CSRLister[T]
-	approver    CSRApprover[T]
-	reconcilers []Reconciler
+	lister        CSRLister[T]
+	approver      CSRApprover[T]
+	reconcilers   []Reconciler
+	denier        *CSRDenier
+	eventRecorder events.Recorder
 }
 
-// NewCSRApprovingController creates a new csr approving controller
+// NewCSRApprovingController creates a new csr approving controller, the denier is optional.
 func NewCSRApprovingController[T CSR](
 	csrInformer cache.SharedIndexInformer,
 	lister CSRLister[T],
 	approver CSRApprover[T],
 	reconcilers []Reconciler,
+	denier *CSRDenier,
 	recorder events.Recorder) factory.Controller {
 	c := &csrApprovingController[T]{
-		lister:      lister,
-		approver:    approver,
-		reconcilers: reconcilers,
+		lister:        lister,
+		approver:      approver,
+		reconcilers:   reconcilers,
+		denier:        denier,
+		eventRecorder: recorder.WithComponentSuffix("csr-approving-controller"),
 	}
 
 	return factory.New().
@@ -55,7 +62,7 @@ func NewCSRApprovingController[T CSR](
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, csrInformer).
-		WithSync(c.sync).
+		WithSync(helpers.RecoverSync("CSRApprovingController", c.sync)).
 		ToController("CSRApprovingController", recorder)
 }
 
@@ -76,8 +83,31 @@ func (c *csrApprovingController[T]) sync(ctx context.Context, syncCtx factory.Sy
 	}
 
 	csrInfo := newCSRInfo(csr)
+	if c.denier != nil {
+		deny, reason, message, requeueAfter, err := c.denier.shouldDeny(csrInfo)
+		if err != nil {
+			return err
+		}
+		if deny {
+			if err := c.approver.deny(ctx, csr, reason, message); err != nil {
+				return err
+			}
+			c.eventRecorder.Eventf("ManagedClusterCSRDenied", "csr %q is denied: %s", csrName, message)
+			return nil
+		}
+		if requeueAfter > 0 {
+			// check the csr again in case its managed cluster is not created or accepted after all
+			syncCtx.Queue().AddAfter(csrName, requeueAfter)
+		}
+	}
+
 	for _, r := range c.reconcilers {
 		state, err := r.Reconcile(ctx, csrInfo, c.approver.approve(ctx, csr))
+		if errors.IsConflict(err) {
+			// the csr in the cache is stale, requeue it with exponential backoff
+			klog.V(4).Infof("Conflict on approving CertificateSigningRequests %q, requeue it", csrName)
+			return factory.SyntheticRequeueError
+		}
 		if err != nil {
 			return err
 		}
@@ -117,6 +147,18 @@ func (c *CSRV1Approver) approve(ctx context.Context, csr *certificatesv1.Certifi
 	}
 }
 
+func (c *CSRV1Approver) deny(ctx context.Context, csr *certificatesv1.CertificateSigningRequest, reason, message string) error {
+	csrCopy := csr.DeepCopy()
+	csrCopy.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:    certificatesv1.CertificateDenied,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	_, err := c.kubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csrCopy.Name, csrCopy, metav1.UpdateOptions{})
+	return err
+}
+
 type CSRV1beta1Approver struct {
 	kubeClient kubernetes.Interface
 }
@@ -143,3 +185,15 @@ func (c *CSRV1beta1Approver) approve(ctx context.Context, csr *certificatesv1bet
 		return err
 	}
 }
+
+func (c *CSRV1beta1Approver) deny(ctx context.Context, csr *certificatesv1beta1.CertificateSigningRequest, reason, message string) error {
+	csrCopy := csr.DeepCopy()
+	csrCopy.Status.Conditions = append(csr.Status.Conditions, certificatesv1beta1.CertificateSigningRequestCondition{
+		Type:    certificatesv1beta1.CertificateDenied,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	_, err := c.kubeClient.CertificatesV1beta1().CertificateSigningRequests().UpdateApproval(ctx, csrCopy, metav1.UpdateOptions{})
+	return err
+}
@@ -9,11 +9,18 @@ import (
 	authorizationv1 "k8s.io/api/authorization/v1"
 	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 	"open-cluster-management.io/registration/pkg/hub/user"
 )
@@ -161,3 +168,117 @@ func Test_v1beta1CSRApprovingController_sync(t *testing.T) {
 		})
 	}
 }
+
+func Test_csrAddOnReconciler(t *testing.T) {
+	addOnCSR := testinghelpers.CSRHolder{
+		Name: "testaddoncsr",
+		Labels: map[string]string{
+			"open-cluster-management.io/cluster-name": testinghelpers.TestManagedClusterName,
+			"open-cluster-management.io/addon-name":   "addon1",
+		},
+		SignerName:   certificatesv1beta1.KubeAPIServerClientSignerName,
+		CN:           "system:open-cluster-management:cluster:testmanagedcluster:addon:addon1:agent:agent1",
+		Orgs:         []string{"system:open-cluster-management:cluster:testmanagedcluster:addon:addon1"},
+		Username:     user.SubjectPrefix + "testmanagedcluster:spokeagent1",
+		ReqBlockType: "CERTIFICATE REQUEST",
+	}
+	newAddOnCSR := func(holder testinghelpers.CSRHolder, groups ...string) *certificatesv1beta1.CertificateSigningRequest {
+		csr := testinghelpers.NewV1beta1CSR(holder)
+		csr.Spec.Groups = groups
+		return csr
+	}
+	newAddOn := func(registrations ...addonv1alpha1.RegistrationConfig) *addonv1alpha1.ManagedClusterAddOn {
+		return &addonv1alpha1.ManagedClusterAddOn{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testinghelpers.TestManagedClusterName, Name: "addon1"},
+			Status:     addonv1alpha1.ManagedClusterAddOnStatus{Registrations: registrations},
+		}
+	}
+	agentGroups := []string{user.SubjectPrefix + testinghelpers.TestManagedClusterName, user.ManagedClustersGroup}
+	defaultRegistration := addonv1alpha1.RegistrationConfig{SignerName: certificatesv1beta1.KubeAPIServerClientSignerName}
+
+	cases := []struct {
+		name          string
+		csr           *certificatesv1beta1.CertificateSigningRequest
+		clusters      []runtime.Object
+		addOns        []runtime.Object
+		expectedState reconcileState
+		approved      bool
+	}{
+		{
+			name:          "not an addon csr",
+			csr:           testinghelpers.NewV1beta1CSR(validV1beta1CSR),
+			expectedState: reconcileContinue,
+		},
+		{
+			name:          "addon csr not created by the cluster agent",
+			csr:           newAddOnCSR(addOnCSR, "system:authenticated"),
+			clusters:      []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			addOns:        []runtime.Object{newAddOn(defaultRegistration)},
+			expectedState: reconcileStop,
+		},
+		{
+			name:          "cluster is not accepted",
+			csr:           newAddOnCSR(addOnCSR, agentGroups...),
+			clusters:      []runtime.Object{testinghelpers.NewManagedCluster()},
+			addOns:        []runtime.Object{newAddOn(defaultRegistration)},
+			expectedState: reconcileStop,
+		},
+		{
+			name:     "subject does not match registration",
+			csr:      newAddOnCSR(addOnCSR, agentGroups...),
+			clusters: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			addOns: []runtime.Object{newAddOn(addonv1alpha1.RegistrationConfig{
+				SignerName: certificatesv1beta1.KubeAPIServerClientSignerName,
+				Subject:    addonv1alpha1.Subject{User: "user1", Groups: []string{"group1"}},
+			})},
+			expectedState: reconcileStop,
+		},
+		{
+			name:          "approve addon csr with default subject",
+			csr:           newAddOnCSR(addOnCSR, agentGroups...),
+			clusters:      []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			addOns:        []runtime.Object{newAddOn(defaultRegistration)},
+			expectedState: reconcileStop,
+			approved:      true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
+			for _, cluster := range c.clusters {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+			addOnClient := addonfake.NewSimpleClientset(c.addOns...)
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, 10*time.Minute)
+			for _, addOn := range c.addOns {
+				if err := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore().Add(addOn); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			reconciler := NewCSRAddOnReconciler(
+				kubefake.NewSimpleClientset(),
+				clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				eventstesting.NewTestingEventRecorder(t),
+			)
+			approved := false
+			state, err := reconciler.Reconcile(context.TODO(), newCSRInfo(c.csr), func(kubernetes.Interface) error {
+				approved = true
+				return nil
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if state != c.expectedState {
+				t.Errorf("expected state %v, but got %v", c.expectedState, state)
+			}
+			if approved != c.approved {
+				t.Errorf("expected approved %v, but got %v", c.approved, approved)
+			}
+		})
+	}
+}
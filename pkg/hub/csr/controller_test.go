@@ -42,6 +42,7 @@ func TestSync(t *testing.T) {
 		startingClusters     []runtime.Object
 		startingCSRs         []runtime.Object
 		approvalUsers        []string
+		approvalIdentities   []string
 		autoApprovingAllowed bool
 		validateActions      func(t *testing.T, actions []clienttesting.Action)
 	}{
@@ -164,6 +165,32 @@ func TestSync(t *testing.T) {
 				testinghelpers.AssertCSRCondition(t, actual.(*certificatesv1.CertificateSigningRequest).Status.Conditions, expectedCondition)
 			},
 		},
+		{
+			name:             "auto approve a bootstrap csr request from an approval group",
+			startingClusters: []runtime.Object{&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managedcluster1"}}},
+			startingCSRs: []runtime.Object{func() *certificatesv1.CertificateSigningRequest {
+				csr := testinghelpers.NewCSR(validCSR)
+				csr.Spec.Username = "system:serviceaccount:open-cluster-management:bootstrap-sa"
+				csr.Spec.Groups = []string{"system:serviceaccounts", "system:serviceaccounts:open-cluster-management"}
+				return csr
+			}()},
+			approvalIdentities: []string{"system:serviceaccounts:open-cluster-management"},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+			},
+		},
+		{
+			name:             "not approve a bootstrap csr request from other identities",
+			startingClusters: []runtime.Object{&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managedcluster1"}}},
+			startingCSRs: []runtime.Object{func() *certificatesv1.CertificateSigningRequest {
+				csr := testinghelpers.NewCSR(validCSR)
+				csr.Spec.Username = "system:serviceaccount:default:bootstrap-sa"
+				csr.Spec.Groups = []string{"system:serviceaccounts", "system:serviceaccounts:default"}
+				return csr
+			}()},
+			approvalIdentities: []string{"system:serviceaccounts:open-cluster-management"},
+			validateActions:    testinghelpers.AssertNoActions,
+		},
 	}
 
 	for _, c := range cases {
@@ -213,6 +240,7 @@ func TestSync(t *testing.T) {
 						clusterClient,
 						clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
 						c.approvalUsers,
+						c.approvalIdentities,
 						recorder,
 					),
 				},
@@ -0,0 +1,89 @@
+package csr
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const (
+	// ManagedClusterNotFoundReason is the reason of a denied csr whose managed cluster does not exist
+	ManagedClusterNotFoundReason = "ManagedClusterNotFound"
+	// ManagedClusterDeletingReason is the reason of a denied csr whose managed cluster is being deleted
+	ManagedClusterDeletingReason = "ManagedClusterDeleting"
+	// ManagedClusterDeniedReason is the reason of a denied csr whose managed cluster is denied by the hub admin
+	ManagedClusterDeniedReason = "ManagedClusterDenied"
+)
+
+// CSRDenier denies the csrs of the managed clusters which have been deleted, or denied by the hub cluster admin
+// for longer than a threshold, so the agents of the decommissioned clusters stop renewing their client
+// certificates endlessly. The denied csrs are garbage collected by the csr cleaner of kube-controller-manager.
+type CSRDenier struct {
+	clusterLister clusterv1listers.ManagedClusterLister
+	// threshold is the period to wait for a managed cluster to be created or accepted before its csrs
+	// are denied, so a csr created at the same time as its managed cluster is not denied.
+	threshold time.Duration
+}
+
+// NewCSRDenier returns a CSRDenier
+func NewCSRDenier(clusterLister clusterv1listers.ManagedClusterLister, threshold time.Duration) *CSRDenier {
+	return &CSRDenier{
+		clusterLister: clusterLister,
+		threshold:     threshold,
+	}
+}
+
+// shouldDeny returns true with the reason and message if the csr should be denied. If the csr might be
+// denied later, it returns a period after which the csr should be checked again.
+func (d *CSRDenier) shouldDeny(csr csrInfo) (bool, string, string, time.Duration, error) {
+	clusterName, existed := csr.labels[clusterv1.ClusterNameLabelKey]
+	if !existed {
+		return false, "", "", 0, nil
+	}
+
+	managedCluster, err := d.clusterLister.Get(clusterName)
+	switch {
+	case errors.IsNotFound(err):
+		if remaining := d.remaining(csr.creationTimestamp); remaining > 0 {
+			return false, "", "", remaining, nil
+		}
+		return true, ManagedClusterNotFoundReason,
+			fmt.Sprintf("Managed cluster %q does not exist on the hub.", clusterName), 0, nil
+	case err != nil:
+		return false, "", "", 0, err
+	}
+
+	if !managedCluster.DeletionTimestamp.IsZero() {
+		return true, ManagedClusterDeletingReason,
+			fmt.Sprintf("Managed cluster %q is being deleted from the hub.", clusterName), 0, nil
+	}
+
+	if managedCluster.Spec.HubAcceptsClient {
+		return false, "", "", 0, nil
+	}
+
+	// only deny the csr of a managed cluster explicitly denied by the hub cluster admin, a managed cluster
+	// waiting for acceptance has no such condition.
+	cond := meta.FindStatusCondition(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != helpers.HubClusterAdminDeniedReason {
+		return false, "", "", 0, nil
+	}
+	if remaining := d.remaining(cond.LastTransitionTime.Time); remaining > 0 {
+		return false, "", "", remaining, nil
+	}
+	klog.V(4).Infof("Managed cluster %q of csr %q has been denied since %v", clusterName, csr.name, cond.LastTransitionTime)
+	return true, ManagedClusterDeniedReason,
+		fmt.Sprintf("Managed cluster %q is denied by the hub cluster admin.", clusterName), 0, nil
+}
+
+func (d *CSRDenier) remaining(since time.Time) time.Duration {
+	return time.Until(since.Add(d.threshold))
+}
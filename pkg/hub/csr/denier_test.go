@@ -0,0 +1,135 @@
+package csr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestShouldDeny(t *testing.T) {
+	now := time.Now()
+	newDeniedCluster := func(since time.Time) *clusterv1.ManagedCluster {
+		cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managedcluster1"}}
+		cluster.Status.Conditions = []metav1.Condition{{
+			Type:               clusterv1.ManagedClusterConditionHubAccepted,
+			Status:             metav1.ConditionFalse,
+			Reason:             helpers.HubClusterAdminDeniedReason,
+			LastTransitionTime: metav1.NewTime(since),
+		}}
+		return cluster
+	}
+
+	cases := []struct {
+		name            string
+		clusters        []runtime.Object
+		csrCreated      time.Time
+		expectedDeny    bool
+		expectedReason  string
+		expectedRequeue bool
+	}{
+		{
+			name:           "cluster not found",
+			csrCreated:     now.Add(-time.Hour),
+			expectedDeny:   true,
+			expectedReason: ManagedClusterNotFoundReason,
+		},
+		{
+			name:            "cluster not found within threshold",
+			csrCreated:      now,
+			expectedRequeue: true,
+		},
+		{
+			name: "cluster is deleting",
+			clusters: []runtime.Object{&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+				Name:              "managedcluster1",
+				DeletionTimestamp: &metav1.Time{Time: now},
+			}}},
+			expectedDeny:   true,
+			expectedReason: ManagedClusterDeletingReason,
+		},
+		{
+			name:     "cluster is waiting for acceptance",
+			clusters: []runtime.Object{&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "managedcluster1"}}},
+		},
+		{
+			name:           "cluster is denied",
+			clusters:       []runtime.Object{newDeniedCluster(now.Add(-time.Hour))},
+			expectedDeny:   true,
+			expectedReason: ManagedClusterDeniedReason,
+		},
+		{
+			name:            "cluster is denied within threshold",
+			clusters:        []runtime.Object{newDeniedCluster(now)},
+			expectedRequeue: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterfake.NewSimpleClientset(), 10*time.Minute)
+			for _, cluster := range c.clusters {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			denier := NewCSRDenier(clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), 10*time.Minute)
+			deny, reason, _, requeueAfter, err := denier.shouldDeny(csrInfo{
+				name:              "testcsr",
+				labels:            map[string]string{clusterv1.ClusterNameLabelKey: "managedcluster1"},
+				creationTimestamp: c.csrCreated,
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if deny != c.expectedDeny || reason != c.expectedReason {
+				t.Errorf("expected deny %v with reason %q, but got %v with %q", c.expectedDeny, c.expectedReason, deny, reason)
+			}
+			if (requeueAfter > 0) != c.expectedRequeue {
+				t.Errorf("expected requeue %v, but got %v", c.expectedRequeue, requeueAfter)
+			}
+		})
+	}
+}
+
+func TestSyncDenyCSR(t *testing.T) {
+	csr := testinghelpers.NewCSR(validCSR)
+	csr.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+
+	kubeClient := kubefake.NewSimpleClientset(csr)
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 3*time.Minute)
+	if err := informerFactory.Certificates().V1().CertificateSigningRequests().Informer().GetStore().Add(csr); err != nil {
+		t.Fatal(err)
+	}
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterfake.NewSimpleClientset(), 10*time.Minute)
+
+	ctrl := &csrApprovingController[*certificatesv1.CertificateSigningRequest]{
+		lister:        informerFactory.Certificates().V1().CertificateSigningRequests().Lister(),
+		approver:      NewCSRV1Approver(kubeClient),
+		denier:        NewCSRDenier(clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), 10*time.Minute),
+		eventRecorder: eventstesting.NewTestingEventRecorder(t),
+	}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, validCSR.Name)); err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+
+	actions := kubeClient.Actions()
+	testinghelpers.AssertActions(t, actions, "update")
+	actual := actions[0].(clienttesting.UpdateActionImpl).Object.(*certificatesv1.CertificateSigningRequest)
+	if !helpers.IsCSRInTerminalState(&actual.Status) || actual.Status.Conditions[0].Type != certificatesv1.CertificateDenied {
+		t.Errorf("expected csr to be denied, but got %v", actual.Status.Conditions)
+	}
+}
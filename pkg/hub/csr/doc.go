@@ -1,3 +1,4 @@
 // package csr contains the hub-side reconciler for auto approving the renewal CertificateSigningRequests
-// for an accepted managed cluster
+// for an accepted managed cluster, and the controller exposing the approved CertificateSigningRequests
+// which are not signed yet
 package csr
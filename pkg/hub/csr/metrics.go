@@ -0,0 +1,28 @@
+package csr
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	signingBacklog = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name: "open_cluster_management_registration_csr_signing_backlog",
+			Help: "The number of the csrs of a managed cluster which are approved but not signed yet.",
+		},
+		[]string{"cluster"},
+	)
+	signingBacklogOldestAge = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name: "open_cluster_management_registration_csr_signing_backlog_oldest_age_seconds",
+			Help: "The seconds since the oldest csr of a managed cluster which is not signed yet was approved.",
+		},
+		[]string{"cluster"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(signingBacklog)
+	legacyregistry.MustRegister(signingBacklogOldestAge)
+}
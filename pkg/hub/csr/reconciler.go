@@ -6,6 +6,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/openshift/library-go/pkg/operator/events"
 
@@ -19,6 +20,8 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonv1alpha1listers "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
 	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
@@ -33,14 +36,15 @@ const (
 )
 
 type csrInfo struct {
-	name       string
-	labels     map[string]string
-	signerName string
-	username   string
-	uid        string
-	groups     []string
-	extra      map[string]authorizationv1.ExtraValue
-	request    []byte
+	name              string
+	labels            map[string]string
+	creationTimestamp time.Time
+	signerName        string
+	username          string
+	uid               string
+	groups            []string
+	extra             map[string]authorizationv1.ExtraValue
+	request           []byte
 }
 
 type approveCSRFunc func(kubernetes.Interface) error
@@ -49,14 +53,19 @@ type Reconciler interface {
 	Reconcile(context.Context, csrInfo, approveCSRFunc) (reconcileState, error)
 }
 
+// authorizedResultTTL is the period an authorized SubjectAccessReview result of a requester is reused
+var authorizedResultTTL = 10 * time.Second
+
 type csrRenewalReconciler struct {
 	kubeClient    kubernetes.Interface
+	reviewer      *subjectAccessReviewer
 	eventRecorder events.Recorder
 }
 
 func NewCSRRenewalReconciler(kubeClient kubernetes.Interface, recorder events.Recorder) Reconciler {
 	return &csrRenewalReconciler{
 		kubeClient:    kubeClient,
+		reviewer:      newSubjectAccessReviewer(kubeClient, authorizedResultTTL),
 		eventRecorder: recorder.WithComponentSuffix("csr-approving-controller"),
 	}
 }
@@ -75,7 +84,7 @@ func (r *csrRenewalReconciler) Reconcile(ctx context.Context, csr csrInfo, appro
 	}
 
 	// Authorize whether the current spoke agent has been authorized to renew its csr.
-	allowed, err := authorize(ctx, r.kubeClient, csr)
+	allowed, err := r.authorize(ctx, csr)
 	if err != nil {
 		return reconcileContinue, err
 	}
@@ -92,25 +101,38 @@ func (r *csrRenewalReconciler) Reconcile(ctx context.Context, csr csrInfo, appro
 	return reconcileStop, nil
 }
 
+func (r *csrRenewalReconciler) authorize(ctx context.Context, csr csrInfo) (bool, error) {
+	if r.reviewer == nil {
+		return authorize(ctx, r.kubeClient, csr)
+	}
+	return r.reviewer.authorize(ctx, csr)
+}
+
 type csrBootstrapReconciler struct {
 	kubeClient    kubernetes.Interface
 	clusterClient clusterclientset.Interface
 	clusterLister clusterv1listers.ManagedClusterLister
 	approvalUsers sets.Set[string]
-	eventRecorder events.Recorder
+	// approvalIdentities are matched with both the user and the groups of the requester
+	approvalIdentities sets.Set[string]
+	eventRecorder      events.Recorder
 }
 
+// NewCSRBootstrapReconciler returns a reconciler which accepts the managed cluster and approves its bootstrap
+// csr if the csr is requested by one of the approvalUsers, or by a user or a group in the approvalIdentities.
 func NewCSRBootstrapReconciler(kubeClient kubernetes.Interface,
 	clusterClient clusterclientset.Interface,
 	clusterLister clusterv1listers.ManagedClusterLister,
 	approvalUsers []string,
+	approvalIdentities []string,
 	recorder events.Recorder) Reconciler {
 	return &csrBootstrapReconciler{
-		kubeClient:    kubeClient,
-		clusterClient: clusterClient,
-		clusterLister: clusterLister,
-		approvalUsers: sets.New(approvalUsers...),
-		eventRecorder: recorder.WithComponentSuffix("csr-approving-controller"),
+		kubeClient:         kubeClient,
+		clusterClient:      clusterClient,
+		clusterLister:      clusterLister,
+		approvalUsers:      sets.New(approvalUsers...),
+		approvalIdentities: sets.New(approvalIdentities...),
+		eventRecorder:      recorder.WithComponentSuffix("csr-approving-controller"),
 	}
 }
 
@@ -123,7 +145,7 @@ func (b *csrBootstrapReconciler) Reconcile(ctx context.Context, csr csrInfo, app
 	}
 
 	// Check whether current csr can be approved.
-	if !b.approvalUsers.Has(csr.username) {
+	if !b.isApprovalIdentity(csr) {
 		return reconcileContinue, nil
 	}
 
@@ -144,6 +166,13 @@ func (b *csrBootstrapReconciler) Reconcile(ctx context.Context, csr csrInfo, app
 	return reconcileStop, nil
 }
 
+func (b *csrBootstrapReconciler) isApprovalIdentity(csr csrInfo) bool {
+	if b.approvalUsers.Has(csr.username) || b.approvalIdentities.Has(csr.username) {
+		return true
+	}
+	return b.approvalIdentities.HasAny(csr.groups...)
+}
+
 func (b *csrBootstrapReconciler) accpetCluster(ctx context.Context, managedClusterName string) error {
 	managedCluster, err := b.clusterLister.Get(managedClusterName)
 	if err != nil {
@@ -160,6 +189,123 @@ func (b *csrBootstrapReconciler) accpetCluster(ctx context.Context, managedClust
 	return err
 }
 
+type csrAddOnReconciler struct {
+	kubeClient    kubernetes.Interface
+	clusterLister clusterv1listers.ManagedClusterLister
+	addOnLister   addonv1alpha1listers.ManagedClusterAddOnLister
+	eventRecorder events.Recorder
+}
+
+// NewCSRAddOnReconciler returns a reconciler which approves the csrs created by the agent of an accepted
+// managed cluster for its addons with the kube-apiserver client signer. The addon csrs are usually approved
+// by the addon managers with the certificates/v1 api, so this reconciler is only used when the hub only
+// supports the certificates/v1beta1 api. It must be put ahead of the other reconcilers, since the addon csrs
+// are not recognized by them.
+func NewCSRAddOnReconciler(kubeClient kubernetes.Interface,
+	clusterLister clusterv1listers.ManagedClusterLister,
+	addOnLister addonv1alpha1listers.ManagedClusterAddOnLister,
+	recorder events.Recorder) Reconciler {
+	return &csrAddOnReconciler{
+		kubeClient:    kubeClient,
+		clusterLister: clusterLister,
+		addOnLister:   addOnLister,
+		eventRecorder: recorder.WithComponentSuffix("csr-approving-controller"),
+	}
+}
+
+func (a *csrAddOnReconciler) Reconcile(ctx context.Context, csr csrInfo, approveCSR approveCSRFunc) (reconcileState, error) {
+	addOnName, existed := csr.labels[addonv1alpha1.AddonLabelKey]
+	if !existed {
+		return reconcileContinue, nil
+	}
+
+	clusterName, existed := csr.labels[clusterv1.ClusterNameLabelKey]
+	if !existed {
+		return reconcileStop, nil
+	}
+
+	// the csrs with other signers are approved by the addon managers
+	if csr.signerName != certificatesv1.KubeAPIServerClientSignerName {
+		return reconcileStop, nil
+	}
+
+	// the csr must be created by the agent of the managed cluster
+	if !sets.New(csr.groups...).Has(user.SubjectPrefix + clusterName) {
+		klog.V(4).Infof("Addon csr %q is not created by the agent of managed cluster %q", csr.name, clusterName)
+		return reconcileStop, nil
+	}
+
+	managedCluster, err := a.clusterLister.Get(clusterName)
+	if errors.IsNotFound(err) {
+		return reconcileStop, nil
+	}
+	if err != nil {
+		return reconcileContinue, err
+	}
+	if !managedCluster.Spec.HubAcceptsClient {
+		return reconcileStop, nil
+	}
+
+	addOn, err := a.addOnLister.ManagedClusterAddOns(clusterName).Get(addOnName)
+	if errors.IsNotFound(err) {
+		return reconcileStop, nil
+	}
+	if err != nil {
+		return reconcileContinue, err
+	}
+
+	if !validateAddOnCSR(csr, clusterName, addOn) {
+		klog.V(4).Infof("Addon csr %q does not match the registrations of addon %q", csr.name, clusterName+"/"+addOnName)
+		return reconcileStop, nil
+	}
+
+	if err := approveCSR(a.kubeClient); err != nil {
+		return reconcileContinue, err
+	}
+
+	a.eventRecorder.Eventf("ManagedClusterAddOnCSRAutoApproved", "addon csr %q is auto approved by hub csr controller", csr.name)
+	return reconcileStop, nil
+}
+
+// validateAddOnCSR checks if the subject in the addon csr request matches the kube-apiserver client
+// registration of the addon. The subject of the registration defaults to the one set by the agent.
+func validateAddOnCSR(csr csrInfo, clusterName string, addOn *addonv1alpha1.ManagedClusterAddOn) bool {
+	block, _ := pem.Decode(csr.request)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return false
+	}
+	x509cr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	defaultOrganization := fmt.Sprintf("%scluster:%s:addon:%s", user.SubjectPrefix, clusterName, addOn.Name)
+	for _, registration := range addOn.Status.Registrations {
+		if registration.SignerName != certificatesv1.KubeAPIServerClientSignerName {
+			continue
+		}
+
+		expectedGroups := registration.Subject.Groups
+		if len(expectedGroups) == 0 {
+			expectedGroups = []string{defaultOrganization}
+		}
+		if !sets.New(x509cr.Subject.Organization...).Equal(sets.New(expectedGroups...)) {
+			continue
+		}
+
+		if len(registration.Subject.User) != 0 {
+			if x509cr.Subject.CommonName == registration.Subject.User {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(x509cr.Subject.CommonName, defaultOrganization+":agent:") {
+			return true
+		}
+	}
+	return false
+}
+
 // To validate a managed cluster csr, we check
 // 1. if the signer name in csr request is valid.
 // 2. if organization field and commonName field in csr request is valid.
@@ -239,28 +385,36 @@ func newCSRInfo(csr any) csrInfo {
 			extra[k] = authorizationv1.ExtraValue(v)
 		}
 		return csrInfo{
-			name:       v.Name,
-			labels:     v.Labels,
-			signerName: v.Spec.SignerName,
-			username:   v.Spec.Username,
-			uid:        v.Spec.UID,
-			groups:     v.Spec.Groups,
-			extra:      extra,
-			request:    v.Spec.Request,
+			name:              v.Name,
+			labels:            v.Labels,
+			creationTimestamp: v.CreationTimestamp.Time,
+			signerName:        v.Spec.SignerName,
+			username:          v.Spec.Username,
+			uid:               v.Spec.UID,
+			groups:            v.Spec.Groups,
+			extra:             extra,
+			request:           v.Spec.Request,
 		}
 	case *certificatesv1beta1.CertificateSigningRequest:
 		for k, v := range v.Spec.Extra {
 			extra[k] = authorizationv1.ExtraValue(v)
 		}
+		// the signer name is dropped by the hub which does not support it, and all the client
+		// certificates are issued for kube-apiserver on such a hub.
+		signerName := certificatesv1beta1.KubeAPIServerClientSignerName
+		if v.Spec.SignerName != nil {
+			signerName = *v.Spec.SignerName
+		}
 		return csrInfo{
-			name:       v.Name,
-			labels:     v.Labels,
-			signerName: *v.Spec.SignerName,
-			username:   v.Spec.Username,
-			uid:        v.Spec.UID,
-			groups:     v.Spec.Groups,
-			extra:      extra,
-			request:    v.Spec.Request,
+			name:              v.Name,
+			labels:            v.Labels,
+			creationTimestamp: v.CreationTimestamp.Time,
+			signerName:        signerName,
+			username:          v.Spec.Username,
+			uid:               v.Spec.UID,
+			groups:            v.Spec.Groups,
+			extra:             extra,
+			request:           v.Spec.Request,
 		}
 	default:
 		klog.Errorf("Unsupported type %T", v)
@@ -0,0 +1,213 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// healthCheckTimeout is the timeout of the requests sent to the kube-apiserver by a health check.
+const healthCheckTimeout = 5 * time.Second
+
+// cacheSyncWaiter is implemented by the shared informer factories of the hub controllers.
+type cacheSyncWaiter interface {
+	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
+}
+
+// metadataCacheSyncWaiter adapts a metadata informer factory, whose informers are keyed by resource, to a
+// cacheSyncWaiter.
+type metadataCacheSyncWaiter struct {
+	factory metadatainformer.SharedInformerFactory
+}
+
+func (w metadataCacheSyncWaiter) WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool {
+	synced := true
+	for resource, ok := range w.factory.WaitForCacheSync(stopCh) {
+		if !ok {
+			klog.Warningf("The cache of the %s metadata informer is not synced", resource)
+			synced = false
+		}
+	}
+	return map[reflect.Type]bool{reflect.TypeOf(&metav1.PartialObjectMetadata{}): synced}
+}
+
+// healthChecker holds the state of the controller manager checked by the health probes. The controllers are only
+// started on the leader, so the checks of the controllers pass until this instance starts leading.
+type healthChecker struct {
+	lock           sync.RWMutex
+	kubeClient     kubernetes.Interface
+	leaseName      string
+	leaseDuration  time.Duration
+	leaseNamespace string
+	leading        bool
+	cachesSynced   func() bool
+}
+
+// ServeHealthProbes serves /healthz and /readyz at the HealthProbeBindAddress until the context is done. They are
+// served before the leader election, so that the instances which are not leading are probed as well.
+//   - /healthz fails once the lease leaseName is not renewed within the leaseDuration while this instance is
+//     leading, e.g. the controller manager is deadlocked, so that it is restarted by the liveness probe. The leader
+//     election is not checked if the leaseName is empty.
+//   - /readyz fails until the informer caches of the controllers are synced once this instance is leading, or
+//     once the kube-apiserver is not reachable.
+func (m *HubManagerOptions) ServeHealthProbes(ctx context.Context, kubeConfig *rest.Config, leaseName string, leaseDuration time.Duration) error {
+	if len(m.HealthProbeBindAddress) == 0 || m.HealthProbeBindAddress == "0" {
+		return nil
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
+	m.health.lock.Lock()
+	m.health.kubeClient = kubeClient
+	m.health.leaseName = leaseName
+	m.health.leaseDuration = leaseDuration
+	m.health.lock.Unlock()
+
+	listener, err := net.Listen("tcp", m.HealthProbeBindAddress)
+	if err != nil {
+		return fmt.Errorf("unable to listen on the health probe bind address %q: %w", m.HealthProbeBindAddress, err)
+	}
+
+	mux := http.NewServeMux()
+	healthz.InstallHandler(mux, m.health.healthzChecks()...)
+	healthz.InstallReadyzHandler(mux, m.health.readyzChecks()...)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: healthCheckTimeout}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		klog.Infof("Serving the health probes at %s", listener.Addr())
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.Errorf("Unable to serve the health probes: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (h *healthChecker) healthzChecks() []healthz.HealthChecker {
+	return []healthz.HealthChecker{
+		healthz.PingHealthz,
+		healthz.NamedCheck("leader-election", h.checkLeaderElection),
+	}
+}
+
+func (h *healthChecker) readyzChecks() []healthz.HealthChecker {
+	return []healthz.HealthChecker{
+		healthz.PingHealthz,
+		healthz.NamedCheck("informer-sync", h.checkInformerSync),
+		healthz.NamedCheck("api-connectivity", h.checkAPIConnectivity),
+	}
+}
+
+// startLeading records that the controllers are started by this instance, the lease of the leader election is in
+// the leaseNamespace.
+func (h *healthChecker) startLeading(leaseNamespace string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.leading = true
+	h.leaseNamespace = leaseNamespace
+}
+
+// waitForCacheSync marks the informer caches synced once all the factories are synced. The previous state is
+// dropped, e.g. the controllers are restarted once the feature gates change.
+func (h *healthChecker) waitForCacheSync(ctx context.Context, factories ...cacheSyncWaiter) {
+	synced := &atomic.Bool{}
+	h.lock.Lock()
+	h.cachesSynced = synced.Load
+	h.lock.Unlock()
+
+	for _, factory := range factories {
+		for informerType, ok := range factory.WaitForCacheSync(ctx.Done()) {
+			if !ok {
+				klog.Warningf("The cache of the %s informer is not synced", informerType)
+				return
+			}
+		}
+	}
+	synced.Store(true)
+}
+
+func (h *healthChecker) checkInformerSync(_ *http.Request) error {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	if !h.leading {
+		return nil
+	}
+	if h.cachesSynced == nil || !h.cachesSynced() {
+		return errors.New("the informer caches of the controllers are not synced")
+	}
+	return nil
+}
+
+// checkLeaderElection fails once the lease of the leader election is not renewed in time or is held by another
+// instance while this instance is leading. An error to get the lease is ignored, the api connectivity is checked
+// by the readiness probe, and the lease is lost anyway once the kube-apiserver is not reachable in the lease duration.
+func (h *healthChecker) checkLeaderElection(r *http.Request) error {
+	h.lock.RLock()
+	kubeClient, leading := h.kubeClient, h.leading
+	namespace, name, leaseDuration := h.leaseNamespace, h.leaseName, h.leaseDuration
+	h.lock.RUnlock()
+	if !leading || len(name) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+	lease, err := kubeClient.CoordinationV1().Leases(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		klog.Warningf("Unable to get the leader election lease %s/%s: %v", namespace, name, err)
+		return nil
+	}
+
+	// the identity of the leader is the hostname with a unique suffix
+	if hostname, err := os.Hostname(); err == nil && lease.Spec.HolderIdentity != nil &&
+		!strings.HasPrefix(*lease.Spec.HolderIdentity, hostname+"_") {
+		return fmt.Errorf("the leader election lease %s/%s is held by %s", namespace, name, *lease.Spec.HolderIdentity)
+	}
+	if lease.Spec.RenewTime == nil {
+		return fmt.Errorf("the leader election lease %s/%s is not renewed", namespace, name)
+	}
+	if since := time.Since(lease.Spec.RenewTime.Time); since > leaseDuration {
+		return fmt.Errorf("the leader election lease %s/%s is not renewed in %s, longer than the lease duration %s",
+			namespace, name, since.Round(time.Second), leaseDuration)
+	}
+	return nil
+}
+
+// checkAPIConnectivity fails once the kube-apiserver is not reachable or not live.
+func (h *healthChecker) checkAPIConnectivity(r *http.Request) error {
+	h.lock.RLock()
+	kubeClient := h.kubeClient
+	h.lock.RUnlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+	statusCode := 0
+	err := kubeClient.Discovery().RESTClient().Get().AbsPath("/livez").Do(ctx).StatusCode(&statusCode).Error()
+	switch {
+	case statusCode == 0:
+		return fmt.Errorf("unable to connect to the kube-apiserver: %w", err)
+	case statusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("the kube-apiserver is not live, status code: %d", statusCode)
+	}
+	// the kube-apiserver is reachable even if the livez endpoint is forbidden or not found
+	return nil
+}
@@ -0,0 +1,173 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	testLeaseNamespace = "open-cluster-management-hub"
+	testLeaseName      = "registration-controller-lock"
+)
+
+type fakeCacheSyncWaiter bool
+
+func (f fakeCacheSyncWaiter) WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool {
+	return map[reflect.Type]bool{reflect.TypeOf(f): bool(f)}
+}
+
+func TestHealthChecks(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLease := func(holder string, renewTime time.Time) *coordinationv1.Lease {
+		return &coordinationv1.Lease{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "coordination.k8s.io/v1", Kind: "Lease"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: testLeaseNamespace, Name: testLeaseName},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity: &holder,
+				RenewTime:      &metav1.MicroTime{Time: renewTime},
+			},
+		}
+	}
+
+	cases := []struct {
+		name                  string
+		leading               bool
+		leaseName             string
+		lease                 *coordinationv1.Lease
+		cachesSynced          bool
+		livezStatus           int
+		expectedLeaderErr     string
+		expectedInformerErr   string
+		expectedConnectionErr string
+	}{
+		{
+			name:        "not leading",
+			leaseName:   testLeaseName,
+			livezStatus: http.StatusOK,
+		},
+		{
+			name:         "leading",
+			leading:      true,
+			leaseName:    testLeaseName,
+			lease:        newLease(hostname+"_1", time.Now()),
+			cachesSynced: true,
+			livezStatus:  http.StatusOK,
+		},
+		{
+			name:                "caches are not synced",
+			leading:             true,
+			leaseName:           testLeaseName,
+			lease:               newLease(hostname+"_1", time.Now()),
+			livezStatus:         http.StatusOK,
+			expectedInformerErr: "the informer caches of the controllers are not synced",
+		},
+		{
+			name:              "lease is not renewed",
+			leading:           true,
+			leaseName:         testLeaseName,
+			lease:             newLease(hostname+"_1", time.Now().Add(-5*time.Minute)),
+			cachesSynced:      true,
+			livezStatus:       http.StatusOK,
+			expectedLeaderErr: "the leader election lease open-cluster-management-hub/registration-controller-lock is not renewed in 5m0s, longer than the lease duration 2m0s",
+		},
+		{
+			name:              "lease is held by another instance",
+			leading:           true,
+			leaseName:         testLeaseName,
+			lease:             newLease("another_1", time.Now()),
+			cachesSynced:      true,
+			livezStatus:       http.StatusOK,
+			expectedLeaderErr: "the leader election lease open-cluster-management-hub/registration-controller-lock is held by another_1",
+		},
+		{
+			name:         "leader election is disabled",
+			leading:      true,
+			lease:        newLease("another_1", time.Now().Add(-5*time.Minute)),
+			cachesSynced: true,
+			livezStatus:  http.StatusOK,
+		},
+		{
+			name:         "livez is forbidden",
+			leaseName:    testLeaseName,
+			cachesSynced: true,
+			livezStatus:  http.StatusForbidden,
+		},
+		{
+			name:                  "kube-apiserver is not live",
+			leading:               true,
+			leaseName:             testLeaseName,
+			cachesSynced:          true,
+			livezStatus:           http.StatusServiceUnavailable,
+			expectedConnectionErr: "the kube-apiserver is not live, status code: 503",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/livez":
+					w.WriteHeader(c.livezStatus)
+				case "/apis/coordination.k8s.io/v1/namespaces/" + testLeaseNamespace + "/leases/" + testLeaseName:
+					if c.lease == nil {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.Header().Set("Content-Type", "application/json")
+					if err := json.NewEncoder(w).Encode(c.lease); err != nil {
+						t.Error(err)
+					}
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			kubeClient, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := &healthChecker{
+				kubeClient:    kubeClient,
+				leaseName:     c.leaseName,
+				leaseDuration: 2 * time.Minute,
+			}
+			if c.leading {
+				h.startLeading(testLeaseNamespace)
+			}
+			h.waitForCacheSync(context.TODO(), fakeCacheSyncWaiter(true), fakeCacheSyncWaiter(c.cachesSynced))
+
+			req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+			testinghelpers.AssertError(t, h.checkLeaderElection(req), c.expectedLeaderErr)
+			testinghelpers.AssertError(t, h.checkInformerSync(req), c.expectedInformerErr)
+			testinghelpers.AssertError(t, h.checkAPIConnectivity(req), c.expectedConnectionErr)
+		})
+	}
+}
+
+func TestServeHealthProbesDisabled(t *testing.T) {
+	m := NewHubManagerOptions()
+	m.HealthProbeBindAddress = "0"
+	if err := m.ServeHealthProbes(context.TODO(), &rest.Config{Host: "https://127.0.0.1:6443"}, testLeaseName, time.Minute); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if m.health.kubeClient != nil {
+		t.Errorf("expected the health probes are not served")
+	}
+}
@@ -2,6 +2,7 @@ package lease
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
@@ -9,23 +10,28 @@ import (
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/hub/user"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 
+	authorizationv1 "k8s.io/api/authorization/v1"
 	coordv1 "k8s.io/api/coordination/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	coordinformers "k8s.io/client-go/informers/coordination/v1"
 	"k8s.io/client-go/kubernetes"
 	coordlisters "k8s.io/client-go/listers/coordination/v1"
+	"k8s.io/utils/clock"
 	"k8s.io/utils/pointer"
 )
 
-const leaseDurationTimes = 5
-const leaseName = "managed-cluster-lease"
+// DefaultLeaseDurationTimes is the default times of the lease duration of a managed cluster, within which
+// the lease is expected to be renewed.
+const DefaultLeaseDurationTimes = 5
 
 var (
 	// LeaseDurationSeconds is lease update time interval
@@ -33,27 +39,52 @@ var (
 )
 
 // leaseController checks the lease of managed clusters on hub cluster to determine whether a managed cluster is available.
+//
+// The grace period follows the changes of the lease duration of a managed cluster without restarting. Once
+// the lease duration is shrunk, the previous grace period is still used until the lease is renewed after
+// the change, so that the managed cluster does not become unknown before its agent observes the change.
 type leaseController struct {
 	kubeClient    kubernetes.Interface
 	clusterClient clientset.Interface
 	clusterLister clusterv1listers.ManagedClusterLister
 	leaseLister   coordlisters.LeaseLister
 	eventRecorder events.Recorder
+	clock         clock.Clock
+	// leaseDurationTimes is the times of the lease duration of a managed cluster used as its grace period
+	leaseDurationTimes int32
+	// gracePeriods are the last observed grace periods of the managed clusters
+	gracePeriods map[string]time.Duration
+	// shrinks are the lease duration shrinks of the managed clusters which are not observed by the agents yet
+	shrinks map[string]gracePeriodShrink
 }
 
-// NewClusterLeaseController creates a cluster lease controller on hub cluster.
+// gracePeriodShrink records the grace period of a managed cluster before its lease duration is shrunk.
+type gracePeriodShrink struct {
+	previous time.Duration
+	shrunkAt time.Time
+}
+
+// NewClusterLeaseController creates a cluster lease controller on hub cluster. Besides checking the lease of
+// each managed cluster once its grace period passes, the leases of all managed clusters are checked every
+// resync period. The grace period of a managed cluster is the leaseDurationTimes of its lease duration.
 func NewClusterLeaseController(
 	kubeClient kubernetes.Interface,
 	clusterClient clientset.Interface,
 	clusterInformer clusterv1informer.ManagedClusterInformer,
 	leaseInformer coordinformers.LeaseInformer,
+	resyncPeriod time.Duration,
+	leaseDurationTimes int32,
 	recorder events.Recorder) factory.Controller {
 	c := &leaseController{
-		kubeClient:    kubeClient,
-		clusterClient: clusterClient,
-		clusterLister: clusterInformer.Lister(),
-		leaseLister:   leaseInformer.Lister(),
-		eventRecorder: recorder.WithComponentSuffix("managed-cluster-lease-controller"),
+		kubeClient:         kubeClient,
+		clusterClient:      clusterClient,
+		clusterLister:      clusterInformer.Lister(),
+		leaseLister:        leaseInformer.Lister(),
+		eventRecorder:      recorder.WithComponentSuffix("managed-cluster-lease-controller"),
+		clock:              clock.RealClock{},
+		gracePeriods:       map[string]time.Duration{},
+		shrinks:            map[string]gracePeriodShrink{},
+		leaseDurationTimes: leaseDurationTimes,
 	}
 	return factory.New().
 		WithFilteredEventsInformersQueueKeyFunc(
@@ -74,7 +105,7 @@ func NewClusterLeaseController(
 					return false
 				}
 
-				return metaObj.GetObjectMeta().GetName() == leaseName
+				return metaObj.GetObjectMeta().GetName() == helpers.ManagedClusterLeaseName
 			},
 			leaseInformer.Informer(),
 		).
@@ -82,17 +113,31 @@ func NewClusterLeaseController(
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(helpers.RecoverSync("ManagedClusterLeaseController", c.sync)).
+		ResyncEvery(resyncPeriod).
 		ToController("ManagedClusterLeaseController", recorder)
 }
 
 // sync checks the lease of each accepted cluster on hub to determine whether a managed cluster is available.
 func (c *leaseController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
 	clusterName := syncCtx.QueueKey()
+	if clusterName == factory.DefaultQueueKey {
+		// handle resync
+		clusters, err := c.clusterLister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		for _, cluster := range clusters {
+			syncCtx.Queue().Add(cluster.Name)
+		}
+		return nil
+	}
 
 	cluster, err := c.clusterLister.Get(clusterName)
 	if errors.IsNotFound(err) {
 		// the cluster is not found, do nothing
+		delete(c.gracePeriods, clusterName)
+		delete(c.shrinks, clusterName)
 		return nil
 	}
 	if err != nil {
@@ -104,23 +149,23 @@ func (c *leaseController) sync(ctx context.Context, syncCtx factory.SyncContext)
 		return nil
 	}
 
-	observedLease, err := c.leaseLister.Leases(cluster.Name).Get(leaseName)
+	observedLease, err := c.leaseLister.Leases(cluster.Name).Get(helpers.ManagedClusterLeaseName)
 	if errors.IsNotFound(err) {
 		if !cluster.DeletionTimestamp.IsZero() {
 			// the lease is not found and the cluster is deleting, update the cluster to unknown immediately
-			return c.updateClusterStatus(ctx, cluster)
+			return c.updateClusterStatus(ctx, cluster, nil, 0)
 		}
 
 		// the lease is not found, try to create it
 		lease := &coordv1.Lease{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      leaseName,
+				Name:      helpers.ManagedClusterLeaseName,
 				Namespace: cluster.Name,
 				Labels:    map[string]string{clusterv1.ClusterNameLabelKey: cluster.Name},
 			},
 			Spec: coordv1.LeaseSpec{
-				HolderIdentity: pointer.StringPtr(leaseName),
-				RenewTime:      &metav1.MicroTime{Time: time.Now()},
+				HolderIdentity: pointer.StringPtr(helpers.ManagedClusterLeaseName),
+				RenewTime:      &metav1.MicroTime{Time: c.clock.Now()},
 			},
 		}
 		_, err := c.kubeClient.CoordinationV1().Leases(cluster.Name).Create(ctx, lease, metav1.CreateOptions{})
@@ -130,16 +175,29 @@ func (c *leaseController) sync(ctx context.Context, syncCtx factory.SyncContext)
 		return err
 	}
 
-	gracePeriod := time.Duration(leaseDurationTimes*cluster.Spec.LeaseDurationSeconds) * time.Second
+	gracePeriod := time.Duration(c.leaseDurationTimes*cluster.Spec.LeaseDurationSeconds) * time.Second
 	if gracePeriod == 0 {
 		// FIX: #183 avoid gracePeriod is zero, will non-stop update ManagedClusterLeaseUpdateStopped condition.
-		gracePeriod = time.Duration(leaseDurationTimes*LeaseDurationSeconds) * time.Second
+		gracePeriod = time.Duration(int(c.leaseDurationTimes)*LeaseDurationSeconds) * time.Second
 	}
 
-	now := time.Now()
-	if !now.Before(observedLease.Spec.RenewTime.Add(gracePeriod)) {
+	now := c.clock.Now()
+	effectiveGracePeriod := c.effectiveGracePeriod(clusterName, gracePeriod, observedLease, now)
+	leaseUpdated := now.Before(observedLease.Spec.RenewTime.Add(effectiveGracePeriod))
+	if !leaseUpdated {
 		// the lease is not updated constantly, change the cluster available condition to unknown
-		if err := c.updateClusterStatus(ctx, cluster); err != nil {
+		if err := c.updateClusterStatus(ctx, cluster, observedLease, effectiveGracePeriod); err != nil {
+			return err
+		}
+		if err := c.reviewAgentHubAccess(ctx, cluster); err != nil {
+			return err
+		}
+	}
+
+	// in the hosted mode, the agent keeps updating its lease even if the managed cluster is unreachable,
+	// report whether the agent itself is available to tell the two failures apart
+	if observedLease.Annotations[helpers.AgentDeployModeAnnotation] == helpers.AgentDeployModeHosted {
+		if err := c.updateAgentAvailableCondition(ctx, cluster, leaseUpdated); err != nil {
 			return err
 		}
 	}
@@ -149,26 +207,137 @@ func (c *leaseController) sync(ctx context.Context, syncCtx factory.SyncContext)
 	return nil
 }
 
-func (c *leaseController) updateClusterStatus(ctx context.Context, cluster *clusterv1.ManagedCluster) error {
+// effectiveGracePeriod returns the grace period to check the lease with. It is the previous grace period
+// if the lease duration is shrunk and the lease is not renewed since then, otherwise the current one.
+func (c *leaseController) effectiveGracePeriod(clusterName string, gracePeriod time.Duration, lease *coordv1.Lease, now time.Time) time.Duration {
+	if last, ok := c.gracePeriods[clusterName]; ok && gracePeriod < last {
+		previous := last
+		if shrink, ok := c.shrinks[clusterName]; ok && shrink.previous > previous {
+			previous = shrink.previous
+		}
+		c.shrinks[clusterName] = gracePeriodShrink{previous: previous, shrunkAt: now}
+	}
+	c.gracePeriods[clusterName] = gracePeriod
+
+	shrink, ok := c.shrinks[clusterName]
+	if !ok {
+		return gracePeriod
+	}
+	if gracePeriod >= shrink.previous || (lease.Spec.RenewTime != nil && !lease.Spec.RenewTime.Time.Before(shrink.shrunkAt)) {
+		// the lease duration is grown back, or the agent renews the lease with the shrunk lease duration
+		delete(c.shrinks, clusterName)
+		return gracePeriod
+	}
+	return shrink.previous
+}
+
+// updateClusterStatus updates the available condition of the cluster to unknown. The last renew time of
+// the lease and the grace period are included in the condition message and the event if the lease exists,
+// so that the time the agent stopped can be correlated with other failures, e.g. network outages.
+func (c *leaseController) updateClusterStatus(ctx context.Context, cluster *clusterv1.ManagedCluster,
+	lease *coordv1.Lease, gracePeriod time.Duration) error {
 	if meta.IsStatusConditionPresentAndEqual(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable, metav1.ConditionUnknown) {
 		// the managed cluster available condition alreay is unknown, do nothing
 		return nil
 	}
 
 	// the lease is not constantly updated, update it to unknown
+	message := "Registration agent stopped updating its lease."
+	if lease != nil && lease.Spec.RenewTime != nil {
+		message = fmt.Sprintf("Registration agent stopped updating its lease, it was last renewed at %s, and the grace period is %s.",
+			lease.Spec.RenewTime.UTC().Format(time.RFC3339), gracePeriod)
+	}
 	conditionUpdateFn := helpers.UpdateManagedClusterConditionFn(metav1.Condition{
 		Type:    clusterv1.ManagedClusterConditionAvailable,
 		Status:  metav1.ConditionUnknown,
 		Reason:  "ManagedClusterLeaseUpdateStopped",
-		Message: "Registration agent stopped updating its lease.",
+		Message: message,
 	})
 
 	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.clusterClient, cluster.Name, conditionUpdateFn)
 	if updated {
-		c.eventRecorder.Eventf("ManagedClusterAvailableConditionUpdated",
-			"update managed cluster %q available condition to unknown, due to its lease is not updated constantly",
-			cluster.Name)
+		c.eventRecorder.Warningf("ManagedClusterLeaseUpdateStopped",
+			"update managed cluster %q available condition to unknown: %s", cluster.Name, message)
 	}
 
 	return err
 }
+
+// reviewAgentHubAccess checks whether the agent of the cluster is still allowed to update its lease on hub once
+// the cluster becomes unknown. The agent is unable to report the HubAccessReady condition by itself if it is
+// missing the permission to update the status of the cluster too, so the missing permission is reported by hub.
+func (c *leaseController) reviewAgentHubAccess(ctx context.Context, cluster *clusterv1.ManagedCluster) error {
+	if meta.IsStatusConditionPresentAndEqual(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable, metav1.ConditionUnknown) {
+		// the access was reviewed once the cluster became unknown
+		return nil
+	}
+	if meta.IsStatusConditionFalse(cluster.Status.Conditions, helpers.ManagedClusterConditionHubAccessReady) {
+		return nil
+	}
+
+	// the agent registered with the token driver accesses the hub as the user of the token
+	review, err := c.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   cluster.Annotations[user.TokenUsernameAnnotation],
+			Groups: []string{user.SubjectPrefix + cluster.Name, user.ManagedClustersGroup},
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:     coordv1.GroupName,
+				Resource:  "leases",
+				Verb:      "update",
+				Namespace: cluster.Name,
+				Name:      helpers.ManagedClusterLeaseName,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	if review.Status.Allowed {
+		return nil
+	}
+
+	message := "The agent is missing the permissions on hub: update leases.coordination.k8s.io"
+	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.clusterClient, cluster.Name,
+		helpers.UpdateManagedClusterConditionFn(metav1.Condition{
+			Type:    helpers.ManagedClusterConditionHubAccessReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "HubPermissionsMissing",
+			Message: message,
+		}))
+	if updated {
+		c.eventRecorder.Warningf("HubPermissionsMissing", "managed cluster %q: %s", cluster.Name, message)
+	}
+	return err
+}
+
+func (c *leaseController) updateAgentAvailableCondition(ctx context.Context, cluster *clusterv1.ManagedCluster, leaseUpdated bool) error {
+	condition := metav1.Condition{
+		Type:    helpers.ManagedClusterConditionAgentAvailable,
+		Status:  metav1.ConditionTrue,
+		Reason:  "AgentAvailable",
+		Message: "Registration agent is running on the management cluster.",
+	}
+	switch {
+	case !leaseUpdated:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "AgentLeaseUpdateStopped"
+		condition.Message = "Registration agent on the management cluster stopped updating its lease."
+	case meta.IsStatusConditionFalse(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable):
+		condition.Reason = "ManagedClusterUnreachable"
+		condition.Message = "Registration agent is running on the management cluster, but the managed cluster is unreachable."
+	}
+
+	existing := meta.FindStatusCondition(cluster.Status.Conditions, condition.Type)
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason {
+		return nil
+	}
+
+	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.clusterClient, cluster.Name,
+		helpers.UpdateManagedClusterConditionFn(condition))
+	if updated {
+		c.eventRecorder.Eventf("ManagedClusterAgentAvailableConditionUpdated",
+			"update managed cluster %q registration agent available condition to %s, due to %s",
+			cluster.Name, condition.Status, condition.Reason)
+	}
+	return err
+}
@@ -11,23 +11,34 @@ import (
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/hub/user"
 
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	coordv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubeinformers "k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
 )
 
 var now = time.Now()
 
 func TestSync(t *testing.T) {
 	cases := []struct {
-		name            string
-		clusters        []runtime.Object
-		clusterLeases   []runtime.Object
-		validateActions func(t *testing.T, leaseActions, clusterActions []clienttesting.Action)
+		name          string
+		clusters      []runtime.Object
+		clusterLeases []runtime.Object
+		accessDenied  bool
+		// leaseDurationTimes is DefaultLeaseDurationTimes if it is not set
+		leaseDurationTimes int32
+		validateActions    func(t *testing.T, leaseActions, clusterActions []clienttesting.Action)
 	}{
 		{
 			name:          "sync unaccepted managed cluster",
@@ -44,6 +55,10 @@ func TestSync(t *testing.T) {
 			clusterLeases: []runtime.Object{},
 			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
 				testinghelpers.AssertActions(t, leaseActions, "create")
+				lease := leaseActions[0].(clienttesting.CreateActionImpl).Object.(*coordv1.Lease)
+				if !lease.Spec.RenewTime.Time.Equal(now) {
+					t.Errorf("expected the lease is renewed at %v, but got %v", now, lease.Spec.RenewTime)
+				}
 				testinghelpers.AssertNoActions(t, clusterActions)
 			},
 		},
@@ -56,11 +71,13 @@ func TestSync(t *testing.T) {
 			},
 			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
 				expected := metav1.Condition{
-					Type:    clusterv1.ManagedClusterConditionAvailable,
-					Status:  metav1.ConditionUnknown,
-					Reason:  "ManagedClusterLeaseUpdateStopped",
-					Message: "Registration agent stopped updating its lease.",
+					Type:   clusterv1.ManagedClusterConditionAvailable,
+					Status: metav1.ConditionUnknown,
+					Reason: "ManagedClusterLeaseUpdateStopped",
+					Message: fmt.Sprintf("Registration agent stopped updating its lease, it was last renewed at %s, and the grace period is 5s.",
+						now.Add(-5*time.Minute).UTC().Format(time.RFC3339)),
 				}
+				testinghelpers.AssertActions(t, leaseActions, "create")
 				testinghelpers.AssertActions(t, clusterActions, "get", "patch")
 				patch := clusterActions[1].(clienttesting.PatchAction).GetPatch()
 				managedCluster := &v1.ManagedCluster{}
@@ -71,6 +88,45 @@ func TestSync(t *testing.T) {
 				testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, expected)
 			},
 		},
+		{
+			name:          "agent of a managed cluster which stops updating lease is missing the hub permissions",
+			clusters:      []runtime.Object{testinghelpers.NewAvailableManagedCluster()},
+			clusterLeases: []runtime.Object{testinghelpers.NewManagedClusterLease("managed-cluster-lease", now.Add(-5*time.Minute))},
+			accessDenied:  true,
+			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
+				expected := metav1.Condition{
+					Type:    helpers.ManagedClusterConditionHubAccessReady,
+					Status:  metav1.ConditionFalse,
+					Reason:  "HubPermissionsMissing",
+					Message: "The agent is missing the permissions on hub: update leases.coordination.k8s.io",
+				}
+				testinghelpers.AssertActions(t, leaseActions, "create")
+				review := leaseActions[0].(clienttesting.CreateActionImpl).Object.(*authorizationv1.SubjectAccessReview)
+				if review.Spec.ResourceAttributes.Namespace != testinghelpers.TestManagedClusterName {
+					t.Errorf("expected the access in the cluster namespace is reviewed, but got %v", review.Spec.ResourceAttributes)
+				}
+				testinghelpers.AssertActions(t, clusterActions, "get", "patch", "get", "patch")
+				patch := clusterActions[3].(clienttesting.PatchAction).GetPatch()
+				managedCluster := &v1.ManagedCluster{}
+				if err := json.Unmarshal(patch, managedCluster); err != nil {
+					t.Fatal(err)
+				}
+				testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, expected)
+			},
+		},
+		{
+			name:          "access of the agent registered with the token driver is reviewed as the user of the token",
+			clusters:      []runtime.Object{newTokenManagedCluster()},
+			clusterLeases: []runtime.Object{testinghelpers.NewManagedClusterLease("managed-cluster-lease", now.Add(-5*time.Minute))},
+			accessDenied:  true,
+			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, leaseActions, "create")
+				review := leaseActions[0].(clienttesting.CreateActionImpl).Object.(*authorizationv1.SubjectAccessReview)
+				if review.Spec.User != "system:serviceaccount:cluster1:agent" {
+					t.Errorf("expected the access of the user of the token is reviewed, but got %q", review.Spec.User)
+				}
+			},
+		},
 		{
 			name:          "managed cluster is available",
 			clusters:      []runtime.Object{testinghelpers.NewAvailableManagedCluster()},
@@ -79,6 +135,25 @@ func TestSync(t *testing.T) {
 				testinghelpers.AssertNoActions(t, clusterActions)
 			},
 		},
+		{
+			name:               "managed cluster is available within a longer grace period",
+			clusters:           []runtime.Object{testinghelpers.NewAvailableManagedCluster()},
+			clusterLeases:      []runtime.Object{testinghelpers.NewManagedClusterLease("managed-cluster-lease", now.Add(-5*time.Minute))},
+			leaseDurationTimes: 600,
+			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, clusterActions)
+			},
+		},
+		{
+			name:     "managed cluster is available at the end of the grace period",
+			clusters: []runtime.Object{testinghelpers.NewAvailableManagedCluster()},
+			clusterLeases: []runtime.Object{
+				testinghelpers.NewManagedClusterLease("managed-cluster-lease", now.Add(-5*time.Second+time.Millisecond)),
+			},
+			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, clusterActions)
+			},
+		},
 		{
 			name:     "managed cluster is deleting",
 			clusters: []runtime.Object{newDeletingManagedCluster()},
@@ -99,6 +174,38 @@ func TestSync(t *testing.T) {
 				testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, expected)
 			},
 		},
+		{
+			name:          "agent in the hosted mode is available",
+			clusters:      []runtime.Object{newHostedManagedCluster(metav1.ConditionTrue, "")},
+			clusterLeases: []runtime.Object{newHostedLease(now)},
+			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
+				assertAgentAvailableCondition(t, clusterActions, metav1.ConditionTrue, "AgentAvailable")
+			},
+		},
+		{
+			name:          "managed cluster in the hosted mode is unreachable",
+			clusters:      []runtime.Object{newHostedManagedCluster(metav1.ConditionFalse, "AgentAvailable")},
+			clusterLeases: []runtime.Object{newHostedLease(now)},
+			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
+				assertAgentAvailableCondition(t, clusterActions, metav1.ConditionTrue, "ManagedClusterUnreachable")
+			},
+		},
+		{
+			name:          "agent in the hosted mode stops updating lease",
+			clusters:      []runtime.Object{newHostedManagedCluster(metav1.ConditionUnknown, "ManagedClusterUnreachable")},
+			clusterLeases: []runtime.Object{newHostedLease(now.Add(-5 * time.Minute))},
+			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
+				assertAgentAvailableCondition(t, clusterActions, metav1.ConditionFalse, "AgentLeaseUpdateStopped")
+			},
+		},
+		{
+			name:          "agent available condition in the hosted mode is up to date",
+			clusters:      []runtime.Object{newHostedManagedCluster(metav1.ConditionFalse, "ManagedClusterUnreachable")},
+			clusterLeases: []runtime.Object{newHostedLease(now)},
+			validateActions: func(t *testing.T, leaseActions, clusterActions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, clusterActions)
+			},
+		},
 		{
 			name:          "managed cluster is unknown",
 			clusters:      []runtime.Object{testinghelpers.NewUnknownManagedCluster()},
@@ -121,6 +228,11 @@ func TestSync(t *testing.T) {
 			}
 
 			leaseClient := kubefake.NewSimpleClientset(c.clusterLeases...)
+			leaseClient.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				review := action.(clienttesting.CreateActionImpl).Object.(*authorizationv1.SubjectAccessReview).DeepCopy()
+				review.Status.Allowed = !c.accessDenied
+				return true, review, nil
+			})
 			leaseInformerFactory := kubeinformers.NewSharedInformerFactory(leaseClient, time.Minute*10)
 			leaseStore := leaseInformerFactory.Coordination().V1().Leases().Informer().GetStore()
 			for _, lease := range c.clusterLeases {
@@ -132,11 +244,18 @@ func TestSync(t *testing.T) {
 			syncCtx := testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName)
 
 			ctrl := &leaseController{
-				kubeClient:    leaseClient,
-				clusterClient: clusterClient,
-				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
-				leaseLister:   leaseInformerFactory.Coordination().V1().Leases().Lister(),
-				eventRecorder: syncCtx.Recorder(),
+				kubeClient:         leaseClient,
+				clusterClient:      clusterClient,
+				clusterLister:      clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				leaseLister:        leaseInformerFactory.Coordination().V1().Leases().Lister(),
+				eventRecorder:      syncCtx.Recorder(),
+				clock:              clocktesting.NewFakeClock(now),
+				gracePeriods:       map[string]time.Duration{},
+				shrinks:            map[string]gracePeriodShrink{},
+				leaseDurationTimes: DefaultLeaseDurationTimes,
+			}
+			if c.leaseDurationTimes != 0 {
+				ctrl.leaseDurationTimes = c.leaseDurationTimes
 			}
 			syncErr := ctrl.sync(context.TODO(), syncCtx)
 			if syncErr != nil {
@@ -153,3 +272,174 @@ func newDeletingManagedCluster() *clusterv1.ManagedCluster {
 	cluster.DeletionTimestamp = &now
 	return cluster
 }
+
+// newTokenManagedCluster returns an available managed cluster registered with the token driver.
+func newTokenManagedCluster() *clusterv1.ManagedCluster {
+	cluster := testinghelpers.NewAvailableManagedCluster()
+	cluster.Annotations = map[string]string{user.TokenUsernameAnnotation: "system:serviceaccount:cluster1:agent"}
+	return cluster
+}
+
+// newHostedLease returns the lease renewed by the agent in the hosted mode at the renewTime.
+func newHostedLease(renewTime time.Time) *coordv1.Lease {
+	lease := testinghelpers.NewManagedClusterLease("managed-cluster-lease", renewTime)
+	lease.Annotations = map[string]string{helpers.AgentDeployModeAnnotation: helpers.AgentDeployModeHosted}
+	return lease
+}
+
+// newHostedManagedCluster returns an accepted managed cluster with the given available condition, and the
+// agent available condition with the given reason if it is not empty.
+func newHostedManagedCluster(available metav1.ConditionStatus, agentAvailableReason string) *clusterv1.ManagedCluster {
+	cluster := testinghelpers.NewAcceptedManagedCluster()
+	cluster.Status.Conditions = append(cluster.Status.Conditions, metav1.Condition{
+		Type:   clusterv1.ManagedClusterConditionAvailable,
+		Status: available,
+		Reason: "Test",
+	})
+	if len(agentAvailableReason) != 0 {
+		cluster.Status.Conditions = append(cluster.Status.Conditions, metav1.Condition{
+			Type:   helpers.ManagedClusterConditionAgentAvailable,
+			Status: metav1.ConditionTrue,
+			Reason: agentAvailableReason,
+		})
+	}
+	return cluster
+}
+
+func assertAgentAvailableCondition(t *testing.T, clusterActions []clienttesting.Action,
+	status metav1.ConditionStatus, reason string) {
+	patch := clusterActions[len(clusterActions)-1].(clienttesting.PatchAction).GetPatch()
+	managedCluster := &v1.ManagedCluster{}
+	if err := json.Unmarshal(patch, managedCluster); err != nil {
+		t.Fatal(err)
+	}
+	condition := meta.FindStatusCondition(managedCluster.Status.Conditions, helpers.ManagedClusterConditionAgentAvailable)
+	if condition == nil || condition.Status != status || condition.Reason != reason {
+		t.Errorf("expected agent available condition %s with reason %q, but got %v", status, reason, condition)
+	}
+}
+
+func TestResync(t *testing.T) {
+	clusters := []runtime.Object{
+		testinghelpers.NewAvailableManagedCluster(),
+		&v1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster2"}},
+	}
+	clusterClient := clusterfake.NewSimpleClientset(clusters...)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+	for _, cluster := range clusters {
+		if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	leaseClient := kubefake.NewSimpleClientset()
+	ctrl := &leaseController{
+		kubeClient:    leaseClient,
+		clusterClient: clusterClient,
+		clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+		gracePeriods:  map[string]time.Duration{},
+		shrinks:       map[string]gracePeriodShrink{},
+	}
+	syncCtx := testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey)
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+
+	testinghelpers.AssertNoActions(t, clusterClient.Actions())
+	testinghelpers.AssertNoActions(t, leaseClient.Actions())
+	if syncCtx.Queue().Len() != len(clusters) {
+		t.Errorf("expected %d clusters are requeued, but got %d", len(clusters), syncCtx.Queue().Len())
+	}
+}
+
+func TestEffectiveGracePeriod(t *testing.T) {
+	cases := []struct {
+		name                string
+		gracePeriods        map[string]time.Duration
+		shrinks             map[string]gracePeriodShrink
+		gracePeriod         time.Duration
+		renewTime           time.Time
+		expectedGracePeriod time.Duration
+		expectedShrunk      bool
+	}{
+		{
+			name:                "grace period is observed first time",
+			gracePeriod:         time.Minute,
+			renewTime:           now.Add(-time.Minute),
+			expectedGracePeriod: time.Minute,
+		},
+		{
+			name:                "lease duration is grown",
+			gracePeriods:        map[string]time.Duration{testinghelpers.TestManagedClusterName: time.Minute},
+			gracePeriod:         5 * time.Minute,
+			renewTime:           now.Add(-time.Minute),
+			expectedGracePeriod: 5 * time.Minute,
+		},
+		{
+			name:                "lease duration is shrunk",
+			gracePeriods:        map[string]time.Duration{testinghelpers.TestManagedClusterName: 5 * time.Minute},
+			gracePeriod:         time.Minute,
+			renewTime:           now.Add(-time.Minute),
+			expectedGracePeriod: 5 * time.Minute,
+			expectedShrunk:      true,
+		},
+		{
+			name:         "lease is not renewed after the lease duration is shrunk",
+			gracePeriods: map[string]time.Duration{testinghelpers.TestManagedClusterName: time.Minute},
+			shrinks: map[string]gracePeriodShrink{
+				testinghelpers.TestManagedClusterName: {previous: 5 * time.Minute, shrunkAt: now.Add(-time.Second)},
+			},
+			gracePeriod:         time.Minute,
+			renewTime:           now.Add(-time.Minute),
+			expectedGracePeriod: 5 * time.Minute,
+			expectedShrunk:      true,
+		},
+		{
+			name:         "lease is renewed after the lease duration is shrunk",
+			gracePeriods: map[string]time.Duration{testinghelpers.TestManagedClusterName: time.Minute},
+			shrinks: map[string]gracePeriodShrink{
+				testinghelpers.TestManagedClusterName: {previous: 5 * time.Minute, shrunkAt: now.Add(-time.Minute)},
+			},
+			gracePeriod:         time.Minute,
+			renewTime:           now,
+			expectedGracePeriod: time.Minute,
+		},
+		{
+			name:         "lease duration is grown back",
+			gracePeriods: map[string]time.Duration{testinghelpers.TestManagedClusterName: time.Minute},
+			shrinks: map[string]gracePeriodShrink{
+				testinghelpers.TestManagedClusterName: {previous: 5 * time.Minute, shrunkAt: now.Add(-time.Second)},
+			},
+			gracePeriod:         10 * time.Minute,
+			renewTime:           now.Add(-time.Minute),
+			expectedGracePeriod: 10 * time.Minute,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := &leaseController{
+				gracePeriods: map[string]time.Duration{},
+				shrinks:      map[string]gracePeriodShrink{},
+			}
+			for name, gracePeriod := range c.gracePeriods {
+				ctrl.gracePeriods[name] = gracePeriod
+			}
+			for name, shrink := range c.shrinks {
+				ctrl.shrinks[name] = shrink
+			}
+
+			lease := testinghelpers.NewManagedClusterLease("managed-cluster-lease", c.renewTime)
+			gracePeriod := ctrl.effectiveGracePeriod(testinghelpers.TestManagedClusterName, c.gracePeriod, lease, now)
+			if gracePeriod != c.expectedGracePeriod {
+				t.Errorf("expected grace period %v, but got %v", c.expectedGracePeriod, gracePeriod)
+			}
+			if _, shrunk := ctrl.shrinks[testinghelpers.TestManagedClusterName]; shrunk != c.expectedShrunk {
+				t.Errorf("expected shrunk %t, but got %t", c.expectedShrunk, shrunk)
+			}
+			if ctrl.gracePeriods[testinghelpers.TestManagedClusterName] != c.gracePeriod {
+				t.Errorf("expected the grace period %v recorded, but got %v", c.gracePeriod, ctrl.gracePeriods[testinghelpers.TestManagedClusterName])
+			}
+		})
+	}
+}
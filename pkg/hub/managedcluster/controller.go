@@ -5,31 +5,54 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
+	addonclientset "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
+	worklister "open-cluster-management.io/api/client/work/listers/work/v1"
 	v1 "open-cluster-management.io/api/cluster/v1"
 	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcehelper"
 	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
 
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
 
 const (
 	managedClusterFinalizer = "cluster.open-cluster-management.io/api-resource-cleanup"
+
+	// managedClusterFieldManager is the field manager of the resources applied for a managed cluster, only
+	// the fields in the manifests are owned by it, the fields added by others are kept.
+	managedClusterFieldManager = "registration-managed-cluster-controller"
 )
 
+// ResyncInterval is exposed so that integration tests can crank up the controller sync speed. The
+// resources of all accepted managed clusters are re-applied periodically to correct the drift of the
+// fields owned by the controller.
+var ResyncInterval = 10 * time.Minute
+
 //go:embed manifests
 var manifestFiles embed.FS
 
@@ -40,43 +63,122 @@ var staticFiles = []string{
 	"manifests/managedcluster-work-rolebinding.yaml",
 }
 
+// appliedResources are the resources applied for a managed cluster keyed by their kinds.
+var appliedResources = map[schema.GroupKind]schema.GroupVersionResource{
+	{Kind: "Namespace"}:                                   corev1.SchemeGroupVersion.WithResource("namespaces"),
+	{Group: rbacv1.GroupName, Kind: "ClusterRole"}:        rbacv1.SchemeGroupVersion.WithResource("clusterroles"),
+	{Group: rbacv1.GroupName, Kind: "ClusterRoleBinding"}: rbacv1.SchemeGroupVersion.WithResource("clusterrolebindings"),
+	{Group: rbacv1.GroupName, Kind: "RoleBinding"}:        rbacv1.SchemeGroupVersion.WithResource("rolebindings"),
+}
+
 // managedClusterController reconciles instances of ManagedCluster on the hub.
 type managedClusterController struct {
-	kubeClient    kubernetes.Interface
-	clusterClient clientset.Interface
-	clusterLister listerv1.ManagedClusterLister
-	cache         resourceapply.ResourceCache
-	eventRecorder events.Recorder
+	kubeClient             kubernetes.Interface
+	clusterClient          clientset.Interface
+	clusterLister          listerv1.ManagedClusterLister
+	clusterPatcher         *helpers.ClusterPatcher
+	appliedResourceListers map[schema.GroupKind]cache.GenericLister
+	resourceCache          resourceapply.ResourceCache
+	workClient             workclientset.Interface
+	workLister             worklister.ManifestWorkLister
+	addOnClient            addonclientset.Interface
+	addOnLister            addonlisterv1alpha1.ManagedClusterAddOnLister
+	eventRecorder          events.Recorder
+
+	cleanupFinalizerPrefixes []string
+	hubNamespace             string
+	manifestValues           map[string]interface{}
+
+	// hubAccessLostAt are the times the HubAccessReady conditions of the managed clusters became false
+	hubAccessLostAt map[string]metav1.Time
+	hubAccessLock   sync.Mutex
 }
 
-// NewManagedClusterController creates a new managed cluster controller
+// NewManagedClusterController creates a new managed cluster controller. Besides the managed clusters, it
+// watches the metadata of the namespace and rbac resources it applies, which are labeled with the cluster
+// name, so a changed or deleted resource is re-applied at once. The appliedResourceInformers must only cache the
+// resources with the cluster name label, e.g. filtered with a label selector. A deleting
+// managed cluster is not cleaned up until its finalizers with any of the cleanupFinalizerPrefixes are removed,
+// see helpers.ManagedClusterCleanupFinalizerPrefix. The manifests are rendered with the hubNamespace and the
+// manifestValues, see helpers.ManagedClusterAssetData.
 func NewManagedClusterController(
 	kubeClient kubernetes.Interface,
 	clusterClient clientset.Interface,
 	clusterInformer informerv1.ManagedClusterInformer,
+	appliedResourceInformers metadatainformer.SharedInformerFactory,
+	workClient workclientset.Interface,
+	workLister worklister.ManifestWorkLister,
+	addOnClient addonclientset.Interface,
+	addOnLister addonlisterv1alpha1.ManagedClusterAddOnLister,
+	cleanupFinalizerPrefixes []string,
+	hubNamespace string,
+	manifestValues map[string]interface{},
 	recorder events.Recorder) factory.Controller {
 	c := &managedClusterController{
-		kubeClient:    kubeClient,
-		clusterClient: clusterClient,
-		clusterLister: clusterInformer.Lister(),
-		cache:         resourceapply.NewResourceCache(),
-		eventRecorder: recorder.WithComponentSuffix("managed-cluster-controller"),
+		kubeClient:             kubeClient,
+		clusterClient:          clusterClient,
+		clusterLister:          clusterInformer.Lister(),
+		clusterPatcher:         helpers.NewClusterPatcher(clusterClient),
+		appliedResourceListers: map[schema.GroupKind]cache.GenericLister{},
+		resourceCache:          helpers.NewResourceCache(),
+		workClient:             workClient,
+		workLister:             workLister,
+		addOnClient:            addOnClient,
+		addOnLister:            addOnLister,
+		eventRecorder:          recorder.WithComponentSuffix("managed-cluster-controller"),
+
+		cleanupFinalizerPrefixes: cleanupFinalizerPrefixes,
+		hubNamespace:             hubNamespace,
+		manifestValues:           manifestValues,
+		hubAccessLostAt:          map[string]metav1.Time{},
+	}
+	appliedResourceInformerList := []factory.Informer{}
+	for kind, resource := range appliedResources {
+		informer := appliedResourceInformers.ForResource(resource)
+		c.appliedResourceListers[kind] = informer.Lister()
+		appliedResourceInformerList = append(appliedResourceInformerList, informer.Informer())
 	}
 	return factory.New().
 		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithInformersQueueKeysFunc(
+			func(obj runtime.Object) []string {
+				accessor, _ := meta.Accessor(obj)
+				clusterName, ok := accessor.GetLabels()[v1.ClusterNameLabelKey]
+				if !ok {
+					return nil
+				}
+				return []string{clusterName}
+			},
+			appliedResourceInformerList...).
+		WithSync(helpers.RecoverSync("ManagedClusterController", c.sync)).
+		ResyncEvery(ResyncInterval).
 		ToController("ManagedClusterController", recorder)
 }
 
 func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
 	managedClusterName := syncCtx.QueueKey()
+	if managedClusterName == factory.DefaultQueueKey {
+		// handle resync
+		clusters, err := c.clusterLister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		for _, cluster := range clusters {
+			syncCtx.Queue().Add(cluster.Name)
+		}
+		return nil
+	}
+
 	klog.V(4).Infof("Reconciling ManagedCluster %s", managedClusterName)
 	managedCluster, err := c.clusterLister.Get(managedClusterName)
 	if errors.IsNotFound(err) {
 		// Spoke cluster not found, could have been deleted, do nothing.
+		c.hubAccessLock.Lock()
+		delete(c.hubAccessLostAt, managedClusterName)
+		c.hubAccessLock.Unlock()
 		return nil
 	}
 	if err != nil {
@@ -107,6 +209,16 @@ func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.Syn
 
 	// Spoke cluster is deleting, we remove its related resources
 	if !managedCluster.DeletionTimestamp.IsZero() {
+		// the cluster is requeued once the finalizers are removed
+		if pending := c.pendingCleanupFinalizers(managedCluster); len(pending) != 0 {
+			klog.V(2).Infof("Waiting for the cleanup finalizers %s of ManagedCluster %s", strings.Join(pending, ", "), managedClusterName)
+			return nil
+		}
+		if managedCluster.Annotations[helpers.ManagedClusterDeletionModeAnnotation] == helpers.ManagedClusterDeletionModePurge {
+			if err := c.purgeManagedClusterWorkloads(ctx, managedCluster); err != nil {
+				return err
+			}
+		}
 		if err := c.removeManagedClusterResources(ctx, managedClusterName); err != nil {
 			return err
 		}
@@ -126,35 +238,45 @@ func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.Syn
 			return err
 		}
 
-		_, _, err := helpers.UpdateManagedClusterStatus(
-			ctx,
-			c.clusterClient,
-			managedClusterName,
-			helpers.UpdateManagedClusterConditionFn(metav1.Condition{
-				Type:    v1.ManagedClusterConditionHubAccepted,
-				Status:  metav1.ConditionFalse,
-				Reason:  "HubClusterAdminDenied",
-				Message: "Denied by hub cluster admin",
-			}),
-		)
+		_, err := c.clusterPatcher.PatchStatusConditions(ctx, managedCluster, metav1.Condition{
+			Type:    v1.ManagedClusterConditionHubAccepted,
+			Status:  metav1.ConditionFalse,
+			Reason:  helpers.HubClusterAdminDeniedReason,
+			Message: "Denied by hub cluster admin",
+		})
 		return err
 	}
 
 	// TODO consider to add the managedcluster-namespace.yaml back to staticFiles,
-	// currently, we keep the namespace after the managed cluster is deleted.
+	// currently, we keep the namespace after the managed cluster is deleted, it is labeled with the cluster name
+	// so that it can be cleaned up by the orphaned namespace controller once it is enabled.
 	applyFiles := []string{"manifests/managedcluster-namespace.yaml"}
 	applyFiles = append(applyFiles, staticFiles...)
 
+	// The agent is missing permissions on the hub, the rbac resources may be changed out of band, re-apply all
+	// of them regardless of the resource cache once the HubAccessReady condition becomes false.
+	resourceCache := c.resourceCache
+	if c.hubAccessLost(managedCluster) {
+		c.eventRecorder.Eventf("ManagedClusterRBACReapplying",
+			"re-apply the rbac of managed cluster %s since its agent is missing permissions on hub", managedClusterName)
+		resourceCache = resourceapply.NewResourceCache()
+	}
+
 	// Hub cluster-admin accepts the spoke cluster, we apply
 	// 1. clusterrole and clusterrolebinding for this spoke cluster.
 	// 2. namespace for this spoke cluster.
 	// 3. role and rolebinding for this spoke cluster on its namespace.
-	resourceResults := resourceapply.ApplyDirectly(
+	// The resources are applied with server-side apply once they or the manifests are changed, so the drift
+	// of the fields in the manifests, e.g. the rbac changed out of band, is corrected, and the labels and
+	// annotations added by users are kept.
+	resourceResults := helpers.ApplyManagedClusterManifests(
 		ctx,
-		resourceapply.NewKubeClientHolder(c.kubeClient),
+		helpers.NewManifestClientHolder(c.kubeClient).
+			WithFieldManager(managedClusterFieldManager).
+			WithExistingObjectFunc(c.getAppliedResource),
 		syncCtx.Recorder(),
-		c.cache,
-		helpers.ManagedClusterAssetFn(manifestFiles, managedClusterName),
+		resourceCache,
+		helpers.ManagedClusterAssetFnWithData(manifestFiles, helpers.ManagedClusterAssetData(managedCluster, c.hubNamespace, c.manifestValues)),
 		applyFiles...,
 	)
 	errs := []error{}
@@ -171,6 +293,10 @@ func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.Syn
 		Reason:  "HubClusterAdminAccepted",
 		Message: "Accepted by hub cluster admin",
 	}
+	if acceptedBy, ok := managedCluster.Annotations[helpers.ManagedClusterAcceptedByAnnotation]; ok {
+		acceptedCondition.Message = fmt.Sprintf("Accepted by hub cluster admin, acceptedBy: %s, acceptedTime: %s",
+			acceptedBy, managedCluster.Annotations[helpers.ManagedClusterAcceptedTimeAnnotation])
+	}
 
 	if len(errs) > 0 {
 		applyErrors := operatorhelpers.NewMultiLineAggregate(errs)
@@ -178,12 +304,7 @@ func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.Syn
 		acceptedCondition.Message = applyErrors.Error()
 	}
 
-	_, updated, updatedErr := helpers.UpdateManagedClusterStatus(
-		ctx,
-		c.clusterClient,
-		managedClusterName,
-		helpers.UpdateManagedClusterConditionFn(acceptedCondition),
-	)
+	updated, updatedErr := c.clusterPatcher.PatchStatusConditions(ctx, managedCluster, acceptedCondition)
 	if updatedErr != nil {
 		errs = append(errs, updatedErr)
 	}
@@ -193,16 +314,168 @@ func (c *managedClusterController) sync(ctx context.Context, syncCtx factory.Syn
 	return operatorhelpers.NewMultiLineAggregate(errs)
 }
 
+// hubAccessLost returns true if the HubAccessReady condition of the managed cluster became false since the last
+// call, so the resources are only re-applied once per transition instead of on every sync.
+func (c *managedClusterController) hubAccessLost(managedCluster *v1.ManagedCluster) bool {
+	c.hubAccessLock.Lock()
+	defer c.hubAccessLock.Unlock()
+
+	cond := meta.FindStatusCondition(managedCluster.Status.Conditions, helpers.ManagedClusterConditionHubAccessReady)
+	if cond == nil || cond.Status != metav1.ConditionFalse {
+		delete(c.hubAccessLostAt, managedCluster.Name)
+		return false
+	}
+	if lostAt, ok := c.hubAccessLostAt[managedCluster.Name]; ok && lostAt.Equal(&cond.LastTransitionTime) {
+		return false
+	}
+	c.hubAccessLostAt[managedCluster.Name] = cond.LastTransitionTime
+	return true
+}
+
+// getAppliedResource returns the metadata of the resource applied for the required object from the informer cache.
+func (c *managedClusterController) getAppliedResource(required runtime.Object) (runtime.Object, error) {
+	lister, ok := c.appliedResourceListers[resourcehelper.GuessObjectGroupVersionKind(required).GroupKind()]
+	if !ok {
+		return nil, fmt.Errorf("unhandled type %T", required)
+	}
+	accessor, err := meta.Accessor(required)
+	if err != nil {
+		return nil, err
+	}
+	if len(accessor.GetNamespace()) == 0 {
+		return lister.Get(accessor.GetName())
+	}
+	return lister.ByNamespace(accessor.GetNamespace()).Get(accessor.GetName())
+}
+
+// pendingCleanupFinalizers returns the finalizers of the managed cluster added by the third-party cleanup
+// controllers, i.e. with any of the cleanup finalizer prefixes.
+func (c *managedClusterController) pendingCleanupFinalizers(managedCluster *v1.ManagedCluster) []string {
+	pending := []string{}
+	for _, finalizer := range managedCluster.Finalizers {
+		if finalizer == managedClusterFinalizer {
+			continue
+		}
+		for _, prefix := range c.cleanupFinalizerPrefixes {
+			if strings.HasPrefix(finalizer, prefix) {
+				pending = append(pending, finalizer)
+				break
+			}
+		}
+	}
+	return pending
+}
+
+// updateContentDeleteProgressFn updates the ContentDeleteSuccess condition with the progress, the message is
+// for display only.
+func updateContentDeleteProgressFn(progress helpers.ContentDeleteProgress) helpers.UpdateManagedClusterStatusFunc {
+	return func(oldStatus *v1.ManagedClusterStatus) error {
+		if progress.Remaining == 0 {
+			meta.SetStatusCondition(&oldStatus.Conditions, metav1.Condition{
+				Type:    helpers.ManagedClusterConditionContentDeleteSuccess,
+				Status:  metav1.ConditionTrue,
+				Reason:  helpers.ContentDeleteReasonContentDeleted,
+				Message: fmt.Sprintf("0 of %d resources remaining", progress.Total),
+			})
+			return nil
+		}
+		meta.SetStatusCondition(&oldStatus.Conditions, metav1.Condition{
+			Type:   helpers.ManagedClusterConditionContentDeleteSuccess,
+			Status: metav1.ConditionFalse,
+			Reason: helpers.ContentDeleteReasonResourceRemaining,
+			Message: fmt.Sprintf("%d of %d resources remaining: %d manifestworks, %d addons",
+				progress.Remaining, progress.Total, progress.ManifestWorks, progress.AddOns),
+		})
+		return nil
+	}
+}
+
+// updateContentDeleteProgress records the progress of the purge of the managed cluster in the annotation and the
+// ContentDeleteSuccess condition. The total is read back from the annotation, so the progress is not reset once
+// the controller restarts.
+func (c *managedClusterController) updateContentDeleteProgress(ctx context.Context, managedCluster *v1.ManagedCluster, works, addOns int) error {
+	progress := helpers.ContentDeleteProgress{
+		Total:         works + addOns,
+		Remaining:     works + addOns,
+		ManifestWorks: works,
+		AddOns:        addOns,
+	}
+	if value, ok := managedCluster.Annotations[helpers.ManagedClusterContentDeleteProgressAnnotation]; ok {
+		last := helpers.ContentDeleteProgress{}
+		if err := json.Unmarshal([]byte(value), &last); err != nil {
+			klog.Warningf("Unable to decode the content delete progress of ManagedCluster %s: %v", managedCluster.Name, err)
+		} else if last.Total > progress.Total {
+			progress.Total = last.Total
+		}
+	}
+
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	if _, err := c.clusterPatcher.PatchAnnotations(ctx, managedCluster, map[string]string{
+		helpers.ManagedClusterContentDeleteProgressAnnotation: string(data),
+	}); err != nil {
+		return err
+	}
+	_, _, err = helpers.UpdateManagedClusterStatus(ctx, c.clusterClient, managedCluster.Name, updateContentDeleteProgressFn(progress))
+	return err
+}
+
 func (c *managedClusterController) removeManagedClusterResources(ctx context.Context, managedClusterName string) error {
 	errs := []error{}
 	// Clean up managed cluster manifests
 	assetFn := helpers.ManagedClusterAssetFn(manifestFiles, managedClusterName)
-	if err := helpers.CleanUpManagedClusterManifests(ctx, c.kubeClient, c.eventRecorder, assetFn, staticFiles...); err != nil {
+	if err := helpers.CleanUpManagedClusterManifests(ctx, helpers.NewManifestClientHolder(c.kubeClient), c.eventRecorder, assetFn, staticFiles...); err != nil {
 		errs = append(errs, err)
 	}
 	return operatorhelpers.NewMultiLineAggregate(errs)
 }
 
+// purgeManagedClusterWorkloads deletes the ManifestWorks and ManagedClusterAddOns in the namespace of the
+// managed cluster, it returns an error to requeue the managed cluster until all of them are gone.
+func (c *managedClusterController) purgeManagedClusterWorkloads(ctx context.Context, managedCluster *v1.ManagedCluster) error {
+	managedClusterName := managedCluster.Name
+	works, err := c.workLister.ManifestWorks(managedClusterName).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	addOns, err := c.addOnLister.ManagedClusterAddOns(managedClusterName).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	if err := c.updateContentDeleteProgress(ctx, managedCluster, len(works), len(addOns)); err != nil {
+		return err
+	}
+	if len(works) == 0 && len(addOns) == 0 {
+		return nil
+	}
+
+	errs := []error{}
+	for _, work := range works {
+		if !work.DeletionTimestamp.IsZero() {
+			continue
+		}
+		err := c.workClient.WorkV1().ManifestWorks(managedClusterName).Delete(ctx, work.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	for _, addOn := range addOns {
+		if !addOn.DeletionTimestamp.IsZero() {
+			continue
+		}
+		err := c.addOnClient.AddonV1alpha1().ManagedClusterAddOns(managedClusterName).Delete(ctx, addOn.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return operatorhelpers.NewMultiLineAggregate(errs)
+	}
+	return fmt.Errorf("still having %d manifestworks and %d addons in the cluster namespace %s", len(works), len(addOns), managedClusterName)
+}
+
 func (c *managedClusterController) removeManagedClusterFinalizer(ctx context.Context, managedCluster *v1.ManagedCluster) error {
 	copiedFinalizers := []string{}
 	for i := range managedCluster.Finalizers {
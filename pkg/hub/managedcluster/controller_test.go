@@ -6,18 +6,28 @@ import (
 	"testing"
 	"time"
 
-	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
+	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	kubefake "k8s.io/client-go/kubernetes/fake"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
 	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestSyncManagedCluster(t *testing.T) {
@@ -57,8 +67,35 @@ func TestSyncManagedCluster(t *testing.T) {
 					Reason:  "HubClusterAdminAccepted",
 					Message: "Accepted by hub cluster admin",
 				}
-				testinghelpers.AssertActions(t, actions, "get", "patch")
-				patch := actions[1].(clienttesting.PatchAction).GetPatch()
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := actions[0].(clienttesting.PatchAction).GetPatch()
+				managedCluster := &v1.ManagedCluster{}
+				err := json.Unmarshal(patch, managedCluster)
+				if err != nil {
+					t.Fatal(err)
+				}
+				testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, expectedCondition)
+			},
+		},
+		{
+			name: "accept a spoke cluster with the acceptance annotations",
+			startingObjects: []runtime.Object{func() *v1.ManagedCluster {
+				cluster := testinghelpers.NewAcceptingManagedCluster()
+				cluster.Annotations = map[string]string{
+					helpers.ManagedClusterAcceptedByAnnotation:   "admin",
+					helpers.ManagedClusterAcceptedTimeAnnotation: "2023-01-01T00:00:00Z",
+				}
+				return cluster
+			}()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				expectedCondition := metav1.Condition{
+					Type:    v1.ManagedClusterConditionHubAccepted,
+					Status:  metav1.ConditionTrue,
+					Reason:  "HubClusterAdminAccepted",
+					Message: "Accepted by hub cluster admin, acceptedBy: admin, acceptedTime: 2023-01-01T00:00:00Z",
+				}
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := actions[0].(clienttesting.PatchAction).GetPatch()
 				managedCluster := &v1.ManagedCluster{}
 				err := json.Unmarshal(patch, managedCluster)
 				if err != nil {
@@ -71,7 +108,22 @@ func TestSyncManagedCluster(t *testing.T) {
 			name:            "sync an accepted spoke cluster",
 			startingObjects: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "get")
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name: "sync an accepted spoke cluster whose agent is missing hub permissions",
+			startingObjects: []runtime.Object{func() *v1.ManagedCluster {
+				cluster := testinghelpers.NewAcceptedManagedCluster()
+				cluster.Status.Conditions = append(cluster.Status.Conditions, metav1.Condition{
+					Type:   helpers.ManagedClusterConditionHubAccessReady,
+					Status: metav1.ConditionFalse,
+					Reason: "HubPermissionsMissing",
+				})
+				return cluster
+			}()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
 			},
 		},
 		{
@@ -81,11 +133,11 @@ func TestSyncManagedCluster(t *testing.T) {
 				expectedCondition := metav1.Condition{
 					Type:    v1.ManagedClusterConditionHubAccepted,
 					Status:  metav1.ConditionFalse,
-					Reason:  "HubClusterAdminDenied",
+					Reason:  helpers.HubClusterAdminDeniedReason,
 					Message: "Denied by hub cluster admin",
 				}
-				testinghelpers.AssertActions(t, actions, "get", "patch")
-				patch := actions[1].(clienttesting.PatchAction).GetPatch()
+				testinghelpers.AssertActions(t, actions, "patch")
+				patch := actions[0].(clienttesting.PatchAction).GetPatch()
 				managedCluster := &v1.ManagedCluster{}
 				err := json.Unmarshal(patch, managedCluster)
 				if err != nil {
@@ -122,13 +174,286 @@ func TestSyncManagedCluster(t *testing.T) {
 				}
 			}
 
-			ctrl := managedClusterController{kubeClient, clusterClient, clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), resourceapply.NewResourceCache(), eventstesting.NewTestingEventRecorder(t)}
+			// the fake kube client is unable to apply a resource which does not exist
+			kubeClient.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				patch := action.(clienttesting.PatchActionImpl)
+				if patch.PatchType != types.ApplyPatchType {
+					return false, nil, nil
+				}
+				applied, _, err := kubescheme.Codecs.UniversalDeserializer().Decode(patch.Patch, nil, nil)
+				if err != nil {
+					return true, nil, err
+				}
+				applied.(metav1.Object).SetResourceVersion("1")
+				return true, applied, nil
+			})
+
+			ctrl := managedClusterController{
+				kubeClient:             kubeClient,
+				clusterClient:          clusterClient,
+				clusterLister:          clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				clusterPatcher:         helpers.NewClusterPatcher(clusterClient),
+				appliedResourceListers: newAppliedResourceListers(),
+				resourceCache:          helpers.NewResourceCache(),
+				eventRecorder:          eventstesting.NewTestingEventRecorder(t),
+				hubAccessLostAt:        map[string]metav1.Time{},
+			}
 			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
 			if syncErr != nil {
 				t.Errorf("unexpected err: %v", syncErr)
 			}
 
 			c.validateActions(t, clusterClient.Actions())
+			for _, action := range kubeClient.Actions() {
+				if action.GetVerb() == "create" || action.GetVerb() == "update" {
+					t.Errorf("expected the resources are applied with server-side apply, but got %v", action)
+				}
+			}
+		})
+	}
+}
+
+func TestResync(t *testing.T) {
+	clusters := []runtime.Object{
+		testinghelpers.NewAcceptedManagedCluster(),
+		&v1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster2"}},
+	}
+	clusterClient := clusterfake.NewSimpleClientset(clusters...)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+	for _, cluster := range clusters {
+		if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctrl := managedClusterController{
+		kubeClient:    kubefake.NewSimpleClientset(),
+		clusterClient: clusterClient,
+		clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+		eventRecorder: eventstesting.NewTestingEventRecorder(t),
+	}
+	syncCtx := testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey)
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+
+	testinghelpers.AssertNoActions(t, clusterClient.Actions())
+	if syncCtx.Queue().Len() != len(clusters) {
+		t.Errorf("expected %d clusters are requeued, but got %d", len(clusters), syncCtx.Queue().Len())
+	}
+}
+
+func TestPurgeManagedCluster(t *testing.T) {
+	newDeletingCluster := func(mode string, finalizers ...string) *v1.ManagedCluster {
+		cluster := testinghelpers.NewDeletingManagedCluster()
+		if len(mode) != 0 {
+			cluster.Annotations = map[string]string{helpers.ManagedClusterDeletionModeAnnotation: mode}
+		}
+		cluster.Finalizers = append(cluster.Finalizers, finalizers...)
+		return cluster
+	}
+	newDeletingClusterWithProgress := func(progress string) *v1.ManagedCluster {
+		cluster := newDeletingCluster(helpers.ManagedClusterDeletionModePurge)
+		cluster.Annotations[helpers.ManagedClusterContentDeleteProgressAnnotation] = progress
+		return cluster
+	}
+	deletingTime := metav1.Now()
+	works := []runtime.Object{
+		testinghelpers.NewManifestWork(testinghelpers.TestManagedClusterName, "work1", nil, nil),
+		testinghelpers.NewManifestWork(testinghelpers.TestManagedClusterName, "work2", nil, &deletingTime),
+	}
+	addOns := []runtime.Object{
+		&addonv1alpha1.ManagedClusterAddOn{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testinghelpers.TestManagedClusterName, Name: "addon1"},
+		},
+	}
+
+	cases := []struct {
+		name                   string
+		cluster                *v1.ManagedCluster
+		works                  []runtime.Object
+		addOns                 []runtime.Object
+		expectedErr            string
+		expectedWorkActions    []string
+		expectedAddOnActions   []string
+		expectedClusterActions []string
+		expectedCondition      *metav1.Condition
+		expectedProgress       string
+	}{
+		{
+			name:                   "workloads are not purged without the deletion mode",
+			cluster:                newDeletingCluster(""),
+			works:                  works,
+			addOns:                 addOns,
+			expectedClusterActions: []string{"patch"},
+		},
+		{
+			name:                   "workloads are not purged if the cluster is detached",
+			cluster:                newDeletingCluster(helpers.ManagedClusterDeletionModeDetach),
+			works:                  works,
+			addOns:                 addOns,
+			expectedClusterActions: []string{"patch"},
+		},
+		{
+			name:                   "purge the workloads",
+			cluster:                newDeletingCluster(helpers.ManagedClusterDeletionModePurge),
+			works:                  works,
+			addOns:                 addOns,
+			expectedErr:            "still having 2 manifestworks and 1 addons in the cluster namespace testmanagedcluster",
+			expectedWorkActions:    []string{"delete"},
+			expectedAddOnActions:   []string{"delete"},
+			expectedClusterActions: []string{"patch", "get", "patch"},
+			expectedCondition: &metav1.Condition{
+				Status:  metav1.ConditionFalse,
+				Reason:  helpers.ContentDeleteReasonResourceRemaining,
+				Message: "3 of 3 resources remaining: 2 manifestworks, 1 addons",
+			},
+			expectedProgress: `{"total":3,"remaining":3,"manifestWorks":2,"addOns":1}`,
+		},
+		{
+			name:                   "workloads are being purged",
+			cluster:                newDeletingClusterWithProgress(`{"total":5,"remaining":3,"manifestWorks":2,"addOns":1}`),
+			works:                  works[1:],
+			expectedErr:            "still having 1 manifestworks and 0 addons in the cluster namespace testmanagedcluster",
+			expectedClusterActions: []string{"patch", "get", "patch"},
+			expectedCondition: &metav1.Condition{
+				Status:  metav1.ConditionFalse,
+				Reason:  helpers.ContentDeleteReasonResourceRemaining,
+				Message: "1 of 5 resources remaining: 1 manifestworks, 0 addons",
+			},
+			expectedProgress: `{"total":5,"remaining":1,"manifestWorks":1,"addOns":0}`,
+		},
+		{
+			name:                   "progress of the workloads is unchanged",
+			cluster:                newDeletingClusterWithProgress(`{"total":5,"remaining":1,"manifestWorks":1,"addOns":0}`),
+			works:                  works[1:],
+			expectedErr:            "still having 1 manifestworks and 0 addons in the cluster namespace testmanagedcluster",
+			expectedClusterActions: []string{"get", "patch"},
+			expectedProgress:       `{"total":5,"remaining":1,"manifestWorks":1,"addOns":0}`,
+		},
+		{
+			name:                   "progress is started over if it is invalid",
+			cluster:                newDeletingClusterWithProgress("invalid"),
+			works:                  works[1:],
+			expectedErr:            "still having 1 manifestworks and 0 addons in the cluster namespace testmanagedcluster",
+			expectedClusterActions: []string{"patch", "get", "patch"},
+			expectedProgress:       `{"total":1,"remaining":1,"manifestWorks":1,"addOns":0}`,
+		},
+		{
+			name:                   "workloads are purged",
+			cluster:                newDeletingClusterWithProgress(`{"total":5,"remaining":1,"manifestWorks":1,"addOns":0}`),
+			expectedClusterActions: []string{"patch", "get", "patch", "patch"},
+			expectedCondition: &metav1.Condition{
+				Status:  metav1.ConditionTrue,
+				Reason:  helpers.ContentDeleteReasonContentDeleted,
+				Message: "0 of 5 resources remaining",
+			},
+			expectedProgress: `{"total":5,"remaining":0,"manifestWorks":0,"addOns":0}`,
+		},
+		{
+			name:    "wait for the cleanup finalizers",
+			cluster: newDeletingCluster(helpers.ManagedClusterDeletionModePurge, helpers.ManagedClusterCleanupFinalizerPrefix+"backup"),
+			works:   works,
+			addOns:  addOns,
+		},
+		{
+			name:                   "finalizers without the cleanup prefixes are not waited for",
+			cluster:                newDeletingCluster("", "example.com/finalizer"),
+			expectedClusterActions: []string{"patch"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.cluster)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(c.cluster); err != nil {
+				t.Fatal(err)
+			}
+			workClient := workfake.NewSimpleClientset(c.works...)
+			workInformerFactory := workinformers.NewSharedInformerFactory(workClient, time.Minute*10)
+			for _, work := range c.works {
+				if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(work); err != nil {
+					t.Fatal(err)
+				}
+			}
+			addOnClient := addonfake.NewSimpleClientset(c.addOns...)
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, time.Minute*10)
+			for _, addOn := range c.addOns {
+				if err := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore().Add(addOn); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := managedClusterController{
+				kubeClient:     kubefake.NewSimpleClientset(),
+				clusterClient:  clusterClient,
+				clusterLister:  clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				clusterPatcher: helpers.NewClusterPatcher(clusterClient),
+				workClient:     workClient,
+				workLister:     workInformerFactory.Work().V1().ManifestWorks().Lister(),
+				addOnClient:    addOnClient,
+				addOnLister:    addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				eventRecorder:  eventstesting.NewTestingEventRecorder(t),
+
+				cleanupFinalizerPrefixes: []string{helpers.ManagedClusterCleanupFinalizerPrefix},
+			}
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			testinghelpers.AssertError(t, err, c.expectedErr)
+
+			testinghelpers.AssertActions(t, workClient.Actions(), c.expectedWorkActions...)
+			testinghelpers.AssertActions(t, addOnClient.Actions(), c.expectedAddOnActions...)
+			testinghelpers.AssertActions(t, clusterClient.Actions(), c.expectedClusterActions...)
+			cluster, err := clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), testinghelpers.TestManagedClusterName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if progress := cluster.Annotations[helpers.ManagedClusterContentDeleteProgressAnnotation]; progress != c.expectedProgress {
+				t.Errorf("expected progress %s, but got %s", c.expectedProgress, progress)
+			}
+			if c.expectedCondition == nil {
+				return
+			}
+			c.expectedCondition.Type = helpers.ManagedClusterConditionContentDeleteSuccess
+			testinghelpers.AssertCondition(t, cluster.Status.Conditions, *c.expectedCondition)
 		})
 	}
 }
+
+func newAppliedResourceListers() map[schema.GroupKind]cache.GenericLister {
+	listers := map[schema.GroupKind]cache.GenericLister{}
+	for kind, resource := range appliedResources {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		listers[kind] = cache.NewGenericLister(indexer, resource.GroupResource())
+	}
+	return listers
+}
+
+func TestHubAccessLost(t *testing.T) {
+	newCluster := func(status metav1.ConditionStatus, transitionTime time.Time) *v1.ManagedCluster {
+		cluster := testinghelpers.NewAcceptedManagedCluster()
+		cluster.Status.Conditions = append(cluster.Status.Conditions, metav1.Condition{
+			Type:               helpers.ManagedClusterConditionHubAccessReady,
+			Status:             status,
+			Reason:             "Test",
+			LastTransitionTime: metav1.NewTime(transitionTime),
+		})
+		return cluster
+	}
+	lostAt := time.Now().Truncate(time.Second)
+
+	ctrl := &managedClusterController{hubAccessLostAt: map[string]metav1.Time{}}
+	steps := []struct {
+		cluster  *v1.ManagedCluster
+		expected bool
+	}{
+		{cluster: newCluster(metav1.ConditionTrue, lostAt.Add(-time.Minute)), expected: false},
+		{cluster: newCluster(metav1.ConditionFalse, lostAt), expected: true},
+		{cluster: newCluster(metav1.ConditionFalse, lostAt), expected: false},
+		{cluster: newCluster(metav1.ConditionFalse, lostAt.Add(time.Minute)), expected: true},
+	}
+	for i, step := range steps {
+		if actual := ctrl.hubAccessLost(step.cluster); actual != step.expected {
+			t.Errorf("expected the hub access lost %t at step %d, but got %t", step.expected, i, actual)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -11,6 +12,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
@@ -23,8 +25,14 @@ import (
 	clusterlisterv1beta2 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta2"
 	v1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
+// ManagedClusterSetMemberCountAnnotation is the annotation of a ManagedClusterSet which holds the number of
+// the ManagedClusters selected by the clusterset, it is maintained by the hub as the clusters join or leave
+// the clusterset.
+const ManagedClusterSetMemberCountAnnotation = "cluster.open-cluster-management.io/member-count"
+
 // managedClusterSetController reconciles instances of ManagedClusterSet on the hub.
 type managedClusterSetController struct {
 	clusterClient    clientset.Interface
@@ -63,7 +71,7 @@ func NewManagedClusterSetController(
 			c.enqueueClusterClusterSet(cluster)
 		},
 		UpdateFunc: func(oldObj, newObj interface{}) {
-			// only need handle label update
+			// only need handle the label and availability updates
 			oldCluster, ok := oldObj.(*v1.ManagedCluster)
 			if !ok {
 				utilruntime.HandleError(fmt.Errorf("error to get object: %v", oldObj))
@@ -75,6 +83,10 @@ func NewManagedClusterSetController(
 				return
 			}
 			if reflect.DeepEqual(oldCluster.Labels, newCluster.Labels) {
+				// the clustersets count the clusters by their availability
+				if clusterAvailability(oldCluster) != clusterAvailability(newCluster) {
+					c.enqueueClusterClusterSet(newCluster)
+				}
 				return
 			}
 			c.enqueueUpdateClusterClusterSet(oldCluster, newCluster)
@@ -110,7 +122,7 @@ func NewManagedClusterSetController(
 			return accessor.GetName()
 		}, clusterSetInformer.Informer()).
 		WithBareInformers(clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(helpers.RecoverSync("ManagedClusterSetController", c.sync)).
 		ToController("ManagedClusterSetController", recorder)
 }
 
@@ -123,6 +135,7 @@ func (c *managedClusterSetController) sync(ctx context.Context, syncCtx factory.
 	clusterSet, err := c.clusterSetLister.Get(clusterSetName)
 	if errors.IsNotFound(err) {
 		// cluster set not found, could have been deleted, do nothing.
+		deleteClusterSetClustersMetric(clusterSetName)
 		return nil
 	}
 	if err != nil {
@@ -149,6 +162,7 @@ func (c *managedClusterSetController) syncClusterSet(ctx context.Context, origin
 		return err
 	}
 	count := len(clusters)
+	setClusterSetClustersMetric(clusterSet.Name, clusters)
 	// update clusterset status
 	emptyCondition := metav1.Condition{
 		Type: clusterv1beta2.ManagedClusterSetConditionEmpty,
@@ -165,13 +179,22 @@ func (c *managedClusterSetController) syncClusterSet(ctx context.Context, origin
 	meta.SetStatusCondition(&clusterSet.Status.Conditions, emptyCondition)
 
 	// skip update if cluster set status does not change
-	if reflect.DeepEqual(clusterSet.Status.Conditions, originalClusterSet.Status.Conditions) {
-		return nil
+	if !reflect.DeepEqual(clusterSet.Status.Conditions, originalClusterSet.Status.Conditions) {
+		_, err = c.clusterClient.ClusterV1beta2().ManagedClusterSets().UpdateStatus(ctx, clusterSet, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to update status of ManagedClusterSet %q: %w", clusterSet.Name, err)
+		}
 	}
 
-	_, err = c.clusterClient.ClusterV1beta2().ManagedClusterSets().UpdateStatus(ctx, clusterSet, metav1.UpdateOptions{})
+	// the annotation is patched after the status is updated, since the patch changes the resourceVersion
+	memberCount := strconv.Itoa(count)
+	if originalClusterSet.Annotations[ManagedClusterSetMemberCountAnnotation] == memberCount {
+		return nil
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, ManagedClusterSetMemberCountAnnotation, memberCount)
+	_, err = c.clusterClient.ClusterV1beta2().ManagedClusterSets().Patch(ctx, clusterSet.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to update status of ManagedClusterSet %q: %w", clusterSet.Name, err)
+		return fmt.Errorf("failed to patch the member count of ManagedClusterSet %q: %w", clusterSet.Name, err)
 	}
 
 	return nil
@@ -23,6 +23,7 @@ func TestSyncClusterSet(t *testing.T) {
 		existingClusterSet *clusterv1beta2.ManagedClusterSet
 		existingClusters   []*clusterv1.ManagedCluster
 		expectCondition    metav1.Condition
+		expectMemberCount  string
 		expectErr          bool
 	}{
 		{
@@ -43,6 +44,7 @@ func TestSyncClusterSet(t *testing.T) {
 				Reason:  "ClustersSelected",
 				Message: "1 ManagedClusters selected",
 			},
+			expectMemberCount: "1",
 		},
 		{
 			name: "sync a legacy clusterset",
@@ -67,6 +69,7 @@ func TestSyncClusterSet(t *testing.T) {
 				Reason:  "ClustersSelected",
 				Message: "1 ManagedClusters selected",
 			},
+			expectMemberCount: "1",
 		},
 		{
 			name: "sync a legacy clusterset, and no cluster matched",
@@ -91,6 +94,7 @@ func TestSyncClusterSet(t *testing.T) {
 				Reason:  "NoClusterMatched",
 				Message: "No ManagedCluster selected",
 			},
+			expectMemberCount: "0",
 		},
 		{
 			name: "sync a labelselector clusterset",
@@ -125,6 +129,7 @@ func TestSyncClusterSet(t *testing.T) {
 				Reason:  "ClustersSelected",
 				Message: "2 ManagedClusters selected",
 			},
+			expectMemberCount: "2",
 		},
 		{
 			name: "sync a global clusterset",
@@ -155,6 +160,7 @@ func TestSyncClusterSet(t *testing.T) {
 				Reason:  "ClustersSelected",
 				Message: "2 ManagedClusters selected",
 			},
+			expectMemberCount: "2",
 		},
 		{
 			name: "sync a label clusterset with no labelselector specified(no cluster matched)",
@@ -184,6 +190,7 @@ func TestSyncClusterSet(t *testing.T) {
 				Reason:  "NoClusterMatched",
 				Message: "No ManagedCluster selected",
 			},
+			expectMemberCount: "0",
 		},
 		{
 			name: "ignore any other clusterset",
@@ -252,10 +259,46 @@ func TestSyncClusterSet(t *testing.T) {
 			if !hasCondition(updatedSet.Status.Conditions, c.expectCondition) {
 				t.Errorf("expected conditon:%v. is not found: %v", c.expectCondition, updatedSet.Status.Conditions)
 			}
+			if memberCount := updatedSet.Annotations[ManagedClusterSetMemberCountAnnotation]; memberCount != c.expectMemberCount {
+				t.Errorf("expected member count %q, but got %q", c.expectMemberCount, memberCount)
+			}
 		})
 	}
 }
 
+func TestSyncUpToDateClusterSet(t *testing.T) {
+	cluster := newManagedCluster("cluster1", map[string]string{clusterv1beta2.ClusterSetLabel: "mcs1"})
+	clusterSet := &clusterv1beta2.ManagedClusterSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "mcs1",
+			Annotations: map[string]string{ManagedClusterSetMemberCountAnnotation: "1"},
+		},
+		Status: clusterv1beta2.ManagedClusterSetStatus{
+			Conditions: []metav1.Condition{{
+				Type:    clusterv1beta2.ManagedClusterSetConditionEmpty,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ClustersSelected",
+				Message: "1 ManagedClusters selected",
+			}},
+		},
+	}
+	clusterClient := clusterfake.NewSimpleClientset(cluster, clusterSet)
+	informerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, 5*time.Minute)
+	if err := informerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := managedClusterSetController{
+		clusterClient: clusterClient,
+		clusterLister: informerFactory.Cluster().V1().ManagedClusters().Lister(),
+		eventRecorder: eventstesting.NewTestingEventRecorder(t),
+	}
+	if err := ctrl.syncClusterSet(context.Background(), clusterSet); err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+	testinghelpers.AssertNoActions(t, clusterClient.Actions())
+}
+
 func TestGetDiffClustersets(t *testing.T) {
 	cases := []struct {
 		name          string
@@ -17,6 +17,7 @@ import (
 	clusterinformerv1beta2 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta2"
 	clusterlisterv1beta2 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta2"
 	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 const (
@@ -68,7 +69,7 @@ func NewGlobalManagedClusterSetController(
 			},
 			clusterSetInformer.Informer(),
 		).
-		WithSync(c.sync).
+		WithSync(helpers.RecoverSync("GlobalManagedClusterSetController", c.sync)).
 		// use ResyncEvery to make sure:
 		// 1. create the global clusterset once controller is launched
 		// 2. the global clusterset be recreated once it is deleted for some reason
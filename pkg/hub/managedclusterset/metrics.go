@@ -0,0 +1,63 @@
+package managedclusterset
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+const (
+	clusterAvailable   = "available"
+	clusterUnavailable = "unavailable"
+	clusterUnknown     = "unknown"
+)
+
+// clusterAvailabilities are the availabilities of the clusters reported with the clusterSetClusters metric.
+var clusterAvailabilities = []string{clusterAvailable, clusterUnavailable, clusterUnknown}
+
+var clusterSetClusters = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Name: "open_cluster_management_registration_clusterset_clusters",
+		Help: "The number of the managed clusters selected by a clusterset by their availability computed from the " +
+			"Available condition, the sum over the availabilities is the number of the clusters of the clusterset.",
+	},
+	[]string{"clusterset", "availability"},
+)
+
+func init() {
+	legacyregistry.MustRegister(clusterSetClusters)
+}
+
+// setClusterSetClustersMetric sets the number of the clusters of the clusterset by their availability.
+func setClusterSetClustersMetric(clusterSetName string, clusters []*clusterv1.ManagedCluster) {
+	counts := map[string]int{}
+	for _, cluster := range clusters {
+		counts[clusterAvailability(cluster)]++
+	}
+	for _, availability := range clusterAvailabilities {
+		clusterSetClusters.WithLabelValues(clusterSetName, availability).Set(float64(counts[availability]))
+	}
+}
+
+// deleteClusterSetClustersMetric deletes the metric of the clusterset once it is deleted.
+func deleteClusterSetClustersMetric(clusterSetName string) {
+	for _, availability := range clusterAvailabilities {
+		clusterSetClusters.Delete(map[string]string{"clusterset": clusterSetName, "availability": availability})
+	}
+}
+
+func clusterAvailability(cluster *clusterv1.ManagedCluster) string {
+	cond := meta.FindStatusCondition(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable)
+	switch {
+	case cond == nil:
+		return clusterUnknown
+	case cond.Status == metav1.ConditionTrue:
+		return clusterAvailable
+	case cond.Status == metav1.ConditionFalse:
+		return clusterUnavailable
+	default:
+		return clusterUnknown
+	}
+}
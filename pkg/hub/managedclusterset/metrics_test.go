@@ -0,0 +1,67 @@
+package managedclusterset
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/testutil"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestClusterSetClustersMetric(t *testing.T) {
+	noCondition := testinghelpers.NewManagedCluster()
+	available := testinghelpers.NewAvailableManagedCluster()
+	unavailable := testinghelpers.NewUnAvailableManagedCluster()
+	unknown := testinghelpers.NewUnknownManagedCluster()
+
+	cases := []struct {
+		name     string
+		clusters []*clusterv1.ManagedCluster
+		expected map[string]float64
+	}{
+		{
+			name:     "no clusters",
+			expected: map[string]float64{clusterAvailable: 0, clusterUnavailable: 0, clusterUnknown: 0},
+		},
+		{
+			name:     "clusters by availability",
+			clusters: []*clusterv1.ManagedCluster{available, available, unavailable, unknown, noCondition},
+			expected: map[string]float64{clusterAvailable: 2, clusterUnavailable: 1, clusterUnknown: 2},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterSetClusters.Reset()
+			setClusterSetClustersMetric("mcs1", c.clusters)
+			for availability, expected := range c.expected {
+				actual, err := testutil.GetGaugeMetricValue(clusterSetClusters.WithLabelValues("mcs1", availability))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if actual != expected {
+					t.Errorf("expected %v %s clusters, but got %v", expected, availability, actual)
+				}
+			}
+
+			deleteClusterSetClustersMetric("mcs1")
+			for availability := range c.expected {
+				if clusterSetClusters.Delete(map[string]string{"clusterset": "mcs1", "availability": availability}) {
+					t.Errorf("expected the %s clusters of the clusterset are deleted", availability)
+				}
+			}
+		})
+	}
+}
+
+func TestClusterAvailability(t *testing.T) {
+	cluster := testinghelpers.NewManagedCluster()
+	if availability := clusterAvailability(cluster); availability != clusterUnknown {
+		t.Errorf("expected %s, but got %s", clusterUnknown, availability)
+	}
+	cluster.Status.Conditions = []metav1.Condition{{Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionTrue}}
+	if availability := clusterAvailability(cluster); availability != clusterAvailable {
+		t.Errorf("expected %s, but got %s", clusterAvailable, availability)
+	}
+}
@@ -0,0 +1,109 @@
+package managedclusterset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	clustersetv1beta2 "open-cluster-management.io/api/client/cluster/clientset/versioned/typed/cluster/v1beta2"
+	clusterinformerv1beta2 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta2"
+	clusterlisterv1beta2 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta2"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// ManagedClusterSetMigratedAnnotation is the annotation of a ManagedClusterSet which is set once the
+// clusterset is migrated to the current schema, its value is the selector type before the migration.
+const ManagedClusterSetMigratedAnnotation = "cluster.open-cluster-management.io/selector-type-migrated"
+
+// clusterSetMigrationController migrates the ManagedClusterSets created before the selector type was
+// defaulted to ExclusiveClusterSetLabel, e.g. by an old release of the hub with the v1beta1 api:
+// 1. the empty selector type is set to ExclusiveClusterSetLabel;
+// 2. the label selector is removed if the selector type is ExclusiveClusterSetLabel, it is ignored anyway.
+// Each clusterset is migrated once, it is written back in the storage version even if nothing is changed,
+// and it is annotated with ManagedClusterSetMigratedAnnotation, so that the validation of the clustersets
+// can be tightened once all of them are migrated.
+type clusterSetMigrationController struct {
+	clusterSetClient clustersetv1beta2.ClusterV1beta2Interface
+	clusterSetLister clusterlisterv1beta2.ManagedClusterSetLister
+	eventRecorder    events.Recorder
+}
+
+func NewClusterSetMigrationController(
+	clusterSetClient clustersetv1beta2.ClusterV1beta2Interface,
+	clusterSetInformer clusterinformerv1beta2.ManagedClusterSetInformer,
+	recorder events.Recorder) factory.Controller {
+
+	c := &clusterSetMigrationController{
+		clusterSetClient: clusterSetClient,
+		clusterSetLister: clusterSetInformer.Lister(),
+		eventRecorder:    recorder.WithComponentSuffix("managed-cluster-set-migration-controller"),
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return accessor.GetName()
+			},
+			func(obj interface{}) bool {
+				accessor, err := meta.Accessor(obj)
+				if err != nil {
+					return false
+				}
+				// the migrated clustersets are skipped
+				_, migrated := accessor.GetAnnotations()[ManagedClusterSetMigratedAnnotation]
+				return !migrated
+			},
+			clusterSetInformer.Informer(),
+		).
+		WithSync(helpers.RecoverSync("ManagedClusterSetMigrationController", c.sync)).
+		ToController("ManagedClusterSetMigrationController", recorder)
+}
+
+func (c *clusterSetMigrationController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusterSetName := syncCtx.QueueKey()
+	klog.V(4).Infof("Migrating ManagedClusterSet %s", clusterSetName)
+	clusterSet, err := c.clusterSetLister.Get(clusterSetName)
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+	if _, migrated := clusterSet.Annotations[ManagedClusterSetMigratedAnnotation]; migrated {
+		return nil
+	}
+	if !clusterSet.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	clusterSet = clusterSet.DeepCopy()
+	selectorType := clusterSet.Spec.ClusterSelector.SelectorType
+	if len(selectorType) == 0 {
+		clusterSet.Spec.ClusterSelector.SelectorType = clusterv1beta2.ExclusiveClusterSetLabel
+	}
+	if clusterSet.Spec.ClusterSelector.SelectorType == clusterv1beta2.ExclusiveClusterSetLabel {
+		clusterSet.Spec.ClusterSelector.LabelSelector = nil
+	}
+	if clusterSet.Annotations == nil {
+		clusterSet.Annotations = map[string]string{}
+	}
+	clusterSet.Annotations[ManagedClusterSetMigratedAnnotation] = string(selectorType)
+
+	// the update conflicts if the clusterset is changed after it is cached, and it is retried then
+	if _, err := c.clusterSetClient.ManagedClusterSets().Update(ctx, clusterSet, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to migrate ManagedClusterSet %q: %w", clusterSetName, err)
+	}
+	if len(selectorType) == 0 {
+		c.eventRecorder.Eventf("ManagedClusterSetMigrated", "The empty selector type of ManagedClusterSet %s is set to %s",
+			clusterSetName, clusterv1beta2.ExclusiveClusterSetLabel)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package managedclusterset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestMigrateClusterSet(t *testing.T) {
+	newClusterSet := func(selector clusterv1beta2.ManagedClusterSelector, annotations map[string]string) *clusterv1beta2.ManagedClusterSet {
+		return &clusterv1beta2.ManagedClusterSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "mcs1",
+				Annotations: annotations,
+			},
+			Spec: clusterv1beta2.ManagedClusterSetSpec{
+				ClusterSelector: selector,
+			},
+		}
+	}
+	labelSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"vendor": "openShift"}}
+
+	cases := []struct {
+		name                string
+		clusterSet          *clusterv1beta2.ManagedClusterSet
+		expectedSelector    *clusterv1beta2.ManagedClusterSelector
+		expectedAnnotation  string
+		validateNoMigration bool
+	}{
+		{
+			name:                "clusterset is not found",
+			validateNoMigration: true,
+		},
+		{
+			name: "clusterset is migrated",
+			clusterSet: newClusterSet(clusterv1beta2.ManagedClusterSelector{},
+				map[string]string{ManagedClusterSetMigratedAnnotation: ""}),
+			validateNoMigration: true,
+		},
+		{
+			name:       "migrate the empty selector type",
+			clusterSet: newClusterSet(clusterv1beta2.ManagedClusterSelector{LabelSelector: labelSelector}, nil),
+			expectedSelector: &clusterv1beta2.ManagedClusterSelector{
+				SelectorType: clusterv1beta2.ExclusiveClusterSetLabel,
+			},
+		},
+		{
+			name: "migrate the exclusive clusterset label",
+			clusterSet: newClusterSet(clusterv1beta2.ManagedClusterSelector{
+				SelectorType: clusterv1beta2.ExclusiveClusterSetLabel,
+			}, map[string]string{"foo": "bar"}),
+			expectedSelector: &clusterv1beta2.ManagedClusterSelector{
+				SelectorType: clusterv1beta2.ExclusiveClusterSetLabel,
+			},
+			expectedAnnotation: string(clusterv1beta2.ExclusiveClusterSetLabel),
+		},
+		{
+			name: "migrate the label selector",
+			clusterSet: newClusterSet(clusterv1beta2.ManagedClusterSelector{
+				SelectorType:  clusterv1beta2.LabelSelector,
+				LabelSelector: labelSelector,
+			}, nil),
+			expectedSelector: &clusterv1beta2.ManagedClusterSelector{
+				SelectorType:  clusterv1beta2.LabelSelector,
+				LabelSelector: labelSelector,
+			},
+			expectedAnnotation: string(clusterv1beta2.LabelSelector),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if c.clusterSet != nil {
+				objects = append(objects, c.clusterSet)
+			}
+			clusterClient := clusterfake.NewSimpleClientset(objects...)
+			informerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, 5*time.Minute)
+			if c.clusterSet != nil {
+				if err := informerFactory.Cluster().V1beta2().ManagedClusterSets().Informer().GetStore().Add(c.clusterSet); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := clusterSetMigrationController{
+				clusterSetClient: clusterClient.ClusterV1beta2(),
+				clusterSetLister: informerFactory.Cluster().V1beta2().ManagedClusterSets().Lister(),
+				eventRecorder:    eventstesting.NewTestingEventRecorder(t),
+			}
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "mcs1"))
+			testinghelpers.AssertError(t, err, "")
+
+			actions := clusterClient.Actions()
+			if c.validateNoMigration {
+				testinghelpers.AssertNoActions(t, actions)
+				return
+			}
+			testinghelpers.AssertActions(t, actions, "update")
+			clusterSet := actions[0].(clienttesting.UpdateAction).GetObject().(*clusterv1beta2.ManagedClusterSet)
+			if !equality.Semantic.DeepEqual(clusterSet.Spec.ClusterSelector, *c.expectedSelector) {
+				t.Errorf("expected selector %v, but got %v", c.expectedSelector, clusterSet.Spec.ClusterSelector)
+			}
+			annotation, ok := clusterSet.Annotations[ManagedClusterSetMigratedAnnotation]
+			if !ok || annotation != c.expectedAnnotation {
+				t.Errorf("expected migrated annotation %q, but got %v", c.expectedAnnotation, clusterSet.Annotations)
+			}
+			for key, value := range c.clusterSet.Annotations {
+				if clusterSet.Annotations[key] != value {
+					t.Errorf("expected annotation %s=%s is kept, but got %v", key, value, clusterSet.Annotations)
+				}
+			}
+		})
+	}
+}
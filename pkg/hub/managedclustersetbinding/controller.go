@@ -22,6 +22,7 @@ import (
 	clusterinformerv1beta2 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta2"
 	clusterlisterv1beta2 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta2"
 	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	"open-cluster-management.io/registration/pkg/helpers"
 )
 
 const (
@@ -84,7 +85,7 @@ func NewManagedClusterSetBindingController(
 			return key
 		}, clusterSetBindingInformer.Informer()).
 		WithBareInformers(clusterSetInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(helpers.RecoverSync("ManagedClusterSetBindingController", c.sync)).
 		ToController("ManagedClusterSetController", recorder)
 }
 
@@ -0,0 +1,137 @@
+package managedclustersetbinding
+
+import (
+	"context"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterinformerv1beta2 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta2"
+	clusterlisterv1beta2 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta2"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// defaultManagedClusterSetBindingController ensures the ManagedClusterSetBindings of the given clustersets,
+// e.g. the default and global clustersets, exist in the given namespaces, so that the workloads in these
+// namespaces are able to target the clustersets without creating the bindings manually. A binding which
+// exists is never changed, and a deleted one is recreated.
+type defaultManagedClusterSetBindingController struct {
+	clusterClient           clientset.Interface
+	namespaceLister         corelisters.NamespaceLister
+	clusterSetBindingLister clusterlisterv1beta2.ManagedClusterSetBindingLister
+	namespaces              sets.Set[string]
+	clusterSets             []string
+	eventRecorder           events.Recorder
+}
+
+func NewDefaultManagedClusterSetBindingController(
+	clusterClient clientset.Interface,
+	namespaceInformer corev1informers.NamespaceInformer,
+	clusterSetBindingInformer clusterinformerv1beta2.ManagedClusterSetBindingInformer,
+	namespaces []string,
+	clusterSets []string,
+	recorder events.Recorder) factory.Controller {
+
+	c := &defaultManagedClusterSetBindingController{
+		clusterClient:           clusterClient,
+		namespaceLister:         namespaceInformer.Lister(),
+		clusterSetBindingLister: clusterSetBindingInformer.Lister(),
+		namespaces:              sets.New[string](namespaces...),
+		clusterSets:             clusterSets,
+		eventRecorder:           recorder.WithComponentSuffix("default-managed-clusterset-binding-controller"),
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return accessor.GetName()
+			},
+			func(obj interface{}) bool {
+				accessor, err := meta.Accessor(obj)
+				if err != nil {
+					return false
+				}
+				return c.namespaces.Has(accessor.GetName())
+			},
+			namespaceInformer.Informer(),
+		).
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return accessor.GetNamespace()
+			},
+			func(obj interface{}) bool {
+				accessor, err := meta.Accessor(obj)
+				if err != nil {
+					return false
+				}
+				return c.namespaces.Has(accessor.GetNamespace())
+			},
+			clusterSetBindingInformer.Informer(),
+		).
+		WithSync(helpers.RecoverSync("DefaultManagedClusterSetBindingController", c.sync)).
+		ToController("DefaultManagedClusterSetBindingController", recorder)
+}
+
+func (c *defaultManagedClusterSetBindingController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	namespace := syncCtx.QueueKey()
+	if !c.namespaces.Has(namespace) {
+		return nil
+	}
+
+	klog.V(4).Infof("Reconciling the default ManagedClusterSetBindings in namespace %s", namespace)
+	ns, err := c.namespaceLister.Get(namespace)
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+	if !ns.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	errs := []error{}
+	for _, clusterSet := range c.clusterSets {
+		_, err := c.clusterSetBindingLister.ManagedClusterSetBindings(namespace).Get(clusterSet)
+		switch {
+		case err == nil:
+			continue
+		case !errors.IsNotFound(err):
+			errs = append(errs, err)
+			continue
+		}
+
+		// the name of a binding must be the same as the clusterset
+		binding := &clusterv1beta2.ManagedClusterSetBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      clusterSet,
+			},
+			Spec: clusterv1beta2.ManagedClusterSetBindingSpec{
+				ClusterSet: clusterSet,
+			},
+		}
+		_, err = c.clusterClient.ClusterV1beta2().ManagedClusterSetBindings(namespace).Create(ctx, binding, metav1.CreateOptions{})
+		switch {
+		case errors.IsAlreadyExists(err):
+		case err != nil:
+			errs = append(errs, err)
+		default:
+			c.eventRecorder.Eventf("ManagedClusterSetBindingCreated",
+				"The ManagedClusterSetBinding of clusterset %s is created in namespace %s", clusterSet, namespace)
+		}
+	}
+	return operatorhelpers.NewMultiLineAggregate(errs)
+}
@@ -0,0 +1,113 @@
+package managedclustersetbinding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestSyncDefaultClusterSetBindings(t *testing.T) {
+	cases := []struct {
+		name            string
+		key             string
+		namespaces      []runtime.Object
+		bindings        []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "namespace is not designated",
+			key:             "other",
+			namespaces:      []runtime.Object{testinghelpers.NewNamespace("other", false)},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "namespace is not found",
+			key:             "testns",
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:            "namespace is terminating",
+			key:             "testns",
+			namespaces:      []runtime.Object{testinghelpers.NewNamespace("testns", true)},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:       "create the bindings",
+			key:        "testns",
+			namespaces: []runtime.Object{testinghelpers.NewNamespace("testns", false)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create", "create")
+				for i, clusterSet := range []string{"default", "global"} {
+					binding := actions[i].(clienttesting.CreateAction).GetObject().(*clusterv1beta2.ManagedClusterSetBinding)
+					if binding.Namespace != "testns" || binding.Name != clusterSet || binding.Spec.ClusterSet != clusterSet {
+						t.Errorf("expected the binding of clusterset %s in testns, but got %#v", clusterSet, binding)
+					}
+				}
+			},
+		},
+		{
+			name:       "create the deleted binding",
+			key:        "testns",
+			namespaces: []runtime.Object{testinghelpers.NewNamespace("testns", false)},
+			bindings:   []runtime.Object{newManagedClusterSetBinding("default", "testns")},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "create")
+				binding := actions[0].(clienttesting.CreateAction).GetObject().(*clusterv1beta2.ManagedClusterSetBinding)
+				if binding.Name != "global" {
+					t.Errorf("expected the binding of clusterset global, but got %#v", binding)
+				}
+			},
+		},
+		{
+			name:       "bindings exist",
+			key:        "testns",
+			namespaces: []runtime.Object{testinghelpers.NewNamespace("testns", false)},
+			bindings: []runtime.Object{
+				newManagedClusterSetBinding("default", "testns"),
+				newManagedClusterSetBinding("global", "testns"),
+			},
+			validateActions: testinghelpers.AssertNoActions,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), time.Minute*10)
+			for _, ns := range c.namespaces {
+				if err := kubeInformerFactory.Core().V1().Namespaces().Informer().GetStore().Add(ns); err != nil {
+					t.Fatal(err)
+				}
+			}
+			clusterClient := clusterfake.NewSimpleClientset(c.bindings...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			for _, binding := range c.bindings {
+				if err := clusterInformerFactory.Cluster().V1beta2().ManagedClusterSetBindings().Informer().GetStore().Add(binding); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := NewDefaultManagedClusterSetBindingController(
+				clusterClient,
+				kubeInformerFactory.Core().V1().Namespaces(),
+				clusterInformerFactory.Cluster().V1beta2().ManagedClusterSetBindings(),
+				[]string{"testns"},
+				[]string{"default", "global"},
+				eventstesting.NewTestingEventRecorder(t),
+			)
+			err := ctrl.Sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, c.key))
+			testinghelpers.AssertError(t, err, "")
+
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
@@ -2,10 +2,18 @@ package hub
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	certv1 "k8s.io/api/certificates/v1"
 	certv1beta1 "k8s.io/api/certificates/v1beta1"
-	"time"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/sets"
 
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	ocmfeature "open-cluster-management.io/api/feature"
 
 	"open-cluster-management.io/registration/pkg/features"
@@ -20,12 +28,21 @@ import (
 	workv1client "open-cluster-management.io/api/client/work/clientset/versioned"
 	workv1informers "open-cluster-management.io/api/client/work/informers/externalversions"
 	"open-cluster-management.io/registration/pkg/hub/addon"
+	"open-cluster-management.io/registration/pkg/hub/admissionpolicy"
+	"open-cluster-management.io/registration/pkg/hub/agentversion"
+	"open-cluster-management.io/registration/pkg/hub/awsauth"
+	"open-cluster-management.io/registration/pkg/hub/clientconfig"
+	"open-cluster-management.io/registration/pkg/hub/clusterclaim"
+	"open-cluster-management.io/registration/pkg/hub/clusteridentity"
+	"open-cluster-management.io/registration/pkg/hub/clusternamespace"
 	"open-cluster-management.io/registration/pkg/hub/clusterrole"
 	"open-cluster-management.io/registration/pkg/hub/csr"
 	"open-cluster-management.io/registration/pkg/hub/lease"
 	"open-cluster-management.io/registration/pkg/hub/managedcluster"
 	"open-cluster-management.io/registration/pkg/hub/managedclusterset"
+	"open-cluster-management.io/registration/pkg/hub/migration"
 	"open-cluster-management.io/registration/pkg/hub/rbacfinalizerdeletion"
+	"open-cluster-management.io/registration/pkg/version"
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -34,20 +51,99 @@ import (
 
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
 )
 
+// ResyncInterval is the default resync period of the lease controller, it is exposed so that integration
+// tests can crank up the controller sync speed.
 var ResyncInterval = 5 * time.Minute
 
+// The names of the hub controllers which can be disabled with the --disabled-controllers flag.
+const (
+	ManagedClusterControllerName        = "managedcluster"
+	CSRControllerName                   = "csr"
+	LeaseControllerName                 = "lease"
+	TaintControllerName                 = "taint"
+	AddOnFeatureDiscoveryControllerName = "addon-discovery"
+	ClusterSetControllerName            = "clusterset"
+	AgentVersionControllerName          = "agent-version"
+	MaintenanceControllerName           = "maintenance"
+	HubMigrationControllerName          = "hub-migration"
+	ClientConfigDriftControllerName     = "client-config-drift"
+	ClusterIdentityControllerName       = "cluster-identity"
+)
+
+var disableableControllers = sets.New[string](
+	ManagedClusterControllerName,
+	CSRControllerName,
+	LeaseControllerName,
+	TaintControllerName,
+	AddOnFeatureDiscoveryControllerName,
+	ClusterSetControllerName,
+	AgentVersionControllerName,
+	MaintenanceControllerName,
+	HubMigrationControllerName,
+	ClientConfigDriftControllerName,
+	ClusterIdentityControllerName,
+)
+
 // HubManagerOptions holds configuration for hub manager controller
 type HubManagerOptions struct {
-	ClusterAutoApprovalUsers []string
+	ClusterAutoApprovalUsers           []string
+	EnableAWSIAMIdentityMapping        bool
+	CSRApprovingWorkers                int
+	ManagedClusterWorkers              int
+	RBACFinalizerWorkers               int
+	AutoApproveBootstrapUsers          []string
+	CSRDenyThreshold                   time.Duration
+	CSRSigningBacklogThreshold         int
+	DisabledControllers                []string
+	KubeAPIQPS                         float32
+	KubeAPIBurst                       int
+	InformerResyncPeriod               time.Duration
+	LeaseControllerResyncPeriod        time.Duration
+	LeaseDurationTimes                 int
+	FeatureGatesFile                   string
+	MaxAgentVersionSkew                int
+	ClusterClaimLabels                 []string
+	DefaultClusterSetBindingNamespaces []string
+	EnableValidatingAdmissionPolicies  bool
+	EnableAddOnStatusesAnnotation      bool
+	HealthProbeBindAddress             string
+	ControllerPanicPolicy              string
+	OrphanedClusterNamespaceGC         string
+	OrphanedClusterNamespaceGCDelay    time.Duration
+	CleanupFinalizerPrefixes           []string
+	ManagedClusterManifestValuesFile   string
+
+	health *healthChecker
 }
 
 // NewHubManagerOptions returns a HubManagerOptions
 func NewHubManagerOptions() *HubManagerOptions {
-	return &HubManagerOptions{}
+	return &HubManagerOptions{
+		CSRApprovingWorkers:             5,
+		ManagedClusterWorkers:           1,
+		RBACFinalizerWorkers:            1,
+		CSRDenyThreshold:                10 * time.Minute,
+		CSRSigningBacklogThreshold:      20,
+		KubeAPIQPS:                      100.0,
+		KubeAPIBurst:                    200,
+		InformerResyncPeriod:            10 * time.Minute,
+		LeaseControllerResyncPeriod:     ResyncInterval,
+		LeaseDurationTimes:              lease.DefaultLeaseDurationTimes,
+		MaxAgentVersionSkew:             2,
+		HealthProbeBindAddress:          ":8000",
+		ControllerPanicPolicy:           helpers.PanicPolicyCrash,
+		OrphanedClusterNamespaceGC:      clusternamespace.GCModeDisabled,
+		OrphanedClusterNamespaceGCDelay: 24 * time.Hour,
+		CleanupFinalizerPrefixes:        []string{helpers.ManagedClusterCleanupFinalizerPrefix},
+		health:                          &healthChecker{},
+	}
 }
 
 // AddFlags registers flags for manager
@@ -55,18 +151,150 @@ func (m *HubManagerOptions) AddFlags(fs *pflag.FlagSet) {
 	features.DefaultHubMutableFeatureGate.AddFlag(fs)
 	fs.StringSliceVar(&m.ClusterAutoApprovalUsers, "cluster-auto-approval-users", m.ClusterAutoApprovalUsers,
 		"A bootstrap user list whose cluster registration requests can be automatically approved.")
+	fs.StringSliceVar(&m.AutoApproveBootstrapUsers, "auto-approve-bootstrap-users", m.AutoApproveBootstrapUsers,
+		"A list of users, service accounts (in the form of 'system:serviceaccount:<namespace>:<name>') and groups. The managed cluster "+
+			"registered by one of them is accepted and its bootstrap csr is approved automatically. It takes effect without the ManagedClusterAutoApproval feature gate.")
+	fs.IntVar(&m.CSRApprovingWorkers, "csr-approving-workers", m.CSRApprovingWorkers,
+		"The number of CertificateSigningRequests approved concurrently. Increase it to keep the approval latency bounded when a large number of clusters register at once.")
+	fs.IntVar(&m.ManagedClusterWorkers, "managed-cluster-workers", m.ManagedClusterWorkers,
+		"The number of managed clusters reconciled concurrently by the managedcluster controller, e.g. their rbac resources are applied once they are accepted, "+
+			"or removed once they are denied or detached. A managed cluster is never reconciled by more than one worker at a time.")
+	fs.IntVar(&m.RBACFinalizerWorkers, "rbac-finalizer-workers", m.RBACFinalizerWorkers,
+		"The number of roles and rolebindings in the namespaces of the deleted managed clusters whose finalizers are removed concurrently once the manifestworks are cleaned up. "+
+			"Increase it to speed up detaching a large number of managed clusters at once.")
+	fs.DurationVar(&m.CSRDenyThreshold, "csr-deny-threshold", m.CSRDenyThreshold,
+		"The period after which the csrs of a managed cluster which does not exist, or is denied by the hub cluster admin, are denied. Set it to zero to disable denying csrs.")
+	fs.IntVar(&m.CSRSigningBacklogThreshold, "csr-signing-backlog-threshold", m.CSRSigningBacklogThreshold,
+		"The number of the approved csrs of the managed clusters which are not signed yet, above which a warning is logged and a CSRSigningBacklogExceeded event is emitted, "+
+			"e.g. once the signer of kube-controller-manager is not configured. Set it to zero to disable the warning, the backlog is still exposed with the metrics.")
+	fs.BoolVar(&m.EnableAWSIAMIdentityMapping, "enable-aws-iam-identity-mapping", m.EnableAWSIAMIdentityMapping,
+		"Map the AWS IAM roles of the accepted managed clusters to their identities in the aws-auth configmap of the EKS hub. It is required by the agents registered with the 'awsirsa' registration driver.")
+	fs.Float32Var(&m.KubeAPIQPS, "kube-api-qps", m.KubeAPIQPS,
+		"The QPS to use while talking with the kube-apiserver, it is used only if the qps is not set in the kubeconfig.")
+	fs.IntVar(&m.KubeAPIBurst, "kube-api-burst", m.KubeAPIBurst,
+		"The burst to use while talking with the kube-apiserver, it is used only if the qps is not set in the kubeconfig.")
+	fs.DurationVar(&m.InformerResyncPeriod, "informer-resync-period", m.InformerResyncPeriod,
+		"The resync period of the informers of the hub controllers. Lengthen it to reduce the load of a hub with a large number of managed clusters.")
+	fs.DurationVar(&m.LeaseControllerResyncPeriod, "lease-controller-resync-period", m.LeaseControllerResyncPeriod,
+		"The period the leases of all managed clusters are checked by the lease controller, besides checking the lease of each managed cluster once its grace period passes.")
+	fs.IntVar(&m.LeaseDurationTimes, "lease-duration-times", m.LeaseDurationTimes,
+		"The times of the lease duration of a managed cluster used as its grace period, a managed cluster whose lease is not renewed within the grace period becomes unknown. Raise it to tolerate the agents on unstable networks.")
+	fs.StringVar(&m.FeatureGatesFile, "feature-gates-file", m.FeatureGatesFile,
+		"The path of the file, e.g. a key of a mounted ConfigMap, containing a list of 'Feature=true|false' pairs. The feature gates are reloaded once the file changes and the controllers are restarted without restarting the controller manager.")
+	fs.StringSliceVar(&m.DisabledControllers, "disabled-controllers", m.DisabledControllers,
+		fmt.Sprintf("A list of controllers which are not started, so that they can be replaced by alternative implementations, e.g. an external csr approver. "+
+			"The supported controllers are %s.", strings.Join(sets.List(disableableControllers), ", ")))
+	fs.IntVar(&m.MaxAgentVersionSkew, "max-agent-version-skew", m.MaxAgentVersionSkew,
+		"The max number of the minor releases the registration agents can be behind the hub. The AgentVersionCompatible condition of a managed cluster is false if its agent is older.")
+	fs.StringSliceVar(&m.ClusterClaimLabels, "cluster-claim-labels", m.ClusterClaimLabels,
+		"A list of cluster claims, e.g. platform.open-cluster-management.io, which are copied into the labels of the managed clusters, so that they can be used by the label selectors of Placements. "+
+			"A label which is set with a different value by others is not overwritten.")
+	fs.StringSliceVar(&m.DefaultClusterSetBindingNamespaces, "default-clusterset-binding-namespaces", m.DefaultClusterSetBindingNamespaces,
+		"A list of namespaces in which the ManagedClusterSetBindings of the default and global clustersets are created, so that the workloads in them, e.g. Placements, "+
+			"are able to target the clustersets without binding them manually. A deleted binding is recreated. It requires the DefaultClusterSet feature gate.")
+	fs.BoolVar(&m.EnableValidatingAdmissionPolicies, "enable-validating-admission-policies", m.EnableValidatingAdmissionPolicies,
+		"Enforce the structural rules, i.e. the https urls of the client configs, the immutable timeAdded of the taints and the clusterset label key reserved for the exclusive clustersets, with ValidatingAdmissionPolicies, which are removed once it is disabled. "+
+			"It requires Kubernetes 1.26+ with the ValidatingAdmissionPolicy feature and the admissionregistration.k8s.io/v1alpha1 api enabled. The checks relying on SubjectAccessReviews are still done by the webhook.")
+	fs.BoolVar(&m.EnableAddOnStatusesAnnotation, "enable-addon-statuses-annotation", m.EnableAddOnStatusesAnnotation,
+		"Maintain the "+helpers.ManagedClusterAddOnStatusesAnnotation+" annotation of the managed clusters with the versions and the health of their addons in json, "+
+			"so that they can be read with one request, e.g. by dashboards. It is applied with server-side apply by the addon-discovery controller.")
+	fs.StringVar(&m.HealthProbeBindAddress, "health-probe-bind-address", m.HealthProbeBindAddress,
+		"The address the /healthz and /readyz endpoints of the health probes bind to over plain http, e.g. '[::]:8000' for an IPv6-only host, set it to \"0\" to disable the health probes. "+
+			"The /healthz fails once the leader election lease is not renewed by the leader, e.g. it is deadlocked, and the /readyz fails until the informer caches of the leader are synced "+
+			"or once the kube-apiserver is not reachable.")
+	fs.StringVar(&m.ControllerPanicPolicy, "controller-panic-policy", m.ControllerPanicPolicy,
+		fmt.Sprintf("The policy once the sync of a controller panics, either '%s' to fail fast and restart the controller manager, or '%s' to fail the sync, which is retried with backoff, "+
+			"and keep the other controllers running. The panics are counted by the open_cluster_management_registration_controller_panics_total metric and recorded as ControllerPanic events.",
+			helpers.PanicPolicyCrash, helpers.PanicPolicyRecover))
+	fs.StringVar(&m.OrphanedClusterNamespaceGC, "orphaned-cluster-namespace-gc", m.OrphanedClusterNamespaceGC,
+		fmt.Sprintf("The garbage collection of the cluster namespaces, labeled with %s by the hub, whose managed clusters no longer exist, e.g. after a failed detach. "+
+			"Either '%s' to keep them, '%s' to report them with OrphanedClusterNamespace events, or '%s' to delete them once they are orphaned for --orphaned-cluster-namespace-gc-delay.",
+			clusterv1.ClusterNameLabelKey, clusternamespace.GCModeDisabled, clusternamespace.GCModeDryRun, clusternamespace.GCModeDelete))
+	fs.DurationVar(&m.OrphanedClusterNamespaceGCDelay, "orphaned-cluster-namespace-gc-delay", m.OrphanedClusterNamespaceGCDelay,
+		"The period a cluster namespace is orphaned before it is deleted or reported, so that a managed cluster deleted and registered again in the meantime keeps its namespace. "+
+			"The period starts over once the controller manager is restarted.")
+	fs.StringSliceVar(&m.CleanupFinalizerPrefixes, "cleanup-finalizer-prefixes", m.CleanupFinalizerPrefixes,
+		"A list of the prefixes of the finalizers of a ManagedCluster added by the third-party controllers which clean up once the managed cluster is deleted. "+
+			"The workloads and the registration resources of a deleting managed cluster are not cleaned up by the hub until all the finalizers with the prefixes are removed, "+
+			"so that the controllers are able to reach the managed cluster during their cleanup.")
+	fs.StringVar(&m.ManagedClusterManifestValuesFile, "managed-cluster-manifest-values-file", m.ManagedClusterManifestValuesFile,
+		"The path of a yaml file with the custom values which the manifests applied for the accepted managed clusters refer to as .Values. "+
+			"It is read once the controller manager starts.")
 
 }
 
 // RunControllerManager starts the controllers on hub to manage spoke cluster registration.
 func (m *HubManagerOptions) RunControllerManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
+	m.health.startLeading(controllerContext.OperatorNamespace)
+
+	run := func(ctx context.Context) error {
+		return m.runControllerManager(ctx, controllerContext)
+	}
+	if len(m.FeatureGatesFile) == 0 {
+		return run(ctx)
+	}
+
+	// restart the controllers in-process once the feature gates in the file change
+	return features.NewFeatureGateReloader(
+		m.FeatureGatesFile, features.DefaultHubMutableFeatureGate, controllerContext.EventRecorder).Run(ctx, run)
+}
+
+func (m *HubManagerOptions) runControllerManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
+	for _, workers := range []struct {
+		flag  string
+		value int
+	}{
+		{"csr-approving-workers", m.CSRApprovingWorkers},
+		{"managed-cluster-workers", m.ManagedClusterWorkers},
+		{"rbac-finalizer-workers", m.RBACFinalizerWorkers},
+		{"lease-duration-times", m.LeaseDurationTimes},
+	} {
+		if workers.value < 1 {
+			return fmt.Errorf("%s must be greater than zero", workers.flag)
+		}
+	}
+	for _, name := range m.DisabledControllers {
+		if !disableableControllers.Has(name) {
+			return fmt.Errorf("unsupported controller %q in disabled-controllers", name)
+		}
+	}
+	if err := helpers.SetControllerPanicPolicy(m.ControllerPanicPolicy); err != nil {
+		return err
+	}
+	switch m.OrphanedClusterNamespaceGC {
+	case clusternamespace.GCModeDisabled, clusternamespace.GCModeDryRun, clusternamespace.GCModeDelete:
+	default:
+		return fmt.Errorf("unsupported orphaned-cluster-namespace-gc mode %q", m.OrphanedClusterNamespaceGC)
+	}
+	if m.OrphanedClusterNamespaceGCDelay < 0 {
+		return fmt.Errorf("orphaned-cluster-namespace-gc-delay must not be negative")
+	}
+	for _, prefix := range m.CleanupFinalizerPrefixes {
+		if len(prefix) == 0 {
+			return fmt.Errorf("cleanup-finalizer-prefixes must not contain an empty prefix")
+		}
+	}
+	manifestValues := map[string]interface{}{}
+	if len(m.ManagedClusterManifestValuesFile) != 0 {
+		data, err := os.ReadFile(m.ManagedClusterManifestValuesFile)
+		if err != nil {
+			return fmt.Errorf("unable to read the managed-cluster-manifest-values-file %q: %w", m.ManagedClusterManifestValuesFile, err)
+		}
+		if err := yaml.Unmarshal(data, &manifestValues); err != nil {
+			return fmt.Errorf("unable to decode the managed-cluster-manifest-values-file %q: %w", m.ManagedClusterManifestValuesFile, err)
+		}
+	}
+	disabledControllers := sets.New[string](m.DisabledControllers...)
+	if disabledControllers.Len() != 0 {
+		klog.Infof("Disabled controllers: %s", strings.Join(sets.List(disabledControllers), ", "))
+	}
+
 	// If qps in kubconfig is not set, increase the qps and burst to enhance the ability of kube client to handle
 	// requests in concurrent
-	// TODO: Use ClientConnectionOverrides flags to change qps/burst when library-go exposes them in the future
 	kubeConfig := rest.CopyConfig(controllerContext.KubeConfig)
 	if kubeConfig.QPS == 0.0 {
-		kubeConfig.QPS = 100.0
-		kubeConfig.Burst = 200
+		kubeConfig.QPS = m.KubeAPIQPS
+		kubeConfig.Burst = m.KubeAPIBurst
 	}
 
 	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
@@ -89,70 +317,141 @@ func (m *HubManagerOptions) RunControllerManager(ctx context.Context, controller
 		return err
 	}
 
-	clusterInformers := clusterv1informers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
-	workInformers := workv1informers.NewSharedInformerFactory(workClient, 10*time.Minute)
-	kubeInfomers := kubeinformers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
-	addOnInformers := addoninformers.NewSharedInformerFactory(addOnClient, 10*time.Minute)
+	metadataClient, err := metadata.NewForConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
 
-	managedClusterController := managedcluster.NewManagedClusterController(
-		kubeClient,
-		clusterClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		controllerContext.EventRecorder,
-	)
+	clusterInformers := clusterv1informers.NewSharedInformerFactory(clusterClient, m.InformerResyncPeriod)
+	workInformers := workv1informers.NewSharedInformerFactory(workClient, m.InformerResyncPeriod)
+	kubeInfomers := kubeinformers.NewSharedInformerFactory(kubeClient, m.InformerResyncPeriod)
+	addOnInformers := addoninformers.NewSharedInformerFactory(addOnClient, m.InformerResyncPeriod)
+	// only the csrs of managed clusters, which have the cluster name label, are cached
+	csrInformers := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, m.InformerResyncPeriod,
+		kubeinformers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+			listOptions.LabelSelector = clusterv1.ClusterNameLabelKey
+		}))
+	// only the leases updated by the registration agents are cached
+	leaseInformers := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, m.InformerResyncPeriod,
+		kubeinformers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+			listOptions.FieldSelector = fields.OneTermEqualSelector("metadata.name", helpers.ManagedClusterLeaseName).String()
+		}))
+
+	// only the metadata of the resources applied for the managed clusters, which have the cluster name label, are cached
+	appliedResourceInformers := metadatainformer.NewFilteredSharedInformerFactory(metadataClient, m.InformerResyncPeriod, metav1.NamespaceAll,
+		func(listOptions *metav1.ListOptions) {
+			listOptions.LabelSelector = clusterv1.ClusterNameLabelKey
+		})
+
+	var managedClusterController factory.Controller
+	if !disabledControllers.Has(ManagedClusterControllerName) {
+		managedClusterController = managedcluster.NewManagedClusterController(
+			kubeClient,
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			appliedResourceInformers,
+			workClient,
+			workInformers.Work().V1().ManifestWorks().Lister(),
+			addOnClient,
+			addOnInformers.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+			m.CleanupFinalizerPrefixes,
+			controllerContext.OperatorNamespace,
+			manifestValues,
+			controllerContext.EventRecorder,
+		)
+	}
 
-	taintController := taint.NewTaintController(
-		clusterClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		controllerContext.EventRecorder,
-	)
+	var taintController factory.Controller
+	if !disabledControllers.Has(TaintControllerName) {
+		taintController = taint.NewTaintController(
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			controllerContext.EventRecorder,
+		)
+	}
 
-	csrReconciles := []csr.Reconciler{csr.NewCSRRenewalReconciler(kubeClient, controllerContext.EventRecorder)}
-	if features.DefaultHubMutableFeatureGate.Enabled(ocmfeature.ManagedClusterAutoApproval) {
-		csrReconciles = append(csrReconciles, csr.NewCSRBootstrapReconciler(
-			kubeClient,
+	var maintenanceController factory.Controller
+	if !disabledControllers.Has(MaintenanceControllerName) {
+		maintenanceController = taint.NewMaintenanceController(
 			clusterClient,
-			clusterInformers.Cluster().V1().ManagedClusters().Lister(),
-			m.ClusterAutoApprovalUsers,
+			clusterInformers.Cluster().V1().ManagedClusters(),
 			controllerContext.EventRecorder,
-		))
+		)
 	}
 
-	var csrController factory.Controller
-	if features.DefaultHubMutableFeatureGate.Enabled(ocmfeature.V1beta1CSRAPICompatibility) {
-		v1CSRSupported, v1beta1CSRSupported, err := helpers.IsCSRSupported(kubeClient)
-		if err != nil {
-			return errors.Wrapf(err, "failed CSR api discovery")
-		}
+	var hubMigrationController factory.Controller
+	if !disabledControllers.Has(HubMigrationControllerName) {
+		hubMigrationController = migration.NewHubMigrationController(
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			controllerContext.EventRecorder,
+		)
+	}
 
-		if !v1CSRSupported && v1beta1CSRSupported {
-			csrController = csr.NewCSRApprovingController[*certv1beta1.CertificateSigningRequest](
-				kubeInfomers.Certificates().V1beta1().CertificateSigningRequests().Informer(),
-				kubeInfomers.Certificates().V1beta1().CertificateSigningRequests().Lister(),
-				csr.NewCSRV1beta1Approver(kubeClient),
-				csrReconciles,
-				controllerContext.EventRecorder,
-			)
-			klog.Info("Using v1beta1 CSR api to manage spoke client certificate")
-		}
+	var clusterClaimLabelController factory.Controller
+	if len(m.ClusterClaimLabels) != 0 {
+		clusterClaimLabelController = clusterclaim.NewClusterClaimLabelController(
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			m.ClusterClaimLabels,
+			controllerContext.EventRecorder,
+		)
 	}
-	if csrController == nil {
-		csrController = csr.NewCSRApprovingController[*certv1.CertificateSigningRequest](
-			kubeInfomers.Certificates().V1().CertificateSigningRequests().Informer(),
-			kubeInfomers.Certificates().V1().CertificateSigningRequests().Lister(),
-			csr.NewCSRV1Approver(kubeClient),
-			csrReconciles,
+
+	var admissionPolicyController factory.Controller
+	if m.EnableValidatingAdmissionPolicies {
+		admissionPolicyController = admissionpolicy.NewAdmissionPolicyController(
+			kubeClient,
 			controllerContext.EventRecorder,
 		)
 	}
 
-	leaseController := lease.NewClusterLeaseController(
-		kubeClient,
-		clusterClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		kubeInfomers.Coordination().V1().Leases(),
-		controllerContext.EventRecorder,
-	)
+	var agentVersionController factory.Controller
+	if !disabledControllers.Has(AgentVersionControllerName) {
+		agentVersionController = agentversion.NewAgentVersionController(
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			version.Get().GitVersion,
+			m.MaxAgentVersionSkew,
+			controllerContext.EventRecorder,
+		)
+	}
+
+	var clientConfigDriftController factory.Controller
+	if !disabledControllers.Has(ClientConfigDriftControllerName) {
+		clientConfigDriftController = clientconfig.NewClientConfigDriftController(
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			controllerContext.EventRecorder,
+		)
+	}
+
+	var clusterIdentityController factory.Controller
+	if !disabledControllers.Has(ClusterIdentityControllerName) {
+		clusterIdentityController = clusteridentity.NewClusterIdentityController(
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			controllerContext.EventRecorder,
+		)
+	}
+
+	csrController, csrSigningBacklogController, err := m.newCSRController(kubeClient, clusterClient, clusterInformers, csrInformers, addOnInformers, disabledControllers, controllerContext)
+	if err != nil {
+		return err
+	}
+
+	var leaseController factory.Controller
+	if !disabledControllers.Has(LeaseControllerName) {
+		leaseController = lease.NewClusterLeaseController(
+			kubeClient,
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			leaseInformers.Coordination().V1().Leases(),
+			m.LeaseControllerResyncPeriod,
+			int32(m.LeaseDurationTimes),
+			controllerContext.EventRecorder,
+		)
+	}
 
 	rbacFinalizerController := rbacfinalizerdeletion.NewFinalizeController(
 		kubeInfomers.Rbac().V1().Roles(),
@@ -164,19 +463,28 @@ func (m *HubManagerOptions) RunControllerManager(ctx context.Context, controller
 		controllerContext.EventRecorder,
 	)
 
-	managedClusterSetController := managedclusterset.NewManagedClusterSetController(
-		clusterClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		clusterInformers.Cluster().V1beta2().ManagedClusterSets(),
-		controllerContext.EventRecorder,
-	)
+	var managedClusterSetController, managedClusterSetBindingController, clusterSetMigrationController factory.Controller
+	if !disabledControllers.Has(ClusterSetControllerName) {
+		managedClusterSetController = managedclusterset.NewManagedClusterSetController(
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			clusterInformers.Cluster().V1beta2().ManagedClusterSets(),
+			controllerContext.EventRecorder,
+		)
 
-	managedClusterSetBindingController := managedclustersetbinding.NewManagedClusterSetBindingController(
-		clusterClient,
-		clusterInformers.Cluster().V1beta2().ManagedClusterSets(),
-		clusterInformers.Cluster().V1beta2().ManagedClusterSetBindings(),
-		controllerContext.EventRecorder,
-	)
+		managedClusterSetBindingController = managedclustersetbinding.NewManagedClusterSetBindingController(
+			clusterClient,
+			clusterInformers.Cluster().V1beta2().ManagedClusterSets(),
+			clusterInformers.Cluster().V1beta2().ManagedClusterSetBindings(),
+			controllerContext.EventRecorder,
+		)
+
+		clusterSetMigrationController = managedclusterset.NewClusterSetMigrationController(
+			clusterClient.ClusterV1beta2(),
+			clusterInformers.Cluster().V1beta2().ManagedClusterSets(),
+			controllerContext.EventRecorder,
+		)
+	}
 
 	clusterroleController := clusterrole.NewManagedClusterClusterroleController(
 		kubeClient,
@@ -192,15 +500,19 @@ func (m *HubManagerOptions) RunControllerManager(ctx context.Context, controller
 		controllerContext.EventRecorder,
 	)
 
-	addOnFeatureDiscoveryController := addon.NewAddOnFeatureDiscoveryController(
-		clusterClient,
-		clusterInformers.Cluster().V1().ManagedClusters(),
-		addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
-		controllerContext.EventRecorder,
-	)
+	var addOnFeatureDiscoveryController factory.Controller
+	if !disabledControllers.Has(AddOnFeatureDiscoveryControllerName) {
+		addOnFeatureDiscoveryController = addon.NewAddOnFeatureDiscoveryController(
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+			m.EnableAddOnStatusesAnnotation,
+			controllerContext.EventRecorder,
+		)
+	}
 
-	var defaultManagedClusterSetController, globalManagedClusterSetController factory.Controller
-	if features.DefaultHubMutableFeatureGate.Enabled(ocmfeature.DefaultClusterSet) {
+	var defaultManagedClusterSetController, globalManagedClusterSetController, defaultManagedClusterSetBindingController factory.Controller
+	if features.DefaultHubMutableFeatureGate.Enabled(ocmfeature.DefaultClusterSet) && !disabledControllers.Has(ClusterSetControllerName) {
 		defaultManagedClusterSetController = managedclusterset.NewDefaultManagedClusterSetController(
 			clusterClient.ClusterV1beta2(),
 			clusterInformers.Cluster().V1beta2().ManagedClusterSets(),
@@ -211,28 +523,198 @@ func (m *HubManagerOptions) RunControllerManager(ctx context.Context, controller
 			clusterInformers.Cluster().V1beta2().ManagedClusterSets(),
 			controllerContext.EventRecorder,
 		)
+		if len(m.DefaultClusterSetBindingNamespaces) != 0 {
+			defaultManagedClusterSetBindingController = managedclustersetbinding.NewDefaultManagedClusterSetBindingController(
+				clusterClient,
+				kubeInfomers.Core().V1().Namespaces(),
+				clusterInformers.Cluster().V1beta2().ManagedClusterSetBindings(),
+				m.DefaultClusterSetBindingNamespaces,
+				[]string{managedclusterset.DefaultManagedClusterSetName, managedclusterset.GlobalManagedClusterSetName},
+				controllerContext.EventRecorder,
+			)
+		}
+	}
+
+	var orphanedNamespaceController factory.Controller
+	if m.OrphanedClusterNamespaceGC != clusternamespace.GCModeDisabled {
+		orphanedNamespaceController = clusternamespace.NewOrphanedNamespaceController(
+			kubeClient,
+			clusterClient,
+			kubeInfomers.Core().V1().Namespaces(),
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			m.OrphanedClusterNamespaceGCDelay,
+			m.OrphanedClusterNamespaceGC == clusternamespace.GCModeDryRun,
+			controllerContext.EventRecorder,
+		)
+	}
+
+	var awsAuthController factory.Controller
+	if m.EnableAWSIAMIdentityMapping {
+		awsAuthController = awsauth.NewAWSAuthController(
+			kubeClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			controllerContext.EventRecorder,
+		)
 	}
 
 	go clusterInformers.Start(ctx.Done())
 	go workInformers.Start(ctx.Done())
 	go kubeInfomers.Start(ctx.Done())
 	go addOnInformers.Start(ctx.Done())
-
-	go managedClusterController.Run(ctx, 1)
-	go taintController.Run(ctx, 1)
-	go csrController.Run(ctx, 1)
-	go leaseController.Run(ctx, 1)
-	go rbacFinalizerController.Run(ctx, 1)
-	go managedClusterSetController.Run(ctx, 1)
-	go managedClusterSetBindingController.Run(ctx, 1)
+	go csrInformers.Start(ctx.Done())
+	go leaseInformers.Start(ctx.Done())
+	go appliedResourceInformers.Start(ctx.Done())
+	go m.health.waitForCacheSync(ctx, clusterInformers, workInformers, kubeInfomers, addOnInformers, csrInformers, leaseInformers,
+		metadataCacheSyncWaiter{factory: appliedResourceInformers})
+
+	if managedClusterController != nil {
+		go managedClusterController.Run(ctx, m.ManagedClusterWorkers)
+	}
+	if taintController != nil {
+		go taintController.Run(ctx, 1)
+	}
+	if maintenanceController != nil {
+		go maintenanceController.Run(ctx, 1)
+	}
+	if hubMigrationController != nil {
+		go hubMigrationController.Run(ctx, 1)
+	}
+	if clusterClaimLabelController != nil {
+		go clusterClaimLabelController.Run(ctx, 1)
+	}
+	if agentVersionController != nil {
+		go agentVersionController.Run(ctx, 1)
+	}
+	if clientConfigDriftController != nil {
+		go clientConfigDriftController.Run(ctx, 1)
+	}
+	if clusterIdentityController != nil {
+		go clusterIdentityController.Run(ctx, 1)
+	}
+	if csrController != nil {
+		go csrController.Run(ctx, m.CSRApprovingWorkers)
+	}
+	if csrSigningBacklogController != nil {
+		go csrSigningBacklogController.Run(ctx, 1)
+	}
+	if leaseController != nil {
+		go leaseController.Run(ctx, 1)
+	}
+	go rbacFinalizerController.Run(ctx, m.RBACFinalizerWorkers)
+	if managedClusterSetController != nil {
+		go managedClusterSetController.Run(ctx, 1)
+		go managedClusterSetBindingController.Run(ctx, 1)
+		go clusterSetMigrationController.Run(ctx, 1)
+	}
 	go clusterroleController.Run(ctx, 1)
+	if admissionPolicyController != nil {
+		go admissionPolicyController.Run(ctx, 1)
+	} else {
+		// the policies applied once they were enabled are removed
+		go func() {
+			if err := admissionpolicy.RemovePolicies(ctx, kubeClient, controllerContext.EventRecorder); err != nil {
+				klog.Errorf("Unable to remove the ValidatingAdmissionPolicies: %v", err)
+			}
+		}()
+	}
 	go addOnHealthCheckController.Run(ctx, 1)
-	go addOnFeatureDiscoveryController.Run(ctx, 1)
-	if features.DefaultHubMutableFeatureGate.Enabled(ocmfeature.DefaultClusterSet) {
+	if addOnFeatureDiscoveryController != nil {
+		go addOnFeatureDiscoveryController.Run(ctx, 1)
+	}
+	if defaultManagedClusterSetController != nil {
 		go defaultManagedClusterSetController.Run(ctx, 1)
 		go globalManagedClusterSetController.Run(ctx, 1)
 	}
+	if defaultManagedClusterSetBindingController != nil {
+		go defaultManagedClusterSetBindingController.Run(ctx, 1)
+	}
+	if awsAuthController != nil {
+		go awsAuthController.Run(ctx, 1)
+	}
+	if orphanedNamespaceController != nil {
+		go orphanedNamespaceController.Run(ctx, 1)
+	}
 
 	<-ctx.Done()
 	return nil
 }
+
+// newCSRController creates the controller approving the csrs of the managed clusters, and the controller
+// exposing the signing backlog of the approved csrs with the certificates/v1 api. nil is returned if the csr
+// controller is disabled.
+func (m *HubManagerOptions) newCSRController(
+	kubeClient kubernetes.Interface,
+	clusterClient clusterv1client.Interface,
+	clusterInformers clusterv1informers.SharedInformerFactory,
+	csrInformers kubeinformers.SharedInformerFactory,
+	addOnInformers addoninformers.SharedInformerFactory,
+	disabledControllers sets.Set[string],
+	controllerContext *controllercmd.ControllerContext) (factory.Controller, factory.Controller, error) {
+	if disabledControllers.Has(CSRControllerName) {
+		return nil, nil, nil
+	}
+
+	csrReconciles := []csr.Reconciler{csr.NewCSRRenewalReconciler(kubeClient, controllerContext.EventRecorder)}
+	var clusterAutoApprovalUsers []string
+	if features.DefaultHubMutableFeatureGate.Enabled(ocmfeature.ManagedClusterAutoApproval) {
+		clusterAutoApprovalUsers = m.ClusterAutoApprovalUsers
+	}
+	if len(clusterAutoApprovalUsers) != 0 || len(m.AutoApproveBootstrapUsers) != 0 {
+		csrReconciles = append(csrReconciles, csr.NewCSRBootstrapReconciler(
+			kubeClient,
+			clusterClient,
+			clusterInformers.Cluster().V1().ManagedClusters().Lister(),
+			clusterAutoApprovalUsers,
+			m.AutoApproveBootstrapUsers,
+			controllerContext.EventRecorder,
+		))
+	}
+
+	var csrDenier *csr.CSRDenier
+	if m.CSRDenyThreshold > 0 {
+		csrDenier = csr.NewCSRDenier(clusterInformers.Cluster().V1().ManagedClusters().Lister(), m.CSRDenyThreshold)
+	}
+
+	if features.DefaultHubMutableFeatureGate.Enabled(ocmfeature.V1beta1CSRAPICompatibility) {
+		v1CSRSupported, v1beta1CSRSupported, err := helpers.IsCSRSupported(kubeClient)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed CSR api discovery")
+		}
+
+		if !v1CSRSupported && v1beta1CSRSupported {
+			// the addon csrs are approved by the addon managers with the certificates/v1 api, approve
+			// the kube-apiserver client csrs of addons on the hub which only supports v1beta1 api.
+			v1beta1CSRReconciles := append([]csr.Reconciler{csr.NewCSRAddOnReconciler(
+				kubeClient,
+				clusterInformers.Cluster().V1().ManagedClusters().Lister(),
+				addOnInformers.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				controllerContext.EventRecorder,
+			)}, csrReconciles...)
+			csrController := csr.NewCSRApprovingController[*certv1beta1.CertificateSigningRequest](
+				csrInformers.Certificates().V1beta1().CertificateSigningRequests().Informer(),
+				csrInformers.Certificates().V1beta1().CertificateSigningRequests().Lister(),
+				csr.NewCSRV1beta1Approver(kubeClient),
+				v1beta1CSRReconciles,
+				csrDenier,
+				controllerContext.EventRecorder,
+			)
+			klog.Info("Using v1beta1 CSR api to manage spoke client certificate")
+			return csrController, nil, nil
+		}
+	}
+	csrController := csr.NewCSRApprovingController[*certv1.CertificateSigningRequest](
+		csrInformers.Certificates().V1().CertificateSigningRequests().Informer(),
+		csrInformers.Certificates().V1().CertificateSigningRequests().Lister(),
+		csr.NewCSRV1Approver(kubeClient),
+		csrReconciles,
+		csrDenier,
+		controllerContext.EventRecorder,
+	)
+
+	csrSigningBacklogController := csr.NewCSRSigningBacklogController(
+		csrInformers.Certificates().V1().CertificateSigningRequests(),
+		m.CSRSigningBacklogThreshold,
+		controllerContext.EventRecorder,
+	)
+	return csrController, csrSigningBacklogController, nil
+}
@@ -0,0 +1,80 @@
+package migration
+
+import (
+	"context"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+)
+
+// hubMigrationController stops accepting the managed clusters once their agents report the
+// HubMigrated condition, i.e. the agents are bootstrapping with the new hub in the
+// hub-migration-bootstrap-kubeconfig secret of the cluster namespace. The managed clusters are kept
+// on the hub as migrated instead of being deleted, while the permissions of their agents are revoked
+// once they are not accepted.
+type hubMigrationController struct {
+	clusterClient clientset.Interface
+	clusterLister listerv1.ManagedClusterLister
+	eventRecorder events.Recorder
+}
+
+// NewHubMigrationController creates a new hub migration controller
+func NewHubMigrationController(
+	clusterClient clientset.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &hubMigrationController{
+		clusterClient: clusterClient,
+		clusterLister: clusterInformer.Lister(),
+		eventRecorder: recorder.WithComponentSuffix("hub-migration-controller"),
+	}
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(helpers.RecoverSync("HubMigrationController", c.sync)).
+		ToController("HubMigrationController", recorder)
+}
+
+func (c *hubMigrationController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	managedClusterName := syncCtx.QueueKey()
+	klog.V(4).Infof("Reconciling ManagedCluster %s", managedClusterName)
+	managedCluster, err := c.clusterLister.Get(managedClusterName)
+	if errors.IsNotFound(err) {
+		// Spoke cluster not found, could have been deleted, do nothing.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !managedCluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	migrated := meta.FindStatusCondition(managedCluster.Status.Conditions, helpers.ManagedClusterConditionHubMigrated)
+	if migrated == nil || migrated.Status != metav1.ConditionTrue || !managedCluster.Spec.HubAcceptsClient {
+		return nil
+	}
+
+	managedCluster = managedCluster.DeepCopy()
+	managedCluster.Spec.HubAcceptsClient = false
+	if _, err := c.clusterClient.ClusterV1().ManagedClusters().Update(ctx, managedCluster, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	c.eventRecorder.Eventf("ManagedClusterMigrated", "The managed cluster %q is migrated to another hub and no longer accepted: %s",
+		managedClusterName, migrated.Message)
+	return nil
+}
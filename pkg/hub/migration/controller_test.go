@@ -0,0 +1,103 @@
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestSync(t *testing.T) {
+	newCluster := func(migrated metav1.ConditionStatus, accepted bool) *v1.ManagedCluster {
+		cluster := testinghelpers.NewAvailableManagedCluster()
+		cluster.Spec.HubAcceptsClient = accepted
+		if len(migrated) != 0 {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				Type:    helpers.ManagedClusterConditionHubMigrated,
+				Status:  migrated,
+				Reason:  "MigratedToNewHub",
+				Message: "The managed cluster is migrated to the hub https://hub2.example.com:6443",
+			})
+		}
+		return cluster
+	}
+
+	cases := []struct {
+		name            string
+		startingObjects []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "sync a deleted spoke cluster",
+			startingObjects: []runtime.Object{},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:            "cluster is not migrated",
+			startingObjects: []runtime.Object{newCluster("", true)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:            "cluster migration is not true",
+			startingObjects: []runtime.Object{newCluster(metav1.ConditionFalse, true)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:            "cluster is migrated",
+			startingObjects: []runtime.Object{newCluster(metav1.ConditionTrue, true)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				managedCluster := (actions[0].(clienttesting.UpdateActionImpl).Object).(*v1.ManagedCluster)
+				if managedCluster.Spec.HubAcceptsClient {
+					t.Errorf("expected the migrated cluster is not accepted")
+				}
+			},
+		},
+		{
+			name:            "migrated cluster is not accepted",
+			startingObjects: []runtime.Object{newCluster(metav1.ConditionTrue, false)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.startingObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.startingObjects {
+				if err := clusterStore.Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := hubMigrationController{clusterClient, clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), eventstesting.NewTestingEventRecorder(t)}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
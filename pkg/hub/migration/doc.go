@@ -0,0 +1,3 @@
+// package migration contains the hub-side controller which stops accepting the managed clusters
+// migrated to another hub.
+package migration
@@ -8,6 +8,7 @@ import (
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	worklister "open-cluster-management.io/api/client/work/listers/work/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -42,7 +43,8 @@ type finalizeController struct {
 }
 
 // NewFinalizeController ensures all manifestworks are deleted before role/rolebinding for work
-// agent are deleted in a terminating cluster namespace.
+// agent are deleted in a terminating cluster namespace. The role and rolebinding with the same name are
+// queued with the same key, so they are never synced by more than one worker at a time.
 func NewFinalizeController(
 	roleInformer rbacv1informers.RoleInformer,
 	roleBindingInformer rbacv1informers.RoleBindingInformer,
@@ -68,7 +70,7 @@ func NewFinalizeController(
 			key, _ := cache.MetaNamespaceKeyFunc(obj)
 			return key
 		}, roleInformer.Informer(), roleBindingInformer.Informer()).
-		WithSync(controller.sync).ToController("FinalizeController", eventRecorder)
+		WithSync(helpers.RecoverSync("FinalizeController", controller.sync)).ToController("FinalizeController", eventRecorder)
 }
 
 func (m *finalizeController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
@@ -113,7 +115,10 @@ func (m *finalizeController) syncRoleAndRoleBinding(ctx context.Context, control
 	// clean of manifestworks.
 	// 1. The namespace is finalizing.
 	// 2. The cluster is finalizing but namespace fails to be deleted.
-	if !ns.DeletionTimestamp.IsZero() || (cluster != nil && !cluster.DeletionTimestamp.IsZero()) {
+	// The manifestworks are not waited for if the cluster is detached, so the workloads are left running.
+	detached := cluster != nil && !cluster.DeletionTimestamp.IsZero() &&
+		cluster.Annotations[helpers.ManagedClusterDeletionModeAnnotation] == helpers.ManagedClusterDeletionModeDetach
+	if !detached && (!ns.DeletionTimestamp.IsZero() || (cluster != nil && !cluster.DeletionTimestamp.IsZero())) {
 		works, err := m.manifestWorkLister.ManifestWorks(ns.Name).List(labels.Everything())
 		if err != nil {
 			return err
@@ -12,6 +12,7 @@ import (
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	workapiv1 "open-cluster-management.io/api/work/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -162,6 +163,22 @@ func TestSyncRoleAndRoleBinding(t *testing.T) {
 				testinghelpers.AssertActions(t, actions, "update", "update")
 			},
 		},
+		{
+			name:        "remove finalizer from role/rolebinding within detached cluster",
+			role:        testinghelpers.NewRole(testinghelpers.TestManagedClusterName, roleName, []string{manifestWorkFinalizer}, true),
+			roleBinding: testinghelpers.NewRoleBinding(testinghelpers.TestManagedClusterName, roleName, []string{manifestWorkFinalizer}, true),
+			cluster: func() *clusterv1.ManagedCluster {
+				cluster := testinghelpers.NewDeletingManagedCluster()
+				cluster.Annotations = map[string]string{helpers.ManagedClusterDeletionModeAnnotation: helpers.ManagedClusterDeletionModeDetach}
+				return cluster
+			}(),
+			namespace:              testinghelpers.NewNamespace(testinghelpers.TestManagedClusterName, false),
+			work:                   testinghelpers.NewManifestWork(testinghelpers.TestManagedClusterName, "work1", []string{manifestWorkFinalizer}, nil),
+			expectedWorkFinalizers: []string{manifestWorkFinalizer},
+			validateRbacActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update", "update")
+			},
+		},
 		{
 			name:        "remove finalizer from role/rolebinding within terminating ns",
 			role:        testinghelpers.NewRole(testinghelpers.TestManagedClusterName, roleName, []string{manifestWorkFinalizer}, true),
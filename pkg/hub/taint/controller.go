@@ -2,6 +2,9 @@ package taint
 
 import (
 	"context"
+	"fmt"
+	"time"
+
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -9,6 +12,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
@@ -30,9 +34,10 @@ var (
 
 // taintController
 type taintController struct {
-	clusterClient clientset.Interface
-	clusterLister listerv1.ManagedClusterLister
-	eventRecorder events.Recorder
+	clusterPatcher *helpers.ClusterPatcher
+	clusterLister  listerv1.ManagedClusterLister
+	clock          clock.Clock
+	eventRecorder  events.Recorder
 }
 
 // NewTaintController creates a new taint controller
@@ -41,16 +46,17 @@ func NewTaintController(
 	clusterInformer informerv1.ManagedClusterInformer,
 	recorder events.Recorder) factory.Controller {
 	c := &taintController{
-		clusterClient: clusterClient,
-		clusterLister: clusterInformer.Lister(),
-		eventRecorder: recorder.WithComponentSuffix("taint-controller"),
+		clusterPatcher: helpers.NewClusterPatcher(clusterClient),
+		clusterLister:  clusterInformer.Lister(),
+		clock:          clock.RealClock{},
+		eventRecorder:  recorder.WithComponentSuffix("taint-controller"),
 	}
 	return factory.New().
 		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, clusterInformer.Informer()).
-		WithSync(c.sync).
+		WithSync(helpers.RecoverSync("taintController", c.sync)).
 		ToController("taintController", recorder)
 }
 
@@ -69,28 +75,71 @@ func (c *taintController) sync(ctx context.Context, syncCtx factory.SyncContext)
 		return nil
 	}
 
-	managedCluster = managedCluster.DeepCopy()
-	newTaints := managedCluster.Spec.Taints
 	cond := meta.FindStatusCondition(managedCluster.Status.Conditions, v1.ManagedClusterConditionAvailable)
-	var updated bool
-
+	var add, remove []v1.Taint
 	switch {
 	case cond == nil || cond.Status == metav1.ConditionUnknown:
-		updated = helpers.RemoveTaints(&newTaints, UnavailableTaint)
-		updated = helpers.AddTaints(&newTaints, UnreachableTaint) || updated
+		add, remove = []v1.Taint{UnreachableTaint}, []v1.Taint{UnavailableTaint}
 	case cond.Status == metav1.ConditionFalse:
-		updated = helpers.RemoveTaints(&newTaints, UnreachableTaint)
-		updated = helpers.AddTaints(&newTaints, UnavailableTaint) || updated
+		add, remove = []v1.Taint{UnavailableTaint}, []v1.Taint{UnreachableTaint}
 	case cond.Status == metav1.ConditionTrue:
-		updated = helpers.RemoveTaints(&newTaints, UnavailableTaint, UnreachableTaint)
+		remove = []v1.Taint{UnavailableTaint, UnreachableTaint}
 	}
 
-	if updated {
-		managedCluster.Spec.Taints = newTaints
-		if _, err = c.clusterClient.ClusterV1().ManagedClusters().Update(ctx, managedCluster, metav1.UpdateOptions{}); err != nil {
-			return err
-		}
+	oldTaints := managedCluster.Spec.Taints
+	newTaints := append([]v1.Taint{}, oldTaints...)
+	helpers.RemoveTaints(&newTaints, remove...)
+	for _, taint := range add {
+		helpers.AddTaints(&newTaints, taint)
+	}
+
+	patched, err := c.clusterPatcher.PatchTaints(ctx, managedCluster, add, remove)
+	if err != nil {
+		return err
+	}
+	if patched {
 		c.eventRecorder.Eventf("ManagedClusterConditionAvailableUpdated", "Update the original taints to the %+v", newTaints)
+		c.recordTaintChanges(managedClusterName, oldTaints, newTaints, cond)
 	}
 	return nil
 }
+
+// recordTaintChanges emits an event for each of the unreachable and unavailable taints added or removed,
+// with the previous status of the Available condition and how long it lasted, so that the disruptions of
+// the placements can be correlated afterwards.
+func (c *taintController) recordTaintChanges(managedClusterName string, oldTaints, newTaints []v1.Taint, cond *metav1.Condition) {
+	added, removed := recordTaintChangesMetric(oldTaints, newTaints)
+
+	previous := availableStatusOf(oldTaints)
+	current := metav1.ConditionUnknown
+	if cond != nil {
+		current = cond.Status
+	}
+	transition := fmt.Sprintf("the Available condition changed from %s to %s", previous, current)
+	for _, taint := range removed {
+		if !taint.TimeAdded.IsZero() {
+			transition = fmt.Sprintf("%s after %s", transition, c.clock.Since(taint.TimeAdded.Time).Round(time.Second))
+		}
+	}
+
+	for _, taint := range added {
+		c.eventRecorder.Eventf("ManagedClusterTaintAdded", "Added taint %q to managed cluster %q, %s",
+			taint.Key, managedClusterName, transition)
+	}
+	for _, taint := range removed {
+		c.eventRecorder.Eventf("ManagedClusterTaintRemoved", "Removed taint %q from managed cluster %q, %s",
+			taint.Key, managedClusterName, transition)
+	}
+}
+
+// availableStatusOf returns the status of the Available condition which the taints were added for.
+func availableStatusOf(taints []v1.Taint) metav1.ConditionStatus {
+	switch {
+	case helpers.FindTaint(taints, UnreachableTaint) != nil:
+		return metav1.ConditionUnknown
+	case helpers.FindTaint(taints, UnavailableTaint) != nil:
+		return metav1.ConditionFalse
+	default:
+		return metav1.ConditionTrue
+	}
+}
@@ -2,6 +2,7 @@ package taint
 
 import (
 	"context"
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
@@ -10,12 +11,17 @@ import (
 
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
+	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
 )
 
 func TestSyncTaintCluster(t *testing.T) {
@@ -35,8 +41,11 @@ func TestSyncTaintCluster(t *testing.T) {
 			name:            "ManagedClusterConditionAvailable conditionStatus is False",
 			startingObjects: []runtime.Object{testinghelpers.NewUnAvailableManagedCluster()},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update")
-				managedCluster := (actions[0].(clienttesting.UpdateActionImpl).Object).(*v1.ManagedCluster)
+				testinghelpers.AssertActions(t, actions, "patch")
+				managedCluster := &v1.ManagedCluster{}
+				if err := json.Unmarshal(actions[0].(clienttesting.PatchActionImpl).GetPatch(), managedCluster); err != nil {
+					t.Fatal(err)
+				}
 				taints := []v1.Taint{UnavailableTaint}
 				if !reflect.DeepEqual(managedCluster.Spec.Taints, taints) {
 					t.Errorf("expected taint %#v, but actualTaints: %#v", taints, managedCluster.Spec.Taints)
@@ -47,8 +56,11 @@ func TestSyncTaintCluster(t *testing.T) {
 			name:            "There is no ManagedClusterConditionAvailable",
 			startingObjects: []runtime.Object{testinghelpers.NewManagedCluster()},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update")
-				managedCluster := (actions[0].(clienttesting.UpdateActionImpl).Object).(*v1.ManagedCluster)
+				testinghelpers.AssertActions(t, actions, "patch")
+				managedCluster := &v1.ManagedCluster{}
+				if err := json.Unmarshal(actions[0].(clienttesting.PatchActionImpl).GetPatch(), managedCluster); err != nil {
+					t.Fatal(err)
+				}
 				taints := []v1.Taint{UnreachableTaint}
 				if !reflect.DeepEqual(managedCluster.Spec.Taints, taints) {
 					t.Errorf("expected taint %#v, but actualTaints: %#v", taints, managedCluster.Spec.Taints)
@@ -59,8 +71,11 @@ func TestSyncTaintCluster(t *testing.T) {
 			name:            "ManagedClusterConditionAvailable conditionStatus is Unknown",
 			startingObjects: []runtime.Object{testinghelpers.NewUnknownManagedCluster()},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
-				testinghelpers.AssertActions(t, actions, "update")
-				managedCluster := (actions[0].(clienttesting.UpdateActionImpl).Object).(*v1.ManagedCluster)
+				testinghelpers.AssertActions(t, actions, "patch")
+				managedCluster := &v1.ManagedCluster{}
+				if err := json.Unmarshal(actions[0].(clienttesting.PatchActionImpl).GetPatch(), managedCluster); err != nil {
+					t.Fatal(err)
+				}
 				taints := []v1.Taint{UnreachableTaint}
 				if !reflect.DeepEqual(managedCluster.Spec.Taints, taints) {
 					t.Errorf("expected taint %#v, but actualTaints: %#v", taints, managedCluster.Spec.Taints)
@@ -87,7 +102,7 @@ func TestSyncTaintCluster(t *testing.T) {
 				}
 			}
 
-			ctrl := taintController{clusterClient, clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), eventstesting.NewTestingEventRecorder(t)}
+			ctrl := taintController{helpers.NewClusterPatcher(clusterClient), clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), clock.RealClock{}, eventstesting.NewTestingEventRecorder(t)}
 			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
 			if syncErr != nil {
 				t.Errorf("unexpected err: %v", syncErr)
@@ -97,3 +112,52 @@ func TestSyncTaintCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordTaintChangeEvents(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name             string
+		oldTaints        []v1.Taint
+		newTaints        []v1.Taint
+		cond             *metav1.Condition
+		expectedMessages []string
+	}{
+		{
+			name:      "cluster becomes unreachable",
+			newTaints: []v1.Taint{UnreachableTaint},
+			expectedMessages: []string{
+				`Added taint "cluster.open-cluster-management.io/unreachable" to managed cluster "cluster1", the Available condition changed from True to Unknown`,
+			},
+		},
+		{
+			name: "cluster becomes unavailable",
+			oldTaints: []v1.Taint{{
+				Key:       UnreachableTaint.Key,
+				Effect:    UnreachableTaint.Effect,
+				TimeAdded: metav1.NewTime(now.Add(-5 * time.Minute)),
+			}},
+			newTaints: []v1.Taint{UnavailableTaint},
+			cond:      &metav1.Condition{Type: v1.ManagedClusterConditionAvailable, Status: metav1.ConditionFalse},
+			expectedMessages: []string{
+				`Added taint "cluster.open-cluster-management.io/unavailable" to managed cluster "cluster1", the Available condition changed from Unknown to False after 5m0s`,
+				`Removed taint "cluster.open-cluster-management.io/unreachable" from managed cluster "cluster1", the Available condition changed from Unknown to False after 5m0s`,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recorder := events.NewInMemoryRecorder("test")
+			ctrl := taintController{clock: clocktesting.NewFakeClock(now), eventRecorder: recorder}
+			ctrl.recordTaintChanges("cluster1", c.oldTaints, c.newTaints, c.cond)
+
+			messages := []string{}
+			for _, event := range recorder.Events() {
+				messages = append(messages, event.Message)
+			}
+			if !reflect.DeepEqual(messages, c.expectedMessages) {
+				t.Errorf("expected events %v, but got %v", c.expectedMessages, messages)
+			}
+		})
+	}
+}
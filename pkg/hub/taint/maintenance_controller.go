@@ -0,0 +1,86 @@
+package taint
+
+import (
+	"context"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	informerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	listerv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+// maintenanceController adds the maintenance taint to the managed clusters cordoned with the
+// maintenance annotation, and removes the taint once the annotation is removed.
+type maintenanceController struct {
+	clusterClient clientset.Interface
+	clusterLister listerv1.ManagedClusterLister
+	clock         clock.Clock
+	eventRecorder events.Recorder
+}
+
+// NewMaintenanceController creates a new maintenance controller
+func NewMaintenanceController(
+	clusterClient clientset.Interface,
+	clusterInformer informerv1.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &maintenanceController{
+		clusterClient: clusterClient,
+		clusterLister: clusterInformer.Lister(),
+		clock:         clock.RealClock{},
+		eventRecorder: recorder.WithComponentSuffix("maintenance-controller"),
+	}
+	return factory.New().
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetName()
+		}, clusterInformer.Informer()).
+		WithSync(helpers.RecoverSync("maintenanceController", c.sync)).
+		ToController("maintenanceController", recorder)
+}
+
+func (c *maintenanceController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	managedClusterName := syncCtx.QueueKey()
+	klog.V(4).Infof("Reconciling ManagedCluster %s", managedClusterName)
+	managedCluster, err := c.clusterLister.Get(managedClusterName)
+	if errors.IsNotFound(err) {
+		// Spoke cluster not found, could have been deleted, do nothing.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !managedCluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	inMaintenance := helpers.IsManagedClusterInMaintenance(managedCluster)
+	taint := helpers.FindTaintByKey(managedCluster, helpers.ManagedClusterTaintMaintenance)
+	if inMaintenance == (taint != nil) {
+		return nil
+	}
+
+	managedCluster = managedCluster.DeepCopy()
+	if inMaintenance {
+		helpers.AddTaints(&managedCluster.Spec.Taints, helpers.NewMaintenanceTaint(metav1.NewTime(c.clock.Now())))
+	} else {
+		helpers.RemoveTaints(&managedCluster.Spec.Taints, *taint)
+	}
+	if _, err := c.clusterClient.ClusterV1().ManagedClusters().Update(ctx, managedCluster, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	if inMaintenance {
+		c.eventRecorder.Eventf("ManagedClusterCordoned", "The managed cluster %q is cordoned for maintenance", managedClusterName)
+	} else {
+		c.eventRecorder.Eventf("ManagedClusterUncordoned", "The managed cluster %q is uncordoned", managedClusterName)
+	}
+	return nil
+}
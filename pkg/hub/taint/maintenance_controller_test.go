@@ -0,0 +1,120 @@
+package taint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "open-cluster-management.io/api/cluster/v1"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestSyncMaintenance(t *testing.T) {
+	newCluster := func(maintenance string, taints ...v1.Taint) *v1.ManagedCluster {
+		cluster := testinghelpers.NewAvailableManagedCluster()
+		if len(maintenance) != 0 {
+			cluster.Annotations = map[string]string{helpers.ManagedClusterMaintenanceAnnotation: maintenance}
+		}
+		cluster.Spec.Taints = taints
+		return cluster
+	}
+	now := time.Now()
+	addedTime := metav1.NewTime(now.Add(-time.Hour))
+
+	cases := []struct {
+		name            string
+		startingObjects []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "cluster is not cordoned",
+			startingObjects: []runtime.Object{newCluster("", UnreachableTaint)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:            "cluster is cordoned",
+			startingObjects: []runtime.Object{newCluster("true", UnreachableTaint)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				managedCluster := (actions[0].(clienttesting.UpdateActionImpl).Object).(*v1.ManagedCluster)
+				if len(managedCluster.Spec.Taints) != 2 {
+					t.Fatalf("expected 2 taints, but got %#v", managedCluster.Spec.Taints)
+				}
+				taint := helpers.FindTaintByKey(managedCluster, helpers.ManagedClusterTaintMaintenance)
+				if taint == nil || taint.Effect != v1.TaintEffectNoSelect || !taint.TimeAdded.Time.Equal(now) {
+					t.Errorf("expected maintenance taint added at %v, but got %#v", now, taint)
+				}
+			},
+		},
+		{
+			name:            "cluster is already tainted",
+			startingObjects: []runtime.Object{newCluster("true", helpers.NewMaintenanceTaint(addedTime))},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:            "cluster is uncordoned",
+			startingObjects: []runtime.Object{newCluster("", UnreachableTaint, helpers.NewMaintenanceTaint(addedTime))},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				managedCluster := (actions[0].(clienttesting.UpdateActionImpl).Object).(*v1.ManagedCluster)
+				if len(managedCluster.Spec.Taints) != 1 || managedCluster.Spec.Taints[0].Key != UnreachableTaint.Key {
+					t.Errorf("expected taint %#v, but got %#v", UnreachableTaint, managedCluster.Spec.Taints)
+				}
+			},
+		},
+		{
+			name:            "maintenance annotation is not true",
+			startingObjects: []runtime.Object{newCluster("false", helpers.NewMaintenanceTaint(addedTime))},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				managedCluster := (actions[0].(clienttesting.UpdateActionImpl).Object).(*v1.ManagedCluster)
+				if len(managedCluster.Spec.Taints) != 0 {
+					t.Errorf("expected no taints, but got %#v", managedCluster.Spec.Taints)
+				}
+			},
+		},
+		{
+			name:            "sync a deleted spoke cluster",
+			startingObjects: []runtime.Object{},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.startingObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.startingObjects {
+				if err := clusterStore.Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := maintenanceController{clusterClient, clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(), clocktesting.NewFakeClock(now), eventstesting.NewTestingEventRecorder(t)}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package taint
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+var taintChangesTotal = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Name: "open_cluster_management_registration_managed_cluster_taint_changes_total",
+		Help: "The number of the unreachable and unavailable taints added to or removed from managed clusters by the taint controller.",
+	},
+	[]string{"taint", "operation"},
+)
+
+func init() {
+	legacyregistry.MustRegister(taintChangesTotal)
+}
+
+// recordTaintChangesMetric counts the unreachable and unavailable taints added or removed from the old taints
+// to the new taints, and returns them. It is only called once the new taints are persisted.
+func recordTaintChangesMetric(oldTaints, newTaints []v1.Taint) (added, removed []v1.Taint) {
+	for _, taint := range []v1.Taint{UnreachableTaint, UnavailableTaint} {
+		oldTaint, newTaint := helpers.FindTaint(oldTaints, taint), helpers.FindTaint(newTaints, taint)
+		switch {
+		case oldTaint == nil && newTaint != nil:
+			added = append(added, *newTaint)
+			taintChangesTotal.WithLabelValues(taint.Key, "added").Inc()
+		case oldTaint != nil && newTaint == nil:
+			removed = append(removed, *oldTaint)
+			taintChangesTotal.WithLabelValues(taint.Key, "removed").Inc()
+		}
+	}
+	return added, removed
+}
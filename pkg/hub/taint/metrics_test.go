@@ -0,0 +1,90 @@
+package taint
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/component-base/metrics/testutil"
+	v1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func TestRecordTaintChangesMetric(t *testing.T) {
+	customTaint := v1.Taint{Key: "foo", Effect: v1.TaintEffectNoSelect}
+
+	cases := []struct {
+		name            string
+		oldTaints       []v1.Taint
+		newTaints       []v1.Taint
+		expectedAdded   []v1.Taint
+		expectedRemoved []v1.Taint
+	}{
+		{
+			name:      "no changes",
+			oldTaints: []v1.Taint{UnreachableTaint},
+			newTaints: []v1.Taint{UnreachableTaint, customTaint},
+		},
+		{
+			name:          "unreachable taint is added",
+			newTaints:     []v1.Taint{UnreachableTaint},
+			expectedAdded: []v1.Taint{UnreachableTaint},
+		},
+		{
+			name:            "unreachable taint is replaced with unavailable taint",
+			oldTaints:       []v1.Taint{UnreachableTaint, customTaint},
+			newTaints:       []v1.Taint{customTaint, UnavailableTaint},
+			expectedAdded:   []v1.Taint{UnavailableTaint},
+			expectedRemoved: []v1.Taint{UnreachableTaint},
+		},
+		{
+			name:            "custom taint is removed",
+			oldTaints:       []v1.Taint{customTaint},
+			expectedRemoved: nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			before := counterValues(t)
+			added, removed := recordTaintChangesMetric(c.oldTaints, c.newTaints)
+			if !reflect.DeepEqual(added, c.expectedAdded) {
+				t.Errorf("expected added taints %v, but got %v", c.expectedAdded, added)
+			}
+			if !reflect.DeepEqual(removed, c.expectedRemoved) {
+				t.Errorf("expected removed taints %v, but got %v", c.expectedRemoved, removed)
+			}
+
+			after := counterValues(t)
+			for _, taint := range []v1.Taint{UnreachableTaint, UnavailableTaint} {
+				assertCounterIncrease(t, taint.Key, "added", before, after, c.expectedAdded)
+				assertCounterIncrease(t, taint.Key, "removed", before, after, c.expectedRemoved)
+			}
+		})
+	}
+}
+
+// counterValues returns the values of the taint changes counter keyed by the taint and the operation.
+func counterValues(t *testing.T) map[string]float64 {
+	values := map[string]float64{}
+	for _, taint := range []v1.Taint{UnreachableTaint, UnavailableTaint} {
+		for _, operation := range []string{"added", "removed"} {
+			value, err := testutil.GetCounterMetricValue(taintChangesTotal.WithLabelValues(taint.Key, operation))
+			if err != nil {
+				t.Fatal(err)
+			}
+			values[taint.Key+"/"+operation] = value
+		}
+	}
+	return values
+}
+
+func assertCounterIncrease(t *testing.T, key, operation string, before, after map[string]float64, changed []v1.Taint) {
+	expected := 0.0
+	for _, taint := range changed {
+		if taint.Key == key {
+			expected++
+		}
+	}
+	if actual := after[key+"/"+operation] - before[key+"/"+operation]; actual != expected {
+		t.Errorf("expected %v %s %q taints, but got %v", expected, operation, key, actual)
+	}
+}
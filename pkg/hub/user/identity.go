@@ -5,4 +5,12 @@ const (
 	SubjectPrefix = "system:open-cluster-management:"
 	// ManagedClustersGroup is a common group for all spoke clusters
 	ManagedClustersGroup = SubjectPrefix + "managed-clusters"
+
+	// IAMRoleARNAnnotation is set on a ManagedCluster by the agent registered with the awsirsa driver,
+	// the AWS IAM role in it is mapped to the identity of the managed cluster on an EKS hub.
+	IAMRoleARNAnnotation = "agent.open-cluster-management.io/managed-cluster-iam-role-arn"
+
+	// TokenUsernameAnnotation is set on a ManagedCluster by the agent registered with the token driver,
+	// the user authenticated by the token is bound to the roles of the managed cluster on the hub.
+	TokenUsernameAnnotation = "agent.open-cluster-management.io/managed-cluster-token-username"
 )
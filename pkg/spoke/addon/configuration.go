@@ -6,6 +6,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/json"
 	"fmt"
+	"net"
 	"strings"
 
 	certificatesv1 "k8s.io/api/certificates/v1"
@@ -16,6 +17,12 @@ const (
 	defaultAddOnInstallationNamespace = "open-cluster-management-agent-addon"
 	// hostingClusterNameAnnotation is the annotation for indicating the hosting cluster name
 	hostingClusterNameAnnotation = "addon.open-cluster-management.io/hosting-cluster-name"
+	// registrationDNSNamesAnnotation is the annotation of an addon for the additional DNS names, separated
+	// by commas, of the certificates signed by the custom signers, e.g. the serving certificates of the addon.
+	registrationDNSNamesAnnotation = "addon.open-cluster-management.io/registration-dns-names"
+	// registrationIPAddressesAnnotation is the annotation of an addon for the additional IP addresses,
+	// separated by commas, of the certificates signed by the custom signers.
+	registrationIPAddressesAnnotation = "addon.open-cluster-management.io/registration-ip-addresses"
 )
 
 // registrationConfig contains necessary information for addon registration
@@ -31,6 +38,7 @@ type registrationConfig struct {
 	stopFunc   context.CancelFunc
 
 	addonInstallOption
+	subjectAltNames
 }
 
 type addonInstallOption struct {
@@ -38,6 +46,26 @@ type addonInstallOption struct {
 	AgentRunningOutsideManagedCluster bool   `json:"agentRunningOutsideManagedCluster"`
 }
 
+// subjectAltNames contains the additional subject alternative names of the certificate besides the
+// default DNS name of the addon.
+type subjectAltNames struct {
+	DNSNames    []string `json:"dnsNames,omitempty"`
+	IPAddresses []string `json:"ipAddresses,omitempty"`
+}
+
+func (n subjectAltNames) isEmpty() bool {
+	return len(n.DNSNames) == 0 && len(n.IPAddresses) == 0
+}
+
+// ips returns the IP addresses, they are validated when the subjectAltNames is read from the addon.
+func (n subjectAltNames) ips() []net.IP {
+	ips := []net.IP{}
+	for _, address := range n.IPAddresses {
+		ips = append(ips, net.ParseIP(address))
+	}
+	return ips
+}
+
 func (c *registrationConfig) x509Subject(clusterName, agentName string) *pkix.Name {
 	subject := &pkix.Name{
 		CommonName:         c.registration.Subject.User,
@@ -82,11 +110,41 @@ func isAddonRunningOutsideManagedCluster(addOn *addonv1alpha1.ManagedClusterAddO
 	return false
 }
 
+// getSubjectAltNames reads the additional subject alternative names from the annotations of the addon.
+func getSubjectAltNames(addOn *addonv1alpha1.ManagedClusterAddOn) (subjectAltNames, error) {
+	names := subjectAltNames{
+		DNSNames:    splitAnnotation(addOn.Annotations[registrationDNSNamesAnnotation]),
+		IPAddresses: splitAnnotation(addOn.Annotations[registrationIPAddressesAnnotation]),
+	}
+	for _, address := range names.IPAddresses {
+		if net.ParseIP(address) == nil {
+			return names, fmt.Errorf("invalid IP address %q in the annotation %q of addon %q",
+				address, registrationIPAddressesAnnotation, addOn.Name)
+		}
+	}
+	return names, nil
+}
+
+func splitAnnotation(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) != 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 // getRegistrationConfigs reads annotations of a addon and returns a map of registrationConfig whose
 // key is the hash of the registrationConfig
 func getRegistrationConfigs(addOn *addonv1alpha1.ManagedClusterAddOn) (map[string]registrationConfig, error) {
 	configs := map[string]registrationConfig{}
 
+	names, err := getSubjectAltNames(addOn)
+	if err != nil {
+		return configs, err
+	}
+
 	for _, registration := range addOn.Status.Registrations {
 		config := registrationConfig{
 			addOnName: addOn.Name,
@@ -97,19 +155,23 @@ func getRegistrationConfigs(addOn *addonv1alpha1.ManagedClusterAddOn) (map[strin
 			registration: registration,
 		}
 
-		// set the secret name of client certificate
+		// set the secret name of client certificate, the additional subject alternative names are only
+		// used by the certificates signed by the custom signers
 		switch registration.SignerName {
 		case certificatesv1.KubeAPIServerClientSignerName:
 			config.secretName = fmt.Sprintf("%s-hub-kubeconfig", addOn.Name)
 		default:
 			config.secretName = fmt.Sprintf("%s-%s-client-cert", addOn.Name, strings.ReplaceAll(registration.SignerName, "/", "-"))
+			config.subjectAltNames = names
 		}
 
-		// hash registration configuration, install namespace and addOnAgentRunningOutsideManagedCluster. Use the hash
-		// value as the key of map to make sure each registration configuration and addon installation option is unique
+		// hash registration configuration, install namespace, addOnAgentRunningOutsideManagedCluster and the
+		// subject alternative names. Use the hash value as the key of map to make sure each registration
+		// configuration and addon installation option is unique
 		hash, err := getConfigHash(
 			registration,
-			config.addonInstallOption)
+			config.addonInstallOption,
+			config.subjectAltNames)
 		if err != nil {
 			return configs, err
 		}
@@ -120,7 +182,7 @@ func getRegistrationConfigs(addOn *addonv1alpha1.ManagedClusterAddOn) (map[strin
 	return configs, nil
 }
 
-func getConfigHash(registration addonv1alpha1.RegistrationConfig, installOption addonInstallOption) (string, error) {
+func getConfigHash(registration addonv1alpha1.RegistrationConfig, installOption addonInstallOption, names subjectAltNames) (string, error) {
 	data, err := json.Marshal(registration)
 	if err != nil {
 		return "", err
@@ -135,6 +197,15 @@ func getConfigHash(registration addonv1alpha1.RegistrationConfig, installOption
 	h.Write(data)
 	h.Write(installOptionData)
 
+	// keep the hash of the configs without subject alternative names unchanged
+	if !names.isEmpty() {
+		namesData, err := json.Marshal(names)
+		if err != nil {
+			return "", err
+		}
+		h.Write(namesData)
+	}
+
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
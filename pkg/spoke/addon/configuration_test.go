@@ -14,9 +14,10 @@ func TestGetRegistrationConfigs(t *testing.T) {
 	addOnNamespace := "ns1"
 
 	cases := []struct {
-		name    string
-		addon   *addonv1alpha1.ManagedClusterAddOn
-		configs []registrationConfig
+		name        string
+		addon       *addonv1alpha1.ManagedClusterAddOn
+		configs     []registrationConfig
+		expectedErr string
 	}{
 		{
 			name: "no registration",
@@ -120,13 +121,67 @@ func TestGetRegistrationConfigs(t *testing.T) {
 				newRegistrationConfig(addOnName, addOnNamespace, "mysigner", "", nil, false),
 			},
 		},
+		{
+			name: "with subject alternative names",
+			addon: &addonv1alpha1.ManagedClusterAddOn{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testinghelpers.TestManagedClusterName,
+					Name:      addOnName,
+					Annotations: map[string]string{
+						registrationDNSNamesAnnotation:    "addon1.svc, addon1.svc.cluster.local",
+						registrationIPAddressesAnnotation: "10.0.0.1",
+					},
+				},
+				Spec: addonv1alpha1.ManagedClusterAddOnSpec{
+					InstallNamespace: addOnNamespace,
+				},
+				Status: addonv1alpha1.ManagedClusterAddOnStatus{
+					Registrations: []addonv1alpha1.RegistrationConfig{
+						{
+							SignerName: "kubernetes.io/kube-apiserver-client",
+						},
+						{
+							SignerName: "mysigner",
+						},
+					},
+				},
+			},
+			configs: []registrationConfig{
+				newRegistrationConfig(addOnName, addOnNamespace, "kubernetes.io/kube-apiserver-client", "", nil, false),
+				withSubjectAltNames(newRegistrationConfig(addOnName, addOnNamespace, "mysigner", "", nil, false), subjectAltNames{
+					DNSNames:    []string{"addon1.svc", "addon1.svc.cluster.local"},
+					IPAddresses: []string{"10.0.0.1"},
+				}),
+			},
+		},
+		{
+			name: "with invalid ip address",
+			addon: &addonv1alpha1.ManagedClusterAddOn{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testinghelpers.TestManagedClusterName,
+					Name:      addOnName,
+					Annotations: map[string]string{
+						registrationIPAddressesAnnotation: "10.0.0",
+					},
+				},
+				Status: addonv1alpha1.ManagedClusterAddOnStatus{
+					Registrations: []addonv1alpha1.RegistrationConfig{
+						{
+							SignerName: "mysigner",
+						},
+					},
+				},
+			},
+			expectedErr: `invalid IP address "10.0.0" in the annotation "addon.open-cluster-management.io/registration-ip-addresses" of addon "addon1"`,
+		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			configs, err := getRegistrationConfigs(c.addon)
+			testinghelpers.AssertError(t, err, c.expectedErr)
 			if err != nil {
-				t.Errorf("unexpected error: %v", err)
+				return
 			}
 			if len(configs) != len(c.configs) {
 				t.Errorf("expected %d configs, but got %d", len(c.configs), len(configs))
@@ -160,8 +215,14 @@ func newRegistrationConfig(addOnName, addOnNamespace, signerName, commonName str
 		registration: registration,
 	}
 
-	hash, _ := getConfigHash(registration, config.addonInstallOption)
+	hash, _ := getConfigHash(registration, config.addonInstallOption, config.subjectAltNames)
 	config.hash = hash
 
 	return config
 }
+
+func withSubjectAltNames(config registrationConfig, names subjectAltNames) registrationConfig {
+	config.subjectAltNames = names
+	config.hash, _ = getConfigHash(config.registration, config.addonInstallOption, names)
+	return config
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/openshift/library-go/pkg/operator/events"
 	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
 	certificatesv1 "k8s.io/api/certificates/v1"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -32,6 +33,10 @@ const (
 
 	// TODO(qiujian16) expose it if necessary in the future.
 	addonCSRThreshold = 10
+
+	// registrationConfigInvalidReason is a reason of the ClusterCertificateRotated condition of an addon
+	// that its registration config is invalid, e.g. an invalid IP address in the annotation.
+	registrationConfigInvalidReason = "RegistrationConfigInvalid"
 )
 
 // addOnRegistrationController monitors ManagedClusterAddOns on hub and starts addOn registration
@@ -152,6 +157,16 @@ func (c *addOnRegistrationController) syncAddOn(ctx context.Context, syncCtx fac
 	cachedConfigs := c.addOnRegistrationConfigs[addOnName]
 	configs, err := getRegistrationConfigs(addOn)
 	if err != nil {
+		// surface the invalid registration config on the addon, otherwise its credentials never appear silently
+		if _, _, updateErr := helpers.UpdateManagedClusterAddOnStatus(ctx, c.addOnClient, c.clusterName, addOnName,
+			helpers.UpdateManagedClusterAddOnStatusFn(metav1.Condition{
+				Type:    clientcert.ClusterCertificateRotatedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  registrationConfigInvalidReason,
+				Message: err.Error(),
+			})); updateErr != nil {
+			return updateErr
+		}
 		return err
 	}
 
@@ -230,11 +245,14 @@ func (c *addOnRegistrationController) startRegistration(ctx context.Context, con
 				addonv1alpha1.AddonLabelKey:   config.addOnName,
 			},
 		},
-		Subject:         config.x509Subject(c.clusterName, c.agentName),
-		DNSNames:        []string{fmt.Sprintf("%s.addon.open-cluster-management.io", config.addOnName)},
-		SignerName:      config.registration.SignerName,
-		EventFilterFunc: createCSREventFilterFunc(c.clusterName, config.addOnName, config.registration.SignerName),
-		HaltCSRCreation: c.haltCSRCreationFunc(config.addOnName),
+		Subject: config.x509Subject(c.clusterName, c.agentName),
+		DNSNames: append([]string{fmt.Sprintf("%s.addon.open-cluster-management.io", config.addOnName)},
+			config.subjectAltNames.DNSNames...),
+		IPAddresses:              config.subjectAltNames.ips(),
+		SubjectAltNamesSensitive: !config.subjectAltNames.isEmpty(),
+		SignerName:               config.registration.SignerName,
+		EventFilterFunc:          createCSREventFilterFunc(c.clusterName, config.addOnName, config.registration.SignerName),
+		HaltCSRCreation:          c.haltCSRCreationFunc(config.addOnName),
 	}
 
 	controllerName := fmt.Sprintf("ClientCertController@addon:%s:signer:%s", config.addOnName, config.registration.SignerName)
@@ -357,16 +375,21 @@ func createCSREventFilterFunc(clusterName, addOnName, signerName string) factory
 		}
 
 		// only enqueue csr with a specific signer name
-		csr, ok := obj.(*certificatesv1.CertificateSigningRequest)
-		if !ok {
-			return false
-		}
-		if len(csr.Spec.SignerName) == 0 {
-			return false
-		}
-		if csr.Spec.SignerName != signerName {
+		switch csr := obj.(type) {
+		case *certificatesv1.CertificateSigningRequest:
+			if len(csr.Spec.SignerName) == 0 {
+				return false
+			}
+			return csr.Spec.SignerName == signerName
+		case *certificatesv1beta1.CertificateSigningRequest:
+			// the signer name is dropped by the hub which does not support it, and only the client certificates
+			// for kube-apiserver are issued by such a hub.
+			if csr.Spec.SignerName == nil {
+				return signerName == certificatesv1.KubeAPIServerClientSignerName
+			}
+			return *csr.Spec.SignerName == signerName
+		default:
 			return false
 		}
-		return true
 	}
 }
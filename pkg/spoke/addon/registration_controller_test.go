@@ -2,12 +2,14 @@ package addon
 
 import (
 	"context"
+	"encoding/json"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	"testing"
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	certificates "k8s.io/api/certificates/v1"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubefake "k8s.io/client-go/kubernetes/fake"
@@ -17,6 +19,7 @@ import (
 	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
 	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/clientcert"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 )
 
@@ -25,10 +28,16 @@ func TestFilterCSREvents(t *testing.T) {
 	addonName := "addon1"
 	signerName := "signer1"
 
+	labels := map[string]string{
+		clusterv1.ClusterNameLabelKey: clusterName,
+		addonv1alpha1.AddonLabelKey:   addonName,
+	}
+
 	cases := []struct {
-		name     string
-		csr      *certificates.CertificateSigningRequest
-		expected bool
+		name       string
+		signerName string
+		csr        runtime.Object
+		expected   bool
 	}{
 		{
 			name: "csr not from the managed cluster",
@@ -58,11 +67,47 @@ func TestFilterCSREvents(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "valid v1beta1 csr",
+			csr: &certificatesv1beta1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: certificatesv1beta1.CertificateSigningRequestSpec{
+					SignerName: &signerName,
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "v1beta1 csr with different signer name",
+			csr: &certificatesv1beta1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: certificatesv1beta1.CertificateSigningRequestSpec{
+					SignerName: &clusterName,
+				},
+			},
+		},
+		{
+			name:       "v1beta1 csr without signer name for kube-apiserver client",
+			signerName: certificates.KubeAPIServerClientSignerName,
+			csr: &certificatesv1beta1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			},
+			expected: true,
+		},
+		{
+			name: "v1beta1 csr without signer name for other signer",
+			csr: &certificatesv1beta1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			},
+		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			filterFunc := createCSREventFilterFunc(clusterName, addonName, signerName)
+			if len(c.signerName) == 0 {
+				c.signerName = signerName
+			}
+			filterFunc := createCSREventFilterFunc(clusterName, addonName, c.signerName)
 			actual := filterFunc(c.csr)
 			if actual != c.expected {
 				t.Errorf("Expected %v but got %v", c.expected, actual)
@@ -94,7 +139,9 @@ func TestRegistrationSync(t *testing.T) {
 		addOnRegistrationConfigs             map[string]map[string]registrationConfig
 		addonAgentOutsideManagedCluster      bool
 		expectedAddOnRegistrationConfigHashs map[string][]string
+		expectedErr                          string
 		validateActions                      func(t *testing.T, actions, managementActions []clienttesting.Action)
+		validateAddOnActions                 func(t *testing.T, actions []clienttesting.Action)
 	}{
 		{
 			name:     "addon registration not enabled",
@@ -320,6 +367,33 @@ func TestRegistrationSync(t *testing.T) {
 				testinghelpers.AssertActions(t, managementActions, "delete")
 			},
 		},
+		{
+			name:     "invalid registration config",
+			queueKey: addonName,
+			addOn: withAnnotations(newManagedClusterAddOn(clusterName, addonName,
+				[]addonv1alpha1.RegistrationConfig{config2}, false), map[string]string{
+				registrationIPAddressesAnnotation: "invalid",
+			}),
+			expectedErr: `invalid IP address "invalid" in the annotation "addon.open-cluster-management.io/registration-ip-addresses" of addon "addon1"`,
+			validateActions: func(t *testing.T, actions, managementActions []clienttesting.Action) {
+				testinghelpers.AssertNoActions(t, actions)
+				testinghelpers.AssertNoActions(t, managementActions)
+			},
+			validateAddOnActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				patch := actions[1].(clienttesting.PatchActionImpl).Patch
+				addOn := &addonv1alpha1.ManagedClusterAddOn{}
+				if err := json.Unmarshal(patch, addOn); err != nil {
+					t.Fatal(err)
+				}
+				testinghelpers.AssertCondition(t, addOn.Status.Conditions, metav1.Condition{
+					Type:    clientcert.ClusterCertificateRotatedCondition,
+					Status:  metav1.ConditionFalse,
+					Reason:  registrationConfigInvalidReason,
+					Message: `invalid IP address "invalid" in the annotation "addon.open-cluster-management.io/registration-ip-addresses" of addon "addon1"`,
+				})
+			},
+		},
 		{
 			name:     "resync",
 			queueKey: factory.DefaultQueueKey,
@@ -381,6 +455,7 @@ func TestRegistrationSync(t *testing.T) {
 				managementKubeClient: managementClient,
 				spokeKubeClient:      kubeClient,
 				hubAddOnLister:       addonInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				addOnClient:          addonClient,
 				recorder:             eventstesting.NewTestingEventRecorder(t),
 				startRegistrationFunc: func(ctx context.Context, config registrationConfig) context.CancelFunc {
 					_, cancel := context.WithCancel(context.Background())
@@ -390,9 +465,7 @@ func TestRegistrationSync(t *testing.T) {
 			}
 
 			err := controller.sync(context.Background(), testinghelpers.NewFakeSyncContext(t, c.queueKey))
-			if err != nil {
-				t.Errorf("unexpected error: %v", err)
-			}
+			testinghelpers.AssertError(t, err, c.expectedErr)
 
 			if len(c.expectedAddOnRegistrationConfigHashs) != len(controller.addOnRegistrationConfigs) {
 				t.Errorf("expected %d addOns, but got %d",
@@ -421,6 +494,9 @@ func TestRegistrationSync(t *testing.T) {
 			if c.validateActions != nil {
 				c.validateActions(t, kubeClient.Actions(), managementClient.Actions())
 			}
+			if c.validateAddOnActions != nil {
+				c.validateAddOnActions(t, addonClient.Actions())
+			}
 		})
 	}
 }
@@ -459,6 +535,11 @@ func hash(registration addonv1alpha1.RegistrationConfig, installNamespace string
 	h, _ := getConfigHash(registration, addonInstallOption{
 		InstallationNamespace:             installNamespace,
 		AgentRunningOutsideManagedCluster: addOnAgentRunningOutsideManagedCluster,
-	})
+	}, subjectAltNames{})
 	return h
 }
+
+func withAnnotations(addOn *addonv1alpha1.ManagedClusterAddOn, annotations map[string]string) *addonv1alpha1.ManagedClusterAddOn {
+	addOn.Annotations = annotations
+	return addOn
+}
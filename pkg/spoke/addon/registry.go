@@ -0,0 +1,122 @@
+package addon
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/client-go/kubernetes"
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+)
+
+const (
+	// LeaseControllerName is the name of the addon lease controller in the registry.
+	LeaseControllerName = "lease"
+	// RegistrationControllerName is the name of the addon registration controller in the registry.
+	RegistrationControllerName = "registration"
+)
+
+// AddOnControllerContext contains the clients and informers shared by the addon controllers of the agent.
+type AddOnControllerContext struct {
+	ClusterName string
+	AgentName   string
+	// KubeconfigData is the hub kubeconfig of the addons with references to the key/cert files in the
+	// same secret.
+	KubeconfigData       []byte
+	HubKubeClient        kubernetes.Interface
+	ManagementKubeClient kubernetes.Interface
+	SpokeKubeClient      kubernetes.Interface
+	AddOnClient          addonclient.Interface
+	AddOnInformer        addoninformerv1alpha1.ManagedClusterAddOnInformer
+	CSRControl           clientcert.CSRControl
+	// LeaseSyncInterval is the interval of the addon lease controller to check the leases of the addons.
+	LeaseSyncInterval time.Duration
+	Recorder          events.Recorder
+}
+
+// AddOnControllerFactory creates an addon controller of the agent with the shared clients and informers.
+type AddOnControllerFactory func(controllerContext AddOnControllerContext) (factory.Controller, error)
+
+// AddOnControllerRegistry holds the factories of the addon controllers which are started by the agent
+// once the AddonManagement feature is enabled. The binaries embedding the agent may register their own
+// addon controllers besides the lease and registration controllers.
+type AddOnControllerRegistry struct {
+	lock      sync.Mutex
+	factories map[string]AddOnControllerFactory
+}
+
+// NewAddOnControllerRegistry returns an empty AddOnControllerRegistry.
+func NewAddOnControllerRegistry() *AddOnControllerRegistry {
+	return &AddOnControllerRegistry{factories: map[string]AddOnControllerFactory{}}
+}
+
+// NewDefaultAddOnControllerRegistry returns an AddOnControllerRegistry with the lease and registration
+// controllers.
+func NewDefaultAddOnControllerRegistry() *AddOnControllerRegistry {
+	r := NewAddOnControllerRegistry()
+	r.factories[LeaseControllerName] = func(controllerContext AddOnControllerContext) (factory.Controller, error) {
+		return NewManagedClusterAddOnLeaseController(
+			controllerContext.ClusterName,
+			controllerContext.AddOnClient,
+			controllerContext.AddOnInformer,
+			controllerContext.HubKubeClient.CoordinationV1(),
+			controllerContext.ManagementKubeClient.CoordinationV1(),
+			controllerContext.SpokeKubeClient.CoordinationV1(),
+			controllerContext.LeaseSyncInterval,
+			controllerContext.Recorder,
+		), nil
+	}
+	r.factories[RegistrationControllerName] = func(controllerContext AddOnControllerContext) (factory.Controller, error) {
+		return NewAddOnRegistrationController(
+			controllerContext.ClusterName,
+			controllerContext.AgentName,
+			controllerContext.KubeconfigData,
+			controllerContext.AddOnClient,
+			controllerContext.ManagementKubeClient,
+			controllerContext.SpokeKubeClient,
+			controllerContext.CSRControl,
+			controllerContext.AddOnInformer,
+			controllerContext.Recorder,
+		), nil
+	}
+	return r
+}
+
+// Register adds the factory of an addon controller with the name, the name must be unique.
+func (r *AddOnControllerRegistry) Register(name string, controllerFactory AddOnControllerFactory) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.factories[name]; ok {
+		return fmt.Errorf("addon controller %q is already registered", name)
+	}
+	r.factories[name] = controllerFactory
+	return nil
+}
+
+// NewControllers creates the registered addon controllers in the order of their names.
+func (r *AddOnControllerRegistry) NewControllers(controllerContext AddOnControllerContext) ([]factory.Controller, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	controllers := make([]factory.Controller, 0, len(names))
+	for _, name := range names {
+		controller, err := r.factories[name](controllerContext)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create addon controller %q: %w", name, err)
+		}
+		controllers = append(controllers, controller)
+	}
+	return controllers, nil
+}
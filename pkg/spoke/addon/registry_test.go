@@ -0,0 +1,132 @@
+package addon
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestAddOnController(name string) AddOnControllerFactory {
+	return func(controllerContext AddOnControllerContext) (factory.Controller, error) {
+		return factory.New().WithSync(func(ctx context.Context, syncCtx factory.SyncContext) error {
+			return nil
+		}).ToController(name, controllerContext.Recorder), nil
+	}
+}
+
+func TestAddOnControllerRegistry(t *testing.T) {
+	cases := []struct {
+		name                string
+		registry            func() *AddOnControllerRegistry
+		register            map[string]AddOnControllerFactory
+		expectedRegisterErr string
+		expectedErr         string
+		expectedControllers []string
+	}{
+		{
+			name:                "empty registry",
+			registry:            NewAddOnControllerRegistry,
+			expectedControllers: []string{},
+		},
+		{
+			name:     "default registry",
+			registry: NewDefaultAddOnControllerRegistry,
+			expectedControllers: []string{
+				"ManagedClusterAddOnLeaseController",
+				"AddOnRegistrationController",
+			},
+		},
+		{
+			name:     "register an addon controller",
+			registry: NewDefaultAddOnControllerRegistry,
+			register: map[string]AddOnControllerFactory{
+				"test": newTestAddOnController("TestAddOnController"),
+			},
+			expectedControllers: []string{
+				"ManagedClusterAddOnLeaseController",
+				"AddOnRegistrationController",
+				"TestAddOnController",
+			},
+		},
+		{
+			name:     "register a duplicated addon controller",
+			registry: NewDefaultAddOnControllerRegistry,
+			register: map[string]AddOnControllerFactory{
+				RegistrationControllerName: newTestAddOnController("TestAddOnController"),
+			},
+			expectedRegisterErr: `addon controller "registration" is already registered`,
+			expectedControllers: []string{
+				"ManagedClusterAddOnLeaseController",
+				"AddOnRegistrationController",
+			},
+		},
+		{
+			name:     "failed to create an addon controller",
+			registry: NewAddOnControllerRegistry,
+			register: map[string]AddOnControllerFactory{
+				"test": func(controllerContext AddOnControllerContext) (factory.Controller, error) {
+					return nil, fmt.Errorf("failed")
+				},
+			},
+			expectedErr: `unable to create addon controller "test": failed`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addOnClient := addonfake.NewSimpleClientset()
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, 0)
+			kubeClient := kubefake.NewSimpleClientset()
+			csrControl, err := clientcert.NewCSRControl(
+				kubeinformers.NewSharedInformerFactory(kubeClient, 0).Certificates(), kubeClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			registry := c.registry()
+			for name, f := range c.register {
+				err = registry.Register(name, f)
+				testinghelpers.AssertError(t, err, c.expectedRegisterErr)
+			}
+
+			controllers, err := registry.NewControllers(AddOnControllerContext{
+				ClusterName:          testinghelpers.TestManagedClusterName,
+				AgentName:            "test",
+				HubKubeClient:        kubeClient,
+				ManagementKubeClient: kubeClient,
+				SpokeKubeClient:      kubeClient,
+				AddOnClient:          addOnClient,
+				AddOnInformer:        addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns(),
+				CSRControl:           csrControl,
+				Recorder:             events.NewInMemoryRecorder("test"),
+			})
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if err != nil {
+				return
+			}
+
+			names := []string{}
+			for _, controller := range controllers {
+				names = append(names, controller.Name())
+			}
+			if len(names) != len(c.expectedControllers) {
+				t.Fatalf("expected controllers %v, but got %v", c.expectedControllers, names)
+			}
+			for i := range names {
+				if names[i] != c.expectedControllers[i] {
+					t.Errorf("expected controllers %v, but got %v", c.expectedControllers, names)
+				}
+			}
+		})
+	}
+}
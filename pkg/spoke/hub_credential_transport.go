@@ -0,0 +1,100 @@
+package spoke
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// inMemoryCredentialTransport is the transport of the hub clients once the hub credentials are in memory. It
+// sends the requests with the latest client certificate in the memory store, so that the rotated client
+// certificate takes effect without restarting the agent and without being written into a file.
+type inMemoryCredentialTransport struct {
+	config *rest.Config
+	store  *clientcert.MemorySecretStore
+
+	lock            sync.Mutex
+	resourceVersion string
+	delegate        http.RoundTripper
+}
+
+// newInMemoryHubClientConfig returns a hub client config built from the bootstrap client config without its
+// credentials, which authenticates with the client certificate in the store. The store must contain a valid
+// hub kubeconfig secret already, i.e. the bootstrap is completed.
+func newInMemoryHubClientConfig(bootstrapClientConfig *rest.Config, store *clientcert.MemorySecretStore) (*rest.Config, error) {
+	t := &inMemoryCredentialTransport{
+		config: rest.AnonymousClientConfig(bootstrapClientConfig),
+		store:  store,
+	}
+	if err := t.reload(); err != nil {
+		return nil, err
+	}
+
+	hubClientConfig := rest.CopyConfig(t.config)
+	hubClientConfig.WrapTransport = func(http.RoundTripper) http.RoundTripper {
+		return t
+	}
+	return hubClientConfig, nil
+}
+
+func (t *inMemoryCredentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.transport().RoundTrip(req)
+}
+
+func (t *inMemoryCredentialTransport) WrappedRoundTripper() http.RoundTripper {
+	return t.transport()
+}
+
+// transport returns the transport built with the latest client certificate in the store. The transport is
+// cached, and only rebuilt once the resource version of the secret in the store is changed, so the secret is
+// not copied on every request.
+func (t *inMemoryCredentialTransport) transport() http.RoundTripper {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.delegate != nil && t.store.ResourceVersion() == t.resourceVersion {
+		return t.delegate
+	}
+	if err := t.reload(); err != nil {
+		klog.Errorf("Unable to reload the client certificate for hub in memory: %v", err)
+	}
+	return t.delegate
+}
+
+// reload rebuilds the delegate transport once the secret in the store is changed, the caller must hold the lock
+// except on the initial load.
+func (t *inMemoryCredentialTransport) reload() error {
+	secret, err := t.store.Get(context.TODO())
+	if err != nil {
+		return err
+	}
+	if t.delegate != nil && secret.ResourceVersion == t.resourceVersion {
+		return nil
+	}
+
+	config := rest.CopyConfig(t.config)
+	config.CertData = secret.Data[clientcert.TLSCertFile]
+	config.KeyData = secret.Data[clientcert.TLSKeyFile]
+	if len(config.CertData) == 0 || len(config.KeyData) == 0 {
+		return fmt.Errorf("no client certificate or key in secret %s/%s", secret.Namespace, secret.Name)
+	}
+	delegate, err := rest.TransportFor(config)
+	if err != nil {
+		return err
+	}
+
+	if t.delegate != nil {
+		utilnet.CloseIdleConnectionsFor(t.delegate)
+		klog.Infof("The client certificate for hub in memory is reloaded")
+	}
+	t.delegate = delegate
+	t.resourceVersion = secret.ResourceVersion
+	return nil
+}
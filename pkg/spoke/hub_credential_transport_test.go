@@ -0,0 +1,76 @@
+package spoke
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestNewInMemoryHubClientConfig(t *testing.T) {
+	hubCA := testinghelpers.NewTestCert("hubca", 60*time.Second).Cert
+	bootstrapClientConfig := &rest.Config{
+		Host:            "https://hub.example.com:6443",
+		BearerToken:     "bootstrap-token",
+		TLSClientConfig: rest.TLSClientConfig{CAData: hubCA},
+		QPS:             50,
+	}
+	store := clientcert.NewMemorySecretStore("open-cluster-management-agent", "hub-kubeconfig-secret")
+
+	if _, err := newInMemoryHubClientConfig(bootstrapClientConfig, store); err == nil {
+		t.Fatalf("expect error without the client certificate in store")
+	}
+
+	cert1 := testinghelpers.NewTestCert("system:open-cluster-management:cluster1:agent1", 60*time.Second)
+	secret := &corev1.Secret{Data: map[string][]byte{
+		clientcert.TLSCertFile: cert1.Cert,
+		clientcert.TLSKeyFile:  cert1.Key,
+	}}
+	if err := store.Save(context.TODO(), secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hubClientConfig, err := newInMemoryHubClientConfig(bootstrapClientConfig, store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hubClientConfig.Host != bootstrapClientConfig.Host || !bytes.Equal(hubClientConfig.CAData, hubCA) || hubClientConfig.QPS != 50 {
+		t.Errorf("expect the host, CA and qps of the bootstrap client config are kept, but got %v", hubClientConfig)
+	}
+	if len(hubClientConfig.BearerToken) != 0 || len(hubClientConfig.CertData) != 0 || len(hubClientConfig.KeyData) != 0 {
+		t.Errorf("expect no credentials in the hub client config, but got %v", hubClientConfig)
+	}
+	if hubClientConfig.WrapTransport == nil {
+		t.Fatalf("expect the transport of the hub client config is wrapped")
+	}
+
+	transport := hubClientConfig.WrapTransport(nil).(*inMemoryCredentialTransport)
+	delegate := transport.WrappedRoundTripper()
+	if transport.WrappedRoundTripper() != delegate {
+		t.Errorf("expect the transport is not changed without rotation")
+	}
+
+	// rotate the client certificate
+	cert2 := testinghelpers.NewTestCert("system:open-cluster-management:cluster1:agent1", 60*time.Second)
+	secret, err = store.Get(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secret.Data[clientcert.TLSCertFile] = cert2.Cert
+	secret.Data[clientcert.TLSKeyFile] = cert2.Key
+	if err := store.Save(context.TODO(), secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.WrappedRoundTripper() == delegate {
+		t.Errorf("expect the transport is rebuilt with the rotated client certificate")
+	}
+	if transport.resourceVersion != "2" {
+		t.Errorf("expect resource version 2 is loaded, but got %q", transport.resourceVersion)
+	}
+}
@@ -0,0 +1,122 @@
+package spoke
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+// SpokeKubeconfigReloadPeriod is exposed so that integration tests can crank up the reload speed.
+var SpokeKubeconfigReloadPeriod = 30 * time.Second
+
+// kubeconfigReloader is the transport of the clients of the spoke cluster in the detached mode. It sends
+// the requests with the credentials of the latest content of the spoke kubeconfig file, which is checked
+// at most once per SpokeKubeconfigReloadPeriod, so that the rotated credentials, e.g. the client
+// certificate embedded in a kubeconfig mounted from a secret, take effect without restarting the agent.
+// The certificate and token files referenced by the kubeconfig are reloaded by client-go already.
+type kubeconfigReloader struct {
+	file string
+	host string
+
+	lock        sync.Mutex
+	data        []byte
+	checkedTime time.Time
+	delegate    http.RoundTripper
+}
+
+// newReloadingKubeConfig returns a copy of the config loaded from the kubeconfig file, whose credentials
+// are reloaded once the file changes. The config is returned as it is if its credentials are provided
+// by a plugin, which refreshes them by itself.
+func newReloadingKubeConfig(file string, config *rest.Config) (*rest.Config, error) {
+	if config.ExecProvider != nil || config.AuthProvider != nil {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	delegate, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, err
+	}
+	reloader := &kubeconfigReloader{
+		file:        file,
+		host:        config.Host,
+		data:        data,
+		checkedTime: time.Now(),
+		delegate:    delegate,
+	}
+
+	reloadingConfig := rest.CopyConfig(config)
+	// the credentials are set by the transport of the reloader, the server CA is kept in the config since
+	// the agent reports it to the hub
+	reloadingConfig.BearerToken = ""
+	reloadingConfig.BearerTokenFile = ""
+	reloadingConfig.Username = ""
+	reloadingConfig.Password = ""
+	reloadingConfig.CertFile = ""
+	reloadingConfig.CertData = nil
+	reloadingConfig.KeyFile = ""
+	reloadingConfig.KeyData = nil
+	reloadingConfig.WrapTransport = func(http.RoundTripper) http.RoundTripper {
+		return reloader
+	}
+	return reloadingConfig, nil
+}
+
+func (r *kubeconfigReloader) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.transport().RoundTrip(req)
+}
+
+func (r *kubeconfigReloader) WrappedRoundTripper() http.RoundTripper {
+	return r.transport()
+}
+
+// transport returns the transport built with the latest content of the kubeconfig file.
+func (r *kubeconfigReloader) transport() http.RoundTripper {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if time.Since(r.checkedTime) < SpokeKubeconfigReloadPeriod {
+		return r.delegate
+	}
+	r.checkedTime = time.Now()
+
+	data, err := os.ReadFile(r.file)
+	if err != nil {
+		klog.Errorf("Unable to read the spoke kubeconfig %q: %v", r.file, err)
+		return r.delegate
+	}
+	if bytes.Equal(data, r.data) {
+		return r.delegate
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("" /* leave masterurl as empty */, r.file)
+	if err != nil {
+		klog.Errorf("Unable to reload the spoke kubeconfig %q: %v", r.file, err)
+		return r.delegate
+	}
+	if config.Host != r.host {
+		klog.Warningf("The server of the spoke kubeconfig %q is changed from %q to %q, restart the agent to connect to the new server",
+			r.file, r.host, config.Host)
+	}
+	delegate, err := rest.TransportFor(config)
+	if err != nil {
+		klog.Errorf("Unable to reload the spoke kubeconfig %q: %v", r.file, err)
+		return r.delegate
+	}
+
+	utilnet.CloseIdleConnectionsFor(r.delegate)
+	r.delegate = delegate
+	r.data = data
+	klog.Infof("The spoke kubeconfig %q is reloaded", r.file)
+	return r.delegate
+}
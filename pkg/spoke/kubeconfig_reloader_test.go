@@ -0,0 +1,102 @@
+package spoke
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func writeSpokeKubeconfig(t *testing.T, file, server, token string) {
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: spoke
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+contexts:
+- name: spoke
+  context:
+    cluster: spoke
+    user: agent
+current-context: spoke
+users:
+- name: agent
+  user:
+    token: %s
+`, server, token)
+	if err := os.WriteFile(file, []byte(kubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadingKubeConfig(t *testing.T) {
+	// the credentials in a kubeconfig are loaded only if the server is secure
+	apiServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, err := w.Write([]byte(req.Header.Get("Authorization"))); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer apiServer.Close()
+
+	file := path.Join(t.TempDir(), "kubeconfig")
+	writeSpokeKubeconfig(t, file, apiServer.URL, "token-a")
+
+	originalPeriod := SpokeKubeconfigReloadPeriod
+	defer func() { SpokeKubeconfigReloadPeriod = originalPeriod }()
+	SpokeKubeconfigReloadPeriod = time.Hour
+
+	config, err := clientcmd.BuildConfigFromFlags("", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloadingConfig, err := newReloadingKubeConfig(file, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloadingConfig.BearerToken) != 0 {
+		t.Errorf("expected the credentials removed from the config, but got %q", reloadingConfig.BearerToken)
+	}
+	httpClient, err := rest.HTTPClientFor(reloadingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertAuthorization := func(expected string) {
+		resp, err := httpClient.Get(apiServer.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		actual, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != expected {
+			t.Errorf("expected authorization %q, but got %q", expected, string(actual))
+		}
+	}
+
+	assertAuthorization("Bearer token-a")
+
+	// the file is not reloaded within the reload period
+	writeSpokeKubeconfig(t, file, apiServer.URL, "token-b")
+	assertAuthorization("Bearer token-a")
+
+	SpokeKubeconfigReloadPeriod = 0
+	assertAuthorization("Bearer token-b")
+
+	// the current credentials are kept if the file is broken
+	if err := os.WriteFile(file, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	assertAuthorization("Bearer token-b")
+}
@@ -0,0 +1,134 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/klog/v2"
+)
+
+// clientCertExpiryResyncInterval is the interval to check the remaining lifetime of the client certificate.
+const clientCertExpiryResyncInterval = 5 * time.Minute
+
+// clientCertExpiryController checks the remaining lifetime of the client certificate in the hub kubeconfig
+// secret, and reports it with the ManagedClusterConditionHubClientCertificateValid condition of the
+// ManagedCluster and a metric. The client certificate is rotated once it has less than 20% of its lifetime
+// remaining, so a certificate with less than the threshold of its lifetime remaining means the rotation
+// keeps failing, e.g. the csrs of the agent are not approved. It is reported before the certificate
+// expires, after which the agent is unable to access the hub any more.
+type clientCertExpiryController struct {
+	clusterName      string
+	secretNamespace  string
+	secretName       string
+	threshold        float64
+	hubClusterClient clientset.Interface
+	secretLister     corev1listers.SecretLister
+	// now is the local clock, it is replaced in the unit tests
+	now func() time.Time
+}
+
+// NewClientCertExpiryController creates a new client cert expiry controller on the managed cluster. The
+// condition is false once the client certificate has less than the threshold of its lifetime remaining,
+// and it is not reported if the threshold is zero.
+func NewClientCertExpiryController(
+	clusterName string,
+	hubKubeconfigSecretNamespace, hubKubeconfigSecretName string,
+	threshold float64,
+	hubClusterClient clientset.Interface,
+	spokeSecretInformer corev1informers.SecretInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &clientCertExpiryController{
+		clusterName:      clusterName,
+		secretNamespace:  hubKubeconfigSecretNamespace,
+		secretName:       hubKubeconfigSecretName,
+		threshold:        threshold,
+		hubClusterClient: hubClusterClient,
+		secretLister:     spokeSecretInformer.Lister(),
+		now:              time.Now,
+	}
+
+	return factory.New().
+		WithFilteredEventsInformers(func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			return accessor.GetNamespace() == hubKubeconfigSecretNamespace && accessor.GetName() == hubKubeconfigSecretName
+		}, spokeSecretInformer.Informer()).
+		WithSync(c.sync).
+		ResyncEvery(clientCertExpiryResyncInterval).
+		ToController("ClientCertExpiryController", recorder)
+}
+
+func (c *clientCertExpiryController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	secret, err := c.secretLister.Secrets(c.secretNamespace).Get(c.secretName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// the hub kubeconfig of the token and awsirsa registration drivers has no client certificate
+	certData, ok := secret.Data[clientcert.TLSCertFile]
+	if !ok {
+		return nil
+	}
+	certs, err := certutil.ParseCertsPEM(certData)
+	if err != nil {
+		return fmt.Errorf("unable to parse the client certificate in secret %q: %w", c.secretNamespace+"/"+c.secretName, err)
+	}
+
+	lifetime := certs[0].NotAfter.Sub(certs[0].NotBefore)
+	remaining := certs[0].NotAfter.Sub(c.now())
+	hubClientCertificateRemaining.WithLabelValues(c.clusterName).Set(remaining.Seconds())
+	if c.threshold <= 0 || lifetime <= 0 {
+		return nil
+	}
+
+	cond := metav1.Condition{
+		Type:    helpers.ManagedClusterConditionHubClientCertificateValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  "HubClientCertificateValid",
+		Message: fmt.Sprintf("The client certificate for the hub expires at %s.", certs[0].NotAfter.UTC().Format(time.RFC3339)),
+	}
+	switch {
+	case remaining <= 0:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "HubClientCertificateExpired"
+		cond.Message = fmt.Sprintf("The client certificate for the hub expired at %s.", certs[0].NotAfter.UTC().Format(time.RFC3339))
+	case remaining.Seconds()/lifetime.Seconds() < c.threshold:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "HubClientCertificateExpiring"
+		cond.Message = fmt.Sprintf("The client certificate for the hub expires in %s with %.1f%% of its lifetime remaining, "+
+			"check why it is not rotated, e.g. the csrs of the agent are not approved.",
+			remaining.Round(time.Second), remaining.Seconds()/lifetime.Seconds()*100)
+	}
+	if cond.Status == metav1.ConditionFalse {
+		klog.Warningf("Managed cluster %q: %s", c.clusterName, cond.Message)
+		syncCtx.Recorder().Warningf(cond.Reason, cond.Message)
+	}
+
+	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName,
+		helpers.UpdateManagedClusterConditionFn(cond))
+	if err != nil {
+		return fmt.Errorf("unable to update status of managed cluster %q: %w", c.clusterName, err)
+	}
+	if updated {
+		klog.V(4).Infof("The hub client certificate valid condition of managed cluster %q is updated to %q", c.clusterName, cond.Status)
+	}
+	return nil
+}
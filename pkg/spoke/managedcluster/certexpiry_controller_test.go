@@ -0,0 +1,168 @@
+package managedcluster
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestClientCertExpirySync(t *testing.T) {
+	notBefore := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(100 * time.Hour)
+	secretWithCert := testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "", nil, map[string][]byte{
+		clientcert.TLSCertFile: newTestCertWithValidity(t, notBefore, notAfter),
+	})
+
+	cases := []struct {
+		name              string
+		secret            *corev1.Secret
+		threshold         float64
+		now               time.Time
+		expectedRemaining float64
+		expectedCondition *metav1.Condition
+	}{
+		{
+			name:      "no hub kubeconfig secret",
+			threshold: 0.1,
+			now:       notBefore,
+		},
+		{
+			name:      "no client certificate",
+			secret:    testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "", nil, map[string][]byte{}),
+			threshold: 0.1,
+			now:       notBefore,
+		},
+		{
+			name:              "client certificate is valid",
+			secret:            secretWithCert,
+			threshold:         0.1,
+			now:               notBefore.Add(50 * time.Hour),
+			expectedRemaining: 50 * 3600,
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionHubClientCertificateValid,
+				Status:  metav1.ConditionTrue,
+				Reason:  "HubClientCertificateValid",
+				Message: "The client certificate for the hub expires at 2023-01-05T04:00:00Z.",
+			},
+		},
+		{
+			name:              "client certificate is expiring",
+			secret:            secretWithCert,
+			threshold:         0.1,
+			now:               notBefore.Add(95 * time.Hour),
+			expectedRemaining: 5 * 3600,
+			expectedCondition: &metav1.Condition{
+				Type:   helpers.ManagedClusterConditionHubClientCertificateValid,
+				Status: metav1.ConditionFalse,
+				Reason: "HubClientCertificateExpiring",
+				Message: "The client certificate for the hub expires in 5h0m0s with 5.0% of its lifetime remaining, " +
+					"check why it is not rotated, e.g. the csrs of the agent are not approved.",
+			},
+		},
+		{
+			name:              "client certificate is expired",
+			secret:            secretWithCert,
+			threshold:         0.1,
+			now:               notAfter.Add(time.Hour),
+			expectedRemaining: -3600,
+			expectedCondition: &metav1.Condition{
+				Type:    helpers.ManagedClusterConditionHubClientCertificateValid,
+				Status:  metav1.ConditionFalse,
+				Reason:  "HubClientCertificateExpired",
+				Message: "The client certificate for the hub expired at 2023-01-05T04:00:00Z.",
+			},
+		},
+		{
+			name:              "condition is disabled",
+			secret:            secretWithCert,
+			now:               notBefore.Add(95 * time.Hour),
+			expectedRemaining: 5 * 3600,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hubClientCertificateRemaining.Reset()
+
+			clusterClient := clusterfake.NewSimpleClientset(testinghelpers.NewAcceptedManagedCluster())
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), time.Minute*10)
+			secretInformer := kubeInformerFactory.Core().V1().Secrets()
+			if c.secret != nil {
+				if err := secretInformer.Informer().GetStore().Add(c.secret); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := &clientCertExpiryController{
+				clusterName:      testinghelpers.TestManagedClusterName,
+				secretNamespace:  testNamespace,
+				secretName:       testSecretName,
+				threshold:        c.threshold,
+				hubClusterClient: clusterClient,
+				secretLister:     secretInformer.Lister(),
+				now:              func() time.Time { return c.now },
+			}
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+			testinghelpers.AssertError(t, err, "")
+
+			remaining, err := testutil.GetGaugeMetricValue(hubClientCertificateRemaining.WithLabelValues(testinghelpers.TestManagedClusterName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if remaining != c.expectedRemaining {
+				t.Errorf("expected remaining seconds %v, but got %v", c.expectedRemaining, remaining)
+			}
+
+			actions := clusterClient.Actions()
+			if c.expectedCondition == nil {
+				testinghelpers.AssertNoActions(t, actions)
+				return
+			}
+			testinghelpers.AssertActions(t, actions, "get", "patch")
+			managedCluster := &clusterv1.ManagedCluster{}
+			if err := json.Unmarshal(actions[1].(clienttesting.PatchAction).GetPatch(), managedCluster); err != nil {
+				t.Fatal(err)
+			}
+			testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, *c.expectedCondition)
+		})
+	}
+}
+
+func newTestCertWithValidity(t *testing.T, notBefore, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		Subject:      pkix.Name{CommonName: testinghelpers.TestManagedClusterName},
+		SerialNumber: big.NewInt(1),
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,134 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/klog/v2"
+)
+
+// maxRecentClientCertificates is the number of the recent client certificates kept in the history.
+const maxRecentClientCertificates = 5
+
+// clientCertRotationController records the client certificates in the hub kubeconfig secret with the
+// AgentClientCertificateRotationsAnnotation of the ManagedCluster on hub once they are rotated, so that the
+// hub is able to report the ages of the client certificates of a fleet. The hub kubeconfig without a client
+// certificate, e.g. of the token registration driver, is not recorded.
+type clientCertRotationController struct {
+	clusterName      string
+	secretNamespace  string
+	secretName       string
+	hubClusterClient clientset.Interface
+	hubClusterLister clusterv1listers.ManagedClusterLister
+	secretLister     corev1listers.SecretLister
+}
+
+// NewClientCertRotationController creates a new client cert rotation controller on the managed cluster.
+func NewClientCertRotationController(
+	clusterName string,
+	hubKubeconfigSecretNamespace, hubKubeconfigSecretName string,
+	hubClusterClient clientset.Interface,
+	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	spokeSecretInformer corev1informers.SecretInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &clientCertRotationController{
+		clusterName:      clusterName,
+		secretNamespace:  hubKubeconfigSecretNamespace,
+		secretName:       hubKubeconfigSecretName,
+		hubClusterClient: hubClusterClient,
+		hubClusterLister: hubClusterInformer.Lister(),
+		secretLister:     spokeSecretInformer.Lister(),
+	}
+
+	return factory.New().
+		WithInformers(hubClusterInformer.Informer()).
+		WithFilteredEventsInformers(func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			return accessor.GetNamespace() == hubKubeconfigSecretNamespace && accessor.GetName() == hubKubeconfigSecretName
+		}, spokeSecretInformer.Informer()).
+		WithSync(c.sync).
+		ToController("ClientCertRotationController", recorder)
+}
+
+func (c *clientCertRotationController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	secret, err := c.secretLister.Secrets(c.secretNamespace).Get(c.secretName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	certData, ok := secret.Data[clientcert.TLSCertFile]
+	if !ok {
+		return nil
+	}
+	certs, err := certutil.ParseCertsPEM(certData)
+	if err != nil {
+		return fmt.Errorf("unable to parse the client certificate in secret %q: %w", c.secretNamespace+"/"+c.secretName, err)
+	}
+	current := helpers.ClientCertificate{
+		SerialNumber: certs[0].SerialNumber.String(),
+		NotBefore:    metav1.NewTime(certs[0].NotBefore),
+		NotAfter:     metav1.NewTime(certs[0].NotAfter),
+	}
+
+	managedCluster, err := c.hubClusterLister.Get(c.clusterName)
+	if errors.IsNotFound(err) {
+		// the managed cluster is not created yet, the controller will be triggered once it is created
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get managed cluster %q from hub: %w", c.clusterName, err)
+	}
+
+	rotations := helpers.ClientCertificateRotations{}
+	if value, ok := managedCluster.Annotations[helpers.AgentClientCertificateRotationsAnnotation]; ok {
+		// the history is started over if it is corrupted
+		if err := json.Unmarshal([]byte(value), &rotations); err != nil {
+			klog.Warningf("Unable to parse the client certificate rotations of managed cluster %q: %v", c.clusterName, err)
+			rotations = helpers.ClientCertificateRotations{}
+		}
+	}
+	if n := len(rotations.Recent); n != 0 && rotations.Recent[n-1].SerialNumber == current.SerialNumber {
+		return nil
+	}
+	rotations.Count++
+	rotations.Recent = append(rotations.Recent, current)
+	if n := len(rotations.Recent); n > maxRecentClientCertificates {
+		rotations.Recent = rotations.Recent[n-maxRecentClientCertificates:]
+	}
+	value, err := json.Marshal(rotations)
+	if err != nil {
+		return err
+	}
+
+	managedCluster = managedCluster.DeepCopy()
+	if managedCluster.Annotations == nil {
+		managedCluster.Annotations = map[string]string{}
+	}
+	managedCluster.Annotations[helpers.AgentClientCertificateRotationsAnnotation] = string(value)
+	if _, err := c.hubClusterClient.ClusterV1().ManagedClusters().Update(ctx, managedCluster, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to update the client certificate rotations of managed cluster %q on hub: %w", c.clusterName, err)
+	}
+	klog.V(4).Infof("The client certificate %s of managed cluster %q is recorded", current.SerialNumber, c.clusterName)
+	return nil
+}
@@ -0,0 +1,156 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestClientCertRotationSync(t *testing.T) {
+	// the serial number of the test certificates is 1
+	secretWithCert := testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "",
+		testinghelpers.NewTestCert(testinghelpers.TestManagedClusterName, time.Hour), map[string][]byte{})
+	rotations := func(count int, serialNumbers ...string) string {
+		history := helpers.ClientCertificateRotations{Count: count}
+		for _, serialNumber := range serialNumbers {
+			history.Recent = append(history.Recent, helpers.ClientCertificate{SerialNumber: serialNumber})
+		}
+		data, err := json.Marshal(history)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	cases := []struct {
+		name                  string
+		secret                *corev1.Secret
+		annotations           map[string]string
+		noManagedCluster      bool
+		expectedCount         int
+		expectedSerialNumbers []string
+	}{
+		{
+			name: "no hub kubeconfig secret",
+		},
+		{
+			name:   "no client certificate",
+			secret: testinghelpers.NewHubKubeconfigSecret(testNamespace, testSecretName, "", nil, map[string][]byte{}),
+		},
+		{
+			name:             "managed cluster is not created",
+			secret:           secretWithCert,
+			noManagedCluster: true,
+		},
+		{
+			name:                  "record the first client certificate",
+			secret:                secretWithCert,
+			expectedCount:         1,
+			expectedSerialNumbers: []string{"1"},
+		},
+		{
+			name:        "client certificate is recorded",
+			secret:      secretWithCert,
+			annotations: map[string]string{helpers.AgentClientCertificateRotationsAnnotation: rotations(2, "2", "1")},
+		},
+		{
+			name:                  "record the rotated client certificate",
+			secret:                secretWithCert,
+			annotations:           map[string]string{helpers.AgentClientCertificateRotationsAnnotation: rotations(2, "3", "2")},
+			expectedCount:         3,
+			expectedSerialNumbers: []string{"3", "2", "1"},
+		},
+		{
+			name:   "keep the recent client certificates",
+			secret: secretWithCert,
+			annotations: map[string]string{
+				helpers.AgentClientCertificateRotationsAnnotation: rotations(10, "7", "6", "5", "4", "3"),
+			},
+			expectedCount:         11,
+			expectedSerialNumbers: []string{"6", "5", "4", "3", "1"},
+		},
+		{
+			name:                  "start over the corrupted history",
+			secret:                secretWithCert,
+			annotations:           map[string]string{helpers.AgentClientCertificateRotationsAnnotation: "invalid"},
+			expectedCount:         1,
+			expectedSerialNumbers: []string{"1"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusters := []runtime.Object{}
+			if !c.noManagedCluster {
+				managedCluster := testinghelpers.NewAcceptedManagedCluster()
+				managedCluster.Annotations = c.annotations
+				clusters = append(clusters, managedCluster)
+			}
+			clusterClient := clusterfake.NewSimpleClientset(clusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterInformer := clusterInformerFactory.Cluster().V1().ManagedClusters()
+			for _, cluster := range clusters {
+				if err := clusterInformer.Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), time.Minute*10)
+			secretInformer := kubeInformerFactory.Core().V1().Secrets()
+			if c.secret != nil {
+				if err := secretInformer.Informer().GetStore().Add(c.secret); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			syncCtx := testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName)
+			ctrl := NewClientCertRotationController(testinghelpers.TestManagedClusterName, testNamespace, testSecretName,
+				clusterClient, clusterInformer, secretInformer, syncCtx.Recorder())
+			if err := ctrl.Sync(context.TODO(), syncCtx); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			actions := clusterClient.Actions()
+			if c.expectedCount == 0 {
+				testinghelpers.AssertNoActions(t, actions)
+				return
+			}
+			testinghelpers.AssertActions(t, actions, "update")
+			managedCluster := actions[0].(clienttesting.UpdateActionImpl).Object.(*clusterv1.ManagedCluster)
+			actual := helpers.ClientCertificateRotations{}
+			if err := json.Unmarshal([]byte(managedCluster.Annotations[helpers.AgentClientCertificateRotationsAnnotation]), &actual); err != nil {
+				t.Fatal(err)
+			}
+			if actual.Count != c.expectedCount {
+				t.Errorf("expected count %d, but got %d", c.expectedCount, actual.Count)
+			}
+			actualSerialNumbers := []string{}
+			for _, cert := range actual.Recent {
+				actualSerialNumbers = append(actualSerialNumbers, cert.SerialNumber)
+			}
+			if fmt.Sprint(actualSerialNumbers) != fmt.Sprint(c.expectedSerialNumbers) {
+				t.Errorf("expected serial numbers %v, but got %v", c.expectedSerialNumbers, actualSerialNumbers)
+			}
+			// the validity period is truncated to seconds, and the test certificate is signed by a ca created
+			// before it, so the period may be a second longer than the duration of the certificate
+			current := actual.Recent[len(actual.Recent)-1]
+			if period := current.NotAfter.Sub(current.NotBefore.Time); period < time.Hour || period > time.Hour+time.Second {
+				t.Errorf("expected the validity period of the current client certificate, but got %v", current)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"k8s.io/apimachinery/pkg/selection"
 	"sort"
+	"time"
 
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
@@ -18,10 +19,13 @@ import (
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/klog/v2"
 )
 
@@ -34,6 +38,9 @@ type managedClusterClaimController struct {
 	hubClusterLister       clusterv1listers.ManagedClusterLister
 	claimLister            clusterv1alpha1listers.ClusterClaimLister
 	maxCustomClusterClaims int
+	// wellKnownClaims synthesizes the well-known claims which are not created on the managed cluster,
+	// no claim is synthesized if it is nil.
+	wellKnownClaims *wellKnownClaimProvider
 }
 
 // NewManagedClusterClaimController creates a new managed cluster claim controller on the managed cluster.
@@ -43,6 +50,8 @@ func NewManagedClusterClaimController(
 	hubClusterClient clientset.Interface,
 	hubManagedClusterInformer clusterv1informer.ManagedClusterInformer,
 	claimInformer clusterv1alpha1informer.ClusterClaimInformer,
+	discoveryClient discovery.DiscoveryInterface,
+	nodeInformer corev1informers.NodeInformer,
 	recorder events.Recorder) factory.Controller {
 	c := &managedClusterClaimController{
 		clusterName:            clusterName,
@@ -50,6 +59,11 @@ func NewManagedClusterClaimController(
 		hubClusterClient:       hubClusterClient,
 		hubClusterLister:       hubManagedClusterInformer.Lister(),
 		claimLister:            claimInformer.Lister(),
+		wellKnownClaims: &wellKnownClaimProvider{
+			discoveryClient: discoveryClient,
+			nodeLister:      nodeInformer.Lister(),
+			now:             time.Now,
+		},
 	}
 
 	return factory.New().
@@ -59,6 +73,9 @@ func NewManagedClusterClaimController(
 			return accessor.GetName()
 		}, hubManagedClusterInformer.Informer()).
 		WithSync(c.sync).
+		// the well-known claims are resynced periodically instead of watching the nodes, whose status
+		// is updated frequently
+		ResyncEvery(10*time.Minute).
 		ToController("ClusterClaimController", recorder)
 }
 
@@ -94,7 +111,10 @@ func (c managedClusterClaimController) exposeClaims(ctx context.Context, syncCtx
 		return fmt.Errorf("unable to list cluster claims: %w", err)
 	}
 
-	reservedClaimNames := sets.NewString(clusterv1alpha1.ReservedClusterClaimNames[:]...)
+	// the well-known claims are given the same priority as the reserved claims, so that they are
+	// never truncated
+	reservedClaimNames := sets.NewString(clusterv1alpha1.ReservedClusterClaimNames[:]...).
+		Insert(helpers.WellKnownClusterClaimNames...)
 	for _, clusterClaim := range clusterClaims {
 		managedClusterClaim := clusterv1.ManagedClusterClaim{
 			Name:  clusterClaim.Name,
@@ -107,6 +127,8 @@ func (c managedClusterClaimController) exposeClaims(ctx context.Context, syncCtx
 		customClaims = append(customClaims, managedClusterClaim)
 	}
 
+	reservedClaims = append(reservedClaims, c.synthesizeWellKnownClaims(ctx)...)
+
 	// sort claims by name
 	sort.SliceStable(reservedClaims, func(i, j int) bool {
 		return reservedClaims[i].Name < reservedClaims[j].Name
@@ -141,6 +163,27 @@ func (c managedClusterClaimController) exposeClaims(ctx context.Context, syncCtx
 	return nil
 }
 
+// synthesizeWellKnownClaims returns the well-known claims which are not created on the managed cluster,
+// a claim created on the managed cluster always takes precedence, even if it is spoke-only. A claim which
+// cannot be synthesized is skipped, so the claims created on the managed cluster are always exposed.
+func (c managedClusterClaimController) synthesizeWellKnownClaims(ctx context.Context) []clusterv1.ManagedClusterClaim {
+	if c.wellKnownClaims == nil {
+		return nil
+	}
+
+	synthesized := []clusterv1.ManagedClusterClaim{}
+	for _, claim := range c.wellKnownClaims.claims(ctx) {
+		_, err := c.claimLister.Get(claim.Name)
+		switch {
+		case errors.IsNotFound(err):
+			synthesized = append(synthesized, claim)
+		case err != nil:
+			klog.Warningf("Unable to get cluster claim %q to synthesize it: %v", claim.Name, err)
+		}
+	}
+	return synthesized
+}
+
 func updateClusterClaimsFn(status clusterv1.ManagedClusterStatus) helpers.UpdateManagedClusterStatusFunc {
 	return func(oldStatus *clusterv1.ManagedClusterStatus) error {
 		oldStatus.ClusterClaims = status.ClusterClaims
@@ -0,0 +1,170 @@
+package managedcluster
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// serverURLProbeTimeout is the timeout of a single probe against a spoke external server URL.
+var serverURLProbeTimeout = 10 * time.Second
+
+// serverURLProber checks whether the server URL is reachable with the given CA bundle.
+type serverURLProber func(ctx context.Context, serverURL string, caBundle []byte) error
+
+// managedClusterClientConfigController probes the spoke external server URLs periodically and keeps
+// the ManagedClusterClientConfigs of the ManagedCluster on hub ordered by reachability. Client configs
+// whose URL is reachable are put ahead of the unreachable ones, so consumers on the hub, which usually
+// use the first client config, always try a healthy endpoint first.
+type managedClusterClientConfigController struct {
+	clusterName             string
+	spokeExternalServerURLs []string
+	spokeCABundle           []byte
+	hubClusterClient        clientset.Interface
+	hubClusterLister        clusterv1listers.ManagedClusterLister
+	probe                   serverURLProber
+}
+
+// NewManagedClusterClientConfigController creates a new managed cluster client config controller on the managed cluster.
+func NewManagedClusterClientConfigController(
+	clusterName string,
+	spokeExternalServerURLs []string,
+	spokeCABundle []byte,
+	hubClusterClient clientset.Interface,
+	hubManagedClusterInformer clusterv1informer.ManagedClusterInformer,
+	probePeriod time.Duration,
+	recorder events.Recorder) factory.Controller {
+	c := &managedClusterClientConfigController{
+		clusterName:             clusterName,
+		spokeExternalServerURLs: spokeExternalServerURLs,
+		spokeCABundle:           spokeCABundle,
+		hubClusterClient:        hubClusterClient,
+		hubClusterLister:        hubManagedClusterInformer.Lister(),
+		probe:                   probeServerURL,
+	}
+
+	return factory.New().
+		WithInformers(hubManagedClusterInformer.Informer()).
+		WithSync(c.sync).
+		ResyncEvery(probePeriod).
+		ToController("ManagedClusterClientConfigController", recorder)
+}
+
+func (c *managedClusterClientConfigController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	if len(c.spokeExternalServerURLs) == 0 {
+		return nil
+	}
+
+	managedCluster, err := c.hubClusterLister.Get(c.clusterName)
+	if err != nil {
+		return fmt.Errorf("unable to get managed cluster with name %q from hub: %w", c.clusterName, err)
+	}
+
+	isSpokeExternalServerURL := map[string]bool{}
+	for _, serverURL := range c.spokeExternalServerURLs {
+		isSpokeExternalServerURL[serverURL] = true
+	}
+
+	// probe the client configs of the spoke external server URLs, the other client configs are
+	// not managed by the agent and are kept as they are.
+	var healthy, unhealthy, unmanaged []clusterv1.ClientConfig
+	unhealthyURLs := []string{}
+	for _, config := range managedCluster.Spec.ManagedClusterClientConfigs {
+		if !isSpokeExternalServerURL[config.URL] {
+			unmanaged = append(unmanaged, config)
+			continue
+		}
+
+		caBundle := config.CABundle
+		if len(caBundle) == 0 {
+			caBundle = c.spokeCABundle
+		}
+		if err := c.probe(ctx, config.URL, caBundle); err != nil {
+			klog.V(4).Infof("Spoke external server URL %q is unreachable: %v", config.URL, err)
+			unhealthy = append(unhealthy, config)
+			unhealthyURLs = append(unhealthyURLs, config.URL)
+			continue
+		}
+		healthy = append(healthy, config)
+	}
+
+	clientConfigs := append(append(healthy, unhealthy...), unmanaged...)
+	if equality.Semantic.DeepEqual(managedCluster.Spec.ManagedClusterClientConfigs, clientConfigs) {
+		return nil
+	}
+
+	clusterCopy := managedCluster.DeepCopy()
+	clusterCopy.Spec.ManagedClusterClientConfigs = clientConfigs
+	if _, err := c.hubClusterClient.ClusterV1().ManagedClusters().Update(ctx, clusterCopy, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to update ManagedClusterClientConfigs of managed cluster %q in hub: %w", c.clusterName, err)
+	}
+
+	syncCtx.Recorder().Eventf("ManagedClusterClientConfigsReordered",
+		"The client configs of managed cluster %q are reordered by reachability, unreachable server URLs: [%s]",
+		c.clusterName, strings.Join(unhealthyURLs, ", "))
+	return nil
+}
+
+// probeServerURL sends a request to the healthz endpoint of the server URL. The probe completes a full
+// TLS handshake, in which the server sends its whole certificate chain, and reads the response body, so
+// an endpoint on a path that drops large packets (e.g. because of an MTU mismatch) is reported as
+// unreachable even though a TCP connection can be established.
+func probeServerURL(ctx context.Context, serverURL string, caBundle []byte) error {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if len(caBundle) > 0 {
+		pool := x509.NewCertPool()
+		if ok := pool.AppendCertsFromPEM(caBundle); !ok {
+			return fmt.Errorf("the ca bundle of %q is invalid", serverURL)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+		Timeout: serverURLProbeTimeout,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(serverURL, "/")+"/healthz", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+
+	// the healthz endpoint may be forbidden for anonymous users, which still means the server is reachable
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
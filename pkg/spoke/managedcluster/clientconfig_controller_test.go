@@ -0,0 +1,175 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestSyncClientConfigs(t *testing.T) {
+	newManagedCluster := func(urls ...string) *clusterv1.ManagedCluster {
+		managedCluster := testinghelpers.NewAcceptedManagedCluster()
+		for _, url := range urls {
+			managedCluster.Spec.ManagedClusterClientConfigs = append(managedCluster.Spec.ManagedClusterClientConfigs,
+				clusterv1.ClientConfig{URL: url, CABundle: []byte("testcabundle")})
+		}
+		return managedCluster
+	}
+
+	cases := []struct {
+		name                    string
+		startingObjects         []runtime.Object
+		spokeExternalServerURLs []string
+		unreachableURLs         []string
+		validateActions         func(t *testing.T, actions []clienttesting.Action)
+		expectedErr             string
+	}{
+		{
+			name:                    "no managed cluster",
+			spokeExternalServerURLs: []string{"https://127.0.0.1:6443"},
+			validateActions:         testinghelpers.AssertNoActions,
+			expectedErr:             "unable to get managed cluster with name \"testmanagedcluster\" from hub: managedcluster.cluster.open-cluster-management.io \"testmanagedcluster\" not found",
+		},
+		{
+			name:                    "all server urls are reachable",
+			startingObjects:         []runtime.Object{newManagedCluster("https://127.0.0.1:6443", "https://127.0.0.2:6443")},
+			spokeExternalServerURLs: []string{"https://127.0.0.1:6443", "https://127.0.0.2:6443"},
+			validateActions:         testinghelpers.AssertNoActions,
+		},
+		{
+			name:                    "unreachable server url is moved behind",
+			startingObjects:         []runtime.Object{newManagedCluster("https://127.0.0.1:6443", "https://127.0.0.2:6443")},
+			spokeExternalServerURLs: []string{"https://127.0.0.1:6443", "https://127.0.0.2:6443"},
+			unreachableURLs:         []string{"https://127.0.0.1:6443"},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				actual := actions[0].(clienttesting.UpdateActionImpl).Object
+				testinghelpers.AssertManagedClusterClientConfigs(t,
+					actual.(*clusterv1.ManagedCluster).Spec.ManagedClusterClientConfigs,
+					[]clusterv1.ClientConfig{
+						{URL: "https://127.0.0.2:6443", CABundle: []byte("testcabundle")},
+						{URL: "https://127.0.0.1:6443", CABundle: []byte("testcabundle")},
+					})
+			},
+		},
+		{
+			name:                    "client configs not managed by agent are kept",
+			startingObjects:         []runtime.Object{newManagedCluster("https://127.0.0.1:6443", "https://127.0.0.3:6443", "https://127.0.0.2:6443")},
+			spokeExternalServerURLs: []string{"https://127.0.0.1:6443", "https://127.0.0.2:6443"},
+			unreachableURLs:         []string{"https://127.0.0.3:6443"},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "update")
+				actual := actions[0].(clienttesting.UpdateActionImpl).Object
+				testinghelpers.AssertManagedClusterClientConfigs(t,
+					actual.(*clusterv1.ManagedCluster).Spec.ManagedClusterClientConfigs,
+					[]clusterv1.ClientConfig{
+						{URL: "https://127.0.0.1:6443", CABundle: []byte("testcabundle")},
+						{URL: "https://127.0.0.2:6443", CABundle: []byte("testcabundle")},
+						{URL: "https://127.0.0.3:6443", CABundle: []byte("testcabundle")},
+					})
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.startingObjects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.startingObjects {
+				if err := clusterStore.Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctrl := managedClusterClientConfigController{
+				clusterName:             testinghelpers.TestManagedClusterName,
+				spokeExternalServerURLs: c.spokeExternalServerURLs,
+				hubClusterClient:        clusterClient,
+				hubClusterLister:        clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				probe: func(ctx context.Context, serverURL string, caBundle []byte) error {
+					for _, url := range c.unreachableURLs {
+						if url == serverURL {
+							return fmt.Errorf("unreachable")
+						}
+					}
+					return nil
+				},
+			}
+
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+			testinghelpers.AssertError(t, syncErr, c.expectedErr)
+
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
+
+func TestProbeServerURL(t *testing.T) {
+	forbiddenServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer forbiddenServer.Close()
+
+	unavailableServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailableServer.Close()
+
+	caBundle := func(server *httptest.Server) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	}
+
+	cases := []struct {
+		name        string
+		serverURL   string
+		caBundle    []byte
+		expectedErr bool
+	}{
+		{
+			name:      "reachable server",
+			serverURL: forbiddenServer.URL,
+			caBundle:  caBundle(forbiddenServer),
+		},
+		{
+			name:        "untrusted server",
+			serverURL:   forbiddenServer.URL,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid ca bundle",
+			serverURL:   forbiddenServer.URL,
+			caBundle:    []byte("invalid"),
+			expectedErr: true,
+		},
+		{
+			name:        "unavailable server",
+			serverURL:   unavailableServer.URL,
+			caBundle:    caBundle(unavailableServer),
+			expectedErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := probeServerURL(context.TODO(), c.serverURL, c.caBundle)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but got nil")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
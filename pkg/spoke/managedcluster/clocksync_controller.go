@@ -0,0 +1,130 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// clockSkewThreshold is the max skew between the clocks of the managed cluster and the hub. The
+	// lease of the managed cluster is renewed every 60 seconds by default, a larger skew may make the
+	// hub consider the managed cluster unavailable.
+	clockSkewThreshold = 30 * time.Second
+
+	// clockSyncResyncInterval is the interval to check the clock skew.
+	clockSyncResyncInterval = 5 * time.Minute
+)
+
+// clockSyncController compares the clock of the agent with the Date header of the responses of the
+// hub kube-apiserver, and reports the result with the ManagedClusterConditionClockSynced condition
+// of the ManagedCluster. A skewed clock silently breaks the lease based availability of the managed
+// cluster and the validity of its client certificates.
+type clockSyncController struct {
+	clusterName      string
+	hubClusterClient clientset.Interface
+	hubHTTPClient    *http.Client
+	hubHost          string
+	// now is the local clock, it is replaced in the unit tests
+	now func() time.Time
+}
+
+// NewClockSyncController creates a new clock sync controller on the managed cluster. The hubHTTPClient
+// and hubHost are used to send requests to the hub kube-apiserver.
+func NewClockSyncController(
+	clusterName string,
+	hubClusterClient clientset.Interface,
+	hubHTTPClient *http.Client,
+	hubHost string,
+	recorder events.Recorder) factory.Controller {
+	c := &clockSyncController{
+		clusterName:      clusterName,
+		hubClusterClient: hubClusterClient,
+		hubHTTPClient:    hubHTTPClient,
+		hubHost:          hubHost,
+		now:              time.Now,
+	}
+
+	return factory.New().
+		WithSync(c.sync).
+		ResyncEvery(clockSyncResyncInterval).
+		ToController("ClockSyncController", recorder)
+}
+
+func (c *clockSyncController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	skew, err := c.clockSkew(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get the clock skew between managed cluster %q and hub: %w", c.clusterName, err)
+	}
+
+	cond := metav1.Condition{
+		Type:    helpers.ManagedClusterConditionClockSynced,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ManagedClusterClockSynced",
+		Message: "The clock of the managed cluster is synced with the hub.",
+	}
+	if skew > clockSkewThreshold || skew < -clockSkewThreshold {
+		direction := "ahead of"
+		if skew < 0 {
+			direction, skew = "behind", -skew
+		}
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "ManagedClusterClockOutOfSync"
+		cond.Message = fmt.Sprintf("The clock of the managed cluster is %s %s the hub, which exceeds %s.",
+			skew.Round(time.Second), direction, clockSkewThreshold)
+		klog.Warningf("Managed cluster %q: %s", c.clusterName, cond.Message)
+		syncCtx.Recorder().Warningf("ManagedClusterClockOutOfSync", cond.Message)
+	}
+
+	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName,
+		helpers.UpdateManagedClusterConditionFn(cond))
+	if err != nil {
+		return fmt.Errorf("unable to update status of managed cluster %q: %w", c.clusterName, err)
+	}
+	if updated {
+		klog.V(4).Infof("The clock synced condition of managed cluster %q is updated to %q", c.clusterName, cond.Status)
+	}
+	return nil
+}
+
+// clockSkew returns the local time minus the time of the hub. The Date header is in seconds, and the
+// response is assumed to be sent at the middle of the round trip, so the skew is accurate to about a
+// second plus half of the round trip.
+func (c *clockSyncController) clockSkew(ctx context.Context) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.hubHost, "/")+"/version", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	start := c.now()
+	resp, err := c.hubHTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	end := c.now()
+
+	// the Date header is set regardless of the status code of the response
+	date := resp.Header.Get("Date")
+	if len(date) == 0 {
+		return 0, fmt.Errorf("the response of hub has no Date header")
+	}
+	hubTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Date header %q: %w", date, err)
+	}
+
+	localTime := start.Add(end.Sub(start) / 2)
+	return localTime.Sub(hubTime), nil
+}
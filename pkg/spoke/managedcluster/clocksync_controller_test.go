@@ -0,0 +1,108 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestClockSyncSync(t *testing.T) {
+	hubTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name              string
+		localTime         time.Time
+		noDateHeader      bool
+		expectedErr       string
+		expectedCondition metav1.Condition
+	}{
+		{
+			name:      "clock is synced",
+			localTime: hubTime.Add(10 * time.Second),
+			expectedCondition: metav1.Condition{
+				Type:    helpers.ManagedClusterConditionClockSynced,
+				Status:  metav1.ConditionTrue,
+				Reason:  "ManagedClusterClockSynced",
+				Message: "The clock of the managed cluster is synced with the hub.",
+			},
+		},
+		{
+			name:      "clock is ahead of hub",
+			localTime: hubTime.Add(2 * time.Minute),
+			expectedCondition: metav1.Condition{
+				Type:    helpers.ManagedClusterConditionClockSynced,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ManagedClusterClockOutOfSync",
+				Message: "The clock of the managed cluster is 2m0s ahead of the hub, which exceeds 30s.",
+			},
+		},
+		{
+			name:      "clock is behind hub",
+			localTime: hubTime.Add(-time.Hour),
+			expectedCondition: metav1.Condition{
+				Type:    helpers.ManagedClusterConditionClockSynced,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ManagedClusterClockOutOfSync",
+				Message: "The clock of the managed cluster is 1h0m0s behind the hub, which exceeds 30s.",
+			},
+		},
+		{
+			name:         "no date header",
+			localTime:    hubTime,
+			noDateHeader: true,
+			expectedErr:  `unable to get the clock skew between managed cluster "testmanagedcluster" and hub: the response of hub has no Date header`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/version" {
+					t.Errorf("unexpected path %q", r.URL.Path)
+				}
+				if c.noDateHeader {
+					// a nil value prevents the server from setting the Date header
+					w.Header()["Date"] = nil
+				} else {
+					w.Header().Set("Date", hubTime.Format(http.TimeFormat))
+				}
+				// the Date header is read regardless of the status code
+				w.WriteHeader(http.StatusForbidden)
+			}))
+			defer server.Close()
+
+			clusterClient := clusterfake.NewSimpleClientset(testinghelpers.NewAcceptedManagedCluster())
+			ctrl := &clockSyncController{
+				clusterName:      testinghelpers.TestManagedClusterName,
+				hubClusterClient: clusterClient,
+				hubHTTPClient:    server.Client(),
+				hubHost:          server.URL + "/",
+				now:              func() time.Time { return c.localTime },
+			}
+			err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if len(c.expectedErr) != 0 {
+				testinghelpers.AssertNoActions(t, clusterClient.Actions())
+				return
+			}
+
+			actions := clusterClient.Actions()
+			testinghelpers.AssertActions(t, actions, "get", "patch")
+			managedCluster := &clusterv1.ManagedCluster{}
+			if err := json.Unmarshal(actions[1].(clienttesting.PatchAction).GetPatch(), managedCluster); err != nil {
+				t.Fatal(err)
+			}
+			testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, c.expectedCondition)
+		})
+	}
+}
@@ -7,9 +7,11 @@ import (
 
 	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -26,6 +28,7 @@ type managedClusterCreatingController struct {
 	clusterName             string
 	spokeExternalServerURLs []string
 	spokeCABundle           []byte
+	clusterAnnotations      map[string]string
 	hubClusterClient        clientset.Interface
 }
 
@@ -33,17 +36,21 @@ type managedClusterCreatingController struct {
 func NewManagedClusterCreatingController(
 	clusterName string, spokeExternalServerURLs []string,
 	spokeCABundle []byte,
+	clusterAnnotations map[string]string,
 	hubClusterClient clientset.Interface,
+	retryBackoff *helpers.RetryBackoff,
 	recorder events.Recorder) factory.Controller {
 	c := &managedClusterCreatingController{
 		clusterName:             clusterName,
 		spokeExternalServerURLs: spokeExternalServerURLs,
 		spokeCABundle:           spokeCABundle,
+		clusterAnnotations:      clusterAnnotations,
 		hubClusterClient:        hubClusterClient,
 	}
 
 	return factory.New().
-		WithSync(c.sync).
+		// the managed cluster is created during the bootstrap, retry with the bootstrap backoff if it fails
+		WithSync(retryBackoff.WrapSync("ManagedClusterCreatingController", c.sync)).
 		ResyncEvery(wait.Jitter(CreatingControllerSyncInterval, 1.0)).
 		ToController("ManagedClusterCreatingController", recorder)
 }
@@ -64,7 +71,8 @@ func (c *managedClusterCreatingController) sync(ctx context.Context, syncCtx fac
 	if errors.IsNotFound(err) {
 		managedCluster := &clusterv1.ManagedCluster{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: c.clusterName,
+				Name:        c.clusterName,
+				Annotations: c.clusterAnnotations,
 			},
 		}
 
@@ -88,13 +96,18 @@ func (c *managedClusterCreatingController) sync(ctx context.Context, syncCtx fac
 		return nil
 	}
 
-	// do not update ManagedClusterClientConfigs in ManagedCluster if spokeExternalServerURLs is empty
-	if len(c.spokeExternalServerURLs) == 0 {
-		return nil
+	clusterCopy := existingCluster.DeepCopy()
+
+	// merge annotations, the annotations set by the agent are required by hub, e.g. to map the
+	// identity of the agent, so they are kept up to date.
+	for key, value := range c.clusterAnnotations {
+		if clusterCopy.Annotations == nil {
+			clusterCopy.Annotations = map[string]string{}
+		}
+		clusterCopy.Annotations[key] = value
 	}
 
 	// merge ClientConfig
-	managedClusterClientConfigs := existingCluster.Spec.ManagedClusterClientConfigs
 	for _, serverURL := range c.spokeExternalServerURLs {
 		isIncludeByExisting := false
 		for _, existingClientConfig := range existingCluster.Spec.ManagedClusterClientConfigs {
@@ -105,21 +118,19 @@ func (c *managedClusterCreatingController) sync(ctx context.Context, syncCtx fac
 		}
 
 		if !isIncludeByExisting {
-			managedClusterClientConfigs = append(managedClusterClientConfigs, clusterv1.ClientConfig{
+			clusterCopy.Spec.ManagedClusterClientConfigs = append(clusterCopy.Spec.ManagedClusterClientConfigs, clusterv1.ClientConfig{
 				URL:      serverURL,
 				CABundle: c.spokeCABundle,
 			})
 		}
 	}
-	if len(existingCluster.Spec.ManagedClusterClientConfigs) == len(managedClusterClientConfigs) {
+	if equality.Semantic.DeepEqual(existingCluster, clusterCopy) {
 		return nil
 	}
 
-	// update ManagedClusterClientConfigs in ManagedCluster
-	clusterCopy := existingCluster.DeepCopy()
-	clusterCopy.Spec.ManagedClusterClientConfigs = managedClusterClientConfigs
+	// update ManagedClusterClientConfigs and annotations in ManagedCluster
 	_, err = c.hubClusterClient.ClusterV1().ManagedClusters().Update(ctx, clusterCopy, metav1.UpdateOptions{})
-	// ManagedClusterClientConfigs in ManagedCluster is only allowed updated during bootstrap. After bootstrap secret expired, an unauthorized error will be got, skip it
+	// ManagedCluster is only allowed updated during bootstrap. After bootstrap secret expired, an unauthorized error will be got, skip it
 	if skipUnauthorizedError(err) != nil {
 		return fmt.Errorf("unable to update ManagedClusterClientConfigs of managed cluster %q in hub: %w", c.clusterName, err)
 	}
@@ -16,9 +16,10 @@ const testSpokeExternalServerUrl = "https://192.168.3.77:32769"
 
 func TestCreateSpokeCluster(t *testing.T) {
 	cases := []struct {
-		name            string
-		startingObjects []runtime.Object
-		validateActions func(t *testing.T, actions []clienttesting.Action)
+		name               string
+		startingObjects    []runtime.Object
+		clusterAnnotations map[string]string
+		validateActions    func(t *testing.T, actions []clienttesting.Action)
 	}{
 		{
 			name:            "create a new cluster",
@@ -43,6 +44,49 @@ func TestCreateSpokeCluster(t *testing.T) {
 				testinghelpers.AssertActions(t, actions, "get", "update")
 			},
 		},
+		{
+			name:               "create a new cluster with annotations",
+			startingObjects:    []runtime.Object{},
+			clusterAnnotations: map[string]string{"test": "value"},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create")
+				actual := actions[1].(clienttesting.CreateActionImpl).Object.(*clusterv1.ManagedCluster)
+				if actual.Annotations["test"] != "value" {
+					t.Errorf("expected annotation to be set, but got %v", actual.Annotations)
+				}
+			},
+		},
+		{
+			name: "merge annotations into an existed cluster",
+			startingObjects: []runtime.Object{func() *clusterv1.ManagedCluster {
+				cluster := testinghelpers.NewManagedCluster()
+				cluster.Spec.ManagedClusterClientConfigs = []clusterv1.ClientConfig{
+					{URL: testSpokeExternalServerUrl, CABundle: []byte("testcabundle")},
+				}
+				return cluster
+			}()},
+			clusterAnnotations: map[string]string{"test": "value"},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				actual := actions[1].(clienttesting.UpdateActionImpl).Object.(*clusterv1.ManagedCluster)
+				if actual.Annotations["test"] != "value" {
+					t.Errorf("expected annotation to be set, but got %v", actual.Annotations)
+				}
+			},
+		},
+		{
+			name: "existed cluster is up to date",
+			startingObjects: []runtime.Object{func() *clusterv1.ManagedCluster {
+				cluster := testinghelpers.NewManagedCluster()
+				cluster.Spec.ManagedClusterClientConfigs = []clusterv1.ClientConfig{
+					{URL: testSpokeExternalServerUrl, CABundle: []byte("testcabundle")},
+				}
+				return cluster
+			}()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -52,6 +96,7 @@ func TestCreateSpokeCluster(t *testing.T) {
 				clusterName:             testinghelpers.TestManagedClusterName,
 				spokeExternalServerURLs: []string{testSpokeExternalServerUrl},
 				spokeCABundle:           []byte("testcabundle"),
+				clusterAnnotations:      c.clusterAnnotations,
 				hubClusterClient:        clusterClient,
 			}
 
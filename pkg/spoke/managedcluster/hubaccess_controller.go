@@ -0,0 +1,213 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// forbiddenErrorThreshold is the number of consecutive forbidden errors from the hub which trigger
+	// a review of the hub permissions.
+	forbiddenErrorThreshold = 3
+)
+
+// HubAccessReviewTrigger triggers a review of the permissions of the agent on the hub once the agent
+// gets forbidden errors from the hub persistently, e.g. the registration rolebinding of the cluster is
+// deleted on the hub, so that the HubAccessReady condition reflects the missing permissions promptly
+// instead of waiting for the next periodic review.
+type HubAccessReviewTrigger struct {
+	syncCtx         factory.SyncContext
+	lock            sync.Mutex
+	forbiddenErrors int
+}
+
+// NewHubAccessReviewTrigger returns a HubAccessReviewTrigger, it must be passed to NewHubAccessController.
+func NewHubAccessReviewTrigger(recorder events.Recorder) *HubAccessReviewTrigger {
+	return &HubAccessReviewTrigger{
+		syncCtx: factory.NewSyncContext("HubAccessController", recorder),
+	}
+}
+
+// ObserveError observes the result of a request to the hub, a nil error resets the count of the
+// consecutive forbidden errors. It is safe to call it on a nil trigger.
+func (t *HubAccessReviewTrigger) ObserveError(err error) {
+	if t == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !apierrors.IsForbidden(err) {
+		t.forbiddenErrors = 0
+		return
+	}
+
+	t.forbiddenErrors++
+	if t.forbiddenErrors == forbiddenErrorThreshold {
+		klog.Warningf("Got %d consecutive forbidden errors from hub, reviewing the hub permissions: %v", t.forbiddenErrors, err)
+		t.syncCtx.Queue().Add(factory.DefaultQueueKey)
+	}
+}
+
+// hubPermission is a permission on the hub required by the agent.
+type hubPermission struct {
+	group       string
+	resource    string
+	subresource string
+	verb        string
+	// namespaced is true if the resource is in the cluster namespace
+	namespaced bool
+	// name is the name of the resource, the cluster name is used if it is empty and named is true
+	name  string
+	named bool
+}
+
+func (p hubPermission) String() string {
+	resource := p.resource
+	if len(p.subresource) != 0 {
+		resource = resource + "/" + p.subresource
+	}
+	if len(p.group) != 0 {
+		resource = resource + "." + p.group
+	}
+	return p.verb + " " + resource
+}
+
+// requiredHubPermissions returns the permissions on the hub required by the agent. The managed
+// cluster, lease and addons are in the cluster namespace or named with the cluster name, so the
+// agent only needs access to the resources of its own cluster.
+func requiredHubPermissions(addOnManagementEnabled bool) []hubPermission {
+	permissions := []hubPermission{
+		{group: "cluster.open-cluster-management.io", resource: "managedclusters", verb: "get", named: true},
+		{group: "cluster.open-cluster-management.io", resource: "managedclusters", verb: "watch", named: true},
+		{group: "cluster.open-cluster-management.io", resource: "managedclusters", verb: "update", named: true},
+		{group: "cluster.open-cluster-management.io", resource: "managedclusters", subresource: "status", verb: "patch", named: true},
+		{group: "coordination.k8s.io", resource: "leases", verb: "get", namespaced: true, name: helpers.ManagedClusterLeaseName, named: true},
+		{group: "coordination.k8s.io", resource: "leases", verb: "update", namespaced: true, name: helpers.ManagedClusterLeaseName, named: true},
+		{group: "certificates.k8s.io", resource: "certificatesigningrequests", verb: "create"},
+		{group: "certificates.k8s.io", resource: "certificatesigningrequests", verb: "watch"},
+	}
+	if addOnManagementEnabled {
+		permissions = append(permissions,
+			hubPermission{group: "addon.open-cluster-management.io", resource: "managedclusteraddons", verb: "list", namespaced: true},
+			hubPermission{group: "addon.open-cluster-management.io", resource: "managedclusteraddons", verb: "watch", namespaced: true},
+			hubPermission{group: "addon.open-cluster-management.io", resource: "managedclusteraddons", subresource: "status", verb: "patch", namespaced: true},
+		)
+	}
+	return permissions
+}
+
+// hubAccessController reviews the permissions of the agent on the hub with SelfSubjectAccessReviews
+// on startup and periodically, and reports the missing permissions with the HubAccessReady condition
+// of the ManagedCluster, so that a misconfigured RBAC of the hub is surfaced clearly instead of as
+// forbidden errors scattered in the logs of the agent.
+type hubAccessController struct {
+	clusterName      string
+	hubKubeClient    kubernetes.Interface
+	hubClusterClient clientset.Interface
+	permissions      []hubPermission
+}
+
+// NewHubAccessController creates a new hub access controller on the managed cluster.
+func NewHubAccessController(
+	clusterName string,
+	hubKubeClient kubernetes.Interface,
+	hubClusterClient clientset.Interface,
+	addOnManagementEnabled bool,
+	trigger *HubAccessReviewTrigger,
+	recorder events.Recorder) factory.Controller {
+	c := &hubAccessController{
+		clusterName:      clusterName,
+		hubKubeClient:    hubKubeClient,
+		hubClusterClient: hubClusterClient,
+		permissions:      requiredHubPermissions(addOnManagementEnabled),
+	}
+
+	f := factory.New()
+	if trigger != nil {
+		f = f.WithSyncContext(trigger.syncCtx)
+	}
+	return f.WithSync(c.sync).
+		ResyncEvery(10*time.Minute).
+		ToController("HubAccessController", recorder)
+}
+
+func (c *hubAccessController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	missing := []string{}
+	for _, permission := range c.permissions {
+		allowed, err := c.review(ctx, permission)
+		if err != nil {
+			return fmt.Errorf("unable to review the permission %q on hub: %w", permission, err)
+		}
+		if !allowed {
+			missing = append(missing, permission.String())
+		}
+	}
+
+	cond := metav1.Condition{
+		Type:    helpers.ManagedClusterConditionHubAccessReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "HubPermissionsGranted",
+		Message: "The agent has the required permissions on hub",
+	}
+	if len(missing) != 0 {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "HubPermissionsMissing"
+		cond.Message = fmt.Sprintf("The agent is missing the permissions on hub: %s", strings.Join(missing, ", "))
+		klog.Warningf("Managed cluster %q: %s", c.clusterName, cond.Message)
+		syncCtx.Recorder().Warningf("HubPermissionsMissing", cond.Message)
+	}
+
+	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName,
+		helpers.UpdateManagedClusterConditionFn(cond))
+	if err != nil {
+		// the agent may be unable to update the status without the permissions, the event and log are the only hints
+		return fmt.Errorf("unable to update status of managed cluster %q: %w", c.clusterName, err)
+	}
+	if updated {
+		klog.V(4).Infof("The hub access condition of managed cluster %q is updated to %q", c.clusterName, cond.Status)
+	}
+	return nil
+}
+
+func (c *hubAccessController) review(ctx context.Context, permission hubPermission) (bool, error) {
+	attributes := &authorizationv1.ResourceAttributes{
+		Group:       permission.group,
+		Resource:    permission.resource,
+		Subresource: permission.subresource,
+		Verb:        permission.verb,
+	}
+	if permission.namespaced {
+		attributes.Namespace = c.clusterName
+	}
+	if permission.named {
+		attributes.Name = permission.name
+		if len(attributes.Name) == 0 {
+			attributes.Name = c.clusterName
+		}
+	}
+
+	review, err := c.hubKubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attributes},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
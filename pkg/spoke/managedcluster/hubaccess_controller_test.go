@@ -0,0 +1,150 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestHubAccessSync(t *testing.T) {
+	cases := []struct {
+		name              string
+		addOnManagement   bool
+		deniedResources   []string
+		expectedReviews   int
+		expectedCondition metav1.Condition
+	}{
+		{
+			name:            "all permissions are granted",
+			expectedReviews: 8,
+			expectedCondition: metav1.Condition{
+				Type:    helpers.ManagedClusterConditionHubAccessReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  "HubPermissionsGranted",
+				Message: "The agent has the required permissions on hub",
+			},
+		},
+		{
+			name:            "lease permissions are missing",
+			deniedResources: []string{"leases"},
+			expectedReviews: 8,
+			expectedCondition: metav1.Condition{
+				Type:    helpers.ManagedClusterConditionHubAccessReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  "HubPermissionsMissing",
+				Message: "The agent is missing the permissions on hub: get leases.coordination.k8s.io, update leases.coordination.k8s.io",
+			},
+		},
+		{
+			name:            "addon permissions are missing",
+			addOnManagement: true,
+			deniedResources: []string{"managedclusteraddons"},
+			expectedReviews: 11,
+			expectedCondition: metav1.Condition{
+				Type:   helpers.ManagedClusterConditionHubAccessReady,
+				Status: metav1.ConditionFalse,
+				Reason: "HubPermissionsMissing",
+				Message: "The agent is missing the permissions on hub: list managedclusteraddons.addon.open-cluster-management.io, " +
+					"watch managedclusteraddons.addon.open-cluster-management.io, patch managedclusteraddons/status.addon.open-cluster-management.io",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				attributes := review.Spec.ResourceAttributes
+				if attributes.Namespace != "" && attributes.Namespace != testinghelpers.TestManagedClusterName {
+					t.Errorf("unexpected namespace %q", attributes.Namespace)
+				}
+				review.Status.Allowed = true
+				for _, resource := range c.deniedResources {
+					if attributes.Resource == resource {
+						review.Status.Allowed = false
+					}
+				}
+				return true, review, nil
+			})
+			clusterClient := clusterfake.NewSimpleClientset(testinghelpers.NewAcceptedManagedCluster())
+
+			ctrl := &hubAccessController{
+				clusterName:      testinghelpers.TestManagedClusterName,
+				hubKubeClient:    kubeClient,
+				hubClusterClient: clusterClient,
+				permissions:      requiredHubPermissions(c.addOnManagement),
+			}
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "")); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if len(kubeClient.Actions()) != c.expectedReviews {
+				t.Errorf("expected %d reviews, but got %d", c.expectedReviews, len(kubeClient.Actions()))
+			}
+
+			actions := clusterClient.Actions()
+			testinghelpers.AssertActions(t, actions, "get", "patch")
+			managedCluster := &clusterv1.ManagedCluster{}
+			if err := json.Unmarshal(actions[1].(clienttesting.PatchAction).GetPatch(), managedCluster); err != nil {
+				t.Fatal(err)
+			}
+			testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, c.expectedCondition)
+		})
+	}
+}
+
+func TestHubAccessReviewTrigger(t *testing.T) {
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}, "managed-cluster-lease", nil)
+	cases := []struct {
+		name           string
+		errs           []error
+		expectedQueued bool
+	}{
+		{
+			name: "transient forbidden errors",
+			errs: []error{forbidden, forbidden, nil, forbidden},
+		},
+		{
+			name: "other errors",
+			errs: []error{fmt.Errorf("timeout"), fmt.Errorf("timeout"), fmt.Errorf("timeout")},
+		},
+		{
+			name:           "persistent forbidden errors",
+			errs:           []error{forbidden, forbidden, forbidden},
+			expectedQueued: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			trigger := NewHubAccessReviewTrigger(eventstesting.NewTestingEventRecorder(t))
+			for _, err := range c.errs {
+				trigger.ObserveError(err)
+			}
+			if queued := trigger.syncCtx.Queue().Len() != 0; queued != c.expectedQueued {
+				t.Errorf("expected queued %v, but got %v", c.expectedQueued, queued)
+			}
+		})
+	}
+
+	// a nil trigger is a no-op
+	var trigger *HubAccessReviewTrigger
+	trigger.ObserveError(forbidden)
+}
@@ -0,0 +1,155 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// HubStateHubAcceptsClientKey is the key of the hubAcceptsClient of the ManagedCluster in the hub state ConfigMap.
+	HubStateHubAcceptsClientKey = "hubAcceptsClient"
+	// HubStateConditionsKey is the key of the human readable conditions of the ManagedCluster in the hub state
+	// ConfigMap, one condition per line.
+	HubStateConditionsKey = "conditions"
+	// HubStateStatusKey is the key of the status of the ManagedCluster in json in the hub state ConfigMap.
+	HubStateStatusKey = "status"
+	// HubStateObservedTimeKey is the key of the time when the hub state in the ConfigMap is observed.
+	HubStateObservedTimeKey = "observedTime"
+)
+
+// hubStateController caches the last known state of the ManagedCluster on the hub into a ConfigMap in the
+// agent namespace on the management cluster, so that the reason why the hub denies the cluster or marks it
+// unknown can be diagnosed on a disconnected cluster without the hub access. The ConfigMap is only changed
+// once the state is observed from the hub, and it is kept if the hub is unreachable.
+type hubStateController struct {
+	clusterName        string
+	componentNamespace string
+	hubIndex           int
+	managementClient   kubernetes.Interface
+	hubClusterLister   clusterv1listers.ManagedClusterLister
+	clock              clock.Clock
+}
+
+// NewHubStateController creates a new hub state controller on the managed cluster. The hubIndex is the index
+// of the hub in the bootstrap kubeconfigs once the managed cluster is registered to multiple hubs, it is 0
+// otherwise.
+func NewHubStateController(
+	clusterName, componentNamespace string,
+	hubIndex int,
+	managementClient kubernetes.Interface,
+	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &hubStateController{
+		clusterName:        clusterName,
+		componentNamespace: componentNamespace,
+		hubIndex:           hubIndex,
+		managementClient:   managementClient,
+		hubClusterLister:   hubClusterInformer.Lister(),
+		clock:              clock.RealClock{},
+	}
+
+	return factory.New().
+		WithInformers(hubClusterInformer.Informer()).
+		WithSync(c.sync).
+		ResyncEvery(5*time.Minute).
+		ToController("HubStateController", recorder)
+}
+
+// HubStateConfigMapName returns the name of the ConfigMap caching the last known state of the managed cluster
+// on the hub with the hubIndex. The state of the i-th (i > 0) hub is cached in '<cluster>-hub-state-<i>', so
+// that the hubs of a managed cluster registered to multiple hubs do not overwrite the state of each other.
+func HubStateConfigMapName(clusterName string, hubIndex int) string {
+	if hubIndex == 0 {
+		return fmt.Sprintf("%s-hub-state", clusterName)
+	}
+	return fmt.Sprintf("%s-hub-state-%d", clusterName, hubIndex)
+}
+
+func (c *hubStateController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	cluster, err := c.hubClusterLister.Get(c.clusterName)
+	// the managed cluster is not created or observed yet, keep the last known state
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get managed cluster %q from hub: %w", c.clusterName, err)
+	}
+
+	data, err := hubStateData(cluster)
+	if err != nil {
+		return err
+	}
+
+	configMapName := HubStateConfigMapName(c.clusterName, c.hubIndex)
+	configMap, err := c.managementClient.CoreV1().ConfigMaps(c.componentNamespace).Get(ctx, configMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		data[HubStateObservedTimeKey] = c.clock.Now().UTC().Format(time.RFC3339)
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configMapName,
+				Namespace: c.componentNamespace,
+				Labels:    map[string]string{clusterv1.ClusterNameLabelKey: c.clusterName},
+			},
+			Data: data,
+		}
+		if _, err := c.managementClient.CoreV1().ConfigMaps(c.componentNamespace).Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("unable to create configmap %q on management cluster: %w", configMapName, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get configmap %q on management cluster: %w", configMapName, err)
+	}
+
+	// the observed time is only changed with the state, so that it tells how long the state lasts
+	data[HubStateObservedTimeKey] = configMap.Data[HubStateObservedTimeKey]
+	if reflect.DeepEqual(configMap.Data, data) {
+		return nil
+	}
+
+	configMap = configMap.DeepCopy()
+	data[HubStateObservedTimeKey] = c.clock.Now().UTC().Format(time.RFC3339)
+	configMap.Data = data
+	if _, err := c.managementClient.CoreV1().ConfigMaps(c.componentNamespace).Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to update configmap %q on management cluster: %w", configMapName, err)
+	}
+	return nil
+}
+
+// hubStateData returns the data of the hub state ConfigMap without the observed time.
+func hubStateData(cluster *clusterv1.ManagedCluster) (map[string]string, error) {
+	status, err := json.Marshal(cluster.Status)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal the status of managed cluster %q: %w", cluster.Name, err)
+	}
+
+	conditions := []string{}
+	for _, condition := range cluster.Status.Conditions {
+		conditions = append(conditions, fmt.Sprintf("%s=%s (%s): %s",
+			condition.Type, condition.Status, condition.Reason, condition.Message))
+	}
+
+	return map[string]string{
+		HubStateHubAcceptsClientKey: strconv.FormatBool(cluster.Spec.HubAcceptsClient),
+		HubStateConditionsKey:       strings.Join(conditions, "\n"),
+		HubStateStatusKey:           string(status),
+	}, nil
+}
@@ -0,0 +1,154 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestHubStateSync(t *testing.T) {
+	configMapName := HubStateConfigMapName(testinghelpers.TestManagedClusterName, 0)
+	now := time.Date(2023, time.March, 1, 8, 0, 0, 0, time.UTC)
+	lastObservedTime := now.Add(-time.Hour).Format(time.RFC3339)
+
+	newConfigMap := func(cluster *clusterv1.ManagedCluster) *corev1.ConfigMap {
+		data, err := hubStateData(cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[HubStateObservedTimeKey] = lastObservedTime
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: "open-cluster-management-agent"},
+			Data:       data,
+		}
+	}
+
+	cases := []struct {
+		name            string
+		clusters        []runtime.Object
+		configMaps      []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:            "managed cluster is not found",
+			validateActions: testinghelpers.AssertNoActions,
+		},
+		{
+			name:     "create configmap",
+			clusters: []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create")
+				configMap := actions[1].(clienttesting.CreateAction).GetObject().(*corev1.ConfigMap)
+				if configMap.Name != configMapName {
+					t.Errorf("expected configmap %q, but got %q", configMapName, configMap.Name)
+				}
+				if configMap.Data[HubStateHubAcceptsClientKey] != "true" {
+					t.Errorf("expected hubAcceptsClient true, but got %v", configMap.Data)
+				}
+				if configMap.Data[HubStateConditionsKey] != "HubAcceptedManagedCluster=True (HubClusterAdminAccepted): Accepted by hub cluster admin" {
+					t.Errorf("unexpected conditions %q", configMap.Data[HubStateConditionsKey])
+				}
+				if configMap.Data[HubStateObservedTimeKey] != now.Format(time.RFC3339) {
+					t.Errorf("expected observed time %v, but got %q", now, configMap.Data[HubStateObservedTimeKey])
+				}
+				status := clusterv1.ManagedClusterStatus{}
+				if err := json.Unmarshal([]byte(configMap.Data[HubStateStatusKey]), &status); err != nil {
+					t.Fatal(err)
+				}
+				testinghelpers.AssertCondition(t, status.Conditions, metav1.Condition{
+					Type:    clusterv1.ManagedClusterConditionHubAccepted,
+					Status:  metav1.ConditionTrue,
+					Reason:  "HubClusterAdminAccepted",
+					Message: "Accepted by hub cluster admin",
+				})
+			},
+		},
+		{
+			name:       "configmap is up to date",
+			clusters:   []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			configMaps: []runtime.Object{newConfigMap(testinghelpers.NewAcceptedManagedCluster())},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:       "update configmap",
+			clusters:   []runtime.Object{testinghelpers.NewDeniedManagedCluster()},
+			configMaps: []runtime.Object{newConfigMap(testinghelpers.NewAcceptedManagedCluster())},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				configMap := actions[1].(clienttesting.UpdateAction).GetObject().(*corev1.ConfigMap)
+				if configMap.Data[HubStateHubAcceptsClientKey] != "false" {
+					t.Errorf("expected hubAcceptsClient false, but got %v", configMap.Data)
+				}
+				if configMap.Data[HubStateObservedTimeKey] != now.Format(time.RFC3339) {
+					t.Errorf("expected observed time %v, but got %q", now, configMap.Data[HubStateObservedTimeKey])
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+			for _, cluster := range c.clusters {
+				if err := clusterStore.Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+			kubeClient := kubefake.NewSimpleClientset(c.configMaps...)
+
+			ctrl := &hubStateController{
+				clusterName:        testinghelpers.TestManagedClusterName,
+				componentNamespace: "open-cluster-management-agent",
+				managementClient:   kubeClient,
+				hubClusterLister:   clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				clock:              clocktesting.NewFakeClock(now),
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+			testinghelpers.AssertError(t, syncErr, "")
+
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
+
+func TestHubStateConfigMapName(t *testing.T) {
+	cases := []struct {
+		name     string
+		hubIndex int
+		expected string
+	}{
+		{
+			name:     "single hub",
+			expected: "cluster1-hub-state",
+		},
+		{
+			name:     "second hub",
+			hubIndex: 1,
+			expected: "cluster1-hub-state-1",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := HubStateConfigMapName("cluster1", c.hubIndex); actual != c.expected {
+				t.Errorf("expected configmap %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+}
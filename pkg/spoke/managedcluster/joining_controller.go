@@ -84,3 +84,20 @@ func (c managedClusterJoiningController) sync(ctx context.Context, syncCtx facto
 	}
 	return nil
 }
+
+// acceptanceRevoked returns a message explaining the denial if the acceptance of the managed cluster is revoked,
+// either the hub cluster admin sets hubAcceptsClient to false, or the hub reports the HubAccepted condition false.
+// The agent stops updating the status and the lease of the managed cluster at once then, rather than failing
+// until its permissions on the hub are removed. A cluster which is not accepted yet is not revoked.
+func acceptanceRevoked(managedCluster *clusterv1.ManagedCluster) (string, bool) {
+	accepted := meta.FindStatusCondition(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted)
+	switch {
+	case accepted == nil:
+		return "", false
+	case accepted.Status == metav1.ConditionFalse:
+		return fmt.Sprintf("managed cluster %q is denied by hub: %s", managedCluster.Name, accepted.Message), true
+	case !managedCluster.Spec.HubAcceptsClient:
+		return fmt.Sprintf("hubAcceptsClient of managed cluster %q is set to false on hub", managedCluster.Name), true
+	}
+	return "", false
+}
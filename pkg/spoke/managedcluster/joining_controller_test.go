@@ -10,9 +10,12 @@ import (
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/helpers/testing/fakehub"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	clienttesting "k8s.io/client-go/testing"
 )
 
@@ -80,3 +83,99 @@ func TestSyncManagedCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestJoinOnFakeHub(t *testing.T) {
+	hub, err := fakehub.NewHub(testinghelpers.NewManagedCluster())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := managedClusterJoiningController{
+		clusterName:      testinghelpers.TestManagedClusterName,
+		hubClusterClient: hub.ClusterClient,
+		hubClusterLister: hub.ClusterInformers.Cluster().V1().ManagedClusters().Lister(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub.Start(ctx)
+
+	if err := ctrl.sync(ctx, testinghelpers.NewFakeSyncContext(t, "")); err != nil {
+		t.Fatal(err)
+	}
+	assertJoined(t, hub, false)
+
+	if err := hub.AcceptCluster(ctx, testinghelpers.TestManagedClusterName, 60); err != nil {
+		t.Fatal(err)
+	}
+	// wait until the informer observes the acceptance
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		cluster, err := ctrl.hubClusterLister.Get(testinghelpers.TestManagedClusterName)
+		if err != nil {
+			return false, err
+		}
+		return cluster.Spec.HubAcceptsClient, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ctrl.sync(ctx, testinghelpers.NewFakeSyncContext(t, "")); err != nil {
+		t.Fatal(err)
+	}
+	assertJoined(t, hub, true)
+}
+
+func assertJoined(t *testing.T, hub *fakehub.Hub, expected bool) {
+	cluster, err := hub.ManagedCluster(context.TODO(), testinghelpers.TestManagedClusterName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joined := meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined); joined != expected {
+		t.Errorf("expected joined %t, but got %t", expected, joined)
+	}
+}
+
+func TestAcceptanceRevoked(t *testing.T) {
+	hubDeniedCluster := testinghelpers.NewAcceptedManagedCluster()
+	hubDeniedCluster.Spec.HubAcceptsClient = false
+	hubDeniedCluster.Status.Conditions = []metav1.Condition{
+		testinghelpers.NewManagedClusterCondition(clusterv1.ManagedClusterConditionHubAccepted, "False",
+			"HubClusterAdminDenied", "Denied by hub cluster admin", nil),
+	}
+
+	cases := []struct {
+		name            string
+		cluster         *clusterv1.ManagedCluster
+		expectedRevoked bool
+		expectedMessage string
+	}{
+		{
+			name:    "not accepted yet",
+			cluster: testinghelpers.NewManagedCluster(),
+		},
+		{
+			name:    "accepted",
+			cluster: testinghelpers.NewAcceptedManagedCluster(),
+		},
+		{
+			name:            "hubAcceptsClient is set to false",
+			cluster:         testinghelpers.NewDeniedManagedCluster(),
+			expectedRevoked: true,
+			expectedMessage: "hubAcceptsClient of managed cluster \"testmanagedcluster\" is set to false on hub",
+		},
+		{
+			name:            "denied by hub",
+			cluster:         hubDeniedCluster,
+			expectedRevoked: true,
+			expectedMessage: "managed cluster \"testmanagedcluster\" is denied by hub: Denied by hub cluster admin",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			message, revoked := acceptanceRevoked(c.cluster)
+			if revoked != c.expectedRevoked || message != c.expectedMessage {
+				t.Errorf("expected revoked %v with message %q, but got %v with %q", c.expectedRevoked, c.expectedMessage, revoked, message)
+			}
+		})
+	}
+}
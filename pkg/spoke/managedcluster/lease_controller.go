@@ -3,12 +3,15 @@ package managedcluster
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -18,6 +21,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/component-base/featuregate"
 )
 
 const leaseUpdateJitterFactor = 0.25
@@ -31,10 +35,14 @@ type managedClusterLeaseController struct {
 }
 
 // NewManagedClusterLeaseController creates a new managed cluster lease controller on the managed cluster.
+// The leaseAnnotations, e.g. the ones returned by AgentLeaseAnnotations, are stamped on the lease each time
+// it is renewed.
 func NewManagedClusterLeaseController(
 	clusterName string,
 	hubClient clientset.Interface,
 	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	hubAccessReviewTrigger *HubAccessReviewTrigger,
+	leaseAnnotations map[string]string,
 	recorder events.Recorder) factory.Controller {
 	c := &managedClusterLeaseController{
 		clusterName:      clusterName,
@@ -42,8 +50,10 @@ func NewManagedClusterLeaseController(
 		leaseUpdater: &leaseUpdater{
 			hubClient:   hubClient,
 			clusterName: clusterName,
-			leaseName:   "managed-cluster-lease",
+			leaseName:   helpers.ManagedClusterLeaseName,
+			annotations: leaseAnnotations,
 			recorder:    recorder,
+			trigger:     hubAccessReviewTrigger,
 		},
 	}
 
@@ -53,6 +63,29 @@ func NewManagedClusterLeaseController(
 		ToController("ManagedClusterLeaseController", recorder)
 }
 
+// AgentLeaseAnnotations returns the annotations of the lease which describe the agent, i.e. its version,
+// the git commit it is built from and its enabled feature gates, so that the hub operators have an
+// inventory of the agents of the fleet. An empty version or git commit is not included.
+func AgentLeaseAnnotations(gitVersion, gitCommit string, featureGate featuregate.MutableFeatureGate) map[string]string {
+	annotations := map[string]string{}
+	if len(gitVersion) != 0 {
+		annotations[helpers.AgentVersionAnnotation] = gitVersion
+	}
+	if len(gitCommit) != 0 {
+		annotations[helpers.AgentBuildAnnotation] = gitCommit
+	}
+
+	enabled := []string{}
+	for feature := range featureGate.GetAll() {
+		if featureGate.Enabled(feature) {
+			enabled = append(enabled, string(feature))
+		}
+	}
+	sort.Strings(enabled)
+	annotations[helpers.AgentFeatureGatesAnnotation] = strings.Join(enabled, ",")
+	return annotations
+}
+
 // sync starts a lease update routine with the managed cluster lease duration.
 func (c *managedClusterLeaseController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
 	cluster, err := c.hubClusterLister.Get(c.clusterName)
@@ -62,8 +95,10 @@ func (c *managedClusterLeaseController) sync(ctx context.Context, syncCtx factor
 		return fmt.Errorf("unable to get managed cluster %q from hub: %w", c.clusterName, err)
 	}
 
-	// the managed cluster is not accepted, make sure there is no lease update routine.
-	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+	// the managed cluster is not accepted or its acceptance is revoked, make sure there is no lease update routine.
+	// the lease is not updated once hubAcceptsClient is set to false, even before the hub denies the cluster.
+	_, revoked := acceptanceRevoked(cluster)
+	if revoked || !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
 		c.leaseUpdater.stop()
 		return nil
 	}
@@ -91,9 +126,11 @@ type leaseUpdater struct {
 	hubClient   clientset.Interface
 	clusterName string
 	leaseName   string
+	annotations map[string]string
 	lock        sync.Mutex
 	cancel      context.CancelFunc
 	recorder    events.Recorder
+	trigger     *HubAccessReviewTrigger
 }
 
 // start a lease update routine to update the lease of a managed cluster periodically.
@@ -127,13 +164,22 @@ func (u *leaseUpdater) stop() {
 // update the lease of a given managed cluster.
 func (u *leaseUpdater) update(ctx context.Context) {
 	lease, err := u.hubClient.CoordinationV1().Leases(u.clusterName).Get(ctx, u.leaseName, metav1.GetOptions{})
+	u.trigger.ObserveError(err)
 	if err != nil {
 		utilruntime.HandleError(fmt.Errorf("unable to get cluster lease %q on hub cluster: %w", u.leaseName, err))
 		return
 	}
 
 	lease.Spec.RenewTime = &metav1.MicroTime{Time: time.Now()}
-	if _, err = u.hubClient.CoordinationV1().Leases(u.clusterName).Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+	for key, value := range u.annotations {
+		if lease.Annotations == nil {
+			lease.Annotations = map[string]string{}
+		}
+		lease.Annotations[key] = value
+	}
+	_, err = u.hubClient.CoordinationV1().Leases(u.clusterName).Update(ctx, lease, metav1.UpdateOptions{})
+	u.trigger.ObserveError(err)
+	if err != nil {
 		utilruntime.HandleError(fmt.Errorf("unable to update cluster lease %q on hub cluster: %w", u.leaseName, err))
 	}
 }
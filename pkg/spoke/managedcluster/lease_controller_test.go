@@ -2,11 +2,13 @@ package managedcluster
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	"open-cluster-management.io/registration/pkg/helpers"
 	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
@@ -15,6 +17,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/component-base/featuregate"
 )
 
 func TestLeaseUpdate(t *testing.T) {
@@ -23,7 +26,9 @@ func TestLeaseUpdate(t *testing.T) {
 		clusters                []runtime.Object
 		validateActions         func(t *testing.T, actions []clienttesting.Action)
 		needToStartUpdateBefore bool
-		expectedErr             string
+		// startLeaseDuration is the lease duration of the lease update routine started before
+		startLeaseDuration time.Duration
+		expectedErr        string
 	}{
 		{
 			name:     "start lease update routine",
@@ -33,6 +38,9 @@ func TestLeaseUpdate(t *testing.T) {
 				leaseObj := actions[1].(clienttesting.UpdateActionImpl).Object
 				lastLeaseObj := actions[len(actions)-1].(clienttesting.UpdateActionImpl).Object
 				testinghelpers.AssertLeaseUpdated(t, leaseObj.(*coordinationv1.Lease), lastLeaseObj.(*coordinationv1.Lease))
+				if version := lastLeaseObj.(*coordinationv1.Lease).Annotations[helpers.AgentVersionAnnotation]; version != "v0.1.0" {
+					t.Errorf("expected the agent version annotated on the lease, but got %q", version)
+				}
 			},
 		},
 		{
@@ -48,6 +56,30 @@ func TestLeaseUpdate(t *testing.T) {
 			needToStartUpdateBefore: true,
 			validateActions:         testinghelpers.AssertNoMoreUpdates,
 		},
+		{
+			name:                    "revoke the acceptance of a managed cluster after lease update routine is started",
+			clusters:                []runtime.Object{testinghelpers.NewDeniedManagedCluster()},
+			needToStartUpdateBefore: true,
+			validateActions:         testinghelpers.AssertNoMoreUpdates,
+		},
+		{
+			name:                    "shrink the lease duration after lease update routine is started",
+			clusters:                []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			needToStartUpdateBefore: true,
+			startLeaseDuration:      time.Minute,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				// the lease is updated once the routine is started, and updated again with the shrunk duration
+				updateActions := 0
+				for _, action := range actions {
+					if action.GetVerb() == "update" {
+						updateActions++
+					}
+				}
+				if updateActions < 2 {
+					t.Errorf("expected the lease updated with the shrunk lease duration, but got %d updates", updateActions)
+				}
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -67,19 +99,25 @@ func TestLeaseUpdate(t *testing.T) {
 				hubClient:   hubClient,
 				clusterName: testinghelpers.TestManagedClusterName,
 				leaseName:   "managed-cluster-lease",
+				annotations: map[string]string{helpers.AgentVersionAnnotation: "v0.1.0"},
 				recorder:    eventstesting.NewTestingEventRecorder(t),
 			}
 
+			startLeaseDuration := time.Duration(testinghelpers.TestLeaseDurationSeconds) * time.Second
+			if c.startLeaseDuration != 0 {
+				startLeaseDuration = c.startLeaseDuration
+			}
 			if c.needToStartUpdateBefore {
-				leaseUpdater.start(context.TODO(), time.Duration(testinghelpers.TestLeaseDurationSeconds)*time.Second)
+				leaseUpdater.start(context.TODO(), startLeaseDuration)
 				// wait a few milliseconds to start the lease update routine
 				time.Sleep(200 * time.Millisecond)
 			}
 
 			ctrl := &managedClusterLeaseController{
-				clusterName:      testinghelpers.TestManagedClusterName,
-				hubClusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
-				leaseUpdater:     leaseUpdater,
+				clusterName:              testinghelpers.TestManagedClusterName,
+				hubClusterLister:         clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				lastLeaseDurationSeconds: int32(startLeaseDuration.Seconds()),
+				leaseUpdater:             leaseUpdater,
 			}
 			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
 			testinghelpers.AssertError(t, syncErr, c.expectedErr)
@@ -90,3 +128,47 @@ func TestLeaseUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestAgentLeaseAnnotations(t *testing.T) {
+	featureGate := featuregate.NewFeatureGate()
+	if err := featureGate.Add(map[featuregate.Feature]featuregate.FeatureSpec{
+		"FeatureB": {Default: true},
+		"FeatureA": {Default: true},
+		"FeatureC": {Default: false},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name                string
+		gitVersion          string
+		gitCommit           string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:       "agent built from a release",
+			gitVersion: "v0.11.0",
+			gitCommit:  "abcdef",
+			expectedAnnotations: map[string]string{
+				helpers.AgentVersionAnnotation:      "v0.11.0",
+				helpers.AgentBuildAnnotation:        "abcdef",
+				helpers.AgentFeatureGatesAnnotation: "FeatureA,FeatureB",
+			},
+		},
+		{
+			name: "agent not built from a release",
+			expectedAnnotations: map[string]string{
+				helpers.AgentFeatureGatesAnnotation: "FeatureA,FeatureB",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			annotations := AgentLeaseAnnotations(c.gitVersion, c.gitCommit, featureGate)
+			if !reflect.DeepEqual(annotations, c.expectedAnnotations) {
+				t.Errorf("expected annotations %v, but got %v", c.expectedAnnotations, annotations)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package managedcluster
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	// probeLatencyWindow is the number of the recent kube-apiserver probes the p95 latency is calculated on.
+	probeLatencyWindow = 20
+	// probeLatencyGranularity is the granularity of the latency reported in the Available condition, the
+	// latency is rounded up to it so that the condition is not updated by tiny fluctuations.
+	probeLatencyGranularity = 10 * time.Millisecond
+)
+
+var kubeAPIServerProbeLatency = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Name: "open_cluster_management_registration_agent_kube_apiserver_probe_latency_p95_seconds",
+		Help: "The p95 latency of the recent health probes of the kube-apiserver of the managed cluster.",
+	},
+	[]string{"cluster"},
+)
+
+var hubClientCertificateRemaining = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Name: "open_cluster_management_registration_agent_hub_client_certificate_remaining_seconds",
+		Help: "The seconds until the client certificate of the agent for the hub expires, it is negative once the certificate is expired.",
+	},
+	[]string{"cluster"},
+)
+
+func init() {
+	legacyregistry.MustRegister(kubeAPIServerProbeLatency)
+	legacyregistry.MustRegister(hubClientCertificateRemaining)
+}
+
+// probeLatencyTracker tracks the latency of the recent kube-apiserver probes of a managed cluster.
+type probeLatencyTracker struct {
+	clusterName string
+	lock        sync.Mutex
+	latencies   []time.Duration
+}
+
+func newProbeLatencyTracker(clusterName string) *probeLatencyTracker {
+	return &probeLatencyTracker{clusterName: clusterName}
+}
+
+// observe records the latency of a probe and returns the p95 latency of the recent probes rounded up to
+// probeLatencyGranularity. It is safe to call it on a nil tracker, which always returns 0.
+func (t *probeLatencyTracker) observe(latency time.Duration) time.Duration {
+	if t == nil {
+		return 0
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.latencies = append(t.latencies, latency)
+	if len(t.latencies) > probeLatencyWindow {
+		t.latencies = t.latencies[len(t.latencies)-probeLatencyWindow:]
+	}
+
+	sorted := make([]time.Duration, len(t.latencies))
+	copy(sorted, t.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	// the nearest-rank percentile
+	p95 := sorted[(len(sorted)*95+99)/100-1]
+
+	kubeAPIServerProbeLatency.WithLabelValues(t.clusterName).Set(p95.Seconds())
+	return (p95 + probeLatencyGranularity - 1) / probeLatencyGranularity * probeLatencyGranularity
+}
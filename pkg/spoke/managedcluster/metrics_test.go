@@ -0,0 +1,81 @@
+package managedcluster
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestProbeLatencyTracker(t *testing.T) {
+	cases := []struct {
+		name             string
+		latencies        []time.Duration
+		expectedP95      time.Duration
+		expectedRoundP95 time.Duration
+	}{
+		{
+			name:             "single probe",
+			latencies:        []time.Duration{3 * time.Millisecond},
+			expectedP95:      3 * time.Millisecond,
+			expectedRoundP95: 10 * time.Millisecond,
+		},
+		{
+			name: "a slow probe in the window",
+			latencies: append(repeatLatency(19, 20*time.Millisecond),
+				500*time.Millisecond),
+			expectedP95:      20 * time.Millisecond,
+			expectedRoundP95: 20 * time.Millisecond,
+		},
+		{
+			name: "slow probes out of the window",
+			latencies: append(repeatLatency(5, time.Second),
+				repeatLatency(20, 42*time.Millisecond)...),
+			expectedP95:      42 * time.Millisecond,
+			expectedRoundP95: 50 * time.Millisecond,
+		},
+		{
+			name: "degrading kube-apiserver",
+			latencies: append(repeatLatency(18, 10*time.Millisecond),
+				1500*time.Millisecond, 2*time.Second),
+			expectedP95:      1500 * time.Millisecond,
+			expectedRoundP95: 1500 * time.Millisecond,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tracker := newProbeLatencyTracker("cluster-" + c.name)
+			var p95 time.Duration
+			for _, latency := range c.latencies {
+				p95 = tracker.observe(latency)
+			}
+			if p95 != c.expectedRoundP95 {
+				t.Errorf("expected p95 latency %v, but got %v", c.expectedRoundP95, p95)
+			}
+
+			actual, err := testutil.GetGaugeMetricValue(kubeAPIServerProbeLatency.WithLabelValues("cluster-" + c.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != c.expectedP95.Seconds() {
+				t.Errorf("expected p95 latency metric %v, but got %v", c.expectedP95.Seconds(), actual)
+			}
+		})
+	}
+}
+
+func TestNilProbeLatencyTracker(t *testing.T) {
+	var tracker *probeLatencyTracker
+	if p95 := tracker.observe(time.Second); p95 != 0 {
+		t.Errorf("expected no p95 latency, but got %v", p95)
+	}
+}
+
+func repeatLatency(n int, latency time.Duration) []time.Duration {
+	latencies := []time.Duration{}
+	for i := 0; i < n; i++ {
+		latencies = append(latencies, latency)
+	}
+	return latencies
+}
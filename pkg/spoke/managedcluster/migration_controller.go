@@ -0,0 +1,210 @@
+package managedcluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+// hubMigrationController migrates the managed cluster to a new hub once the bootstrap kubeconfig of
+// the new hub is put in the hub-migration-bootstrap-kubeconfig secret of the cluster namespace on the
+// current hub. It
+//  1. writes the bootstrap kubeconfig of the new hub into the bootstrap kubeconfig secret of the agent;
+//  2. waits until the bootstrap kubeconfig file mounted from the secret is updated;
+//  3. reports the HubMigrated condition to the current hub, which stops accepting the managed cluster;
+//  4. removes the hub kubeconfig secret and files of the current hub, and restarts the agent, so that
+//     the agent bootstraps with the new hub with the same cluster name.
+type hubMigrationController struct {
+	clusterName               string
+	hubHost                   string
+	hubKubeClient             kubernetes.Interface
+	hubClusterClient          clientset.Interface
+	managementCoreClient      corev1client.CoreV1Interface
+	componentNamespace        string
+	bootstrapKubeconfigSecret string
+	bootstrapKubeconfigFile   string
+	hubKubeconfigSecret       string
+	hubKubeconfigDir          string
+	restartAgent              func()
+}
+
+// NewHubMigrationController creates a new hub migration controller on the managed cluster. The
+// restartAgent func is called once the agent is ready to bootstrap with the new hub.
+func NewHubMigrationController(
+	clusterName, hubHost string,
+	hubKubeClient kubernetes.Interface,
+	hubClusterClient clientset.Interface,
+	managementCoreClient corev1client.CoreV1Interface,
+	componentNamespace, bootstrapKubeconfigSecret, bootstrapKubeconfigFile string,
+	hubKubeconfigSecret, hubKubeconfigDir string,
+	restartAgent func(),
+	recorder events.Recorder) factory.Controller {
+	c := &hubMigrationController{
+		clusterName:               clusterName,
+		hubHost:                   hubHost,
+		hubKubeClient:             hubKubeClient,
+		hubClusterClient:          hubClusterClient,
+		managementCoreClient:      managementCoreClient,
+		componentNamespace:        componentNamespace,
+		bootstrapKubeconfigSecret: bootstrapKubeconfigSecret,
+		bootstrapKubeconfigFile:   bootstrapKubeconfigFile,
+		hubKubeconfigSecret:       hubKubeconfigSecret,
+		hubKubeconfigDir:          hubKubeconfigDir,
+		restartAgent:              restartAgent,
+	}
+
+	return factory.New().
+		WithSync(c.sync).
+		ResyncEvery(time.Minute).
+		ToController("HubMigrationController", recorder)
+}
+
+func (c *hubMigrationController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	secret, err := c.hubKubeClient.CoreV1().Secrets(c.clusterName).Get(ctx, helpers.HubMigrationBootstrapKubeconfigSecretName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil
+	case apierrors.IsForbidden(err):
+		// the hub does not support the migration or the managed cluster is not accepted any more
+		klog.V(4).Infof("Unable to get the hub migration secret of managed cluster %q: %v", c.clusterName, err)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	kubeconfig := secret.Data[clientcert.KubeconfigFile]
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		syncCtx.Recorder().Warningf("HubMigrationInvalid", "The bootstrap kubeconfig of the new hub in secret %s/%s is invalid: %v",
+			secret.Namespace, secret.Name, err)
+		return nil
+	}
+	if config.Host == c.hubHost {
+		syncCtx.Recorder().Warningf("HubMigrationInvalid", "The new hub %s in secret %s/%s is the current hub",
+			config.Host, secret.Namespace, secret.Name)
+		return nil
+	}
+
+	if err := c.applyBootstrapKubeconfigSecret(ctx, kubeconfig, syncCtx.Recorder()); err != nil {
+		return err
+	}
+
+	// the agent bootstraps with the file mounted from the bootstrap kubeconfig secret, it takes a while
+	// for the kubelet to update the file.
+	current, err := ioutil.ReadFile(path.Clean(c.bootstrapKubeconfigFile))
+	if err != nil {
+		return fmt.Errorf("unable to read the bootstrap kubeconfig file %q: %w", c.bootstrapKubeconfigFile, err)
+	}
+	if !bytes.Equal(current, kubeconfig) {
+		klog.Infof("Waiting for the bootstrap kubeconfig file %q to be updated with the new hub %s", c.bootstrapKubeconfigFile, config.Host)
+		return nil
+	}
+
+	// the current hub stops accepting the managed cluster once it is migrated, so the condition is
+	// reported after the agent is ready to bootstrap with the new hub.
+	cond := metav1.Condition{
+		Type:    helpers.ManagedClusterConditionHubMigrated,
+		Status:  metav1.ConditionTrue,
+		Reason:  "MigratedToNewHub",
+		Message: fmt.Sprintf("The managed cluster is migrated to the hub %s", config.Host),
+	}
+	if _, _, err := helpers.UpdateManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName,
+		helpers.UpdateManagedClusterConditionFn(cond)); err != nil {
+		return fmt.Errorf("unable to update status of managed cluster %q: %w", c.clusterName, err)
+	}
+
+	if err := c.removeHubKubeconfig(ctx); err != nil {
+		return err
+	}
+
+	syncCtx.Recorder().Eventf("HubMigrated", "The managed cluster %q is migrated to the hub %s, restarting the agent", c.clusterName, config.Host)
+	if c.restartAgent != nil {
+		c.restartAgent()
+	}
+	return nil
+}
+
+// applyBootstrapKubeconfigSecret writes the kubeconfig into the bootstrap kubeconfig secret.
+func (c *hubMigrationController) applyBootstrapKubeconfigSecret(ctx context.Context, kubeconfig []byte, recorder events.Recorder) error {
+	secret, err := c.managementCoreClient.Secrets(c.componentNamespace).Get(ctx, c.bootstrapKubeconfigSecret, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = c.managementCoreClient.Secrets(c.componentNamespace).Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: c.componentNamespace,
+				Name:      c.bootstrapKubeconfigSecret,
+			},
+			Data: map[string][]byte{clientcert.KubeconfigFile: kubeconfig},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		recorder.Eventf("BootstrapKubeconfigSecretCreated", "Secret %s/%s is created with the bootstrap kubeconfig of the new hub",
+			c.componentNamespace, c.bootstrapKubeconfigSecret)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(secret.Data[clientcert.KubeconfigFile], kubeconfig) {
+		return nil
+	}
+
+	secret = secret.DeepCopy()
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[clientcert.KubeconfigFile] = kubeconfig
+	if _, err := c.managementCoreClient.Secrets(c.componentNamespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	recorder.Eventf("BootstrapKubeconfigSecretUpdated", "Secret %s/%s is updated with the bootstrap kubeconfig of the new hub",
+		c.componentNamespace, c.bootstrapKubeconfigSecret)
+	return nil
+}
+
+// removeHubKubeconfig removes the hub kubeconfig secret and files of the current hub, only the cluster
+// name is kept in the hub kubeconfig dir so that the agent registers the same cluster to the new hub.
+func (c *hubMigrationController) removeHubKubeconfig(ctx context.Context) error {
+	err := c.managementCoreClient.Secrets(c.componentNamespace).Delete(ctx, c.hubKubeconfigSecret, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	entries, err := os.ReadDir(c.hubKubeconfigDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(path.Join(c.hubKubeconfigDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(c.hubKubeconfigDir, 0700); err != nil {
+		return fmt.Errorf("unable to create dir %q : %w", c.hubKubeconfigDir, err)
+	}
+	clusterNameFile := path.Join(c.hubKubeconfigDir, clientcert.ClusterNameFile)
+	if err := ioutil.WriteFile(clusterNameFile, []byte(c.clusterName), 0600); err != nil {
+		return fmt.Errorf("unable to write file %q: %w", clusterNameFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestHubMigrationSync(t *testing.T) {
+	// the server of the new hub is https://127.0.0.1:6001
+	newHubKubeconfig := testinghelpers.NewKubeconfig([]byte("key"), []byte("cert"))
+	newSecret := func(namespace, name string, kubeconfig []byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Data:       map[string][]byte{clientcert.KubeconfigFile: kubeconfig},
+		}
+	}
+
+	cases := []struct {
+		name                  string
+		hubHost               string
+		hubObjects            []runtime.Object
+		managementObjects     []runtime.Object
+		bootstrapKubeconfig   []byte
+		expectedManagement    []string
+		expectedBootstrap     []byte
+		expectedClusterStatus bool
+		expectedRestart       bool
+	}{
+		{
+			name:              "no migration",
+			hubHost:           "https://hub1.example.com:6443",
+			managementObjects: []runtime.Object{newSecret("test", "bootstrap-hub-kubeconfig", []byte("hub1"))},
+		},
+		{
+			name:    "invalid bootstrap kubeconfig of the new hub",
+			hubHost: "https://hub1.example.com:6443",
+			hubObjects: []runtime.Object{
+				newSecret(testinghelpers.TestManagedClusterName, helpers.HubMigrationBootstrapKubeconfigSecretName, []byte("invalid")),
+			},
+			managementObjects: []runtime.Object{newSecret("test", "bootstrap-hub-kubeconfig", []byte("hub1"))},
+		},
+		{
+			name:    "new hub is the current hub",
+			hubHost: "https://127.0.0.1:6001",
+			hubObjects: []runtime.Object{
+				newSecret(testinghelpers.TestManagedClusterName, helpers.HubMigrationBootstrapKubeconfigSecretName, newHubKubeconfig),
+			},
+			managementObjects: []runtime.Object{newSecret("test", "bootstrap-hub-kubeconfig", []byte("hub1"))},
+		},
+		{
+			name:    "bootstrap kubeconfig secret is updated",
+			hubHost: "https://hub1.example.com:6443",
+			hubObjects: []runtime.Object{
+				newSecret(testinghelpers.TestManagedClusterName, helpers.HubMigrationBootstrapKubeconfigSecretName, newHubKubeconfig),
+			},
+			managementObjects:   []runtime.Object{newSecret("test", "bootstrap-hub-kubeconfig", []byte("hub1"))},
+			bootstrapKubeconfig: []byte("hub1"),
+			expectedManagement:  []string{"get", "update"},
+			expectedBootstrap:   newHubKubeconfig,
+		},
+		{
+			name:    "bootstrap kubeconfig secret is created",
+			hubHost: "https://hub1.example.com:6443",
+			hubObjects: []runtime.Object{
+				newSecret(testinghelpers.TestManagedClusterName, helpers.HubMigrationBootstrapKubeconfigSecretName, newHubKubeconfig),
+			},
+			bootstrapKubeconfig: []byte("hub1"),
+			expectedManagement:  []string{"get", "create"},
+			expectedBootstrap:   newHubKubeconfig,
+		},
+		{
+			name:    "bootstrap kubeconfig file is updated",
+			hubHost: "https://hub1.example.com:6443",
+			hubObjects: []runtime.Object{
+				newSecret(testinghelpers.TestManagedClusterName, helpers.HubMigrationBootstrapKubeconfigSecretName, newHubKubeconfig),
+			},
+			managementObjects: []runtime.Object{
+				newSecret("test", "bootstrap-hub-kubeconfig", newHubKubeconfig),
+				newSecret("test", "hub-kubeconfig-secret", []byte("hub1")),
+			},
+			bootstrapKubeconfig:   newHubKubeconfig,
+			expectedManagement:    []string{"get", "delete"},
+			expectedBootstrap:     newHubKubeconfig,
+			expectedClusterStatus: true,
+			expectedRestart:       true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tempDir, err := ioutil.TempDir("", "testhubmigration")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			bootstrapKubeconfigFile := path.Join(tempDir, "bootstrap-kubeconfig")
+			testinghelpers.WriteFile(bootstrapKubeconfigFile, c.bootstrapKubeconfig)
+			hubKubeconfigDir := path.Join(tempDir, "hub-kubeconfig")
+			if err := os.MkdirAll(hubKubeconfigDir, 0700); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testinghelpers.WriteFile(path.Join(hubKubeconfigDir, clientcert.KubeconfigFile), []byte("hub1"))
+			testinghelpers.WriteFile(path.Join(hubKubeconfigDir, clientcert.TLSCertFile), []byte("cert"))
+
+			hubKubeClient := kubefake.NewSimpleClientset(c.hubObjects...)
+			hubClusterClient := clusterfake.NewSimpleClientset(testinghelpers.NewAcceptedManagedCluster())
+			managementKubeClient := kubefake.NewSimpleClientset(c.managementObjects...)
+
+			restarted := false
+			ctrl := &hubMigrationController{
+				clusterName:               testinghelpers.TestManagedClusterName,
+				hubHost:                   c.hubHost,
+				hubKubeClient:             hubKubeClient,
+				hubClusterClient:          hubClusterClient,
+				managementCoreClient:      managementKubeClient.CoreV1(),
+				componentNamespace:        "test",
+				bootstrapKubeconfigSecret: "bootstrap-hub-kubeconfig",
+				bootstrapKubeconfigFile:   bootstrapKubeconfigFile,
+				hubKubeconfigSecret:       "hub-kubeconfig-secret",
+				hubKubeconfigDir:          hubKubeconfigDir,
+				restartAgent:              func() { restarted = true },
+			}
+			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			if len(c.expectedManagement) == 0 {
+				testinghelpers.AssertNoActions(t, managementKubeClient.Actions())
+			} else {
+				testinghelpers.AssertActions(t, managementKubeClient.Actions(), c.expectedManagement...)
+			}
+
+			if len(c.expectedBootstrap) != 0 {
+				secret, err := managementKubeClient.CoreV1().Secrets("test").Get(context.TODO(), "bootstrap-hub-kubeconfig", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(secret.Data[clientcert.KubeconfigFile]) != string(c.expectedBootstrap) {
+					t.Errorf("expected bootstrap kubeconfig %q, but got %q", c.expectedBootstrap, secret.Data[clientcert.KubeconfigFile])
+				}
+			}
+
+			if !c.expectedClusterStatus {
+				testinghelpers.AssertNoActions(t, hubClusterClient.Actions())
+			} else {
+				testinghelpers.AssertActions(t, hubClusterClient.Actions(), "get", "patch")
+				patch := hubClusterClient.Actions()[1].(clienttesting.PatchActionImpl).Patch
+				managedCluster := &clusterv1.ManagedCluster{}
+				if err := json.Unmarshal(patch, managedCluster); err != nil {
+					t.Fatal(err)
+				}
+				testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, metav1.Condition{
+					Type:    helpers.ManagedClusterConditionHubMigrated,
+					Status:  metav1.ConditionTrue,
+					Reason:  "MigratedToNewHub",
+					Message: "The managed cluster is migrated to the hub https://127.0.0.1:6001",
+				})
+			}
+
+			if restarted != c.expectedRestart {
+				t.Errorf("expected agent restarted %t, but got %t", c.expectedRestart, restarted)
+			}
+
+			// only the cluster name is kept in the hub kubeconfig dir once the cluster is migrated
+			files, err := os.ReadDir(hubKubeconfigDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expectedFiles := []string{clientcert.KubeconfigFile, clientcert.TLSCertFile}
+			if c.expectedRestart {
+				expectedFiles = []string{clientcert.ClusterNameFile}
+			}
+			if len(files) != len(expectedFiles) {
+				t.Fatalf("expected files %v in hub kubeconfig dir, but got %v", expectedFiles, files)
+			}
+			for i, file := range files {
+				if file.Name() != expectedFiles[i] {
+					t.Errorf("expected files %v in hub kubeconfig dir, but got %v", expectedFiles, files)
+				}
+			}
+		})
+	}
+}
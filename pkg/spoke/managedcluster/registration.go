@@ -36,6 +36,7 @@ const (
 // NewClientCertForHubController returns a controller to
 // 1). Create a new client certificate and build a hub kubeconfig for the registration agent;
 // 2). Or rotate the client certificate referenced by the hub kubeconfig before it become expired;
+// The hub kubeconfig is kept in the secretStore if it is set, otherwise in the client cert secret.
 func NewClientCertForHubController(
 	clusterName string,
 	agentName string,
@@ -43,10 +44,13 @@ func NewClientCertForHubController(
 	clientCertSecretName string,
 	kubeconfigData []byte,
 	spokeSecretInformer corev1informers.SecretInformer,
+	secretStore clientcert.SecretStore,
 	csrControl clientcert.CSRControl,
 	csrExpirationSeconds int32,
+	rotationJitter *float64,
 	spokeKubeClient kubernetes.Interface,
 	statusUpdater clientcert.StatusUpdateFunc,
+	retryBackoff *helpers.RetryBackoff,
 	recorder events.Recorder,
 	controllerName string,
 ) factory.Controller {
@@ -64,6 +68,9 @@ func NewClientCertForHubController(
 			clientcert.AgentNameFile:   []byte(agentName),
 			clientcert.KubeconfigFile:  kubeconfigData,
 		},
+		RotationJitter: rotationJitter,
+		RetryBackoff:   retryBackoff,
+		SecretStore:    secretStore,
 	}
 
 	var csrExpirationSecondsInCSROption *int32
@@ -17,9 +17,12 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/storage/value"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
 )
 
 // hubKubeconfigSecretController watches the HubKubeconfig secret, if the secret is changed, this controller creates/updates the
@@ -29,12 +32,15 @@ type hubKubeconfigSecretController struct {
 	hubKubeconfigSecretNamespace string
 	hubKubeconfigSecretName      string
 	spokeCoreClient              corev1client.CoreV1Interface
+	transformer                  value.Transformer
 }
 
-// NewHubKubeconfigSecretController returns a new HubKubeconfigSecretController
+// NewHubKubeconfigSecretController returns a new HubKubeconfigSecretController, the data of the secret
+// encrypted with the transformer is decrypted before it is dumped if the transformer is not nil.
 func NewHubKubeconfigSecretController(
 	hubKubeconfigDir, hubKubeconfigSecretNamespace, hubKubeconfigSecretName string,
 	spokeCoreClient corev1client.CoreV1Interface,
+	transformer value.Transformer,
 	spokeSecretInformer corev1informers.SecretInformer,
 	recorder events.Recorder) factory.Controller {
 	s := &hubKubeconfigSecretController{
@@ -42,6 +48,7 @@ func NewHubKubeconfigSecretController(
 		hubKubeconfigSecretNamespace: hubKubeconfigSecretNamespace,
 		hubKubeconfigSecretName:      hubKubeconfigSecretName,
 		spokeCoreClient:              spokeCoreClient,
+		transformer:                  transformer,
 	}
 
 	return factory.New().
@@ -68,15 +75,17 @@ func NewHubKubeconfigSecretController(
 
 func (s *hubKubeconfigSecretController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
 	klog.V(4).Infof("Reconciling Hub KubeConfig secret %q", s.hubKubeconfigSecretName)
-	return DumpSecret(s.spokeCoreClient, s.hubKubeconfigSecretNamespace, s.hubKubeconfigSecretName, s.hubKubeconfigDir, ctx, syncCtx.Recorder())
+	return DumpSecret(s.spokeCoreClient, s.hubKubeconfigSecretNamespace, s.hubKubeconfigSecretName, s.hubKubeconfigDir, s.transformer, ctx, syncCtx.Recorder())
 }
 
 // DumpSecret dumps the data in the given seccret into a directory in file system.
-// The output directory will be created if not exists.
+// The output directory will be created if not exists. The data encrypted with the transformer, see
+// clientcert.EncryptedSecretKeys, is decrypted before it is dumped if the transformer is not nil.
 // TO DO: remove the file once the corresponding key is removed from secret.
 func DumpSecret(
 	coreV1Client corev1client.CoreV1Interface,
 	secretNamespace, secretName, outputDir string,
+	transformer value.Transformer,
 	ctx context.Context,
 	recorder events.Recorder) error {
 	secret, err := coreV1Client.Secrets(secretNamespace).Get(ctx, secretName, metav1.GetOptions{})
@@ -86,6 +95,12 @@ func DumpSecret(
 	if err != nil {
 		return fmt.Errorf("unable to get secret %s/%s : %w", secretNamespace, secretName, err)
 	}
+	if transformer != nil {
+		secret, _, err = clientcert.TransformSecretFromStorage(ctx, transformer, secret)
+		if err != nil {
+			return err
+		}
+	}
 
 	if err := os.MkdirAll(outputDir, 0700); err != nil {
 		return fmt.Errorf("unable to create dir %q : %w", outputDir, err)
@@ -1,6 +1,7 @@
 package managedcluster
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
@@ -15,6 +16,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apiserver/pkg/storage/value"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 )
 
@@ -23,6 +25,20 @@ const (
 	testSecretName = "testsecret"
 )
 
+// testTransformer encrypts the data by adding a prefix.
+type testTransformer struct{}
+
+func (testTransformer) TransformFromStorage(ctx context.Context, data []byte, dataCtx value.Context) ([]byte, bool, error) {
+	if !bytes.HasPrefix(data, []byte("encrypted:")) {
+		return nil, false, fmt.Errorf("data is not encrypted")
+	}
+	return bytes.TrimPrefix(data, []byte("encrypted:")), false, nil
+}
+
+func (testTransformer) TransformToStorage(ctx context.Context, data []byte, dataCtx value.Context) ([]byte, error) {
+	return append([]byte("encrypted:"), data...), nil
+}
+
 func TestDumpSecret(t *testing.T) {
 	testDir, err := ioutil.TempDir("", "dumpsecret")
 	if err != nil {
@@ -37,6 +53,7 @@ func TestDumpSecret(t *testing.T) {
 		queueKey      string
 		secret        *corev1.Secret
 		oldConfigData map[string][]byte
+		transformer   value.Transformer
 		validateFiles func(t *testing.T, fileDir string)
 	}{
 		{
@@ -98,6 +115,25 @@ func TestDumpSecret(t *testing.T) {
 				testinghelpers.AssertFileExist(t, path.Join(hubKubeconfigDir, clientcert.TLSCertFile))
 			},
 		},
+		{
+			name:     "secret is encrypted",
+			queueKey: testSecretName,
+			secret: testinghelpers.NewHubKubeconfigSecret(
+				testNamespace, testSecretName, "",
+				nil,
+				map[string][]byte{
+					clientcert.ClusterNameFile:     []byte("test"),
+					clientcert.EncryptedTLSKeyFile: []byte("encrypted:key"),
+					clientcert.TLSCertFile:         []byte("cert"),
+				},
+			),
+			transformer: testTransformer{},
+			validateFiles: func(t *testing.T, hubKubeconfigDir string) {
+				testinghelpers.AssertFileContent(t, path.Join(hubKubeconfigDir, clientcert.ClusterNameFile), []byte("test"))
+				testinghelpers.AssertFileContent(t, path.Join(hubKubeconfigDir, clientcert.TLSKeyFile), []byte("key"))
+				testinghelpers.AssertFileContent(t, path.Join(hubKubeconfigDir, clientcert.TLSCertFile), []byte("cert"))
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -111,7 +147,7 @@ func TestDumpSecret(t *testing.T) {
 				testinghelpers.WriteFile(path.Join(hubKubeconfigDir, k), v)
 			}
 
-			err = DumpSecret(kubeClient.CoreV1(), testNamespace, testSecretName, hubKubeconfigDir, context.TODO(), eventstesting.NewTestingEventRecorder(t))
+			err = DumpSecret(kubeClient.CoreV1(), testNamespace, testSecretName, hubKubeconfigDir, c.transformer, context.TODO(), eventstesting.NewTestingEventRecorder(t))
 			if err != nil {
 				t.Errorf("unexpected err: %v", err)
 			}
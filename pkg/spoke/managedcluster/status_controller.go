@@ -21,6 +21,7 @@ import (
 	discovery "k8s.io/client-go/discovery"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	corev1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
 )
 
 // managedClusterStatusController checks the kube-apiserver health on managed cluster to determine it whether is available
@@ -31,6 +32,13 @@ type managedClusterStatusController struct {
 	hubClusterLister              clusterv1listers.ManagedClusterLister
 	managedClusterDiscoveryClient discovery.DiscoveryInterface
 	nodeLister                    corev1lister.NodeLister
+	hubAccessReviewTrigger        *HubAccessReviewTrigger
+	// probeLatencies tracks the latency of the kube-apiserver probes, whose p95 is reported in the
+	// Available condition, so that a degrading but available cluster is visible on the hub.
+	probeLatencies *probeLatencyTracker
+	// revoked is true once the acceptance of the managed cluster is revoked, so that the denial is only
+	// surfaced once until the cluster is accepted again.
+	revoked bool
 }
 
 // NewManagedClusterStatusController creates a managed cluster status controller on managed cluster.
@@ -41,6 +49,7 @@ func NewManagedClusterStatusController(
 	managedClusterDiscoveryClient discovery.DiscoveryInterface,
 	nodeInformer corev1informers.NodeInformer,
 	resyncInterval time.Duration,
+	hubAccessReviewTrigger *HubAccessReviewTrigger,
 	recorder events.Recorder) factory.Controller {
 	c := &managedClusterStatusController{
 		clusterName:                   clusterName,
@@ -48,6 +57,8 @@ func NewManagedClusterStatusController(
 		hubClusterLister:              hubClusterInformer.Lister(),
 		managedClusterDiscoveryClient: managedClusterDiscoveryClient,
 		nodeLister:                    nodeInformer.Lister(),
+		hubAccessReviewTrigger:        hubAccessReviewTrigger,
+		probeLatencies:                newProbeLatencyTracker(clusterName),
 	}
 
 	return factory.New().
@@ -60,10 +71,23 @@ func NewManagedClusterStatusController(
 // sync updates managed cluster available condition by checking kube-apiserver health on managed cluster.
 // if the kube-apiserver is health, it will ensure that managed cluster resources and version are up to date.
 func (c *managedClusterStatusController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
-	if _, err := c.hubClusterLister.Get(c.clusterName); err != nil {
+	cluster, err := c.hubClusterLister.Get(c.clusterName)
+	if err != nil {
 		return fmt.Errorf("unable to get managed cluster %q from hub: %w", c.clusterName, err)
 	}
 
+	// the acceptance of the managed cluster is revoked, stop updating its status. The status is updated
+	// again once the cluster is accepted.
+	if message, revoked := acceptanceRevoked(cluster); revoked {
+		if !c.revoked {
+			klog.Warningf("Stop updating the status, %s", message)
+			syncCtx.Recorder().Warningf("ManagedClusterAcceptanceRevoked", "Stop updating the status, %s", message)
+		}
+		c.revoked = true
+		return nil
+	}
+	c.revoked = false
+
 	updateStatusFuncs := []helpers.UpdateManagedClusterStatusFunc{}
 
 	// check the kube-apiserver health on managed cluster.
@@ -90,6 +114,7 @@ func (c *managedClusterStatusController) sync(ctx context.Context, syncCtx facto
 
 	updateStatusFuncs = append(updateStatusFuncs, helpers.UpdateManagedClusterConditionFn(condition))
 	_, updated, err := helpers.UpdateManagedClusterStatus(ctx, c.hubClusterClient, c.clusterName, updateStatusFuncs...)
+	c.hubAccessReviewTrigger.ObserveError(err)
 	if err != nil {
 		return fmt.Errorf("unable to update status of managed cluster %q: %w", c.clusterName, err)
 	}
@@ -104,22 +129,25 @@ func (c *managedClusterStatusController) sync(ctx context.Context, syncCtx facto
 func (c *managedClusterStatusController) checkKubeAPIServerStatus(ctx context.Context) metav1.Condition {
 	statusCode := 0
 	condition := metav1.Condition{Type: clusterv1.ManagedClusterConditionAvailable}
+	start := time.Now()
 	result := c.managedClusterDiscoveryClient.RESTClient().Get().AbsPath("/livez").Do(ctx).StatusCode(&statusCode)
 	if statusCode == http.StatusOK {
 		condition.Status = metav1.ConditionTrue
 		condition.Reason = "ManagedClusterAvailable"
-		condition.Message = "Managed cluster is available"
+		condition.Message = c.availableMessage(time.Since(start))
 		return condition
 	}
 
 	// for backward compatible, the livez endpoint is supported from Kubernetes 1.16, so if the livez is not found or
 	// forbidden, the healthz endpoint will be used.
 	if statusCode == http.StatusNotFound || statusCode == http.StatusForbidden {
+		// only the latency of the healthz probe is recorded
+		start = time.Now()
 		result = c.managedClusterDiscoveryClient.RESTClient().Get().AbsPath("/healthz").Do(ctx).StatusCode(&statusCode)
 		if statusCode == http.StatusOK {
 			condition.Status = metav1.ConditionTrue
 			condition.Reason = "ManagedClusterAvailable"
-			condition.Message = "Managed cluster is available"
+			condition.Message = c.availableMessage(time.Since(start))
 			return condition
 		}
 	}
@@ -136,6 +164,16 @@ func (c *managedClusterStatusController) checkKubeAPIServerStatus(ctx context.Co
 	return condition
 }
 
+// availableMessage records the latency of the probe and returns the message of the Available condition
+// with the p95 latency of the recent probes.
+func (c *managedClusterStatusController) availableMessage(latency time.Duration) string {
+	p95 := c.probeLatencies.observe(latency)
+	if p95 == 0 {
+		return "Managed cluster is available"
+	}
+	return fmt.Sprintf("Managed cluster is available, the p95 latency of the kube-apiserver probes is %s", p95)
+}
+
 func (c *managedClusterStatusController) getClusterVersion() (*clusterv1.ManagedClusterVersion, error) {
 	serverVersion, err := c.managedClusterDiscoveryClient.ServerVersion()
 	if err != nil {
@@ -69,6 +69,7 @@ func TestHealthCheck(t *testing.T) {
 		nodes           []runtime.Object
 		httpStatus      int
 		responseMsg     string
+		probeLatencies  []time.Duration
 		validateActions func(t *testing.T, actions []clienttesting.Action)
 		expectedErr     string
 	}{
@@ -78,6 +79,12 @@ func TestHealthCheck(t *testing.T) {
 			validateActions: testinghelpers.AssertNoActions,
 			expectedErr:     "unable to get managed cluster \"testmanagedcluster\" from hub: managedcluster.cluster.open-cluster-management.io \"testmanagedcluster\" not found",
 		},
+		{
+			name:            "the acceptance of the managed cluster is revoked",
+			clusters:        []runtime.Object{testinghelpers.NewDeniedManagedCluster()},
+			httpStatus:      http.StatusOK,
+			validateActions: testinghelpers.AssertNoActions,
+		},
 		{
 			name:        "kube-apiserver is not health",
 			clusters:    []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
@@ -138,6 +145,29 @@ func TestHealthCheck(t *testing.T) {
 				testinghelpers.AssertManagedClusterStatus(t, managedCluster.Status, expectedStatus)
 			},
 		},
+		{
+			name:           "report the p95 latency of the probes",
+			clusters:       []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
+			nodes:          []runtime.Object{},
+			httpStatus:     http.StatusOK,
+			probeLatencies: repeatLatency(probeLatencyWindow, 195*time.Millisecond),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				expectedCondition := metav1.Condition{
+					Type:    clusterv1.ManagedClusterConditionAvailable,
+					Status:  metav1.ConditionTrue,
+					Reason:  "ManagedClusterAvailable",
+					Message: "Managed cluster is available, the p95 latency of the kube-apiserver probes is 200ms",
+				}
+				testinghelpers.AssertActions(t, actions, "get", "patch")
+				patch := actions[1].(clienttesting.PatchAction).GetPatch()
+				managedCluster := &clusterv1.ManagedCluster{}
+				err := json.Unmarshal(patch, managedCluster)
+				if err != nil {
+					t.Fatal(err)
+				}
+				testinghelpers.AssertCondition(t, managedCluster.Status.Conditions, expectedCondition)
+			},
+		},
 		{
 			name:       "there is no livez endpoint",
 			clusters:   []runtime.Object{testinghelpers.NewAcceptedManagedCluster()},
@@ -261,6 +291,12 @@ func TestHealthCheck(t *testing.T) {
 				managedClusterDiscoveryClient: discoveryClient,
 				nodeLister:                    kubeInformerFactory.Core().V1().Nodes().Lister(),
 			}
+			if len(c.probeLatencies) != 0 {
+				ctrl.probeLatencies = newProbeLatencyTracker(testinghelpers.TestManagedClusterName)
+				for _, latency := range c.probeLatencies {
+					ctrl.probeLatencies.observe(latency)
+				}
+			}
 			syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
 			testinghelpers.AssertError(t, syncErr, c.expectedErr)
 
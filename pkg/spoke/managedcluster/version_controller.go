@@ -0,0 +1,91 @@
+package managedcluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	clientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informer "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/version"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// agentVersionController publishes the version and the api compatibility level of the agent with the
+// annotations of the ManagedCluster on hub, so that the hub is able to find the agents which are too
+// old for it, e.g. to drive the upgrade of the agents of a fleet.
+type agentVersionController struct {
+	clusterName      string
+	hubClusterClient clientset.Interface
+	hubClusterLister clusterv1listers.ManagedClusterLister
+	annotations      map[string]string
+}
+
+// NewAgentVersionController creates a new agent version controller on the managed cluster. The
+// agentVersion is not published if it is empty, e.g. the agent is not built from a release.
+func NewAgentVersionController(
+	clusterName string,
+	agentVersion string,
+	hubClusterClient clientset.Interface,
+	hubClusterInformer clusterv1informer.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	annotations := map[string]string{
+		helpers.AgentAPICompatibilityLevelAnnotation: strconv.Itoa(version.APICompatibilityLevel),
+	}
+	if len(agentVersion) != 0 {
+		annotations[helpers.AgentVersionAnnotation] = agentVersion
+	}
+
+	c := &agentVersionController{
+		clusterName:      clusterName,
+		hubClusterClient: hubClusterClient,
+		hubClusterLister: hubClusterInformer.Lister(),
+		annotations:      annotations,
+	}
+
+	return factory.New().
+		WithInformers(hubClusterInformer.Informer()).
+		WithSync(c.sync).
+		ToController("AgentVersionController", recorder)
+}
+
+func (c *agentVersionController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	managedCluster, err := c.hubClusterLister.Get(c.clusterName)
+	if errors.IsNotFound(err) {
+		// the managed cluster is not created yet, the controller will be triggered once it is created
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get managed cluster %q from hub: %w", c.clusterName, err)
+	}
+
+	managedCluster = managedCluster.DeepCopy()
+	updated := false
+	for key, value := range c.annotations {
+		if managedCluster.Annotations[key] == value {
+			continue
+		}
+		if managedCluster.Annotations == nil {
+			managedCluster.Annotations = map[string]string{}
+		}
+		managedCluster.Annotations[key] = value
+		updated = true
+	}
+	if !updated {
+		return nil
+	}
+
+	if _, err := c.hubClusterClient.ClusterV1().ManagedClusters().Update(ctx, managedCluster, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to update the agent version of managed cluster %q on hub: %w", c.clusterName, err)
+	}
+	klog.V(4).Infof("The agent version of managed cluster %q is updated to %q", c.clusterName, c.annotations[helpers.AgentVersionAnnotation])
+	return nil
+}
@@ -0,0 +1,105 @@
+package managedcluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestAgentVersionSync(t *testing.T) {
+	cases := []struct {
+		name                string
+		agentVersion        string
+		annotations         map[string]string
+		noManagedCluster    bool
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:             "managed cluster is not created",
+			agentVersion:     "v0.11.0",
+			noManagedCluster: true,
+		},
+		{
+			name:         "publish the agent version",
+			agentVersion: "v0.11.0",
+			annotations:  map[string]string{"foo": "bar"},
+			expectedAnnotations: map[string]string{
+				"foo":                          "bar",
+				helpers.AgentVersionAnnotation: "v0.11.0",
+				helpers.AgentAPICompatibilityLevelAnnotation: "1",
+			},
+		},
+		{
+			name:         "update the agent version",
+			agentVersion: "v0.11.0",
+			annotations: map[string]string{
+				helpers.AgentVersionAnnotation:               "v0.10.0",
+				helpers.AgentAPICompatibilityLevelAnnotation: "1",
+			},
+			expectedAnnotations: map[string]string{
+				helpers.AgentVersionAnnotation:               "v0.11.0",
+				helpers.AgentAPICompatibilityLevelAnnotation: "1",
+			},
+		},
+		{
+			name:         "agent version is not changed",
+			agentVersion: "v0.11.0",
+			annotations: map[string]string{
+				helpers.AgentVersionAnnotation:               "v0.11.0",
+				helpers.AgentAPICompatibilityLevelAnnotation: "1",
+			},
+		},
+		{
+			name: "agent is not built from a release",
+			expectedAnnotations: map[string]string{
+				helpers.AgentAPICompatibilityLevelAnnotation: "1",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if !c.noManagedCluster {
+				managedCluster := testinghelpers.NewAcceptedManagedCluster()
+				managedCluster.Annotations = c.annotations
+				objects = append(objects, managedCluster)
+			}
+			clusterClient := clusterfake.NewSimpleClientset(objects...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			clusterInformer := clusterInformerFactory.Cluster().V1().ManagedClusters()
+			for _, cluster := range objects {
+				if err := clusterInformer.Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			syncCtx := testinghelpers.NewFakeSyncContext(t, testinghelpers.TestManagedClusterName)
+			ctrl := NewAgentVersionController(testinghelpers.TestManagedClusterName, c.agentVersion, clusterClient, clusterInformer, syncCtx.Recorder())
+			if err := ctrl.Sync(context.TODO(), syncCtx); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			actions := clusterClient.Actions()
+			if c.expectedAnnotations == nil {
+				testinghelpers.AssertNoActions(t, actions)
+				return
+			}
+			testinghelpers.AssertActions(t, actions, "update")
+			managedCluster := actions[0].(clienttesting.UpdateActionImpl).Object.(*clusterv1.ManagedCluster)
+			if !reflect.DeepEqual(managedCluster.Annotations, c.expectedAnnotations) {
+				t.Errorf("expected annotations %v, but got %v", c.expectedAnnotations, managedCluster.Annotations)
+			}
+		})
+	}
+}
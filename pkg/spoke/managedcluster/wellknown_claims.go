@@ -0,0 +1,164 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	corev1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// clusterVersionPath is the path of the ClusterVersion of an OpenShift cluster, which holds the cluster ID.
+const clusterVersionPath = "/apis/config.openshift.io/v1/clusterversions/version"
+
+// providerPlatforms maps the scheme of the provider ID of the nodes to the platform of the cluster.
+var providerPlatforms = map[string]string{
+	"aws":          "AWS",
+	"azure":        "Azure",
+	"gce":          "GCP",
+	"ibm":          "IBM",
+	"openstack":    "OpenStack",
+	"vsphere":      "VSphere",
+	"equinixmetal": "EquinixMetal",
+	"packet":       "EquinixMetal",
+	"alicloud":     "AliCloud",
+}
+
+// discoveryCacheTTL is the time to cache the kubernetes version and the openshift cluster ID, which are
+// rarely changed, so that the discovery requests are not sent on every sync.
+const discoveryCacheTTL = 30 * time.Minute
+
+// wellKnownClaimProvider synthesizes the well-known cluster claims from the managed cluster, so that
+// every cluster exposes a consistent baseline of claims without creating them manually.
+type wellKnownClaimProvider struct {
+	discoveryClient discovery.DiscoveryInterface
+	nodeLister      corev1lister.NodeLister
+
+	// the discovery results cached until expiresAt
+	kubeVersion string
+	clusterID   string
+	expiresAt   time.Time
+	// now is the local clock, it is replaced in the unit tests
+	now func() time.Time
+}
+
+// claims returns the well-known claims which can be resolved on the managed cluster, a claim is
+// omitted if its value is unknown, e.g. the nodes have no topology labels, or it cannot be resolved
+// for now, so that the other claims are still exposed.
+func (p *wellKnownClaimProvider) claims(ctx context.Context) []clusterv1.ManagedClusterClaim {
+	claims := []clusterv1.ManagedClusterClaim{}
+	addClaim := func(name, value string) {
+		if len(value) != 0 {
+			claims = append(claims, clusterv1.ManagedClusterClaim{Name: name, Value: value})
+		}
+	}
+
+	kubeVersion, clusterID := p.discover(ctx)
+	addClaim(helpers.ClusterClaimKubeVersion, kubeVersion)
+
+	nodes, err := p.nodeLister.List(labels.Everything())
+	if err != nil {
+		klog.Warningf("Unable to list nodes to synthesize the well-known cluster claims: %v", err)
+	}
+	addClaim(helpers.ClusterClaimPlatform, nodePlatform(nodes))
+	addClaim(helpers.ClusterClaimRegion, nodeLabelValue(nodes, corev1.LabelTopologyRegion))
+	addClaim(helpers.ClusterClaimZone, nodeLabelValue(nodes, corev1.LabelTopologyZone))
+
+	addClaim(helpers.ClusterClaimOpenShiftID, clusterID)
+
+	return claims
+}
+
+// discover returns the kubernetes version and the openshift cluster ID, they are cached for the
+// discoveryCacheTTL once both are resolved. A value is empty if it cannot be resolved.
+func (p *wellKnownClaimProvider) discover(ctx context.Context) (string, string) {
+	now := p.now()
+	if now.Before(p.expiresAt) {
+		return p.kubeVersion, p.clusterID
+	}
+
+	var kubeVersion string
+	serverVersion, versionErr := p.discoveryClient.ServerVersion()
+	if versionErr != nil {
+		klog.Warningf("Unable to get the kubernetes version to synthesize the well-known cluster claims: %v", versionErr)
+	} else {
+		kubeVersion = serverVersion.String()
+	}
+
+	clusterID, clusterIDErr := p.openShiftClusterID(ctx)
+	if clusterIDErr != nil {
+		klog.Warningf("Unable to get the openshift cluster ID to synthesize the well-known cluster claims: %v", clusterIDErr)
+	}
+
+	if versionErr == nil && clusterIDErr == nil {
+		p.kubeVersion, p.clusterID, p.expiresAt = kubeVersion, clusterID, now.Add(discoveryCacheTTL)
+	}
+	return kubeVersion, clusterID
+}
+
+// openShiftClusterID returns the cluster ID in the ClusterVersion, it is empty if the cluster is not an
+// OpenShift cluster.
+func (p *wellKnownClaimProvider) openShiftClusterID(ctx context.Context) (string, error) {
+	data, err := p.discoveryClient.RESTClient().Get().AbsPath(clusterVersionPath).Do(ctx).Raw()
+	// the ClusterVersion does not exist on a non-OpenShift cluster
+	if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	clusterVersion := struct {
+		Spec struct {
+			ClusterID string `json:"clusterID"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(data, &clusterVersion); err != nil {
+		return "", err
+	}
+	return clusterVersion.Spec.ClusterID, nil
+}
+
+// nodePlatform returns the platform by the provider ID of the nodes, it is empty if the provider is unknown.
+func nodePlatform(nodes []*corev1.Node) string {
+	platforms := sets.NewString()
+	for _, node := range nodes {
+		scheme, _, found := strings.Cut(node.Spec.ProviderID, "://")
+		if !found {
+			continue
+		}
+		if platform, ok := providerPlatforms[scheme]; ok {
+			platforms.Insert(platform)
+		}
+	}
+	// the platform is ambiguous if the nodes are on different platforms
+	if platforms.Len() != 1 {
+		return ""
+	}
+	return platforms.List()[0]
+}
+
+// nodeLabelValue returns the value of the label on the nodes, it is empty if the nodes have different
+// values, e.g. the nodes are in multiple zones, so that the value is always a valid label value.
+func nodeLabelValue(nodes []*corev1.Node, key string) string {
+	values := sets.NewString()
+	for _, node := range nodes {
+		if value := node.Labels[key]; len(value) != 0 {
+			values.Insert(value)
+		}
+	}
+	if values.Len() != 1 {
+		return ""
+	}
+	return values.List()[0]
+}
@@ -0,0 +1,282 @@
+package managedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newClusterNode(name, providerID, region, zone string) *corev1.Node {
+	node := testinghelpers.NewNode(name, testinghelpers.NewResourceList(32, 64), testinghelpers.NewResourceList(16, 32))
+	node.Spec.ProviderID = providerID
+	node.Labels = map[string]string{}
+	if len(region) != 0 {
+		node.Labels[corev1.LabelTopologyRegion] = region
+	}
+	if len(zone) != 0 {
+		node.Labels[corev1.LabelTopologyZone] = zone
+	}
+	return node
+}
+
+func newWellKnownClaimServer(t *testing.T, clusterID string) *httptest.Server {
+	writeJSON := func(w http.ResponseWriter, obj interface{}) {
+		output, err := json.Marshal(obj)
+		if err != nil {
+			t.Errorf("unexpected encoding error: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(output); err != nil {
+			t.Error(err)
+		}
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/version":
+			writeJSON(w, version.Info{GitVersion: "v1.26.3"})
+		case req.URL.Path == clusterVersionPath && len(clusterID) != 0:
+			writeJSON(w, map[string]interface{}{
+				"spec": map[string]interface{}{"clusterID": clusterID},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestWellKnownClaims(t *testing.T) {
+	cases := []struct {
+		name           string
+		nodes          []*corev1.Node
+		clusterID      string
+		expectedClaims []clusterv1.ManagedClusterClaim
+	}{
+		{
+			name: "no nodes",
+			expectedClaims: []clusterv1.ManagedClusterClaim{
+				{Name: helpers.ClusterClaimKubeVersion, Value: "v1.26.3"},
+			},
+		},
+		{
+			name: "nodes on a cloud provider",
+			nodes: []*corev1.Node{
+				newClusterNode("node1", "aws:///us-east-1a/i-1", "us-east-1", "us-east-1a"),
+				newClusterNode("node2", "aws:///us-east-1b/i-2", "us-east-1", "us-east-1b"),
+			},
+			expectedClaims: []clusterv1.ManagedClusterClaim{
+				{Name: helpers.ClusterClaimKubeVersion, Value: "v1.26.3"},
+				{Name: helpers.ClusterClaimPlatform, Value: "AWS"},
+				{Name: helpers.ClusterClaimRegion, Value: "us-east-1"},
+			},
+		},
+		{
+			name: "nodes in a single zone",
+			nodes: []*corev1.Node{
+				newClusterNode("node1", "aws:///us-east-1a/i-1", "us-east-1", "us-east-1a"),
+				newClusterNode("node2", "aws:///us-east-1a/i-2", "us-east-1", "us-east-1a"),
+			},
+			expectedClaims: []clusterv1.ManagedClusterClaim{
+				{Name: helpers.ClusterClaimKubeVersion, Value: "v1.26.3"},
+				{Name: helpers.ClusterClaimPlatform, Value: "AWS"},
+				{Name: helpers.ClusterClaimRegion, Value: "us-east-1"},
+				{Name: helpers.ClusterClaimZone, Value: "us-east-1a"},
+			},
+		},
+		{
+			name: "nodes on different platforms",
+			nodes: []*corev1.Node{
+				newClusterNode("node1", "aws:///us-east-1a/i-1", "", ""),
+				newClusterNode("node2", "gce://project/us-central1-a/node2", "", ""),
+				newClusterNode("node3", "", "", ""),
+			},
+			expectedClaims: []clusterv1.ManagedClusterClaim{
+				{Name: helpers.ClusterClaimKubeVersion, Value: "v1.26.3"},
+			},
+		},
+		{
+			name:      "openshift cluster",
+			nodes:     []*corev1.Node{newClusterNode("node1", "unknown://node1", "", "")},
+			clusterID: "cluster-id",
+			expectedClaims: []clusterv1.ManagedClusterClaim{
+				{Name: helpers.ClusterClaimKubeVersion, Value: "v1.26.3"},
+				{Name: helpers.ClusterClaimOpenShiftID, Value: "cluster-id"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			apiServer := newWellKnownClaimServer(t, c.clusterID)
+			defer apiServer.Close()
+
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), time.Minute*10)
+			for _, node := range c.nodes {
+				if err := kubeInformerFactory.Core().V1().Nodes().Informer().GetStore().Add(node); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			provider := &wellKnownClaimProvider{
+				discoveryClient: discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{Host: apiServer.URL}),
+				nodeLister:      kubeInformerFactory.Core().V1().Nodes().Lister(),
+				now:             time.Now,
+			}
+			claims := provider.claims(context.TODO())
+			if !reflect.DeepEqual(claims, c.expectedClaims) {
+				t.Errorf("expected claims %v but got: %v", c.expectedClaims, claims)
+			}
+		})
+	}
+}
+
+func TestSyncWellKnownClaims(t *testing.T) {
+	apiServer := newWellKnownClaimServer(t, "")
+	defer apiServer.Close()
+
+	cluster := testinghelpers.NewJoinedManagedCluster()
+	clusterClient := clusterfake.NewSimpleClientset(cluster)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+	if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+		t.Fatal(err)
+	}
+	// the claim created on the managed cluster takes precedence over the synthesized one
+	claims := []*clusterv1alpha1.ClusterClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: helpers.ClusterClaimPlatform},
+			Spec:       clusterv1alpha1.ClusterClaimSpec{Value: "BareMetal"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "a"},
+			Spec:       clusterv1alpha1.ClusterClaimSpec{Value: "b"},
+		},
+	}
+	for _, claim := range claims {
+		if err := clusterInformerFactory.Cluster().V1alpha1().ClusterClaims().Informer().GetStore().Add(claim); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), time.Minute*10)
+	if err := kubeInformerFactory.Core().V1().Nodes().Informer().GetStore().Add(
+		newClusterNode("node1", "aws:///us-east-1a/i-1", "us-east-1", "us-east-1a")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := managedClusterClaimController{
+		clusterName:            testinghelpers.TestManagedClusterName,
+		maxCustomClusterClaims: 0,
+		hubClusterClient:       clusterClient,
+		hubClusterLister:       clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+		claimLister:            clusterInformerFactory.Cluster().V1alpha1().ClusterClaims().Lister(),
+		wellKnownClaims: &wellKnownClaimProvider{
+			discoveryClient: discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{Host: apiServer.URL}),
+			nodeLister:      kubeInformerFactory.Core().V1().Nodes().Lister(),
+			now:             time.Now,
+		},
+	}
+
+	syncErr := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, ""))
+	testinghelpers.AssertError(t, syncErr, "")
+
+	actions := clusterClient.Actions()
+	testinghelpers.AssertActions(t, actions, "get", "patch")
+	patch := actions[1].(clienttesting.PatchAction).GetPatch()
+	patchedCluster := &clusterv1.ManagedCluster{}
+	if err := json.Unmarshal(patch, patchedCluster); err != nil {
+		t.Fatal(err)
+	}
+	// the well-known claims are not truncated with the custom claims
+	expected := []clusterv1.ManagedClusterClaim{
+		{Name: helpers.ClusterClaimKubeVersion, Value: "v1.26.3"},
+		{Name: helpers.ClusterClaimPlatform, Value: "BareMetal"},
+		{Name: helpers.ClusterClaimRegion, Value: "us-east-1"},
+		{Name: helpers.ClusterClaimZone, Value: "us-east-1a"},
+	}
+	if !reflect.DeepEqual(patchedCluster.Status.ClusterClaims, expected) {
+		t.Errorf("expected cluster claims %v but got: %v", expected, patchedCluster.Status.ClusterClaims)
+	}
+}
+
+func TestWellKnownClaimsDiscovery(t *testing.T) {
+	requests := 0
+	failed := true
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/version" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+		if failed {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(version.Info{GitVersion: "v1.26.3"}); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer apiServer.Close()
+
+	now := time.Now()
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), time.Minute*10)
+	if err := kubeInformerFactory.Core().V1().Nodes().Informer().GetStore().Add(
+		newClusterNode("node1", "aws:///us-east-1a/i-1", "", "")); err != nil {
+		t.Fatal(err)
+	}
+	provider := &wellKnownClaimProvider{
+		discoveryClient: discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{Host: apiServer.URL}),
+		nodeLister:      kubeInformerFactory.Core().V1().Nodes().Lister(),
+		now:             func() time.Time { return now },
+	}
+
+	// the kubernetes version is skipped if it cannot be got, the other claims are still synthesized
+	expected := []clusterv1.ManagedClusterClaim{{Name: helpers.ClusterClaimPlatform, Value: "AWS"}}
+	if claims := provider.claims(context.TODO()); !reflect.DeepEqual(claims, expected) {
+		t.Errorf("expected claims %v but got: %v", expected, claims)
+	}
+
+	// the discovery result is cached once it is got
+	failed = false
+	expected = []clusterv1.ManagedClusterClaim{
+		{Name: helpers.ClusterClaimKubeVersion, Value: "v1.26.3"},
+		{Name: helpers.ClusterClaimPlatform, Value: "AWS"},
+	}
+	for i := 0; i < 2; i++ {
+		if claims := provider.claims(context.TODO()); !reflect.DeepEqual(claims, expected) {
+			t.Errorf("expected claims %v but got: %v", expected, claims)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("expected the kubernetes version requested 2 times, but got %d", requests)
+	}
+
+	// the cache expires after the ttl
+	now = now.Add(discoveryCacheTTL)
+	provider.claims(context.TODO())
+	if requests != 3 {
+		t.Errorf("expected the kubernetes version requested 3 times, but got %d", requests)
+	}
+}
@@ -0,0 +1,146 @@
+package spoke
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+)
+
+// SpokeAgentOption customizes the clients and informer factories used by RunSpokeAgentWithContext.
+// It allows a project which embeds the registration agent to share its own clients and informer
+// factories with the agent instead of having the agent construct them internally.
+type SpokeAgentOption func(*spokeAgentConfig)
+
+// spokeAgentConfig holds the clients and informer factories of the management and spoke clusters.
+// The clients and informers of the hub cluster are always built by the agent because they depend
+// on the hub kubeconfig which is produced during the bootstrap.
+type spokeAgentConfig struct {
+	eventRecorder events.Recorder
+
+	managementKubeClient                    kubernetes.Interface
+	namespacedManagementKubeInformerFactory informers.SharedInformerFactory
+
+	spokeClientConfig           *rest.Config
+	spokeKubeClient             kubernetes.Interface
+	spokeClusterClient          clusterv1client.Interface
+	spokeKubeInformerFactory    informers.SharedInformerFactory
+	spokeClusterInformerFactory clusterv1informers.SharedInformerFactory
+}
+
+// WithEventRecorder sets the event recorder used by the agent controllers.
+func WithEventRecorder(recorder events.Recorder) SpokeAgentOption {
+	return func(c *spokeAgentConfig) {
+		c.eventRecorder = recorder
+	}
+}
+
+// WithManagementKubeClient sets the kube client of the cluster where the agent runs.
+func WithManagementKubeClient(client kubernetes.Interface) SpokeAgentOption {
+	return func(c *spokeAgentConfig) {
+		c.managementKubeClient = client
+	}
+}
+
+// WithManagementKubeInformerFactory sets the informer factory of the cluster where the agent runs.
+// The factory must be scoped to the component namespace of the agent.
+func WithManagementKubeInformerFactory(factory informers.SharedInformerFactory) SpokeAgentOption {
+	return func(c *spokeAgentConfig) {
+		c.namespacedManagementKubeInformerFactory = factory
+	}
+}
+
+// WithSpokeClientConfig sets the client config of the managed cluster. It takes precedence
+// over the SpokeKubeconfig of the SpokeAgentOptions.
+func WithSpokeClientConfig(config *rest.Config) SpokeAgentOption {
+	return func(c *spokeAgentConfig) {
+		c.spokeClientConfig = config
+	}
+}
+
+// WithSpokeKubeClient sets the kube client of the managed cluster.
+func WithSpokeKubeClient(client kubernetes.Interface) SpokeAgentOption {
+	return func(c *spokeAgentConfig) {
+		c.spokeKubeClient = client
+	}
+}
+
+// WithSpokeClusterClient sets the cluster client of the managed cluster.
+func WithSpokeClusterClient(client clusterv1client.Interface) SpokeAgentOption {
+	return func(c *spokeAgentConfig) {
+		c.spokeClusterClient = client
+	}
+}
+
+// WithSpokeKubeInformerFactory sets the kube informer factory of the managed cluster.
+func WithSpokeKubeInformerFactory(factory informers.SharedInformerFactory) SpokeAgentOption {
+	return func(c *spokeAgentConfig) {
+		c.spokeKubeInformerFactory = factory
+	}
+}
+
+// WithSpokeClusterInformerFactory sets the cluster informer factory of the managed cluster.
+func WithSpokeClusterInformerFactory(factory clusterv1informers.SharedInformerFactory) SpokeAgentOption {
+	return func(c *spokeAgentConfig) {
+		c.spokeClusterInformerFactory = factory
+	}
+}
+
+// newSpokeAgentConfig applies the options and builds the clients and informer factories which
+// are not provided from the management cluster config.
+func newSpokeAgentConfig(cfg *rest.Config, o *SpokeAgentOptions, opts ...SpokeAgentOption) (*spokeAgentConfig, error) {
+	c := &spokeAgentConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	var err error
+	if c.eventRecorder == nil {
+		c.eventRecorder = events.NewLoggingEventRecorder("registration-agent")
+	}
+
+	if c.managementKubeClient == nil {
+		if cfg == nil {
+			return nil, fmt.Errorf("either the management cluster config or the management kube client is required")
+		}
+		if c.managementKubeClient, err = kubernetes.NewForConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// the registration agent may not running in the spoke/managed cluster.
+	if c.spokeClientConfig == nil {
+		if c.spokeClientConfig, err = o.spokeKubeConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if c.spokeClientConfig == nil {
+		return nil, fmt.Errorf("the spoke cluster config is required")
+	}
+
+	if c.spokeKubeClient == nil {
+		if c.spokeKubeClient, err = kubernetes.NewForConfig(c.spokeClientConfig); err != nil {
+			return nil, err
+		}
+	}
+	if c.spokeClusterClient == nil {
+		if c.spokeClusterClient, err = clusterv1client.NewForConfig(c.spokeClientConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.spokeKubeInformerFactory == nil {
+		c.spokeKubeInformerFactory = informers.NewSharedInformerFactory(c.spokeKubeClient, 10*time.Minute)
+	}
+	if c.spokeClusterInformerFactory == nil {
+		c.spokeClusterInformerFactory = clusterv1informers.NewSharedInformerFactory(c.spokeClusterClient, 10*time.Minute)
+	}
+
+	return c, nil
+}
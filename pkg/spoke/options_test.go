@@ -0,0 +1,82 @@
+package spoke
+
+import (
+	"testing"
+
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestNewSpokeAgentConfig(t *testing.T) {
+	managementKubeClient := kubefake.NewSimpleClientset()
+	spokeKubeClient := kubefake.NewSimpleClientset()
+	spokeClusterClient := clusterfake.NewSimpleClientset()
+	spokeClientConfig := &rest.Config{Host: "https://spoke:6443"}
+
+	cases := []struct {
+		name        string
+		cfg         *rest.Config
+		options     *SpokeAgentOptions
+		opts        []SpokeAgentOption
+		expectedErr string
+		validate    func(t *testing.T, c *spokeAgentConfig)
+	}{
+		{
+			name:        "no management config",
+			options:     &SpokeAgentOptions{},
+			expectedErr: "either the management cluster config or the management kube client is required",
+		},
+		{
+			name:        "no spoke config",
+			options:     &SpokeAgentOptions{},
+			opts:        []SpokeAgentOption{WithManagementKubeClient(managementKubeClient)},
+			expectedErr: "the spoke cluster config is required",
+		},
+		{
+			name:    "build from management config",
+			cfg:     &rest.Config{Host: "https://management:6443"},
+			options: &SpokeAgentOptions{},
+			validate: func(t *testing.T, c *spokeAgentConfig) {
+				if c.spokeClientConfig.Host != "https://management:6443" {
+					t.Errorf("expected the management config is used for the spoke, but got %q", c.spokeClientConfig.Host)
+				}
+				if c.eventRecorder == nil || c.managementKubeClient == nil || c.spokeKubeClient == nil || c.spokeClusterClient == nil {
+					t.Errorf("expected the recorder and clients are built")
+				}
+				if c.spokeKubeInformerFactory == nil || c.spokeClusterInformerFactory == nil {
+					t.Errorf("expected the informer factories are built")
+				}
+			},
+		},
+		{
+			name:    "use provided clients",
+			options: &SpokeAgentOptions{},
+			opts: []SpokeAgentOption{
+				WithManagementKubeClient(managementKubeClient),
+				WithSpokeClientConfig(spokeClientConfig),
+				WithSpokeKubeClient(spokeKubeClient),
+				WithSpokeClusterClient(spokeClusterClient),
+			},
+			validate: func(t *testing.T, c *spokeAgentConfig) {
+				if c.spokeClientConfig != spokeClientConfig {
+					t.Errorf("expected the provided spoke config is used")
+				}
+				if c.managementKubeClient != managementKubeClient || c.spokeKubeClient != spokeKubeClient || c.spokeClusterClient != spokeClusterClient {
+					t.Errorf("expected the provided clients are used")
+				}
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			agentConfig, err := newSpokeAgentConfig(c.cfg, c.options, c.opts...)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if c.validate != nil {
+				c.validate(t, agentConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package registration
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const (
+	// AWSIRSADriverName is the name of the driver which accesses an EKS hub with the AWS IAM role
+	// assumed by the agent, e.g. through IAM roles for service accounts (IRSA).
+	AWSIRSADriverName = "awsirsa"
+
+	// awsExecCommand is the command used by the exec credential plugin to get a token of an EKS cluster
+	awsExecCommand = "aws"
+)
+
+var _ RegistrationDriver = &awsIRSADriver{}
+
+// awsIRSADriver builds a hub kubeconfig which uses an exec credential plugin to get a token of the EKS
+// hub with the AWS IAM role of the agent, instead of a client certificate. No CSR is created, the
+// IAM role is mapped to the identity of the managed cluster on the hub once the cluster is accepted.
+type awsIRSADriver struct {
+	Options
+}
+
+// IsHubKubeconfigValid returns true if the KubeconfigFile exists and is built for the current cluster/agent.
+func (d *awsIRSADriver) IsHubKubeconfigValid() (bool, error) {
+	kubeconfigPath := path.Join(d.HubKubeconfigDir, clientcert.KubeconfigFile)
+	if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) {
+		klog.V(4).Infof("Kubeconfig file %q not found", kubeconfigPath)
+		return false, nil
+	}
+
+	clusterName, err := ioutil.ReadFile(path.Clean(path.Join(d.HubKubeconfigDir, clientcert.ClusterNameFile)))
+	if err != nil || string(clusterName) != d.ClusterName {
+		return false, nil
+	}
+	agentName, err := ioutil.ReadFile(path.Clean(path.Join(d.HubKubeconfigDir, clientcert.AgentNameFile)))
+	if err != nil || string(agentName) != d.AgentName {
+		return false, nil
+	}
+	return true, nil
+}
+
+// NewCredentialController returns a controller which writes the hub kubeconfig with the exec credential
+// plugin into the hub kubeconfig secret.
+func (d *awsIRSADriver) NewCredentialController(
+	hubClientConfig *rest.Config,
+	_ kubernetes.Interface,
+	_ informers.SharedInformerFactory,
+	managementSecretInformer corev1informers.SecretInformer,
+	_ clientcert.StatusUpdateFunc,
+	// the controller does not send requests to the hub
+	_ *helpers.RetryBackoff,
+	controllerName string,
+) (factory.Controller, error) {
+	kubeconfigData, err := buildAWSIRSAKubeconfig(hubClientConfig, d.HubProxyURL, d.HubClusterARN, d.ManagedClusterRoleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &awsIRSAController{
+		awsIRSADriver:        d,
+		kubeconfigData:       kubeconfigData,
+		managementCoreClient: d.ManagementKubeClient.CoreV1(),
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			// only enqueue the hub kubeconfig secret
+			return accessor.GetNamespace() == d.ComponentNamespace && accessor.GetName() == d.HubKubeconfigSecret
+		}, managementSecretInformer.Informer()).
+		WithSync(c.sync).
+		ResyncEvery(5*time.Minute).
+		ToController(controllerName, d.Recorder), nil
+}
+
+// parseEKSClusterARN returns the region and the name of the EKS cluster from its ARN, which is in
+// the form of arn:aws:eks:<region>:<account-id>:cluster/<cluster-name>.
+func parseEKSClusterARN(clusterARN string) (string, string, error) {
+	parts := strings.Split(clusterARN, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "eks" {
+		return "", "", fmt.Errorf("%q is not a valid EKS cluster ARN", clusterARN)
+	}
+	resource := strings.SplitN(parts[5], "/", 2)
+	if len(resource) != 2 || resource[0] != "cluster" || len(resource[1]) == 0 {
+		return "", "", fmt.Errorf("%q is not a valid EKS cluster ARN", clusterARN)
+	}
+	return parts[3], resource[1], nil
+}
+
+// ValidateEKSClusterARN returns an error if the ARN is not the ARN of an EKS cluster.
+func ValidateEKSClusterARN(clusterARN string) error {
+	_, _, err := parseEKSClusterARN(clusterARN)
+	return err
+}
+
+// buildAWSIRSAKubeconfig builds a kubeconfig which gets a token of the EKS hub with the aws cli,
+// the same as the kubeconfig generated by 'aws eks update-kubeconfig'.
+func buildAWSIRSAKubeconfig(hubClientConfig *rest.Config, proxyURL, hubClusterARN, roleARN string) ([]byte, error) {
+	region, hubClusterName, err := parseEKSClusterARN(hubClusterARN)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"--region", region, "eks", "get-token", "--cluster-name", hubClusterName, "--output", "json"}
+	if len(roleARN) != 0 {
+		args = append(args, "--role-arn", roleARN)
+	}
+
+	kubeconfig := clientcert.BuildKubeconfig(hubClientConfig, proxyURL, "", "")
+	kubeconfig.AuthInfos["default-auth"] = &clientcmdapi.AuthInfo{
+		Exec: &clientcmdapi.ExecConfig{
+			APIVersion:      "client.authentication.k8s.io/v1beta1",
+			Command:         awsExecCommand,
+			Args:            args,
+			InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+		},
+	}
+	return clientcmd.Write(kubeconfig)
+}
+
+// awsIRSAController keeps the kubeconfig in the hub kubeconfig secret up to date.
+type awsIRSAController struct {
+	*awsIRSADriver
+	kubeconfigData       []byte
+	managementCoreClient corev1client.CoreV1Interface
+}
+
+func (c *awsIRSAController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	updated, err := applyHubKubeconfigSecret(ctx, c.managementCoreClient, c.ComponentNamespace, c.HubKubeconfigSecret,
+		map[string][]byte{
+			clientcert.ClusterNameFile: []byte(c.ClusterName),
+			clientcert.AgentNameFile:   []byte(c.AgentName),
+			clientcert.KubeconfigFile:  c.kubeconfigData,
+		})
+	if err != nil {
+		return err
+	}
+	if updated {
+		syncCtx.Recorder().Eventf("HubKubeconfigUpdated", "The hub kubeconfig with AWS IAM authentication for %s is updated", c.ClusterName)
+	}
+	return nil
+}
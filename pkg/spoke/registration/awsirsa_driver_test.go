@@ -0,0 +1,63 @@
+package registration
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestBuildAWSIRSAKubeconfig(t *testing.T) {
+	cases := []struct {
+		name          string
+		hubClusterARN string
+		roleARN       string
+		expectedArgs  []string
+		expectedErr   bool
+	}{
+		{
+			name:          "invalid hub cluster arn",
+			hubClusterARN: "arn:aws:iam::123456789012:role/hub",
+			expectedErr:   true,
+		},
+		{
+			name:          "without role arn",
+			hubClusterARN: "arn:aws:eks:us-west-2:123456789012:cluster/hub",
+			expectedArgs:  []string{"--region", "us-west-2", "eks", "get-token", "--cluster-name", "hub", "--output", "json"},
+		},
+		{
+			name:          "with role arn",
+			hubClusterARN: "arn:aws:eks:us-west-2:123456789012:cluster/hub",
+			roleARN:       "arn:aws:iam::123456789012:role/cluster1",
+			expectedArgs: []string{"--region", "us-west-2", "eks", "get-token", "--cluster-name", "hub", "--output", "json",
+				"--role-arn", "arn:aws:iam::123456789012:role/cluster1"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := buildAWSIRSAKubeconfig(&rest.Config{Host: "https://127.0.0.1:6443"}, "", c.hubClusterARN, c.roleARN)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			kubeconfig, err := clientcmd.Load(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			exec := kubeconfig.AuthInfos["default-auth"].Exec
+			if exec == nil || exec.Command != awsExecCommand {
+				t.Fatalf("expected exec plugin %q, but got %v", awsExecCommand, exec)
+			}
+			if !reflect.DeepEqual(exec.Args, c.expectedArgs) {
+				t.Errorf("expected args %v, but got %v", c.expectedArgs, exec.Args)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package registration
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+)
+
+var _ RegistrationDriver = &csrDriver{}
+
+// csrDriver obtains a client certificate for the hub by creating CertificateSigningRequests on the hub,
+// and rotates the client certificate before it becomes expired.
+type csrDriver struct {
+	Options
+}
+
+// IsHubKubeconfigValid returns ture if all the conditions below are met:
+//  1. KubeconfigFile exists;
+//  2. TLSKeyFile exists;
+//  3. TLSCertFile exists;
+//  4. Certificate in TLSCertFile is issued for the current cluster/agent;
+//  5. Certificate in TLSCertFile is not expired;
+//
+// Normally, KubeconfigFile/TLSKeyFile/TLSCertFile will be created once the bootstrap process
+// completes. Changing the name of the cluster will make the existing hub kubeconfig invalid,
+// because certificate in TLSCertFile is issued to a specific cluster/agent.
+//
+// If the SecretStore is set, the same conditions are checked against the data of the secret in the store.
+func (d *csrDriver) IsHubKubeconfigValid() (bool, error) {
+	if d.SecretStore != nil {
+		return d.isStoredHubKubeconfigValid()
+	}
+
+	kubeconfigPath := path.Join(d.HubKubeconfigDir, clientcert.KubeconfigFile)
+	if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) {
+		klog.V(4).Infof("Kubeconfig file %q not found", kubeconfigPath)
+		return false, nil
+	}
+
+	keyPath := path.Join(d.HubKubeconfigDir, clientcert.TLSKeyFile)
+	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
+		klog.V(4).Infof("TLS key file %q not found", keyPath)
+		return false, nil
+	}
+
+	certPath := path.Join(d.HubKubeconfigDir, clientcert.TLSCertFile)
+	certData, err := ioutil.ReadFile(path.Clean(certPath))
+	if err != nil {
+		klog.V(4).Infof("Unable to load TLS cert file %q", certPath)
+		return false, nil
+	}
+
+	return d.isCertificateValid(certData, fmt.Sprintf("file %q", certPath))
+}
+
+// secretStore returns the store of the hub kubeconfig secret, it is nil if the secret is stored in the cluster
+// where the agent pod runs as it is.
+func (d *csrDriver) secretStore() clientcert.SecretStore {
+	if d.SecretStore != nil || d.SecretTransformer == nil {
+		return d.SecretStore
+	}
+	return clientcert.NewTransformingSecretStore(
+		clientcert.NewKubeSecretStore(d.ManagementKubeClient.CoreV1(), d.ComponentNamespace, d.HubKubeconfigSecret),
+		d.SecretTransformer,
+	)
+}
+
+// isStoredHubKubeconfigValid returns true if the secret in the SecretStore contains the kubeconfig, the key and
+// a valid certificate issued for the current cluster/agent.
+func (d *csrDriver) isStoredHubKubeconfigValid() (bool, error) {
+	secret, err := d.SecretStore.Get(context.TODO())
+	if apierrors.IsNotFound(err) {
+		klog.V(4).Infof("Hub kubeconfig secret not found in store")
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, key := range []string{clientcert.KubeconfigFile, clientcert.TLSKeyFile, clientcert.TLSCertFile} {
+		if len(secret.Data[key]) == 0 {
+			klog.V(4).Infof("No %s found in the hub kubeconfig secret in store", key)
+			return false, nil
+		}
+	}
+
+	return d.isCertificateValid(secret.Data[clientcert.TLSCertFile], "store")
+}
+
+// isCertificateValid returns true if the certificate is issued for the current cluster/agent and not expired.
+func (d *csrDriver) isCertificateValid(certData []byte, source string) (bool, error) {
+	clusterName, agentName, err := managedcluster.GetClusterAgentNamesFromCertificate(certData)
+	if err != nil {
+		return false, nil
+	}
+	if clusterName != d.ClusterName || agentName != d.AgentName {
+		klog.V(4).Infof("Certificate in %s is issued for agent %q instead of %q",
+			source, fmt.Sprintf("%s:%s", clusterName, agentName),
+			fmt.Sprintf("%s:%s", d.ClusterName, d.AgentName))
+		return false, nil
+	}
+
+	return clientcert.IsCertificateValid(certData, nil)
+}
+
+// NewCredentialController returns a ClientCertForHubController which creates a client certificate
+// with the given hub client config and rotates it.
+func (d *csrDriver) NewCredentialController(
+	hubClientConfig *rest.Config,
+	hubKubeClient kubernetes.Interface,
+	hubKubeInformerFactory informers.SharedInformerFactory,
+	managementSecretInformer corev1informers.SecretInformer,
+	statusUpdater clientcert.StatusUpdateFunc,
+	retryBackoff *helpers.RetryBackoff,
+	controllerName string,
+) (factory.Controller, error) {
+	// create a kubeconfig with references to the key/cert files in the same secret
+	kubeconfig := clientcert.BuildKubeconfig(hubClientConfig, d.HubProxyURL, clientcert.TLSCertFile, clientcert.TLSKeyFile)
+	kubeconfigData, err := clientcmd.Write(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	csrControl, err := clientcert.NewCSRControl(hubKubeInformerFactory.Certificates(), hubKubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return managedcluster.NewClientCertForHubController(
+		d.ClusterName, d.AgentName, d.ComponentNamespace, d.HubKubeconfigSecret,
+		kubeconfigData,
+		// store the secret in the cluster where the agent pod runs, or in memory if the store is set
+		managementSecretInformer,
+		d.secretStore(),
+		csrControl,
+		d.ClientCertExpirationSeconds,
+		&d.ClientCertRotationJitter,
+		d.ManagementKubeClient,
+		statusUpdater,
+		retryBackoff,
+		d.Recorder,
+		controllerName,
+	), nil
+}
@@ -0,0 +1,79 @@
+package registration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestCSRDriverIsStoredHubKubeconfigValid(t *testing.T) {
+	cert := testinghelpers.NewTestCert("system:open-cluster-management:cluster1:agent1", 60*time.Second)
+	otherCert := testinghelpers.NewTestCert("system:open-cluster-management:cluster2:agent1", 60*time.Second)
+
+	cases := []struct {
+		name    string
+		data    map[string][]byte
+		isValid bool
+	}{
+		{
+			name:    "no secret",
+			isValid: false,
+		},
+		{
+			name: "no key",
+			data: map[string][]byte{
+				clientcert.KubeconfigFile: testinghelpers.NewKubeconfig(nil, nil),
+				clientcert.TLSCertFile:    cert.Cert,
+			},
+			isValid: false,
+		},
+		{
+			name: "cert is issued for another cluster",
+			data: map[string][]byte{
+				clientcert.KubeconfigFile: testinghelpers.NewKubeconfig(nil, nil),
+				clientcert.TLSKeyFile:     otherCert.Key,
+				clientcert.TLSCertFile:    otherCert.Cert,
+			},
+			isValid: false,
+		},
+		{
+			name: "valid hub kubeconfig",
+			data: map[string][]byte{
+				clientcert.KubeconfigFile: testinghelpers.NewKubeconfig(nil, nil),
+				clientcert.TLSKeyFile:     cert.Key,
+				clientcert.TLSCertFile:    cert.Cert,
+			},
+			isValid: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := clientcert.NewMemorySecretStore("open-cluster-management-agent", "hub-kubeconfig-secret")
+			if c.data != nil {
+				if err := store.Save(context.TODO(), &corev1.Secret{Data: c.data}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			driver := &csrDriver{Options: Options{
+				ClusterName: "cluster1",
+				AgentName:   "agent1",
+				// the hub kubeconfig dir is ignored once the store is set
+				HubKubeconfigDir: "/nonexistent",
+				SecretStore:      store,
+			}}
+			valid, err := driver.IsHubKubeconfigValid()
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if valid != c.isValid {
+				t.Errorf("expect %t, but %t", c.isValid, valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+// package registration contains the drivers used by the spoke agent to obtain and maintain the
+// credential for the hub.
+package registration
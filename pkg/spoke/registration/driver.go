@@ -0,0 +1,155 @@
+package registration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/storage/value"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const (
+	// CSRDriverName is the name of the driver which obtains a client certificate for the hub by
+	// creating CertificateSigningRequests on the hub.
+	CSRDriverName = "csr"
+	// TokenDriverName is the name of the driver which uses a token minted outside the agent,
+	// e.g. a long-lived ServiceAccount token minted per cluster or an OIDC workload identity token,
+	// to access the hub.
+	TokenDriverName = "token"
+)
+
+// RegistrationDriver obtains the credential of the agent for the hub and maintains it in the
+// hub kubeconfig secret.
+type RegistrationDriver interface {
+	// IsHubKubeconfigValid returns true if the hub kubeconfig dumped into the hub kubeconfig dir
+	// is valid for the current cluster and agent.
+	IsHubKubeconfigValid() (bool, error)
+
+	// NewCredentialController returns a controller which builds the hub kubeconfig with the given
+	// hub client config and keeps the credential in the hub kubeconfig secret up to date. The failed
+	// requests to the hub are retried with the retryBackoff if it is set.
+	NewCredentialController(
+		hubClientConfig *rest.Config,
+		hubKubeClient kubernetes.Interface,
+		hubKubeInformerFactory informers.SharedInformerFactory,
+		managementSecretInformer corev1informers.SecretInformer,
+		statusUpdater clientcert.StatusUpdateFunc,
+		retryBackoff *helpers.RetryBackoff,
+		controllerName string,
+	) (factory.Controller, error)
+}
+
+// Options holds the configuration shared by the registration drivers.
+type Options struct {
+	ClusterName         string
+	AgentName           string
+	ComponentNamespace  string
+	HubKubeconfigSecret string
+	HubKubeconfigDir    string
+	// HubProxyURL is the URL of the proxy written into the hub kubeconfig
+	HubProxyURL string
+
+	// ClientCertExpirationSeconds is the requested validity of the client certificate, used by the csr driver
+	ClientCertExpirationSeconds int32
+	// ClientCertRotationJitter randomizes when the client certificate is rotated, used by the csr driver
+	ClientCertRotationJitter float64
+	// SecretStore keeps the hub kubeconfig secret instead of HubKubeconfigSecret and HubKubeconfigDir if it
+	// is set, e.g. in memory only. It is only supported by the csr driver.
+	SecretStore clientcert.SecretStore
+	// SecretTransformer encrypts the clientcert.EncryptedSecretKeys of the HubKubeconfigSecret before it is
+	// written if it is set, e.g. with a KMS plugin. It is only supported by the csr driver.
+	SecretTransformer value.Transformer
+
+	// HubTokenFile is the path of the file containing the token for the hub, used by the token driver
+	HubTokenFile string
+
+	// HubClusterARN is the ARN of the EKS hub cluster, used by the awsirsa driver
+	HubClusterARN string
+	// ManagedClusterRoleARN is the ARN of the AWS IAM role assumed by the agent to access the hub,
+	// it is required by the awsirsa driver.
+	ManagedClusterRoleARN string
+
+	ManagementKubeClient kubernetes.Interface
+	Recorder             events.Recorder
+}
+
+// NewRegistrationDriver returns the registration driver with the given name. The csr driver is
+// returned if the name is empty. The options are expected to be validated by the agent already.
+func NewRegistrationDriver(name string, options Options) (RegistrationDriver, error) {
+	switch name {
+	case "", CSRDriverName:
+		return &csrDriver{Options: options}, nil
+	case TokenDriverName:
+		return &tokenDriver{Options: options}, nil
+	case AWSIRSADriverName:
+		return &awsIRSADriver{Options: options}, nil
+	default:
+		return nil, fmt.Errorf("unsupported registration driver %q", name)
+	}
+}
+
+// applyHubKubeconfigSecret creates the hub kubeconfig secret with the given data, or merges the data
+// into the existing secret. It returns true if the secret is created or updated.
+func applyHubKubeconfigSecret(
+	ctx context.Context,
+	coreClient corev1client.CoreV1Interface,
+	namespace, name string,
+	data map[string][]byte) (bool, error) {
+	secret, err := coreClient.Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+			},
+			Data: data,
+		}
+		clientcert.SetSecretChecksum(secret)
+		if _, err := coreClient.Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return false, err
+		}
+		return true, nil
+	case err != nil:
+		return false, fmt.Errorf("unable to get secret %q: %w", namespace+"/"+name, err)
+	}
+
+	changed := false
+	secret = secret.DeepCopy()
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	for key, value := range data {
+		if bytes.Equal(secret.Data[key], value) {
+			continue
+		}
+		secret.Data[key] = value
+		changed = true
+	}
+	// the checksum is also set on the secret written without one
+	if clientcert.SetSecretChecksum(secret) {
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+
+	if _, err := coreClient.Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,151 @@
+package registration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const (
+	// TokenFile is the name of the token file in the hub kubeconfig secret
+	TokenFile = "token"
+)
+
+// TokenControllerResyncInterval is exposed so that integration tests can crank up the controller sync speed.
+var TokenControllerResyncInterval = 1 * time.Minute
+
+var _ RegistrationDriver = &tokenDriver{}
+
+// tokenDriver uses a token minted outside the agent to access the hub. The token is read from the
+// HubTokenFile and copied into the hub kubeconfig secret, which makes it work with both long-lived
+// ServiceAccount tokens and periodically refreshed tokens, like projected ServiceAccount tokens or OIDC
+// workload identity tokens, without relying on the CSR signing controller of the hub.
+type tokenDriver struct {
+	Options
+}
+
+// IsHubKubeconfigValid returns true if the KubeconfigFile and TokenFile exist and the hub kubeconfig
+// is built for the current cluster/agent.
+func (d *tokenDriver) IsHubKubeconfigValid() (bool, error) {
+	for _, file := range []string{clientcert.KubeconfigFile, TokenFile} {
+		data, err := ioutil.ReadFile(path.Clean(path.Join(d.HubKubeconfigDir, file)))
+		if os.IsNotExist(err) || len(data) == 0 {
+			klog.V(4).Infof("File %q not found in %q", file, d.HubKubeconfigDir)
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	clusterName, err := ioutil.ReadFile(path.Clean(path.Join(d.HubKubeconfigDir, clientcert.ClusterNameFile)))
+	if err != nil || string(clusterName) != d.ClusterName {
+		return false, nil
+	}
+	agentName, err := ioutil.ReadFile(path.Clean(path.Join(d.HubKubeconfigDir, clientcert.AgentNameFile)))
+	if err != nil || string(agentName) != d.AgentName {
+		return false, nil
+	}
+	return true, nil
+}
+
+// NewCredentialController returns a controller which copies the token into the hub kubeconfig secret.
+func (d *tokenDriver) NewCredentialController(
+	hubClientConfig *rest.Config,
+	_ kubernetes.Interface,
+	_ informers.SharedInformerFactory,
+	managementSecretInformer corev1informers.SecretInformer,
+	_ clientcert.StatusUpdateFunc,
+	// the controller does not send requests to the hub
+	_ *helpers.RetryBackoff,
+	controllerName string,
+) (factory.Controller, error) {
+	kubeconfigData, err := buildTokenKubeconfig(hubClientConfig, d.HubProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &tokenController{
+		tokenDriver:          d,
+		kubeconfigData:       kubeconfigData,
+		managementCoreClient: d.ManagementKubeClient.CoreV1(),
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, func(obj interface{}) bool {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			// only enqueue the hub kubeconfig secret
+			return accessor.GetNamespace() == d.ComponentNamespace && accessor.GetName() == d.HubKubeconfigSecret
+		}, managementSecretInformer.Informer()).
+		WithSync(c.sync).
+		ResyncEvery(TokenControllerResyncInterval).
+		ToController(controllerName, d.Recorder), nil
+}
+
+// buildTokenKubeconfig builds a kubeconfig which references the token file in the same secret.
+func buildTokenKubeconfig(hubClientConfig *rest.Config, proxyURL string) ([]byte, error) {
+	kubeconfig := clientcert.BuildKubeconfig(hubClientConfig, proxyURL, "", "")
+	kubeconfig.AuthInfos["default-auth"] = &clientcmdapi.AuthInfo{
+		TokenFile: TokenFile,
+	}
+	return clientcmd.Write(kubeconfig)
+}
+
+// tokenController keeps the token and the kubeconfig in the hub kubeconfig secret up to date.
+type tokenController struct {
+	*tokenDriver
+	kubeconfigData       []byte
+	managementCoreClient corev1client.CoreV1Interface
+}
+
+func (c *tokenController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	token, err := ioutil.ReadFile(path.Clean(c.HubTokenFile))
+	if err != nil {
+		return fmt.Errorf("unable to load hub token from file %q: %w", c.HubTokenFile, err)
+	}
+	token = bytes.TrimSpace(token)
+	if len(token) == 0 {
+		return fmt.Errorf("hub token file %q is empty", c.HubTokenFile)
+	}
+
+	data := map[string][]byte{
+		clientcert.ClusterNameFile: []byte(c.ClusterName),
+		clientcert.AgentNameFile:   []byte(c.AgentName),
+		clientcert.KubeconfigFile:  c.kubeconfigData,
+		TokenFile:                  token,
+	}
+
+	updated, err := applyHubKubeconfigSecret(ctx, c.managementCoreClient, c.ComponentNamespace, c.HubKubeconfigSecret, data)
+	if err != nil {
+		return err
+	}
+	if updated {
+		syncCtx.Recorder().Eventf("HubTokenUpdated", "The hub token for %s is updated", c.ClusterName)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package registration
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/storage/value/encrypt/identity"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+)
+
+func TestTokenControllerSync(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "testtokencontroller")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	tokenFile := path.Join(tempDir, "token")
+	testinghelpers.WriteFile(tokenFile, []byte("testtoken\n"))
+
+	kubeconfigData, err := buildTokenKubeconfig(&rest.Config{Host: "https://127.0.0.1:6443"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name            string
+		existingObjects []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name: "create hub kubeconfig secret",
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "create")
+				secret := actions[1].(clienttesting.CreateActionImpl).Object.(*corev1.Secret)
+				if string(secret.Data[TokenFile]) != "testtoken" {
+					t.Errorf("expect token %q but got %q", "testtoken", secret.Data[TokenFile])
+				}
+				kubeconfig, err := clientcmd.Load(secret.Data[clientcert.KubeconfigFile])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if kubeconfig.AuthInfos["default-auth"].TokenFile != TokenFile {
+					t.Errorf("expect token file %q in kubeconfig, but got %q", TokenFile, kubeconfig.AuthInfos["default-auth"].TokenFile)
+				}
+			},
+		},
+		{
+			name: "update token in hub kubeconfig secret",
+			existingObjects: []runtime.Object{
+				testinghelpers.NewHubKubeconfigSecret("open-cluster-management-agent", "hub-kubeconfig-secret", "1", nil, map[string][]byte{
+					TokenFile: []byte("oldtoken"),
+				}),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testinghelpers.AssertActions(t, actions, "get", "update")
+				secret := actions[1].(clienttesting.UpdateActionImpl).Object.(*corev1.Secret)
+				if string(secret.Data[TokenFile]) != "testtoken" {
+					t.Errorf("expect token %q but got %q", "testtoken", secret.Data[TokenFile])
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(c.existingObjects...)
+			driver := &tokenDriver{Options: Options{
+				ClusterName:          testinghelpers.TestManagedClusterName,
+				AgentName:            "testagent",
+				ComponentNamespace:   "open-cluster-management-agent",
+				HubKubeconfigSecret:  "hub-kubeconfig-secret",
+				HubTokenFile:         tokenFile,
+				ManagementKubeClient: kubeClient,
+			}}
+			ctrl := &tokenController{
+				tokenDriver:          driver,
+				kubeconfigData:       kubeconfigData,
+				managementCoreClient: kubeClient.CoreV1(),
+			}
+
+			if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "")); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			c.validateActions(t, kubeClient.Actions())
+		})
+	}
+}
+
+func TestTokenDriverIsHubKubeconfigValid(t *testing.T) {
+	cases := []struct {
+		name    string
+		files   map[string][]byte
+		isValid bool
+	}{
+		{
+			name:    "no kubeconfig",
+			isValid: false,
+		},
+		{
+			name: "no token",
+			files: map[string][]byte{
+				clientcert.KubeconfigFile: testinghelpers.NewKubeconfig(nil, nil),
+			},
+			isValid: false,
+		},
+		{
+			name: "token is issued for another cluster",
+			files: map[string][]byte{
+				clientcert.KubeconfigFile:  testinghelpers.NewKubeconfig(nil, nil),
+				TokenFile:                  []byte("testtoken"),
+				clientcert.ClusterNameFile: []byte("cluster2"),
+				clientcert.AgentNameFile:   []byte("agent1"),
+			},
+			isValid: false,
+		},
+		{
+			name: "valid hub kubeconfig",
+			files: map[string][]byte{
+				clientcert.KubeconfigFile:  testinghelpers.NewKubeconfig(nil, nil),
+				TokenFile:                  []byte("testtoken"),
+				clientcert.ClusterNameFile: []byte("cluster1"),
+				clientcert.AgentNameFile:   []byte("agent1"),
+			},
+			isValid: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tempDir, err := ioutil.TempDir("", "testtokendriver")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			for name, data := range c.files {
+				testinghelpers.WriteFile(path.Join(tempDir, name), data)
+			}
+
+			driver := &tokenDriver{Options: Options{
+				ClusterName:      "cluster1",
+				AgentName:        "agent1",
+				HubKubeconfigDir: tempDir,
+			}}
+			valid, err := driver.IsHubKubeconfigValid()
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if valid != c.isValid {
+				t.Errorf("expect %t, but %t", c.isValid, valid)
+			}
+		})
+	}
+}
+
+func TestNewRegistrationDriver(t *testing.T) {
+	cases := []struct {
+		name        string
+		driverName  string
+		options     Options
+		expectedErr string
+	}{
+		{
+			name:       "default driver",
+			driverName: "",
+		},
+		{
+			name:       "csr driver",
+			driverName: CSRDriverName,
+		},
+		{
+			name:       "token driver",
+			driverName: TokenDriverName,
+			options:    Options{HubTokenFile: "/spoke/token/token"},
+		},
+		{
+			name:       "csr driver with secret store",
+			driverName: CSRDriverName,
+			options:    Options{SecretStore: clientcert.NewMemorySecretStore("ns1", "secret1")},
+		},
+		{
+			name:       "csr driver with secret transformer",
+			driverName: CSRDriverName,
+			options:    Options{SecretTransformer: identity.NewEncryptCheckTransformer()},
+		},
+		{
+			name:       "awsirsa driver",
+			driverName: AWSIRSADriverName,
+			options: Options{
+				HubClusterARN:         "arn:aws:eks:us-west-2:123456789012:cluster/hub",
+				ManagedClusterRoleARN: "arn:aws:iam::123456789012:role/cluster1",
+			},
+		},
+		{
+			name:        "unsupported driver",
+			driverName:  "unknown",
+			expectedErr: "unsupported registration driver \"unknown\"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewRegistrationDriver(c.driverName, c.options)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+		})
+	}
+}
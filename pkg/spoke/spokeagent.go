@@ -1,12 +1,19 @@
 package spoke
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"os"
+	"net"
+	"net/http"
+	"net/url"
 	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
@@ -19,8 +26,11 @@ import (
 	"open-cluster-management.io/registration/pkg/clientcert"
 	"open-cluster-management.io/registration/pkg/features"
 	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/hub/user"
 	"open-cluster-management.io/registration/pkg/spoke/addon"
 	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+	"open-cluster-management.io/registration/pkg/spoke/registration"
+	"open-cluster-management.io/registration/pkg/version"
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -28,16 +38,20 @@ import (
 
 	"github.com/spf13/pflag"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	utilrand "k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/storage/value"
+	"k8s.io/apiserver/pkg/storage/value/encrypt/envelope"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/klog/v2"
 )
 
@@ -46,6 +60,15 @@ const (
 	spokeAgentNameLength = 5
 	// defaultSpokeComponentNamespace is the default namespace in which the spoke agent is deployed
 	defaultSpokeComponentNamespace = "open-cluster-management-agent"
+
+	// clusterInfoNamespace and clusterInfoName locate the configmap which contains a kubeconfig with
+	// the server URL and CA data of the spoke cluster.
+	clusterInfoNamespace     = "kube-public"
+	clusterInfoName          = "cluster-info"
+	clusterInfoKubeconfigKey = "kubeconfig"
+
+	// clusterIdentityNamespace is the namespace whose UID identifies the spoke cluster.
+	clusterIdentityNamespace = "kube-system"
 )
 
 // AddOnLeaseControllerSyncInterval is exposed so that integration tests can crank up the constroller sync speed.
@@ -54,26 +77,81 @@ var AddOnLeaseControllerSyncInterval = 30 * time.Second
 
 // SpokeAgentOptions holds configuration for spoke cluster agent
 type SpokeAgentOptions struct {
-	ComponentNamespace          string
-	ClusterName                 string
-	AgentName                   string
-	BootstrapKubeconfig         string
-	HubKubeconfigSecret         string
-	HubKubeconfigDir            string
-	SpokeExternalServerURLs     []string
-	ClusterHealthCheckPeriod    time.Duration
-	MaxCustomClusterClaims      int
-	SpokeKubeconfig             string
-	ClientCertExpirationSeconds int32
+	ComponentNamespace                string
+	ClusterName                       string
+	AgentName                         string
+	BootstrapKubeconfig               string
+	BootstrapKubeconfigs              []string
+	BootstrapKubeconfigSecret         string
+	HubKubeconfigSecret               string
+	HubKubeconfigDir                  string
+	SpokeExternalServerURLs           []string
+	ClusterHealthCheckPeriod          time.Duration
+	MaxCustomClusterClaims            int
+	SpokeKubeconfig                   string
+	ClientCertExpirationSeconds       int32
+	ClientCertRotationJitter          float64
+	ClientCertExpiryWarningThreshold  float64
+	HubProxyURL                       string
+	HubProxyCAFile                    string
+	AddOnHubCAFile                    string
+	HubProxyCredentialsFile           string
+	HubHostAliases                    []string
+	HubDNSServer                      string
+	SpokeExternalServerURLProbePeriod time.Duration
+	RegistrationDriver                string
+	HubTokenFile                      string
+	HubTokenUsername                  string
+	HubClusterARN                     string
+	ManagedClusterRoleARN             string
+	HubKubeAPIQPS                     float32
+	HubKubeAPIBurst                   int
+	FeatureGatesFile                  string
+	DeterministicAgentName            bool
+	BootstrapRetryInitialInterval     time.Duration
+	BootstrapRetryMaxInterval         time.Duration
+	BootstrapRetryJitter              float64
+	BootstrapMaxElapsedTime           time.Duration
+	InMemoryHubCredentials            bool
+	HubKubeconfigKMSEndpoint          string
+	HubKubeconfigKMSTimeout           time.Duration
+
+	// clusterIdentity is the identity of the spoke cluster, it is empty if it is unable to be got.
+	clusterIdentity string
+
+	// hubIndex is the index of the hub in BootstrapKubeconfigs, it is 0 if the agent runs on a single hub.
+	hubIndex int
+
+	// hubCredentialStore keeps the hub kubeconfig secret in memory once InMemoryHubCredentials is enabled.
+	hubCredentialStore *clientcert.MemorySecretStore
+
+	// HubKubeconfigTransformer encrypts the private key in the hub kubeconfig secret before it is written, and
+	// decrypts it once the secret is read. It is built with HubKubeconfigKMSEndpoint if it is not set, the
+	// binaries embedding the agent may set their own, e.g. with a sealing provider.
+	HubKubeconfigTransformer value.Transformer
+
+	// AddOnControllers holds the addon controllers started once the AddonManagement feature is enabled,
+	// the binaries embedding the agent may register their own addon controllers into it.
+	AddOnControllers *addon.AddOnControllerRegistry
 }
 
 // NewSpokeAgentOptions returns a SpokeAgentOptions
 func NewSpokeAgentOptions() *SpokeAgentOptions {
 	return &SpokeAgentOptions{
-		HubKubeconfigSecret:      "hub-kubeconfig-secret",
-		HubKubeconfigDir:         "/spoke/hub-kubeconfig",
-		ClusterHealthCheckPeriod: 1 * time.Minute,
-		MaxCustomClusterClaims:   20,
+		HubKubeconfigSecret:               "hub-kubeconfig-secret",
+		BootstrapKubeconfigSecret:         "bootstrap-hub-kubeconfig",
+		HubKubeconfigDir:                  "/spoke/hub-kubeconfig",
+		ClusterHealthCheckPeriod:          1 * time.Minute,
+		MaxCustomClusterClaims:            20,
+		SpokeExternalServerURLProbePeriod: 5 * time.Minute,
+		ClientCertRotationJitter:          clientcert.DefaultRotationJitter,
+		ClientCertExpiryWarningThreshold:  0.1,
+		RegistrationDriver:                registration.CSRDriverName,
+		BootstrapRetryInitialInterval:     1 * time.Second,
+		BootstrapRetryMaxInterval:         5 * time.Minute,
+		BootstrapRetryJitter:              0.2,
+		HubKubeconfigKMSTimeout:           3 * time.Second,
+		AddOnControllers:                  addon.NewDefaultAddOnControllerRegistry(),
 	}
 }
 
@@ -108,52 +186,194 @@ func NewSpokeAgentOptions() *SpokeAgentOptions {
 // create a valid hub kubeconfig. Once the hub kubeconfig is valid, the
 // temporary controller is stopped and the main controllers are started.
 func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
-	// create management kube client
-	managementKubeClient, err := kubernetes.NewForConfig(controllerContext.KubeConfig)
-	if err != nil {
-		return err
+	run := func(ctx context.Context) error {
+		return o.runSpokeAgentOnHubs(ctx, controllerContext.KubeConfig, WithEventRecorder(controllerContext.EventRecorder))
+	}
+	if len(o.FeatureGatesFile) == 0 {
+		return run(ctx)
 	}
 
-	// load spoke client config and create spoke clients,
-	// the registration agent may not running in the spoke/managed cluster.
-	spokeClientConfig, err := o.spokeKubeConfig(controllerContext)
+	// restart the agent in-process once the feature gates in the file change
+	return features.NewFeatureGateReloader(
+		o.FeatureGatesFile, features.DefaultSpokeMutableFeatureGate, controllerContext.EventRecorder).Run(ctx, run)
+}
+
+// hubAgentRestartPeriod is the period to restart the agent on one of multiple hubs once it returns.
+var hubAgentRestartPeriod = 10 * time.Second
+
+// runSpokeAgentOnHubs runs the spoke agent on each hub in BootstrapKubeconfigs concurrently, or on the
+// hub of BootstrapKubeconfig if BootstrapKubeconfigs is empty. The agents on multiple hubs are independent,
+// once the agent on one hub fails, only that agent is restarted and the agents on the other hubs keep
+// running until the context is done.
+func (o *SpokeAgentOptions) runSpokeAgentOnHubs(ctx context.Context, cfg *rest.Config, opts ...SpokeAgentOption) error {
+	hubOptions, err := o.hubOptions()
 	if err != nil {
 		return err
 	}
+	if len(hubOptions) == 1 {
+		return RunSpokeAgentWithContext(ctx, cfg, hubOptions[0], opts...)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := range hubOptions {
+		wg.Add(1)
+		go func(hubOptions *SpokeAgentOptions) {
+			defer wg.Done()
+			wait.UntilWithContext(ctx, func(ctx context.Context) {
+				if err := RunSpokeAgentWithContext(ctx, cfg, hubOptions, opts...); err != nil {
+					klog.Errorf("Failed to run agent on the hub of %q: %v", hubOptions.BootstrapKubeconfig, err)
+				}
+			}, hubAgentRestartPeriod)
+		}(hubOptions[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+// hubOptions returns the options of the agent on each hub in BootstrapKubeconfigs. The managed cluster
+// is registered to each hub with its own hub kubeconfig secret, lease and credential flow:
+//   - the hub kubeconfig of the first hub is stored in HubKubeconfigSecret and HubKubeconfigDir, so
+//     that an existing registration is kept once more hubs are added;
+//   - the hub kubeconfig of the i-th (i > 0) hub is stored in '<HubKubeconfigSecret>-<i>' and
+//     '<HubKubeconfigDir>-<i>', the volumes of them are mounted in deploy/spoke-multihub.
+//
+// The addons are only managed with the first hub, since the hub kubeconfig secrets of an addon are in
+// the same install namespace on the managed cluster for all hubs. The hub migration is disabled, since
+// the bootstrap kubeconfigs of the hubs are not mounted from the bootstrap kubeconfig secret.
+func (o *SpokeAgentOptions) hubOptions() ([]*SpokeAgentOptions, error) {
+	if len(o.BootstrapKubeconfigs) == 0 {
+		return []*SpokeAgentOptions{o}, nil
+	}
+	if len(o.BootstrapKubeconfig) != 0 {
+		return nil, errors.New("bootstrap-kubeconfig and bootstrap-kubeconfigs are mutually exclusive")
+	}
+	// the generated cluster name may be different on each hub
+	if len(o.ClusterName) == 0 {
+		return nil, errors.New("cluster-name is required when bootstrap-kubeconfigs is specified")
+	}
+
+	hubOptions := []*SpokeAgentOptions{}
+	for i, bootstrapKubeconfig := range o.BootstrapKubeconfigs {
+		options := o.deepCopy()
+		options.BootstrapKubeconfigs = nil
+		options.BootstrapKubeconfig = bootstrapKubeconfig
+		options.BootstrapKubeconfigSecret = ""
+		options.hubIndex = i
+		if i > 0 {
+			options.HubKubeconfigSecret = fmt.Sprintf("%s-%d", o.HubKubeconfigSecret, i)
+			options.HubKubeconfigDir = fmt.Sprintf("%s-%d", o.HubKubeconfigDir, i)
+			options.AddOnControllers = addon.NewAddOnControllerRegistry()
+		}
+		hubOptions = append(hubOptions, options)
+	}
+	return hubOptions, nil
+}
+
+// deepCopy returns a copy of the options which shares no slices with the original, so that the options
+// of each hub are able to be completed separately.
+func (o *SpokeAgentOptions) deepCopy() *SpokeAgentOptions {
+	options := *o
+	options.BootstrapKubeconfigs = append([]string(nil), o.BootstrapKubeconfigs...)
+	options.SpokeExternalServerURLs = append([]string(nil), o.SpokeExternalServerURLs...)
+	options.HubHostAliases = append([]string(nil), o.HubHostAliases...)
+	return &options
+}
 
-	spokeKubeClient, err := kubernetes.NewForConfig(spokeClientConfig)
+// RunSpokeAgentWithContext completes and validates the options and then runs the spoke agent until
+// the context is done. The cfg is the client config of the cluster where the agent runs. It is the
+// entry point for projects which embed the registration agent, the clients and informer factories
+// of the management and spoke clusters can be shared with the agent by the SpokeAgentOption opts, the
+// missing ones are built from the cfg and the SpokeKubeconfig of the options.
+//
+// Unlike the agent command, which exits on an invalid option, all errors are returned to the caller.
+func RunSpokeAgentWithContext(ctx context.Context, cfg *rest.Config, o *SpokeAgentOptions, opts ...SpokeAgentOption) error {
+	// the controllers are stopped once the agent returns, e.g. the managed cluster is migrated to a new hub
+	ctx, stopAgent := context.WithCancel(ctx)
+	defer stopAgent()
+
+	agentConfig, err := newSpokeAgentConfig(cfg, o, opts...)
 	if err != nil {
 		return err
 	}
+	recorder := agentConfig.eventRecorder
+	managementKubeClient := agentConfig.managementKubeClient
+	spokeClientConfig := agentConfig.spokeClientConfig
+	spokeKubeClient := agentConfig.spokeKubeClient
+
+	// the identity of the spoke cluster is reported to the hub, the agent name is derived from it if the
+	// deterministic agent name is enabled
+	clusterIdentity, err := getClusterIdentity(ctx, spokeKubeClient.CoreV1())
+	switch {
+	case err == nil:
+		o.clusterIdentity = clusterIdentity
+	case o.DeterministicAgentName:
+		return fmt.Errorf("unable to get the identity of the spoke cluster to derive the agent name: %w", err)
+	default:
+		klog.Warningf("Unable to get the identity of the spoke cluster: %v", err)
+	}
 
 	// the hub kubeconfig secret stored in the cluster where the agent pod runs
-	if err := o.Complete(managementKubeClient.CoreV1(), ctx, controllerContext.EventRecorder); err != nil {
-		klog.Fatal(err)
+	if err := o.Complete(managementKubeClient.CoreV1(), ctx, recorder); err != nil {
+		return err
 	}
 
 	if err := o.Validate(); err != nil {
-		klog.Fatal(err)
+		return err
 	}
 
 	klog.Infof("Cluster name is %q and agent name is %q", o.ClusterName, o.AgentName)
 
-	// create shared informer factory for spoke cluster
-	spokeKubeInformerFactory := informers.NewSharedInformerFactory(spokeKubeClient, 10*time.Minute)
+	if o.InMemoryHubCredentials {
+		o.hubCredentialStore = clientcert.NewMemorySecretStore(o.ComponentNamespace, o.HubKubeconfigSecret)
+	}
 
-	// get spoke cluster CA bundle
-	spokeClusterCABundle, err := o.getSpokeClusterCABundle(spokeClientConfig)
+	registrationDriver, err := o.registrationDriver(managementKubeClient, recorder)
 	if err != nil {
 		return err
 	}
 
+	spokeKubeInformerFactory := agentConfig.spokeKubeInformerFactory
+
+	// discover the spoke external server URL from the cluster-info configmap if it is not specified
+	var spokeClusterCABundle []byte
+	if len(o.SpokeExternalServerURLs) == 0 {
+		serverURL, caData, err := discoverSpokeExternalServerURL(ctx, spokeKubeClient.CoreV1())
+		switch {
+		case err != nil:
+			klog.Warningf("Unable to discover the spoke external server URL from cluster-info: %v", err)
+		case len(serverURL) != 0:
+			klog.Infof("Use the spoke external server URL %q discovered from cluster-info", serverURL)
+			o.SpokeExternalServerURLs = []string{serverURL}
+			spokeClusterCABundle = caData
+		}
+	}
+
+	// get spoke cluster CA bundle
+	if len(spokeClusterCABundle) == 0 {
+		spokeClusterCABundle, err = o.getSpokeClusterCABundle(spokeClientConfig)
+		if err != nil {
+			return err
+		}
+	}
+
 	// create a shared informer factory with specific namespace for the management cluster.
-	namespacedManagementKubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(managementKubeClient, 10*time.Minute, informers.WithNamespace(o.ComponentNamespace))
+	namespacedManagementKubeInformerFactory := agentConfig.namespacedManagementKubeInformerFactory
+	if namespacedManagementKubeInformerFactory == nil {
+		namespacedManagementKubeInformerFactory = informers.NewSharedInformerFactoryWithOptions(managementKubeClient, 10*time.Minute, informers.WithNamespace(o.ComponentNamespace))
+	}
 
 	// load bootstrap client config and create bootstrap clients
-	bootstrapClientConfig, err := clientcmd.BuildConfigFromFlags("", o.BootstrapKubeconfig)
+	bootstrapClientConfig, err := loadClientConfig(o.BootstrapKubeconfig)
 	if err != nil {
 		return fmt.Errorf("unable to load bootstrap kubeconfig from file %q: %w", o.BootstrapKubeconfig, err)
 	}
+	if err := o.applyHubProxy(bootstrapClientConfig); err != nil {
+		return err
+	}
+	if err := o.applyHubDialer(bootstrapClientConfig); err != nil {
+		return err
+	}
+	o.applyHubRateLimit(bootstrapClientConfig)
 	bootstrapKubeClient, err := kubernetes.NewForConfig(bootstrapClientConfig)
 	if err != nil {
 		return err
@@ -164,22 +384,43 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 	}
 
 	// start a SpokeClusterCreatingController to make sure there is a spoke cluster on hub cluster
+	clusterAnnotations := map[string]string{}
+	if o.RegistrationDriver == registration.AWSIRSADriverName {
+		// the hub maps the IAM role to the identity of the managed cluster once it is accepted
+		clusterAnnotations[user.IAMRoleARNAnnotation] = o.ManagedClusterRoleARN
+	}
+	if o.RegistrationDriver == registration.TokenDriverName {
+		// the hub binds the roles of the managed cluster to the user of the token once it is accepted
+		clusterAnnotations[user.TokenUsernameAnnotation] = o.HubTokenUsername
+	}
+	// the hub detects another cluster registered with the same cluster name by the identity
+	if len(o.clusterIdentity) != 0 {
+		clusterAnnotations[helpers.AgentClusterIdentityAnnotation] = o.clusterIdentity
+	}
+	// the controllers sending requests to the hub with the bootstrap kubeconfig retry with the backoff
+	bootstrapBackoff := o.bootstrapRetryBackoff()
 	spokeClusterCreatingController := managedcluster.NewManagedClusterCreatingController(
 		o.ClusterName, o.SpokeExternalServerURLs,
 		spokeClusterCABundle,
+		clusterAnnotations,
 		bootstrapClusterClient,
-		controllerContext.EventRecorder,
+		bootstrapBackoff,
+		recorder,
 	)
 	go spokeClusterCreatingController.Run(ctx, 1)
 
-	hubKubeconfigSecretController := managedcluster.NewHubKubeconfigSecretController(
-		o.HubKubeconfigDir, o.ComponentNamespace, o.HubKubeconfigSecret,
-		// the hub kubeconfig secret stored in the cluster where the agent pod runs
-		managementKubeClient.CoreV1(),
-		namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
-		controllerContext.EventRecorder,
-	)
-	go hubKubeconfigSecretController.Run(ctx, 1)
+	// the hub kubeconfig secret is not dumped into the hub kubeconfig dir once it is in memory
+	if !o.InMemoryHubCredentials {
+		hubKubeconfigSecretController := managedcluster.NewHubKubeconfigSecretController(
+			o.HubKubeconfigDir, o.ComponentNamespace, o.HubKubeconfigSecret,
+			// the hub kubeconfig secret stored in the cluster where the agent pod runs
+			managementKubeClient.CoreV1(),
+			o.HubKubeconfigTransformer,
+			namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			recorder,
+		)
+		go hubKubeconfigSecretController.Run(ctx, 1)
+	}
 	go namespacedManagementKubeInformerFactory.Start(ctx.Done())
 
 	// check if there already exists a valid client config for hub
@@ -199,31 +440,20 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 		bootstrapInformerFactory := informers.NewSharedInformerFactory(bootstrapKubeClient, 10*time.Minute)
 		bootstrapNamespacedManagementKubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(managementKubeClient, 10*time.Minute, informers.WithNamespace(o.ComponentNamespace))
 
-		// create a kubeconfig with references to the key/cert files in the same secret
-		kubeconfig := clientcert.BuildKubeconfig(bootstrapClientConfig, clientcert.TLSCertFile, clientcert.TLSKeyFile)
-		kubeconfigData, err := clientcmd.Write(kubeconfig)
-		if err != nil {
-			return err
-		}
-
-		csrControl, err := clientcert.NewCSRControl(bootstrapInformerFactory.Certificates(), bootstrapKubeClient)
-		if err != nil {
-			return err
-		}
-
 		controllerName := fmt.Sprintf("BootstrapClientCertController@cluster:%s", o.ClusterName)
-		clientCertForHubController := managedcluster.NewClientCertForHubController(
-			o.ClusterName, o.AgentName, o.ComponentNamespace, o.HubKubeconfigSecret,
-			kubeconfigData,
+		clientCertForHubController, err := registrationDriver.NewCredentialController(
+			bootstrapClientConfig,
+			bootstrapKubeClient,
+			bootstrapInformerFactory,
 			// store the secret in the cluster where the agent pod runs
 			bootstrapNamespacedManagementKubeInformerFactory.Core().V1().Secrets(),
-			csrControl,
-			o.ClientCertExpirationSeconds,
-			managementKubeClient,
 			managedcluster.GenerateBootstrapStatusUpdater(),
-			controllerContext.EventRecorder,
+			bootstrapBackoff,
 			controllerName,
 		)
+		if err != nil {
+			return err
+		}
 
 		bootstrapCtx, stopBootstrap := context.WithCancel(ctx)
 
@@ -234,7 +464,16 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 
 		// wait for the hub client config is ready.
 		klog.Info("Waiting for hub client config and managed cluster to be ready")
-		if err := wait.PollImmediateInfinite(1*time.Second, o.hasValidHubClientConfig); err != nil {
+		if err := wait.PollImmediateUntil(1*time.Second, func() (bool, error) {
+			// give up the bootstrap once it keeps failing longer than the max elapsed time, the agent exits
+			// with the error and is restarted by its deployment
+			if err := bootstrapBackoff.Terminal(); err != nil {
+				klog.Errorf("Bootstrap failed: %v", err)
+				recorder.Warningf("BootstrapFailed", "Bootstrap failed: %v", err)
+				return false, err
+			}
+			return o.hasValidHubClientConfig()
+		}, ctx.Done()); err != nil {
 			// TODO need run the bootstrap CSR forever to re-establish the client-cert if it is ever lost.
 			stopBootstrap()
 			return err
@@ -245,7 +484,7 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 	}
 
 	// create hub clients and shared informer factories from hub kube config
-	hubClientConfig, err := clientcmd.BuildConfigFromFlags("", path.Join(o.HubKubeconfigDir, clientcert.KubeconfigFile))
+	hubClientConfig, err := o.hubClientConfig(bootstrapClientConfig)
 	if err != nil {
 		return err
 	}
@@ -265,6 +504,8 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 		return err
 	}
 
+	// the hub kube informers are used for the csrs of the agent and addons which are cluster scoped,
+	// filter them with the cluster name label so that only the csrs of the current cluster are cached.
 	hubKubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(
 		hubKubeClient,
 		10*time.Minute,
@@ -283,11 +524,11 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 		}),
 	)
 
-	controllerContext.EventRecorder.Event("HubClientConfigReady", "Client config for hub is ready.")
+	recorder.Event("HubClientConfigReady", "Client config for hub is ready.")
 
-	// create a kubeconfig with references to the key/cert files in the same secret
-	kubeconfig := clientcert.BuildKubeconfig(hubClientConfig, clientcert.TLSCertFile, clientcert.TLSKeyFile)
-	kubeconfigData, err := clientcmd.Write(kubeconfig)
+	// create a kubeconfig with references to the key/cert files in the same secret, it is used to build
+	// the hub kubeconfig of addons
+	kubeconfigData, err := o.buildAddOnHubKubeconfig(hubClientConfig)
 	if err != nil {
 		return err
 	}
@@ -299,15 +540,13 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 
 	// create another ClientCertForHubController for client certificate rotation
 	controllerName := fmt.Sprintf("ClientCertController@cluster:%s", o.ClusterName)
-	clientCertForHubController := managedcluster.NewClientCertForHubController(
-		o.ClusterName, o.AgentName, o.ComponentNamespace, o.HubKubeconfigSecret,
-		kubeconfigData,
+	clientCertForHubController, err := registrationDriver.NewCredentialController(
+		hubClientConfig,
+		hubKubeClient,
+		hubKubeInformerFactory,
 		namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
-		csrControl,
-		o.ClientCertExpirationSeconds,
-		managementKubeClient,
 		managedcluster.GenerateStatusUpdater(hubClusterClient, o.ClusterName),
-		controllerContext.EventRecorder,
+		nil,
 		controllerName,
 	)
 	if err != nil {
@@ -319,15 +558,95 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 		o.ClusterName,
 		hubClusterClient,
 		hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
-		controllerContext.EventRecorder,
+		recorder,
 	)
 
-	// create ManagedClusterLeaseController to keep the spoke cluster heartbeat
+	// the hub permissions of the agent are reviewed once the lease or status updates are forbidden persistently
+	hubAccessReviewTrigger := managedcluster.NewHubAccessReviewTrigger(recorder)
+
+	// create ManagedClusterLeaseController to keep the spoke cluster heartbeat. The lease is renewed as long as
+	// the agent runs, so in the hosted mode the hub tells the unavailable agent apart from the unreachable
+	// managed cluster with the deploy mode on the lease.
+	leaseAnnotations := managedcluster.AgentLeaseAnnotations(version.Get().GitVersion, version.Get().GitCommit, features.DefaultSpokeMutableFeatureGate)
+	leaseAnnotations[helpers.AgentDeployModeAnnotation] = helpers.AgentDeployModeDefault
+	if o.hostedMode() {
+		leaseAnnotations[helpers.AgentDeployModeAnnotation] = helpers.AgentDeployModeHosted
+	}
 	managedClusterLeaseController := managedcluster.NewManagedClusterLeaseController(
 		o.ClusterName,
 		hubKubeClient,
 		hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
-		controllerContext.EventRecorder,
+		hubAccessReviewTrigger,
+		leaseAnnotations,
+		recorder,
+	)
+
+	// create HubAccessController to report the missing permissions of the agent on the hub
+	hubAccessController := managedcluster.NewHubAccessController(
+		o.ClusterName,
+		hubKubeClient,
+		hubClusterClient,
+		features.DefaultSpokeMutableFeatureGate.Enabled(ocmfeature.AddonManagement),
+		hubAccessReviewTrigger,
+		recorder,
+	)
+
+	// create ClockSyncController to report the clock skew between the managed cluster and the hub
+	hubHTTPClient, err := rest.HTTPClientFor(hubClientConfig)
+	if err != nil {
+		return err
+	}
+	clockSyncController := managedcluster.NewClockSyncController(
+		o.ClusterName,
+		hubClusterClient,
+		hubHTTPClient,
+		hubClientConfig.Host,
+		recorder,
+	)
+
+	// create AgentVersionController to publish the version of the agent on the hub
+	agentVersionController := managedcluster.NewAgentVersionController(
+		o.ClusterName,
+		version.Get().GitVersion,
+		hubClusterClient,
+		hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+		recorder,
+	)
+
+	// both controllers watch the hub kubeconfig secret, which is not written once the hub credentials are in memory
+	var clientCertRotationController, clientCertExpiryController factory.Controller
+	if !o.InMemoryHubCredentials {
+		// create ClientCertRotationController to publish the history of the client certificates on the hub
+		clientCertRotationController = managedcluster.NewClientCertRotationController(
+			o.ClusterName,
+			o.ComponentNamespace,
+			o.HubKubeconfigSecret,
+			hubClusterClient,
+			hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+			namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			recorder,
+		)
+
+		// create ClientCertExpiryController to report the client certificate which is about to expire
+		clientCertExpiryController = managedcluster.NewClientCertExpiryController(
+			o.ClusterName,
+			o.ComponentNamespace,
+			o.HubKubeconfigSecret,
+			o.ClientCertExpiryWarningThreshold,
+			hubClusterClient,
+			namespacedManagementKubeInformerFactory.Core().V1().Secrets(),
+			recorder,
+		)
+	}
+
+	// create HubStateController to cache the last known hub state of the managed cluster on the management cluster
+	hubStateController := managedcluster.NewHubStateController(
+		o.ClusterName,
+		o.ComponentNamespace,
+		o.hubIndex,
+		managementKubeClient,
+		hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+		recorder,
 	)
 
 	// create NewManagedClusterStatusController to update the spoke cluster status
@@ -338,52 +657,87 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 		spokeKubeClient.Discovery(),
 		spokeKubeInformerFactory.Core().V1().Nodes(),
 		o.ClusterHealthCheckPeriod,
-		controllerContext.EventRecorder,
+		hubAccessReviewTrigger,
+		recorder,
 	)
-	spokeClusterClient, err := clusterv1client.NewForConfig(spokeClientConfig)
-	if err != nil {
-		return err
+	var managedClusterClientConfigController factory.Controller
+	if len(o.SpokeExternalServerURLs) != 0 && o.SpokeExternalServerURLProbePeriod > 0 {
+		// create managedClusterClientConfigController to order the client configs by reachability
+		managedClusterClientConfigController = managedcluster.NewManagedClusterClientConfigController(
+			o.ClusterName,
+			o.SpokeExternalServerURLs,
+			spokeClusterCABundle,
+			hubClusterClient,
+			hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
+			o.SpokeExternalServerURLProbePeriod,
+			recorder,
+		)
 	}
-	spokeClusterInformerFactory := clusterv1informers.NewSharedInformerFactory(spokeClusterClient, 10*time.Minute)
+
+	spokeClusterInformerFactory := agentConfig.spokeClusterInformerFactory
 
 	var managedClusterClaimController factory.Controller
 	if features.DefaultSpokeMutableFeatureGate.Enabled(ocmfeature.ClusterClaim) {
-		// create managedClusterClaimController to sync cluster claims
+		// create managedClusterClaimController to sync cluster claims, the well-known claims are synthesized
+		// if they are not created on the managed cluster
 		managedClusterClaimController = managedcluster.NewManagedClusterClaimController(
 			o.ClusterName,
 			o.MaxCustomClusterClaims,
 			hubClusterClient,
 			hubClusterInformerFactory.Cluster().V1().ManagedClusters(),
 			spokeClusterInformerFactory.Cluster().V1alpha1().ClusterClaims(),
-			controllerContext.EventRecorder,
+			spokeKubeClient.Discovery(),
+			spokeKubeInformerFactory.Core().V1().Nodes(),
+			recorder,
 		)
 	}
 
-	var addOnLeaseController factory.Controller
-	var addOnRegistrationController factory.Controller
-	if features.DefaultSpokeMutableFeatureGate.Enabled(ocmfeature.AddonManagement) {
-		addOnLeaseController = addon.NewManagedClusterAddOnLeaseController(
+	// create HubMigrationController to migrate the managed cluster to a new hub, the agent returns an error
+	// once it is ready to bootstrap with the new hub, so that it is restarted
+	var migrated atomic.Bool
+	var hubMigrationController factory.Controller
+	if len(o.BootstrapKubeconfigSecret) != 0 {
+		hubMigrationController = managedcluster.NewHubMigrationController(
 			o.ClusterName,
-			addOnClient,
-			addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns(),
-			hubKubeClient.CoordinationV1(),
-			managementKubeClient.CoordinationV1(),
-			spokeKubeClient.CoordinationV1(),
-			AddOnLeaseControllerSyncInterval, //TODO: this interval time should be allowed to change from outside
-			controllerContext.EventRecorder,
+			hubClientConfig.Host,
+			hubKubeClient,
+			hubClusterClient,
+			managementKubeClient.CoreV1(),
+			o.ComponentNamespace,
+			o.BootstrapKubeconfigSecret,
+			o.BootstrapKubeconfig,
+			o.HubKubeconfigSecret,
+			o.HubKubeconfigDir,
+			func() {
+				migrated.Store(true)
+				stopAgent()
+			},
+			recorder,
 		)
+	}
 
-		addOnRegistrationController = addon.NewAddOnRegistrationController(
-			o.ClusterName,
-			o.AgentName,
-			kubeconfigData,
-			addOnClient,
-			managementKubeClient,
-			spokeKubeClient,
-			csrControl,
-			addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns(),
-			controllerContext.EventRecorder,
-		)
+	var addOnControllers []factory.Controller
+	if features.DefaultSpokeMutableFeatureGate.Enabled(ocmfeature.AddonManagement) {
+		addOnControllerRegistry := o.AddOnControllers
+		if addOnControllerRegistry == nil {
+			addOnControllerRegistry = addon.NewDefaultAddOnControllerRegistry()
+		}
+		addOnControllers, err = addOnControllerRegistry.NewControllers(addon.AddOnControllerContext{
+			ClusterName:          o.ClusterName,
+			AgentName:            o.AgentName,
+			KubeconfigData:       kubeconfigData,
+			HubKubeClient:        hubKubeClient,
+			ManagementKubeClient: managementKubeClient,
+			SpokeKubeClient:      spokeKubeClient,
+			AddOnClient:          addOnClient,
+			AddOnInformer:        addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns(),
+			CSRControl:           csrControl,
+			LeaseSyncInterval:    AddOnLeaseControllerSyncInterval, //TODO: this interval time should be allowed to change from outside
+			Recorder:             recorder,
+		})
+		if err != nil {
+			return err
+		}
 	}
 
 	go hubKubeInformerFactory.Start(ctx.Done())
@@ -396,16 +750,34 @@ func (o *SpokeAgentOptions) RunSpokeAgent(ctx context.Context, controllerContext
 	go clientCertForHubController.Run(ctx, 1)
 	go managedClusterJoiningController.Run(ctx, 1)
 	go managedClusterLeaseController.Run(ctx, 1)
+	go hubAccessController.Run(ctx, 1)
+	go clockSyncController.Run(ctx, 1)
+	go agentVersionController.Run(ctx, 1)
+	if clientCertRotationController != nil {
+		go clientCertRotationController.Run(ctx, 1)
+	}
+	if clientCertExpiryController != nil {
+		go clientCertExpiryController.Run(ctx, 1)
+	}
+	go hubStateController.Run(ctx, 1)
 	go managedClusterHealthCheckController.Run(ctx, 1)
+	if managedClusterClientConfigController != nil {
+		go managedClusterClientConfigController.Run(ctx, 1)
+	}
 	if features.DefaultSpokeMutableFeatureGate.Enabled(ocmfeature.ClusterClaim) {
 		go managedClusterClaimController.Run(ctx, 1)
 	}
-	if features.DefaultSpokeMutableFeatureGate.Enabled(ocmfeature.AddonManagement) {
-		go addOnLeaseController.Run(ctx, 1)
-		go addOnRegistrationController.Run(ctx, 1)
+	for _, addOnController := range addOnControllers {
+		go addOnController.Run(ctx, 1)
+	}
+	if hubMigrationController != nil {
+		go hubMigrationController.Run(ctx, 1)
 	}
 
 	<-ctx.Done()
+	if migrated.Load() {
+		return errors.New("the managed cluster is migrated to a new hub, restart the agent to bootstrap with the new hub")
+	}
 	return nil
 }
 
@@ -416,20 +788,79 @@ func (o *SpokeAgentOptions) AddFlags(fs *pflag.FlagSet) {
 		"If non-empty, will use as cluster name instead of generated random name.")
 	fs.StringVar(&o.BootstrapKubeconfig, "bootstrap-kubeconfig", o.BootstrapKubeconfig,
 		"The path of the kubeconfig file for agent bootstrap.")
+	fs.StringSliceVar(&o.BootstrapKubeconfigs, "bootstrap-kubeconfigs", o.BootstrapKubeconfigs,
+		"The paths of the kubeconfig files for agent bootstrap on multiple hubs. The managed cluster is registered to all of the hubs simultaneously, the hub kubeconfig of the i-th (i > 0) hub is stored in '<hub-kubeconfig-secret>-<i>' and '<hub-kubeconfig-dir>-<i>', the addons are only managed with the first hub and the hub migration is disabled. It is mutually exclusive with --bootstrap-kubeconfig and requires --cluster-name.")
+	fs.StringVar(&o.BootstrapKubeconfigSecret, "bootstrap-kubeconfig-secret", o.BootstrapKubeconfigSecret,
+		"The name of secret in component namespace mounted as --bootstrap-kubeconfig. Once the managed cluster is migrated to a new hub, the agent writes the bootstrap kubeconfig of the new hub into it and bootstraps with the new hub. Set it to empty to disable the hub migration.")
 	fs.StringVar(&o.HubKubeconfigSecret, "hub-kubeconfig-secret", o.HubKubeconfigSecret,
 		"The name of secret in component namespace storing kubeconfig for hub.")
 	fs.StringVar(&o.HubKubeconfigDir, "hub-kubeconfig-dir", o.HubKubeconfigDir,
 		"The mount path of hub-kubeconfig-secret in the container.")
 	fs.StringVar(&o.SpokeKubeconfig, "spoke-kubeconfig", o.SpokeKubeconfig,
-		"The path of the kubeconfig file for managed/spoke cluster. If this is not set, will use '--kubeconfig' to build client to connect to the managed cluster.")
+		"The path of the kubeconfig file for managed/spoke cluster. If this is not set, will use '--kubeconfig' to build client to connect to the managed cluster. The credentials in the file are reloaded once it changes.")
 	fs.StringArrayVar(&o.SpokeExternalServerURLs, "spoke-external-server-urls", o.SpokeExternalServerURLs,
-		"A list of reachable spoke cluster api server URLs for hub cluster.")
+		"A list of reachable spoke cluster api server URLs for hub cluster. If this is not set, the agent tries to discover it from the cluster-info configmap in the kube-public namespace.")
+	fs.DurationVar(&o.SpokeExternalServerURLProbePeriod, "spoke-external-server-url-probe-period", o.SpokeExternalServerURLProbePeriod,
+		"The period to probe the reachability of the spoke external server URLs and reorder the client configs of the managed cluster accordingly. Set it to zero to disable probing.")
 	fs.DurationVar(&o.ClusterHealthCheckPeriod, "cluster-healthcheck-period", o.ClusterHealthCheckPeriod,
 		"The period to check managed cluster kube-apiserver health")
 	fs.IntVar(&o.MaxCustomClusterClaims, "max-custom-cluster-claims", o.MaxCustomClusterClaims,
 		"The max number of custom cluster claims to expose.")
 	fs.Int32Var(&o.ClientCertExpirationSeconds, "client-cert-expiration-seconds", o.ClientCertExpirationSeconds,
 		"The requested duration in seconds of validity of the issued client certificate. If this is not set, the value of --cluster-signing-duration command-line flag of the kube-controller-manager will be used.")
+	fs.Float64Var(&o.ClientCertRotationJitter, "client-cert-rotation-jitter", o.ClientCertRotationJitter,
+		"The max fraction of the life of the client certificate added randomly to the 20% of its life remaining when it is rotated, e.g. 0.3 rotates it with a random percentage range from 20% to 50% of its life remaining. Raise it to spread the rotation of the certificates of the agents registered at the same time, which otherwise request new certificates from the hub at the same time. Set it to zero to rotate the certificates with exactly 20% of their life remaining.")
+	fs.Float64Var(&o.ClientCertExpiryWarningThreshold, "client-cert-expiry-warning-threshold", o.ClientCertExpiryWarningThreshold,
+		"The fraction of the lifetime of the client certificate for the hub, below which the remaining lifetime is reported with a false HubClientCertificateValid condition of the managed cluster and a warning event, since the rotation of the certificate keeps failing. It must be less than 0.2, at which the rotation starts. Set it to zero to disable the condition.")
+	fs.StringVar(&o.RegistrationDriver, "registration-driver", o.RegistrationDriver,
+		"The driver used to obtain the credential for the hub, one of 'csr', 'token' and 'awsirsa'. The 'token' driver uses the token in --hub-token-file instead of a client certificate signed through CertificateSigningRequest. The 'awsirsa' driver uses the AWS IAM role in --managed-cluster-role-arn to access an EKS hub.")
+	fs.StringVar(&o.HubTokenFile, "hub-token-file", o.HubTokenFile,
+		"The path of the file containing the token for the hub, e.g. a ServiceAccount token minted for the cluster or an OIDC token. It is required by the 'token' registration driver.")
+	fs.StringVar(&o.HubTokenUsername, "hub-token-username", o.HubTokenUsername,
+		"The username authenticated by the hub with the token in --hub-token-file, e.g. system:serviceaccount:<namespace>:<name> for a ServiceAccount token. The hub binds the roles of the managed cluster to it once the cluster is accepted. It is required by the 'token' registration driver.")
+	fs.StringVar(&o.HubClusterARN, "hub-cluster-arn", o.HubClusterARN,
+		"The ARN of the EKS hub cluster. It is required by the 'awsirsa' registration driver.")
+	fs.StringVar(&o.ManagedClusterRoleARN, "managed-cluster-role-arn", o.ManagedClusterRoleARN,
+		"The ARN of the AWS IAM role used by the agent to access the EKS hub cluster. It is required by the 'awsirsa' registration driver.")
+	fs.StringVar(&o.HubProxyURL, "hub-proxy-url", o.HubProxyURL,
+		"The URL of the HTTP(S) or SOCKS5 proxy used by the agent to connect to the hub cluster. If this is not set, the agent connects to the hub directly.")
+	fs.StringVar(&o.HubProxyCAFile, "hub-proxy-ca-file", o.HubProxyCAFile,
+		"The path of the CA bundle file used to verify a HTTPS proxy. It is appended to the CA bundle of the hub kubeconfig.")
+	fs.StringVar(&o.HubProxyCredentialsFile, "hub-proxy-credentials-file", o.HubProxyCredentialsFile,
+		"The path of the file containing the proxy credentials in the form of 'username:password'. The credentials are only used by the agent in memory and are never written into the hub kubeconfigs "+
+			"of the agent and the addons, so an addon connecting to the hub through the proxy needs its own credentials.")
+	fs.StringSliceVar(&o.HubHostAliases, "hub-host-aliases", o.HubHostAliases,
+		"A list of 'host=ip' entries used by the agent to resolve the hosts of the hub and the proxy instead of the DNS, e.g. on an air-gapped cluster which cannot resolve the public DNS name of the hub. "+
+			"A host may be listed with several IPs, e.g. an IPv4 and an IPv6 address, which are tried in order. The certificate of the hub is still verified against the host name.")
+	fs.StringVar(&o.HubDNSServer, "hub-dns-server", o.HubDNSServer,
+		"The address of the DNS server, in the form of 'ip' or 'ip:port', used by the agent to resolve the hosts of the hub and the proxy which are not in --hub-host-aliases. If this is not set, the resolver of the system is used.")
+	fs.StringVar(&o.AddOnHubCAFile, "addon-hub-ca-file", o.AddOnHubCAFile,
+		"The path of an additional CA bundle file appended to the CA bundle of the hub kubeconfig of the addons, e.g. the CA of a proxy between the addons and the hub. The hub kubeconfig of the addons contains the proxy of the agent and its CA already.")
+	fs.StringVar(&o.FeatureGatesFile, "feature-gates-file", o.FeatureGatesFile,
+		"The path of the file, e.g. a key of a mounted ConfigMap, containing a list of 'Feature=true|false' pairs. The feature gates are reloaded once the file changes and the controllers are restarted without restarting the agent.")
+	fs.Float32Var(&o.HubKubeAPIQPS, "hub-kube-api-qps", o.HubKubeAPIQPS,
+		"The QPS to use while talking with the hub kube-apiserver. If this is not set, the default of the kube client is used.")
+	fs.IntVar(&o.HubKubeAPIBurst, "hub-kube-api-burst", o.HubKubeAPIBurst,
+		"The burst to use while talking with the hub kube-apiserver. If this is not set, the default of the kube client is used.")
+	fs.BoolVar(&o.DeterministicAgentName, "deterministic-agent-name", o.DeterministicAgentName,
+		"Derive the agent name from the UID of the kube-system namespace of the managed cluster instead of generating a random one, so that the agent reinstalled on the same cluster "+
+			"keeps its identity, e.g. the subject of its CSRs. The agent name in the existing hub kubeconfig secret is still preferred.")
+	fs.DurationVar(&o.BootstrapRetryInitialInterval, "bootstrap-retry-initial-interval", o.BootstrapRetryInitialInterval,
+		"The interval to retry once the agent fails to send a request to the hub during the bootstrap, e.g. creating the managed cluster or the CSR. The interval is doubled after each failure up to --bootstrap-retry-max-interval, and reset once the request succeeds.")
+	fs.DurationVar(&o.BootstrapRetryMaxInterval, "bootstrap-retry-max-interval", o.BootstrapRetryMaxInterval,
+		"The max interval to retry the failed requests to the hub during the bootstrap.")
+	fs.Float64Var(&o.BootstrapRetryJitter, "bootstrap-retry-jitter", o.BootstrapRetryJitter,
+		"The factor to extend each retry interval randomly during the bootstrap, e.g. 0.2 extends it by up to 20%, so the agents of many clusters do not retry at the same time once the hub is back.")
+	fs.DurationVar(&o.BootstrapMaxElapsedTime, "bootstrap-max-elapsed-time", o.BootstrapMaxElapsedTime,
+		"The max time the requests to the hub keep failing during the bootstrap. Once it is exceeded, the agent reports a BootstrapFailed event and exits with an error. Set it to zero to retry forever.")
+	fs.BoolVar(&o.InMemoryHubCredentials, "in-memory-hub-credentials", o.InMemoryHubCredentials,
+		"Keep the client certificate and key for the hub in memory only instead of writing them into --hub-kubeconfig-secret and --hub-kubeconfig-dir, so they are never persisted on the managed cluster. "+
+			"A new client certificate is requested with the bootstrap kubeconfig on every start of the agent, and the history and the expiry of the client certificate are not reported. It requires --cluster-name and the 'csr' registration driver.")
+	fs.StringVar(&o.HubKubeconfigKMSEndpoint, "hub-kubeconfig-kms-endpoint", o.HubKubeconfigKMSEndpoint,
+		"The unix socket endpoint of a KMS v1 plugin, e.g. 'unix:///var/run/kmsplugin/socket.sock', used to encrypt the private key in --hub-kubeconfig-secret with the envelope encryption before it is written into the managed cluster, "+
+			"for the environments which distrust the encryption of the etcd of the managed cluster. The encrypted private key is kept in the 'tls.key.enc' key of the secret instead of 'tls.key'. The private key is decrypted into --hub-kubeconfig-dir, which should be a volume in memory. It is only supported by the 'csr' registration driver.")
+	fs.DurationVar(&o.HubKubeconfigKMSTimeout, "hub-kubeconfig-kms-timeout", o.HubKubeconfigKMSTimeout,
+		"The timeout of the requests to the KMS plugin in --hub-kubeconfig-kms-endpoint.")
 }
 
 // Validate verifies the inputs.
@@ -459,13 +890,102 @@ func (o *SpokeAgentOptions) Validate() error {
 		return errors.New("cluster healthcheck period must greater than zero")
 	}
 
+	if o.BootstrapRetryInitialInterval < 0 || o.BootstrapRetryMaxInterval < o.BootstrapRetryInitialInterval {
+		return errors.New("bootstrap retry max interval must greater or equal to the initial interval")
+	}
+
+	if o.BootstrapRetryJitter < 0 {
+		return errors.New("bootstrap retry jitter must not be negative")
+	}
+
+	if o.BootstrapMaxElapsedTime < 0 {
+		return errors.New("bootstrap max elapsed time must not be negative")
+	}
+
 	if o.ClientCertExpirationSeconds != 0 && o.ClientCertExpirationSeconds < 600 {
 		return errors.New("client certificate expiration seconds must greater or qual to 600")
 	}
 
+	if o.ClientCertRotationJitter < 0 || o.ClientCertRotationJitter > 0.5 {
+		return errors.New("client certificate rotation jitter must be in the range from 0 to 0.5")
+	}
+
+	if o.ClientCertExpiryWarningThreshold < 0 || o.ClientCertExpiryWarningThreshold >= 0.2 {
+		return errors.New("client certificate expiry warning threshold must be in the range from 0 to 0.2")
+	}
+
+	if len(o.HubProxyURL) != 0 {
+		proxyURL, err := url.Parse(o.HubProxyURL)
+		if err != nil {
+			return fmt.Errorf("hub proxy url %q is invalid: %w", o.HubProxyURL, err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("hub proxy url %q is invalid: unsupported scheme %q", o.HubProxyURL, proxyURL.Scheme)
+		}
+	}
+
+	if _, err := o.hubHostAliases(); err != nil {
+		return err
+	}
+
+	if len(o.HubDNSServer) != 0 {
+		if _, err := hubDNSServerAddress(o.HubDNSServer); err != nil {
+			return err
+		}
+	}
+
+	switch o.RegistrationDriver {
+	case "", registration.CSRDriverName:
+	case registration.TokenDriverName:
+		if len(o.HubTokenFile) == 0 || len(o.HubTokenUsername) == 0 {
+			return errors.New("hub-token-file and hub-token-username are required by the token registration driver")
+		}
+	case registration.AWSIRSADriverName:
+		if len(o.HubClusterARN) == 0 || len(o.ManagedClusterRoleARN) == 0 {
+			return errors.New("hub-cluster-arn and managed-cluster-role-arn are required by the awsirsa registration driver")
+		}
+		if err := registration.ValidateEKSClusterARN(o.HubClusterARN); err != nil {
+			return fmt.Errorf("hub-cluster-arn is invalid: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported registration driver %q", o.RegistrationDriver)
+	}
+
+	if len(o.HubProxyURL) == 0 && (len(o.HubProxyCAFile) != 0 || len(o.HubProxyCredentialsFile) != 0) {
+		return errors.New("hub-proxy-url is required when hub proxy CA or credentials are specified")
+	}
+
+	if o.InMemoryHubCredentials && len(o.RegistrationDriver) != 0 && o.RegistrationDriver != registration.CSRDriverName {
+		return fmt.Errorf("in-memory-hub-credentials is not supported by the %s registration driver", o.RegistrationDriver)
+	}
+
+	if len(o.HubKubeconfigKMSEndpoint) != 0 || o.HubKubeconfigTransformer != nil {
+		if len(o.RegistrationDriver) != 0 && o.RegistrationDriver != registration.CSRDriverName {
+			return fmt.Errorf("the encryption of the hub kubeconfig secret is not supported by the %s registration driver", o.RegistrationDriver)
+		}
+		if o.InMemoryHubCredentials {
+			return errors.New("the hub kubeconfig secret is not written to be encrypted when in-memory-hub-credentials is enabled")
+		}
+	}
+	if len(o.HubKubeconfigKMSEndpoint) != 0 && o.HubKubeconfigKMSTimeout <= 0 {
+		return errors.New("hub kubeconfig kms timeout must greater than zero")
+	}
+
 	return nil
 }
 
+// bootstrapRetryBackoff returns the backoff of the bootstrap, it is nil if the initial interval is not set and
+// the failed requests are retried by the rate limiter of the controllers instead.
+func (o *SpokeAgentOptions) bootstrapRetryBackoff() *helpers.RetryBackoff {
+	if o.BootstrapRetryInitialInterval <= 0 {
+		return nil
+	}
+	return helpers.NewRetryBackoff(o.BootstrapRetryInitialInterval, o.BootstrapRetryMaxInterval,
+		o.BootstrapRetryJitter, o.BootstrapMaxElapsedTime)
+}
+
 // Complete fills in missing values.
 func (o *SpokeAgentOptions) Complete(coreV1Client corev1client.CoreV1Interface, ctx context.Context, recorder events.Recorder) error {
 	// get component namespace of spoke agent
@@ -476,9 +996,31 @@ func (o *SpokeAgentOptions) Complete(coreV1Client corev1client.CoreV1Interface,
 		o.ComponentNamespace = string(nsBytes)
 	}
 
+	// nothing of the hub kubeconfig is persisted once the hub credentials are in memory, so the cluster name
+	// is not able to be loaded, and the agent name is derived or generated on every start.
+	if o.InMemoryHubCredentials {
+		if len(o.ClusterName) == 0 {
+			return errors.New("cluster-name is required when the hub credentials are in memory")
+		}
+		o.AgentName = generateAgentName()
+		if o.DeterministicAgentName && len(o.clusterIdentity) != 0 {
+			o.AgentName = deriveAgentName(o.clusterIdentity)
+		}
+		return nil
+	}
+
+	// the hub kubeconfig secret is decrypted with the KMS plugin once it is dumped
+	if len(o.HubKubeconfigKMSEndpoint) != 0 && o.HubKubeconfigTransformer == nil {
+		kmsService, err := envelope.NewGRPCService(ctx, o.HubKubeconfigKMSEndpoint, o.HubKubeconfigKMSTimeout)
+		if err != nil {
+			return fmt.Errorf("unable to connect to the kms plugin %q: %w", o.HubKubeconfigKMSEndpoint, err)
+		}
+		o.HubKubeconfigTransformer = clientcert.NewKMSSecretTransformer(kmsService)
+	}
+
 	// dump data in hub kubeconfig secret into file system if it exists
 	err = managedcluster.DumpSecret(coreV1Client, o.ComponentNamespace, o.HubKubeconfigSecret,
-		o.HubKubeconfigDir, ctx, recorder)
+		o.HubKubeconfigDir, o.HubKubeconfigTransformer, ctx, recorder)
 	if err != nil {
 		return err
 	}
@@ -499,49 +1041,47 @@ func generateAgentName() string {
 	return utilrand.String(spokeAgentNameLength)
 }
 
-// hasValidHubClientConfig returns ture if all the conditions below are met:
-//  1. KubeconfigFile exists;
-//  2. TLSKeyFile exists;
-//  3. TLSCertFile exists;
-//  4. Certificate in TLSCertFile is issued for the current cluster/agent;
-//  5. Certificate in TLSCertFile is not expired;
-//
-// Normally, KubeconfigFile/TLSKeyFile/TLSCertFile will be created once the bootstrap process
-// completes. Changing the name of the cluster will make the existing hub kubeconfig invalid,
-// because certificate in TLSCertFile is issued to a specific cluster/agent.
-func (o *SpokeAgentOptions) hasValidHubClientConfig() (bool, error) {
-	kubeconfigPath := path.Join(o.HubKubeconfigDir, clientcert.KubeconfigFile)
-	if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) {
-		klog.V(4).Infof("Kubeconfig file %q not found", kubeconfigPath)
-		return false, nil
-	}
-
-	keyPath := path.Join(o.HubKubeconfigDir, clientcert.TLSKeyFile)
-	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
-		klog.V(4).Infof("TLS key file %q not found", keyPath)
-		return false, nil
-	}
+// deriveAgentName derives the name for spoke cluster agent from the identity of the spoke cluster
+func deriveAgentName(clusterIdentity string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(clusterIdentity)))[:spokeAgentNameLength]
+}
 
-	certPath := path.Join(o.HubKubeconfigDir, clientcert.TLSCertFile)
-	certData, err := ioutil.ReadFile(path.Clean(certPath))
+// hasValidHubClientConfig returns true if the hub kubeconfig in HubKubeconfigDir is valid for the
+// current cluster/agent. The validation is delegated to the registration driver.
+func (o *SpokeAgentOptions) hasValidHubClientConfig() (bool, error) {
+	registrationDriver, err := o.registrationDriver(nil, nil)
 	if err != nil {
-		klog.V(4).Infof("Unable to load TLS cert file %q", certPath)
-		return false, nil
+		return false, err
 	}
+	return registrationDriver.IsHubKubeconfigValid()
+}
 
-	// check if the tls certificate is issued for the current cluster/agent
-	clusterName, agentName, err := managedcluster.GetClusterAgentNamesFromCertificate(certData)
+// registrationDriver returns the registration driver specified by RegistrationDriver.
+func (o *SpokeAgentOptions) registrationDriver(managementKubeClient kubernetes.Interface, recorder events.Recorder) (registration.RegistrationDriver, error) {
+	proxyURL, err := o.hubProxyURLString()
 	if err != nil {
-		return false, nil
+		return nil, err
 	}
-	if clusterName != o.ClusterName || agentName != o.AgentName {
-		klog.V(4).Infof("Certificate in file %q is issued for agent %q instead of %q",
-			certPath, fmt.Sprintf("%s:%s", clusterName, agentName),
-			fmt.Sprintf("%s:%s", o.ClusterName, o.AgentName))
-		return false, nil
+	options := registration.Options{
+		ClusterName:                 o.ClusterName,
+		AgentName:                   o.AgentName,
+		ComponentNamespace:          o.ComponentNamespace,
+		HubKubeconfigSecret:         o.HubKubeconfigSecret,
+		HubKubeconfigDir:            o.HubKubeconfigDir,
+		HubProxyURL:                 proxyURL,
+		ClientCertExpirationSeconds: o.ClientCertExpirationSeconds,
+		ClientCertRotationJitter:    o.ClientCertRotationJitter,
+		SecretTransformer:           o.HubKubeconfigTransformer,
+		HubTokenFile:                o.HubTokenFile,
+		HubClusterARN:               o.HubClusterARN,
+		ManagedClusterRoleARN:       o.ManagedClusterRoleARN,
+		ManagementKubeClient:        managementKubeClient,
+		Recorder:                    recorder,
 	}
-
-	return clientcert.IsCertificateValid(certData, nil)
+	if o.hubCredentialStore != nil {
+		options.SecretStore = o.hubCredentialStore
+	}
+	return registration.NewRegistrationDriver(o.RegistrationDriver, options)
 }
 
 // getOrGenerateClusterAgentNames returns cluster name and agent name.
@@ -603,6 +1143,10 @@ func (o *SpokeAgentOptions) getOrGenerateClusterAgentNames() (string, string) {
 	case err == nil:
 		// use agent name loaded from the mounted secret
 		agentName = string(agentNameBytes)
+	case o.DeterministicAgentName && len(o.clusterIdentity) != 0:
+		// derive the agent name from the identity of the spoke cluster, so that the agent reinstalled on
+		// the same cluster keeps its name
+		agentName = deriveAgentName(o.clusterIdentity)
 	default:
 		// generate random agent name
 		agentName = generateAgentName()
@@ -626,15 +1170,356 @@ func (o *SpokeAgentOptions) getSpokeClusterCABundle(kubeConfig *rest.Config) ([]
 	return data, nil
 }
 
-// spokeKubeConfig builds kubeconfig for the spoke/managed cluster
-func (o *SpokeAgentOptions) spokeKubeConfig(controllerContext *controllercmd.ControllerContext) (*rest.Config, error) {
+// discoverSpokeExternalServerURL returns the server URL and CA data of the spoke cluster recorded in the
+// cluster-info configmap in the kube-public namespace, which is maintained by kubeadm and similar tools.
+// An empty server URL is returned if the configmap does not exist or contains no valid https server URL.
+func discoverSpokeExternalServerURL(ctx context.Context, coreV1Client corev1client.CoreV1Interface) (string, []byte, error) {
+	clusterInfo, err := coreV1Client.ConfigMaps(clusterInfoNamespace).Get(ctx, clusterInfoName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	kubeconfigData, ok := clusterInfo.Data[clusterInfoKubeconfigKey]
+	if !ok {
+		return "", nil, nil
+	}
+
+	kubeconfig, err := clientcmd.Load([]byte(kubeconfigData))
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to load kubeconfig in configmap %s/%s: %w", clusterInfoNamespace, clusterInfoName, err)
+	}
+
+	// prefer the cluster of the current context, and fall back to the only cluster in the kubeconfig
+	var cluster *clientcmdapi.Cluster
+	if currentContext, ok := kubeconfig.Contexts[kubeconfig.CurrentContext]; ok {
+		cluster = kubeconfig.Clusters[currentContext.Cluster]
+	}
+	if cluster == nil && len(kubeconfig.Clusters) == 1 {
+		for _, c := range kubeconfig.Clusters {
+			cluster = c
+		}
+	}
+	if cluster == nil || !helpers.IsValidHTTPSURL(cluster.Server) {
+		return "", nil, nil
+	}
+
+	return cluster.Server, cluster.CertificateAuthorityData, nil
+}
+
+// getClusterIdentity returns the UID of the kube-system namespace of the spoke cluster, which is unique
+// and does not change unless the cluster is rebuilt.
+func getClusterIdentity(ctx context.Context, coreV1Client corev1client.CoreV1Interface) (string, error) {
+	namespace, err := coreV1Client.Namespaces().Get(ctx, clusterIdentityNamespace, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return string(namespace.UID), nil
+}
+
+// hostedMode returns true if the agent runs on a management cluster other than the managed cluster.
+func (o *SpokeAgentOptions) hostedMode() bool {
+	return len(o.SpokeKubeconfig) != 0
+}
+
+// spokeKubeConfig builds kubeconfig for the spoke/managed cluster, its credentials are reloaded once the
+// spoke kubeconfig file changes
+func (o *SpokeAgentOptions) spokeKubeConfig(managementKubeConfig *rest.Config) (*rest.Config, error) {
 	if o.SpokeKubeconfig == "" {
-		return controllerContext.KubeConfig, nil
+		return managementKubeConfig, nil
 	}
 
 	config, err := clientcmd.BuildConfigFromFlags("" /* leave masterurl as empty */, o.SpokeKubeconfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load spoke kubeconfig from file %q: %w", o.SpokeKubeconfig, err)
 	}
+	// the spoke kubeconfig may be rotated, e.g. by the operator which deploys the agent in the detached mode
+	return newReloadingKubeConfig(o.SpokeKubeconfig, config)
+}
+
+// hubProxyURL returns the URL of the proxy used to connect to the hub cluster. It does not contain the
+// credentials in HubProxyCredentialsFile, which are only added at runtime by applyHubProxy.
+func (o *SpokeAgentOptions) hubProxyURL() (*url.URL, error) {
+	if len(o.HubProxyURL) == 0 {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(o.HubProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("hub proxy url %q is invalid: %w", o.HubProxyURL, err)
+	}
+	return proxyURL, nil
+}
+
+// hubProxyCredentials returns the credentials in HubProxyCredentialsFile, it returns nil if the file is
+// not specified.
+func (o *SpokeAgentOptions) hubProxyCredentials() (*url.Userinfo, error) {
+	if len(o.HubProxyCredentialsFile) == 0 {
+		return nil, nil
+	}
+
+	credentials, err := ioutil.ReadFile(path.Clean(o.HubProxyCredentialsFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load hub proxy credentials from file %q: %w", o.HubProxyCredentialsFile, err)
+	}
+	username, password, found := strings.Cut(strings.TrimSpace(string(credentials)), ":")
+	if !found {
+		return nil, fmt.Errorf("hub proxy credentials in file %q are not in the form of 'username:password'", o.HubProxyCredentialsFile)
+	}
+	return url.UserPassword(username, password), nil
+}
+
+// hubProxyURLString returns the proxy URL written into the generated hub kubeconfigs of the agent and the
+// addons. It never contains the proxy credentials, since the kubeconfigs are persisted in secrets.
+func (o *SpokeAgentOptions) hubProxyURLString() (string, error) {
+	proxyURL, err := o.hubProxyURL()
+	if err != nil || proxyURL == nil {
+		return "", err
+	}
+	return proxyURL.String(), nil
+}
+
+// hubClientConfig returns the client config of the hub clients. It is loaded from the hub kubeconfig in
+// HubKubeconfigDir, or built from the bootstrap client config with the client certificate in memory once
+// InMemoryHubCredentials is enabled.
+func (o *SpokeAgentOptions) hubClientConfig(bootstrapClientConfig *rest.Config) (*rest.Config, error) {
+	if o.hubCredentialStore != nil {
+		// the proxy, dialer and rate limit of the bootstrap client config are kept
+		return newInMemoryHubClientConfig(bootstrapClientConfig, o.hubCredentialStore)
+	}
+
+	hubClientConfig, err := loadClientConfig(path.Join(o.HubKubeconfigDir, clientcert.KubeconfigFile))
+	if err != nil {
+		return nil, err
+	}
+	// the hub kubeconfig generated by the agent contains the proxy already, apply it again in case the
+	// hub kubeconfig was generated before the proxy was configured.
+	if err := o.applyHubProxy(hubClientConfig); err != nil {
+		return nil, err
+	}
+	if err := o.applyHubDialer(hubClientConfig); err != nil {
+		return nil, err
+	}
+	o.applyHubRateLimit(hubClientConfig)
+	return hubClientConfig, nil
+}
+
+// loadClientConfig loads a client config from the kubeconfig file. Besides client certificates and
+// tokens, the kubeconfig may authenticate with an exec credential plugin or an auth provider, e.g. a
+// kubeconfig generated by 'aws eks update-kubeconfig' or an OIDC kubeconfig, both are kept in the
+// returned config.
+func loadClientConfig(kubeconfigPath string) (*rest.Config, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// inline the CA file, so the kubeconfigs built from the config, e.g. the hub kubeconfig built from
+	// the bootstrap kubeconfig, do not depend on a file which is only available to the agent.
+	if len(config.CAData) == 0 && len(config.CAFile) != 0 {
+		config.CAData, err = ioutil.ReadFile(path.Clean(config.CAFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to load CA from file %q: %w", config.CAFile, err)
+		}
+		config.CAFile = ""
+	}
+
+	// an auth provider persists the refreshed credential back to the kubeconfig file, which usually
+	// is mounted from a secret and is read only. Tolerate the failure, the credential is still cached
+	// in memory by the auth provider.
+	if config.AuthProvider != nil && config.AuthConfigPersister != nil {
+		config.AuthConfigPersister = &bestEffortPersister{kubeconfigPath: kubeconfigPath, delegate: config.AuthConfigPersister}
+	}
 	return config, nil
 }
+
+// bestEffortPersister persists the auth provider config with the delegate and ignores the error
+type bestEffortPersister struct {
+	kubeconfigPath string
+	delegate       rest.AuthProviderConfigPersister
+}
+
+func (p *bestEffortPersister) Persist(config map[string]string) error {
+	if err := p.delegate.Persist(config); err != nil {
+		klog.V(4).Infof("Unable to persist the auth provider config into kubeconfig %q: %v", p.kubeconfigPath, err)
+	}
+	return nil
+}
+
+// applyHubProxy configures the given hub client config to connect to the hub cluster through the
+// proxy. If a proxy CA file is specified, its content is appended to the CA bundle of the config,
+// so the config is able to verify both the hub and a HTTPS proxy.
+//
+// The proxy credentials are only added to the proxy URL of the config in memory. The transport sends
+// them with the CONNECT request of the tunnel to the hub, which a wrapped round tripper cannot do.
+func (o *SpokeAgentOptions) applyHubProxy(config *rest.Config) error {
+	proxyURL, err := o.hubProxyURL()
+	if err != nil {
+		return err
+	}
+	if proxyURL == nil {
+		return nil
+	}
+	credentials, err := o.hubProxyCredentials()
+	if err != nil {
+		return err
+	}
+	if credentials != nil {
+		authProxyURL := *proxyURL
+		authProxyURL.User = credentials
+		proxyURL = &authProxyURL
+	}
+	config.Proxy = http.ProxyURL(proxyURL)
+
+	if len(o.HubProxyCAFile) == 0 {
+		return nil
+	}
+
+	proxyCAData, err := ioutil.ReadFile(path.Clean(o.HubProxyCAFile))
+	if err != nil {
+		return fmt.Errorf("unable to load hub proxy CA from file %q: %w", o.HubProxyCAFile, err)
+	}
+
+	caData := config.CAData
+	if len(caData) == 0 && len(config.CAFile) != 0 {
+		caData, err = ioutil.ReadFile(path.Clean(config.CAFile))
+		if err != nil {
+			return err
+		}
+	}
+	config.CAData = appendCAData(caData, proxyCAData)
+	config.CAFile = ""
+	return nil
+}
+
+// applyHubDialer configures the given hub client config to resolve the hosts of the hub and the proxy with
+// HubHostAliases and HubDNSServer, so that the agent on an air-gapped cluster, which cannot resolve the public
+// DNS name of the hub, connects to the hub with the given IPs. The host of the config is kept, so the
+// certificate of the hub is still verified against the host name.
+func (o *SpokeAgentOptions) applyHubDialer(config *rest.Config) error {
+	if len(o.HubHostAliases) == 0 && len(o.HubDNSServer) == 0 {
+		return nil
+	}
+
+	hostAliases, err := o.hubHostAliases()
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if len(o.HubDNSServer) != 0 {
+		dnsServer, err := hubDNSServerAddress(o.HubDNSServer)
+		if err != nil {
+			return err
+		}
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				d := net.Dialer{Timeout: 5 * time.Second}
+				return d.DialContext(ctx, network, dnsServer)
+			},
+		}
+	}
+
+	config.Dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+		ips, ok := hostAliases[strings.ToLower(host)]
+		if !ok {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		// try the IPs in order, so that a dual-stack hub is still reachable once one of the
+		// address families is not routable from the cluster.
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+	return nil
+}
+
+// hubHostAliases returns the IPs of the hosts in HubHostAliases, keyed by the lower case host names.
+func (o *SpokeAgentOptions) hubHostAliases() (map[string][]string, error) {
+	hostAliases := map[string][]string{}
+	for _, hostAlias := range o.HubHostAliases {
+		host, ip, found := strings.Cut(hostAlias, "=")
+		host = strings.TrimSpace(host)
+		ip = strings.TrimSpace(ip)
+		if !found || len(host) == 0 {
+			return nil, fmt.Errorf("hub host alias %q is not in the form of 'host=ip'", hostAlias)
+		}
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("hub host alias %q is invalid: %q is not an IP address", hostAlias, ip)
+		}
+		host = strings.ToLower(host)
+		hostAliases[host] = append(hostAliases[host], ip)
+	}
+	return hostAliases, nil
+}
+
+// hubDNSServerAddress returns the address of the DNS server in the form of 'ip:port', the port is 53 if it
+// is not specified.
+func hubDNSServerAddress(dnsServer string) (string, error) {
+	ip, port, err := net.SplitHostPort(dnsServer)
+	if err != nil {
+		ip, port = strings.Trim(dnsServer, "[]"), "53"
+	}
+	if net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("hub dns server %q is invalid: %q is not an IP address", dnsServer, ip)
+	}
+	return net.JoinHostPort(ip, port), nil
+}
+
+// appendCAData appends the extra CA bundle to the CA bundle if it is not included yet.
+func appendCAData(caData, extraCAData []byte) []byte {
+	if bytes.Contains(caData, extraCAData) {
+		return caData
+	}
+
+	mergedCAData := append([]byte{}, caData...)
+	if len(mergedCAData) > 0 && !bytes.HasSuffix(mergedCAData, []byte("\n")) {
+		mergedCAData = append(mergedCAData, '\n')
+	}
+	return append(mergedCAData, extraCAData...)
+}
+
+// buildAddOnHubKubeconfig builds the hub kubeconfig of the addons with references to the key/cert files
+// in the same secret. The kubeconfig connects to the hub through the proxy of the agent, if any, and
+// trusts the CA bundle in AddOnHubCAFile besides the CA bundle of the agent.
+func (o *SpokeAgentOptions) buildAddOnHubKubeconfig(hubClientConfig *rest.Config) ([]byte, error) {
+	proxyURL, err := o.hubProxyURLString()
+	if err != nil {
+		return nil, err
+	}
+	kubeconfig := clientcert.BuildKubeconfig(hubClientConfig, proxyURL, clientcert.TLSCertFile, clientcert.TLSKeyFile)
+	if len(o.AddOnHubCAFile) != 0 {
+		extraCAData, err := ioutil.ReadFile(path.Clean(o.AddOnHubCAFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to load addon hub CA from file %q: %w", o.AddOnHubCAFile, err)
+		}
+		for _, cluster := range kubeconfig.Clusters {
+			cluster.CertificateAuthorityData = appendCAData(cluster.CertificateAuthorityData, extraCAData)
+		}
+	}
+	return clientcmd.Write(kubeconfig)
+}
+
+// applyHubRateLimit sets the qps and burst of the client config for the hub if they are specified.
+func (o *SpokeAgentOptions) applyHubRateLimit(config *rest.Config) {
+	if o.HubKubeAPIQPS > 0 {
+		config.QPS = o.HubKubeAPIQPS
+	}
+	if o.HubKubeAPIBurst > 0 {
+		config.Burst = o.HubKubeAPIBurst
+	}
+}
@@ -21,6 +21,11 @@ var (
 	buildDate string
 )
 
+// APICompatibilityLevel is increased once a release of the registration agent requires the APIs or
+// behaviors of the hub which are not provided by the previous releases, or the other way around, so
+// that the hub can tell the agents which are not compatible with it regardless of the version skew.
+const APICompatibilityLevel = 1
+
 // Get returns the overall codebase version. It's for detecting
 // what code a binary was built from.
 func Get() version.Info {
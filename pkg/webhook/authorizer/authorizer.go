@@ -0,0 +1,263 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// SubjectAccessReviewMode authorizes the requests with the SubjectAccessReview api of the hub.
+	SubjectAccessReviewMode = "SubjectAccessReview"
+	// StaticPolicyMode authorizes the requests with the rules in a static policy file.
+	StaticPolicyMode = "StaticPolicy"
+	// WebhookMode authorizes the requests with an external authorizer which serves SubjectAccessReviews.
+	WebhookMode = "Webhook"
+)
+
+// webhookTimeout is the timeout of the requests to the external authorizer.
+const webhookTimeout = 10 * time.Second
+
+// Authorizer checks whether a user is allowed to access a resource. The webhook relies on it to check the
+// permission to accept a ManagedCluster, to set the clusterset label of a ManagedCluster and to bind a
+// ManagedClusterSet.
+type Authorizer interface {
+	Authorize(ctx context.Context, userInfo authenticationv1.UserInfo, attributes authorizationv1.ResourceAttributes) (bool, error)
+	// Mode returns the mode of the authorizer, e.g. SubjectAccessReview, which is recorded in the audit
+	// annotations of the checks made by the authorizer.
+	Mode() string
+}
+
+// subjectAccessReviewAuthorizer authorizes the requests with the SubjectAccessReview api.
+type subjectAccessReviewAuthorizer struct {
+	kubeClient kubernetes.Interface
+}
+
+// NewSubjectAccessReviewAuthorizer returns an Authorizer which creates SubjectAccessReviews on the hub.
+func NewSubjectAccessReviewAuthorizer(kubeClient kubernetes.Interface) Authorizer {
+	return &subjectAccessReviewAuthorizer{kubeClient: kubeClient}
+}
+
+func (a *subjectAccessReviewAuthorizer) Authorize(ctx context.Context, userInfo authenticationv1.UserInfo,
+	attributes authorizationv1.ResourceAttributes) (bool, error) {
+	sar, err := a.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(
+		ctx, newSubjectAccessReview(userInfo, attributes), metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return sar.Status.Allowed, nil
+}
+
+func (a *subjectAccessReviewAuthorizer) Mode() string {
+	return SubjectAccessReviewMode
+}
+
+// webhookAuthorizer posts SubjectAccessReviews to an external authorizer, which follows the protocol of the
+// authorization webhook of the kube-apiserver, so the hub does not have to grant the webhook the permission
+// to create SubjectAccessReviews.
+type webhookAuthorizer struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookAuthorizer returns an Authorizer which posts SubjectAccessReviews to the url of an external
+// authorizer. The server certificate of the authorizer is verified with the CA file if it is set, otherwise
+// with the system CAs.
+func NewWebhookAuthorizer(url, caFile string) (Authorizer, error) {
+	if !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("the url of the authorizer %q is not a https url", url)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(caFile) != 0 {
+		caData, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the CA file of the authorizer: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in the CA file of the authorizer %q", caFile)
+		}
+	}
+
+	return &webhookAuthorizer{
+		url: url,
+		httpClient: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+func (a *webhookAuthorizer) Authorize(ctx context.Context, userInfo authenticationv1.UserInfo,
+	attributes authorizationv1.ResourceAttributes) (bool, error) {
+	sar := newSubjectAccessReview(userInfo, attributes)
+	sar.APIVersion = authorizationv1.SchemeGroupVersion.String()
+	sar.Kind = "SubjectAccessReview"
+	body, err := json.Marshal(sar)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("unable to call the authorizer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("unable to read the response of the authorizer: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return false, fmt.Errorf("the authorizer responded with status %d: %s", resp.StatusCode, string(data))
+	}
+
+	result := &authorizationv1.SubjectAccessReview{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return false, fmt.Errorf("unable to decode the response of the authorizer: %w", err)
+	}
+	return result.Status.Allowed, nil
+}
+
+func (a *webhookAuthorizer) Mode() string {
+	return WebhookMode
+}
+
+// StaticPolicy is the content of the static policy file. A request is allowed if any of its rules matches.
+type StaticPolicy struct {
+	Rules []StaticPolicyRule `json:"rules"`
+}
+
+// StaticPolicyRule allows the users and the groups to access the resources. The resources are in the
+// format of <resource>/<subresource>, e.g. managedclusters/accept. "*" matches any value, <resource>/*
+// matches any subresource of the resource, and empty resourceNames matches any resource name.
+type StaticPolicyRule struct {
+	Users         []string `json:"users,omitempty"`
+	Groups        []string `json:"groups,omitempty"`
+	Verbs         []string `json:"verbs"`
+	APIGroups     []string `json:"apiGroups"`
+	Resources     []string `json:"resources"`
+	ResourceNames []string `json:"resourceNames,omitempty"`
+}
+
+type staticPolicyAuthorizer struct {
+	policy *StaticPolicy
+}
+
+// NewStaticPolicyAuthorizer returns an Authorizer which authorizes the requests with the rules in the
+// static policy file.
+func NewStaticPolicyAuthorizer(policyFile string) (Authorizer, error) {
+	data, err := os.ReadFile(policyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the policy file: %w", err)
+	}
+	policy := &StaticPolicy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("unable to decode the policy file %q: %w", policyFile, err)
+	}
+	return &staticPolicyAuthorizer{policy: policy}, nil
+}
+
+func (a *staticPolicyAuthorizer) Authorize(_ context.Context, userInfo authenticationv1.UserInfo,
+	attributes authorizationv1.ResourceAttributes) (bool, error) {
+	resource := attributes.Resource
+	if len(attributes.Subresource) != 0 {
+		resource = resource + "/" + attributes.Subresource
+	}
+
+	for _, rule := range a.policy.Rules {
+		if !matches(rule.Users, userInfo.Username) && !matchesAny(rule.Groups, userInfo.Groups) {
+			continue
+		}
+		if !matches(rule.Verbs, attributes.Verb) || !matches(rule.APIGroups, attributes.Group) ||
+			!matchesResource(rule.Resources, resource) {
+			continue
+		}
+		if len(rule.ResourceNames) != 0 && !matches(rule.ResourceNames, attributes.Name) {
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (a *staticPolicyAuthorizer) Mode() string {
+	return StaticPolicyMode
+}
+
+func matches(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesResource(resources []string, resource string) bool {
+	for _, r := range resources {
+		if r == "*" || r == resource {
+			return true
+		}
+		if strings.HasSuffix(r, "/*") && strings.HasPrefix(resource, strings.TrimSuffix(r, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if matches(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func newSubjectAccessReview(userInfo authenticationv1.UserInfo,
+	attributes authorizationv1.ResourceAttributes) *authorizationv1.SubjectAccessReview {
+	extra := make(map[string]authorizationv1.ExtraValue)
+	for k, v := range userInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	return &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               userInfo.Username,
+			UID:                userInfo.UID,
+			Groups:             userInfo.Groups,
+			Extra:              extra,
+			ResourceAttributes: &attributes,
+		},
+	}
+}
+
+// OrSubjectAccessReview returns the authorizer if it is set, otherwise the SubjectAccessReview authorizer
+// with the kube client, which is the default of the webhook.
+func OrSubjectAccessReview(authorizer Authorizer, kubeClient kubernetes.Interface) Authorizer {
+	if authorizer != nil {
+		return authorizer
+	}
+	return NewSubjectAccessReviewAuthorizer(kubeClient)
+}
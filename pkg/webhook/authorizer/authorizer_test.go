@@ -0,0 +1,206 @@
+package authorizer
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var acceptAttributes = authorizationv1.ResourceAttributes{
+	Group:       "register.open-cluster-management.io",
+	Resource:    "managedclusters",
+	Verb:        "update",
+	Subresource: "accept",
+	Name:        "cluster1",
+}
+
+func TestSubjectAccessReviewAuthorizer(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "subjectaccessreviews",
+		func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+			sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+			return true, &authorizationv1.SubjectAccessReview{
+				Status: authorizationv1.SubjectAccessReviewStatus{
+					Allowed: sar.Spec.User == "admin" && sar.Spec.ResourceAttributes.Subresource == "accept",
+				},
+			}, nil
+		})
+
+	allowed, err := NewSubjectAccessReviewAuthorizer(kubeClient).Authorize(context.TODO(),
+		authenticationv1.UserInfo{Username: "admin"}, acceptAttributes)
+	testinghelpers.AssertError(t, err, "")
+	if !allowed {
+		t.Errorf("expected the request is allowed")
+	}
+}
+
+func TestStaticPolicyAuthorizer(t *testing.T) {
+	policy := `
+rules:
+- users: ["admin"]
+  verbs: ["update"]
+  apiGroups: ["register.open-cluster-management.io"]
+  resources: ["managedclusters/accept"]
+- groups: ["clusterset-admins"]
+  verbs: ["create"]
+  apiGroups: ["cluster.open-cluster-management.io"]
+  resources: ["managedclustersets/*"]
+  resourceNames: ["dev"]
+`
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(policyFile, []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewStaticPolicyAuthorizer(policyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	joinAttributes := func(clusterSet string) authorizationv1.ResourceAttributes {
+		return authorizationv1.ResourceAttributes{
+			Group:       "cluster.open-cluster-management.io",
+			Resource:    "managedclustersets",
+			Subresource: "join",
+			Verb:        "create",
+			Name:        clusterSet,
+		}
+	}
+
+	cases := []struct {
+		name            string
+		userInfo        authenticationv1.UserInfo
+		attributes      authorizationv1.ResourceAttributes
+		expectedAllowed bool
+	}{
+		{
+			name:            "user allowed to accept",
+			userInfo:        authenticationv1.UserInfo{Username: "admin"},
+			attributes:      acceptAttributes,
+			expectedAllowed: true,
+		},
+		{
+			name:       "user not allowed to accept",
+			userInfo:   authenticationv1.UserInfo{Username: "dev", Groups: []string{"clusterset-admins"}},
+			attributes: acceptAttributes,
+		},
+		{
+			name:            "group allowed to join the clusterset",
+			userInfo:        authenticationv1.UserInfo{Username: "dev", Groups: []string{"system:authenticated", "clusterset-admins"}},
+			attributes:      joinAttributes("dev"),
+			expectedAllowed: true,
+		},
+		{
+			name:       "group not allowed to join another clusterset",
+			userInfo:   authenticationv1.UserInfo{Username: "dev", Groups: []string{"clusterset-admins"}},
+			attributes: joinAttributes("prod"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			allowed, err := a.Authorize(context.TODO(), c.userInfo, c.attributes)
+			testinghelpers.AssertError(t, err, "")
+			if allowed != c.expectedAllowed {
+				t.Errorf("expected allowed %v, but got %v", c.expectedAllowed, allowed)
+			}
+		})
+	}
+}
+
+func TestInvalidStaticPolicy(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(policyFile, []byte("rules:\n- user: admin\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStaticPolicyAuthorizer(policyFile); err == nil {
+		t.Errorf("expected an error for the unknown field of the policy")
+	}
+}
+
+func TestWebhookAuthorizer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/authorize" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		sar := &authorizationv1.SubjectAccessReview{}
+		if err := json.NewDecoder(req.Body).Decode(sar); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if sar.Kind != "SubjectAccessReview" || sar.Spec.ResourceAttributes == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sar.Status.Allowed = sar.Spec.User == "admin"
+		if err := json.NewEncoder(w).Encode(sar); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caData, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name            string
+		url             string
+		username        string
+		expectedAllowed bool
+		expectedErr     bool
+	}{
+		{
+			name:            "allowed",
+			url:             server.URL + "/authorize",
+			username:        "admin",
+			expectedAllowed: true,
+		},
+		{
+			name:     "denied",
+			url:      server.URL + "/authorize",
+			username: "dev",
+		},
+		{
+			name:        "authorizer error",
+			url:         server.URL + "/notfound",
+			username:    "admin",
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := NewWebhookAuthorizer(c.url, caFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed, err := a.Authorize(context.TODO(), authenticationv1.UserInfo{Username: c.username}, acceptAttributes)
+			if c.expectedErr != (err != nil) {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if allowed != c.expectedAllowed {
+				t.Errorf("expected allowed %v, but got %v", c.expectedAllowed, allowed)
+			}
+		})
+	}
+
+	if _, err := NewWebhookAuthorizer("http://authorizer", ""); err == nil {
+		t.Errorf("expected an error for the non-https url")
+	}
+}
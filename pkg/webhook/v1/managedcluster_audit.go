@@ -0,0 +1,61 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+)
+
+const (
+	// auditAcceptedBy is the audit annotation of the user who accepts the managed cluster.
+	auditAcceptedBy = "accepted-by"
+	// auditTaintsStamped is the audit annotation of the keys of the taints whose timeAdded is set by the webhook.
+	auditTaintsStamped = "taints-stamped"
+	// auditDefaultClusterSet is the audit annotation of the default clusterset label set by the webhook.
+	auditDefaultClusterSet = "default-clusterset"
+	// auditAcceptAuthorized is the audit annotation of the permission which allows the user to accept the
+	// managed cluster.
+	auditAcceptAuthorized = "accept-authorized"
+	// auditClusterSetMoved is the audit annotation of the clustersets the managed cluster is moved from and to.
+	auditClusterSetMoved = "clusterset-moved"
+	// auditClusterSetJoinAuthorized is the audit annotation of the permission which allows the user to move the
+	// managed cluster between the clustersets.
+	auditClusterSetJoinAuthorized = "clusterset-join-authorized"
+	// auditDeletionConfirmed is the audit annotation of an available managed cluster deleted with the
+	// deletion confirmation.
+	auditDeletionConfirmed = "deletion-confirmed"
+)
+
+// auditClusterSetMove adds the audit annotations of the managed cluster moved between the clustersets, once the
+// user is authorized to join the clustersets by the authorizer of the mode. Only the non-empty clustersets are
+// checked, so an empty clusterset is not recorded as authorized.
+func auditClusterSetMove(ctx context.Context, mode, originalClusterSet, newClusterSet string) {
+	if originalClusterSet == newClusterSet {
+		return
+	}
+	clusterSets := []string{}
+	for _, clusterSet := range []string{originalClusterSet, newClusterSet} {
+		if len(clusterSet) != 0 {
+			clusterSets = append(clusterSets, clusterSet)
+		}
+	}
+	addAuditAnnotation(ctx, auditClusterSetMoved, fmt.Sprintf("%q -> %q", originalClusterSet, newClusterSet))
+	addAuditAnnotation(ctx, auditClusterSetJoinAuthorized,
+		fmt.Sprintf("create managedclustersets/join of %s by %s", strings.Join(clusterSets, ","), mode))
+}
+
+// stampedTaints returns the keys of the taints whose timeAdded is set by the webhook, they are the taints which
+// are added or whose value or effect is changed.
+func stampedTaints(oldManagedCluster, managedCluster *clusterv1.ManagedCluster) []string {
+	keys := []string{}
+	for _, taint := range managedCluster.Spec.Taints {
+		originalTaint := helpers.FindTaintByKey(oldManagedCluster, taint.Key)
+		if originalTaint == nil || originalTaint.Value != taint.Value || originalTaint.Effect != taint.Effect {
+			keys = append(keys, taint.Key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,191 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	"open-cluster-management.io/registration/pkg/webhook/authorizer"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestAuditAnnotations(t *testing.T) {
+	newCluster := func(clusterSet string, hubAcceptsClient bool, taints ...v1.Taint) *v1.ManagedCluster {
+		return &v1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "cluster1",
+				Labels: map[string]string{clusterv1beta2.ClusterSetLabel: clusterSet},
+			},
+			Spec: v1.ManagedClusterSpec{
+				HubAcceptsClient: hubAcceptsClient,
+				Taints:           taints,
+			},
+		}
+	}
+	taint := v1.Taint{Key: "t1", Effect: v1.TaintEffectNoSelect}
+
+	cases := []struct {
+		name                string
+		mutating            bool
+		operation           admissionv1.Operation
+		cluster             *v1.ManagedCluster
+		oldCluster          *v1.ManagedCluster
+		allowedClusterSets  map[string]bool
+		policy              string
+		expectedAllowed     bool
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:            "stamp taints and accept",
+			mutating:        true,
+			operation:       admissionv1.Update,
+			cluster:         newCluster("default", true, taint),
+			oldCluster:      newCluster("default", false),
+			expectedAllowed: true,
+			expectedAnnotations: map[string]string{
+				auditTaintsStamped: "t1",
+				auditAcceptedBy:    "admin",
+			},
+		},
+		{
+			name:            "keep taints",
+			mutating:        true,
+			operation:       admissionv1.Update,
+			cluster:         newCluster("default", false, taint),
+			oldCluster:      newCluster("default", false, taint),
+			expectedAllowed: true,
+		},
+		{
+			name:               "move clusterset",
+			operation:          admissionv1.Update,
+			cluster:            newCluster("set2", false),
+			oldCluster:         newCluster("set1", false),
+			allowedClusterSets: map[string]bool{"set1": true, "set2": true},
+			expectedAllowed:    true,
+			expectedAnnotations: map[string]string{
+				auditClusterSetMoved:          "\"set1\" -> \"set2\"",
+				auditClusterSetJoinAuthorized: "create managedclustersets/join of set1,set2 by SubjectAccessReview",
+			},
+		},
+		{
+			name:       "move clusterset with static policy",
+			operation:  admissionv1.Update,
+			cluster:    newCluster("set2", false),
+			oldCluster: newCluster("set1", false),
+			policy: `
+rules:
+- users: ["admin"]
+  verbs: ["create"]
+  apiGroups: ["cluster.open-cluster-management.io"]
+  resources: ["managedclustersets/join"]
+`,
+			expectedAllowed: true,
+			expectedAnnotations: map[string]string{
+				auditClusterSetMoved:          "\"set1\" -> \"set2\"",
+				auditClusterSetJoinAuthorized: "create managedclustersets/join of set1,set2 by StaticPolicy",
+			},
+		},
+		{
+			name:               "remove clusterset",
+			operation:          admissionv1.Update,
+			cluster:            newCluster("", false),
+			oldCluster:         newCluster("set1", false),
+			allowedClusterSets: map[string]bool{"set1": true},
+			expectedAllowed:    true,
+			expectedAnnotations: map[string]string{
+				auditClusterSetMoved:          "\"set1\" -> \"\"",
+				auditClusterSetJoinAuthorized: "create managedclustersets/join of set1 by SubjectAccessReview",
+			},
+		},
+		{
+			name:               "not allowed to move clusterset",
+			operation:          admissionv1.Update,
+			cluster:            newCluster("set2", false),
+			oldCluster:         newCluster("set1", false),
+			allowedClusterSets: map[string]bool{"set1": true},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				return true, &authorizationv1.SubjectAccessReview{
+					Status: authorizationv1.SubjectAccessReviewStatus{
+						Allowed: c.allowedClusterSets[sar.Spec.ResourceAttributes.Name],
+					},
+				}, nil
+			})
+			r := &ManagedClusterWebhook{kubeClient: kubeClient}
+			if len(c.policy) != 0 {
+				policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+				if err := os.WriteFile(policyFile, []byte(c.policy), 0600); err != nil {
+					t.Fatal(err)
+				}
+				a, err := authorizer.NewStaticPolicyAuthorizer(policyFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.Authorizer = a
+			}
+
+			var wh *admission.Webhook
+			if c.mutating {
+				wh = withResponseExtras(admission.WithCustomDefaulter(&v1.ManagedCluster{}, r))
+			} else {
+				wh = withResponseExtras(admission.WithCustomValidator(&v1.ManagedCluster{}, r))
+			}
+			scheme := runtime.NewScheme()
+			if err := v1.Install(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := wh.InjectScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+
+			req := admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: c.operation,
+					Name:      c.cluster.Name,
+					Object:    runtime.RawExtension{Raw: toJSON(t, c.cluster)},
+				},
+			}
+			req.UserInfo.Username = "admin"
+			if c.oldCluster != nil {
+				req.OldObject = runtime.RawExtension{Raw: toJSON(t, c.oldCluster)}
+			}
+
+			resp := wh.Handle(context.Background(), req)
+			if resp.Allowed != c.expectedAllowed {
+				t.Errorf("expected allowed %v, but got %v", c.expectedAllowed, resp.Result)
+			}
+			if len(resp.AuditAnnotations) != len(c.expectedAnnotations) {
+				t.Errorf("expected audit annotations %v, but got %v", c.expectedAnnotations, resp.AuditAnnotations)
+			}
+			for key, value := range c.expectedAnnotations {
+				if resp.AuditAnnotations[key] != value {
+					t.Errorf("expected audit annotation %s=%q, but got %v", key, value, resp.AuditAnnotations)
+				}
+			}
+		})
+	}
+}
+
+func toJSON(t *testing.T, obj runtime.Object) []byte {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -50,16 +50,62 @@ func (r *ManagedClusterWebhook) Default(ctx context.Context, obj runtime.Object)
 	if err != nil {
 		return err
 	}
+	if keys := stampedTaints(oldManagedCluster, managedCluster); len(keys) != 0 {
+		addAuditAnnotation(ctx, auditTaintsStamped, strings.Join(keys, ","))
+	}
+
+	//Record the acceptance of the cluster
+	processAcceptance(managedCluster, oldManagedCluster, req.UserInfo.Username)
+	if managedCluster.Spec.HubAcceptsClient && (oldManagedCluster == nil || !oldManagedCluster.Spec.HubAcceptsClient) {
+		addAuditAnnotation(ctx, auditAcceptedBy, req.UserInfo.Username)
+	}
 
 	//Set default clusterset label
 	if features.DefaultHubMutableFeatureGate.Enabled(ocmfeature.DefaultClusterSet) {
+		clusterSetName := managedCluster.Labels[clusterv1beta2.ClusterSetLabel]
 		r.addDefaultClusterSetLabel(managedCluster)
+		if managedCluster.Labels[clusterv1beta2.ClusterSetLabel] != clusterSetName {
+			addAuditAnnotation(ctx, auditDefaultClusterSet, managedCluster.Labels[clusterv1beta2.ClusterSetLabel])
+		}
 	}
 
 	return nil
 }
 
-// processTaints set cluster taints
+// processAcceptance records the user who sets hubAcceptsClient to true and the time with annotations for
+// auditability, the annotations are removed once hubAcceptsClient is set to false. The annotations set by
+// the request are ignored, they are kept the same as the old managed cluster otherwise.
+func processAcceptance(managedCluster, oldManagedCluster *clusterv1.ManagedCluster, username string) {
+	var acceptedBy, acceptedTime string
+	switch {
+	case !managedCluster.Spec.HubAcceptsClient:
+	case oldManagedCluster == nil || !oldManagedCluster.Spec.HubAcceptsClient:
+		acceptedBy = username
+		acceptedTime = nowFunc().UTC().Format(time.RFC3339)
+	default:
+		acceptedBy = oldManagedCluster.Annotations[helpers.ManagedClusterAcceptedByAnnotation]
+		acceptedTime = oldManagedCluster.Annotations[helpers.ManagedClusterAcceptedTimeAnnotation]
+	}
+
+	setAnnotation(managedCluster, helpers.ManagedClusterAcceptedByAnnotation, acceptedBy)
+	setAnnotation(managedCluster, helpers.ManagedClusterAcceptedTimeAnnotation, acceptedTime)
+}
+
+// setAnnotation sets the annotation of the managed cluster, or removes it if the value is empty.
+func setAnnotation(managedCluster *clusterv1.ManagedCluster, key, value string) {
+	if len(value) == 0 {
+		delete(managedCluster.Annotations, key)
+		return
+	}
+	if managedCluster.Annotations == nil {
+		managedCluster.Annotations = map[string]string{}
+	}
+	managedCluster.Annotations[key] = value
+}
+
+// processTaints set cluster taints. The clients, e.g. the server-side apply clients, may resend the whole
+// taint list with the timeAdded echoed back from the live object or omitted, so an unset timeAdded or the
+// timeAdded of the original taint is tolerated for an existing taint.
 func (r *ManagedClusterWebhook) processTaints(managedCluster, oldManagedCluster *clusterv1.ManagedCluster) error {
 	if len(managedCluster.Spec.Taints) == 0 {
 		return nil
@@ -77,8 +123,9 @@ func (r *ManagedClusterWebhook) processTaints(managedCluster, oldManagedCluster
 		case originalTaint == nil:
 			// handle UPDATE operation.
 			// new taint
-			// The request will be denied if it has any taint with timeAdded specified.
-			if !taint.TimeAdded.IsZero() {
+			// The request will be denied if it has any taint with timeAdded specified, except the
+			// maintenance taint added by the hub, whose timeAdded is reset.
+			if !taint.TimeAdded.IsZero() && taint.Key != helpers.ManagedClusterTaintMaintenance {
 				invalidTaints = append(invalidTaints, taint.Key)
 				continue
 			}
@@ -86,15 +133,21 @@ func (r *ManagedClusterWebhook) processTaints(managedCluster, oldManagedCluster
 		case originalTaint.Value == taint.Value && originalTaint.Effect == taint.Effect:
 			// handle UPDATE operation.
 			// no change
-			// The request will be denied if it has any taint with different timeAdded specified.
+			// The timeAdded is kept if it is not specified, and the request will be denied if it has
+			// any taint with different timeAdded specified.
+			if taint.TimeAdded.IsZero() {
+				managedCluster.Spec.Taints[index].TimeAdded = originalTaint.TimeAdded
+				continue
+			}
 			if !originalTaint.TimeAdded.Equal(&taint.TimeAdded) {
 				invalidTaints = append(invalidTaints, taint.Key)
 			}
 		default:
 			// handle UPDATE operation.
 			// taint's value/effect has changed
-			// The request will be denied if it has any taint with timeAdded specified.
-			if !taint.TimeAdded.IsZero() {
+			// The request will be denied if it has any taint with timeAdded specified, except the
+			// timeAdded echoed back from the original taint.
+			if !taint.TimeAdded.IsZero() && !originalTaint.TimeAdded.Equal(&taint.TimeAdded) {
 				invalidTaints = append(invalidTaints, taint.Key)
 				continue
 			}
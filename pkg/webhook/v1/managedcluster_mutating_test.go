@@ -13,6 +13,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/runtime"
 	ocmfeature "open-cluster-management.io/api/feature"
 	"open-cluster-management.io/registration/pkg/features"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -365,6 +367,44 @@ func TestDefault(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:          "new maintenance taint with timeAdded specified",
+			expectedError: false,
+			oldCluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "set-1",
+					Labels: map[string]string{
+						clusterv1beta2.ClusterSetLabel: defaultClusterSetName,
+					},
+				},
+			},
+			cluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "set-1",
+					Labels: map[string]string{
+						clusterv1beta2.ClusterSetLabel: defaultClusterSetName,
+					},
+				},
+				Spec: clusterv1.ManagedClusterSpec{
+					Taints: []clusterv1.Taint{
+						helpers.NewMaintenanceTaint(newTime(now, -10*time.Second)),
+					},
+				},
+			},
+			expectCluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "set-1",
+					Labels: map[string]string{
+						clusterv1beta2.ClusterSetLabel: defaultClusterSetName,
+					},
+				},
+				Spec: clusterv1.ManagedClusterSpec{
+					Taints: []clusterv1.Taint{
+						helpers.NewMaintenanceTaint(newTime(now, 0)),
+					},
+				},
+			},
+		},
 		{
 			name:          "Has clusterset label",
 			expectedError: false,
@@ -502,6 +542,163 @@ func TestDefault(t *testing.T) {
 	}
 }
 
+func TestProcessAcceptance(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+	defer func() { nowFunc = time.Now }()
+
+	newCluster := func(accepted bool, annotations map[string]string) *clusterv1.ManagedCluster {
+		return &clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Annotations: annotations},
+			Spec:       clusterv1.ManagedClusterSpec{HubAcceptsClient: accepted},
+		}
+	}
+	acceptedAnnotations := func(user, acceptedTime string) map[string]string {
+		return map[string]string{
+			helpers.ManagedClusterAcceptedByAnnotation:   user,
+			helpers.ManagedClusterAcceptedTimeAnnotation: acceptedTime,
+		}
+	}
+
+	cases := []struct {
+		name                string
+		cluster             *clusterv1.ManagedCluster
+		oldCluster          *clusterv1.ManagedCluster
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:    "create a cluster which is not accepted",
+			cluster: newCluster(false, nil),
+		},
+		{
+			name:                "create an accepted cluster",
+			cluster:             newCluster(true, nil),
+			expectedAnnotations: acceptedAnnotations("admin", "2023-01-01T00:00:00Z"),
+		},
+		{
+			name:                "accept a cluster",
+			cluster:             newCluster(true, map[string]string{"k": "v"}),
+			oldCluster:          newCluster(false, map[string]string{"k": "v"}),
+			expectedAnnotations: map[string]string{"k": "v", helpers.ManagedClusterAcceptedByAnnotation: "admin", helpers.ManagedClusterAcceptedTimeAnnotation: "2023-01-01T00:00:00Z"},
+		},
+		{
+			name:                "update an accepted cluster",
+			cluster:             newCluster(true, nil),
+			oldCluster:          newCluster(true, acceptedAnnotations("user1", "2022-01-01T00:00:00Z")),
+			expectedAnnotations: acceptedAnnotations("user1", "2022-01-01T00:00:00Z"),
+		},
+		{
+			name:                "change the acceptance annotations",
+			cluster:             newCluster(true, acceptedAnnotations("user2", "2022-06-01T00:00:00Z")),
+			oldCluster:          newCluster(true, acceptedAnnotations("user1", "2022-01-01T00:00:00Z")),
+			expectedAnnotations: acceptedAnnotations("user1", "2022-01-01T00:00:00Z"),
+		},
+		{
+			name:                "deny a cluster",
+			cluster:             newCluster(false, acceptedAnnotations("user1", "2022-01-01T00:00:00Z")),
+			oldCluster:          newCluster(true, acceptedAnnotations("user1", "2022-01-01T00:00:00Z")),
+			expectedAnnotations: map[string]string{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			processAcceptance(c.cluster, c.oldCluster, "admin")
+			if len(c.cluster.Annotations) == 0 && len(c.expectedAnnotations) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(c.cluster.Annotations, c.expectedAnnotations) {
+				t.Errorf("expected annotations %v, but got %v", c.expectedAnnotations, c.cluster.Annotations)
+			}
+		})
+	}
+}
+
+func TestProcessTaints(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+	defer func() { nowFunc = time.Now }()
+
+	added := metav1.NewTime(now.Add(-time.Hour))
+	newTaint := func(key, value string, timeAdded metav1.Time) clusterv1.Taint {
+		return clusterv1.Taint{Key: key, Value: value, Effect: clusterv1.TaintEffectNoSelect, TimeAdded: timeAdded}
+	}
+	newCluster := func(taints ...clusterv1.Taint) *clusterv1.ManagedCluster {
+		return &clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1"},
+			Spec:       clusterv1.ManagedClusterSpec{Taints: taints},
+		}
+	}
+
+	cases := []struct {
+		name           string
+		cluster        *clusterv1.ManagedCluster
+		oldCluster     *clusterv1.ManagedCluster
+		expectedTaints []clusterv1.Taint
+		expectedErr    string
+	}{
+		{
+			name:           "create with the timeAdded echoed back",
+			cluster:        newCluster(newTaint("a", "b", added)),
+			expectedTaints: []clusterv1.Taint{newTaint("a", "b", metav1.NewTime(now))},
+		},
+		{
+			name:           "resend unchanged taints with the timeAdded echoed back",
+			cluster:        newCluster(newTaint("a", "b", added), newTaint("c", "", added)),
+			oldCluster:     newCluster(newTaint("a", "b", added), newTaint("c", "", added)),
+			expectedTaints: []clusterv1.Taint{newTaint("a", "b", added), newTaint("c", "", added)},
+		},
+		{
+			name:           "resend unchanged taints without the timeAdded",
+			cluster:        newCluster(newTaint("a", "b", metav1.Time{}), newTaint("c", "", metav1.Time{})),
+			oldCluster:     newCluster(newTaint("a", "b", added), newTaint("c", "", added)),
+			expectedTaints: []clusterv1.Taint{newTaint("a", "b", added), newTaint("c", "", added)},
+		},
+		{
+			name:           "change a taint with the timeAdded echoed back",
+			cluster:        newCluster(newTaint("a", "b", added), newTaint("c", "d", added)),
+			oldCluster:     newCluster(newTaint("a", "b", added), newTaint("c", "", added)),
+			expectedTaints: []clusterv1.Taint{newTaint("a", "b", added), newTaint("c", "d", metav1.NewTime(now))},
+		},
+		{
+			name:           "add a taint with the whole list resent",
+			cluster:        newCluster(newTaint("a", "b", added), newTaint("c", "", metav1.Time{})),
+			oldCluster:     newCluster(newTaint("a", "b", added)),
+			expectedTaints: []clusterv1.Taint{newTaint("a", "b", added), newTaint("c", "", metav1.NewTime(now))},
+		},
+		{
+			name:        "change the timeAdded of an unchanged taint",
+			cluster:     newCluster(newTaint("a", "b", metav1.NewTime(now))),
+			oldCluster:  newCluster(newTaint("a", "b", added)),
+			expectedErr: "It is not allowed to set TimeAdded of Taint \"a\".",
+		},
+		{
+			name:        "change a taint with a different timeAdded",
+			cluster:     newCluster(newTaint("a", "c", metav1.NewTime(now))),
+			oldCluster:  newCluster(newTaint("a", "b", added)),
+			expectedErr: "It is not allowed to set TimeAdded of Taint \"a\".",
+		},
+		{
+			name:        "add a taint with the timeAdded",
+			cluster:     newCluster(newTaint("a", "b", added), newTaint("c", "", added)),
+			oldCluster:  newCluster(newTaint("a", "b", added)),
+			expectedErr: "It is not allowed to set TimeAdded of Taint \"c\".",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := ManagedClusterWebhook{}
+			err := w.processTaints(c.cluster, c.oldCluster)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(c.cluster.Spec.Taints, c.expectedTaints) {
+				t.Errorf("expected taints %v, but got %v", c.expectedTaints, c.cluster.Spec.Taints)
+			}
+		})
+	}
+}
+
 func DiffTaintTime(src, dest []clusterv1.Taint) bool {
 	if len(src) != len(dest) {
 		return false
@@ -0,0 +1,176 @@
+package v1
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"open-cluster-management.io/registration/pkg/hub/user"
+)
+
+// StatusUpdateMinIntervalAnnotation is the annotation of a ManagedCluster which overrides the min interval
+// between the status updates of its agent, e.g. "30s". Zero disables the limit for the managed cluster.
+const StatusUpdateMinIntervalAnnotation = "cluster.open-cluster-management.io/status-update-min-interval"
+
+// statusUpdateLimiterSweepInterval is the interval to remove the full buckets from the limiter.
+const statusUpdateLimiterSweepInterval = time.Minute
+
+// statusUpdateLimiter keeps a token bucket of the status updates of the managed clusters per identity, so
+// that an agent which updates the status too frequently is throttled before it overwhelms the hub. A bucket
+// holds up to burst tokens and gets one more token each interval, each persisted status update takes one.
+//
+// The limiter only sees the admission requests, so an allowed status update which is not persisted at last,
+// e.g. it is rejected by another admission webhook or fails on the storage, cannot be observed directly. It
+// is detected by the next status update of the identity instead: the old object of that update is still the
+// one the failed update was based on, so the token of the failed update is given to the retry.
+//
+// The state is kept in memory of each webhook replica, so the limit applies per replica.
+type statusUpdateLimiter struct {
+	lock      sync.Mutex
+	buckets   map[string]*statusUpdateBucket
+	lastSweep time.Time
+	// now is the local clock, it is replaced in the unit tests
+	now func() time.Time
+}
+
+type statusUpdateBucket struct {
+	tokens   float64
+	time     time.Time
+	interval time.Duration
+	burst    int
+	// resourceVersion is the resource version of the managed cluster which the last allowed status
+	// update was based on.
+	resourceVersion string
+}
+
+// refill adds the tokens generated since the last refill to the bucket.
+func (b *statusUpdateBucket) refill(now time.Time, interval time.Duration, burst int) {
+	b.tokens = math.Min(float64(burst), b.tokens+float64(now.Sub(b.time))/float64(interval))
+	b.time = now
+	b.interval = interval
+	b.burst = burst
+}
+
+func newStatusUpdateLimiter() *statusUpdateLimiter {
+	return &statusUpdateLimiter{
+		buckets: map[string]*statusUpdateBucket{},
+		now:     time.Now,
+	}
+}
+
+// allow returns true if the status update of the key, which is based on the managed cluster with the
+// resourceVersion, is allowed, otherwise it returns false with the time to wait for the next token.
+func (l *statusUpdateLimiter) allow(key, resourceVersion string, interval time.Duration, burst int) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > statusUpdateLimiterSweepInterval {
+		for k, bucket := range l.buckets {
+			if bucket.refill(now, bucket.interval, bucket.burst); bucket.tokens >= float64(bucket.burst) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &statusUpdateBucket{tokens: float64(burst), time: now}
+		l.buckets[key] = bucket
+	}
+	bucket.refill(now, interval, burst)
+
+	// the last allowed status update was not persisted, otherwise the resource version would be changed,
+	// it is retried with the token taken by the last one
+	if len(resourceVersion) != 0 && bucket.resourceVersion == resourceVersion {
+		return true, 0
+	}
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) * float64(interval))
+	}
+	bucket.tokens--
+	bucket.resourceVersion = resourceVersion
+	return true, 0
+}
+
+// limitStatusUpdate denies the status update of a managed cluster with TooManyRequests if its agent
+// updated the status more than the burst within the min interval. Only the status updates of the agents,
+// which are in the managed clusters group, are limited, and each identity is limited separately.
+func (r *ManagedClusterWebhook) limitStatusUpdate(req admission.Request, oldCluster, cluster *v1.ManagedCluster) error {
+	if r.statusUpdateLimiter == nil || req.SubResource != "status" || !isAgent(req.UserInfo) {
+		return nil
+	}
+
+	interval, err := statusUpdateMinInterval(cluster, r.StatusUpdateMinInterval)
+	if err != nil || interval <= 0 {
+		return nil
+	}
+
+	allowed, wait := r.statusUpdateLimiter.allow(
+		statusUpdateKey(req.UserInfo, cluster.Name), oldCluster.ResourceVersion, interval, r.StatusUpdateBurst)
+	if allowed {
+		return nil
+	}
+	return apierrors.NewTooManyRequests(
+		fmt.Sprintf("the status of managed cluster %q is updated by %q too frequently, the min interval is %s",
+			cluster.Name, req.UserInfo.Username, interval),
+		int(math.Ceil(wait.Seconds())))
+}
+
+// allowSetStatusUpdateMinInterval forbids the agents to set or change the StatusUpdateMinIntervalAnnotation,
+// otherwise an agent could lift the limit of itself. The oldCluster is nil if the managed cluster is created.
+func allowSetStatusUpdateMinInterval(userInfo authenticationv1.UserInfo, oldCluster, cluster *v1.ManagedCluster) error {
+	if !isAgent(userInfo) {
+		return nil
+	}
+	value, ok := cluster.Annotations[StatusUpdateMinIntervalAnnotation]
+	var oldValue string
+	var oldOk bool
+	if oldCluster != nil {
+		oldValue, oldOk = oldCluster.Annotations[StatusUpdateMinIntervalAnnotation]
+	}
+	if ok == oldOk && value == oldValue {
+		return nil
+	}
+	return apierrors.NewForbidden(v1.Resource("managedclusters"), cluster.Name,
+		fmt.Errorf("user %q cannot set the annotation %q", userInfo.Username, StatusUpdateMinIntervalAnnotation))
+}
+
+// statusUpdateMinInterval returns the min interval of the status updates of the managed cluster, which is
+// the value of the StatusUpdateMinIntervalAnnotation if it is set, otherwise the default one.
+func statusUpdateMinInterval(cluster *v1.ManagedCluster, defaultInterval time.Duration) (time.Duration, error) {
+	value, ok := cluster.Annotations[StatusUpdateMinIntervalAnnotation]
+	if !ok {
+		return defaultInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if interval < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return interval, nil
+}
+
+func statusUpdateKey(userInfo authenticationv1.UserInfo, clusterName string) string {
+	return userInfo.Username + "/" + clusterName
+}
+
+// isAgent returns true if the user is the agent of a managed cluster.
+func isAgent(userInfo authenticationv1.UserInfo) bool {
+	return sets.New[string](userInfo.Groups...).Has(user.ManagedClustersGroup)
+}
@@ -0,0 +1,285 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"open-cluster-management.io/registration/pkg/hub/user"
+)
+
+func TestLimitStatusUpdate(t *testing.T) {
+	now := time.Now()
+	agent := authenticationv1.UserInfo{
+		Username: "system:open-cluster-management:cluster1:agent",
+		Groups:   []string{user.ManagedClustersGroup},
+	}
+	newCluster := func(annotations map[string]string) *v1.ManagedCluster {
+		return &v1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1", ResourceVersion: "2", Annotations: annotations}}
+	}
+	newBucket := func(tokens float64, resourceVersion string) *statusUpdateBucket {
+		return &statusUpdateBucket{tokens: tokens, time: now, interval: time.Minute, burst: 1, resourceVersion: resourceVersion}
+	}
+
+	cases := []struct {
+		name           string
+		subResource    string
+		userInfo       authenticationv1.UserInfo
+		cluster        *v1.ManagedCluster
+		interval       time.Duration
+		burst          int
+		buckets        map[string]*statusUpdateBucket
+		expectedDenied bool
+	}{
+		{
+			name:        "first status update",
+			subResource: "status",
+			userInfo:    agent,
+			cluster:     newCluster(nil),
+			interval:    time.Minute,
+		},
+		{
+			name:        "status update after the interval",
+			subResource: "status",
+			userInfo:    agent,
+			cluster:     newCluster(nil),
+			interval:    time.Minute,
+			buckets: map[string]*statusUpdateBucket{
+				statusUpdateKey(agent, "cluster1"): {time: now.Add(-2 * time.Minute), interval: time.Minute, burst: 1, resourceVersion: "1"},
+			},
+		},
+		{
+			name:        "status update within the interval",
+			subResource: "status",
+			userInfo:    agent,
+			cluster:     newCluster(nil),
+			interval:    time.Minute,
+			buckets: map[string]*statusUpdateBucket{
+				statusUpdateKey(agent, "cluster1"): newBucket(0.5, "1"),
+			},
+			expectedDenied: true,
+		},
+		{
+			name:        "status update within the burst",
+			subResource: "status",
+			userInfo:    agent,
+			cluster:     newCluster(nil),
+			interval:    time.Minute,
+			burst:       3,
+			buckets: map[string]*statusUpdateBucket{
+				statusUpdateKey(agent, "cluster1"): newBucket(1, "1"),
+			},
+		},
+		{
+			name:        "retry of a status update which is not persisted",
+			subResource: "status",
+			userInfo:    agent,
+			cluster:     newCluster(nil),
+			interval:    time.Minute,
+			buckets: map[string]*statusUpdateBucket{
+				statusUpdateKey(agent, "cluster1"): newBucket(0, "2"),
+			},
+		},
+		{
+			name:        "status update of another identity",
+			subResource: "status",
+			userInfo:    agent,
+			cluster:     newCluster(nil),
+			interval:    time.Minute,
+			buckets: map[string]*statusUpdateBucket{
+				"system:open-cluster-management:cluster1:another/cluster1": newBucket(0, "1"),
+			},
+		},
+		{
+			name:        "status update of the hub controllers",
+			subResource: "status",
+			userInfo:    authenticationv1.UserInfo{Username: "system:serviceaccount:open-cluster-management-hub:registration-controller-sa"},
+			cluster:     newCluster(nil),
+			interval:    time.Minute,
+			buckets: map[string]*statusUpdateBucket{
+				"system:serviceaccount:open-cluster-management-hub:registration-controller-sa/cluster1": newBucket(0, "1"),
+			},
+		},
+		{
+			name:     "update of the managed cluster",
+			userInfo: agent,
+			cluster:  newCluster(nil),
+			interval: time.Minute,
+			buckets: map[string]*statusUpdateBucket{
+				statusUpdateKey(agent, "cluster1"): newBucket(0, "1"),
+			},
+		},
+		{
+			name:        "interval is overridden by the annotation",
+			subResource: "status",
+			userInfo:    agent,
+			cluster:     newCluster(map[string]string{StatusUpdateMinIntervalAnnotation: "10s"}),
+			interval:    time.Minute,
+			buckets: map[string]*statusUpdateBucket{
+				statusUpdateKey(agent, "cluster1"): {time: now.Add(-30 * time.Second), interval: time.Minute, burst: 1, resourceVersion: "1"},
+			},
+		},
+		{
+			name:        "limit is disabled by the annotation",
+			subResource: "status",
+			userInfo:    agent,
+			cluster:     newCluster(map[string]string{StatusUpdateMinIntervalAnnotation: "0s"}),
+			interval:    time.Minute,
+			buckets: map[string]*statusUpdateBucket{
+				statusUpdateKey(agent, "cluster1"): newBucket(0, "1"),
+			},
+		},
+		{
+			name:        "limit is enabled by the annotation",
+			subResource: "status",
+			userInfo:    agent,
+			cluster:     newCluster(map[string]string{StatusUpdateMinIntervalAnnotation: "1m"}),
+			buckets: map[string]*statusUpdateBucket{
+				statusUpdateKey(agent, "cluster1"): newBucket(0.5, "1"),
+			},
+			expectedDenied: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			limiter := newStatusUpdateLimiter()
+			limiter.now = func() time.Time { return now }
+			limiter.lastSweep = now
+			for key, bucket := range c.buckets {
+				limiter.buckets[key] = bucket
+			}
+			w := &ManagedClusterWebhook{
+				StatusUpdateMinInterval: c.interval,
+				StatusUpdateBurst:       c.burst,
+				statusUpdateLimiter:     limiter,
+			}
+			req := admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation:   admissionv1.Update,
+					SubResource: c.subResource,
+					UserInfo:    c.userInfo,
+				},
+			}
+
+			err := w.limitStatusUpdate(req, c.cluster, c.cluster)
+			if c.expectedDenied && !apierrors.IsTooManyRequests(err) {
+				t.Errorf("expected the status update denied, but got %v", err)
+			}
+			if !c.expectedDenied && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestStatusUpdateLimiterBurst(t *testing.T) {
+	now := time.Now()
+	limiter := newStatusUpdateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	for i, resourceVersion := range []string{"1", "2", "3"} {
+		if allowed, _ := limiter.allow("agent", resourceVersion, time.Minute, 3); !allowed {
+			t.Errorf("expected the status update %d within the burst allowed", i)
+		}
+	}
+	allowed, wait := limiter.allow("agent", "4", time.Minute, 3)
+	if allowed {
+		t.Errorf("expected the status update beyond the burst denied")
+	}
+	if wait != time.Minute {
+		t.Errorf("expected to wait %v, but got %v", time.Minute, wait)
+	}
+
+	now = now.Add(time.Minute)
+	if allowed, _ := limiter.allow("agent", "4", time.Minute, 3); !allowed {
+		t.Errorf("expected the status update allowed once a token is generated")
+	}
+}
+
+func TestStatusUpdateLimiterSweep(t *testing.T) {
+	now := time.Now()
+	limiter := newStatusUpdateLimiter()
+	limiter.now = func() time.Time { return now }
+	limiter.buckets["full"] = &statusUpdateBucket{time: now.Add(-2 * time.Minute), interval: time.Minute, burst: 1}
+	limiter.buckets["active"] = &statusUpdateBucket{time: now.Add(-30 * time.Second), interval: time.Minute, burst: 1}
+
+	if allowed, _ := limiter.allow("new", "1", time.Minute, 1); !allowed {
+		t.Errorf("expected the first status update allowed")
+	}
+	if _, ok := limiter.buckets["full"]; ok {
+		t.Errorf("expected the full bucket removed")
+	}
+	if _, ok := limiter.buckets["active"]; !ok {
+		t.Errorf("expected the active bucket kept")
+	}
+}
+
+func TestAllowSetStatusUpdateMinInterval(t *testing.T) {
+	agent := authenticationv1.UserInfo{
+		Username: "system:open-cluster-management:cluster1:agent",
+		Groups:   []string{user.ManagedClustersGroup},
+	}
+	admin := authenticationv1.UserInfo{Username: "admin"}
+	newCluster := func(annotations map[string]string) *v1.ManagedCluster {
+		return &v1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Annotations: annotations}}
+	}
+
+	cases := []struct {
+		name              string
+		userInfo          authenticationv1.UserInfo
+		oldCluster        *v1.ManagedCluster
+		cluster           *v1.ManagedCluster
+		expectedForbidden bool
+	}{
+		{
+			name:     "admin sets the annotation",
+			userInfo: admin,
+			cluster:  newCluster(map[string]string{StatusUpdateMinIntervalAnnotation: "0s"}),
+		},
+		{
+			name:              "agent creates the managed cluster with the annotation",
+			userInfo:          agent,
+			cluster:           newCluster(map[string]string{StatusUpdateMinIntervalAnnotation: "0s"}),
+			expectedForbidden: true,
+		},
+		{
+			name:              "agent changes the annotation",
+			userInfo:          agent,
+			oldCluster:        newCluster(map[string]string{StatusUpdateMinIntervalAnnotation: "1m"}),
+			cluster:           newCluster(map[string]string{StatusUpdateMinIntervalAnnotation: "0s"}),
+			expectedForbidden: true,
+		},
+		{
+			name:              "agent removes the annotation",
+			userInfo:          agent,
+			oldCluster:        newCluster(map[string]string{StatusUpdateMinIntervalAnnotation: "1m"}),
+			cluster:           newCluster(nil),
+			expectedForbidden: true,
+		},
+		{
+			name:       "agent keeps the annotation",
+			userInfo:   agent,
+			oldCluster: newCluster(map[string]string{StatusUpdateMinIntervalAnnotation: "1m"}),
+			cluster:    newCluster(map[string]string{StatusUpdateMinIntervalAnnotation: "1m"}),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := allowSetStatusUpdateMinInterval(c.userInfo, c.oldCluster, c.cluster)
+			if c.expectedForbidden && !apierrors.IsForbidden(err) {
+				t.Errorf("expected forbidden, but got %v", err)
+			}
+			if !c.expectedForbidden && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
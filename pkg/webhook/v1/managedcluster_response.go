@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"context"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+type responseExtrasKey struct{}
+
+// responseExtras are the audit annotations and the warnings added while an admission request is handled, the
+// CustomDefaulter and CustomValidator of the controller-runtime return an error only, so they are set on the
+// admission response by the responseExtrasHandler.
+type responseExtras struct {
+	lock sync.Mutex
+	// auditAnnotations tell why the webhook admits or mutates a ManagedCluster in the audit logs of the
+	// kube-apiserver, which prefixes the keys with the name of the webhook.
+	auditAnnotations map[string]string
+	// warnings are returned to the clients of the kube-apiserver.
+	warnings []string
+}
+
+// addAuditAnnotation adds an audit annotation to the admission request of the context, it does nothing if the
+// context is not of an admission request, e.g. the webhook is called directly.
+func addAuditAnnotation(ctx context.Context, key, value string) {
+	extras, ok := ctx.Value(responseExtrasKey{}).(*responseExtras)
+	if !ok {
+		return
+	}
+	extras.lock.Lock()
+	defer extras.lock.Unlock()
+	extras.auditAnnotations[key] = value
+}
+
+// addWarning adds a warning to the admission request of the context, it does nothing if the context is not of
+// an admission request.
+func addWarning(ctx context.Context, warning string) {
+	extras, ok := ctx.Value(responseExtrasKey{}).(*responseExtras)
+	if !ok {
+		return
+	}
+	extras.lock.Lock()
+	defer extras.lock.Unlock()
+	extras.warnings = append(extras.warnings, warning)
+}
+
+// responseExtrasHandler sets the audit annotations and the warnings added by the handler on the admission response.
+type responseExtrasHandler struct {
+	handler admission.Handler
+}
+
+var _ admission.DecoderInjector = &responseExtrasHandler{}
+
+// withResponseExtras wraps the handler of the webhook with a responseExtrasHandler.
+func withResponseExtras(webhook *admission.Webhook) *admission.Webhook {
+	webhook.Handler = &responseExtrasHandler{handler: webhook.Handler}
+	return webhook
+}
+
+func (h *responseExtrasHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	extras := &responseExtras{auditAnnotations: map[string]string{}}
+	resp := h.handler.Handle(context.WithValue(ctx, responseExtrasKey{}, extras), req)
+
+	extras.lock.Lock()
+	defer extras.lock.Unlock()
+	resp.Warnings = append(resp.Warnings, extras.warnings...)
+	if len(extras.auditAnnotations) == 0 {
+		return resp
+	}
+	if resp.AuditAnnotations == nil {
+		resp.AuditAnnotations = map[string]string{}
+	}
+	for key, value := range extras.auditAnnotations {
+		resp.AuditAnnotations[key] = value
+	}
+	return resp
+}
+
+// InjectDecoder injects the decoder into the wrapped handler.
+func (h *responseExtrasHandler) InjectDecoder(d *admission.Decoder) error {
+	_, err := admission.InjectDecoderInto(d, h.handler)
+	return err
+}
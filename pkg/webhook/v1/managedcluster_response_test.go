@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestResponseExtrasHandler(t *testing.T) {
+	handler := &responseExtrasHandler{
+		handler: admission.HandlerFunc(func(ctx context.Context, req admission.Request) admission.Response {
+			addAuditAnnotation(ctx, "key1", "value1")
+			addWarning(ctx, "warning2")
+			resp := admission.Allowed("")
+			resp.Warnings = []string{"warning1"}
+			return resp
+		}),
+	}
+
+	resp := handler.Handle(context.Background(), admission.Request{})
+	if !resp.Allowed {
+		t.Errorf("expected allowed, but got %v", resp.Result)
+	}
+	if len(resp.AuditAnnotations) != 1 || resp.AuditAnnotations["key1"] != "value1" {
+		t.Errorf("expected audit annotation key1=value1, but got %v", resp.AuditAnnotations)
+	}
+	if fmt.Sprint(resp.Warnings) != "[warning1 warning2]" {
+		t.Errorf("expected warnings [warning1 warning2], but got %v", resp.Warnings)
+	}
+
+	// the extras are ignored if the webhook is called directly
+	addAuditAnnotation(context.Background(), "key1", "value1")
+	addWarning(context.Background(), "warning1")
+}
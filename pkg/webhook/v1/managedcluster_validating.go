@@ -2,20 +2,30 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
-	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	certutil "k8s.io/client-go/util/cert"
 	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	v1 "open-cluster-management.io/api/cluster/v1"
 	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/hub/user"
+	"open-cluster-management.io/registration/pkg/webhook/authorizer"
 
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -25,6 +35,26 @@ import (
 
 var _ webhook.CustomValidator = &ManagedClusterWebhook{}
 
+// DeletionConfirmationAnnotation confirms the deletion of an available ManagedCluster if the deletion
+// protection of the webhook is enabled.
+const DeletionConfirmationAnnotation = "cluster.open-cluster-management.io/deletion-confirmed"
+
+// MinLeaseDurationSeconds is the min lease duration of a ManagedCluster, a shorter lease duration makes
+// the agent renew its lease too frequently. Zero is allowed and means the default lease duration.
+const MinLeaseDurationSeconds = 5
+
+// clientConfigLimitWarningRatio is the ratio of the limits of the client configs of a ManagedCluster, a
+// warning is returned once the client configs reach it.
+const clientConfigLimitWarningRatio = 0.8
+
+// immutableAnnotationsOnceAccepted are the annotations set by the agent which the hub relies on to
+// map the identity of the managed cluster, they cannot be changed once the managed cluster is
+// accepted, otherwise the identity of the accepted managed cluster could be taken over.
+var immutableAnnotationsOnceAccepted = []string{
+	user.IAMRoleARNAnnotation,
+	user.TokenUsernameAnnotation,
+}
+
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *ManagedClusterWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
 	managedCluster, ok := obj.(*v1.ManagedCluster)
@@ -36,9 +66,16 @@ func (r *ManagedClusterWebhook) ValidateCreate(ctx context.Context, obj runtime.
 		return apierrors.NewBadRequest(err.Error())
 	}
 
-	//Validate if Spec.ManagedClusterClientConfigs is Valid HTTPS URL
-	err = r.validateManagedClusterObj(*managedCluster)
-	if err != nil {
+	errs := validateManagedClusterObj(managedCluster)
+	errs = append(errs, validateManagedClusterFields(nil, managedCluster)...)
+	errs = append(errs, r.validateClientConfigLimits(ctx, nil, managedCluster)...)
+	if len(errs) != 0 {
+		return apierrors.NewInvalid(schema.GroupKind{Group: v1.GroupName, Kind: "ManagedCluster"}, managedCluster.Name, errs)
+	}
+	if err := allowSetStatusUpdateMinInterval(req.UserInfo, nil, managedCluster); err != nil {
+		return err
+	}
+	if err := r.allowSetProtectedLabels(req.UserInfo, nil, managedCluster); err != nil {
 		return err
 	}
 
@@ -53,6 +90,7 @@ func (r *ManagedClusterWebhook) ValidateCreate(ctx context.Context, obj runtime.
 		if err := r.allowUpdateAcceptField(managedCluster.Name, req.UserInfo); err != nil {
 			return err
 		}
+		addAuditAnnotation(ctx, auditAcceptAuthorized, "update managedclusters/accept by "+r.authorizer().Mode())
 	}
 
 	// check whether the request user has been allowed to set clusterset label
@@ -61,7 +99,11 @@ func (r *ManagedClusterWebhook) ValidateCreate(ctx context.Context, obj runtime.
 		clusterSetName = managedCluster.Labels[clusterv1beta2.ClusterSetLabel]
 	}
 
-	return r.allowSetClusterSetLabel(req.UserInfo, "", clusterSetName)
+	if err := r.allowSetClusterSetLabel(req.UserInfo, "", clusterSetName); err != nil {
+		return err
+	}
+	auditClusterSetMove(ctx, r.authorizer().Mode(), "", clusterSetName)
+	return nil
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
@@ -79,12 +121,20 @@ func (r *ManagedClusterWebhook) ValidateUpdate(ctx context.Context, oldObj, newO
 		return apierrors.NewBadRequest(err.Error())
 	}
 
-	//Validate if Spec.ManagedClusterClientConfigs is Valid HTTPS URL
-	err = r.validateManagedClusterObj(*managedCluster)
-	if err != nil {
+	if err := allowSetStatusUpdateMinInterval(req.UserInfo, oldManagedCluster, managedCluster); err != nil {
+		return err
+	}
+	if err := r.allowSetProtectedLabels(req.UserInfo, oldManagedCluster, managedCluster); err != nil {
 		return err
 	}
 
+	errs := validateManagedClusterObj(managedCluster)
+	errs = append(errs, validateManagedClusterFields(oldManagedCluster, managedCluster)...)
+	errs = append(errs, r.validateClientConfigLimits(ctx, oldManagedCluster, managedCluster)...)
+	if len(errs) != 0 {
+		return apierrors.NewInvalid(schema.GroupKind{Group: v1.GroupName, Kind: "ManagedCluster"}, managedCluster.Name, errs)
+	}
+
 	// the HubAcceptsClient field is changed, we need to:
 	// 1. check whether cluster namespace is terminating.
 	// 2. check the request user whether has been allowed to change the HubAcceptsClient field with
@@ -97,6 +147,7 @@ func (r *ManagedClusterWebhook) ValidateUpdate(ctx context.Context, oldObj, newO
 			if err := r.allowUpdateAcceptField(managedCluster.Name, req.UserInfo); err != nil {
 				return err
 			}
+			addAuditAnnotation(ctx, auditAcceptAuthorized, "update managedclusters/accept by "+r.authorizer().Mode())
 		}
 	}
 
@@ -109,62 +160,195 @@ func (r *ManagedClusterWebhook) ValidateUpdate(ctx context.Context, oldObj, newO
 		currentClusterSetName = managedCluster.Labels[clusterv1beta2.ClusterSetLabel]
 	}
 
-	return r.allowSetClusterSetLabel(req.UserInfo, originalClusterSetName, currentClusterSetName)
-}
+	if err := r.allowSetClusterSetLabel(req.UserInfo, originalClusterSetName, currentClusterSetName); err != nil {
+		return err
+	}
 
-// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
-func (r *ManagedClusterWebhook) ValidateDelete(_ context.Context, obj runtime.Object) error {
+	// the status update is limited at last, so that an update denied by the other checks takes no token
+	if err := r.limitStatusUpdate(req, oldManagedCluster, managedCluster); err != nil {
+		return err
+	}
+	auditClusterSetMove(ctx, r.authorizer().Mode(), originalClusterSetName, currentClusterSetName)
 	return nil
 }
 
-// validateManagedClusterObj validates the fileds of ManagedCluster object
-func (r *ManagedClusterWebhook) validateManagedClusterObj(cluster v1.ManagedCluster) error {
-	errs := []error{}
-	// The cluster name must be the same format of namespace name.
-	if errMsgs := apimachineryvalidation.ValidateNamespaceName(cluster.Name, false); len(errMsgs) > 0 {
-		errs = append(errs, fmt.Errorf("metadata.name format is not correct: %s", strings.Join(errMsgs, ",")))
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ManagedClusterWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	if !r.DeletionProtection {
+		return nil
+	}
+	managedCluster, ok := obj.(*v1.ManagedCluster)
+	if !ok {
+		return apierrors.NewBadRequest("Request cluster obj format is not right")
+	}
+
+	// only the available clusters are protected, a cluster which is unavailable or not accepted yet
+	// can be deleted as usual
+	if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, v1.ManagedClusterConditionAvailable) {
+		return nil
 	}
-	// there are no spoke client configs, finish the validation process
-	if len(cluster.Spec.ManagedClusterClientConfigs) == 0 {
+	if managedCluster.Annotations[DeletionConfirmationAnnotation] == "true" {
+		addAuditAnnotation(ctx, auditDeletionConfirmed, "true")
 		return nil
 	}
 
-	// validate the url in spoke client configs
-	for _, clientConfig := range cluster.Spec.ManagedClusterClientConfigs {
+	message := fmt.Sprintf("the managed cluster is available, set the annotation %q to \"true\" to confirm the deletion",
+		DeletionConfirmationAnnotation)
+	addOns, err := r.addOnClient.AddonV1alpha1().ManagedClusterAddOns(managedCluster.Name).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return apierrors.NewForbidden(v1.Resource("managedclusters"), managedCluster.Name,
+			fmt.Errorf("%s, unable to list the addons of the managed cluster: %v", message, err))
+	}
+	runningAddOns := []string{}
+	for _, addOn := range addOns.Items {
+		if meta.IsStatusConditionTrue(addOn.Status.Conditions, addonv1alpha1.ManagedClusterAddOnConditionAvailable) {
+			runningAddOns = append(runningAddOns, addOn.Name)
+		}
+	}
+	if len(runningAddOns) != 0 {
+		sort.Strings(runningAddOns)
+		message = fmt.Sprintf("%s, the addons still running on the managed cluster: %s", message, strings.Join(runningAddOns, ", "))
+	}
+	return apierrors.NewForbidden(v1.Resource("managedclusters"), managedCluster.Name, errors.New(message))
+}
+
+// validateManagedClusterObj validates the name of the ManagedCluster has the format of a namespace name,
+// and the URLs of its client configs are valid HTTPS URLs.
+func validateManagedClusterObj(cluster *v1.ManagedCluster) field.ErrorList {
+	errs := field.ErrorList{}
+	for _, msg := range apimachineryvalidation.ValidateNamespaceName(cluster.Name, false) {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), cluster.Name, msg))
+	}
+
+	fldPath := field.NewPath("spec", "managedClusterClientConfigs")
+	for i, clientConfig := range cluster.Spec.ManagedClusterClientConfigs {
 		if !helpers.IsValidHTTPSURL(clientConfig.URL) {
-			errs = append(errs, fmt.Errorf("url %q is invalid in client configs", clientConfig.URL))
+			errs = append(errs, field.Invalid(fldPath.Index(i).Child("url"), clientConfig.URL, "must be a valid HTTPS URL"))
 		}
 	}
-	if len(errs) != 0 {
-		return apierrors.NewBadRequest(operatorhelpers.NewMultiLineAggregate(errs).Error())
+
+	if _, err := statusUpdateMinInterval(cluster, 0); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(StatusUpdateMinIntervalAnnotation),
+			cluster.Annotations[StatusUpdateMinIntervalAnnotation], fmt.Sprintf("must be a valid duration: %v", err)))
 	}
-	return nil
+	return errs
+}
+
+// validateManagedClusterFields validates the fields of a ManagedCluster which is created, the oldCluster
+// is nil, or updated. The lease duration cannot be set below the minimum, an existing lease duration
+// below the minimum is kept as it is, the changed client configs must have valid CA bundles and
+// distinct URLs, and the immutable annotations cannot be changed once the managed cluster is accepted.
+func validateManagedClusterFields(oldCluster, cluster *v1.ManagedCluster) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if oldCluster == nil || !equality.Semantic.DeepEqual(oldCluster.Spec.ManagedClusterClientConfigs, cluster.Spec.ManagedClusterClientConfigs) {
+		errs = append(errs, validateClientConfigs(cluster.Spec.ManagedClusterClientConfigs,
+			field.NewPath("spec", "managedClusterClientConfigs"))...)
+	}
+
+	leaseDurationSeconds := cluster.Spec.LeaseDurationSeconds
+	if leaseDurationSeconds != 0 && leaseDurationSeconds < MinLeaseDurationSeconds &&
+		(oldCluster == nil || oldCluster.Spec.LeaseDurationSeconds != leaseDurationSeconds) {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "leaseDurationSeconds"), leaseDurationSeconds,
+			fmt.Sprintf("must be no less than %d seconds", MinLeaseDurationSeconds)))
+	}
+
+	if oldCluster == nil || !oldCluster.Spec.HubAcceptsClient {
+		return errs
+	}
+	for _, key := range immutableAnnotationsOnceAccepted {
+		oldValue, oldOk := oldCluster.Annotations[key]
+		value, ok := cluster.Annotations[key]
+		if oldOk == ok && oldValue == value {
+			continue
+		}
+		errs = append(errs, field.Forbidden(field.NewPath("metadata", "annotations").Key(key),
+			"cannot be changed once the managed cluster is accepted, set hubAcceptsClient to false first"))
+	}
+	return errs
+}
+
+// validateClientConfigLimits validates the number of the client configs of a ManagedCluster which is created,
+// the oldCluster is nil, or whose client configs are changed, and the size of their CA bundles do not exceed
+// the limits, so that a ManagedCluster cannot bloat the storage of the hub. A warning is returned once the
+// client configs approach the limits.
+func (r *ManagedClusterWebhook) validateClientConfigLimits(ctx context.Context, oldCluster, cluster *v1.ManagedCluster) field.ErrorList {
+	clientConfigs := cluster.Spec.ManagedClusterClientConfigs
+	if oldCluster != nil && equality.Semantic.DeepEqual(oldCluster.Spec.ManagedClusterClientConfigs, clientConfigs) {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	fldPath := field.NewPath("spec", "managedClusterClientConfigs")
+	switch {
+	case r.MaxClientConfigs <= 0:
+	case len(clientConfigs) > r.MaxClientConfigs:
+		errs = append(errs, field.TooMany(fldPath, len(clientConfigs), r.MaxClientConfigs))
+	case approachesLimit(len(clientConfigs), r.MaxClientConfigs):
+		addWarning(ctx, fmt.Sprintf("%s: %d client configs approach the limit of %d", fldPath, len(clientConfigs), r.MaxClientConfigs))
+	}
+
+	if r.MaxCABundleBytes <= 0 {
+		return errs
+	}
+	for i, clientConfig := range clientConfigs {
+		caBundlePath := fldPath.Index(i).Child("caBundle")
+		switch {
+		case len(clientConfig.CABundle) > r.MaxCABundleBytes:
+			// the CA bundle is omitted from the error, it is too long to be readable
+			errs = append(errs, field.TooLong(caBundlePath, "<omitted>", r.MaxCABundleBytes))
+		case approachesLimit(len(clientConfig.CABundle), r.MaxCABundleBytes):
+			addWarning(ctx, fmt.Sprintf("%s: %d bytes approach the limit of %d bytes", caBundlePath, len(clientConfig.CABundle), r.MaxCABundleBytes))
+		}
+	}
+	return errs
+}
+
+// approachesLimit returns true if the value reaches the clientConfigLimitWarningRatio of the limit.
+func approachesLimit(value, limit int) bool {
+	return float64(value) >= float64(limit)*clientConfigLimitWarningRatio
 }
 
-// allowUpdateHubAcceptsClientField using SubjectAccessReview API to check whether a request user has been authorized to update
-// HubAcceptsClient field
+// validateClientConfigs validates the CA bundles of the client configs are PEM encoded certificates
+// and the URLs of the client configs are not duplicated.
+func validateClientConfigs(clientConfigs []v1.ClientConfig, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	urls := sets.NewString()
+	for i, clientConfig := range clientConfigs {
+		idxPath := fldPath.Index(i)
+		if urls.Has(clientConfig.URL) {
+			errs = append(errs, field.Duplicate(idxPath.Child("url"), clientConfig.URL))
+		}
+		urls.Insert(clientConfig.URL)
+
+		if len(clientConfig.CABundle) == 0 {
+			continue
+		}
+		if _, err := certutil.ParseCertsPEM(clientConfig.CABundle); err != nil {
+			// the CA bundle is omitted from the error, it is too long to be readable
+			errs = append(errs, field.Invalid(idxPath.Child("caBundle"), "<omitted>",
+				fmt.Sprintf("must be PEM encoded certificates: %v", err)))
+		}
+	}
+	return errs
+}
+
+// authorizer returns the authorizer of the webhook, the SubjectAccessReview api by default.
+func (r *ManagedClusterWebhook) authorizer() authorizer.Authorizer {
+	return authorizer.OrSubjectAccessReview(r.Authorizer, r.kubeClient)
+}
+
+// allowUpdateHubAcceptsClientField using the authorizer, the SubjectAccessReview API by default, to check whether a
+// request user has been authorized to update HubAcceptsClient field
 func (r *ManagedClusterWebhook) allowUpdateAcceptField(clusterName string, userInfo authenticationv1.UserInfo) error {
-	extra := make(map[string]authorizationv1.ExtraValue)
-	for k, v := range userInfo.Extra {
-		extra[k] = authorizationv1.ExtraValue(v)
-	}
-
-	sar := &authorizationv1.SubjectAccessReview{
-		Spec: authorizationv1.SubjectAccessReviewSpec{
-			User:   userInfo.Username,
-			UID:    userInfo.UID,
-			Groups: userInfo.Groups,
-			Extra:  extra,
-			ResourceAttributes: &authorizationv1.ResourceAttributes{
-				Group:       "register.open-cluster-management.io",
-				Resource:    "managedclusters",
-				Verb:        "update",
-				Subresource: "accept",
-				Name:        clusterName,
-			},
-		},
-	}
-	sar, err := r.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+	allowed, err := r.authorizer().Authorize(context.TODO(), userInfo,
+		authorizationv1.ResourceAttributes{
+			Group:       "register.open-cluster-management.io",
+			Resource:    "managedclusters",
+			Verb:        "update",
+			Subresource: "accept",
+			Name:        clusterName,
+		})
 	if err != nil {
 		return apierrors.NewForbidden(
 			v1.Resource("managedclusters/accept"),
@@ -173,7 +357,7 @@ func (r *ManagedClusterWebhook) allowUpdateAcceptField(clusterName string, userI
 		)
 	}
 
-	if !sar.Status.Allowed {
+	if !allowed {
 		return apierrors.NewForbidden(
 			v1.Resource("managedclusters/accept"),
 			clusterName,
@@ -207,6 +391,49 @@ func (r *ManagedClusterWebhook) validateAcceptByClusterNamespace(clusterName str
 	return nil
 }
 
+// allowSetProtectedLabels checks whether a request user is one of the writers of the protected labels if the
+// protected labels are set, changed or removed by the request, the oldCluster is nil if it is created.
+func (r *ManagedClusterWebhook) allowSetProtectedLabels(userInfo authenticationv1.UserInfo, oldCluster, cluster *v1.ManagedCluster) error {
+	if len(r.ProtectedLabelPrefixes) == 0 {
+		return nil
+	}
+
+	changed := sets.NewString()
+	var oldLabels map[string]string
+	if oldCluster != nil {
+		oldLabels = oldCluster.Labels
+	}
+	for key, value := range cluster.Labels {
+		if oldValue, ok := oldLabels[key]; (!ok || oldValue != value) && r.isProtectedLabel(key) {
+			changed.Insert(key)
+		}
+	}
+	for key := range oldLabels {
+		if _, ok := cluster.Labels[key]; !ok && r.isProtectedLabel(key) {
+			changed.Insert(key)
+		}
+	}
+	if changed.Len() == 0 || sets.NewString(r.ProtectedLabelWriters...).Has(userInfo.Username) {
+		return nil
+	}
+
+	return apierrors.NewForbidden(
+		v1.Resource("managedclusters"),
+		cluster.Name,
+		fmt.Errorf("user %q cannot set, change or remove the labels %s, they are maintained by the hub",
+			userInfo.Username, strings.Join(changed.List(), ", ")),
+	)
+}
+
+func (r *ManagedClusterWebhook) isProtectedLabel(key string) bool {
+	for _, prefix := range r.ProtectedLabelPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // allowSetClusterSetLabel checks whether a request user has been authorized to set clusterset label
 func (r *ManagedClusterWebhook) allowSetClusterSetLabel(userInfo authenticationv1.UserInfo, originalClusterSet, newClusterSet string) error {
 	if originalClusterSet == newClusterSet {
@@ -233,27 +460,14 @@ func (r *ManagedClusterWebhook) allowSetClusterSetLabel(userInfo authenticationv
 // allowUpdateClusterSet checks whether a request user has been authorized to add/remove a ManagedCluster
 // to/from the ManagedClusterSet
 func (r *ManagedClusterWebhook) allowUpdateClusterSet(userInfo authenticationv1.UserInfo, clusterSetName string) error {
-	extra := make(map[string]authorizationv1.ExtraValue)
-	for k, v := range userInfo.Extra {
-		extra[k] = authorizationv1.ExtraValue(v)
-	}
-
-	sar := &authorizationv1.SubjectAccessReview{
-		Spec: authorizationv1.SubjectAccessReviewSpec{
-			User:   userInfo.Username,
-			UID:    userInfo.UID,
-			Groups: userInfo.Groups,
-			Extra:  extra,
-			ResourceAttributes: &authorizationv1.ResourceAttributes{
-				Group:       "cluster.open-cluster-management.io",
-				Resource:    "managedclustersets",
-				Subresource: "join",
-				Name:        clusterSetName,
-				Verb:        "create",
-			},
-		},
-	}
-	sar, err := r.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+	allowed, err := r.authorizer().Authorize(context.TODO(), userInfo,
+		authorizationv1.ResourceAttributes{
+			Group:       "cluster.open-cluster-management.io",
+			Resource:    "managedclustersets",
+			Subresource: "join",
+			Name:        clusterSetName,
+			Verb:        "create",
+		})
 	if err != nil {
 		return apierrors.NewForbidden(
 			v1.Resource("managedclustersets/join"),
@@ -262,7 +476,7 @@ func (r *ManagedClusterWebhook) allowUpdateClusterSet(userInfo authenticationv1.
 		)
 	}
 
-	if !sar.Status.Allowed {
+	if !allowed {
 		return apierrors.NewForbidden(
 			v1.Resource("managedclustersets/join"),
 			clusterSetName,
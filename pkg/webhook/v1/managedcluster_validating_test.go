@@ -2,9 +2,12 @@ package v1
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	authorizationv1 "k8s.io/api/authorization/v1"
@@ -13,8 +16,12 @@ import (
 	kubefake "k8s.io/client-go/kubernetes/fake"
 
 	clienttesting "k8s.io/client-go/testing"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
 	v1 "open-cluster-management.io/api/cluster/v1"
 	"open-cluster-management.io/api/cluster/v1beta1"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+	"open-cluster-management.io/registration/pkg/hub/user"
 
 	corev1 "k8s.io/api/core/v1"
 )
@@ -550,6 +557,70 @@ func TestValidateUpdate(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:          "validate shrinking the lease duration below the minimum",
+			expectedError: true,
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "set-1",
+				},
+				Spec: v1.ManagedClusterSpec{
+					LeaseDurationSeconds: 1,
+				},
+			},
+			oldCluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "set-1",
+				},
+				Spec: v1.ManagedClusterSpec{
+					LeaseDurationSeconds: 60,
+				},
+			},
+		},
+		{
+			name:          "validate changing the iam role of an accepted ManagedCluster",
+			expectedError: true,
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "set-1",
+					Annotations: map[string]string{user.IAMRoleARNAnnotation: "arn:aws:iam::123456789012:role/role2"},
+				},
+				Spec: v1.ManagedClusterSpec{
+					HubAcceptsClient: true,
+				},
+			},
+			oldCluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "set-1",
+					Annotations: map[string]string{user.IAMRoleARNAnnotation: "arn:aws:iam::123456789012:role/role1"},
+				},
+				Spec: v1.ManagedClusterSpec{
+					HubAcceptsClient: true,
+				},
+			},
+		},
+		{
+			name:          "validate changing the token username of an accepted ManagedCluster",
+			expectedError: true,
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "set-1",
+					Annotations: map[string]string{user.TokenUsernameAnnotation: "system:serviceaccount:set-1:agent2"},
+				},
+				Spec: v1.ManagedClusterSpec{
+					HubAcceptsClient: true,
+				},
+			},
+			oldCluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "set-1",
+					Annotations: map[string]string{user.TokenUsernameAnnotation: "system:serviceaccount:set-1:agent1"},
+				},
+				Spec: v1.ManagedClusterSpec{
+					HubAcceptsClient: true,
+				},
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -605,3 +676,392 @@ func TestValidateUpdate(t *testing.T) {
 		t.Errorf("Non cluster obj, Expect Error but got nil")
 	}
 }
+
+func TestValidateManagedClusterObj(t *testing.T) {
+	cases := []struct {
+		name          string
+		cluster       *v1.ManagedCluster
+		expectedError string
+	}{
+		{
+			name: "valid cluster",
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster1"},
+				Spec: v1.ManagedClusterSpec{
+					ManagedClusterClientConfigs: []v1.ClientConfig{{URL: "https://127.0.0.1:8001"}},
+				},
+			},
+		},
+		{
+			name: "invalid name and urls",
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "01.cluster"},
+				Spec: v1.ManagedClusterSpec{
+					ManagedClusterClientConfigs: []v1.ClientConfig{
+						{URL: "https://127.0.0.1:8001"},
+						{URL: "http://127.0.0.1:8002"},
+					},
+				},
+			},
+			expectedError: "[metadata.name: Invalid value: \"01.cluster\": a lowercase RFC 1123 label must consist of " +
+				"lower case alphanumeric characters or '-', and must start and end with an alphanumeric character " +
+				"(e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?'), " +
+				"spec.managedClusterClientConfigs[1].url: Invalid value: \"http://127.0.0.1:8002\": must be a valid HTTPS URL]",
+		}, {
+			name: "invalid status update min interval",
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "cluster1",
+					Annotations: map[string]string{StatusUpdateMinIntervalAnnotation: "-1m"},
+				},
+			},
+			expectedError: "metadata.annotations[cluster.open-cluster-management.io/status-update-min-interval]: " +
+				"Invalid value: \"-1m\": must be a valid duration: must not be negative",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			errs := validateManagedClusterObj(c.cluster)
+			if actual := errs.ToAggregate(); actual == nil && len(c.expectedError) != 0 {
+				t.Errorf("expected error %q, but got nil", c.expectedError)
+			} else if actual != nil && actual.Error() != c.expectedError {
+				t.Errorf("expected error %q, but got %q", c.expectedError, actual.Error())
+			}
+		})
+	}
+}
+
+func TestValidateManagedClusterFields(t *testing.T) {
+	newCluster := func(accepted bool, leaseDurationSeconds int32, annotations map[string]string) *v1.ManagedCluster {
+		return &v1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Annotations: annotations},
+			Spec: v1.ManagedClusterSpec{
+				HubAcceptsClient:     accepted,
+				LeaseDurationSeconds: leaseDurationSeconds,
+			},
+		}
+	}
+	role1 := map[string]string{user.IAMRoleARNAnnotation: "role1"}
+	role2 := map[string]string{user.IAMRoleARNAnnotation: "role2"}
+	withClientConfigs := func(cluster *v1.ManagedCluster, clientConfigs ...v1.ClientConfig) *v1.ManagedCluster {
+		cluster.Spec.ManagedClusterClientConfigs = clientConfigs
+		return cluster
+	}
+	caBundle := testinghelpers.NewTestCert("ca", time.Hour).Cert
+
+	cases := []struct {
+		name        string
+		oldCluster  *v1.ManagedCluster
+		cluster     *v1.ManagedCluster
+		expectedErr string
+	}{
+		{
+			name:    "create with the default lease duration",
+			cluster: newCluster(false, 0, nil),
+		},
+		{
+			name:        "create with a short lease duration",
+			cluster:     newCluster(false, 3, nil),
+			expectedErr: "spec.leaseDurationSeconds: Invalid value: 3: must be no less than 5 seconds",
+		},
+		{
+			name:       "keep an existing short lease duration",
+			oldCluster: newCluster(true, 3, nil),
+			cluster:    newCluster(true, 3, nil),
+		},
+		{
+			name:        "shrink the lease duration",
+			oldCluster:  newCluster(true, 60, nil),
+			cluster:     newCluster(true, 1, nil),
+			expectedErr: "spec.leaseDurationSeconds: Invalid value: 1: must be no less than 5 seconds",
+		},
+		{
+			name:       "change the iam role of a cluster which is not accepted",
+			oldCluster: newCluster(false, 60, role1),
+			cluster:    newCluster(true, 60, role2),
+		},
+		{
+			name:       "set the iam role of an accepted cluster",
+			oldCluster: newCluster(true, 60, nil),
+			cluster:    newCluster(true, 60, role1),
+			expectedErr: "metadata.annotations[agent.open-cluster-management.io/managed-cluster-iam-role-arn]: Forbidden: " +
+				"cannot be changed once the managed cluster is accepted, set hubAcceptsClient to false first",
+		},
+		{
+			name: "create with valid client configs",
+			cluster: withClientConfigs(newCluster(false, 0, nil),
+				v1.ClientConfig{URL: "https://a.com", CABundle: caBundle},
+				v1.ClientConfig{URL: "https://b.com"}),
+		},
+		{
+			name: "create with duplicate urls and an invalid ca bundle",
+			cluster: withClientConfigs(newCluster(false, 0, nil),
+				v1.ClientConfig{URL: "https://a.com", CABundle: []byte("invalid")},
+				v1.ClientConfig{URL: "https://a.com", CABundle: caBundle}),
+			expectedErr: "[spec.managedClusterClientConfigs[0].caBundle: Invalid value: \"<omitted>\": " +
+				"must be PEM encoded certificates: data does not contain any valid RSA or ECDSA certificates, " +
+				"spec.managedClusterClientConfigs[1].url: Duplicate value: \"https://a.com\"]",
+		},
+		{
+			name: "keep existing duplicate urls",
+			oldCluster: withClientConfigs(newCluster(false, 60, nil),
+				v1.ClientConfig{URL: "https://a.com"}, v1.ClientConfig{URL: "https://a.com"}),
+			cluster: withClientConfigs(newCluster(true, 60, nil),
+				v1.ClientConfig{URL: "https://a.com"}, v1.ClientConfig{URL: "https://a.com"}),
+		},
+		{
+			name:       "update with an invalid ca bundle",
+			oldCluster: withClientConfigs(newCluster(true, 60, nil), v1.ClientConfig{URL: "https://a.com"}),
+			cluster: withClientConfigs(newCluster(true, 60, nil),
+				v1.ClientConfig{URL: "https://a.com", CABundle: []byte("invalid")}),
+			expectedErr: "spec.managedClusterClientConfigs[0].caBundle: Invalid value: \"<omitted>\": " +
+				"must be PEM encoded certificates: data does not contain any valid RSA or ECDSA certificates",
+		},
+		{
+			name:       "remove the iam role of an accepted cluster",
+			oldCluster: newCluster(true, 60, role1),
+			cluster:    newCluster(false, 60, nil),
+			expectedErr: "metadata.annotations[agent.open-cluster-management.io/managed-cluster-iam-role-arn]: Forbidden: " +
+				"cannot be changed once the managed cluster is accepted, set hubAcceptsClient to false first",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			errs := validateManagedClusterFields(c.oldCluster, c.cluster)
+			if actual := errs.ToAggregate(); actual == nil && len(c.expectedErr) != 0 {
+				t.Errorf("expected error %q, but got nil", c.expectedErr)
+			} else if actual != nil && actual.Error() != c.expectedErr {
+				t.Errorf("expected error %q, but got %q", c.expectedErr, actual.Error())
+			}
+		})
+	}
+}
+
+func TestValidateClientConfigLimits(t *testing.T) {
+	newCluster := func(caBundleBytes int, urls ...string) *v1.ManagedCluster {
+		cluster := &v1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+		for _, url := range urls {
+			cluster.Spec.ManagedClusterClientConfigs = append(cluster.Spec.ManagedClusterClientConfigs,
+				v1.ClientConfig{URL: url, CABundle: make([]byte, caBundleBytes)})
+		}
+		return cluster
+	}
+
+	cases := []struct {
+		name             string
+		maxClientConfigs int
+		maxCABundleBytes int
+		oldCluster       *v1.ManagedCluster
+		cluster          *v1.ManagedCluster
+		expectedErr      string
+		expectedWarnings []string
+	}{
+		{
+			name:    "no limits",
+			cluster: newCluster(1024, "https://a.com", "https://b.com", "https://c.com"),
+		},
+		{
+			name:             "within the limits",
+			maxClientConfigs: 5,
+			maxCABundleBytes: 1024,
+			cluster:          newCluster(512, "https://a.com", "https://b.com"),
+		},
+		{
+			name:             "approach the limits",
+			maxClientConfigs: 2,
+			maxCABundleBytes: 1024,
+			cluster:          newCluster(1000, "https://a.com", "https://b.com"),
+			expectedWarnings: []string{
+				"spec.managedClusterClientConfigs: 2 client configs approach the limit of 2",
+				"spec.managedClusterClientConfigs[0].caBundle: 1000 bytes approach the limit of 1024 bytes",
+				"spec.managedClusterClientConfigs[1].caBundle: 1000 bytes approach the limit of 1024 bytes",
+			},
+		},
+		{
+			name:             "exceed the limits",
+			maxClientConfigs: 1,
+			maxCABundleBytes: 1024,
+			cluster:          newCluster(2048, "https://a.com", "https://b.com"),
+			expectedErr: "[spec.managedClusterClientConfigs: Too many: 2: must have at most 1 items, " +
+				"spec.managedClusterClientConfigs[0].caBundle: Too long: must have at most 1024 bytes, " +
+				"spec.managedClusterClientConfigs[1].caBundle: Too long: must have at most 1024 bytes]",
+		},
+		{
+			name:             "keep the client configs exceeding the limits",
+			maxClientConfigs: 1,
+			maxCABundleBytes: 1024,
+			oldCluster:       newCluster(2048, "https://a.com", "https://b.com"),
+			cluster:          newCluster(2048, "https://a.com", "https://b.com"),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := &ManagedClusterWebhook{MaxClientConfigs: c.maxClientConfigs, MaxCABundleBytes: c.maxCABundleBytes}
+			extras := &responseExtras{auditAnnotations: map[string]string{}}
+			ctx := context.WithValue(context.Background(), responseExtrasKey{}, extras)
+
+			errs := r.validateClientConfigLimits(ctx, c.oldCluster, c.cluster)
+			if actual := errs.ToAggregate(); actual == nil && len(c.expectedErr) != 0 {
+				t.Errorf("expected error %q, but got nil", c.expectedErr)
+			} else if actual != nil && actual.Error() != c.expectedErr {
+				t.Errorf("expected error %q, but got %q", c.expectedErr, actual.Error())
+			}
+			if fmt.Sprint(extras.warnings) != fmt.Sprint(c.expectedWarnings) {
+				t.Errorf("expected warnings %v, but got %v", c.expectedWarnings, extras.warnings)
+			}
+		})
+	}
+}
+
+func TestAllowSetProtectedLabels(t *testing.T) {
+	newCluster := func(labels map[string]string) *v1.ManagedCluster {
+		return &v1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Labels: labels}}
+	}
+	writer := "system:serviceaccount:open-cluster-management-hub:registration-controller-sa"
+
+	cases := []struct {
+		name        string
+		prefixes    []string
+		username    string
+		oldCluster  *v1.ManagedCluster
+		cluster     *v1.ManagedCluster
+		expectedErr string
+	}{
+		{
+			name:     "no protected labels",
+			username: "user1",
+			cluster:  newCluster(map[string]string{"feature.open-cluster-management.io/addon-a": "available"}),
+		},
+		{
+			name:     "create with unprotected labels",
+			prefixes: []string{"feature.open-cluster-management.io/"},
+			username: "user1",
+			cluster:  newCluster(map[string]string{"vendor": "OpenShift"}),
+		},
+		{
+			name:     "create with protected labels",
+			prefixes: []string{"feature.open-cluster-management.io/"},
+			username: "user1",
+			cluster:  newCluster(map[string]string{"feature.open-cluster-management.io/addon-a": "available"}),
+			expectedErr: "managedclusters.cluster.open-cluster-management.io \"cluster1\" is forbidden: " +
+				"user \"user1\" cannot set, change or remove the labels feature.open-cluster-management.io/addon-a, they are maintained by the hub",
+		},
+		{
+			name:       "keep protected labels",
+			prefixes:   []string{"feature.open-cluster-management.io/"},
+			username:   "user1",
+			oldCluster: newCluster(map[string]string{"feature.open-cluster-management.io/addon-a": "available"}),
+			cluster:    newCluster(map[string]string{"feature.open-cluster-management.io/addon-a": "available", "vendor": "OpenShift"}),
+		},
+		{
+			name:       "change and remove protected labels",
+			prefixes:   []string{"feature.open-cluster-management.io/"},
+			username:   "user1",
+			oldCluster: newCluster(map[string]string{"feature.open-cluster-management.io/addon-a": "available", "feature.open-cluster-management.io/addon-b": "available"}),
+			cluster:    newCluster(map[string]string{"feature.open-cluster-management.io/addon-a": "unhealthy"}),
+			expectedErr: "managedclusters.cluster.open-cluster-management.io \"cluster1\" is forbidden: " +
+				"user \"user1\" cannot set, change or remove the labels feature.open-cluster-management.io/addon-a, " +
+				"feature.open-cluster-management.io/addon-b, they are maintained by the hub",
+		},
+		{
+			name:       "change protected labels by the writer",
+			prefixes:   []string{"feature.open-cluster-management.io/"},
+			username:   writer,
+			oldCluster: newCluster(map[string]string{"feature.open-cluster-management.io/addon-a": "available"}),
+			cluster:    newCluster(map[string]string{"feature.open-cluster-management.io/addon-a": "unhealthy"}),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := &ManagedClusterWebhook{ProtectedLabelPrefixes: c.prefixes, ProtectedLabelWriters: []string{writer}}
+			err := r.allowSetProtectedLabels(authenticationv1.UserInfo{Username: c.username}, c.oldCluster, c.cluster)
+			testinghelpers.AssertError(t, err, c.expectedErr)
+		})
+	}
+}
+
+func TestValidateDelete(t *testing.T) {
+	availableCondition := metav1.Condition{Type: v1.ManagedClusterConditionAvailable, Status: metav1.ConditionTrue}
+	newAddOn := func(name string, available bool) *addonv1alpha1.ManagedClusterAddOn {
+		addOn := &addonv1alpha1.ManagedClusterAddOn{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "cluster1", Name: name},
+		}
+		if available {
+			addOn.Status.Conditions = []metav1.Condition{{Type: addonv1alpha1.ManagedClusterAddOnConditionAvailable, Status: metav1.ConditionTrue}}
+		}
+		return addOn
+	}
+
+	cases := []struct {
+		name               string
+		deletionProtection bool
+		cluster            *v1.ManagedCluster
+		addOns             []runtime.Object
+		expectedErr        string
+	}{
+		{
+			name: "deletion protection is disabled",
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster1"},
+				Status:     v1.ManagedClusterStatus{Conditions: []metav1.Condition{availableCondition}},
+			},
+		},
+		{
+			name:               "cluster is not available",
+			deletionProtection: true,
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster1"},
+			},
+		},
+		{
+			name:               "deletion is confirmed",
+			deletionProtection: true,
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "cluster1",
+					Annotations: map[string]string{DeletionConfirmationAnnotation: "true"},
+				},
+				Status: v1.ManagedClusterStatus{Conditions: []metav1.Condition{availableCondition}},
+			},
+		},
+		{
+			name:               "deletion is not confirmed",
+			deletionProtection: true,
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster1"},
+				Status:     v1.ManagedClusterStatus{Conditions: []metav1.Condition{availableCondition}},
+			},
+			expectedErr: `managedclusters.cluster.open-cluster-management.io "cluster1" is forbidden: the managed cluster is available, ` +
+				`set the annotation "cluster.open-cluster-management.io/deletion-confirmed" to "true" to confirm the deletion`,
+		},
+		{
+			name:               "deletion is not confirmed with running addons",
+			deletionProtection: true,
+			cluster: &v1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "cluster1",
+					Annotations: map[string]string{DeletionConfirmationAnnotation: "false"},
+				},
+				Status: v1.ManagedClusterStatus{Conditions: []metav1.Condition{availableCondition}},
+			},
+			addOns: []runtime.Object{newAddOn("search", true), newAddOn("policy", true), newAddOn("observability", false)},
+			expectedErr: `managedclusters.cluster.open-cluster-management.io "cluster1" is forbidden: the managed cluster is available, ` +
+				`set the annotation "cluster.open-cluster-management.io/deletion-confirmed" to "true" to confirm the deletion, ` +
+				`the addons still running on the managed cluster: policy, search`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := ManagedClusterWebhook{DeletionProtection: c.deletionProtection}
+			w.SetExternalAddOnClientSet(addonfake.NewSimpleClientset(c.addOns...))
+
+			err := w.ValidateDelete(context.Background(), c.cluster)
+			switch {
+			case err == nil && len(c.expectedErr) != 0:
+				t.Errorf("expected error %q, but got nil", c.expectedErr)
+			case err != nil && err.Error() != c.expectedErr:
+				t.Errorf("expected error %q, but got %q", c.expectedErr, err.Error())
+			}
+		})
+	}
+}
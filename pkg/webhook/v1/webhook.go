@@ -1,13 +1,55 @@
 package v1
 
 import (
+	"time"
+
 	"k8s.io/client-go/kubernetes"
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
 	v1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/webhook/authorizer"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	mutatingWebhookPath   = "/mutate-cluster-open-cluster-management-io-v1-managedcluster"
+	validatingWebhookPath = "/validate-cluster-open-cluster-management-io-v1-managedcluster"
 )
 
 type ManagedClusterWebhook struct {
-	kubeClient kubernetes.Interface
+	kubeClient  kubernetes.Interface
+	addOnClient addonclient.Interface
+
+	// Authorizer checks the permissions of the request users, the SubjectAccessReview api is used if it is nil.
+	Authorizer authorizer.Authorizer
+
+	// DeletionProtection denies deleting an available ManagedCluster without the
+	// DeletionConfirmationAnnotation.
+	DeletionProtection bool
+
+	// StatusUpdateMinInterval is the min interval between the status updates of a ManagedCluster by an
+	// agent, zero disables it. It can be overridden by the StatusUpdateMinIntervalAnnotation.
+	StatusUpdateMinInterval time.Duration
+
+	// StatusUpdateBurst is the max number of the status updates of a ManagedCluster by an agent within the
+	// StatusUpdateMinInterval, e.g. when several controllers of the agent update the status at once.
+	StatusUpdateBurst int
+
+	// MaxClientConfigs is the max number of the client configs of a ManagedCluster, zero means no limit.
+	MaxClientConfigs int
+
+	// MaxCABundleBytes is the max size of the CA bundle of a client config of a ManagedCluster in bytes,
+	// zero means no limit.
+	MaxCABundleBytes int
+
+	// ProtectedLabelPrefixes are the prefixes of the labels of a ManagedCluster maintained by the hub, e.g. the
+	// feature labels of the addons, which only the ProtectedLabelWriters can set, change or remove.
+	ProtectedLabelPrefixes []string
+
+	// ProtectedLabelWriters are the users allowed to write the protected labels of a ManagedCluster.
+	ProtectedLabelWriters []string
+
+	statusUpdateLimiter *statusUpdateLimiter
 }
 
 func (r *ManagedClusterWebhook) Init(mgr ctrl.Manager) error {
@@ -15,7 +57,12 @@ func (r *ManagedClusterWebhook) Init(mgr ctrl.Manager) error {
 	if err != nil {
 		return err
 	}
+	r.statusUpdateLimiter = newStatusUpdateLimiter()
 	r.kubeClient, err = kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	r.addOnClient, err = addonclient.NewForConfig(mgr.GetConfig())
 	return err
 }
 
@@ -24,10 +71,19 @@ func (r *ManagedClusterWebhook) SetExternalKubeClientSet(client kubernetes.Inter
 	r.kubeClient = client
 }
 
+// SetExternalAddOnClientSet sets the addon client used to list the addons of a ManagedCluster
+func (r *ManagedClusterWebhook) SetExternalAddOnClientSet(client addonclient.Interface) {
+	r.addOnClient = client
+}
+
+// SetupWebhookWithManager registers the mutating and validating webhooks of the ManagedCluster on the paths
+// the webhook builder of the controller-runtime generates, with the audit annotations and the warnings added by
+// the webhooks set on the admission responses.
 func (r *ManagedClusterWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewWebhookManagedBy(mgr).
-		WithValidator(r).
-		WithDefaulter(r).
-		For(&v1.ManagedCluster{}).
-		Complete()
+	server := mgr.GetWebhookServer()
+	server.Register(mutatingWebhookPath,
+		withResponseExtras(admission.WithCustomDefaulter(&v1.ManagedCluster{}, r)))
+	server.Register(validatingWebhookPath,
+		withResponseExtras(admission.WithCustomValidator(&v1.ManagedCluster{}, r)))
+	return nil
 }
@@ -6,13 +6,12 @@ import (
 
 	authenticationv1 "k8s.io/api/authentication/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/client-go/kubernetes"
 
 	authorizationv1 "k8s.io/api/authorization/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"open-cluster-management.io/api/cluster/v1beta1"
+	"open-cluster-management.io/registration/pkg/webhook/authorizer"
 
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -36,7 +35,7 @@ func (b *ManagedClusterSetBindingWebhook) ValidateCreate(ctx context.Context, ob
 	if err != nil {
 		return apierrors.NewBadRequest(err.Error())
 	}
-	return AllowBindingToClusterSet(b.kubeClient, binding.Spec.ClusterSet, req.UserInfo)
+	return AllowBindingToClusterSet(authorizer.OrSubjectAccessReview(b.Authorizer, b.kubeClient), binding.Spec.ClusterSet, req.UserInfo)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
@@ -58,29 +57,15 @@ func (b *ManagedClusterSetBindingWebhook) ValidateDelete(_ context.Context, obj
 	return nil
 }
 
-// allowBindingToClusterSet checks if the user has permission to bind a particular cluster set
-func AllowBindingToClusterSet(kubeClient kubernetes.Interface, clusterSetName string, userInfo authenticationv1.UserInfo) error {
-	extra := make(map[string]authorizationv1.ExtraValue)
-	for k, v := range userInfo.Extra {
-		extra[k] = authorizationv1.ExtraValue(v)
-	}
-
-	sar := &authorizationv1.SubjectAccessReview{
-		Spec: authorizationv1.SubjectAccessReviewSpec{
-			User:   userInfo.Username,
-			UID:    userInfo.UID,
-			Groups: userInfo.Groups,
-			Extra:  extra,
-			ResourceAttributes: &authorizationv1.ResourceAttributes{
-				Group:       "cluster.open-cluster-management.io",
-				Resource:    "managedclustersets",
-				Subresource: "bind",
-				Verb:        "create",
-				Name:        clusterSetName,
-			},
-		},
-	}
-	sar, err := kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+// AllowBindingToClusterSet checks if the user has permission to bind a particular cluster set
+func AllowBindingToClusterSet(a authorizer.Authorizer, clusterSetName string, userInfo authenticationv1.UserInfo) error {
+	allowed, err := a.Authorize(context.TODO(), userInfo, authorizationv1.ResourceAttributes{
+		Group:       "cluster.open-cluster-management.io",
+		Resource:    "managedclustersets",
+		Subresource: "bind",
+		Verb:        "create",
+		Name:        clusterSetName,
+	})
 	if err != nil {
 		return apierrors.NewForbidden(
 			v1beta1.Resource("managedclustersets/bind"),
@@ -88,7 +73,7 @@ func AllowBindingToClusterSet(kubeClient kubernetes.Interface, clusterSetName st
 			err,
 		)
 	}
-	if !sar.Status.Allowed {
+	if !allowed {
 		return apierrors.NewForbidden(
 			v1beta1.Resource("managedclustersets/bind"),
 			clusterSetName,
@@ -6,6 +6,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
 	"open-cluster-management.io/api/cluster/v1beta1"
+	"open-cluster-management.io/registration/pkg/webhook/authorizer"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -31,6 +32,9 @@ type ManagedClusterSet struct {
 
 type ManagedClusterSetBindingWebhook struct {
 	kubeClient kubernetes.Interface
+
+	// Authorizer checks the permissions of the request users, the SubjectAccessReview api is used if it is nil.
+	Authorizer authorizer.Authorizer
 }
 
 func (r *ManagedClusterSet) SetupWebhookWithManager(mgr ctrl.Manager) error {
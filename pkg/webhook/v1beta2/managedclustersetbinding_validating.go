@@ -7,6 +7,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"open-cluster-management.io/api/cluster/v1beta2"
+	"open-cluster-management.io/registration/pkg/webhook/authorizer"
 	internalv1beta1 "open-cluster-management.io/registration/pkg/webhook/v1beta1"
 
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -31,7 +32,7 @@ func (b *ManagedClusterSetBindingWebhook) ValidateCreate(ctx context.Context, ob
 	if err != nil {
 		return apierrors.NewBadRequest(err.Error())
 	}
-	return internalv1beta1.AllowBindingToClusterSet(b.kubeClient, binding.Spec.ClusterSet, req.UserInfo)
+	return internalv1beta1.AllowBindingToClusterSet(authorizer.OrSubjectAccessReview(b.Authorizer, b.kubeClient), binding.Spec.ClusterSet, req.UserInfo)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
@@ -5,6 +5,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"open-cluster-management.io/api/cluster/v1beta2"
+	"open-cluster-management.io/registration/pkg/webhook/authorizer"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -30,6 +31,9 @@ type ManagedClusterSet struct {
 
 type ManagedClusterSetBindingWebhook struct {
 	kubeClient kubernetes.Interface
+
+	// Authorizer checks the permissions of the request users, the SubjectAccessReview api is used if it is nil.
+	Authorizer authorizer.Authorizer
 }
 
 func (src *ManagedClusterSet) SetupWebhookWithManager(mgr ctrl.Manager) error {
@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	ginkgo "github.com/onsi/ginkgo/v2"
 	gomega "github.com/onsi/gomega"
 
-	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
@@ -23,6 +23,7 @@ import (
 
 	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/test/framework"
 )
 
 var hubNamespace = "open-cluster-management-hub"
@@ -114,32 +115,8 @@ var _ = ginkgo.BeforeSuite(func() {
 	}()
 	gomega.Expect(err).ToNot(gomega.HaveOccurred())
 
-	gomega.Eventually(func() error {
-		var err error
-		var mutatingWebhookPods *v1.PodList
-		var mutatingWebhookDeployment *appsv1.Deployment
-
-		if mutatingWebhookPods, err = hubClient.CoreV1().Pods(hubNamespace).List(context.Background(),
-			metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", mutatingWebhookName)}); err != nil {
-			return err
-		}
-		if mutatingWebhookDeployment, err = hubClient.AppsV1().Deployments(hubNamespace).Get(context.Background(),
-			mutatingWebhookName, metav1.GetOptions{}); err != nil {
-			return err
-		}
-
-		pods := len(mutatingWebhookPods.Items)
-		replicas := *mutatingWebhookDeployment.Spec.Replicas
-		readyReplicas := mutatingWebhookDeployment.Status.ReadyReplicas
-
-		if pods != (int)(replicas) {
-			return fmt.Errorf("deployment %s pods should have %d but got %d ", mutatingWebhookName, replicas, pods)
-		}
-
-		if readyReplicas != replicas {
-			return fmt.Errorf("deployment %s should have %d but got %d ready replicas", mutatingWebhookName, replicas, readyReplicas)
-		}
-
-		return nil
-	}, eventuallyTimeout, eventuallyInterval).Should(gomega.BeNil())
+	// wait until the webhook handles the admission requests, otherwise the specs fail on calling the webhook
+	err = framework.WaitForWebhookReady(context.Background(), hubClient, hubNamespace, mutatingWebhookName,
+		framework.ManagedClusterAdmissionProbe(clusterClient), 2*time.Minute)
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
 })
@@ -13,6 +13,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 
@@ -407,7 +408,15 @@ var _ = ginkgo.Describe("Loopback registration [development]", func() {
 				return false, err
 			}
 
-			return reflect.DeepEqual(clusterClaims, managedCluster.Status.ClusterClaims), nil
+			// the well-known claims synthesized by the agent are ignored
+			wellKnownClaimNames := sets.NewString(helpers.WellKnownClusterClaimNames...)
+			createdClaims := []clusterv1.ManagedClusterClaim{}
+			for _, claim := range managedCluster.Status.ClusterClaims {
+				if !wellKnownClaimNames.Has(claim.Name) {
+					createdClaims = append(createdClaims, claim)
+				}
+			}
+			return reflect.DeepEqual(clusterClaims, createdClaims), nil
 		})
 		gomega.Expect(err).ToNot(gomega.HaveOccurred())
 
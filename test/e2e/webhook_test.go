@@ -183,7 +183,7 @@ var _ = ginkgo.Describe("Admission webhook", func() {
 				gomega.Expect(u.deleteManageClusterAndRelatedNamespace(clusterName)).ToNot(gomega.HaveOccurred())
 			})
 
-			ginkgo.It("Should respond bad request when creating a managed cluster with invalid external server URLs", func() {
+			ginkgo.It("Should respond invalid when creating a managed cluster with invalid external server URLs", func() {
 				clusterName := fmt.Sprintf("webhook-spoke-%s", rand.String(6))
 				ginkgo.By(fmt.Sprintf("create a managed cluster %q with an invalid external server URL %q", clusterName, invalidURL))
 
@@ -191,17 +191,19 @@ var _ = ginkgo.Describe("Admission webhook", func() {
 
 				_, err := clusterClient.ClusterV1().ManagedClusters().Create(context.TODO(), managedCluster, metav1.CreateOptions{})
 				gomega.Expect(err).To(gomega.HaveOccurred())
-				gomega.Expect(errors.IsBadRequest(err)).Should(gomega.BeTrue())
+				gomega.Expect(errors.IsInvalid(err)).Should(gomega.BeTrue())
 				gomega.Expect(err.Error()).Should(gomega.Equal(fmt.Sprintf(
-					"admission webhook \"%s\" denied the request: url \"%s\" is invalid in client configs",
+					"admission webhook \"%s\" denied the request: ManagedCluster.cluster.open-cluster-management.io \"%s\" is invalid: "+
+						"spec.managedClusterClientConfigs[0].url: Invalid value: \"%s\": must be a valid HTTPS URL",
 					admissionName,
+					clusterName,
 					invalidURL,
 				)))
 
 				gomega.Expect(u.deleteManageClusterAndRelatedNamespace(clusterName)).ToNot(gomega.HaveOccurred())
 			})
 
-			ginkgo.It("Should respond bad request when cluster name is invalid", func() {
+			ginkgo.It("Should respond invalid when cluster name is invalid", func() {
 				clusterName := fmt.Sprintf("webhook.spoke-%s", rand.String(6))
 				ginkgo.By(fmt.Sprintf("create a managed cluster %q with an invalid name", clusterName))
 
@@ -209,7 +211,7 @@ var _ = ginkgo.Describe("Admission webhook", func() {
 
 				_, err := clusterClient.ClusterV1().ManagedClusters().Create(context.TODO(), managedCluster, metav1.CreateOptions{})
 				gomega.Expect(err).To(gomega.HaveOccurred())
-				gomega.Expect(errors.IsBadRequest(err)).Should(gomega.BeTrue())
+				gomega.Expect(errors.IsInvalid(err)).Should(gomega.BeTrue())
 				gomega.Expect(u.deleteManageClusterAndRelatedNamespace(clusterName)).ToNot(gomega.HaveOccurred())
 			})
 
@@ -517,7 +519,7 @@ var _ = ginkgo.Describe("Admission webhook", func() {
 				gomega.Expect(managedCluster.Labels[clusterv1beta2.ClusterSetLabel]).To(gomega.Equal("s1"))
 			})
 
-			ginkgo.It("Should respond bad request when updating a managed cluster with invalid external server URLs", func() {
+			ginkgo.It("Should respond invalid when updating a managed cluster with invalid external server URLs", func() {
 				ginkgo.By(fmt.Sprintf("update managed cluster %q with an invalid external server URL %q", clusterName, invalidURL))
 
 				err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -529,7 +531,7 @@ var _ = ginkgo.Describe("Admission webhook", func() {
 					return err
 				})
 				gomega.Expect(err).To(gomega.HaveOccurred())
-				gomega.Expect(errors.IsBadRequest(err)).Should(gomega.BeTrue())
+				gomega.Expect(errors.IsInvalid(err)).Should(gomega.BeTrue())
 			})
 
 			ginkgo.It("Should forbid the request when updating an unaccepted managed cluster to accepted by unauthorized user", func() {
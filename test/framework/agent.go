@@ -0,0 +1,34 @@
+package framework
+
+import (
+	"context"
+
+	"open-cluster-management.io/registration/pkg/spoke"
+
+	"github.com/openshift/library-go/pkg/controller/controllercmd"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// NewEventRecorder creates the event recorder of the agents run by RunAgent, the events are logged by
+// default. Replace it to redirect the events, e.g. to the GinkgoWriter.
+var NewEventRecorder = events.NewLoggingEventRecorder
+
+// RunAgent runs a registration agent with the options in the background, the cfg is the kubeconfig of
+// the cluster the agent runs on. The returned function stops the agent.
+func RunAgent(name string, opt spoke.SpokeAgentOptions, cfg *rest.Config) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		err := opt.RunSpokeAgent(ctx, &controllercmd.ControllerContext{
+			KubeConfig:    cfg,
+			EventRecorder: NewEventRecorder(name),
+		})
+		if err != nil {
+			klog.Errorf("Agent %q exits with error: %v", name, err)
+		}
+	}()
+
+	return cancel
+}
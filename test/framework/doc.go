@@ -0,0 +1,6 @@
+// Package framework provides the harness to run the registration agent against a fake hub in the
+// integration tests, e.g. of the addons built on open-cluster-management registration. A test starts a
+// fake hub with StartFakeHub, runs an agent with RunAgent, then approves the CSR of the agent and accepts
+// the managed cluster with ApproveCSR and AcceptCluster of the hub. The e2e suites running against a
+// real hub wait for the webhook with WaitForWebhookReady before running the specs.
+package framework
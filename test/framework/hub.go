@@ -0,0 +1,243 @@
+package framework
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/pkg/cmd/webhook"
+	"open-cluster-management.io/registration/pkg/hub"
+
+	"github.com/openshift/library-go/pkg/controller/controllercmd"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// FakeHub is a kube-apiserver started by envtest with the registration hub controllers running on it.
+type FakeHub struct {
+	// Config is the kubeconfig of the cluster admin of the hub.
+	Config        *rest.Config
+	KubeClient    kubernetes.Interface
+	ClusterClient clusterclientset.Interface
+	// Authn signs the client certificates of the hub.
+	Authn *TestAuthn
+	// ServerCertFile is the file of the serving certificate of the kube-apiserver.
+	ServerCertFile string
+	// SecurePort is the port the kube-apiserver listens on.
+	SecurePort string
+
+	workDir string
+	env     *envtest.Environment
+
+	lock            sync.Mutex
+	stopControllers func()
+	stopWebhook     func()
+}
+
+// FakeHubOptions are the options of a fake hub.
+type FakeHubOptions struct {
+	// CRDPaths are the paths of the CRDs installed on the hub, e.g. the deploy/hub directory of this
+	// repository.
+	CRDPaths []string
+	// WebhookConfigFile is the file of the webhook configurations of the registration webhook, e.g. the
+	// deploy/webhook/webhook.yaml of this repository. The webhook server is not run if it is empty.
+	WebhookConfigFile string
+}
+
+// StartFakeHub starts a kube-apiserver with the CRDs in the crdPaths installed, e.g. the deploy/hub
+// directory of this repository, and runs the hub controllers on it. The certificates are generated in
+// a temporary directory, which is removed once the hub is stopped.
+func StartFakeHub(crdPaths ...string) (*FakeHub, error) {
+	return StartFakeHubWithOptions(FakeHubOptions{CRDPaths: crdPaths})
+}
+
+// StartFakeHubWithOptions starts a kube-apiserver with the options, and runs the hub controllers and the
+// webhook server on it.
+func StartFakeHubWithOptions(o FakeHubOptions) (*FakeHub, error) {
+	workDir, err := os.MkdirTemp("", "registration-fake-hub")
+	if err != nil {
+		return nil, err
+	}
+
+	authn := NewTestAuthn(path.Join(workDir, "ca.crt"), path.Join(workDir, "ca.key"))
+	apiServer := &envtest.APIServer{}
+	apiServer.SecureServing.Authn = authn
+	env := &envtest.Environment{
+		ControlPlane:          envtest.ControlPlane{APIServer: apiServer},
+		ErrorIfCRDPathMissing: true,
+		CRDDirectoryPaths:     o.CRDPaths,
+	}
+	if len(o.WebhookConfigFile) != 0 {
+		webhookConfigFile, err := prepareWebhookConfigFile(workDir, o.WebhookConfigFile)
+		if err != nil {
+			os.RemoveAll(workDir)
+			return nil, err
+		}
+		env.WebhookInstallOptions = envtest.WebhookInstallOptions{Paths: []string{webhookConfigFile}}
+	}
+	cfg, err := env.Start()
+	if err != nil {
+		os.RemoveAll(workDir)
+		return nil, fmt.Errorf("unable to start the kube-apiserver of the fake hub: %w", err)
+	}
+
+	h := &FakeHub{
+		Config:         cfg,
+		Authn:          authn,
+		ServerCertFile: path.Join(apiServer.CertDir, "apiserver.crt"),
+		SecurePort:     apiServer.SecureServing.Port,
+		workDir:        workDir,
+		env:            env,
+	}
+	if h.KubeClient, err = kubernetes.NewForConfig(cfg); err != nil {
+		h.Stop()
+		return nil, err
+	}
+	if h.ClusterClient, err = clusterclientset.NewForConfig(cfg); err != nil {
+		h.Stop()
+		return nil, err
+	}
+
+	h.StartControllers()
+	if len(o.WebhookConfigFile) != 0 {
+		h.StartWebhook()
+	}
+	return h, nil
+}
+
+// prepareWebhookConfigFile copies the webhook configurations to the work dir without the placeholder of
+// the CA bundle, envtest fills the CA bundle of its own serving certificate instead.
+func prepareWebhookConfigFile(workDir, webhookConfigFile string) (string, error) {
+	data, err := os.ReadFile(webhookConfigFile)
+	if err != nil {
+		return "", err
+	}
+	file := path.Join(workDir, "webhook.yaml")
+	if err := os.WriteFile(file, bytes.ReplaceAll(data, []byte("CA_PLACE_HOLDER"), nil), 0600); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// StartControllers runs the hub controllers, they are run once the hub is started. It does nothing if the
+// controllers are running.
+func (h *FakeHub) StartControllers() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.stopControllers != nil {
+		return
+	}
+	h.stopControllers = runUntilStopped(func(ctx context.Context) error {
+		return hub.NewHubManagerOptions().RunControllerManager(ctx, &controllercmd.ControllerContext{
+			KubeConfig:    h.Config,
+			EventRecorder: NewEventRecorder("hub"),
+		})
+	}, "Hub controllers")
+}
+
+// StopControllers stops the hub controllers and waits until they exit, e.g. to simulate a restart of the
+// hub controller manager.
+func (h *FakeHub) StopControllers() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.stopControllers != nil {
+		h.stopControllers()
+		h.stopControllers = nil
+	}
+}
+
+// StartWebhook runs the webhook server with the serving certificate of envtest. It does nothing if the hub
+// is started without the webhook configurations or the webhook server is running.
+func (h *FakeHub) StartWebhook() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.stopWebhook != nil || len(h.env.WebhookInstallOptions.LocalServingCertDir) == 0 {
+		return
+	}
+	webhookOptions := webhook.NewOptions()
+	webhookOptions.Port = h.env.WebhookInstallOptions.LocalServingPort
+	webhookOptions.CertDir = h.env.WebhookInstallOptions.LocalServingCertDir
+	webhookOptions.MetricsBindAddress = "0"
+	webhookOptions.HealthProbeBindAddress = "0"
+	h.stopWebhook = runUntilStopped(func(ctx context.Context) error {
+		return webhookOptions.RunWebhookServerWithContext(ctx, h.Config)
+	}, "Webhook server")
+}
+
+// StopWebhook stops the webhook server and waits until it exits, the requests to the hub which are
+// intercepted by the webhook are rejected until it is started again.
+func (h *FakeHub) StopWebhook() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.stopWebhook != nil {
+		h.stopWebhook()
+		h.stopWebhook = nil
+	}
+}
+
+// Stop stops the hub controllers, the webhook server and the kube-apiserver.
+func (h *FakeHub) Stop() error {
+	h.StopWebhook()
+	h.StopControllers()
+	if err := h.env.Stop(); err != nil {
+		return err
+	}
+	return os.RemoveAll(h.workDir)
+}
+
+// runUntilStopped runs the fn in a goroutine, and returns a func which cancels the ctx of the fn and
+// waits until the fn returns.
+func runUntilStopped(fn func(ctx context.Context) error, name string) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := fn(ctx); err != nil {
+			klog.Errorf("%s exit with error: %v", name, err)
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// CreateBootstrapKubeConfig writes a bootstrap kubeconfig of the hub for an agent, its client
+// certificate is valid in 24 hours.
+func (h *FakeHub) CreateBootstrapKubeConfig(configFileName string) error {
+	return h.Authn.CreateBootstrapKubeConfigWithCertAge(configFileName, h.ServerCertFile, h.SecurePort, 24*time.Hour)
+}
+
+// ApproveCSR approves the CSR of the agent of the managed cluster, the certificate is valid in certAge.
+func (h *FakeHub) ApproveCSR(clusterName string, certAge time.Duration) error {
+	return h.Authn.ApproveSpokeClusterCSR(h.KubeClient, clusterName, certAge)
+}
+
+// AcceptCluster accepts the managed cluster with the lease duration.
+func (h *FakeHub) AcceptCluster(clusterName string, leaseDurationSeconds int32) error {
+	return AcceptManagedCluster(h.ClusterClient, clusterName, leaseDurationSeconds)
+}
+
+// AcceptManagedCluster accepts the managed cluster with the lease duration.
+func AcceptManagedCluster(clusterClient clusterclientset.Interface, clusterName string, leaseDurationSeconds int32) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		cluster, err := clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), clusterName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		cluster.Spec.HubAcceptsClient = true
+		cluster.Spec.LeaseDurationSeconds = leaseDurationSeconds
+		_, err = clusterClient.ClusterV1().ManagedClusters().Update(context.TODO(), cluster, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,110 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AdmissionProbe sends an admission request intercepted by a webhook, it returns an error if the webhook
+// fails to handle the request.
+type AdmissionProbe func(ctx context.Context) error
+
+// ManagedClusterAdmissionProbe creates a ManagedCluster in the dry run mode, which is handled by both the
+// mutating and the validating webhooks of the ManagedCluster without being persisted.
+func ManagedClusterAdmissionProbe(clusterClient clusterclientset.Interface) AdmissionProbe {
+	return func(ctx context.Context) error {
+		cluster := &clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("webhook-probe-%s", rand.String(6)),
+			},
+		}
+		_, err := clusterClient.ClusterV1().ManagedClusters().Create(ctx, cluster, metav1.CreateOptions{
+			DryRun: []string{metav1.DryRunAll},
+		})
+		return err
+	}
+}
+
+// WaitForWebhookReady waits until the webhook served by the deployment and the service of the name is
+// ready to handle the admission requests:
+//   - the replicas of the deployment are rolled out and ready, and the pods of the old replicas are gone;
+//   - the endpoints of the service have an address for each replica and all of them are ready;
+//   - the admission request of the probe succeeds.
+//
+// The kube-apiserver fails to call the webhook for a while after the deployment is ready, until the
+// endpoints are propagated, so the suites should wait for it before running the specs.
+func WaitForWebhookReady(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string,
+	probe AdmissionProbe, timeout time.Duration) error {
+	var lastErr error
+	err := wait.PollImmediateWithContext(ctx, time.Second, timeout, func(ctx context.Context) (bool, error) {
+		lastErr = checkWebhookReady(ctx, kubeClient, namespace, name, probe)
+		return lastErr == nil, nil
+	})
+	if err != nil && lastErr != nil {
+		return fmt.Errorf("webhook %s/%s is not ready in %s: %w", namespace, name, timeout, lastErr)
+	}
+	return err
+}
+
+func checkWebhookReady(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, probe AdmissionProbe) error {
+	deployment, err := kubeClient.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return fmt.Errorf("deployment %s is not observed yet", name)
+	}
+	if deployment.Status.UpdatedReplicas != replicas {
+		return fmt.Errorf("deployment %s should have %d but got %d updated replicas", name, replicas, deployment.Status.UpdatedReplicas)
+	}
+	if deployment.Status.ReadyReplicas != replicas {
+		return fmt.Errorf("deployment %s should have %d but got %d ready replicas", name, replicas, deployment.Status.ReadyReplicas)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	pods, err := kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) != int(replicas) {
+		return fmt.Errorf("deployment %s pods should have %d but got %d", name, replicas, len(pods.Items))
+	}
+
+	endpoints, err := kubeClient.CoreV1().Endpoints(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	readyAddresses, notReadyAddresses := 0, 0
+	for _, subset := range endpoints.Subsets {
+		readyAddresses += len(subset.Addresses)
+		notReadyAddresses += len(subset.NotReadyAddresses)
+	}
+	if readyAddresses != int(replicas) || notReadyAddresses != 0 {
+		return fmt.Errorf("service %s should have %d ready endpoints but got %d ready and %d not ready endpoints",
+			name, replicas, readyAddresses, notReadyAddresses)
+	}
+
+	if probe == nil {
+		return nil
+	}
+	if err := probe(ctx); err != nil {
+		return fmt.Errorf("probe admission request fails: %w", err)
+	}
+	return nil
+}
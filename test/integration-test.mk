@@ -22,6 +22,7 @@ clean-integration-test:
 	$(RM) '$(KB_TOOLS_ARCHIVE_PATH)'
 	rm -rf $(TEST_TMP)/kubebuilder
 	$(RM) ./integration.test
+	$(RM) ./resilience.test
 .PHONY: clean-integration-test
 
 clean: clean-integration-test
@@ -29,4 +30,6 @@ clean: clean-integration-test
 test-integration: ensure-kubebuilder-tools
 	go test -c ./test/integration
 	./integration.test -ginkgo.slowSpecThreshold=15 -ginkgo.v -ginkgo.failFast
+	go test -c ./test/integration/resilience
+	./resilience.test -ginkgo.slowSpecThreshold=15 -ginkgo.v -ginkgo.failFast
 .PHONY: test-integration
@@ -159,6 +159,60 @@ var _ = ginkgo.Describe("Cluster Lease Update", func() {
 		gracePeriod := 2 * 5 * util.TestLeaseDurationSeconds
 		assertAvailableCondition(managedClusterName, metav1.ConditionUnknown, gracePeriod)
 	})
+
+	ginkgo.It("managed cluster should be available after its lease duration is shrunk", func() {
+		// run registration agent
+		agentOptions := spoke.SpokeAgentOptions{
+			ClusterName:              managedClusterName,
+			BootstrapKubeconfig:      bootstrapKubeConfigFile,
+			HubKubeconfigSecret:      hubKubeconfigSecret,
+			HubKubeconfigDir:         hubKubeconfigDir,
+			ClusterHealthCheckPeriod: 1 * time.Minute,
+		}
+		cancel := util.RunAgent("cluster-leasetest", agentOptions, spokeCfg)
+		defer cancel()
+
+		bootstrapManagedCluster(managedClusterName, hubKubeconfigSecret, 60)
+		assertAvailableCondition(managedClusterName, metav1.ConditionTrue, 0)
+
+		// shrink the lease duration without restarting the agent
+		err := updateManagedClusterLeaseDuration(managedClusterName, util.TestLeaseDurationSeconds)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		// after two short grace period, make sure the agent updates the lease with the shrunk lease duration
+		gracePeriod := 2 * 5 * util.TestLeaseDurationSeconds
+		assertAvailableConditionUnchanged(managedClusterName, metav1.ConditionTrue, gracePeriod)
+	})
+
+	ginkgo.It("managed cluster should use the grace period of its grown lease duration", func() {
+		// run registration agent
+		agentOptions := spoke.SpokeAgentOptions{
+			ClusterName:              managedClusterName,
+			BootstrapKubeconfig:      bootstrapKubeConfigFile,
+			HubKubeconfigSecret:      hubKubeconfigSecret,
+			HubKubeconfigDir:         hubKubeconfigDir,
+			ClusterHealthCheckPeriod: 1 * time.Minute,
+		}
+		stop := util.RunAgent("cluster-leasetest", agentOptions, spokeCfg)
+
+		bootstrapManagedCluster(managedClusterName, hubKubeconfigSecret, util.TestLeaseDurationSeconds)
+		assertAvailableCondition(managedClusterName, metav1.ConditionTrue, 0)
+
+		// grow the lease duration without restarting the hub
+		leaseDuration := int32(4)
+		err := updateManagedClusterLeaseDuration(managedClusterName, leaseDuration)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		// stop the agent
+		stop()
+
+		// after two short grace period, make sure the managed cluster is still available
+		shortGracePeriod := 2 * 5 * util.TestLeaseDurationSeconds
+		assertAvailableConditionUnchanged(managedClusterName, metav1.ConditionTrue, shortGracePeriod)
+
+		// after the grown grace period, make sure the managed cluster is unknown
+		assertAvailableCondition(managedClusterName, metav1.ConditionUnknown, 5*int(leaseDuration)-shortGracePeriod)
+	})
 })
 
 func bootstrapManagedCluster(managedClusterName, hubKubeconfigSecret string, leaseDuration int32) {
@@ -196,6 +250,22 @@ func assertAvailableCondition(managedClusterName string, status metav1.Condition
 	}, eventuallyTimeout, eventuallyInterval).ShouldNot(gomega.HaveOccurred())
 }
 
+// assertAvailableConditionUnchanged asserts the available condition of the managed cluster is kept with
+// the status for d seconds.
+func assertAvailableConditionUnchanged(managedClusterName string, status metav1.ConditionStatus, d int) {
+	gomega.Consistently(func() error {
+		managedCluster, err := util.GetManagedCluster(clusterClient, managedClusterName)
+		if err != nil {
+			return err
+		}
+		if !meta.IsStatusConditionPresentAndEqual(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable, status) {
+			return fmt.Errorf("expected avaibale condition is %s, but %v", status,
+				meta.FindStatusCondition(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable))
+		}
+		return nil
+	}, time.Duration(d)*time.Second, eventuallyInterval).ShouldNot(gomega.HaveOccurred())
+}
+
 func updateManagedClusterLeaseDuration(clusterName string, leaseDuration int32) error {
 	cluster, err := clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), clusterName, metav1.GetOptions{})
 	if err != nil {
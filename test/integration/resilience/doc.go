@@ -0,0 +1,6 @@
+// Package resilience provides integration tests for the recovery of the registration from the failures of
+// the hub in the middle of the registration, the test cases include
+// - the hub controller manager restarts before a managed cluster joins
+// - the webhook server is unavailable when the agent creates its managed cluster
+// - the bootstrap certificate of the agent expires before its csr is approved
+package resilience
@@ -0,0 +1,77 @@
+package resilience_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+
+	"k8s.io/client-go/transport"
+
+	"open-cluster-management.io/registration/pkg/clientcert"
+	"open-cluster-management.io/registration/pkg/hub"
+	"open-cluster-management.io/registration/pkg/spoke/managedcluster"
+	"open-cluster-management.io/registration/test/framework"
+	"open-cluster-management.io/registration/test/integration/util"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	eventuallyTimeout  = 60 // seconds
+	eventuallyInterval = 1  // seconds
+)
+
+// agentNamespace is the namespace of the hub kubeconfig secrets of the agents.
+const agentNamespace = "open-cluster-management-agent"
+
+var fakeHub *framework.FakeHub
+
+var testDir string
+
+func TestResilience(t *testing.T) {
+	gomega.RegisterFailHandler(ginkgo.Fail)
+	ginkgo.RunSpecs(t, "Resilience Suite")
+}
+
+var _ = ginkgo.BeforeSuite(func() {
+	logf.SetLogger(zap.New(zap.WriteTo(ginkgo.GinkgoWriter), zap.UseDevMode(true)))
+
+	ginkgo.By("bootstrapping the hub with the webhook")
+
+	// crank up the sync speed
+	transport.CertCallbackRefreshDuration = 5 * time.Second
+	clientcert.ControllerResyncInterval = 5 * time.Second
+	managedcluster.CreatingControllerSyncInterval = 1 * time.Second
+	hub.ResyncInterval = 5 * time.Second
+
+	var err error
+	fakeHub, err = framework.StartFakeHubWithOptions(framework.FakeHubOptions{
+		CRDPaths: []string{
+			filepath.Join(".", "deploy", "hub"),
+			filepath.Join(".", "deploy", "spoke"),
+		},
+		WebhookConfigFile: filepath.Join(".", "deploy", "webhook", "webhook.yaml"),
+	})
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+	err = util.PrepareSpokeAgentNamespace(fakeHub.KubeClient, agentNamespace)
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+	testDir, err = os.MkdirTemp("", "registration-resilience")
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+})
+
+var _ = ginkgo.AfterSuite(func() {
+	ginkgo.By("tearing down the hub")
+
+	err := fakeHub.Stop()
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+	err = os.RemoveAll(testDir)
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+})
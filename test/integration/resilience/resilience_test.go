@@ -0,0 +1,175 @@
+package resilience_test
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/spoke"
+	"open-cluster-management.io/registration/test/integration/util"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = ginkgo.Describe("Registration Resilience", func() {
+	var managedClusterName, hubKubeconfigSecret, bootstrapFile string
+	var agentOptions spoke.SpokeAgentOptions
+
+	ginkgo.BeforeEach(func() {
+		suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+		managedClusterName = "resilience-spokecluster-" + suffix
+		hubKubeconfigSecret = "resilience-hub-kubeconfig-secret-" + suffix
+		bootstrapFile = path.Join(testDir, managedClusterName, "bootstrap", "kubeconfig")
+
+		agentOptions = spoke.SpokeAgentOptions{
+			ClusterName:              managedClusterName,
+			BootstrapKubeconfig:      bootstrapFile,
+			HubKubeconfigSecret:      hubKubeconfigSecret,
+			HubKubeconfigDir:         path.Join(testDir, managedClusterName, "hub-kubeconfig"),
+			ClusterHealthCheckPeriod: 1 * time.Minute,
+		}
+	})
+
+	// assertJoined asserts the agent gets its hub kubeconfig and the managed cluster joins the hub.
+	assertJoined := func() {
+		gomega.Eventually(func() error {
+			_, err := util.GetFilledHubKubeConfigSecret(fakeHub.KubeClient, agentNamespace, hubKubeconfigSecret)
+			return err
+		}, eventuallyTimeout, eventuallyInterval).ShouldNot(gomega.HaveOccurred())
+
+		gomega.Eventually(func() error {
+			cluster, err := util.GetManagedCluster(fakeHub.ClusterClient, managedClusterName)
+			if err != nil {
+				return err
+			}
+			if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined) {
+				return fmt.Errorf("managed cluster %q is not joined", managedClusterName)
+			}
+			return nil
+		}, eventuallyTimeout, eventuallyInterval).ShouldNot(gomega.HaveOccurred())
+	}
+
+	// acceptAndApprove accepts the managed cluster and approves the csr of its agent as a hub admin does.
+	acceptAndApprove := func() {
+		gomega.Eventually(func() error {
+			_, err := util.FindUnapprovedSpokeCSR(fakeHub.KubeClient, managedClusterName)
+			return err
+		}, eventuallyTimeout, eventuallyInterval).ShouldNot(gomega.HaveOccurred())
+
+		err := fakeHub.AcceptCluster(managedClusterName, 60)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		err = fakeHub.ApproveCSR(managedClusterName, 24*time.Hour)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+
+	ginkgo.It("should join once the hub controller manager restarts in the middle of the registration", func() {
+		err := fakeHub.CreateBootstrapKubeConfig(bootstrapFile)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		cancel := util.RunAgent("resilience-controllers-restart", agentOptions, fakeHub.Config)
+		defer cancel()
+
+		gomega.Eventually(func() error {
+			_, err := util.GetManagedCluster(fakeHub.ClusterClient, managedClusterName)
+			return err
+		}, eventuallyTimeout, eventuallyInterval).ShouldNot(gomega.HaveOccurred())
+
+		ginkgo.By("stopping the hub controller manager")
+		fakeHub.StopControllers()
+		// start the controllers again even if the test fails, the other tests rely on them
+		defer fakeHub.StartControllers()
+
+		acceptAndApprove()
+
+		// the acceptance of the managed cluster is not handled until the controllers are back
+		gomega.Consistently(func() bool {
+			cluster, err := util.GetManagedCluster(fakeHub.ClusterClient, managedClusterName)
+			if err != nil {
+				return false
+			}
+			return meta.FindStatusCondition(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) == nil
+		}, 5*time.Second, eventuallyInterval).Should(gomega.BeTrue())
+
+		ginkgo.By("restarting the hub controller manager")
+		fakeHub.StartControllers()
+
+		assertJoined()
+	})
+
+	ginkgo.It("should join once the webhook server is back", func() {
+		ginkgo.By("stopping the webhook server")
+		fakeHub.StopWebhook()
+		defer fakeHub.StartWebhook()
+
+		err := fakeHub.CreateBootstrapKubeConfig(bootstrapFile)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		cancel := util.RunAgent("resilience-webhook-restart", agentOptions, fakeHub.Config)
+		defer cancel()
+
+		// the creation of the managed cluster is rejected since the webhook fails closed
+		gomega.Consistently(func() bool {
+			_, err := util.GetManagedCluster(fakeHub.ClusterClient, managedClusterName)
+			return errors.IsNotFound(err)
+		}, 5*time.Second, eventuallyInterval).Should(gomega.BeTrue())
+
+		ginkgo.By("restarting the webhook server")
+		fakeHub.StartWebhook()
+
+		gomega.Eventually(func() error {
+			_, err := util.GetManagedCluster(fakeHub.ClusterClient, managedClusterName)
+			return err
+		}, eventuallyTimeout, eventuallyInterval).ShouldNot(gomega.HaveOccurred())
+
+		acceptAndApprove()
+		assertJoined()
+	})
+
+	ginkgo.It("should join once the expired bootstrap kubeconfig is rotated in the middle of the registration", func() {
+		bootstrapCertAge := 10 * time.Second
+		bootstrapCertExpiry := time.Now().Add(bootstrapCertAge)
+		err := fakeHub.Authn.CreateBootstrapKubeConfigWithCertAge(bootstrapFile, fakeHub.ServerCertFile, fakeHub.SecurePort, bootstrapCertAge)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		cancel := util.RunAgent("resilience-bootstrap-expiry", agentOptions, fakeHub.Config)
+		defer cancel()
+
+		var csrName string
+		gomega.Eventually(func() error {
+			csr, err := util.FindUnapprovedSpokeCSR(fakeHub.KubeClient, managedClusterName)
+			if err != nil {
+				return err
+			}
+			csrName = csr.Name
+			return nil
+		}, eventuallyTimeout, eventuallyInterval).ShouldNot(gomega.HaveOccurred())
+
+		ginkgo.By("expiring the bootstrap certificate before the csr is approved")
+		time.Sleep(time.Until(bootstrapCertExpiry) + time.Second)
+
+		// the pending csr is gone, e.g. it is cleaned up by the hub admin, the agent has to create a new one
+		// with the expired bootstrap certificate
+		err = fakeHub.KubeClient.CertificatesV1().CertificateSigningRequests().Delete(context.TODO(), csrName, metav1.DeleteOptions{})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		gomega.Consistently(func() bool {
+			_, err := util.FindUnapprovedSpokeCSR(fakeHub.KubeClient, managedClusterName)
+			return err != nil
+		}, 5*time.Second, eventuallyInterval).Should(gomega.BeTrue())
+
+		ginkgo.By("rotating the bootstrap kubeconfig")
+		err = fakeHub.CreateBootstrapKubeConfig(bootstrapFile)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		acceptAndApprove()
+		assertJoined()
+	})
+})
@@ -11,10 +11,12 @@ import (
 	"github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	"open-cluster-management.io/registration/pkg/helpers"
 	"open-cluster-management.io/registration/pkg/spoke"
 	"open-cluster-management.io/registration/test/integration/util"
 )
@@ -175,7 +177,7 @@ var _ = ginkgo.Describe("Cluster Claim", func() {
 				if err != nil {
 					return false
 				}
-				return reflect.DeepEqual(clusterClaims, spokeCluster.Status.ClusterClaims)
+				return reflect.DeepEqual(clusterClaims, createdClusterClaims(spokeCluster))
 			}, eventuallyTimeout, eventuallyInterval).Should(gomega.BeTrue())
 
 			ginkgo.By("Create a new claim")
@@ -202,7 +204,7 @@ var _ = ginkgo.Describe("Cluster Claim", func() {
 				if err != nil {
 					return false
 				}
-				return reflect.DeepEqual(newClusterClaims, spokeCluster.Status.ClusterClaims)
+				return reflect.DeepEqual(newClusterClaims, createdClusterClaims(spokeCluster))
 			}, eventuallyTimeout, eventuallyInterval).Should(gomega.BeTrue())
 
 			ginkgo.By("Update the claim")
@@ -222,7 +224,7 @@ var _ = ginkgo.Describe("Cluster Claim", func() {
 				if err != nil {
 					return false
 				}
-				return reflect.DeepEqual(updatedClusterClaims, spokeCluster.Status.ClusterClaims)
+				return reflect.DeepEqual(updatedClusterClaims, createdClusterClaims(spokeCluster))
 			}, eventuallyTimeout, eventuallyInterval).Should(gomega.BeTrue())
 
 			ginkgo.By("Delete the claim")
@@ -233,7 +235,7 @@ var _ = ginkgo.Describe("Cluster Claim", func() {
 				if err != nil {
 					return false
 				}
-				return reflect.DeepEqual(clusterClaims, spokeCluster.Status.ClusterClaims)
+				return reflect.DeepEqual(clusterClaims, createdClusterClaims(spokeCluster))
 			}, eventuallyTimeout, eventuallyInterval).Should(gomega.BeTrue())
 		})
 	})
@@ -264,8 +266,21 @@ var _ = ginkgo.Describe("Cluster Claim", func() {
 					return false
 				}
 
-				return len(spokeCluster.Status.ClusterClaims) == maxCustomClusterClaims
+				return len(createdClusterClaims(spokeCluster)) == maxCustomClusterClaims
 			}, eventuallyTimeout, eventuallyInterval).Should(gomega.BeTrue())
 		})
 	})
 })
+
+// createdClusterClaims returns the claims in the status of the managed cluster except the well-known
+// claims synthesized by the agent.
+func createdClusterClaims(managedCluster *clusterv1.ManagedCluster) []clusterv1.ManagedClusterClaim {
+	wellKnownClaimNames := sets.NewString(helpers.WellKnownClusterClaimNames...)
+	claims := []clusterv1.ManagedClusterClaim{}
+	for _, claim := range managedCluster.Status.ClusterClaims {
+		if !wellKnownClaimNames.Has(claim.Name) {
+			claims = append(claims, claim)
+		}
+	}
+	return claims
+}
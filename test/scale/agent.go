@@ -0,0 +1,223 @@
+package scale
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	"open-cluster-management.io/registration/pkg/hub/user"
+
+	certificates "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/client-go/util/retry"
+)
+
+// counters count the requests of the simulated agents.
+type counters struct {
+	leaseRenewals int64
+	statusUpdates int64
+	errors        int64
+}
+
+// simulatedAgent acts as the registration agent of a managed cluster on the hub, without a real cluster.
+type simulatedAgent struct {
+	clusterName   string
+	kubeClient    kubernetes.Interface
+	clusterClient clusterclientset.Interface
+	options       *Options
+	counters      *counters
+}
+
+// register creates the managed cluster and the CSR of the agent, and waits until the managed cluster is
+// accepted. It returns the time it takes.
+func (a *simulatedAgent) register(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+
+	cluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   a.clusterName,
+			Labels: map[string]string{scaleTestLabel: "true"},
+		},
+		Spec: clusterv1.ManagedClusterSpec{
+			ManagedClusterClientConfigs: []clusterv1.ClientConfig{
+				{URL: fmt.Sprintf("https://%s.scale.test:6443", a.clusterName)},
+			},
+		},
+	}
+	_, err := a.clusterClient.ClusterV1().ManagedClusters().Create(ctx, cluster, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return 0, fmt.Errorf("unable to create managed cluster %q: %w", a.clusterName, err)
+	}
+
+	csr, err := a.createCSR(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if a.options.ApproveCSRs {
+		if err := a.approveCSR(ctx, csr); err != nil {
+			return 0, err
+		}
+	}
+	if a.options.AcceptClusters {
+		if err := a.acceptCluster(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	err = wait.PollImmediateWithContext(ctx, time.Second, a.options.RegistrationTimeout, func(ctx context.Context) (bool, error) {
+		cluster, err := a.clusterClient.ClusterV1().ManagedClusters().Get(ctx, a.clusterName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		return meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted), nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("managed cluster %q is not accepted in %s: %w", a.clusterName, a.options.RegistrationTimeout, err)
+	}
+	return time.Since(start), nil
+}
+
+// createCSR creates a CSR in the same form as the one created by the registration agent.
+func (a *simulatedAgent) createCSR(ctx context.Context) (*certificates.CertificateSigningRequest, error) {
+	keyData, err := keyutil.MakeEllipticPrivateKeyPEM()
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := keyutil.ParsePrivateKeyPEM(keyData)
+	if err != nil {
+		return nil, err
+	}
+	csrData, err := certutil.MakeCSR(privateKey, &pkix.Name{
+		Organization: []string{user.SubjectPrefix + a.clusterName, user.ManagedClustersGroup},
+		CommonName:   fmt.Sprintf("%s%s:%s", user.SubjectPrefix, a.clusterName, a.clusterName),
+	}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	csr := &certificates.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", a.clusterName),
+			Labels: map[string]string{
+				clusterv1.ClusterNameLabelKey: a.clusterName,
+				scaleTestLabel:                "true",
+			},
+		},
+		Spec: certificates.CertificateSigningRequestSpec{
+			Request: csrData,
+			Usages: []certificates.KeyUsage{
+				certificates.UsageDigitalSignature,
+				certificates.UsageKeyEncipherment,
+				certificates.UsageClientAuth,
+			},
+			SignerName: certificates.KubeAPIServerClientSignerName,
+		},
+	}
+	created, err := a.kubeClient.CertificatesV1().CertificateSigningRequests().Create(ctx, csr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create csr of managed cluster %q: %w", a.clusterName, err)
+	}
+	return created, nil
+}
+
+func (a *simulatedAgent) approveCSR(ctx context.Context, csr *certificates.CertificateSigningRequest) error {
+	csr = csr.DeepCopy()
+	csr.Status.Conditions = append(csr.Status.Conditions, certificates.CertificateSigningRequestCondition{
+		Type:    certificates.CertificateApproved,
+		Status:  corev1.ConditionTrue,
+		Reason:  "ApprovedByScaleTest",
+		Message: "Approved by the scale test",
+	})
+	_, err := a.kubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to approve csr %q: %w", csr.Name, err)
+	}
+	return nil
+}
+
+func (a *simulatedAgent) acceptCluster(ctx context.Context) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		cluster, err := a.clusterClient.ClusterV1().ManagedClusters().Get(ctx, a.clusterName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		cluster = cluster.DeepCopy()
+		cluster.Spec.HubAcceptsClient = true
+		cluster.Spec.LeaseDurationSeconds = a.options.LeaseDurationSeconds
+		_, err = a.clusterClient.ClusterV1().ManagedClusters().Update(ctx, cluster, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// run renews the lease and updates the status of the managed cluster periodically until the ctx is done.
+func (a *simulatedAgent) run(ctx context.Context) {
+	leaseDuration := time.Duration(a.options.LeaseDurationSeconds) * time.Second
+	go wait.JitterUntilWithContext(ctx, a.renewLease, leaseDuration, 0.25, true)
+	wait.JitterUntilWithContext(ctx, a.updateStatus, a.options.StatusUpdatePeriod, 0.25, true)
+}
+
+func (a *simulatedAgent) renewLease(ctx context.Context) {
+	lease, err := a.kubeClient.CoordinationV1().Leases(a.clusterName).Get(ctx, helpers.ManagedClusterLeaseName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		// the lease is not created by the hub yet
+		return
+	}
+	if err != nil {
+		a.observeError(ctx)
+		return
+	}
+
+	lease = lease.DeepCopy()
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: time.Now()}
+	if _, err := a.kubeClient.CoordinationV1().Leases(a.clusterName).Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		a.observeError(ctx)
+		return
+	}
+	atomic.AddInt64(&a.counters.leaseRenewals, 1)
+}
+
+func (a *simulatedAgent) updateStatus(ctx context.Context) {
+	capacity := clusterv1.ResourceList{
+		clusterv1.ResourceCPU:    *resource.NewQuantity(16, resource.DecimalSI),
+		clusterv1.ResourceMemory: *resource.NewQuantity(64*1024*1024*1024, resource.BinarySI),
+	}
+	_, _, err := helpers.UpdateManagedClusterStatus(ctx, a.clusterClient, a.clusterName,
+		helpers.UpdateManagedClusterConditionFn(metav1.Condition{
+			Type:    clusterv1.ManagedClusterConditionAvailable,
+			Status:  metav1.ConditionTrue,
+			Reason:  "ManagedClusterAvailable",
+			Message: "Managed cluster is available",
+		}),
+		func(oldStatus *clusterv1.ManagedClusterStatus) error {
+			oldStatus.Version = clusterv1.ManagedClusterVersion{Kubernetes: "v1.26.3"}
+			oldStatus.Capacity = capacity
+			oldStatus.Allocatable = capacity
+			return nil
+		})
+	if err != nil {
+		a.observeError(ctx)
+		return
+	}
+	atomic.AddInt64(&a.counters.statusUpdates, 1)
+}
+
+func (a *simulatedAgent) observeError(ctx context.Context) {
+	// the requests are cancelled once the test is done
+	if ctx.Err() != nil {
+		return
+	}
+	atomic.AddInt64(&a.counters.errors, 1)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	goflag "flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/registration/test/scale"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+// The scale-test binary simulates a number of registration agents against a hub, prints the registration
+// latency and the peak resource usage of the hub controllers, and exits with an error if the agents fail
+// to register or register too slowly.
+
+func main() {
+	o := scale.NewOptions()
+	var kubeconfig string
+	var cleanup bool
+	var qps float32
+	var burst int
+	var maxRegistrationLatency time.Duration
+
+	klog.InitFlags(nil)
+	fs := pflag.CommandLine
+	fs.AddGoFlagSet(goflag.CommandLine)
+	fs.StringVar(&kubeconfig, "kubeconfig", os.Getenv("KUBECONFIG"), "The kubeconfig of the hub.")
+	fs.IntVar(&o.Clusters, "clusters", o.Clusters, "The number of the simulated agents.")
+	fs.StringVar(&o.ClusterNamePrefix, "cluster-name-prefix", o.ClusterNamePrefix, "The prefix of the names of the managed clusters.")
+	fs.IntVar(&o.Concurrency, "concurrency", o.Concurrency, "The number of the agents registering at the same time.")
+	fs.Int32Var(&o.LeaseDurationSeconds, "lease-duration-seconds", o.LeaseDurationSeconds, "The lease duration of the managed clusters.")
+	fs.DurationVar(&o.StatusUpdatePeriod, "status-update-period", o.StatusUpdatePeriod, "The period the agents update the status of their managed clusters.")
+	fs.DurationVar(&o.Duration, "duration", o.Duration, "How long the agents keep running once all of them are registered.")
+	fs.DurationVar(&o.RegistrationTimeout, "registration-timeout", o.RegistrationTimeout, "How long an agent waits for its managed cluster to be accepted.")
+	fs.BoolVar(&o.ApproveCSRs, "approve-csrs", o.ApproveCSRs, "Approve the CSRs of the agents, disable it if they are approved automatically.")
+	fs.BoolVar(&o.AcceptClusters, "accept-clusters", o.AcceptClusters, "Accept the managed clusters, disable it if they are accepted automatically.")
+	fs.StringVar(&o.HubNamespace, "hub-namespace", o.HubNamespace, "The namespace of the hub controllers whose resource usage is sampled, set it to empty to disable the sampling.")
+	fs.DurationVar(&o.UsageSamplePeriod, "usage-sample-period", o.UsageSamplePeriod, "The period the resource usage of the hub controllers is sampled.")
+	fs.BoolVar(&cleanup, "cleanup", true, "Delete the managed clusters and the CSRs of the agents once the test is done.")
+	fs.Float32Var(&qps, "kube-api-qps", 200, "The QPS of the clients of all of the agents.")
+	fs.IntVar(&burst, "kube-api-burst", 400, "The burst of the clients of all of the agents.")
+	fs.DurationVar(&maxRegistrationLatency, "max-registration-latency", 0, "Fail if the p95 registration latency exceeds it, 0 to disable the check.")
+	pflag.Parse()
+
+	if err := run(kubeconfig, qps, burst, cleanup, maxRegistrationLatency, o); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(kubeconfig string, qps float32, burst int, cleanup bool, maxRegistrationLatency time.Duration, o *scale.Options) error {
+	config, err := clientcmd.BuildConfigFromFlags("" /* leave masterurl as empty */, kubeconfig)
+	if err != nil {
+		return fmt.Errorf("unable to load kubeconfig from file %q: %w", kubeconfig, err)
+	}
+	config.QPS = qps
+	config.Burst = burst
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	clusterClient, err := clusterclientset.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	result, err := scale.Run(ctx, kubeClient, clusterClient, o)
+	if cleanup {
+		if cleanupErr := scale.Cleanup(context.Background(), kubeClient, clusterClient); cleanupErr != nil {
+			klog.Errorf("Unable to clean up the scale test: %v", cleanupErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	result.Print(os.Stdout)
+	if result.Failed > 0 {
+		return fmt.Errorf("%d agents fail to register", result.Failed)
+	}
+	if p95 := scale.Percentile(result.RegistrationLatencies, 95); maxRegistrationLatency > 0 && p95 > maxRegistrationLatency {
+		return fmt.Errorf("the p95 registration latency %s exceeds %s", p95, maxRegistrationLatency)
+	}
+	return nil
+}
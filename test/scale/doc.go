@@ -0,0 +1,5 @@
+// Package scale simulates a large number of lightweight registration agents against a hub, so that the
+// resource usage and the latency of the hub controllers can be measured without real managed clusters.
+// Each simulated agent creates its ManagedCluster and CSR, waits until the managed cluster is accepted,
+// then keeps renewing its lease and updating the status of its managed cluster like a real agent.
+package scale
@@ -0,0 +1,200 @@
+package scale
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// scaleTestLabel is the label of the resources created by the simulated agents.
+const scaleTestLabel = "scale-test.open-cluster-management.io"
+
+// Options are the options of a scale test.
+type Options struct {
+	// Clusters is the number of the simulated agents.
+	Clusters int
+	// ClusterNamePrefix is the prefix of the names of the managed clusters.
+	ClusterNamePrefix string
+	// Concurrency is the number of the agents registering at the same time.
+	Concurrency int
+	// LeaseDurationSeconds is the lease duration of the managed clusters.
+	LeaseDurationSeconds int32
+	// StatusUpdatePeriod is the period the agents update the status of their managed clusters.
+	StatusUpdatePeriod time.Duration
+	// Duration is how long the agents keep running once all of them are registered.
+	Duration time.Duration
+	// RegistrationTimeout is how long an agent waits for its managed cluster to be accepted.
+	RegistrationTimeout time.Duration
+	// ApproveCSRs approves the CSRs of the agents, disable it if they are approved automatically.
+	ApproveCSRs bool
+	// AcceptClusters accepts the managed clusters, disable it if they are accepted automatically.
+	AcceptClusters bool
+	// HubNamespace is the namespace of the hub controllers whose resource usage is sampled, the usage is
+	// not sampled if it is empty.
+	HubNamespace string
+	// UsageSamplePeriod is the period the resource usage of the hub controllers is sampled.
+	UsageSamplePeriod time.Duration
+}
+
+// NewOptions returns the default options of a scale test.
+func NewOptions() *Options {
+	return &Options{
+		Clusters:             1000,
+		ClusterNamePrefix:    "scale-test-",
+		Concurrency:          50,
+		LeaseDurationSeconds: 60,
+		StatusUpdatePeriod:   time.Minute,
+		Duration:             10 * time.Minute,
+		RegistrationTimeout:  5 * time.Minute,
+		ApproveCSRs:          true,
+		AcceptClusters:       true,
+		HubNamespace:         "open-cluster-management-hub",
+		UsageSamplePeriod:    15 * time.Second,
+	}
+}
+
+// Result is the result of a scale test.
+type Result struct {
+	Registered int
+	Failed     int
+	// RegistrationLatencies are the time the registered agents take to get their managed clusters accepted.
+	RegistrationLatencies []time.Duration
+	LeaseRenewals         int64
+	StatusUpdates         int64
+	Errors                int64
+	// HubUsage is the peak resource usage of the pods of the hub controllers, it is empty if the
+	// metrics api is not available on the hub.
+	HubUsage map[string]ResourceUsage
+}
+
+// Run simulates the agents against the hub. The agents register in batches of the concurrency, and keep
+// running for the duration once all of them are registered.
+func Run(ctx context.Context, kubeClient kubernetes.Interface, clusterClient clusterclientset.Interface, o *Options) (*Result, error) {
+	if o.Clusters <= 0 || o.Concurrency <= 0 {
+		return nil, fmt.Errorf("the number of clusters and the concurrency must be positive")
+	}
+
+	sampler := newUsageSampler(kubeClient, o.HubNamespace)
+	samplerCtx, stopSampler := context.WithCancel(ctx)
+	defer stopSampler()
+	if len(o.HubNamespace) != 0 {
+		go sampler.run(samplerCtx, o.UsageSamplePeriod)
+	}
+
+	agentCtx, stopAgents := context.WithCancel(ctx)
+	defer stopAgents()
+
+	result := &Result{}
+	agentCounters := &counters{}
+	lock := sync.Mutex{}
+	agents := sync.WaitGroup{}
+	registering := make(chan struct{}, o.Concurrency)
+	for i := 0; i < o.Clusters; i++ {
+		agent := &simulatedAgent{
+			clusterName:   fmt.Sprintf("%s%d", o.ClusterNamePrefix, i),
+			kubeClient:    kubeClient,
+			clusterClient: clusterClient,
+			options:       o,
+			counters:      agentCounters,
+		}
+
+		registering <- struct{}{}
+		agents.Add(1)
+		go func() {
+			defer agents.Done()
+			latency, err := agent.register(agentCtx)
+			<-registering
+
+			lock.Lock()
+			if err != nil {
+				klog.Errorf("Agent of managed cluster %q fails to register: %v", agent.clusterName, err)
+				result.Failed++
+			} else {
+				result.Registered++
+				result.RegistrationLatencies = append(result.RegistrationLatencies, latency)
+			}
+			lock.Unlock()
+
+			if err == nil {
+				agent.run(agentCtx)
+			}
+		}()
+	}
+
+	// wait for all of the agents to finish the registration
+	for i := 0; i < o.Concurrency; i++ {
+		registering <- struct{}{}
+	}
+	klog.Infof("%d agents are registered, %d agents fail to register", result.Registered, result.Failed)
+
+	select {
+	case <-time.After(o.Duration):
+	case <-ctx.Done():
+	}
+	stopAgents()
+	agents.Wait()
+	stopSampler()
+
+	result.LeaseRenewals = atomic.LoadInt64(&agentCounters.leaseRenewals)
+	result.StatusUpdates = atomic.LoadInt64(&agentCounters.statusUpdates)
+	result.Errors = atomic.LoadInt64(&agentCounters.errors)
+	result.HubUsage = sampler.peak()
+	sort.Slice(result.RegistrationLatencies, func(i, j int) bool {
+		return result.RegistrationLatencies[i] < result.RegistrationLatencies[j]
+	})
+	return result, nil
+}
+
+// Cleanup deletes the managed clusters and the CSRs created by the simulated agents, the hub removes the
+// namespaces and the leases of the managed clusters once they are deleted.
+func Cleanup(ctx context.Context, kubeClient kubernetes.Interface, clusterClient clusterclientset.Interface) error {
+	listOptions := metav1.ListOptions{LabelSelector: scaleTestLabel}
+	if err := clusterClient.ClusterV1().ManagedClusters().DeleteCollection(ctx, metav1.DeleteOptions{}, listOptions); err != nil {
+		return fmt.Errorf("unable to delete managed clusters: %w", err)
+	}
+	if err := kubeClient.CertificatesV1().CertificateSigningRequests().DeleteCollection(ctx, metav1.DeleteOptions{}, listOptions); err != nil {
+		return fmt.Errorf("unable to delete csrs: %w", err)
+	}
+	return nil
+}
+
+// Percentile returns the nearest-rank percentile of the sorted latencies.
+func Percentile(sorted []time.Duration, percentile int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)*percentile+99)/100-1]
+}
+
+// Print prints the result in a human readable form.
+func (r *Result) Print(out io.Writer) {
+	fmt.Fprintf(out, "Registered agents: %d, failed agents: %d\n", r.Registered, r.Failed)
+	fmt.Fprintf(out, "Registration latency: p50 %s, p95 %s, max %s\n",
+		Percentile(r.RegistrationLatencies, 50), Percentile(r.RegistrationLatencies, 95), Percentile(r.RegistrationLatencies, 100))
+	fmt.Fprintf(out, "Lease renewals: %d, status updates: %d, errors: %d\n", r.LeaseRenewals, r.StatusUpdates, r.Errors)
+	if len(r.HubUsage) == 0 {
+		fmt.Fprintf(out, "Hub resource usage: unavailable\n")
+		return
+	}
+
+	pods := []string{}
+	for pod := range r.HubUsage {
+		pods = append(pods, pod)
+	}
+	sort.Strings(pods)
+	fmt.Fprintf(out, "Peak hub resource usage:\n")
+	for _, pod := range pods {
+		usage := r.HubUsage[pod]
+		fmt.Fprintf(out, "  %s: cpu %s, memory %s\n", pod, usage.CPU.String(), usage.Memory.String())
+	}
+}
@@ -0,0 +1,106 @@
+package scale
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/registration/pkg/helpers"
+	testinghelpers "open-cluster-management.io/registration/pkg/helpers/testing"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	coordv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestRun(t *testing.T) {
+	o := NewOptions()
+	o.Clusters = 3
+	o.Concurrency = 2
+	o.LeaseDurationSeconds = 1
+	o.StatusUpdatePeriod = 200 * time.Millisecond
+	o.Duration = 1500 * time.Millisecond
+	o.RegistrationTimeout = 5 * time.Second
+	o.HubNamespace = ""
+
+	// the leases are created by the hub once the managed clusters are accepted
+	leases := []runtime.Object{}
+	for _, name := range []string{"scale-test-0", "scale-test-1", "scale-test-2"} {
+		leases = append(leases, &coordv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: helpers.ManagedClusterLeaseName, Namespace: name},
+		})
+	}
+	kubeClient := kubefake.NewSimpleClientset(leases...)
+	// the fake client does not generate the names of the csrs
+	kubeClient.PrependReactor("create", "certificatesigningrequests", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		csr := action.(clienttesting.CreateAction).GetObject().(*certificatesv1.CertificateSigningRequest)
+		csr.Name = csr.GenerateName + "csr"
+		return false, nil, nil
+	})
+
+	// the hub accepts the managed clusters once hubAcceptsClient is set
+	clusterClient := clusterfake.NewSimpleClientset()
+	clusterClient.PrependReactor("update", "managedclusters", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		cluster := action.(clienttesting.UpdateAction).GetObject().(*clusterv1.ManagedCluster)
+		if cluster.Spec.HubAcceptsClient {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				Type:   clusterv1.ManagedClusterConditionHubAccepted,
+				Status: metav1.ConditionTrue,
+				Reason: "HubClusterAdminAccepted",
+			})
+		}
+		return false, nil, nil
+	})
+
+	result, err := Run(context.TODO(), kubeClient, clusterClient, o)
+	testinghelpers.AssertError(t, err, "")
+
+	if result.Registered != 3 || result.Failed != 0 || len(result.RegistrationLatencies) != 3 {
+		t.Errorf("expected 3 agents registered, but got %d registered and %d failed", result.Registered, result.Failed)
+	}
+	if result.LeaseRenewals == 0 || result.StatusUpdates == 0 {
+		t.Errorf("expected leases renewed and status updated, but got %d and %d", result.LeaseRenewals, result.StatusUpdates)
+	}
+	if result.Errors != 0 {
+		t.Errorf("expected no errors, but got %d", result.Errors)
+	}
+
+	csrs, err := kubeClient.CertificatesV1().CertificateSigningRequests().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, csr := range csrs.Items {
+		if len(csr.Status.Conditions) == 0 {
+			t.Errorf("expected csr %q approved", csr.Name)
+		}
+	}
+	cluster, err := clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "scale-test-0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable) {
+		t.Errorf("expected managed cluster available, but got %v", cluster.Status.Conditions)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 1; i <= 20; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Second)
+	}
+	if p := Percentile(latencies, 95); p != 19*time.Second {
+		t.Errorf("expected p95 19s, but got %s", p)
+	}
+	if p := Percentile(latencies, 100); p != 20*time.Second {
+		t.Errorf("expected max 20s, but got %s", p)
+	}
+	if p := Percentile(nil, 95); p != 0 {
+		t.Errorf("expected no percentile, but got %s", p)
+	}
+}
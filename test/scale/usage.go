@@ -0,0 +1,108 @@
+package scale
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// ResourceUsage is the resource usage of a pod.
+type ResourceUsage struct {
+	CPU    resource.Quantity
+	Memory resource.Quantity
+}
+
+// podMetricsList is the part of the PodMetricsList of the metrics api the sampler relies on, the metrics
+// api is requested directly to avoid the dependency on its client.
+type podMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Containers []struct {
+			Usage map[string]resource.Quantity `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// usageSampler samples the resource usage of the pods in a namespace with the metrics api, and keeps the
+// peak usage of each pod.
+type usageSampler struct {
+	kubeClient kubernetes.Interface
+	namespace  string
+
+	lock      sync.Mutex
+	peakUsage map[string]ResourceUsage
+}
+
+func newUsageSampler(kubeClient kubernetes.Interface, namespace string) *usageSampler {
+	return &usageSampler{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		peakUsage:  map[string]ResourceUsage{},
+	}
+}
+
+func (s *usageSampler) run(ctx context.Context, period time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.sample(ctx); err != nil && ctx.Err() == nil {
+			klog.Warningf("Unable to sample the resource usage of the hub: %v", err)
+		}
+	}, period)
+}
+
+func (s *usageSampler) sample(ctx context.Context) error {
+	data, err := s.kubeClient.CoreV1().RESTClient().Get().
+		AbsPath(fmt.Sprintf("/apis/metrics.k8s.io/v1beta1/namespaces/%s/pods", s.namespace)).
+		DoRaw(ctx)
+	if err != nil {
+		return err
+	}
+	metrics := &podMetricsList{}
+	if err := json.Unmarshal(data, metrics); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, pod := range metrics.Items {
+		usage := ResourceUsage{}
+		for _, container := range pod.Containers {
+			if cpu, ok := container.Usage["cpu"]; ok {
+				usage.CPU.Add(cpu)
+			}
+			if memory, ok := container.Usage["memory"]; ok {
+				usage.Memory.Add(memory)
+			}
+		}
+
+		peak := s.peakUsage[pod.Metadata.Name]
+		if usage.CPU.Cmp(peak.CPU) > 0 {
+			peak.CPU = usage.CPU
+		}
+		if usage.Memory.Cmp(peak.Memory) > 0 {
+			peak.Memory = usage.Memory
+		}
+		s.peakUsage[pod.Metadata.Name] = peak
+	}
+	return nil
+}
+
+// peak returns the peak resource usage of each pod sampled.
+func (s *usageSampler) peak() map[string]ResourceUsage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	peak := map[string]ResourceUsage{}
+	for pod, usage := range s.peakUsage {
+		peak[pod] = usage
+	}
+	return peak
+}